@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"slices"
 	"strconv"
 	"strings"
@@ -11,35 +12,121 @@ import (
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/encrypt"
+	"github.com/lone-faerie/mqttop/internal/mqttutil"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sign"
+	"github.com/lone-faerie/mqttop/tracing"
+	"github.com/lone-faerie/mqttop/transport"
 )
 
 // Bridge is the mqtt client that bridges metrics to the mqtt broker.
 type Bridge struct {
-	client mqtt.Client
+	// transport is what the bridge publishes to, and client is transport as
+	// an [mqtt.Client] counting its publishes.
+	transport transport.Transport
+	client    mqtt.Client
+	// initErr is the error setting up the bridge from the config, such as
+	// opening its transport, which is returned by [Bridge.Start].
+	initErr error
 
 	baseTopic string
 	discovery *discovery.Discovery
 	migrate   bool
+	store     *discovery.Store
 	metrics   []metrics.Metric
 	states    sync.Map
 
-	updates    chan metrics.Metric
+	// unsupported are the metrics of the config that couldn't be created,
+	// which are only reported if the bridge created its own metrics.
+	unsupported       []metrics.Unsupported
+	reportUnsupported bool
+
+	power    *power
+	wol      []wolTarget
+	commands []*command
+	birth    *birth
+
+	stats         stats
+	statsInterval time.Duration
+	// events are the pending connection events of the client, if they're
+	// enabled.
+	events *events
+
+	// summary is the config of the summary of the host, if it's enabled.
+	summary *config.SummaryConfig
+	// derived are the values derived from the payloads of the metrics, if
+	// any are configured.
+	derived *derived
+	// onSelectionMode is called once the selection mode of the CPU metric may
+	// have been changed over MQTT, if not nil.
+	onSelectionMode func(*metrics.CPU)
+
+	// timeout is how long to wait for the broker to acknowledge a token.
+	timeout time.Duration
+	// results indicates if the result of each command is published.
+	results bool
+	// schemas indicates if the schema of each metric is published.
+	schemas bool
+	// encrypter encrypts the payload of each metric, if not nil.
+	encrypter *encrypt.Encrypter
+	// signer signs the payload of each metric, if not nil.
+	signer *sign.Signer
+	// tracer traces the update and publish of each metric, and tp is its
+	// provider if created by the bridge, which is shut down once it stops.
+	tracer trace.Tracer
+	tp     tracing.Provider
+
+	// cfg is the config the bridge was created with, which the watchdog
+	// renews stuck metrics from.
+	cfg      *config.Config
+	watchdog *watchdog
+	lazy     *lazy
+
+	updates    chan tick
 	rediscover chan metrics.Metric
 
 	ready chan struct{}
 	done  chan struct{}
 	err   error
 
-	mu     sync.Mutex
-	wg     sync.WaitGroup
-	once   sync.Once
-	cancel context.CancelFunc
+	// ctx is the context the metrics are started with, and running indicates
+	// metrics added to the bridge must be started by [Bridge.AddMetric].
+	ctx     context.Context
+	running bool
+
+	mu          sync.Mutex
+	discoveryMu sync.Mutex
+	wg          sync.WaitGroup
+	once        sync.Once
+	cancel      context.CancelFunc
 }
 
+var (
+	// ErrDuplicateTopic is returned when adding a metric with the same topic
+	// as another metric of the bridge.
+	ErrDuplicateTopic = errors.New("duplicate topic")
+	// ErrNoMetrics is returned when starting a bridge without any metrics.
+	ErrNoMetrics = errors.New("no metrics")
+	// ErrInvalidConfig is returned when starting a bridge whose config is
+	// invalid, such as an unknown transport or encryption recipient.
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrUnknownMetric is returned when removing a metric that does not belong
+	// to the bridge.
+	ErrUnknownMetric = errors.New("unknown metric")
+	// ErrTimeout is returned when the broker doesn't acknowledge a publish,
+	// subscribe or unsubscribe within the publish timeout. It wraps
+	// [os.ErrDeadlineExceeded], and the operation may be retried.
+	ErrTimeout = mqttutil.ErrTimeout
+)
+
 var noopLogger = mqtt.NOOPLogger{}
 
 // New returns a new Bridge with the givenn config and options. The config will be used to fill
@@ -47,22 +134,89 @@ var noopLogger = mqtt.NOOPLogger{}
 // and [Bridge.Ready] called on it before it may be used. This follows the convention of
 // [mqtt.NewClient] as well as waiting for metrics to be ready.
 func New(cfg *config.Config, opts ...Option) *Bridge {
-	b := &Bridge{}
+	b := &Bridge{cfg: cfg}
 
 	for _, opt := range opts {
 		opt(b)
 	}
 
-	if b.client == nil {
+	if b.transport == nil && !cfg.Transport.IsMQTT() {
+		t, err := transport.Open(cfg)
+		if err != nil {
+			b.initErr = fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		} else {
+			b.transport = t
+		}
+	}
+
+	if b.transport == nil {
 		opts := cfg.MQTT.ClientOptions()
-		b.client = mqtt.NewClient(opts)
+		opts.SetOnConnectHandler(b.onConnect)
+
+		if cfg.Events.Enabled {
+			b.events = new(events)
+			opts.SetConnectionLostHandler(b.onConnectionLost)
+			opts.SetReconnectingHandler(b.onReconnecting)
+		}
+
+		b.transport = transport.MQTT(mqtt.NewClient(opts))
 	}
 
+	b.client = &statsClient{Client: transport.Client(b.transport), stats: &b.stats}
+
 	if len(b.metrics) == 0 {
-		b.metrics = metrics.New(cfg)
+		b.metrics, b.unsupported = metrics.NewWithUnsupported(cfg)
+		b.reportUnsupported = true
 	}
 
-	if b.discovery == nil && cfg.Discovery.Enabled {
+	b.results = cfg.MQTT.CommandResults
+	b.schemas = cfg.MQTT.PublishSchemas
+
+	if b.encrypter == nil && cfg.Encryption.Enabled() {
+		e, err := encrypt.NewEncrypter(cfg.Encryption.Recipients...)
+		if err != nil {
+			// Never fall back to publishing in the clear
+			b.initErr = errors.Join(b.initErr, fmt.Errorf("%w: encryption: %w", ErrInvalidConfig, err))
+		} else {
+			b.encrypter = e
+		}
+	}
+
+	if b.signer == nil && cfg.Signing.Enabled() {
+		s, err := newSigner(&cfg.Signing)
+		if err != nil {
+			b.initErr = errors.Join(b.initErr, fmt.Errorf("%w: signing: %w", ErrInvalidConfig, err))
+		} else {
+			b.signer = s
+		}
+	}
+
+	if b.tracer == nil && cfg.Tracing.Enabled() {
+		tp, err := tracing.New(context.Background(), &cfg.Tracing, cfg.MQTT.ClientID)
+		switch {
+		case errors.Is(err, tracing.ErrNotSupported):
+			log.Warn("Not tracing updates, built with notrace")
+		case err != nil:
+			b.initErr = errors.Join(b.initErr, fmt.Errorf("%w: tracing: %w", ErrInvalidConfig, err))
+		default:
+			b.tp = tp
+			b.tracer = tp.Tracer(tracing.Name)
+		}
+	}
+
+	if b.tracer == nil {
+		b.tracer = noop.NewTracerProvider().Tracer(tracing.Name)
+	}
+
+	if b.timeout == 0 {
+		b.timeout = cfg.MQTT.PublishTimeout
+		if b.timeout == 0 {
+			b.timeout = mqttutil.Timeout(cfg.MQTT.WriteTimeout)
+		}
+	}
+
+	// Home Assistant only discovers over MQTT
+	if b.discovery == nil && cfg.Discovery.Enabled && cfg.Transport.IsMQTT() {
 		d, err := discovery.New(&cfg.Discovery)
 		if err != nil {
 			log.Error("Unable to get discovery", err)
@@ -71,8 +225,16 @@ func New(cfg *config.Config, opts ...Option) *Bridge {
 		}
 	}
 
-	if cfg.MQTT.LogLevel < log.LevelDisabled && mqtt.ERROR != noopLogger {
-		WithLogLevel(cfg.MQTT.LogLevel)(b)
+	if b.discovery != nil && b.discovery.Timeout == 0 {
+		b.discovery.Timeout = b.timeout
+	}
+
+	if b.power == nil {
+		b.power = newPower(&cfg.Power)
+	}
+
+	if b.wol == nil {
+		b.wol = newWOL(cfg.WOL)
 	}
 
 	if b.baseTopic == "" {
@@ -83,44 +245,186 @@ func New(cfg *config.Config, opts ...Option) *Bridge {
 		}
 	}
 
+	if b.commands == nil {
+		b.commands = newCommands(cfg.Commands, b.baseTopic)
+	}
+
+	if b.statsInterval == 0 && cfg.Stats.Enabled {
+		b.statsInterval = cfg.Stats.PublishInterval
+		if b.statsInterval <= 0 {
+			b.statsInterval = config.DefaultStats.PublishInterval
+		}
+	}
+
+	if cfg.Summary.Enabled {
+		b.summary = &cfg.Summary
+	}
+
+	if d, err := newDerived(cfg.Derived); err != nil {
+		b.initErr = errors.Join(b.initErr, fmt.Errorf("%w: derived: %w", ErrInvalidConfig, err))
+	} else {
+		b.derived = d
+	}
+
+	if b.watchdog == nil && cfg.Watchdog.Enabled {
+		missed := cfg.Watchdog.MissedIntervals
+		if missed <= 0 {
+			missed = config.DefaultWatchdog.MissedIntervals
+		}
+
+		b.watchdog = newWatchdog(missed)
+	}
+
+	if b.lazy == nil && cfg.Lazy.Enabled {
+		timeout := cfg.Lazy.Timeout
+		if timeout <= 0 {
+			timeout = config.DefaultLazy.Timeout
+		}
+
+		b.lazy = newLazy(timeout, cfg.Lazy.Metrics)
+	}
+
+	if b.birth == nil && cfg.MQTT.BirthWillEnabled {
+		b.birth = &birth{
+			topic:    cfg.MQTT.BirthWillTopic,
+			payload:  cfg.MQTT.BirthPayload,
+			qos:      cfg.MQTT.BirthQoS,
+			retained: cfg.MQTT.BirthRetained,
+		}
+	}
+
+	if cfg.MQTT.LogLevel < log.LevelDisabled && mqtt.ERROR != noopLogger {
+		WithLogLevel(cfg.MQTT.LogLevel)(b)
+	}
+
 	return b
 }
 
-func (b *Bridge) AddMetric(ctx context.Context, m metrics.Metric) {
-	var done <-chan struct{}
+// AddMetric adds m to the bridge. If the bridge is running, m is started and
+// its discovery is published. Otherwise, m is started along with the other
+// metrics and included in the first discovery. An error wrapping [ErrDuplicateTopic]
+// is returned if the bridge already has a metric with the same topic as m.
+func (b *Bridge) AddMetric(ctx context.Context, m metrics.Metric) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	if ctx != nil {
-		done = ctx.Done()
+	if b.ctx != nil && b.ctx.Err() != nil {
+		return errors.New("bridge is stopped")
 	}
 
-	select {
-	case <-done:
-		return
-	case <-b.done:
-		return
-	case <-b.ready:
-		b.mu.Lock()
+	for _, mm := range b.metrics {
+		if mm != nil && mm.Topic() == m.Topic() {
+			return fmt.Errorf("%w %q", ErrDuplicateTopic, m.Topic())
+		}
+	}
 
-		i := len(b.metrics)
-		b.metrics = append(b.metrics, m)
+	i := len(b.metrics)
+	b.metrics = append(b.metrics, m)
 
-		b.mu.Unlock()
-		b.startMetric(ctx, i, m, true)
-	default:
-		b.metrics = append(b.metrics, m)
+	if !b.running {
+		if dd, ok := m.(discovery.Discoverer); ok && b.discovery != nil {
+			dd.Discover(b.discovery)
+		}
+
+		return nil
 	}
+
+	b.startMetric(b.ctx, i, m, true)
+
+	return nil
 }
 
-// waitToken waits for the first of ctx.Done() or t.Done() and returns t.Error(), or nil if
-// ctx.Done() finished first.
-func waitToken(ctx context.Context, t mqtt.Token) error {
-	select {
-	case <-ctx.Done():
+// metricTopics returns the topics the bridge subscribes to for m once it's
+// started, which are the "/update" and "/stop" subtopics and the topic of each
+// command of m.
+func metricTopics(m metrics.Metric) []string {
+	topics := []string{m.Topic() + "/update", m.Topic() + "/stop"}
+
+	if cm, ok := m.(metrics.Commander); ok {
+		for topic := range cm.Commands() {
+			topics = append(topics, m.Topic()+"/"+topic)
+		}
+	}
+
+	return topics
+}
+
+// RemoveMetric stops m and removes it from the bridge. If the bridge is running,
+// the subscriptions of m are removed and the removal of its discovery is published.
+// An error wrapping [ErrUnknownMetric] is returned if m does not belong to the bridge.
+func (b *Bridge) RemoveMetric(ctx context.Context, m metrics.Metric) error {
+	if m == nil {
+		return ErrUnknownMetric
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := slices.Index(b.metrics, m)
+	if i < 0 {
+		return fmt.Errorf("%w %q", ErrUnknownMetric, m.Topic())
+	}
+
+	b.metrics[i] = nil
+	b.watchdog.forget(m)
+
+	m.Stop()
+	b.states.Delete(m.Topic())
+
+	var cmps []string
+
+	if dd, ok := m.(discovery.Discoverer); ok && b.discovery != nil {
+		b.discoveryMu.Lock()
+		defer b.discoveryMu.Unlock()
+
+		cmps = b.discovery.ComponentsOf(dd)
+	}
+
+	if !b.running {
+		if len(cmps) > 0 {
+			b.discovery.Delete(cmps...)
+		}
+
 		return nil
-	case <-t.Done():
 	}
 
-	return t.Error()
+	topics := metricTopics(m)
+
+	if b.lazy.isLazy(m) {
+		b.lazy.release(m.Topic())
+		topics = append(topics, m.Topic()+subscribeTopic)
+	}
+
+	t := b.client.Unsubscribe(topics...)
+	if err := b.waitToken(ctx, t); err != nil {
+		return err
+	}
+
+	t = b.publishStates(false)
+	if err := b.waitToken(ctx, t); err != nil {
+		return err
+	}
+
+	if len(cmps) > 0 {
+		if err := b.discovery.Remove(ctx, b.client, cmps...); err != nil {
+			return err
+		}
+
+		b.saveDiscovery()
+	}
+
+	return nil
+}
+
+// waitToken waits for the first of ctx.Done() or t.Done() and returns t.Error(), or nil if
+// ctx.Done() finished first. If t isn't done within the publish timeout, an error wrapping
+// [ErrTimeout] is returned.
+func (b *Bridge) waitToken(ctx context.Context, t mqtt.Token) error {
+	return mqttutil.Wait(ctx, t, b.timeout)
 }
 
 // ctxDone indicates whether the given context has been canceled.
@@ -149,8 +453,12 @@ func (b *Bridge) loopMetric(ctx context.Context, i int, m metrics.Metric) {
 	defer func() {
 		m.Stop()
 
+		// The metrics running when the bridge stops are kept, so that their
+		// state may still be saved from [Bridge.Metrics].
 		b.mu.Lock()
-		b.metrics[i] = nil
+		if b.metrics[i] == m && ctx.Err() == nil {
+			b.metrics[i] = nil
+		}
 		b.mu.Unlock()
 
 		b.wg.Done()
@@ -160,27 +468,117 @@ func (b *Bridge) loopMetric(ctx context.Context, i int, m metrics.Metric) {
 		select {
 		case <-ctx.Done():
 			return
-		case err := <-m.Updated():
+		case err, ok := <-m.Updated():
+			if !ok {
+				// The watchdog still tracks m if it wasn't stopped by the bridge.
+				b.restartMetric(ctx, m, reasonStopped)
+				return
+			}
+
+			b.watchdog.touch(m)
+
 			updated := b.updateState(ctx, m, err)
+			t := b.startTick(ctx, m, err)
 
 			switch err {
 			case nil:
-				maybeSend(ctx, b.updates, m)
+				b.sendTick(ctx, t)
 			case metrics.ErrNoChange:
 				if updated {
-					maybeSend(ctx, b.updates, m)
+					b.sendTick(ctx, t)
+				} else {
+					t.end(err)
 				}
 			case metrics.ErrRescanned:
+				t.end(err)
+
 				if b.rediscover != nil {
 					maybeSend(ctx, b.rediscover, m)
 				}
 			default:
-				log.WarnError("Error updating "+m.Type(), err)
+				t.end(err)
+
+				if errors.Is(err, metrics.ErrPermission) {
+					// The metric won't be readable until it is restarted with
+					// sufficient permission, so it is stopped.
+					log.Error("Stopping "+m.Type()+", permission denied", err)
+					b.watchdog.forget(m)
+
+					return
+				}
+
+				var pe *metrics.PanicError
+
+				if errors.As(err, &pe) {
+					log.Error("Recovered panic updating "+m.Type(), err, "stack", string(pe.Stack))
+				} else if errors.Is(err, metrics.ErrMissingHardware) {
+					log.Error("Error updating "+m.Type()+", hardware is missing", err)
+				} else {
+					log.WarnError("Error updating "+m.Type(), err)
+				}
 			}
 		}
 	}
 }
 
+// appendMetric returns the JSON-encoded m, recovering any panic while encoding
+// it as an error so that the bridge keeps running.
+func appendMetric(m metrics.Metric) (data []byte, err error) {
+	defer metrics.Recover(&err)
+
+	return m.AppendText(nil)
+}
+
+// marshal returns the payload of m, sealed by seal.
+func (b *Bridge) marshal(m metrics.Metric) ([]byte, error) {
+	data, err := appendMetric(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.seal(m.Topic(), data)
+}
+
+// seal returns the payload data of topic, encrypted if the bridge has an
+// encrypter and then signed if it has a signer.
+func (b *Bridge) seal(topic string, data []byte) ([]byte, error) {
+	var err error
+
+	if b.encrypter != nil {
+		data, err = b.encrypter.Encrypt(topic, data)
+	}
+
+	if err == nil && b.signer != nil {
+		data, err = b.signer.Sign(topic, data, time.Now())
+	}
+
+	return data, err
+}
+
+// newSigner returns the signer of cfg.
+func newSigner(cfg *config.SigningConfig) (*sign.Signer, error) {
+	switch cfg.Method {
+	case "", config.SigningHMAC:
+		return sign.NewHMAC([]byte(cfg.Key)), nil
+	case config.SigningEd25519:
+		k, err := sign.ParsePrivateKey(cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		return sign.NewEd25519(k), nil
+	}
+
+	return nil, config.InvalidValue("signing.method", cfg.Method)
+}
+
+// runCommand runs cmd with payload, recovering any panic as an error.
+func runCommand(cmd metrics.Command, payload []byte) (err error) {
+	defer metrics.Recover(&err)
+
+	return cmd(payload)
+}
+
 // nilToken implements [mqtt.Token] with a nil channel.
 type nilToken struct{}
 
@@ -206,6 +604,7 @@ func (b *Bridge) loop(ctx context.Context) {
 		}
 
 		b.wg.Wait()
+		b.shutdownTracing()
 
 		close(b.done)
 	}()
@@ -216,18 +615,20 @@ func (b *Bridge) loop(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case m, ok := <-b.updates:
+		case u, ok := <-b.updates:
 			if !ok {
 				return
 			}
 
-			data, err := m.AppendText(nil)
-			if err != nil {
-				log.WarnError("Unable to marshal "+m.Type(), err)
-				break
+			if pt := b.publishTick(ctx, u); pt != nil {
+				t = pt
 			}
 
-			t = b.client.Publish(m.Topic(), 0, false, data)
+			if b.derived != nil {
+				if dt := b.publishDerived(u.m); dt != nil {
+					t = dt
+				}
+			}
 		case m, ok := <-b.rediscover:
 			if !ok {
 				return
@@ -260,7 +661,7 @@ func (b *Bridge) updateState(ctx context.Context, m metrics.Metric, err error) (
 	log.Debug("State changed", "topic", key, "from", !state, "to", state)
 
 	t := b.publishStates(false)
-	if err := waitToken(ctx, t); err != nil {
+	if err := b.waitToken(ctx, t); err != nil {
 		log.WarnError("Unable to publish states", err)
 	}
 
@@ -295,24 +696,86 @@ func handleUpdatePayload(m metrics.Metric, payload []byte) error {
 }
 
 // metricHandler returns a [mqtt.MessageHandler] for the given metric that handles the "/update" and "/stop"
-// topics of the metric.
-func (b *Bridge) metricHandler(ctx context.Context, i int, m metrics.Metric) mqtt.MessageHandler {
+// topics of the metric, as well as the command topics if the metric implements [metrics.Commander].
+func (b *Bridge) metricHandler(ctx context.Context, i int, m metrics.Metric, cmds map[string]metrics.Command) mqtt.MessageHandler {
 	return func(_ mqtt.Client, msg mqtt.Message) {
+		// A retained command would otherwise be run each time the bridge
+		// subscribes, such as cleaning a directory on every reconnect.
+		if msg.Retained() {
+			log.Warn("Ignoring retained message", "topic", msg.Topic())
+			return
+		}
+
+		if cmd, ok := cmds[strings.TrimPrefix(msg.Topic(), m.Topic()+"/")]; ok {
+			go func(msg mqtt.Message) {
+				if err := runCommand(cmd, msg.Payload()); err != nil {
+					log.WarnError("Command failed", err, "topic", msg.Topic())
+					b.reply(ctx, msg, StatusError, err)
+
+					return
+				}
+
+				if c, ok := m.(*metrics.CPU); ok {
+					b.selectionModeChanged(ctx, c)
+				}
+
+				if err := m.Update(); err == nil {
+					b.send(ctx, m, err)
+				}
+
+				b.reply(ctx, msg, StatusOK, nil)
+			}(msg)
+
+			return
+		}
+
 		switch {
 		case strings.HasSuffix(msg.Topic(), "/update"):
 			go func(msg mqtt.Message) {
-				handleUpdatePayload(m, msg.Payload())
+				err := handleUpdatePayload(m, msg.Payload())
+
+				if c, ok := m.(*metrics.CPU); ok {
+					b.selectionModeChanged(ctx, c)
+				}
 
 				if err := m.Update(); err == nil {
-					maybeSend(ctx, b.updates, m)
+					b.send(ctx, m, err)
 				}
+
+				b.reply(ctx, msg, StatusOK, err)
 			}(msg)
 		case strings.HasSuffix(msg.Topic(), "/stop"):
-			go m.Stop()
+			b.watchdog.forget(m)
+
+			go func(msg mqtt.Message) {
+				m.Stop()
+				b.reply(ctx, msg, StatusOK, nil)
+			}(msg)
 		}
 	}
 }
 
+// publishSelectionMode publishes the selection mode of c retained to the
+// "/selection_mode" subtopic of the metric.
+func (b *Bridge) publishSelectionMode(ctx context.Context, c *metrics.CPU) {
+	data, _ := c.AppendSelectionMode(nil)
+
+	t := b.client.Publish(c.Topic()+"/selection_mode", 0, true, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.Error("Could not publish selection mode", err)
+	}
+}
+
+// selectionModeChanged publishes the selection mode of c once it may have been
+// changed, and passes c to the callback of [WithOnSelectionMode].
+func (b *Bridge) selectionModeChanged(ctx context.Context, c *metrics.CPU) {
+	b.publishSelectionMode(ctx, c)
+
+	if b.onSelectionMode != nil {
+		b.onSelectionMode(c)
+	}
+}
+
 // startMetric initializes the given metric and starts its event loop.
 func (b *Bridge) startMetric(ctx context.Context, i int, m metrics.Metric, discover bool) {
 	if m.Topic() == "" {
@@ -320,6 +783,16 @@ func (b *Bridge) startMetric(ctx context.Context, i int, m metrics.Metric, disco
 		return
 	}
 
+	if b.lazy.idle(m) {
+		b.waitSubscriber(ctx, m)
+
+		if discover && b.rediscover != nil {
+			maybeSend(ctx, b.rediscover, m)
+		}
+
+		return
+	}
+
 	if err := m.Start(ctx); err != nil {
 		log.Error("Could not start "+m.Type(), err)
 		b.states.Store(m.Topic(), false)
@@ -329,18 +802,42 @@ func (b *Bridge) startMetric(ctx context.Context, i int, m metrics.Metric, disco
 
 	b.states.Store(m.Topic(), true)
 
-	t := b.client.SubscribeMultiple(map[string]byte{
+	filters := map[string]byte{
 		m.Topic() + "/update": 0,
 		m.Topic() + "/stop":   0,
-	}, b.metricHandler(ctx, i, m))
-	if err := waitToken(ctx, t); err != nil {
+	}
+
+	var cmds map[string]metrics.Command
+
+	if cm, ok := m.(metrics.Commander); ok {
+		cmds = cm.Commands()
+		for topic := range cmds {
+			filters[m.Topic()+"/"+topic] = 0
+		}
+	}
+
+	t := b.client.SubscribeMultiple(filters, b.countCommands(b.metricHandler(ctx, i, m, cmds)))
+	if err := b.waitToken(ctx, t); err != nil {
 		log.Error("Could not subscribe to "+m.Topic(), err)
 		m.Stop()
 
 		return
 	}
 
+	if im, ok := m.(metrics.Informer); ok {
+		b.publishInfo(ctx, m, im)
+	}
+
+	if c, ok := m.(*metrics.CPU); ok {
+		b.publishSelectionMode(ctx, c)
+	}
+
+	if b.schemas {
+		b.publishSchema(ctx, m)
+	}
+
 	b.wg.Add(1)
+	b.watchdog.watch(m)
 
 	go b.loopMetric(ctx, i, m)
 
@@ -349,17 +846,58 @@ func (b *Bridge) startMetric(ctx context.Context, i int, m metrics.Metric, disco
 	}
 }
 
-// start starts the bridge's metrics and the bridge's event loop.
+// publishInfo publishes the static information of m retained to the "/info"
+// subtopic of the metric.
+func (b *Bridge) publishInfo(ctx context.Context, m metrics.Metric, im metrics.Informer) {
+	data, err := im.AppendInfo(nil)
+	if err != nil {
+		log.Error("Could not encode info of "+m.Type(), err)
+		return
+	}
+
+	t := b.client.Publish(m.Topic()+"/info", 0, true, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.Error("Could not publish info of "+m.Type(), err)
+	}
+}
+
+// publishSchema publishes the JSON Schema of the payload of m retained to the
+// "/$schema" subtopic of the metric, if there is one.
+func (b *Bridge) publishSchema(ctx context.Context, m metrics.Metric) {
+	data, ok := payload.Schema(m.Type())
+	if !ok {
+		return
+	}
+
+	t := b.client.Publish(m.Topic()+"/$schema", 0, true, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.Error("Could not publish schema of "+m.Type(), err)
+	}
+}
+
+// start starts the bridge's metrics and the bridge's event loop. The loop is
+// started even if ctx is done while starting, since it disconnects the client
+// and waits for the metrics once ctx is done, so that the bridge may always
+// be stopped once ready.
 func (b *Bridge) start(ctx context.Context) {
 	defer func() {
-		select {
-		case <-ctx.Done():
-		default:
-			close(b.ready)
-		}
+		go b.loop(ctx)
+		close(b.ready)
 	}()
 
+	// Metrics added while starting are started here, and those added after are
+	// started by AddMetric.
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ctx = ctx
+	b.done = make(chan struct{})
+
 	for i, m := range b.metrics {
+		if m == nil {
+			continue
+		}
+
 		b.startMetric(ctx, i, m, false)
 
 		if ctxDone(ctx) {
@@ -368,48 +906,113 @@ func (b *Bridge) start(ctx context.Context) {
 	}
 
 	t := b.publishStates(false)
-	if err := waitToken(ctx, t); err != nil {
+	if err := b.waitToken(ctx, t); err != nil {
 		b.err = err
 	}
 
-	t = b.client.Subscribe(b.baseTopic+"/bridge/stop", 0, func(_ mqtt.Client, _ mqtt.Message) {
-		go b.Stop()
-	})
-	if err := waitToken(ctx, t); err != nil && b.err == nil {
+	if b.reportUnsupported {
+		b.publishUnsupported(ctx)
+	}
+
+	t = b.client.Subscribe(b.baseTopic+"/bridge/stop", 0, b.countCommands(func(_ mqtt.Client, msg mqtt.Message) {
+		// The result is published first, since the client is disconnected once stopped
+		go func() {
+			b.reply(ctx, msg, StatusOK, nil)
+			b.Stop()
+		}()
+	}))
+	if err := b.waitToken(ctx, t); err != nil && b.err == nil {
 		b.err = err
 	}
 
-	t = b.client.Subscribe(b.baseTopic+"/bridge/update", 0, func(_ mqtt.Client, _ mqtt.Message) {
-		go b.update(ctx)
-	})
-	if err := waitToken(ctx, t); err != nil && b.err == nil {
+	t = b.client.Subscribe(b.baseTopic+"/bridge/update", 0, b.countCommands(func(_ mqtt.Client, msg mqtt.Message) {
+		go func() {
+			b.update(ctx)
+			b.reply(ctx, msg, StatusOK, nil)
+		}()
+	}))
+	if err := b.waitToken(ctx, t); err != nil && b.err == nil {
 		b.err = err
 	}
 
+	if b.power != nil {
+		t = b.client.Subscribe(b.baseTopic+"/bridge/power/set", 0, b.countCommands(func(_ mqtt.Client, msg mqtt.Message) {
+			go b.handlePower(ctx, msg)
+		}))
+		if err := b.waitToken(ctx, t); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+
+	if len(b.wol) > 0 {
+		t = b.client.Subscribe(b.baseTopic+"/bridge/wol", 0, b.countCommands(func(_ mqtt.Client, msg mqtt.Message) {
+			go b.handleWOL(ctx, msg)
+		}))
+		if err := b.waitToken(ctx, t); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+
+	for _, c := range b.commands {
+		t = b.client.Subscribe(c.topic, 0, b.countCommands(func(_ mqtt.Client, msg mqtt.Message) {
+			go b.handleCommand(ctx, c, msg)
+		}))
+		if err := b.waitToken(ctx, t); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+
 	if b.discovery != nil {
 		if err := b.discover(ctx); err != nil && b.err == nil {
 			b.err = err
 		}
 	}
 
-	b.done = make(chan struct{})
+	b.running = true
+
+	if b.statsInterval > 0 {
+		go b.loopStats(ctx, b.statsInterval)
+	}
 
-	go b.loop(ctx)
+	if b.watchdog != nil {
+		b.wg.Add(1)
+
+		go b.loopWatchdog(ctx)
+	}
+
+	if b.summary != nil && b.cfg.Interval > 0 {
+		b.wg.Add(1)
+
+		go b.loopSummary(ctx, b.cfg.Interval)
+	}
 }
 
 func (b *Bridge) Start(ctx context.Context) error {
 	if len(b.metrics) == 0 {
-		return errors.New("no metrics")
+		return ErrNoMetrics
 	}
 
+	if b.initErr != nil {
+		return b.initErr
+	}
+
+	// The connect timeout is up to the client, which may keep retrying
 	t := b.client.Connect()
-	if err := waitToken(ctx, t); err != nil {
+	if err := mqttutil.Wait(ctx, t, 0); err != nil {
+		// The client may still connect if ctx is done first
+		b.client.Disconnect(250)
+		return err
+	}
+
+	t = b.publishBirth()
+	if err := b.waitToken(ctx, t); err != nil {
+		b.client.Disconnect(250)
 		return err
 	}
 
 	b.once.Do(func() {
 		b.ready = make(chan struct{})
-		b.updates = make(chan metrics.Metric)
+		b.updates = make(chan tick)
 
 		if b.discovery != nil {
 			b.rediscover = make(chan metrics.Metric)
@@ -438,6 +1041,26 @@ func (b *Bridge) Stop() {
 	}
 }
 
+func (b *Bridge) handlePower(ctx context.Context, msg mqtt.Message) {
+	// A retained action would otherwise be run each time the bridge
+	// subscribes, such as rebooting on every boot.
+	if msg.Retained() {
+		log.Warn("Ignoring retained power action", "topic", msg.Topic())
+		return
+	}
+
+	ran, err := b.power.handle(ctx, msg.Payload())
+	if err != nil {
+		log.Error("Unable to run power action", err)
+	}
+
+	if ran {
+		b.reply(ctx, msg, StatusOK, err)
+	} else {
+		b.reply(ctx, msg, StatusPending, err)
+	}
+}
+
 func (b *Bridge) Ready() <-chan struct{} {
 	return b.ready
 }
@@ -450,6 +1073,50 @@ func (b *Bridge) Error() error {
 	return b.err
 }
 
+// Metrics returns the metrics of the bridge that have not been stopped. Once
+// the bridge is stopped, these are the metrics that were running when it
+// stopped. The returned slice is a copy and may be modified.
+func (b *Bridge) Metrics() []metrics.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m := make([]metrics.Metric, 0, len(b.metrics))
+
+	for _, mm := range b.metrics {
+		if mm != nil {
+			m = append(m, mm)
+		}
+	}
+
+	return m
+}
+
+// State returns the state of the metric with the given topic, which is true if
+// its last update succeeded, as published to the LWT topic. If there is no
+// metric with the topic, ok is false.
+func (b *Bridge) State(topic string) (state, ok bool) {
+	v, ok := b.states.Load(topic)
+	if !ok {
+		return false, false
+	}
+
+	return v.(bool), true
+}
+
+// Publish publishes the current value of m to its topic, whether or not it has
+// changed since it was last published. The metric does not need to belong to
+// the bridge, but the bridge must be connected.
+func (b *Bridge) Publish(ctx context.Context, m metrics.Metric) error {
+	data, err := b.marshal(m)
+	if err != nil {
+		return err
+	}
+
+	t := b.client.Publish(m.Topic(), 0, false, data)
+
+	return b.waitToken(ctx, t)
+}
+
 func (b *Bridge) update(ctx context.Context) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -479,56 +1146,98 @@ func (b *Bridge) update(ctx context.Context) {
 				return
 			}
 
-			maybeSend(ctx, b.updates, m)
+			b.send(ctx, m, err)
 		}(m)
 	}
 
 	wg.Wait()
 }
 
-// publishStates publishes the bridge's states map to the LWT topic. If lwt is true, publishState
-// publishes the client's LWT payload instead.
-func (b *Bridge) publishStates(lwt bool) mqtt.Token {
-	var (
-		payload []byte
-		opts    = b.client.OptionsReader()
-	)
+// birth is the birth message of the bridge, which is published to the LWT
+// topic along with the states of the metrics.
+type birth struct {
+	topic    string
+	payload  string
+	qos      byte
+	retained bool
+}
+
+// publishBirth publishes the bridge's birth payload to the LWT topic.
+func (b *Bridge) publishBirth() mqtt.Token {
+	if b.birth == nil {
+		return &mqtt.DummyToken{}
+	}
 
+	log.Debug("Publishing birth", "topic", b.birth.topic, "payload", b.birth.payload)
+
+	return b.client.Publish(b.birth.topic, b.birth.qos, b.birth.retained, b.birth.payload)
+}
+
+// publishStates publishes the bridge's states map to the LWT topic, with the QoS and retained flag
+// of the birth if it is enabled. If lwt is true, publishState publishes the client's LWT payload
+// instead.
+func (b *Bridge) publishStates(lwt bool) mqtt.Token {
 	if lwt {
-		payload = opts.WillPayload()
-	} else {
-		payload = []byte{'{'}
-		first := true
+		opts := b.client.OptionsReader()
+		if opts.WillTopic() == "" {
+			return &mqtt.DummyToken{}
+		}
 
-		b.states.Range(func(k, v any) bool {
-			if !first {
-				payload = append(payload, ',')
-			}
+		return b.client.Publish(opts.WillTopic(), opts.WillQos(), opts.WillRetained(), opts.WillPayload())
+	}
 
-			payload = strconv.AppendQuote(payload, k.(string))
-			payload = append(payload, ':')
-			payload = strconv.AppendBool(payload, v.(bool))
+	payload := []byte{'{'}
+	first := true
 
-			first = false
+	b.states.Range(func(k, v any) bool {
+		if !first {
+			payload = append(payload, ',')
+		}
+
+		payload = strconv.AppendQuote(payload, k.(string))
+		payload = append(payload, ':')
+		payload = strconv.AppendBool(payload, v.(bool))
 
-			return true
-		})
+		first = false
 
-		payload = append(payload, '}')
+		return true
+	})
+
+	payload = append(payload, '}')
+
+	if b.birth != nil {
+		return b.client.Publish(b.birth.topic, b.birth.qos, b.birth.retained, payload)
 	}
 
+	opts := b.client.OptionsReader()
+
 	return b.client.Publish(opts.WillTopic(), opts.WillQos(), opts.WillRetained(), payload)
 }
 
-func (b *Bridge) publishRediscovery(ctx context.Context, m metrics.Metric) error {
+func (b *Bridge) publishRediscovery(ctx context.Context, m metrics.Metric) (err error) {
+	defer metrics.Recover(&err)
+
 	dd, ok := m.(discovery.Discoverer)
 	if !ok || b.discovery == nil {
 		return nil
 	}
 
-	var cmps []string
+	b.discoveryMu.Lock()
+	defer b.discoveryMu.Unlock()
+
+	var (
+		cmps  []string
+		sizes map[string]int
+	)
 
 	if b.discovery.Nodes != nil {
+		// Some metrics, e.g. CPU, may add components to other nodes, which also
+		// need to be published if they change.
+		sizes = make(map[string]int, len(b.discovery.Nodes))
+		for name, node := range b.discovery.Nodes {
+			sizes[name] = len(node)
+		}
+
 		node, ok := b.discovery.Nodes[m.Type()]
 		if ok && node != nil {
 			b.discovery.Nodes[m.Type()] = nil
@@ -559,7 +1268,32 @@ func (b *Bridge) publishRediscovery(ctx context.Context, m metrics.Metric) error
 		}
 	}
 
-	return b.discovery.Publish(ctx, b.client, false, m.Type())
+	nodes := []string{m.Type()}
+
+	for name, node := range b.discovery.Nodes {
+		if size, ok := sizes[name]; name != m.Type() && (!ok || len(node) > size) {
+			nodes = append(nodes, name)
+		}
+	}
+
+	if err := b.discovery.Publish(ctx, b.client, false, nodes...); err != nil {
+		return err
+	}
+
+	b.saveDiscovery()
+
+	return nil
+}
+
+// saveDiscovery saves the discovery payload to the store of the bridge, if any.
+func (b *Bridge) saveDiscovery() {
+	if b.store == nil {
+		return
+	}
+
+	if err := b.store.Save(b.discovery); err != nil {
+		log.WarnError("Unable to save discovery", err)
+	}
 }
 
 func (b *Bridge) discover(ctx context.Context) error {
@@ -569,6 +1303,8 @@ func (b *Bridge) discover(ctx context.Context) error {
 		return err
 	}
 
+	b.saveDiscovery()
+
 	return b.discovery.SubscribeFunc(ctx, b.client, func(ctx context.Context) {
 		select {
 		case <-ctx.Done():
@@ -591,7 +1327,7 @@ func (b *Bridge) Discover(d *discovery.Discovery) {
 		cmps = node
 	}
 
-	id := d.Origin.Name + "_update"
+	id := d.ID("update")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -601,11 +1337,34 @@ func (b *Bridge) Discover(d *discovery.Discovery) {
 		discovery.Name:                 "Update",
 		discovery.DeviceClass:          "restart",
 		discovery.AvailabilityTopic:    d.AvailabilityTopic,
-		discovery.AvailabilityTemplate: "{{ iif(value == 'offline', value, 'online') }}",
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
 		discovery.CommandTopic:         b.baseTopic + "/bridge/update",
 		discovery.UniqueID:             id,
 	}
 
+	if b.power != nil {
+		cmps = b.power.discover(b.baseTopic+"/bridge/power/set", d, cmps)
+	}
+
+	cmps = b.discoverWOL(d, cmps)
+	cmps = b.discoverCommands(d, cmps)
+
+	if b.summary != nil {
+		cmps = b.discoverSummary(d, cmps)
+	}
+
+	if b.derived != nil {
+		cmps = b.discoverDerived(d, cmps)
+	}
+
+	if b.events != nil {
+		cmps = b.discoverEvents(d, cmps)
+	}
+
+	if b.reportUnsupported {
+		cmps = b.discoverUnsupported(d, cmps)
+	}
+
 	if cmps != nil {
 		d.Nodes["bridge"] = cmps
 	}
@@ -0,0 +1,1089 @@
+//go:build integration
+
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/encrypt"
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sign"
+)
+
+const testTimeout = 5 * time.Second
+
+// testRoot returns a root filesystem made of the procfs and sysfs fixtures, along
+// with an /etc directory so that discovery is able to identify the device.
+func testRoot(t *testing.T) string {
+	t.Helper()
+
+	fixtures, err := filepath.Abs("../testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+
+	for _, dir := range []string{"proc", "sys"} {
+		if err := os.Symlink(filepath.Join(fixtures, dir), filepath.Join(root, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"etc/machine-id": "0123456789abcdef0123456789abcdef\n",
+		"etc/hostname":   "integration\n",
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+// testBroker starts an embedded broker with hooks, or allowing everything if
+// there are none, and returns its address.
+func testBroker(t *testing.T, hooks ...mochi.Hook) string {
+	t.Helper()
+
+	srv := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.DiscardHandler),
+	})
+
+	if len(hooks) == 0 {
+		hooks = append(hooks, new(auth.AllowHook))
+	}
+
+	for _, h := range hooks {
+		if err := srv.AddHook(h, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+
+	if err := srv.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { srv.Close() })
+
+	return "tcp://" + tcp.Address()
+}
+
+// aclHook is a broker hook allowing every client, but denying publishing or
+// subscribing to the topics matching its filters, as with the ACL of a broker.
+type aclHook struct {
+	mochi.HookBase
+	denyPublish   []string
+	denySubscribe []string
+}
+
+func (h *aclHook) ID() string {
+	return "acl"
+}
+
+func (h *aclHook) Provides(b byte) bool {
+	return b == mochi.OnConnectAuthenticate || b == mochi.OnACLCheck || b == mochi.OnPublish
+}
+
+func (h *aclHook) OnConnectAuthenticate(*mochi.Client, packets.Packet) bool {
+	return true
+}
+
+// OnACLCheck denies subscribing to the topics of denySubscribe. Publishing is
+// denied by OnPublish instead, since the broker disconnects MQTT 3 clients
+// that publish with QoS 1 to a topic denied here.
+func (h *aclHook) OnACLCheck(_ *mochi.Client, topic string, write bool) bool {
+	return write || !matchAny(h.denySubscribe, topic)
+}
+
+// OnPublish acknowledges the messages published to the topics of denyPublish
+// but never delivers them, like most brokers do for MQTT 3 clients.
+func (h *aclHook) OnPublish(_ *mochi.Client, pk packets.Packet) (packets.Packet, error) {
+	if matchAny(h.denyPublish, pk.TopicName) {
+		return pk, packets.CodeSuccessIgnore
+	}
+
+	return pk, nil
+}
+
+// matchAny indicates whether topic matches any of the topic filters.
+func matchAny(filters []string, topic string) bool {
+	ts := strings.Split(topic, "/")
+
+	for _, filter := range filters {
+		fs := strings.Split(filter, "/")
+
+		for i, f := range fs {
+			if f == "#" {
+				return true
+			} else if i >= len(ts) || f != "+" && f != ts[i] {
+				break
+			} else if i == len(fs)-1 && len(fs) == len(ts) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// testSubscriber connects a client to the broker and returns a channel that
+// receives every message published to topics matching filter.
+func testSubscriber(t *testing.T, broker, filter string) <-chan mqtt.Message {
+	t.Helper()
+
+	ch := make(chan mqtt.Message, 64)
+
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID("mqttop-test-subscriber")
+	c := mqtt.NewClient(opts)
+
+	if tok := c.Connect(); !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+		t.Fatal("Unable to connect subscriber:", tok.Error())
+	}
+
+	t.Cleanup(func() { c.Disconnect(0) })
+
+	tok := c.Subscribe(filter, 0, func(_ mqtt.Client, msg mqtt.Message) {
+		ch <- msg
+	})
+	if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+		t.Fatal("Unable to subscribe:", tok.Error())
+	}
+
+	return ch
+}
+
+// waitMessage waits for a message on ch published to topic, discarding any others.
+func waitMessage(t *testing.T, ch <-chan mqtt.Message, topic string) mqtt.Message {
+	t.Helper()
+
+	timeout := time.After(testTimeout)
+
+	for {
+		select {
+		case msg := <-ch:
+			if msg.Topic() == topic {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for message on %q", topic)
+			return nil
+		}
+	}
+}
+
+// drain discards any messages on ch until none have been received for d.
+func drain(ch <-chan mqtt.Message, d time.Duration) {
+	for {
+		select {
+		case <-ch:
+		case <-time.After(d):
+			return
+		}
+	}
+}
+
+// testBridge starts a bridge of the memory metric connected to a new broker,
+// calling each of opts with its config before it's started.
+func testBridge(t *testing.T, opts ...func(*config.Config)) (*Bridge, *config.Config, <-chan mqtt.Message) {
+	t.Helper()
+
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "#")
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.WaitTopic = "homeassistant/status"
+	cfg.Memory.Interval = time.Hour
+	cfg.Stats.Enabled = true
+	cfg.Stats.PublishInterval = 100 * time.Millisecond
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := discovery.New(&cfg.Discovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem.Discover(d)
+
+	b := New(cfg, WithMetrics(mem), WithDiscovery(d, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-b.Ready():
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for bridge")
+	}
+
+	if err := b.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b, cfg, msgs
+}
+
+func TestBridge_Integration(t *testing.T) {
+	b, cfg, msgs := testBridge(t)
+
+	mem := b.metrics[0]
+	status := cfg.MQTT.BirthWillTopic
+
+	t.Run("Status", func(t *testing.T) {
+		msg := waitMessage(t, msgs, status)
+		if p := string(msg.Payload()); p != cfg.MQTT.BirthPayload {
+			t.Errorf("Birth: want %q, got %q", cfg.MQTT.BirthPayload, p)
+		}
+
+		msg = waitMessage(t, msgs, status)
+
+		var states map[string]bool
+		if err := json.Unmarshal(msg.Payload(), &states); err != nil {
+			t.Fatal(err)
+		}
+
+		if !states[mem.Topic()] {
+			t.Errorf("State of %s: want true, got %v", mem.Topic(), states)
+		}
+	})
+
+	t.Run("Discovery", func(t *testing.T) {
+		d := b.discovery
+		topic := d.Topic(cfg.Discovery.Prefix, "device", d.NodeID, d.ObjectID)
+		msg := waitMessage(t, msgs, topic)
+
+		var got discovery.Discovery
+		if err := json.Unmarshal(msg.Payload(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Device == nil || got.Device.Name != "Integration" {
+			t.Errorf("Device: want name %q, got %+v", "Integration", got.Device)
+		}
+
+		var found bool
+
+		for _, cmp := range got.Components {
+			if s, ok := cmp[discovery.StateTopic].(string); ok && s == mem.Topic() {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("Components: no component with state topic %q", mem.Topic())
+		}
+	})
+
+	t.Run("Accessors", func(t *testing.T) {
+		if got := b.Metrics(); len(got) != 1 || got[0] != mem {
+			t.Errorf("Metrics: want [%s], got %v", mem.Type(), got)
+		}
+
+		if state, ok := b.State(mem.Topic()); !state || !ok {
+			t.Errorf("State of %s: want true, true, got %v, %v", mem.Topic(), state, ok)
+		}
+
+		if _, ok := b.State("not/a/metric"); ok {
+			t.Error("State of not/a/metric: want not ok")
+		}
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+
+		if err := b.Publish(ctx, mem); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := waitMessage(t, msgs, mem.Topic())
+		if !strings.Contains(string(msg.Payload()), `"total"`) {
+			t.Errorf("Payload: want memory payload, got %s", msg.Payload())
+		}
+	})
+
+	t.Run("AddRemoveMetric", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+
+		if err := b.AddMetric(ctx, mem); !errors.Is(err, ErrDuplicateTopic) {
+			t.Errorf("AddMetric: want %v, got %v", ErrDuplicateTopic, err)
+		}
+
+		cfg.Battery.Interval = time.Hour
+
+		bat, err := metrics.NewBattery(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := b.AddMetric(ctx, bat); err != nil {
+			t.Fatal(err)
+		}
+
+		if state, ok := b.State(bat.Topic()); !state || !ok {
+			t.Errorf("State of %s: want true, true, got %v, %v", bat.Topic(), state, ok)
+		}
+
+		d := b.discovery
+		topic := d.Topic(cfg.Discovery.Prefix, "device", d.NodeID, d.ObjectID)
+		id := d.ID("battery_state")
+
+		var got discovery.Discovery
+
+		msg := waitMessage(t, msgs, topic)
+		if err := json.Unmarshal(msg.Payload(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if cmp := got.Components[id]; len(cmp) <= 1 {
+			t.Errorf("Component %s: want discovered, got %v", id, cmp)
+		}
+
+		if err := b.RemoveMetric(ctx, bat); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := b.RemoveMetric(ctx, bat); !errors.Is(err, ErrUnknownMetric) {
+			t.Errorf("RemoveMetric: want %v, got %v", ErrUnknownMetric, err)
+		}
+
+		if _, ok := b.State(bat.Topic()); ok {
+			t.Errorf("State of %s: want not ok", bat.Topic())
+		}
+
+		if got := b.Metrics(); len(got) != 1 {
+			t.Errorf("Metrics: want [%s], got %v", mem.Type(), got)
+		}
+
+		got = discovery.Discovery{}
+
+		msg = waitMessage(t, msgs, topic)
+		if err := json.Unmarshal(msg.Payload(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if cmp, ok := got.Components[id]; !ok || len(cmp) != 1 {
+			t.Errorf("Component %s: want only platform, got %v", id, cmp)
+		}
+
+		if _, ok := d.Components[id]; ok {
+			t.Errorf("Component %s: want deleted", id)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		tok := b.client.Publish(mem.Topic()+"/update", 0, false, `{"interval": "1m"}`)
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		msg := waitMessage(t, msgs, mem.Topic())
+
+		var p payload.Memory
+		if err := json.Unmarshal(msg.Payload(), &p); err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Total == 0 {
+			t.Errorf("Total: want non-zero, got %s", msg.Payload())
+		}
+	})
+
+	t.Run("BridgeUpdate", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		tok := b.client.Publish(cfg.BaseTopic+"/bridge/update", 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		waitMessage(t, msgs, mem.Topic())
+	})
+
+	t.Run("CommandResult", func(t *testing.T) {
+		b, _, msgs := testBridge(t, func(cfg *config.Config) {
+			cfg.MQTT.CommandResults = true
+		})
+		mem := b.metrics[0]
+
+		drain(msgs, 100*time.Millisecond)
+
+		for payload, want := range map[string]CommandResult{
+			`{"interval": "1m"}`: {Status: StatusOK},
+			`not json`:           {Status: StatusError, Error: "invalid character 'o' in literal null (expecting 'u')"},
+		} {
+			tok := b.client.Publish(mem.Topic()+"/update", 0, false, payload)
+			if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+				t.Fatal(tok.Error())
+			}
+
+			msg := waitMessage(t, msgs, mem.Topic()+"/update/result")
+
+			var got CommandResult
+			if err := json.Unmarshal(msg.Payload(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != want {
+				t.Errorf("%s: want %+v, got %+v", payload, want, got)
+			}
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+
+		b.publishSchema(ctx, mem)
+
+		msg := waitMessage(t, msgs, mem.Topic()+"/$schema")
+		if want, _ := payload.Schema("memory"); !bytes.Equal(msg.Payload(), want) {
+			t.Errorf("Schema: want memory schema, got %s", msg.Payload())
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		msg := waitMessage(t, msgs, cfg.BaseTopic+"/bridge/stats")
+
+		var got Stats
+		if err := json.Unmarshal(msg.Payload(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Publishes == 0 || got.Bytes == 0 {
+			t.Errorf("Publishes: want non-zero, got %+v", got)
+		}
+
+		// The metric and bridge updates
+		if got.Commands < 2 {
+			t.Errorf("Commands: want at least 2, got %+v", got)
+		}
+
+		if got.Failures != 0 || got.Reconnects != 0 {
+			t.Errorf("Failures: want none, got %+v", got)
+		}
+	})
+
+	t.Run("Rediscover", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		tok := b.client.Publish(cfg.Discovery.WaitTopic, 0, false, "online")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		msg := waitMessage(t, msgs, mem.Topic())
+		if !strings.Contains(string(msg.Payload()), `"total"`) {
+			t.Errorf("Payload: want memory payload, got %s", msg.Payload())
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		tok := b.client.Publish(mem.Topic()+"/stop", 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		select {
+		case _, ok := <-mem.Updated():
+			if ok {
+				t.Error("Updated: want closed channel after stop")
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("Timed out waiting for metric to stop")
+		}
+	})
+
+	t.Run("BridgeStop", func(t *testing.T) {
+		drain(msgs, 100*time.Millisecond)
+
+		tok := b.client.Publish(cfg.BaseTopic+"/bridge/stop", 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		msg := waitMessage(t, msgs, status)
+		if got := string(msg.Payload()); got != "offline" {
+			t.Errorf("Status: want %q, got %q", "offline", got)
+		}
+
+		select {
+		case <-b.Done():
+		case <-time.After(testTimeout):
+			t.Fatal("Timed out waiting for bridge to stop")
+		}
+	})
+}
+
+func TestBridge_Check(t *testing.T) {
+	broker := testBroker(t, &aclHook{
+		denyPublish:   []string{"homeassistant/#"},
+		denySubscribe: []string{"mqttop/bridge/stop", "mqttop/bridge/status/#"},
+	})
+
+	msgs := testSubscriber(t, broker, "mqttop/metric/memory")
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := discovery.New(&cfg.Discovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(cfg, WithMetrics(mem), WithDiscovery(d, false))
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	checks, err := b.Check(ctx, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]TopicCheck, len(checks))
+	for _, c := range checks {
+		got[c.Topic] = c
+	}
+
+	tests := []TopicCheck{
+		{Topic: mem.Topic(), Want: PermPublish},
+		{Topic: mem.Topic() + "/update", Want: PermSubscribe},
+		{Topic: cfg.MQTT.BirthWillTopic, Want: PermPublish, Unknown: PermPublish},
+		{Topic: "mqttop/bridge/stop", Want: PermSubscribe, Denied: PermSubscribe},
+		{Topic: d.Topic(cfg.Discovery.Prefix, "device", d.NodeID, d.ObjectID), Want: PermPublish, Denied: PermPublish},
+	}
+
+	for _, want := range tests {
+		if c, ok := got[want.Topic]; !ok {
+			t.Errorf("%s: not checked", want.Topic)
+		} else if c != want {
+			t.Errorf("%s: want %+v, got %+v", want.Topic, want, c)
+		}
+	}
+
+	select {
+	case msg := <-msgs:
+		t.Errorf("Published to %s: %q", msg.Topic(), msg.Payload())
+	default:
+	}
+}
+
+func TestBridge_Watchdog(t *testing.T) {
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "mqttop/bridge/watchdog")
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+	cfg.Memory.Interval = 50 * time.Millisecond
+	cfg.Watchdog.Enabled = true
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(cfg, WithMetrics(mem))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-b.Ready():
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for bridge")
+	}
+
+	if _, ok := b.Topics()[b.watchdogTopic()]; !ok {
+		t.Errorf("Topics: want %s", b.watchdogTopic())
+	}
+
+	t.Run("Stopped", func(t *testing.T) {
+		mem.Stop()
+
+		msg := waitMessage(t, msgs, b.watchdogTopic())
+
+		var e WatchdogEvent
+		if err := json.Unmarshal(msg.Payload(), &e); err != nil {
+			t.Fatal(err)
+		}
+
+		want := WatchdogEvent{Metric: "memory", Topic: mem.Topic(), Reason: "stopped", Restarted: true}
+		if e != want {
+			t.Errorf("Event: want %+v, got %+v", want, e)
+		}
+
+		m := b.Metrics()
+		if len(m) != 1 || m[0] == mem {
+			t.Fatalf("Metrics: want restarted memory, got %v", m)
+		}
+
+		select {
+		case _, ok := <-m[0].Updated():
+			if !ok {
+				t.Error("Updated: want restarted metric to update")
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("Timed out waiting for restarted metric")
+		}
+	})
+
+	t.Run("StopCommand", func(t *testing.T) {
+		m := b.Metrics()[0]
+
+		tok := b.client.Publish(m.Topic()+"/stop", 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		select {
+		case msg := <-msgs:
+			t.Errorf("Published to %s: %q", msg.Topic(), msg.Payload())
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
+
+func TestBridge_Lazy(t *testing.T) {
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "mqttop/metric/#")
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+	cfg.Memory.Interval = 50 * time.Millisecond
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(cfg, WithMetrics(mem), WithLazy(300*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-b.Ready():
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for bridge")
+	}
+
+	// noMessage fails if anything but a subscribe request is published to
+	// the metric for d.
+	noMessage := func(t *testing.T, d time.Duration) {
+		t.Helper()
+
+		timeout := time.After(d)
+
+		for {
+			select {
+			case msg := <-msgs:
+				if !strings.HasSuffix(msg.Topic(), subscribeTopic) {
+					t.Fatalf("Published to %s: %q", msg.Topic(), msg.Payload())
+				}
+			case <-timeout:
+				return
+			}
+		}
+	}
+
+	t.Run("Idle", func(t *testing.T) {
+		noMessage(t, 200*time.Millisecond)
+
+		if state, _ := b.State(mem.Topic()); !state {
+			t.Error("State: want idle metric running")
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		tok := b.client.Publish(mem.Topic()+subscribeTopic, 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		// The metric is published right away, then every interval
+		waitMessage(t, msgs, mem.Topic())
+		waitMessage(t, msgs, mem.Topic())
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		deadline := time.Now().Add(testTimeout)
+
+		for b.Metrics()[0] == mem {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for metric to idle")
+			}
+
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		drain(msgs, 100*time.Millisecond)
+
+		noMessage(t, 200*time.Millisecond)
+
+		// The stopped metric no longer handles its commands
+		tok := b.client.Publish(mem.Topic()+"/update", 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		timeout := time.After(200 * time.Millisecond)
+
+		for {
+			select {
+			case msg := <-msgs:
+				if msg.Topic() == mem.Topic() {
+					t.Fatalf("Published to %s after update: %q", msg.Topic(), msg.Payload())
+				}
+			case <-timeout:
+				return
+			}
+		}
+	})
+
+	t.Run("Resubscribe", func(t *testing.T) {
+		tok := b.client.Publish(mem.Topic()+subscribeTopic, 0, false, "")
+		if !tok.WaitTimeout(testTimeout) || tok.Error() != nil {
+			t.Fatal(tok.Error())
+		}
+
+		waitMessage(t, msgs, mem.Topic())
+	})
+}
+
+func TestBridge_Encryption(t *testing.T) {
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "mqttop/metric/#")
+
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+	cfg.Encryption.Recipients = []string{"age1invalid"}
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, WithMetrics(mem)).Start(context.Background()); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Invalid recipient: want %v, got %v", ErrInvalidConfig, err)
+	}
+
+	cfg.Encryption.Recipients = []string{encrypt.FormatPublicKey(key.Recipient())}
+	b := New(cfg, WithMetrics(mem))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitMessage(t, msgs, mem.Topic())
+	if !encrypt.IsEncrypted(msg.Payload()) {
+		t.Fatalf("Payload: want encrypted, got %s", msg.Payload())
+	}
+
+	data, err := encrypt.Decrypt(key, mem.Topic(), msg.Payload())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Memory
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Errorf("Decrypted: %v: %s", err, data)
+	}
+}
+
+func TestBridge_Signing(t *testing.T) {
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "mqttop/metric/#")
+
+	priv, err := sign.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+	cfg.Signing = config.SigningConfig{Method: "rsa", Key: "secret"}
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, WithMetrics(mem)).Start(context.Background()); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Invalid method: want %v, got %v", ErrInvalidConfig, err)
+	}
+
+	// Payloads are encrypted, then signed
+	cfg.Signing = config.SigningConfig{Method: config.SigningEd25519, Key: sign.FormatPrivateKey(priv)}
+	cfg.Encryption.Recipients = []string{encrypt.FormatPublicKey(key.Recipient())}
+	b := New(cfg, WithMetrics(mem))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitMessage(t, msgs, mem.Topic())
+
+	v := sign.NewEd25519Verifier(priv.Public().(ed25519.PublicKey))
+	v.MaxAge = time.Minute
+
+	data, _, err := v.Verify(mem.Topic(), msg.Payload())
+	if err != nil {
+		t.Fatalf("Verify: %v: %s", err, msg.Payload())
+	}
+
+	if data, err = encrypt.Decrypt(key, mem.Topic(), data); err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Memory
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Errorf("Decrypted: %v: %s", err, data)
+	}
+}
+
+func TestRun(t *testing.T) {
+	broker := testBroker(t)
+
+	cfg := config.Default()
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+
+	msgs := testSubscriber(t, broker, cfg.MQTT.BirthWillTopic)
+
+	t.Run("NoMetrics", func(t *testing.T) {
+		cfg.SetRootFS(t.TempDir())
+
+		var runErr *RunError
+
+		err := Run(context.Background(), cfg)
+		if !errors.Is(err, ErrNoMetrics) || !errors.As(err, &runErr) || runErr.Op != "metrics" {
+			t.Fatalf("want metrics error wrapping %v, got %v", ErrNoMetrics, err)
+		}
+	})
+
+	cfg.SetRootFS(testRoot(t))
+	cfg.Memory.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Run(ctx, cfg)
+	}()
+
+	// The birth is followed by the states once every metric is started
+	waitMessage(t, msgs, cfg.MQTT.BirthWillTopic)
+	waitMessage(t, msgs, cfg.MQTT.BirthWillTopic)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("want nil, got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for Run to return")
+	}
+}
+
+func TestBridge_Tracing(t *testing.T) {
+	broker := testBroker(t)
+
+	msgs := testSubscriber(t, broker, "mqttop/metric/#")
+
+	cfg := config.Default()
+	cfg.SetRootFS(testRoot(t))
+	cfg.MQTT.Broker = broker
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+	cfg.Tracing.Endpoint = "localhost:4318"
+
+	mem, err := metrics.NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, WithMetrics(mem)).Start(context.Background()); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Invalid endpoint: want %v, got %v", ErrInvalidConfig, err)
+	}
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	b := New(cfg, WithMetrics(mem), WithTracerProvider(tp))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		b.Stop()
+	})
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	waitMessage(t, msgs, mem.Topic())
+
+	// The publish span ends once the broker acknowledges it
+	var spans tracetest.SpanStubs
+
+	for deadline := time.Now().Add(testTimeout); ; time.Sleep(10 * time.Millisecond) {
+		spans = exp.GetSpans()
+		if slices.ContainsFunc(spans, func(s tracetest.SpanStub) bool { return s.Name == mem.Type() }) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the trace of %s, got %d spans", mem.Type(), len(spans))
+		}
+	}
+
+	i := slices.IndexFunc(spans, func(s tracetest.SpanStub) bool { return s.Name == mem.Type() })
+	root := spans[i].SpanContext
+
+	for _, name := range []string{"update", "marshal", "publish"} {
+		i := slices.IndexFunc(spans, func(s tracetest.SpanStub) bool {
+			return s.Name == name && s.Parent.SpanID() == root.SpanID()
+		})
+		if i < 0 {
+			t.Errorf("Want %s span in the trace of %s", name, mem.Type())
+			continue
+		}
+
+		if spans[i].SpanContext.TraceID() != root.TraceID() {
+			t.Errorf("%s: want trace %s, got %s", name, root.TraceID(), spans[i].SpanContext.TraceID())
+		}
+	}
+}
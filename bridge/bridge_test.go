@@ -0,0 +1,226 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/mock"
+	"github.com/lone-faerie/mqttop/transport"
+)
+
+// commandMetric is a payloadMetric with a topic that never changes.
+type commandMetric struct {
+	payloadMetric
+	topic string
+}
+
+func (m *commandMetric) Topic() string {
+	return m.topic
+}
+
+func (m *commandMetric) Update() error {
+	return metrics.ErrNoChange
+}
+
+func TestMetricHandler_Retained(t *testing.T) {
+	m := &commandMetric{payloadMetric: payloadMetric{typ: "dir"}, topic: "mqttop/metric/dir/tmp"}
+
+	ran := make(chan struct{}, 2)
+	cmds := map[string]metrics.Command{
+		"clean": func([]byte) error {
+			ran <- struct{}{}
+			return nil
+		},
+	}
+
+	b := &Bridge{}
+	h := b.metricHandler(context.Background(), 0, m, cmds)
+
+	h(nil, &testMessage{topic: m.topic + "/clean", retained: true})
+	h(nil, &testMessage{topic: m.topic + "/clean"})
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("clean: want ran")
+	}
+
+	select {
+	case <-ran:
+		t.Error("clean: want retained message ignored")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPublishStates(t *testing.T) {
+	const status = "mqttop/bridge/status"
+
+	tests := []struct {
+		name  string
+		birth *birth
+	}{
+		{"Birth", &birth{topic: status, payload: "online", qos: 1, retained: true}},
+		{"NoBirth", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			opts := mqtt.NewClientOptions().SetWill(status, "offline", 1, true)
+
+			b := &Bridge{client: mock.NewMockClient(opts, &buf), birth: tt.birth}
+			b.states.Store("mqttop/metric/cpu", true)
+
+			if err := b.publishStates(false).Error(); err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]map[string]bool
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !got[status]["mqttop/metric/cpu"] {
+				t.Errorf("want states published to %q, got %s", status, buf.Bytes())
+			}
+		})
+	}
+}
+
+// recordTransport is a [transport.Transport] recording the topics published
+// to it, which fails each publish with err if not nil.
+type recordTransport struct {
+	mu     sync.Mutex
+	topics []string
+	closed bool
+	err    error
+}
+
+func (r *recordTransport) Connect(context.Context) error { return nil }
+
+func (r *recordTransport) Publish(_ context.Context, topic string, _ byte, _ bool, _ []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.topics = append(r.topics, topic)
+
+	return r.err
+}
+
+func (r *recordTransport) Subscribe(context.Context, string, byte, transport.Handler) error {
+	return nil
+}
+
+func (r *recordTransport) Unsubscribe(context.Context, ...string) error {
+	return nil
+}
+
+func (r *recordTransport) Connected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.closed
+}
+
+func (r *recordTransport) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	return nil
+}
+
+func TestNew_Transport(t *testing.T) {
+	m := &commandMetric{payloadMetric: payloadMetric{typ: "dir"}, topic: "mqttop/metric/dir/tmp"}
+	tr := new(recordTransport)
+
+	b := New(config.Default(), WithMetrics(m), WithTransport(tr))
+
+	b.client.Publish(m.topic, 0, false, "{}").Wait()
+
+	if tok := b.client.Publish(m.topic, 0, false, "{}"); !tok.WaitTimeout(5*time.Second) || tok.Error() != nil {
+		t.Fatal("Publish:", tok.Error())
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if len(tr.topics) == 0 || tr.topics[len(tr.topics)-1] != m.topic {
+		t.Errorf("want published to %q, got %q", m.topic, tr.topics)
+	}
+
+	if got := b.Stats().Publishes; got != 2 {
+		t.Errorf("Publishes: want 2, got %d", got)
+	}
+}
+
+func TestStart_BirthError(t *testing.T) {
+	m := &commandMetric{payloadMetric: payloadMetric{typ: "dir"}, topic: "mqttop/metric/dir/tmp"}
+	tr := &recordTransport{err: errors.New("publish failed")}
+
+	b := New(config.Default(), WithMetrics(m), WithTransport(tr))
+
+	if err := b.Start(context.Background()); !errors.Is(err, tr.err) {
+		t.Fatalf("want %v, got %v", tr.err, err)
+	}
+
+	if tr.Connected() {
+		t.Error("want transport closed once the birth fails")
+	}
+}
+
+// startMetric is a commandMetric that calls cancel once it's started, as if
+// the bridge were stopped while starting.
+type startMetric struct {
+	commandMetric
+	cancel context.CancelFunc
+}
+
+func (m *startMetric) Start(context.Context) error {
+	m.cancel()
+	return metrics.ErrNotSupported
+}
+
+func TestRun_CancelStarting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &startMetric{
+		commandMetric: commandMetric{payloadMetric: payloadMetric{typ: "dir"}, topic: "mqttop/metric/dir/tmp"},
+		cancel:        cancel,
+	}
+	tr := new(recordTransport)
+
+	cfg := config.Default()
+	cfg.Discovery.Enabled = false
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Run(ctx, cfg, WithMetrics(m), WithTransport(tr))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("want nil, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for Run to return")
+	}
+
+	if tr.Connected() {
+		t.Error("want transport closed once canceled")
+	}
+}
@@ -0,0 +1,288 @@
+package bridge
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/lone-faerie/mqttop/internal/mqttutil"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// Permission is an operation on a topic that may be denied by the ACL of the
+// broker.
+type Permission uint8
+
+const (
+	PermPublish Permission = 1 << iota
+	PermSubscribe
+)
+
+func (p Permission) String() string {
+	switch p {
+	case 0:
+		return "none"
+	case PermPublish:
+		return "publish"
+	case PermSubscribe:
+		return "subscribe"
+	}
+
+	return "publish, subscribe"
+}
+
+// checkSuffix is appended to the topics that the bridge publishes to, to get
+// the topic that a test message is published to. This keeps the test messages
+// from being received by anything subscribed to the topic itself, such as Home
+// Assistant, while being allowed by any ACL with a wildcard for the topic.
+const checkSuffix = "/mqttop_check"
+
+// subackFailure is the return code of a denied subscription in a SUBACK.
+const subackFailure = 0x80
+
+// TopicCheck is the result of checking the permissions of a topic used by the
+// bridge.
+type TopicCheck struct {
+	Topic string
+	// Want is the permissions that the bridge needs.
+	Want Permission
+	// Denied is the permissions that were denied by the broker.
+	Denied Permission
+	// Unknown is the permissions that couldn't be verified. This is the case
+	// for publishing if subscribing to the test topic was denied.
+	Unknown Permission
+}
+
+// OK indicates whether every permission the bridge needs was granted.
+func (c TopicCheck) OK() bool {
+	return c.Denied == 0 && c.Unknown == 0
+}
+
+// Topics returns the topics that the bridge publishes or subscribes to, along
+// with the permissions it needs for each. These include the topics of the
+// metrics, commands and discovery.
+func (b *Bridge) Topics() map[string]Permission {
+	topics := make(map[string]Permission)
+
+	for _, m := range b.Metrics() {
+		topic := m.Topic()
+		if topic == "" {
+			continue
+		}
+
+		topics[topic] |= PermPublish
+		topics[topic+"/update"] |= PermSubscribe
+		topics[topic+"/stop"] |= PermSubscribe
+
+		if _, ok := m.(metrics.Informer); ok {
+			topics[topic+"/info"] |= PermPublish
+		}
+
+		if _, ok := m.(*metrics.CPU); ok {
+			topics[topic+"/selection_mode"] |= PermPublish
+		}
+
+		if cm, ok := m.(metrics.Commander); ok {
+			for cmd := range cm.Commands() {
+				topics[topic+"/"+cmd] |= PermSubscribe
+			}
+		}
+	}
+
+	opts := b.client.OptionsReader()
+	if will := opts.WillTopic(); will != "" {
+		topics[will] |= PermPublish
+	}
+
+	if b.birth != nil {
+		topics[b.birth.topic] |= PermPublish
+	}
+
+	topics[b.baseTopic+"/bridge/stop"] |= PermSubscribe
+	topics[b.baseTopic+"/bridge/update"] |= PermSubscribe
+
+	if b.statsInterval > 0 {
+		topics[b.baseTopic+"/bridge/stats"] |= PermPublish
+	}
+
+	if b.summary != nil {
+		topics[b.summaryTopic()] |= PermPublish
+	}
+
+	if b.derived != nil {
+		topics[b.derivedTopic()] |= PermPublish
+	}
+
+	if b.events != nil {
+		topics[b.eventsTopic()] |= PermPublish
+	}
+
+	if b.reportUnsupported {
+		topics[b.unsupportedTopic()] |= PermPublish
+	}
+
+	if b.watchdog != nil {
+		topics[b.watchdogTopic()] |= PermPublish
+	}
+
+	if b.power != nil {
+		topics[b.baseTopic+"/bridge/power/set"] |= PermSubscribe
+	}
+
+	if len(b.wol) > 0 {
+		topics[b.baseTopic+"/bridge/wol"] |= PermSubscribe
+	}
+
+	for _, c := range b.commands {
+		topics[c.topic] |= PermSubscribe
+	}
+
+	if b.discovery != nil {
+		b.discoveryMu.Lock()
+
+		if _, ok := b.discovery.Components[b.discovery.ID("update")]; !ok {
+			b.Discover(b.discovery)
+		}
+
+		for _, topic := range b.discovery.Topics() {
+			topics[topic] |= PermPublish
+		}
+
+		if topic := b.discovery.WaitTopic(); topic != "" {
+			topics[topic] |= PermSubscribe
+		}
+		b.discoveryMu.Unlock()
+	}
+
+	return topics
+}
+
+// Check connects to the broker, if not already connected, and checks that the
+// bridge is allowed to publish and subscribe to each of its [Bridge.Topics].
+// The results are sorted by topic.
+//
+// Subscribing is checked by subscribing to the topic. Publishing is checked by
+// subscribing to a subtopic of the topic and waiting up to timeout for a test
+// message published to it, so nothing subscribed to the topic itself receives
+// the test message. Nothing is published to the topics themselves.
+func (b *Bridge) Check(ctx context.Context, timeout time.Duration) ([]TopicCheck, error) {
+	if !b.client.IsConnected() {
+		if err := mqttutil.Wait(ctx, b.client.Connect(), 0); err != nil {
+			return nil, err
+		}
+
+		defer b.client.Disconnect(250)
+	}
+
+	topics := b.Topics()
+	filters := make(map[string]byte, len(topics))
+
+	for topic, perm := range topics {
+		if perm&PermSubscribe != 0 {
+			filters[topic] = 0
+		}
+
+		if perm&PermPublish != 0 {
+			filters[topic+checkSuffix] = 0
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string]bool)
+		want     int
+		all      = make(chan struct{})
+	)
+
+	t := b.client.SubscribeMultiple(filters, func(_ mqtt.Client, msg mqtt.Message) {
+		topic, ok := strings.CutSuffix(msg.Topic(), checkSuffix)
+		if !ok || string(msg.Payload()) != "mqttop check" {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if received[topic] {
+			return
+		}
+
+		received[topic] = true
+
+		if len(received) == want {
+			close(all)
+		}
+	})
+	if err := b.waitToken(ctx, t); err != nil {
+		return nil, err
+	} else if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer b.client.Unsubscribe(slices.Collect(maps.Keys(filters))...)
+
+	var result map[string]byte
+	if st, ok := t.(*mqtt.SubscribeToken); ok {
+		result = st.Result()
+	}
+
+	var publish []string
+
+	for topic, perm := range topics {
+		if perm&PermPublish != 0 && result[topic+checkSuffix] != subackFailure {
+			publish = append(publish, topic)
+		}
+	}
+
+	mu.Lock()
+	want = len(publish)
+	mu.Unlock()
+
+	for _, topic := range publish {
+		t := b.client.Publish(topic+checkSuffix, 1, false, "mqttop check")
+		if err := b.waitToken(ctx, t); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(publish) > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-all:
+		case <-timer.C:
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	checks := make([]TopicCheck, 0, len(topics))
+
+	for _, topic := range slices.Sorted(maps.Keys(topics)) {
+		c := TopicCheck{Topic: topic, Want: topics[topic]}
+
+		if c.Want&PermSubscribe != 0 && result[topic] == subackFailure {
+			c.Denied |= PermSubscribe
+		}
+
+		if c.Want&PermPublish != 0 {
+			if result[topic+checkSuffix] == subackFailure {
+				c.Unknown |= PermPublish
+			} else if !received[topic] {
+				c.Denied |= PermPublish
+			}
+		}
+
+		checks = append(checks, c)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,193 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/internal/expr"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// derivedValue is a value derived from the payloads of the metrics, see
+// [config.DerivedConfig].
+type derivedValue struct {
+	cfg  *config.DerivedConfig
+	expr *expr.Expr
+}
+
+// derived are the values derived from the payloads of the metrics, which are
+// evaluated after each update of a metric they use.
+type derived struct {
+	mu       sync.Mutex
+	values   []derivedValue
+	payloads map[string]any     // last payload of each metric, by type
+	results  map[string]float64 // last result of each value, by name
+}
+
+// newDerived returns the derived values of cfgs, or nil if there are none.
+func newDerived(cfgs []config.DerivedConfig) (*derived, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	d := &derived{
+		values:   make([]derivedValue, len(cfgs)),
+		payloads: make(map[string]any),
+		results:  make(map[string]float64, len(cfgs)),
+	}
+
+	for i := range cfgs {
+		e, err := expr.Parse(cfgs[i].Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		d.values[i] = derivedValue{cfg: &cfgs[i], expr: e}
+	}
+
+	return d, nil
+}
+
+// uses reports whether any of the values use the metric of type typ.
+func (d *derived) uses(typ string) bool {
+	for i := range d.values {
+		if _, ok := slices.BinarySearch(d.values[i].expr.Refs(), typ); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// update sets the payload of the metric of type typ to data, and evaluates
+// the values that use it. It returns whether any of the results changed.
+func (d *derived) update(typ string, data []byte) (bool, error) {
+	var p any
+	if err := json.Unmarshal(data, &p); err != nil {
+		return false, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.payloads[typ] = p
+
+	var changed bool
+
+	for i := range d.values {
+		v := &d.values[i]
+		if _, ok := slices.BinarySearch(v.expr.Refs(), typ); !ok {
+			continue
+		}
+
+		r, err := v.expr.Eval(d.payloads)
+		if errors.Is(err, expr.ErrUndefined) {
+			// The other metrics it uses may not have updated yet.
+			log.Debug("Derived value is undefined", "name", v.cfg.Name, "err", err)
+			continue
+		} else if err != nil {
+			log.WarnError("Unable to evaluate derived value", err, "name", v.cfg.Name)
+			continue
+		}
+
+		if old, ok := d.results[v.cfg.Name]; !ok || old != r {
+			d.results[v.cfg.Name] = r
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// derivedTopic returns the topic the derived values are published to.
+func (b *Bridge) derivedTopic() string {
+	return b.baseTopic + "/derived"
+}
+
+// publishDerived evaluates the derived values that use m and publishes the
+// results if any changed, returning the token of the publish or nil if the
+// results weren't published.
+func (b *Bridge) publishDerived(m metrics.Metric) mqtt.Token {
+	if !b.derived.uses(m.Type()) {
+		return nil
+	}
+
+	data, err := appendMetric(m)
+	if err != nil {
+		return nil
+	}
+
+	changed, err := b.derived.update(m.Type(), data)
+	if err != nil {
+		log.WarnError("Unable to decode "+m.Type()+" for derived values", err)
+		return nil
+	}
+
+	if !changed {
+		return nil
+	}
+
+	b.derived.mu.Lock()
+	data, err = json.Marshal(b.derived.results)
+	b.derived.mu.Unlock()
+
+	if err == nil {
+		data, err = b.seal(b.derivedTopic(), data)
+	}
+
+	if err != nil {
+		log.Error("Could not encode derived values", err)
+		return nil
+	}
+
+	return b.client.Publish(b.derivedTopic(), 0, false, data)
+}
+
+// discoverDerived adds a sensor for each derived value.
+func (b *Bridge) discoverDerived(d *discovery.Discovery, cmps []string) []string {
+	for i := range b.derived.values {
+		cfg := b.derived.values[i].cfg
+
+		id := d.ID("derived_" + cfg.Name)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		cmp := discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 cfg.Name,
+			discovery.Icon:                 icon.Function,
+			discovery.StateClass:           "measurement",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+			discovery.StateTopic:           b.derivedTopic(),
+			discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q] }}", cfg.Name),
+			discovery.UniqueID:             id,
+		}
+
+		if cfg.Unit != "" {
+			cmp[discovery.UnitOfMeasurement] = cfg.Unit
+		}
+
+		if cfg.DeviceClass != "" {
+			cmp[discovery.DeviceClass] = cfg.DeviceClass
+		}
+
+		if cfg.Icon != "" {
+			cmp[discovery.Icon] = cfg.Icon
+		}
+
+		d.Components[id] = cmp
+	}
+
+	return cmps
+}
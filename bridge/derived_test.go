@@ -0,0 +1,168 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/mock"
+	"github.com/lone-faerie/mqttop/sign"
+)
+
+// decodeDerived returns the payloads published to topic in the output of a
+// [mock.MockClient].
+func decodeDerived(t *testing.T, r io.Reader, topic string) []map[string]float64 {
+	t.Helper()
+
+	var payloads []map[string]float64
+
+	dec := json.NewDecoder(r)
+
+	for {
+		var msg map[string]json.RawMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return payloads
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		data, ok := msg[topic]
+		if !ok {
+			continue
+		}
+
+		var p map[string]float64
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatal(err)
+		}
+
+		payloads = append(payloads, p)
+	}
+}
+
+func TestDerived(t *testing.T) {
+	cfg, err := config.Read(strings.NewReader(`
+derived:
+  - name: vm_pressure
+    expression: cpu.usage*0.6 + memory.used/memory.total*40
+    unit: "%"
+  - name: hot
+    expression: iif(cpu.temperature > 80, 1, 0)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := newDerived(cfg.Derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	b := &Bridge{
+		client:    mock.NewMockClient(mqtt.NewClientOptions(), &buf),
+		baseTopic: "mqttop",
+		derived:   d,
+	}
+
+	cpu := &payloadMetric{typ: "cpu", data: `{"usage": 50, "temperature": 81}`}
+	mem := &payloadMetric{typ: "memory", data: `{"total": 16, "used": 4}`}
+	disks := &payloadMetric{typ: "disks", data: `{}`}
+
+	// Only hot is defined until the memory updates.
+	if b.publishDerived(cpu) == nil {
+		t.Error("cpu: want publish")
+	}
+	if b.publishDerived(disks) != nil {
+		t.Error("disks: want no publish, not used")
+	}
+	if b.publishDerived(mem) == nil {
+		t.Error("memory: want publish")
+	}
+	// Nothing changed.
+	if b.publishDerived(mem) != nil {
+		t.Error("memory: want no publish, unchanged")
+	}
+
+	cpu.data = `{"usage": 10, "temperature": 60}`
+	if b.publishDerived(cpu) == nil {
+		t.Error("cpu: want publish")
+	}
+
+	got := decodeDerived(t, &buf, "mqttop/derived")
+	want := []map[string]float64{
+		{"hot": 1},
+		{"hot": 1, "vm_pressure": 40},
+		{"hot": 0, "vm_pressure": 16},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("want %d payloads, got %d: %v", len(want), len(got), got)
+	}
+
+	for i := range want {
+		for k, v := range want[i] {
+			if got[i][k] != v || len(got[i]) != len(want[i]) {
+				t.Errorf("payload %d: want %v, got %v", i, want[i], got[i])
+				break
+			}
+		}
+	}
+
+	disc := &discovery.Discovery{
+		Components:        make(map[string]discovery.Component),
+		IDPrefix:          "mqttop",
+		AvailabilityTopic: "mqttop/bridge/status",
+	}
+
+	b.discoverDerived(disc, nil)
+
+	if len(disc.Components) != 2 {
+		t.Fatalf("want 2 components, got %d", len(disc.Components))
+	}
+
+	for id, cmp := range disc.Components {
+		if cmp[discovery.StateTopic] != "mqttop/derived" {
+			t.Errorf("%s: want state topic mqttop/derived, got %v", id, cmp[discovery.StateTopic])
+		}
+		if cmp[discovery.Name] == "vm_pressure" && cmp[discovery.UnitOfMeasurement] != "%" {
+			t.Errorf("%s: want unit %%, got %v", id, cmp[discovery.UnitOfMeasurement])
+		}
+	}
+
+	// The values are signed like the payloads of the metrics
+	b.signer = sign.NewHMAC([]byte("secret"))
+
+	cpu.data = `{"usage": 20, "temperature": 60}`
+	if b.publishDerived(cpu) == nil {
+		t.Fatal("Signed: want publish")
+	}
+
+	var msg map[string]json.RawMessage
+	if err := json.NewDecoder(&buf).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mock client indents the payload, which was published compacted
+	var payload bytes.Buffer
+
+	if err := json.Compact(&payload, msg["mqttop/derived"]); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _, err := sign.NewHMACVerifier([]byte("secret")).Verify("mqttop/derived", payload.Bytes())
+	if err != nil {
+		t.Fatalf("Verify: %v: %s", err, payload.Bytes())
+	}
+
+	if want := `{"hot":0,"vm_pressure":22}`; string(data) != want {
+		t.Errorf("Signed: want %s, got %s", want, data)
+	}
+}
@@ -0,0 +1,215 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// The kinds of [ConnectionEvent].
+const (
+	eventConnected    = "connected"
+	eventLost         = "connection_lost"
+	eventReconnecting = "reconnecting"
+)
+
+// maxPendingEvents is the number of events kept while the client is
+// disconnected, beyond which the oldest are dropped.
+const maxPendingEvents = 32
+
+// ConnectionEvent is published to the "bridge/events" subtopic of the base
+// topic each time the client connects, loses its connection or attempts to
+// reconnect to the broker.
+type ConnectionEvent struct {
+	// Event is the kind of event, either "connected", "connection_lost" or
+	// "reconnecting".
+	Event string `json:"event"`
+	// Time is when the event happened, which is before it was published if
+	// the client was disconnected.
+	Time time.Time `json:"time"`
+	// Reason is why the connection was lost.
+	Reason string `json:"reason,omitempty"`
+	// Attempt is the number of the attempt to reconnect since the connection
+	// was lost, starting at 1.
+	Attempt int `json:"attempt,omitempty"`
+	// Downtime is how long the client was disconnected before it reconnected
+	// in seconds.
+	Downtime float64 `json:"downtime,omitempty"`
+	// KeepAlive is the keepalive interval of the client in seconds. The
+	// broker drops the client if it doesn't hear from it within 1.5 times the
+	// interval, which is a common reason of lost connections.
+	KeepAlive float64 `json:"keepalive,omitempty"`
+	// Reconnects is the number of times the client reconnected to the broker
+	// since the bridge was created.
+	Reconnects uint64 `json:"reconnects"`
+}
+
+// events are the connection events of the client that haven't been published
+// yet, since the client was disconnected when they happened.
+type events struct {
+	mu      sync.Mutex
+	pending []ConnectionEvent
+	lost    time.Time
+	attempt int
+}
+
+// add adds e to the pending events, dropping the oldest if there are too many.
+func (ev *events) add(e ConnectionEvent) {
+	if len(ev.pending) == maxPendingEvents {
+		ev.pending = append(ev.pending[:0], ev.pending[1:]...)
+	}
+
+	ev.pending = append(ev.pending, e)
+}
+
+// eventsTopic returns the topic the connection events are published to.
+func (b *Bridge) eventsTopic() string {
+	return b.baseTopic + "/bridge/events"
+}
+
+// onConnect counts the connection of the client, then publishes the pending
+// connection events followed by a "connected" event, if events are enabled.
+func (b *Bridge) onConnect(c mqtt.Client) {
+	b.stats.onConnect(c)
+
+	if b.events == nil {
+		return
+	}
+
+	opts := c.OptionsReader()
+
+	e := ConnectionEvent{
+		Event:      eventConnected,
+		Time:       time.Now(),
+		KeepAlive:  opts.KeepAlive().Seconds(),
+		Reconnects: b.stats.snapshot().Reconnects,
+	}
+
+	b.events.mu.Lock()
+
+	if !b.events.lost.IsZero() {
+		e.Downtime = e.Time.Sub(b.events.lost).Seconds()
+	}
+
+	b.events.add(e)
+
+	pending := b.events.pending
+	b.events.pending = nil
+	b.events.lost = time.Time{}
+	b.events.attempt = 0
+
+	b.events.mu.Unlock()
+
+	log.Info("Connected to broker", "reconnects", e.Reconnects)
+
+	for i := range pending {
+		b.publishConnectionEvent(context.Background(), &pending[i])
+	}
+}
+
+// onConnectionLost records a "connection_lost" event, to be published once
+// the client reconnects. The attempts to reconnect are counted from the first
+// lost connection since the client was last connected.
+func (b *Bridge) onConnectionLost(_ mqtt.Client, err error) {
+	log.WarnError("Connection to broker lost", err)
+
+	b.events.mu.Lock()
+	defer b.events.mu.Unlock()
+
+	now := time.Now()
+
+	if b.events.lost.IsZero() {
+		b.events.lost = now
+	}
+
+	b.events.add(ConnectionEvent{
+		Event:      eventLost,
+		Time:       now,
+		Reason:     err.Error(),
+		Reconnects: b.stats.snapshot().Reconnects,
+	})
+}
+
+// onReconnecting records a "reconnecting" event for each attempt to
+// reconnect, to be published once the client reconnects.
+func (b *Bridge) onReconnecting(mqtt.Client, *mqtt.ClientOptions) {
+	b.events.mu.Lock()
+	defer b.events.mu.Unlock()
+
+	b.events.attempt++
+
+	log.Info("Reconnecting to broker", "attempt", b.events.attempt)
+
+	b.events.add(ConnectionEvent{
+		Event:      eventReconnecting,
+		Time:       time.Now(),
+		Attempt:    b.events.attempt,
+		Reconnects: b.stats.snapshot().Reconnects,
+	})
+}
+
+// publishConnectionEvent publishes e to the "bridge/events" subtopic of the
+// base topic.
+func (b *Bridge) publishConnectionEvent(ctx context.Context, e *ConnectionEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Error("Could not encode connection event", err)
+		return
+	}
+
+	t := b.client.Publish(b.eventsTopic(), 0, false, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.WarnError("Unable to publish connection event", err)
+	}
+}
+
+// discoverEvents adds a sensor for the last connection event, with the rest
+// of the event as its attributes, and a sensor for the number of reconnects.
+func (b *Bridge) discoverEvents(d *discovery.Discovery, cmps []string) []string {
+	id := d.ID("connection_event")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Connection event",
+		discovery.Icon:                 icon.Connection,
+		discovery.EntityCategory:       discovery.Diagnostic,
+		discovery.DeviceClass:          "enum",
+		discovery.Options:              []string{eventConnected, eventLost, eventReconnecting},
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+		discovery.StateTopic:           b.eventsTopic(),
+		discovery.ValueTemplate:        "{{ value_json.event }}",
+		discovery.JSONAttributesTopic:  b.eventsTopic(),
+		discovery.UniqueID:             id,
+	}
+
+	id = d.ID("reconnects")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Reconnects",
+		discovery.Icon:                 icon.Restart,
+		discovery.EntityCategory:       discovery.Diagnostic,
+		discovery.StateClass:           "total_increasing",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+		discovery.StateTopic:           b.eventsTopic(),
+		discovery.ValueTemplate:        "{{ value_json.reconnects }}",
+		discovery.UniqueID:             id,
+	}
+
+	return cmps
+}
@@ -0,0 +1,118 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/mock"
+)
+
+// decodeEvents returns the connection events published to topic in the
+// output of a [mock.MockClient].
+func decodeEvents(t *testing.T, r io.Reader, topic string) []ConnectionEvent {
+	t.Helper()
+
+	var events []ConnectionEvent
+
+	dec := json.NewDecoder(r)
+
+	for {
+		var msg map[string]json.RawMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return events
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		data, ok := msg[topic]
+		if !ok {
+			continue
+		}
+
+		var e ConnectionEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatal(err)
+		}
+
+		events = append(events, e)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	var buf bytes.Buffer
+
+	opts := mqtt.NewClientOptions().SetKeepAlive(30 * time.Second)
+	client := mock.NewMockClient(opts, &buf)
+
+	b := &Bridge{
+		client:    client,
+		baseTopic: "mqttop",
+		events:    new(events),
+		timeout:   time.Second,
+	}
+
+	b.onConnect(client)
+	b.onConnectionLost(client, errors.New("pingresp not received, disconnecting"))
+	b.onReconnecting(client, opts)
+	b.onReconnecting(client, opts)
+	b.onConnect(client)
+
+	events := decodeEvents(t, &buf, "mqttop/bridge/events")
+
+	type event struct {
+		Event      string
+		Reason     string
+		Attempt    int
+		KeepAlive  float64
+		Reconnects uint64
+	}
+
+	want := []event{
+		{Event: eventConnected, KeepAlive: 30},
+		{Event: eventLost, Reason: "pingresp not received, disconnecting"},
+		{Event: eventReconnecting, Attempt: 1},
+		{Event: eventReconnecting, Attempt: 2},
+		{Event: eventConnected, KeepAlive: 30, Reconnects: 1},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("want %d events, got %+v", len(want), events)
+	}
+
+	for i, e := range events {
+		got := event{e.Event, e.Reason, e.Attempt, e.KeepAlive, e.Reconnects}
+		if got != want[i] {
+			t.Errorf("event %d: want %+v, got %+v", i, want[i], got)
+		}
+
+		if e.Time.IsZero() {
+			t.Errorf("event %d: want time", i)
+		}
+	}
+
+	if got := b.Stats().Reconnects; got != 1 {
+		t.Errorf("Reconnects: want 1, got %d", got)
+	}
+}
+
+func TestEvents_Pending(t *testing.T) {
+	b := &Bridge{events: new(events)}
+
+	for range maxPendingEvents + 3 {
+		b.onReconnecting(nil, nil)
+	}
+
+	if got := len(b.events.pending); got != maxPendingEvents {
+		t.Fatalf("want %d pending events, got %d", maxPendingEvents, got)
+	}
+
+	if want, got := 4, b.events.pending[0].Attempt; got != want {
+		t.Errorf("oldest attempt: want %d, got %d", want, got)
+	}
+}
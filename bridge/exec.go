@@ -0,0 +1,203 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// command is a local command that is run when a message is published to its topic.
+type command struct {
+	name    string
+	topic   string
+	args    []string
+	timeout time.Duration
+	cred    *syscall.Credential
+	env     []string
+
+	mu sync.Mutex
+}
+
+// defaultPath is the $PATH of a command if the bridge has none.
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// lookupUser returns the credential and home directory of the user with the
+// given name or ID.
+func lookupUser(name string) (*syscall.Credential, string, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if u, err = user.LookupId(name); err != nil {
+			return nil, "", err
+		}
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, u.HomeDir, nil
+}
+
+// commandEnv returns the environment of a command run with the given home
+// directory, which only has $PATH, $HOME and the variables of the bridge named
+// by allow.
+func commandEnv(home string, allow []string) []string {
+	path := os.Getenv("PATH")
+	if path == "" {
+		path = defaultPath
+	}
+
+	env := []string{"PATH=" + path, "HOME=" + home}
+
+	for _, name := range allow {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+
+	return env
+}
+
+// newCommands returns the valid commands of cfg. Any command without a name
+// or program, or with an unknown user, is ignored.
+func newCommands(cfg []config.CommandConfig, baseTopic string) []*command {
+	var cmds []*command
+
+	for i := range cfg {
+		c := &cfg[i]
+
+		if c.Name == "" || len(c.Command) == 0 {
+			log.Warn("Command is missing a name or program, ignoring", "name", c.Name)
+			continue
+		}
+
+		cmd := &command{
+			name:    c.Name,
+			topic:   c.Topic,
+			args:    c.Command,
+			timeout: c.Timeout,
+		}
+
+		if cmd.topic == "" {
+			cmd.topic = baseTopic + "/command/" + c.Name
+		}
+
+		if cmd.timeout <= 0 {
+			cmd.timeout = config.DefaultCommandTimeout
+		}
+
+		home, _ := os.UserHomeDir()
+
+		if c.User != "" {
+			cred, dir, err := lookupUser(c.User)
+			if err != nil {
+				log.WarnError("Unknown command user, ignoring", err, "name", c.Name, "user", c.User)
+				continue
+			}
+
+			cmd.cred = cred
+			home = dir
+		}
+
+		cmd.env = commandEnv(home, c.Env)
+
+		cmds = append(cmds, cmd)
+	}
+
+	return cmds
+}
+
+// run runs the command with payload as stdin and $MQTTOP_PAYLOAD. A payload
+// with a NUL byte can't be in the environment, so it is only passed as stdin.
+// The command doesn't inherit the environment of the bridge, see [commandEnv].
+// If the command is already running, it is not run again and the returned bool
+// is false.
+func (c *command) run(ctx context.Context, payload []byte) (bool, error) {
+	if !c.mu.TryLock() {
+		return false, nil
+	}
+	defer c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(slices.Clip(c.env), "MQTTOP_TOPIC="+c.topic)
+
+	if bytes.IndexByte(payload, 0) < 0 {
+		cmd.Env = append(cmd.Env, "MQTTOP_PAYLOAD="+string(payload))
+	}
+
+	if c.cred != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: c.cred}
+	}
+
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Debug("Command output", "name", c.name, "output", string(out))
+	}
+
+	return true, err
+}
+
+func (b *Bridge) handleCommand(ctx context.Context, c *command, msg mqtt.Message) {
+	if msg.Retained() {
+		log.Warn("Ignoring retained command", "name", c.name, "topic", msg.Topic())
+		return
+	}
+
+	ran, err := c.run(ctx, msg.Payload())
+
+	switch {
+	case err != nil:
+		log.Error("Command "+c.name+" failed", err)
+		b.reply(ctx, msg, StatusError, err)
+	case !ran:
+		log.Warn("Command is already running, ignoring", "name", c.name)
+		b.reply(ctx, msg, StatusBusy, nil)
+	default:
+		log.Info("Command finished", "name", c.name)
+		b.reply(ctx, msg, StatusOK, nil)
+	}
+}
+
+// discoverCommands adds a button for each of the commands.
+func (b *Bridge) discoverCommands(d *discovery.Discovery, cmps []string) []string {
+	for _, c := range b.commands {
+		id := d.ID("command_" + c.name)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Button,
+			discovery.Name:                 c.name,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+			discovery.CommandTopic:         c.topic,
+			discovery.UniqueID:             id,
+		}
+	}
+
+	return cmps
+}
@@ -0,0 +1,100 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+)
+
+func TestCommands(t *testing.T) {
+	cur, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("MQTTOP_TEST_SECRET", "secret")
+	t.Setenv("MQTTOP_TEST_ALLOWED", "allowed")
+
+	cmds := newCommands([]config.CommandConfig{
+		{Name: "check", Command: []string{"sh", "-c", `test "$(cat)" = "$MQTTOP_PAYLOAD"`}},
+		{Name: "sleep", Topic: "custom/sleep", Command: []string{"sleep", "1"}, Timeout: 50 * time.Millisecond},
+		{Name: "self", Command: []string{"true"}, User: cur.Username},
+		{Name: "empty"},
+		{Name: "nobody", Command: []string{"true"}, User: "mqttop-no-such-user"},
+		{Name: "nul", Command: []string{"sh", "-c", `test -z "${MQTTOP_PAYLOAD+set}" && test $(wc -c) -eq 3`}},
+		{Name: "env", Command: []string{"sh", "-c", `test -z "$MQTTOP_TEST_SECRET" && test "$MQTTOP_TEST_ALLOWED" = allowed && test -n "$HOME"`}, Env: []string{"MQTTOP_TEST_ALLOWED"}},
+	}, "mqttop")
+
+	if want, got := 5, len(cmds); got != want {
+		t.Fatalf("Commands: want %d, got %d", want, got)
+	}
+	if want, got := "mqttop/command/check", cmds[0].topic; got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := "custom/sleep", cmds[1].topic; got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := config.DefaultCommandTimeout, cmds[0].timeout; got != want {
+		t.Errorf("Timeout: want %v, got %v", want, got)
+	}
+	if cmds[2].cred == nil {
+		t.Error("Credential: want non-nil with user")
+	}
+
+	var tests = []struct {
+		cmd     *command
+		payload string
+		err     bool
+	}{
+		{cmds[0], "hello", false},
+		{cmds[1], "", true},
+		{cmds[3], "a\x00b", false},
+		{cmds[4], "", false},
+	}
+	for _, tt := range tests {
+		ran, err := tt.cmd.run(context.Background(), []byte(tt.payload))
+		if !ran {
+			t.Errorf("%s: Wanted ran", tt.cmd.name)
+		}
+		if (err != nil) != tt.err {
+			t.Errorf("%s: Wanted error %v, got %v", tt.cmd.name, tt.err, err)
+		}
+	}
+
+	cmds[1].mu.Lock()
+	if ran, _ := cmds[1].run(context.Background(), nil); ran {
+		t.Error("sleep: Wanted not ran while running")
+	}
+	cmds[1].mu.Unlock()
+
+	b := &Bridge{commands: cmds}
+
+	touched := filepath.Join(t.TempDir(), "touched")
+	touch := newCommands([]config.CommandConfig{{Name: "touch", Command: []string{"touch", touched}}}, "mqttop")[0]
+
+	b.handleCommand(context.Background(), touch, &testMessage{topic: touch.topic, retained: true})
+	if _, err := os.Stat(touched); err == nil {
+		t.Error("touch: Wanted retained message ignored")
+	}
+
+	b.handleCommand(context.Background(), touch, &testMessage{topic: touch.topic})
+	if _, err := os.Stat(touched); err != nil {
+		t.Errorf("touch: Wanted ran, got %v", err)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	b.discoverCommands(d, nil)
+
+	if want, got := "custom/sleep", d.Components["mqttop_command_sleep"][discovery.CommandTopic]; got != want {
+		t.Errorf("Command topic: want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,229 @@
+package bridge
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// subscribeTopic is the subtopic of a lazy metric that subscribers publish to
+// in order to start the metric.
+const subscribeTopic = "/subscribe"
+
+// lazy tracks the lease of each lazy metric that has been asked for by a
+// subscriber, see [config.LazyConfig]. A lazy metric isn't started until it
+// has a lease, and is renewed once its lease expires.
+type lazy struct {
+	types   []string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	leases map[string]*lease
+}
+
+// lease is the lease of a lazy metric, which expires at deadline unless it's
+// renewed before.
+type lease struct {
+	timer    *time.Timer
+	deadline time.Time
+}
+
+func newLazy(timeout time.Duration, types []string) *lazy {
+	return &lazy{
+		types:   types,
+		timeout: timeout,
+		leases:  make(map[string]*lease),
+	}
+}
+
+// isLazy reports whether m is lazy.
+func (l *lazy) isLazy(m metrics.Metric) bool {
+	return l != nil && (len(l.types) == 0 || slices.Contains(l.types, m.Type()))
+}
+
+// idle reports whether m is lazy and doesn't have a lease.
+func (l *lazy) idle(m metrics.Metric) bool {
+	if !l.isLazy(m) {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.leases[m.Topic()]
+
+	return !ok
+}
+
+// renew renews the lease of the metric of topic, and reports whether it is a
+// new lease. Once the lease expires, expire is called.
+func (l *lazy) renew(topic string, expire func()) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deadline := time.Now().Add(l.timeout)
+
+	if ls, ok := l.leases[topic]; ok {
+		ls.deadline = deadline
+		return false
+	}
+
+	ls := &lease{deadline: deadline}
+	ls.timer = time.AfterFunc(l.timeout, func() {
+		if l.expired(topic, ls) {
+			expire()
+		}
+	})
+
+	l.leases[topic] = ls
+
+	return true
+}
+
+// expired reports whether ls has expired, and if so removes it. Otherwise its
+// timer is reset to the renewed deadline.
+func (l *lazy) expired(topic string, ls *lease) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leases[topic] != ls {
+		return false
+	}
+
+	if d := time.Until(ls.deadline); d > 0 {
+		ls.timer.Reset(d)
+		return false
+	}
+
+	delete(l.leases, topic)
+
+	return true
+}
+
+// release removes the lease of the metric of topic without expiring it.
+func (l *lazy) release(topic string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ls, ok := l.leases[topic]; ok {
+		ls.timer.Stop()
+		delete(l.leases, topic)
+	}
+}
+
+// waitSubscriber subscribes to the "/subscribe" subtopic of the lazy metric
+// m, which is started by [Bridge.activateMetric] once a subscriber publishes
+// to it. The metric is considered running in the meantime, since it will be
+// once there's a subscriber.
+func (b *Bridge) waitSubscriber(ctx context.Context, m metrics.Metric) {
+	b.states.Store(m.Topic(), true)
+
+	t := b.client.Subscribe(m.Topic()+subscribeTopic, 0, b.countCommands(b.subscribeHandler(ctx, m.Topic())))
+	if err := b.waitToken(ctx, t); err != nil {
+		log.Error("Could not subscribe to "+m.Topic()+subscribeTopic, err)
+		return
+	}
+
+	log.Debug("Waiting for subscriber", "metric", m.Type(), "topic", m.Topic())
+}
+
+// subscribeHandler returns a [mqtt.MessageHandler] for the "/subscribe"
+// subtopic of the lazy metric of topic, which renews its lease and starts the
+// metric if it was idle. The metric is looked up by its topic, since it's
+// replaced each time it's idled or restarted.
+func (b *Bridge) subscribeHandler(ctx context.Context, topic string) mqtt.MessageHandler {
+	return func(_ mqtt.Client, _ mqtt.Message) {
+		if b.lazy.renew(topic, func() { b.idleMetric(ctx, topic) }) {
+			go b.activateMetric(ctx, topic)
+		}
+	}
+}
+
+// indexOf returns the index of the metric of topic, or -1 if there is none.
+// The bridge must be locked.
+func (b *Bridge) indexOf(topic string) int {
+	return slices.IndexFunc(b.metrics, func(m metrics.Metric) bool {
+		return m != nil && m.Topic() == topic
+	})
+}
+
+// activateMetric starts the idle lazy metric of topic, and publishes it right
+// away instead of after its first update interval.
+func (b *Bridge) activateMetric(ctx context.Context, topic string) {
+	b.mu.Lock()
+
+	i := b.indexOf(topic)
+	if i < 0 || ctxDone(ctx) {
+		b.mu.Unlock()
+		b.lazy.release(topic)
+
+		return
+	}
+
+	m := b.metrics[i]
+
+	log.Info("Starting "+m.Type()+", subscribed", "topic", topic)
+	b.startMetric(ctx, i, m, false)
+
+	b.mu.Unlock()
+
+	if err := m.Update(); err == nil {
+		b.send(ctx, m, err)
+	}
+}
+
+// idleMetric stops the lazy metric of topic once its lease expires, and
+// replaces it with a renewed metric that waits for the next subscriber. The
+// state of the metric, such as its counters, is carried over to the renewed
+// metric by [metrics.Renew], and the subscriptions of the metric are removed
+// until the renewed metric starts. If the metric can't be renewed it keeps
+// running for another lease.
+func (b *Bridge) idleMetric(ctx context.Context, topic string) {
+	if ctxDone(ctx) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.indexOf(topic)
+	if i < 0 {
+		return
+	}
+
+	m := b.metrics[i]
+
+	mm, err := metrics.Renew(m, b.cfg)
+	if err != nil {
+		log.WarnError("Unable to idle "+m.Type(), err, "topic", topic)
+		b.lazy.renew(topic, func() { b.idleMetric(ctx, topic) })
+
+		return
+	}
+
+	log.Info("Stopping "+m.Type()+", no subscribers", "topic", topic)
+
+	b.watchdog.forget(m)
+	b.metrics[i] = mm
+
+	go m.Stop()
+
+	// The handler of the subscriptions still refers to m, so they're removed
+	// rather than left to command the stopped metric.
+	t := b.client.Unsubscribe(metricTopics(m)...)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.WarnError("Unable to unsubscribe from "+m.Type(), err, "topic", topic)
+	}
+
+	b.startMetric(ctx, i, mm, false)
+}
@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+)
+
+type typedMetric struct {
+	intervalMetric
+	typ, topic string
+}
+
+func (m *typedMetric) Type() string  { return m.typ }
+func (m *typedMetric) Topic() string { return m.topic }
+
+func TestLazy(t *testing.T) {
+	var (
+		cpu = &typedMetric{typ: "cpu", topic: "mqttop/metric/cpu"}
+		mem = &typedMetric{typ: "memory", topic: "mqttop/metric/memory"}
+	)
+
+	var l *lazy
+
+	if l.isLazy(cpu) || l.idle(cpu) {
+		t.Error("nil: want not lazy")
+	}
+
+	l = newLazy(50*time.Millisecond, []string{"cpu"})
+
+	if !l.idle(cpu) {
+		t.Error("idle: want cpu idle")
+	}
+
+	if l.idle(mem) {
+		t.Error("idle: want memory not lazy")
+	}
+
+	expired := make(chan struct{}, 1)
+	expire := func() { expired <- struct{}{} }
+
+	if !l.renew(cpu.topic, expire) {
+		t.Error("renew: want new lease")
+	}
+
+	if l.idle(cpu) {
+		t.Error("idle: want cpu leased")
+	}
+
+	// Renewing halfway pushes the deadline past the first timer
+	time.Sleep(30 * time.Millisecond)
+
+	if l.renew(cpu.topic, expire) {
+		t.Error("renew: want existing lease")
+	}
+
+	select {
+	case <-expired:
+		t.Fatal("expired: want renewed lease")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for lease to expire")
+	}
+
+	if !l.idle(cpu) {
+		t.Error("idle: want cpu idle after expiry")
+	}
+
+	l.renew(cpu.topic, expire)
+	l.release(cpu.topic)
+
+	select {
+	case <-expired:
+		t.Error("expired: want released lease not to expire")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -1,17 +1,66 @@
 package bridge
 
 import (
+	"time"
+
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/encrypt"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/sign"
+	"github.com/lone-faerie/mqttop/tracing"
+	"github.com/lone-faerie/mqttop/transport"
 )
 
 type Option func(*Bridge)
 
+// WithClient publishes to c as an MQTT transport, see [transport.MQTT].
 func WithClient(c mqtt.Client) Option {
 	return func(b *Bridge) {
-		b.client = c
+		b.transport = transport.MQTT(c)
+	}
+}
+
+// WithOnSelectionMode calls fn with the CPU metric each time its selection mode
+// may have been changed over MQTT, such as to persist the mode as soon as it
+// changes.
+func WithOnSelectionMode(fn func(*metrics.CPU)) Option {
+	return func(b *Bridge) {
+		b.onSelectionMode = fn
+	}
+}
+
+// WithTransport publishes to t instead of the transport of the config, see
+// [transport.Open].
+func WithTransport(t transport.Transport) Option {
+	return func(b *Bridge) {
+		b.transport = t
+	}
+}
+
+// WithEncrypter encrypts the payload of each metric with e, see [encrypt].
+func WithEncrypter(e *encrypt.Encrypter) Option {
+	return func(b *Bridge) {
+		b.encrypter = e
+	}
+}
+
+// WithSigner signs the payload of each metric with s, see [sign].
+func WithSigner(s *sign.Signer) Option {
+	return func(b *Bridge) {
+		b.signer = s
+	}
+}
+
+// WithTracerProvider traces the update and publish of each metric with a
+// tracer of tp, instead of the provider of the tracing config. The bridge
+// doesn't shut tp down.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(b *Bridge) {
+		b.tracer = tp.Tracer(tracing.Name)
 	}
 }
 
@@ -22,6 +71,15 @@ func WithDiscovery(d *discovery.Discovery, migrate bool) Option {
 	}
 }
 
+// WithDiscoveryStore saves the discovery payload to s each time it is published,
+// so that the next run may diff and migrate it even if the bridge isn't stopped
+// cleanly.
+func WithDiscoveryStore(s *discovery.Store) Option {
+	return func(b *Bridge) {
+		b.store = s
+	}
+}
+
 func WithMetrics(m ...metrics.Metric) Option {
 	return func(b *Bridge) {
 		b.metrics = append(b.metrics, m...)
@@ -59,3 +117,31 @@ func WithBaseTopic(topic string) Option {
 		b.baseTopic = topic
 	}
 }
+
+// WithStats publishes the [Stats] of the bridge to the "bridge/stats" subtopic
+// of the base topic and logs them every d.
+func WithStats(d time.Duration) Option {
+	return func(b *Bridge) {
+		b.statsInterval = d
+	}
+}
+
+// WithLazy only starts the metrics of the given types, or every metric if
+// none are given, while they have a subscriber. A subscriber asks for a metric
+// by publishing to the "/subscribe" subtopic of the metric, and the metric is
+// stopped once nothing has asked for it in timeout. Metrics are renewed from
+// the config the bridge is created with.
+func WithLazy(timeout time.Duration, types ...string) Option {
+	return func(b *Bridge) {
+		b.lazy = newLazy(timeout, types)
+	}
+}
+
+// WithWatchdog restarts any metric that hasn't updated in missed update
+// intervals, or whose updates stop without it being stopped by the bridge.
+// Metrics are renewed from the config the bridge is created with.
+func WithWatchdog(missed int) Option {
+	return func(b *Bridge) {
+		b.watchdog = newWatchdog(missed)
+	}
+}
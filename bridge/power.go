@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// powerAction is an action that may be published to the power topic of the
+// bridge, which is run with the systemctl command of the same name.
+type powerAction struct {
+	name  string
+	label string
+	icon  string
+}
+
+var powerActions = []powerAction{
+	{"poweroff", "Power off", icon.Power},
+	{"reboot", "Reboot", icon.Restart},
+	{"suspend", "Suspend", icon.Sleep},
+	{"hibernate", "Hibernate", icon.Sleep},
+}
+
+// runPowerAction runs the power action with systemctl.
+var runPowerAction = func(ctx context.Context, action string) error {
+	return exec.CommandContext(ctx, "systemctl", action).Run()
+}
+
+// power handles the power actions published to the bridge.
+type power struct {
+	allow   []string
+	confirm time.Duration
+
+	mu      sync.Mutex
+	pending string
+	expires time.Time
+}
+
+// newPower returns the power actions allowed by cfg, or nil if none are allowed.
+func newPower(cfg *config.PowerConfig) *power {
+	var allow []string
+
+	for _, name := range cfg.Allow {
+		name = strings.ToLower(name)
+
+		if !slices.ContainsFunc(powerActions, func(a powerAction) bool { return a.name == name }) {
+			log.Warn("Unknown power action, ignoring", "action", name)
+			continue
+		}
+
+		if !slices.Contains(allow, name) {
+			allow = append(allow, name)
+		}
+	}
+
+	if len(allow) == 0 {
+		return nil
+	}
+
+	p := &power{allow: allow}
+
+	if cfg.Confirm {
+		if cfg.ConfirmTimeout > 0 {
+			p.confirm = cfg.ConfirmTimeout
+		} else {
+			p.confirm = config.DefaultPower.ConfirmTimeout
+		}
+	}
+
+	return p
+}
+
+// handle runs the action in payload if it is allowed. If confirmation is required,
+// the action is only run if it is the same as the pending action and published
+// before the pending action expires, otherwise it becomes the pending action.
+// The returned bool reports whether the action was run.
+func (p *power) handle(ctx context.Context, payload []byte) (bool, error) {
+	action := strings.ToLower(string(bytes.TrimSpace(payload)))
+	if !slices.Contains(p.allow, action) {
+		return false, fmt.Errorf("power action %q is not allowed", action)
+	}
+
+	if p.confirm > 0 {
+		p.mu.Lock()
+
+		if now := time.Now(); action != p.pending || now.After(p.expires) {
+			p.pending = action
+			p.expires = now.Add(p.confirm)
+			p.mu.Unlock()
+
+			log.Warn("Power action pending, publish again to confirm", "action", action, "timeout", p.confirm)
+
+			return false, nil
+		}
+
+		p.pending = ""
+		p.mu.Unlock()
+	}
+
+	log.Warn("Running power action", "action", action)
+
+	return true, runPowerAction(ctx, action)
+}
+
+// discover adds a button for each of the allowed power actions.
+func (p *power) discover(topic string, d *discovery.Discovery, cmps []string) []string {
+	for _, a := range powerActions {
+		if !slices.Contains(p.allow, a.name) {
+			continue
+		}
+
+		id := d.ID("power_" + a.name)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Button,
+			discovery.Name:                 a.label,
+			discovery.Icon:                 a.icon,
+			discovery.EntityCategory:       discovery.Config,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+			discovery.CommandTopic:         topic,
+			discovery.PayloadPress:         a.name,
+			discovery.UniqueID:             id,
+		}
+
+		if a.name == "reboot" {
+			d.Components[id][discovery.DeviceClass] = "restart"
+		}
+	}
+
+	return cmps
+}
@@ -0,0 +1,161 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+)
+
+// testMessage is a [mqtt.Message] received on topic.
+type testMessage struct {
+	mqtt.Message
+
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+func (m *testMessage) Topic() string   { return m.topic }
+func (m *testMessage) Payload() []byte { return m.payload }
+func (m *testMessage) Retained() bool  { return m.retained }
+
+func testPower(t *testing.T, cfg *config.PowerConfig) (*power, *[]string) {
+	t.Helper()
+
+	var ran []string
+
+	run := runPowerAction
+	runPowerAction = func(_ context.Context, action string) error {
+		ran = append(ran, action)
+		return nil
+	}
+	t.Cleanup(func() { runPowerAction = run })
+
+	p := newPower(cfg)
+	if p == nil {
+		t.Fatal("power is nil")
+	}
+
+	return p, &ran
+}
+
+func TestPower(t *testing.T) {
+	if p := newPower(&config.PowerConfig{Allow: []string{"explode"}}); p != nil {
+		t.Errorf("newPower: want nil without allowed actions, got %+v", p)
+	}
+
+	p, ran := testPower(t, &config.PowerConfig{Allow: []string{"Reboot", "suspend"}})
+
+	var tests = []struct {
+		payload string
+		ran     bool
+		err     bool
+	}{
+		{"reboot", true, false},
+		{"SUSPEND\n", true, false},
+		{"poweroff", false, true},
+		{"", false, true},
+	}
+	for _, tt := range tests {
+		got, err := p.handle(context.Background(), []byte(tt.payload))
+		if (err != nil) != tt.err {
+			t.Errorf("%q: Wanted error %v, got %v", tt.payload, tt.err, err)
+		}
+		if got != tt.ran {
+			t.Errorf("%q: Wanted ran %v, got %v", tt.payload, tt.ran, got)
+		}
+	}
+
+	if want, got := 2, len(*ran); got != want {
+		t.Errorf("Ran: want %d actions, got %v", want, *ran)
+	}
+}
+
+func TestPower_Retained(t *testing.T) {
+	p, ran := testPower(t, &config.PowerConfig{Allow: []string{"reboot"}})
+
+	b := &Bridge{power: p}
+
+	b.handlePower(context.Background(), &testMessage{
+		topic:    "mqttop/bridge/power/set",
+		payload:  []byte("reboot"),
+		retained: true,
+	})
+
+	if len(*ran) > 0 {
+		t.Fatalf("Retained: want no actions, got %v", *ran)
+	}
+
+	b.handlePower(context.Background(), &testMessage{
+		topic:   "mqttop/bridge/power/set",
+		payload: []byte("reboot"),
+	})
+
+	if want, got := 1, len(*ran); got != want {
+		t.Errorf("Ran: want %d actions, got %v", want, *ran)
+	}
+}
+
+func TestPower_Confirm(t *testing.T) {
+	p, ran := testPower(t, &config.PowerConfig{
+		Allow:          []string{"reboot", "poweroff"},
+		Confirm:        true,
+		ConfirmTimeout: 50 * time.Millisecond,
+	})
+
+	var tests = []struct {
+		payload string
+		sleep   time.Duration
+		ran     bool
+	}{
+		{"reboot", 0, false},
+		{"poweroff", 0, false},
+		{"reboot", 0, false},
+		{"reboot", 0, true},
+		{"poweroff", 0, false},
+		{"poweroff", 100 * time.Millisecond, false},
+		{"poweroff", 0, true},
+	}
+	for i, tt := range tests {
+		time.Sleep(tt.sleep)
+
+		got, err := p.handle(context.Background(), []byte(tt.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.ran {
+			t.Errorf("%d %q: Wanted ran %v, got %v", i, tt.payload, tt.ran, got)
+		}
+	}
+
+	if want, got := 2, len(*ran); got != want {
+		t.Errorf("Ran: want %d actions, got %v", want, *ran)
+	}
+}
+
+func TestPower_Discover(t *testing.T) {
+	p, _ := testPower(t, &config.PowerConfig{Allow: []string{"reboot", "hibernate"}})
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	p.discover("mqttop/bridge/power/set", d, nil)
+
+	if want, got := 2, len(d.Components); got != want {
+		t.Fatalf("Components: want %d, got %d", want, got)
+	}
+
+	cmp := d.Components["mqttop_power_reboot"]
+	if want, got := "reboot", cmp[discovery.PayloadPress]; got != want {
+		t.Errorf("Payload: want %v, got %v", want, got)
+	}
+	if want, got := discovery.Button, cmp[discovery.Platform]; got != want {
+		t.Errorf("Platform: want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,60 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// Statuses of a [CommandResult].
+const (
+	// StatusOK is the status of a command that succeeded.
+	StatusOK = "ok"
+	// StatusPending is the status of a power action waiting to be confirmed.
+	StatusPending = "pending"
+	// StatusBusy is the status of a local command that is already running,
+	// which isn't run again.
+	StatusBusy = "busy"
+	// StatusError is the status of a command that failed.
+	StatusError = "error"
+)
+
+// CommandResult is the reply to a command message, which is published to the
+// result topic of the command if command results are enabled, so that the
+// caller doesn't need to infer whether the command succeeded from the state.
+type CommandResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// resultTopic returns the topic the result of msg is published to. MQTT 5
+// messages may carry a response topic, but the client only speaks MQTT 3.1.1,
+// so the result is always published to the "/result" subtopic of the command.
+func resultTopic(msg mqtt.Message) string {
+	return msg.Topic() + "/result"
+}
+
+// reply publishes the result of the command msg with the given status, or
+// StatusError if err is non-nil. Nothing is published unless command results
+// are enabled.
+func (b *Bridge) reply(ctx context.Context, msg mqtt.Message, status string, err error) {
+	if !b.results {
+		return
+	}
+
+	result := CommandResult{Status: status}
+
+	if err != nil {
+		result = CommandResult{Status: StatusError, Error: err.Error()}
+	}
+
+	data, _ := json.Marshal(result)
+
+	t := b.client.Publish(resultTopic(msg), 0, false, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.WarnError("Unable to publish command result", err, "topic", msg.Topic())
+	}
+}
@@ -0,0 +1,91 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// RunError is returned by [Run] if the bridge is unable to run, along with
+// the step that failed.
+type RunError struct {
+	// Op is the step that failed, one of "config", "metrics", "connect" or
+	// "start".
+	Op  string
+	Err error
+}
+
+func (e *RunError) Error() string {
+	return "run bridge: " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs a bridge until ctx is canceled or the bridge is stopped over MQTT,
+// which is all that's needed to embed the bridge in another program:
+//
+//	cfg, err := config.Load("mqttop.yaml")
+//	if err != nil {
+//		return err
+//	}
+//
+//	return bridge.Run(ctx, cfg)
+//
+// If cfg is nil, the config is loaded from the default paths, see
+// [config.Load]. The metrics, discovery and everything else are created from
+// the config, unless given by opts, the same as [New]. Unlike "mqttop run",
+// nothing is persisted in a data directory, and the runtime config isn't
+// applied to the process.
+//
+// Run returns nil once the bridge is stopped. Otherwise, the returned error
+// is a [*RunError]. If none of the metrics of the config are supported, the
+// error wraps [ErrNoMetrics] along with the reason each metric isn't supported.
+func Run(ctx context.Context, cfg *config.Config, opts ...Option) error {
+	if cfg == nil {
+		var err error
+
+		if cfg, err = config.Load(); err != nil {
+			return &RunError{Op: "config", Err: err}
+		}
+	}
+
+	b := New(cfg, opts...)
+
+	if len(b.metrics) == 0 {
+		errs := []error{ErrNoMetrics}
+
+		for _, u := range b.unsupported {
+			errs = append(errs, fmt.Errorf("%s: %s", u.Metric, u.Error))
+		}
+
+		return &RunError{Op: "metrics", Err: errors.Join(errs...)}
+	}
+
+	if err := b.Start(ctx); err != nil {
+		return &RunError{Op: "connect", Err: err}
+	}
+
+	// Stopped even if ctx is done before the bridge is ready, since it's
+	// still connected
+	defer b.Stop()
+
+	select {
+	case <-b.Ready():
+		if err := b.Error(); err != nil {
+			return &RunError{Op: "start", Err: err}
+		}
+	case <-ctx.Done():
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-b.Done():
+	}
+
+	return nil
+}
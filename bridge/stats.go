@@ -0,0 +1,137 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// Stats are the counts of the traffic between the bridge and the broker since
+// the bridge was created.
+type Stats struct {
+	// Publishes is the number of messages published, including discovery.
+	Publishes uint64 `json:"publishes"`
+	// Bytes is the total size of the payloads of the messages published.
+	Bytes uint64 `json:"bytes"`
+	// Failures is the number of messages that failed to publish.
+	Failures uint64 `json:"failures"`
+	// Reconnects is the number of times the client reconnected to the broker.
+	Reconnects uint64 `json:"reconnects"`
+	// Commands is the number of messages handled on the command topics of the
+	// bridge and its metrics.
+	Commands uint64 `json:"commands"`
+}
+
+// stats are the counters of [Stats], which are updated concurrently.
+type stats struct {
+	publishes atomic.Uint64
+	bytes     atomic.Uint64
+	failures  atomic.Uint64
+	connects  atomic.Uint64
+	commands  atomic.Uint64
+}
+
+func (s *stats) snapshot() Stats {
+	var reconnects uint64
+	if n := s.connects.Load(); n > 1 {
+		reconnects = n - 1
+	}
+
+	return Stats{
+		Publishes:  s.publishes.Load(),
+		Bytes:      s.bytes.Load(),
+		Failures:   s.failures.Load(),
+		Reconnects: reconnects,
+		Commands:   s.commands.Load(),
+	}
+}
+
+// onConnect counts each connection of the client, all but the first of which
+// are reconnects.
+func (s *stats) onConnect(mqtt.Client) {
+	s.connects.Add(1)
+}
+
+// statsClient is an [mqtt.Client] that counts the messages it publishes.
+type statsClient struct {
+	mqtt.Client
+	stats *stats
+}
+
+func (c *statsClient) Publish(topic string, qos byte, retained bool, payload any) mqtt.Token {
+	t := c.Client.Publish(topic, qos, retained, payload)
+
+	c.stats.publishes.Add(1)
+
+	switch p := payload.(type) {
+	case []byte:
+		c.stats.bytes.Add(uint64(len(p)))
+	case string:
+		c.stats.bytes.Add(uint64(len(p)))
+	}
+
+	go func() {
+		<-t.Done()
+		if t.Error() != nil {
+			c.stats.failures.Add(1)
+		}
+	}()
+
+	return t
+}
+
+// countCommands returns h, counting each message it handles as a command.
+func (b *Bridge) countCommands(h mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		b.stats.commands.Add(1)
+		h(c, msg)
+	}
+}
+
+// Stats returns the counts of the traffic between the bridge and the broker.
+func (b *Bridge) Stats() Stats {
+	return b.stats.snapshot()
+}
+
+// loopStats publishes the stats of the bridge to the "bridge/stats" subtopic
+// of the base topic and logs them every d, until ctx is canceled.
+func (b *Bridge) loopStats(ctx context.Context, d time.Duration) {
+	tick := time.NewTicker(d)
+	defer tick.Stop()
+
+	topic := b.baseTopic + "/bridge/stats"
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		s := b.Stats()
+
+		log.Info("Bridge stats",
+			"publishes", s.Publishes,
+			"bytes", s.Bytes,
+			"failures", s.Failures,
+			"reconnects", s.Reconnects,
+			"commands", s.Commands,
+		)
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			log.Error("Could not encode stats", err)
+			continue
+		}
+
+		t := b.client.Publish(topic, 0, false, data)
+		if err := b.waitToken(ctx, t); err != nil {
+			log.WarnError("Unable to publish stats", err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// The health of the host in a [Summary].
+const (
+	healthOK       = "ok"
+	healthWarn     = "warn"
+	healthCritical = "critical"
+)
+
+// Summary is published to the "summary" subtopic of the base topic every
+// update interval if the summary is enabled, see [config.SummaryConfig]. Each
+// usage is missing if none of the metrics report it.
+type Summary struct {
+	// Health is the overall health of the host, either "ok", "warn" or
+	// "critical", by the worst of the usages compared to the thresholds.
+	Health string `json:"health"`
+	// Reasons are the usages at or above their "warn" threshold, by the names
+	// of their fields.
+	Reasons []string `json:"reasons,omitempty"`
+	// CPU is the usage of the CPU as a percent.
+	CPU payload.Optional[int] `json:"cpu,omitzero"`
+	// Memory is the used memory as a percent.
+	Memory payload.Optional[payload.Milli] `json:"memory,omitzero"`
+	// Temperature is the hottest temperature of the CPU and GPU in °C.
+	Temperature payload.Optional[payload.Milli] `json:"temperature,omitzero"`
+	// Disk is the used space of the fullest disk as a percent.
+	Disk payload.Optional[payload.Milli] `json:"disk,omitzero"`
+
+	temps []temperature
+}
+
+// temperature is the temperature of the CPU or a GPU, with the high and
+// critical limits reported by its hardware, which are 0 if not reported.
+type temperature struct {
+	value, high, crit payload.Milli
+}
+
+// addTemperature adds the temperature v of m to s.
+func (s *Summary) addTemperature(m metrics.Metric, v payload.Milli) {
+	maxOptional(&s.Temperature, v)
+
+	t := temperature{value: v}
+	if l, ok := m.(metrics.TemperatureLimiter); ok {
+		high, crit := l.TemperatureLimits()
+		t.high, t.crit = payload.Milli(high), payload.Milli(crit)
+	}
+
+	s.temps = append(s.temps, t)
+}
+
+// percent returns used as a percent of total in thousandths.
+func percent(used, total payload.Size) payload.Milli {
+	if total == 0 {
+		return 0
+	}
+
+	return payload.Milli(100_000 * float64(used) / float64(total))
+}
+
+// maxOptional sets o to v if it's greater, or if o isn't valid.
+func maxOptional(o *payload.Optional[payload.Milli], v payload.Milli) {
+	if !o.Valid || v > o.Value {
+		*o = payload.Some(v)
+	}
+}
+
+// summarize returns the summary of the payloads of ms with the health by cfg.
+// Metrics other than the cpu, memory, disks and gpu are ignored, as are any
+// whose payload can't be decoded.
+func summarize(ms []metrics.Metric, cfg *config.SummaryConfig) Summary {
+	var s Summary
+
+	for _, m := range ms {
+		data, err := m.MarshalJSON()
+		if err != nil {
+			continue
+		}
+
+		switch m.Type() {
+		case "cpu":
+			var p payload.CPU
+			if json.Unmarshal(data, &p) != nil {
+				continue
+			}
+
+			if p.Usage.Valid {
+				s.CPU = payload.Some(max(s.CPU.Value, p.Usage.Value))
+			}
+
+			if p.Temperature.Valid {
+				s.addTemperature(m, p.Temperature.Value)
+			}
+		case "memory":
+			var p payload.Memory
+			if json.Unmarshal(data, &p) != nil || p.Total == 0 {
+				continue
+			}
+
+			maxOptional(&s.Memory, percent(p.Used, p.Total))
+		case "disks":
+			var p payload.Disks
+			if json.Unmarshal(data, &p) != nil {
+				continue
+			}
+
+			for _, d := range p {
+				// The sizes of a stale disk may be long out of date.
+				if d.Stale || d.Total == 0 {
+					continue
+				}
+
+				maxOptional(&s.Disk, percent(d.Used, d.Total))
+			}
+		case "gpu":
+			var p payload.GPU
+			if json.Unmarshal(data, &p) != nil {
+				continue
+			}
+
+			if p.Temperature.Valid {
+				s.addTemperature(m, payload.Milli(p.Temperature.Value)*1000)
+			}
+		}
+	}
+
+	s.health(cfg)
+
+	return s
+}
+
+// health sets the health and reasons of s by the thresholds of cfg.
+func (s *Summary) health(cfg *config.SummaryConfig) {
+	s.Health = healthOK
+	s.Reasons = nil
+
+	check := func(name string, v payload.Milli, ok bool, warn, critical payload.Milli) {
+		switch {
+		case !ok:
+			return
+		case critical > 0 && v >= critical:
+			s.Health = healthCritical
+		case warn > 0 && v >= warn:
+			if s.Health == healthOK {
+				s.Health = healthWarn
+			}
+		default:
+			return
+		}
+
+		if !slices.Contains(s.Reasons, name) {
+			s.Reasons = append(s.Reasons, name)
+		}
+	}
+
+	threshold := func(v int) payload.Milli {
+		return payload.Milli(v) * 1000
+	}
+
+	check("cpu", payload.Milli(s.CPU.Value)*1000, s.CPU.Valid, threshold(cfg.Warn.CPU), threshold(cfg.Critical.CPU))
+	check("memory", s.Memory.Value, s.Memory.Valid, threshold(cfg.Warn.Memory), threshold(cfg.Critical.Memory))
+
+	// Each temperature is checked against the limits of its own hardware.
+	for _, t := range s.temps {
+		warn, critical := threshold(cfg.Warn.Temperature), threshold(cfg.Critical.Temperature)
+
+		if cfg.HardwareLimits && t.high > 0 {
+			warn = t.high
+		}
+
+		if cfg.HardwareLimits && t.crit > 0 {
+			critical = t.crit
+		}
+
+		check("temperature", t.value, true, warn, critical)
+	}
+
+	check("disk", s.Disk.Value, s.Disk.Valid, threshold(cfg.Warn.Disk), threshold(cfg.Critical.Disk))
+}
+
+// summaryTopic returns the topic the summary is published to.
+func (b *Bridge) summaryTopic() string {
+	return b.baseTopic + "/summary"
+}
+
+// loopSummary publishes the summary of the metrics of the bridge every d,
+// until ctx is canceled.
+func (b *Bridge) loopSummary(ctx context.Context, d time.Duration) {
+	defer b.wg.Done()
+
+	tick := time.NewTicker(d)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		b.publishSummary(ctx)
+	}
+}
+
+// publishSummary publishes the summary of the metrics of the bridge, sealed
+// like the payloads of the metrics.
+func (b *Bridge) publishSummary(ctx context.Context) {
+	data, err := json.Marshal(summarize(b.Metrics(), b.summary))
+	if err == nil {
+		data, err = b.seal(b.summaryTopic(), data)
+	}
+
+	if err != nil {
+		log.Error("Could not encode summary", err)
+		return
+	}
+
+	t := b.client.Publish(b.summaryTopic(), 0, false, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.WarnError("Unable to publish summary", err)
+	}
+}
+
+// discoverSummary adds a sensor for the health of the host, with the summary
+// as its attributes.
+func (b *Bridge) discoverSummary(d *discovery.Discovery, cmps []string) []string {
+	id := d.ID("health")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Health",
+		discovery.Icon:                 icon.Alert,
+		discovery.DeviceClass:          "enum",
+		discovery.Options:              []string{healthOK, healthWarn, healthCritical},
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+		discovery.StateTopic:           b.summaryTopic(),
+		discovery.ValueTemplate:        "{{ value_json.health }}",
+		discovery.JSONAttributesTopic:  b.summaryTopic(),
+		discovery.UniqueID:             id,
+	}
+
+	return cmps
+}
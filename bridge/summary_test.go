@@ -0,0 +1,168 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/encrypt"
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/mock"
+	"github.com/lone-faerie/mqttop/sign"
+)
+
+// payloadMetric is a metric of the type typ whose payload is data.
+type payloadMetric struct {
+	metrics.Metric
+	typ  string
+	data string
+}
+
+func (m *payloadMetric) Type() string {
+	return m.typ
+}
+
+func (m *payloadMetric) MarshalJSON() ([]byte, error) {
+	return []byte(m.data), nil
+}
+
+func (m *payloadMetric) AppendText(b []byte) ([]byte, error) {
+	return append(b, m.data...), nil
+}
+
+// limitMetric is a payloadMetric whose temperature has limits.
+type limitMetric struct {
+	payloadMetric
+	high, crit int64
+}
+
+func (m *limitMetric) TemperatureLimits() (high, crit int64) {
+	return m.high, m.crit
+}
+
+func TestSummarize(t *testing.T) {
+	ms := []metrics.Metric{
+		&payloadMetric{typ: "cpu", data: `{"name": "cpu", "temperature": 81, "usage": 12}`},
+		&payloadMetric{typ: "memory", data: `{"total": 16, "used": 4, "available": 12, "cached": 2, "free": 10}`},
+		&payloadMetric{typ: "disks", data: `{"root": {"mnt": "/", "total": 200, "free": 20, "used": 180}, "share": {"mnt": "/mnt/share", "total": 1, "free": 0, "used": 1, "stale": true}}`},
+		&payloadMetric{typ: "gpu", data: `{"name": "gpu", "temperature": 60}`},
+		&payloadMetric{typ: "idle", data: `{"idle": 42, "active": true}`},
+	}
+
+	cfg := config.DefaultSummary
+
+	s := summarize(ms, &cfg)
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The disk is over the warn threshold of 85%, and the temperature of the
+	// CPU, which is hotter than the GPU, is over 80°C. The stale disk is
+	// ignored.
+	want := `{"health":"warn","reasons":["temperature","disk"],"cpu":12,"memory":25,"temperature":81,"disk":90}`
+	if got := string(b); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+
+	cfg.Critical.Disk = 90
+	cfg.Warn.Temperature = 0
+
+	s = summarize(ms, &cfg)
+	if want := healthCritical; s.Health != want {
+		t.Errorf("Health: want %q, got %q", want, s.Health)
+	}
+	if want := []string{"disk"}; !slices.Equal(s.Reasons, want) {
+		t.Errorf("Reasons: want %v, got %v", want, s.Reasons)
+	}
+
+	// The CPU is below its own limits, while the GPU is over its high limit.
+	ms = []metrics.Metric{
+		&limitMetric{payloadMetric{typ: "cpu", data: `{"name": "cpu", "temperature": 81}`}, 100_000, 105_000},
+		&limitMetric{payloadMetric{typ: "gpu", data: `{"name": "gpu", "temperature": 70}`}, 65_000, 0},
+	}
+	cfg = config.DefaultSummary
+
+	s = summarize(ms, &cfg)
+	if want := healthWarn; s.Health != want {
+		t.Errorf("Limits: Health: want %q, got %q", want, s.Health)
+	}
+	if want := []string{"temperature"}; !slices.Equal(s.Reasons, want) {
+		t.Errorf("Limits: Reasons: want %v, got %v", want, s.Reasons)
+	}
+
+	ms[1].(*limitMetric).high = 0
+
+	if s = summarize(ms, &cfg); s.Health != healthOK {
+		t.Errorf("Limits: Health: want %q, got %q", healthOK, s.Health)
+	}
+
+	cfg.HardwareLimits = false
+
+	if s = summarize(ms, &cfg); s.Health != healthWarn {
+		t.Errorf("No limits: Health: want %q, got %q", healthWarn, s.Health)
+	}
+
+	s = summarize(nil, &cfg)
+	if b, _ := json.Marshal(s); string(b) != `{"health":"ok"}` {
+		t.Errorf("no metrics: want ok, got %s", b)
+	}
+}
+
+func TestPublishSummary(t *testing.T) {
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := encrypt.NewEncrypter(encrypt.FormatPublicKey(key.Recipient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	cfg := config.DefaultSummary
+	b := &Bridge{
+		client:    mock.NewMockClient(mqtt.NewClientOptions(), &buf),
+		baseTopic: "mqttop",
+		metrics:   []metrics.Metric{&payloadMetric{typ: "cpu", data: `{"usage": 12}`}},
+		summary:   &cfg,
+		encrypter: e,
+		signer:    sign.NewHMAC([]byte("secret")),
+	}
+
+	b.publishSummary(context.Background())
+
+	var msg map[string]json.RawMessage
+	if err := json.NewDecoder(&buf).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mock client indents the payload, which was published compacted
+	var payload bytes.Buffer
+
+	if err := json.Compact(&payload, msg["mqttop/summary"]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The summary is encrypted, then signed, like the payloads of the metrics
+	data, _, err := sign.NewHMACVerifier([]byte("secret")).Verify("mqttop/summary", payload.Bytes())
+	if err != nil {
+		t.Fatalf("Verify: %v: %s", err, payload.Bytes())
+	}
+
+	if data, err = encrypt.Decrypt(key, "mqttop/summary", data); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"health":"ok","cpu":12}`; string(data) != want {
+		t.Errorf("want %s, got %s", want, data)
+	}
+}
@@ -0,0 +1,128 @@
+package bridge
+
+import (
+	"context"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// traceShutdownTimeout is how long to wait for the remaining spans to be
+// exported once the bridge stops.
+const traceShutdownTimeout = 5 * time.Second
+
+// tick is an update of a metric to be published by the loop of the bridge,
+// along with the root span of its trace.
+type tick struct {
+	m    metrics.Metric
+	span trace.Span
+}
+
+// endSpan ends span, recording err unless it's nil, [metrics.ErrNoChange] or
+// [metrics.ErrRescanned].
+func endSpan(span trace.Span, err error, opts ...trace.SpanEndOption) {
+	switch err {
+	case nil, metrics.ErrNoChange, metrics.ErrRescanned:
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End(opts...)
+}
+
+// end ends the trace of t.
+func (t tick) end(err error) {
+	endSpan(t.span, err)
+}
+
+// startTick returns the tick of the last update of m, which ended with err. Its
+// trace starts with a span of the update, since the metric updates itself.
+func (b *Bridge) startTick(ctx context.Context, m metrics.Metric, err error) tick {
+	start, end, ok := metrics.LastUpdate(m)
+	if !ok {
+		start = time.Now()
+		end = start
+	}
+
+	ctx, span := b.tracer.Start(ctx, m.Type(),
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			attribute.String("mqttop.metric", m.Type()),
+			attribute.String("messaging.destination.name", m.Topic()),
+		),
+	)
+
+	_, update := b.tracer.Start(ctx, "update", trace.WithTimestamp(start))
+	endSpan(update, err, trace.WithTimestamp(end))
+
+	return tick{m: m, span: span}
+}
+
+// send sends the update of m, which ended with err, to be published by the
+// loop of the bridge.
+func (b *Bridge) send(ctx context.Context, m metrics.Metric, err error) {
+	b.sendTick(ctx, b.startTick(ctx, m, err))
+}
+
+// sendTick sends t to be published by the loop of the bridge, ending its trace
+// if it can't be.
+func (b *Bridge) sendTick(ctx context.Context, t tick) {
+	if !maybeSend(ctx, b.updates, t) {
+		t.end(ctx.Err())
+	}
+}
+
+// publishTick serializes and publishes the metric of t, tracing each, and
+// returns the token of the publish, or nil if it couldn't be serialized.
+func (b *Bridge) publishTick(ctx context.Context, t tick) mqtt.Token {
+	ctx = trace.ContextWithSpan(ctx, t.span)
+
+	_, span := b.tracer.Start(ctx, "marshal")
+
+	data, err := b.marshal(t.m)
+	span.SetAttributes(attribute.Int("messaging.message.body.size", len(data)))
+	endSpan(span, err)
+
+	if err != nil {
+		log.WarnError("Unable to marshal "+t.m.Type(), err)
+		t.end(err)
+
+		return nil
+	}
+
+	_, span = b.tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer))
+
+	pt := b.client.Publish(t.m.Topic(), 0, false, data)
+
+	if span.IsRecording() {
+		go func() {
+			<-pt.Done()
+			endSpan(span, pt.Error())
+			t.end(nil)
+		}()
+	}
+
+	return pt
+}
+
+// shutdownTracing exports the remaining spans of the bridge, if traced.
+func (b *Bridge) shutdownTracing() {
+	if b.tp == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), traceShutdownTimeout)
+	defer cancel()
+
+	if err := b.tp.Shutdown(ctx); err != nil {
+		log.WarnError("Unable to export traces", err)
+	}
+}
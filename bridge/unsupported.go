@@ -0,0 +1,64 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// unsupportedTopic returns the topic the report of unsupported metrics is
+// published to.
+func (b *Bridge) unsupportedTopic() string {
+	return b.baseTopic + "/bridge/unsupported"
+}
+
+// publishUnsupported publishes the metrics that couldn't be created retained
+// to the "bridge/unsupported" subtopic of the base topic, as a JSON object
+// keyed by the type of each metric, or the path of a directory. The object is
+// empty if every metric was created, which replaces any earlier report.
+func (b *Bridge) publishUnsupported(ctx context.Context) {
+	report := make(map[string]metrics.Unsupported, len(b.unsupported))
+
+	for _, u := range b.unsupported {
+		report[u.Metric] = u
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Error("Could not encode unsupported metrics", err)
+		return
+	}
+
+	t := b.client.Publish(b.unsupportedTopic(), 0, true, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.Error("Could not publish unsupported metrics", err)
+	}
+}
+
+// discoverUnsupported adds a diagnostic sensor for the number of unsupported
+// metrics, with the report as its attributes.
+func (b *Bridge) discoverUnsupported(d *discovery.Discovery, cmps []string) []string {
+	id := d.ID("unsupported")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Unsupported metrics",
+		discovery.Icon:                 icon.Alert,
+		discovery.EntityCategory:       discovery.Diagnostic,
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+		discovery.StateTopic:           b.unsupportedTopic(),
+		discovery.ValueTemplate:        "{{ value_json | length }}",
+		discovery.JSONAttributesTopic:  b.unsupportedTopic(),
+		discovery.UniqueID:             id,
+	}
+
+	return cmps
+}
@@ -0,0 +1,223 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// watchdogPeriod is how often the watchdog checks for stuck metrics.
+var watchdogPeriod = time.Second
+
+// The reasons a metric is restarted by the watchdog.
+const (
+	// reasonStuck is the reason of a metric that hasn't updated in the
+	// missed intervals of the watchdog.
+	reasonStuck = "stuck"
+	// reasonStopped is the reason of a metric whose updates stopped without
+	// it being stopped by the bridge.
+	reasonStopped = "stopped"
+)
+
+// WatchdogEvent is published to the "bridge/watchdog" subtopic of the base
+// topic each time the watchdog restarts a metric.
+type WatchdogEvent struct {
+	// Metric is the type of the metric.
+	Metric string `json:"metric"`
+	// Topic is the topic of the metric.
+	Topic string `json:"topic"`
+	// Reason is why the metric was restarted, either "stuck" if it hasn't
+	// updated in the missed intervals of the watchdog, or "stopped" if its
+	// updates stopped unexpectedly.
+	Reason string `json:"reason"`
+	// Restarted indicates if the metric was restarted, otherwise Error is
+	// why it couldn't be and the metric is gone until the bridge restarts.
+	Restarted bool   `json:"restarted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// watchdog tracks the last update of each running metric of the bridge.
+type watchdog struct {
+	missed int
+
+	mu   sync.Mutex
+	last map[metrics.Metric]time.Time
+}
+
+func newWatchdog(missed int) *watchdog {
+	return &watchdog{
+		missed: missed,
+		last:   make(map[metrics.Metric]time.Time),
+	}
+}
+
+// watch starts tracking m as if it updated now.
+func (w *watchdog) watch(m metrics.Metric) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	w.last[m] = time.Now()
+	w.mu.Unlock()
+}
+
+// touch records an update of m now, if m is tracked.
+func (w *watchdog) touch(m metrics.Metric) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	if _, ok := w.last[m]; ok {
+		w.last[m] = time.Now()
+	}
+	w.mu.Unlock()
+}
+
+// forget stops tracking m, such as when it is stopped by the bridge, and
+// returns whether it was being tracked.
+func (w *watchdog) forget(m metrics.Metric) bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, ok := w.last[m]
+	delete(w.last, m)
+
+	return ok
+}
+
+// stuck returns the tracked metrics that haven't updated in the missed
+// intervals as of now. Metrics that don't implement [metrics.Intervaler] are
+// only restarted if their updates stop.
+func (w *watchdog) stuck(now time.Time) (stuck []metrics.Metric) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for m, last := range w.last {
+		im, ok := m.(metrics.Intervaler)
+		if !ok {
+			continue
+		}
+
+		d := im.Interval()
+		if d <= 0 {
+			continue
+		}
+
+		if now.Sub(last) > time.Duration(w.missed)*d {
+			stuck = append(stuck, m)
+		}
+	}
+
+	return
+}
+
+// watchdogTopic returns the topic the events of the watchdog are published to.
+func (b *Bridge) watchdogTopic() string {
+	return b.baseTopic + "/bridge/watchdog"
+}
+
+// loopWatchdog restarts any metric that is stuck every [watchdogPeriod], until
+// ctx is canceled.
+func (b *Bridge) loopWatchdog(ctx context.Context) {
+	defer b.wg.Done()
+
+	tick := time.NewTicker(watchdogPeriod)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			for _, m := range b.watchdog.stuck(now) {
+				b.restartMetric(ctx, m, reasonStuck)
+			}
+		}
+	}
+}
+
+// restartMetric replaces m with a new metric renewed from the config of the
+// bridge and starts it, publishing a [WatchdogEvent]. The old metric is
+// stopped in the background, since it may be wedged. If m was already
+// restarted or stopped by the bridge, nothing is done.
+func (b *Bridge) restartMetric(ctx context.Context, m metrics.Metric, reason string) {
+	if ctxDone(ctx) || !b.watchdog.forget(m) {
+		return
+	}
+
+	log.Warn("Restarting "+m.Type(), "topic", m.Topic(), "reason", reason)
+
+	go m.Stop()
+
+	event := WatchdogEvent{
+		Metric: m.Type(),
+		Topic:  m.Topic(),
+		Reason: reason,
+	}
+
+	defer b.publishWatchdogEvent(ctx, &event)
+
+	mm, err := metrics.Renew(m, b.cfg)
+	if err != nil {
+		log.Error("Could not restart "+m.Type(), err)
+		event.Error = err.Error()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := slices.Index(b.metrics, m)
+	if i < 0 {
+		// m was removed while it was being renewed.
+		if mm != nil {
+			mm.Stop()
+		}
+
+		event.Error = "metric removed"
+
+		return
+	}
+
+	if mm == nil {
+		b.metrics[i] = nil
+		b.states.Store(m.Topic(), false)
+
+		t := b.publishStates(false)
+		if err := b.waitToken(ctx, t); err != nil {
+			log.WarnError("Unable to publish states", err)
+		}
+
+		return
+	}
+
+	b.metrics[i] = mm
+	b.startMetric(ctx, i, mm, false)
+
+	event.Restarted = true
+}
+
+// publishWatchdogEvent publishes e to the "bridge/watchdog" subtopic of the
+// base topic.
+func (b *Bridge) publishWatchdogEvent(ctx context.Context, e *WatchdogEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Error("Could not encode watchdog event", err)
+		return
+	}
+
+	t := b.client.Publish(b.watchdogTopic(), 0, false, data)
+	if err := b.waitToken(ctx, t); err != nil {
+		log.WarnError("Unable to publish watchdog event", err)
+	}
+}
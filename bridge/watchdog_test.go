@@ -0,0 +1,51 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+type intervalMetric struct {
+	metrics.Metric
+	interval time.Duration
+}
+
+func (m *intervalMetric) Interval() time.Duration {
+	return m.interval
+}
+
+func TestWatchdog_Stuck(t *testing.T) {
+	var (
+		fast    = &intervalMetric{interval: time.Second}
+		slow    = &intervalMetric{interval: time.Minute}
+		watched = &intervalMetric{}
+	)
+
+	w := newWatchdog(3)
+	w.watch(fast)
+	w.watch(slow)
+	w.watch(watched)
+
+	now := time.Now().Add(10 * time.Second)
+
+	stuck := w.stuck(now)
+	if len(stuck) != 1 || stuck[0] != fast {
+		t.Errorf("stuck: want [fast], got %v", stuck)
+	}
+
+	if !w.forget(fast) {
+		t.Error("forget: want tracked")
+	}
+
+	w.touch(fast)
+
+	if stuck := w.stuck(now); len(stuck) != 0 {
+		t.Errorf("stuck after forget: want none, got %v", stuck)
+	}
+
+	if w.forget(fast) {
+		t.Error("forget: want untracked after touch")
+	}
+}
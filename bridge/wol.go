@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/discovery/icon"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+const defaultWOLBroadcast = "255.255.255.255:9"
+
+// wolTarget is a Wake-on-LAN target that may be woken through the bridge.
+type wolTarget struct {
+	name      string
+	mac       net.HardwareAddr
+	broadcast string
+}
+
+// newWOL returns the valid targets of cfg. Any target with an invalid MAC
+// address is ignored.
+func newWOL(cfg []config.WOLConfig) []wolTarget {
+	var targets []wolTarget
+
+	for i := range cfg {
+		mac, err := net.ParseMAC(cfg[i].MAC)
+		if err != nil || len(mac) != 6 {
+			log.Warn("Invalid Wake-on-LAN MAC address, ignoring", "mac", cfg[i].MAC)
+			continue
+		}
+
+		t := wolTarget{
+			name:      cfg[i].Name,
+			mac:       mac,
+			broadcast: cfg[i].Broadcast,
+		}
+
+		if t.name == "" {
+			t.name = mac.String()
+		}
+
+		switch {
+		case t.broadcast == "":
+			t.broadcast = defaultWOLBroadcast
+		case !strings.Contains(t.broadcast, ":"):
+			t.broadcast = net.JoinHostPort(t.broadcast, "9")
+		}
+
+		targets = append(targets, t)
+	}
+
+	return targets
+}
+
+// magicPacket returns the Wake-on-LAN magic packet of mac, which is 6 bytes of
+// 0xFF followed by 16 repetitions of mac.
+func magicPacket(mac net.HardwareAddr) []byte {
+	p := bytes.Repeat([]byte{0xFF}, 6)
+
+	for range 16 {
+		p = append(p, mac...)
+	}
+
+	return p
+}
+
+// wake sends the magic packet of t to its broadcast address.
+func (t *wolTarget) wake() error {
+	conn, err := net.Dial("udp", t.broadcast)
+	if err != nil {
+		return err
+	}
+
+	if _, err = conn.Write(magicPacket(t.mac)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	return conn.Close()
+}
+
+// findWOL returns the target with the name or MAC address in payload.
+func findWOL(targets []wolTarget, payload []byte) (*wolTarget, error) {
+	s := string(bytes.TrimSpace(payload))
+	mac, _ := net.ParseMAC(s)
+
+	for i := range targets {
+		if targets[i].name == s || (mac != nil && bytes.Equal(targets[i].mac, mac)) {
+			return &targets[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown Wake-on-LAN target %q", s)
+}
+
+func (b *Bridge) handleWOL(ctx context.Context, msg mqtt.Message) {
+	t, err := findWOL(b.wol, msg.Payload())
+	if err == nil {
+		log.Info("Sending Wake-on-LAN packet", "target", t.name, "mac", t.mac)
+		err = t.wake()
+	}
+
+	if err != nil {
+		log.Error("Unable to send Wake-on-LAN packet", err)
+	}
+
+	b.reply(ctx, msg, StatusOK, err)
+}
+
+// discoverWOL adds a button for each of the Wake-on-LAN targets.
+func (b *Bridge) discoverWOL(d *discovery.Discovery, cmps []string) []string {
+	for i := range b.wol {
+		t := &b.wol[i]
+
+		id := d.ID("wol_" + strings.ReplaceAll(t.mac.String(), ":", ""))
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Button,
+			discovery.Name:                 "Wake " + t.name,
+			discovery.Icon:                 icon.Power,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: d.AvailabilityTemplate(""),
+			discovery.CommandTopic:         b.baseTopic + "/bridge/wol",
+			discovery.PayloadPress:         t.name,
+			discovery.UniqueID:             id,
+		}
+	}
+
+	return cmps
+}
@@ -0,0 +1,71 @@
+package bridge
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestWOL(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	targets := newWOL([]config.WOLConfig{
+		{Name: "desktop", MAC: "01:23:45:67:89:ab", Broadcast: conn.LocalAddr().String()},
+		{MAC: "01-23-45-67-89-cd", Broadcast: "192.168.1.255"},
+		{Name: "invalid", MAC: "01:23:45"},
+	})
+
+	if want, got := 2, len(targets); got != want {
+		t.Fatalf("Targets: want %d, got %d", want, got)
+	}
+	if want, got := "01:23:45:67:89:cd", targets[1].name; got != want {
+		t.Errorf("Name: want %q, got %q", want, got)
+	}
+	if want, got := "192.168.1.255:9", targets[1].broadcast; got != want {
+		t.Errorf("Broadcast: want %q, got %q", want, got)
+	}
+
+	var tests = []struct {
+		payload string
+		want    string
+		err     bool
+	}{
+		{"desktop", "desktop", false},
+		{"01:23:45:67:89:AB\n", "desktop", false},
+		{"01:23:45:67:89:cd", "01:23:45:67:89:cd", false},
+		{"laptop", "", true},
+	}
+	for _, tt := range tests {
+		target, err := findWOL(targets, []byte(tt.payload))
+		if (err != nil) != tt.err {
+			t.Errorf("%q: Wanted error %v, got %v", tt.payload, tt.err, err)
+		}
+		if err == nil && target.name != tt.want {
+			t.Errorf("%q: Wanted %q, got %q", tt.payload, tt.want, target.name)
+		}
+	}
+
+	if err := targets[0].wake(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(bytes.Repeat([]byte{0xFF}, 6), bytes.Repeat(targets[0].mac, 16)...)
+	if got := buf[:n]; !bytes.Equal(got, want) {
+		t.Errorf("Packet: want %x, got %x", want, got)
+	}
+}
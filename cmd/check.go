@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/bridge"
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// Flags for mqttop check broker
+var (
+	CheckTimeout time.Duration // Time to wait for test messages
+)
+
+// NewCmdCheck returns the [cobra.Command] used for checking the setup of the bridge.
+//
+// Usage:
+//
+//	mqttop check [command]
+//
+// Available Commands:
+//
+//	broker      Check the ACL permissions of the broker
+//
+// Flags:
+//
+//	-h, --help   help for check
+func NewCmdCheck() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the setup of the bridge",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdCheckBroker())
+
+	return cmd
+}
+
+// NewCmdCheckBroker returns the [cobra.Command] used for checking that the
+// bridge is allowed to publish and subscribe to every topic it uses.
+//
+// The topics are those of the enabled metrics, the commands, and discovery. The
+// permissions of each topic are printed, and an error is returned if any are
+// missing. Publishing is checked with a test message on a subtopic, so nothing
+// is published to the topics themselves.
+//
+// Usage:
+//
+//	mqttop check broker [flags] [metrics...]
+//
+// Flags:
+//
+//	-c, --config strings     Path(s) to config file/directory
+//	    --profile strings    Config profile(s) to activate
+//	-b, --broker string      MQTT broker address
+//	-p, --port int           MQTT broker port, if not in the address (default 1883, or 8883 for ssl)
+//	    --username string    MQTT client username
+//	    --password string    MQTT client password
+//	-t, --timeout duration   Time to wait for test messages (default 2s)
+//	-h, --help               help for broker
+func NewCmdCheckBroker() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "broker [flags] [metrics...]",
+		Short: "Check the ACL permissions of the broker",
+		Long: `Check that the bridge is allowed to publish and subscribe to every topic it
+uses, including the topics of the metrics, the commands, and discovery.
+
+Brokers such as Mosquitto silently drop messages published to a topic denied by
+their ACL, so publishing is checked by subscribing to a subtopic of each topic,
+such as "mqttop/metric/cpu/mqttop_check", and waiting for a test message
+published to it. Nothing is published to the topics themselves. If subscribing
+to the subtopic is denied, publishing can't be verified.
+
+The client id of the config is suffixed with "_check", so that a running bridge
+isn't disconnected.`,
+		Example: `  mqttop check broker
+  mqttop check broker --config /etc/mqttop.yaml cpu net`,
+		ValidArgs: []cobra.Completion{
+			cobra.CompletionWithDesc("all", "all metrics"),
+			"cpu", "memory", "disks", "net", "battery", "fans", "audio", "idle", "processes", "system", "smart", "energy", "dirs", "gpu",
+		},
+		Args: cobra.OnlyValidArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			log.SetLogLevel(log.LevelWarn)
+			cfg, err = loadConfig()
+			if err != nil {
+				return exitError(err, cmdutil.ExitConfig)
+			}
+
+			if err = flagsToConfig(cfg, args); err != nil {
+				return exitError(err, cmdutil.ExitConfig)
+			}
+
+			setLogHandler(cfg, log.LevelWarn)
+
+			return nil
+		},
+		RunE: checkBroker,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
+	cmd.Flags().StringVarP(&Broker, "broker", "b", "", "MQTT broker address")
+	cmd.Flags().IntVarP(&Port, "port", "p", 0, "MQTT broker port, if not in the address (default 1883, or 8883 for ssl)")
+	cmd.Flags().StringVar(&Username, "username", "", "MQTT client username")
+	cmd.Flags().StringVar(&Password, "password", "", "MQTT client password")
+	cmd.Flags().DurationVarP(&CheckTimeout, "timeout", "t", 2*time.Second, "Time to wait for test messages")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+	cmd.MarkFlagDirname("config")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func checkBroker(cmd *cobra.Command, _ []string) error {
+	m := metrics.New(cfg)
+	defer metrics.Stop(m...)
+
+	// The will topic is still checked, but the will must not be published
+	// if the check is interrupted.
+	opts := auxClientOptions(cfg, "_check")
+
+	bopts := []bridge.Option{
+		bridge.WithClient(mqtt.NewClient(opts)),
+		bridge.WithMetrics(m...),
+	}
+
+	if cfg.Discovery.Enabled {
+		d, err := discovery.New(&cfg.Discovery)
+		if err != nil {
+			return err
+		}
+
+		for _, mm := range m {
+			if dd, ok := mm.(discovery.Discoverer); ok {
+				dd.Discover(d)
+			}
+		}
+
+		bopts = append(bopts, bridge.WithDiscovery(d, false))
+	}
+
+	checks, err := bridge.New(cfg, bopts...).Check(cmd.Context(), CheckTimeout)
+	if err != nil {
+		return exitError(err, cmdutil.ExitBroker)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tNEEDS\tSTATUS")
+
+	missing := 0
+
+	for _, c := range checks {
+		status := "ok"
+
+		switch {
+		case c.Denied != 0 && c.Unknown != 0:
+			status = "denied: " + c.Denied.String() + ", unverified: " + c.Unknown.String()
+		case c.Denied != 0:
+			status = "denied: " + c.Denied.String()
+		case c.Unknown != 0:
+			status = "unverified: " + c.Unknown.String()
+		}
+
+		if !c.OK() {
+			missing++
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Topic, c.Want, status)
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d of %d topics are missing permissions", missing, len(checks))
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package cmdutil provides the helpers shared by the commands of mqttop, for
+// reuse by custom builds that embed the command line, such as builds with extra
+// metrics.
+//
+// Commands register cleanup functions with [AddCleanup], which are run however
+// the command executed by [Execute] exits, and return an [ExitError] to exit
+// with one of the exit codes, such as [ExitConfig]:
+//
+//	root := cmd.NewCmdRoot()
+//	root.AddCommand(newCmdCustom())
+//
+//	if _, err := cmdutil.Execute(context.Background(), root); err != nil {
+//		fmt.Fprintln(os.Stderr, "Error:", err)
+//		os.Exit(cmdutil.ExitCode(err))
+//	}
+package cmdutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/internal/build"
+)
+
+// ConfigPaths returns the default path(s) to config files, which is the first
+// defined of the comma-separated list $MQTTOP_CONFIG_PATH,
+// $XDG_CONFIG_HOME/mqttop.yaml or $HOME/.config/mqttop.yaml.
+func ConfigPaths() ([]string, error) {
+	const defaultConfigFile = "mqttop.yaml"
+
+	if env, ok := os.LookupEnv("MQTTOP_CONFIG_PATH"); ok {
+		return strings.Split(env, ","), nil
+	}
+
+	if xdg, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
+		return []string{filepath.Join(xdg, defaultConfigFile)}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filepath.Join(home, ".config", defaultConfigFile)}, nil
+}
+
+// DataPath returns the default path to the data directory, which is the first
+// defined of $MQTTOP_DATA_PATH, $XDG_DATA_HOME/mqttop or
+// $HOME/.local/share/mqttop.
+func DataPath() (string, error) {
+	const defaultDataDir = "mqttop"
+
+	if env, ok := os.LookupEnv("MQTTOP_DATA_PATH"); ok {
+		return env, nil
+	}
+
+	if xdg, ok := os.LookupEnv("XDG_DATA_HOME"); ok {
+		return filepath.Join(xdg, defaultDataDir), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", defaultDataDir), nil
+}
+
+const banner = `┌────────────────────────────────────────────────────────────┐
+│                                                            │
+│   ███╗   ███╗ ██████╗ ████████╗████████╗ ██████╗ ██████╗   │
+│   ████╗ ████║██╔═══██╗╚══██╔══╝╚══██╔══╝██╔═══██╗██╔══██╗  │
+│   ██╔████╔██║██║   ██║   ██║      ██║   ██║   ██║██████╔╝  │
+│   ██║╚██╔╝██║██║▄▄ ██║   ██║      ██║   ██║   ██║██╔═══╝   │
+│   ██║ ╚═╝ ██║╚██████╔╝   ██║      ██║   ╚██████╔╝██║       │
+│   ╚═╝     ╚═╝ ╚══▀▀═╝    ╚═╝      ╚═╝    ╚═════╝ ╚═╝       │
+│                                                            │
+│     Author: lone-faerie                                    │
+│                                                            │
+│     Version: {{printf "%%-18.18s" .Version}}                            │
+│     Build Time: %-26.26s                 │
+│                                                            │
+└────────────────────────────────────────────────────────────┘
+`
+
+// BannerTemplate returns the string used for templating the banner, which is
+// executed with the root [cobra.Command].
+func BannerTemplate() string {
+	return fmt.Sprintf(banner, build.BuildTime())
+}
+
+// PrintBanner prints the banner to the given commands output.
+func PrintBanner(cmd *cobra.Command) error {
+	t := template.New("banner")
+
+	template.Must(t.Parse(BannerTemplate()))
+
+	return t.Execute(cmd.OutOrStdout(), cmd.Root())
+}
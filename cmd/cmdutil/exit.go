@@ -0,0 +1,100 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The exit codes of the commands. These are stable, so that supervisors and
+// scripts may branch on the reason the program exited. A process exited by a
+// signal has the code 128 plus the signal number.
+const (
+	ExitOK         = 0  // Success
+	ExitFailure    = 1  // Any error without a more specific code
+	ExitConfig     = 2  // The config is invalid or can't be read
+	ExitBroker     = 3  // The broker is unreachable or refused the connection
+	ExitNoMetrics  = 4  // None of the enabled metrics are supported
+	ExitRunning    = 5  // The bridge of the instance is already running
+	ExitNotRunning = 6  // The bridge of the instance isn't running
+	ExitUsage      = 64 // The flags or arguments are invalid
+)
+
+var exitKinds = map[int]string{
+	ExitOK:         "ok",
+	ExitFailure:    "failure",
+	ExitConfig:     "config",
+	ExitBroker:     "broker",
+	ExitNoMetrics:  "no_metrics",
+	ExitRunning:    "running",
+	ExitNotRunning: "not_running",
+	ExitUsage:      "usage",
+}
+
+// ExitKind returns the name of the exit code, such as "config" for
+// [ExitConfig], or "signal" for a code greater than 128.
+func ExitKind(code int) string {
+	if kind, ok := exitKinds[code]; ok {
+		return kind
+	} else if code > 128 {
+		return "signal"
+	}
+
+	return exitKinds[ExitFailure]
+}
+
+// ExitError is an error that should cause the program to exit with the given code.
+type ExitError struct {
+	Err  error
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the code the program should exit with for err, which is the
+// code of the first [ExitError] in the tree of err, 0 if err is nil, or
+// [ExitFailure].
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+
+	return ExitFailure
+}
+
+// JSONError is the machine-readable form of an error, as written by
+// [WriteJSONError].
+type JSONError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+	Kind  string `json:"kind"`
+}
+
+// WriteJSONError writes err to w as a [JSONError] on a single line, such as:
+//
+//	{"error":"dial tcp 127.0.0.1:1883: connect: connection refused","code":3,"kind":"broker"}
+func WriteJSONError(w io.Writer, err error) error {
+	code := ExitCode(err)
+
+	return json.NewEncoder(w).Encode(JSONError{
+		Error: err.Error(),
+		Code:  code,
+		Kind:  ExitKind(code),
+	})
+}
@@ -0,0 +1,61 @@
+package cmdutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	errBroker := &ExitError{Err: errors.New("connection refused"), Code: ExitBroker}
+
+	var tests = []struct {
+		name string
+		err  error
+		code int
+		kind string
+	}{
+		{"Nil", nil, ExitOK, "ok"},
+		{"Error", errors.New("failed"), ExitFailure, "failure"},
+		{"ExitError", errBroker, ExitBroker, "broker"},
+		{"Wrapped", fmt.Errorf("run: %w", errBroker), ExitBroker, "broker"},
+		{"Signal", &ExitError{Code: 130}, 130, "signal"},
+		{"Unknown", &ExitError{Code: 42}, 42, "failure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := ExitCode(tt.err)
+			if code != tt.code {
+				t.Errorf("ExitCode: want %d, got %d", tt.code, code)
+			}
+			if kind := ExitKind(code); kind != tt.kind {
+				t.Errorf("ExitKind: want %q, got %q", tt.kind, kind)
+			}
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := fmt.Errorf("load config: %w", &ExitError{Err: errors.New("invalid yaml"), Code: ExitConfig})
+	if werr := WriteJSONError(&buf, err); werr != nil {
+		t.Fatal(werr)
+	}
+
+	if n := bytes.Count(buf.Bytes(), []byte{'\n'}); n != 1 {
+		t.Errorf("want 1 line, got %d: %q", n, buf.String())
+	}
+
+	var got JSONError
+	if jerr := json.Unmarshal(buf.Bytes(), &got); jerr != nil {
+		t.Fatal(jerr)
+	}
+
+	want := JSONError{Error: "load config: invalid yaml", Code: ExitConfig, Kind: "config"}
+	if got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,170 @@
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// Lifecycle runs the cleanup functions of a command, such as closing the log
+// file or saving the discovery, however the command exits. This includes
+// returning an error, such as an [ExitError], and receiving SIGINT or SIGTERM.
+// The cleanup functions are run once, in the reverse order they were added.
+type Lifecycle struct {
+	mu       sync.Mutex
+	cleanup  []func()
+	done     bool
+	graceful bool
+
+	// exit is called with the exit code after the cleanup if the process must
+	// exit because of a signal. If nil, os.Exit is called.
+	exit func(code int)
+}
+
+// std is the [Lifecycle] used by the package-level functions.
+var std = &Lifecycle{}
+
+// AddCleanup adds function(s) to be run when the command executed by [Execute]
+// exits, in the reverse order they were added. See [Lifecycle.Add].
+func AddCleanup(f ...func()) {
+	std.Add(f...)
+}
+
+// Graceful indicates that the command executed by [Execute] returns on its own
+// once its context is canceled by a signal. See [Lifecycle.Graceful].
+func Graceful() {
+	std.Graceful()
+}
+
+// Cleanup runs the cleanup functions added by [AddCleanup]. See
+// [Lifecycle.Cleanup].
+func Cleanup() {
+	std.Cleanup()
+}
+
+// Execute executes root and runs the cleanup functions added by [AddCleanup]
+// however it exits. See [Lifecycle.Execute].
+func Execute(ctx context.Context, root *cobra.Command) (*cobra.Command, error) {
+	return std.Execute(ctx, root)
+}
+
+// Add adds function(s) to be run on cleanup. If the cleanup was already run,
+// they are run immediately.
+func (l *Lifecycle) Add(f ...func()) {
+	l.mu.Lock()
+
+	if !l.done {
+		l.cleanup = append(l.cleanup, f...)
+		l.mu.Unlock()
+
+		return
+	}
+
+	l.mu.Unlock()
+
+	for i := len(f) - 1; i >= 0; i-- {
+		runCleanup(f[i])
+	}
+}
+
+// Graceful indicates that the executing command returns on its own once its
+// context is canceled by a signal, such as the bridge stopping. Otherwise the
+// cleanup is run and the process exits as soon as a signal is received. In
+// either case, a second signal exits the process after running the cleanup.
+func (l *Lifecycle) Graceful() {
+	l.mu.Lock()
+	l.graceful = true
+	l.mu.Unlock()
+}
+
+// Cleanup runs the cleanup functions in the reverse order they were added. Only
+// the first call runs them. A panic in a cleanup function is logged, and the
+// rest of the functions are still run.
+func (l *Lifecycle) Cleanup() {
+	l.mu.Lock()
+	f := l.cleanup
+	l.cleanup, l.done = nil, true
+	l.mu.Unlock()
+
+	for i := len(f) - 1; i >= 0; i-- {
+		runCleanup(f[i])
+	}
+}
+
+func runCleanup(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Cleanup panicked", fmt.Errorf("%v", r))
+		}
+	}()
+
+	f()
+}
+
+// Execute executes root with a context that is canceled by SIGINT or SIGTERM,
+// then runs the cleanup. If the executed command isn't [Lifecycle.Graceful],
+// or on a second signal, the cleanup is run and the process exits with the
+// code 128 plus the signal number without waiting for the command to return.
+func (l *Lifecycle) Execute(ctx context.Context, root *cobra.Command) (*cobra.Command, error) {
+	defer l.Cleanup()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	returned := make(chan struct{})
+	defer close(returned)
+
+	go l.handleSignals(sig, cancel, returned)
+
+	return root.ExecuteContextC(ctx)
+}
+
+func (l *Lifecycle) handleSignals(sig <-chan os.Signal, cancel context.CancelFunc, returned <-chan struct{}) {
+	var s os.Signal
+
+	select {
+	case <-returned:
+		return
+	case s = <-sig:
+	}
+
+	log.Debug("Received signal", "signal", s)
+	cancel()
+
+	l.mu.Lock()
+	graceful := l.graceful
+	l.mu.Unlock()
+
+	if graceful {
+		select {
+		case <-returned:
+			return
+		case s = <-sig:
+			log.Warn("Received second signal, exiting", "signal", s)
+		}
+	}
+
+	l.Cleanup()
+
+	code := 1
+	if s, ok := s.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+
+	if l.exit != nil {
+		l.exit(code)
+	} else {
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,170 @@
+package cmdutil
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newTestTree returns a root command with a child command "child" that adds the
+// cleanup functions 1, 2 and 3 to l and returns the error of run.
+func newTestTree(l *Lifecycle, got *[]int, preRun func() error, run func(cmd *cobra.Command) error) *cobra.Command {
+	root := &cobra.Command{Use: "root", SilenceErrors: true, SilenceUsage: true}
+
+	child := &cobra.Command{
+		Use: "child",
+		PreRunE: func(*cobra.Command, []string) error {
+			l.Add(func() { *got = append(*got, 1) })
+			return preRun()
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			l.Add(
+				func() { *got = append(*got, 2) },
+				func() { *got = append(*got, 3) },
+			)
+			return run(cmd)
+		},
+	}
+
+	root.AddCommand(child)
+	root.SetArgs([]string{"child"})
+
+	return root
+}
+
+func TestLifecycle(t *testing.T) {
+	errExit := &ExitError{Err: errors.New("exit"), Code: 2}
+
+	var tests = []struct {
+		name   string
+		preRun error
+		run    error
+		want   []int
+	}{
+		{"success", nil, nil, []int{3, 2, 1}},
+		{"error", nil, errors.New("run"), []int{3, 2, 1}},
+		{"exit error", nil, errExit, []int{3, 2, 1}},
+		{"pre-run error", errExit, nil, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				l   Lifecycle
+				got []int
+			)
+
+			root := newTestTree(&l, &got,
+				func() error { return tt.preRun },
+				func(*cobra.Command) error { return tt.run },
+			)
+
+			_, err := l.Execute(context.Background(), root)
+			if want := errors.Join(tt.preRun, tt.run); (err == nil) != (want == nil) {
+				t.Errorf("Execute: want error %v, got %v", want, err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Cleanup: want %v, got %v", tt.want, got)
+			}
+
+			// The cleanup only runs once, and anything added after is run
+			// immediately.
+			l.Cleanup()
+			l.Add(func() { got = append(got, 4) })
+
+			if want := append(tt.want, 4); !slices.Equal(got, want) {
+				t.Errorf("Cleanup after: want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestLifecycle_Panic(t *testing.T) {
+	var (
+		l   Lifecycle
+		got []int
+	)
+
+	l.Add(
+		func() { got = append(got, 1) },
+		func() { panic("cleanup") },
+	)
+	l.Cleanup()
+
+	if want := []int{1}; !slices.Equal(got, want) {
+		t.Errorf("Cleanup: want %v, got %v", want, got)
+	}
+}
+
+func TestLifecycle_Signal(t *testing.T) {
+	var tests = []struct {
+		name     string
+		graceful bool
+		wantExit int
+	}{
+		{"graceful", true, 0},
+		{"exit", false, 128 + int(syscall.SIGINT)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got    []int
+				exited = make(chan int, 1)
+				l      = Lifecycle{exit: func(code int) { exited <- code }}
+			)
+
+			// The command is only ended by the signal, or by exiting if it
+			// doesn't stop gracefully.
+			root := newTestTree(&l, &got,
+				func() error { return nil },
+				func(cmd *cobra.Command) error {
+					if tt.graceful {
+						l.Graceful()
+					}
+
+					syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+
+					select {
+					case <-cmd.Context().Done():
+						if tt.graceful {
+							return nil
+						}
+					case <-time.After(5 * time.Second):
+						return errors.New("context not canceled")
+					}
+
+					select {
+					case code := <-exited:
+						exited <- code
+					case <-time.After(5 * time.Second):
+					}
+
+					return nil
+				},
+			)
+
+			if _, err := l.Execute(context.Background(), root); err != nil {
+				t.Fatal(err)
+			}
+
+			code := 0
+			select {
+			case code = <-exited:
+			default:
+			}
+
+			if code != tt.wantExit {
+				t.Errorf("Exit: want %d, got %d", tt.wantExit, code)
+			}
+
+			if want := []int{3, 2, 1}; !slices.Equal(got, want) {
+				t.Errorf("Cleanup: want %v, got %v", want, got)
+			}
+		})
+	}
+}
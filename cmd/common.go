@@ -1,131 +1,109 @@
 package cmd
 
 import (
-	"fmt"
+	"errors"
 	"io"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
-	"text/template"
 
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/build"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/spf13/cobra"
 )
 
 func findConfig() {
-	const defaultConfigFile = "mqttop.yaml"
-
 	if len(ConfigPath) > 0 {
 		return
 	}
 
-	if env, ok := os.LookupEnv("MQTTOP_CONFIG_PATH"); ok {
-		ConfigPath = strings.Split(env, ",")
-		return
-	}
+	var err error
 
-	if xdg, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok {
-		ConfigPath = []string{filepath.Join(xdg, defaultConfigFile)}
-		return
+	ConfigPath, err = cmdutil.ConfigPaths()
+	cobra.CheckErr(err)
+}
+
+// loadConfig finds the config paths and loads the config with the profiles. A
+// missing config file is an error if the config paths were given, such as with
+// --config. Otherwise the default config is loaded if the first file of the
+// default paths is missing, see [config.LoadProfile].
+func loadConfig() (*config.Config, error) {
+	if len(ConfigPath) > 0 {
+		if _, err := os.Stat(ConfigPath[0]); err != nil {
+			return nil, err
+		}
 	}
 
-	home, err := os.UserHomeDir()
-	cobra.CheckErr(err)
+	findConfig()
 
-	ConfigPath = []string{filepath.Join(home, ".config", defaultConfigFile)}
+	return config.LoadProfile(Profiles, ConfigPath...)
 }
 
 func findData() {
-	const defaultDataDir = "mqttop"
-
 	if DataPath != "" {
 		return
 	}
 
-	if env, ok := os.LookupEnv("MQTTOP_DATA_PATH"); ok {
-		DataPath = env
-		return
-	}
+	var err error
+
+	DataPath, err = cmdutil.DataPath()
+	cobra.CheckErr(err)
+}
 
-	if xdg, ok := os.LookupEnv("XDG_DATA_HOME"); ok {
-		DataPath = filepath.Join(xdg, defaultDataDir)
+func findFixture() {
+	if Fixture != "" {
 		return
 	}
 
-	home, err := os.UserHomeDir()
-	cobra.CheckErr(err)
-
-	DataPath = filepath.Join(home, ".local", "share", defaultDataDir)
+	if env, ok := os.LookupEnv("MQTTOP_FIXTURE_PATH"); ok {
+		Fixture = env
+	}
 }
 
-const banner = `┌────────────────────────────────────────────────────────────┐
-│                                                            │
-│   ███╗   ███╗ ██████╗ ████████╗████████╗ ██████╗ ██████╗   │
-│   ████╗ ████║██╔═══██╗╚══██╔══╝╚══██╔══╝██╔═══██╗██╔══██╗  │
-│   ██╔████╔██║██║   ██║   ██║      ██║   ██║   ██║██████╔╝  │
-│   ██║╚██╔╝██║██║▄▄ ██║   ██║      ██║   ██║   ██║██╔═══╝   │
-│   ██║ ╚═╝ ██║╚██████╔╝   ██║      ██║   ╚██████╔╝██║       │
-│   ╚═╝     ╚═╝ ╚══▀▀═╝    ╚═╝      ╚═╝    ╚═════╝ ╚═╝       │
-│                                                            │
-│     Author: lone-faerie                                    │
-│                                                            │
-│     Version: {{printf "%%-18.18s" .Version}}                            │
-│     Build Time: %-26.26s                 │
-│                                                            │
-└────────────────────────────────────────────────────────────┘
-`
-
 // BannerTemplate returns the string used for templating the banner.
 func BannerTemplate() string {
-	return fmt.Sprintf(banner, build.BuildTime())
+	return cmdutil.BannerTemplate()
 }
 
 // PrintBanner prints the banner to the given commands output.
 func PrintBanner(cmd *cobra.Command) error {
-	t := template.New("banner")
-
-	template.Must(t.Parse(BannerTemplate()))
-
-	return t.Execute(cmd.OutOrStdout(), cmd.Root())
+	return cmdutil.PrintBanner(cmd)
 }
 
 const fullDocsFooter = `Full documentation is available at:
 https://pkg.go.dev/github.com/lone-faerie/mqttop`
 
 // ExitError is an error that should cause the program to exit with the given code.
-type ExitError struct {
-	Err  error
-	Code int
-}
+type ExitError = cmdutil.ExitError
+
+// exitError returns err as an [ExitError] with code, unless err is nil or
+// already has an exit code.
+func exitError(err error, code int) error {
+	var exit *ExitError
+	if err == nil || errors.As(err, &exit) {
+		return err
+	}
 
-func (e *ExitError) Error() string {
-	return e.Err.Error()
+	return &ExitError{Err: err, Code: code}
 }
 
-func maybeWithPort(addr string, port int) string {
-	var hasPort bool
-
-	if last := addr[len(addr)-1]; '0' <= last && last <= '9' {
-		for _, c := range addr {
-			switch {
-			case c == ':':
-				hasPort = true
-			case '0' <= c && c <= '9':
-				hasPort = hasPort && true
-			default:
-				hasPort = false
-			}
-		}
+// auxClientOptions returns the client options of cfg for a command other than
+// run, which connects alongside the bridge. The client id is suffixed so that the
+// bridge isn't disconnected, and the will, session and message store of the
+// bridge aren't used.
+func auxClientOptions(cfg *config.Config, suffix string) *mqtt.ClientOptions {
+	opts := cfg.MQTT.ClientOptions()
+	if cfg.MQTT.ClientID != "" {
+		opts.SetClientID(cfg.MQTT.ClientID + suffix)
 	}
 
-	if hasPort || port < 0 {
-		return addr
-	}
+	opts.UnsetWill()
+	opts.SetCleanSession(true)
+	opts.SetStore(nil)
 
-	return addr + ":" + strconv.Itoa(port)
+	return opts
 }
 
 func flagsToConfig(cfg *config.Config, args []string) error {
@@ -140,7 +118,12 @@ func flagsToConfig(cfg *config.Config, args []string) error {
 	}
 
 	if Broker != "" {
-		cfg.MQTT.Broker = maybeWithPort(Broker, Port)
+		broker, err := config.ParseBroker(Broker, Port)
+		if err != nil {
+			return err
+		}
+
+		cfg.MQTT.Broker = broker
 	}
 
 	if Username != "" {
@@ -226,4 +209,8 @@ func setLogHandler(cfg *config.Config, minLevel log.Level) {
 			log.SetOutput(w)
 		}
 	}
+
+	if cfg.Log.Sampling.Limit > 0 {
+		log.SetSampling(cfg.Log.Sampling.Options())
+	}
 }
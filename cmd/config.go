@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// Flags for mqttop config init
+var (
+	ConfigFull  bool // Write every option, not only those with a default value
+	ConfigForce bool // Overwrite an existing config file
+)
+
+// Flags for mqttop config reference
+var (
+	ConfigFormat string // Format of the reference, either json or yaml
+)
+
+// NewCmdConfig returns the [cobra.Command] used for managing config files.
+//
+// Usage:
+//
+//	mqttop config [command]
+//
+// Available Commands:
+//
+//	init        Write the default config
+//	reference   Print the reference of every config option
+//
+// Flags:
+//
+//	-h, --help   help for config
+func NewCmdConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage config files",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		NewCmdConfigInit(),
+		NewCmdConfigReference(),
+	)
+
+	return cmd
+}
+
+// NewCmdConfigInit returns the [cobra.Command] used for writing the default config.
+//
+// The default config is written to the first config path, annotated with the
+// documentation of each option. Options without a default value are written
+// commented out, unless --full is specified. If the path is "-" the config is
+// written to stdout.
+//
+// Usage:
+//
+//	mqttop config init [flags]
+//
+// Flags:
+//
+//	-c, --config strings   Path(s) to config file/directory
+//	    --full             Write every option uncommented
+//	-f, --force            Overwrite an existing config file
+//	-h, --help             help for init
+func NewCmdConfigInit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init [flags]",
+		Short: "Write the default config",
+		Long: `Write the default config to the first config path, annotated with the
+documentation of each option.
+
+Options without a default value are written commented out, unless --full is
+specified. Lists, such as dirs, are always written as commented out examples.
+If the path is "-" the config is written to stdout.`,
+		Example: `  mqttop config init
+  mqttop config init --full --config /etc/mqttop.yaml
+  mqttop config init --config - > mqttop.yaml`,
+		Args: cobra.NoArgs,
+		RunE: initConfig,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().BoolVar(&ConfigFull, "full", false, "Write every option uncommented")
+	cmd.Flags().BoolVarP(&ConfigForce, "force", "f", false, "Overwrite an existing config file")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func initConfig(cmd *cobra.Command, _ []string) error {
+	findConfig()
+
+	var b bytes.Buffer
+	if err := config.WriteDefault(&b, ConfigFull); err != nil {
+		return err
+	}
+
+	path := ConfigPath[0]
+	if path == "-" {
+		_, err := b.WriteTo(cmd.OutOrStdout())
+		return err
+	}
+
+	if fi, err := os.Stat(path); (err == nil && fi.IsDir()) || strings.HasSuffix(path, string(filepath.Separator)) {
+		path = filepath.Join(path, "mqttop.yaml")
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !ConfigForce {
+		flag |= os.O_EXCL
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, flag, 0o600)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	} else if err != nil {
+		return err
+	}
+
+	if _, err = b.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	cmd.Println("Wrote config to", path)
+
+	return nil
+}
+
+// NewCmdConfigReference returns the [cobra.Command] used for printing the
+// reference of every config option.
+//
+// Each option is printed with its key, type, default value, documented values
+// and documentation, generated from the config structs. If a key is specified,
+// only the option and any options under it are printed.
+//
+// Usage:
+//
+//	mqttop config reference [key] [flags]
+//
+// Flags:
+//
+//	    --format string   Format of the reference, either json or yaml (default "json")
+//	-h, --help            help for reference
+func NewCmdConfigReference() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reference [key] [flags]",
+		Short: "Print the reference of every config option",
+		Long: `Print the reference of every config option, with the key, type, default
+value, documented values and documentation of each.
+
+The reference is generated from the config structs, so that documentation
+tooling can't go stale. The options of the items of a list are under the key
+of the list suffixed with "[]", such as "dirs[].path". If a key is specified,
+only the option and any options under it are printed.`,
+		Example: `  mqttop config reference
+  mqttop config reference --format yaml mqtt`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: printReference,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().StringVar(&ConfigFormat, "format", "json", "Format of the reference, either json or yaml")
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"json", "yaml"}, cobra.ShellCompDirectiveNoFileComp))
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func printReference(cmd *cobra.Command, args []string) error {
+	opts := config.Reference()
+
+	if len(args) == 1 {
+		key := args[0]
+		opts = slices.DeleteFunc(opts, func(o config.Option) bool {
+			return o.Key != key && !strings.HasPrefix(o.Key, key+".") && !strings.HasPrefix(o.Key, key+"[].")
+		})
+
+		if len(opts) == 0 {
+			return &ExitError{Err: fmt.Errorf("unknown option %q", key), Code: cmdutil.ExitUsage}
+		}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	switch ConfigFormat {
+	case "json":
+		if data, err = json.MarshalIndent(opts, "", "\t"); err == nil {
+			data = append(data, '\n')
+		}
+	case "yaml":
+		data, err = yaml.Marshal(opts)
+	default:
+		return &ExitError{Err: fmt.Errorf("invalid format %q, must be one of json or yaml", ConfigFormat), Code: cmdutil.ExitUsage}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.OutOrStdout().Write(data)
+
+	return err
+}
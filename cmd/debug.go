@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// Flags for mqttop debug snapshot
+var (
+	SnapshotOutput string // Path of the tarball to write
+)
+
+// NewCmdDebug returns the [cobra.Command] used for debugging tools.
+//
+// Usage:
+//
+//	mqttop debug [command]
+//
+// Available Commands:
+//
+//	snapshot    Record the system files read by the metrics
+//
+// Flags:
+//
+//	-h, --help   help for debug
+func NewCmdDebug() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Debugging tools",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdDebugSnapshot())
+
+	return cmd
+}
+
+// NewCmdDebugSnapshot returns the [cobra.Command] used for recording the
+// procfs and sysfs files read by the metrics.
+//
+// Every enabled metric is updated twice while recording the files
+// under /proc and /sys that are read. These are then written to a gzipped
+// tarball that can be extracted and used as the root of the fixtures in tests.
+// Serial numbers, UUIDs and MAC addresses are replaced before being written.
+//
+// Usage:
+//
+//	mqttop debug snapshot [flags] [metrics...]
+//
+// Flags:
+//
+//	-c, --config strings   Path(s) to config file/directory
+//	    --profile strings  Config profile(s) to activate
+//	-o, --output string    Path of the tarball to write (default "mqttop-snapshot.tar.gz")
+//	-h, --help             help for snapshot
+func NewCmdDebugSnapshot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot [flags] [metrics...]",
+		Short: "Record the system files read by the metrics",
+		Long: `Record the files under /proc and /sys that are read by the metrics into a
+gzipped tarball, to attach to bug reports.
+
+Every enabled metric, or only those given as arguments, is updated twice while
+recording the files that are read. Dirs are never included. Serial numbers,
+UUIDs and MAC addresses are replaced before being written. If the output is "-"
+the tarball is written to stdout.
+
+The tarball can be extracted and used as the root of the fixtures in tests with
+the rootfs option, or with $MQTTOP_ROOTFS_PATH.`,
+		Example: `  mqttop debug snapshot
+  mqttop debug snapshot --config /etc/mqttop.yaml -o snapshot.tar.gz
+  mqttop debug snapshot cpu net`,
+		ValidArgs: []cobra.Completion{
+			"cpu", "memory", "disks", "net", "battery", "fans", "audio", "idle", "processes", "system", "smart", "energy", "gpu",
+		},
+		Args: cobra.OnlyValidArgs,
+		RunE: debugSnapshot,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
+	cmd.Flags().StringVarP(&SnapshotOutput, "output", "o", "mqttop-snapshot.tar.gz", "Path of the tarball to write")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+	cmd.MarkFlagFilename("output", "tar.gz", "tgz")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func debugSnapshot(cmd *cobra.Command, args []string) (err error) {
+	log.SetLogLevel(log.LevelWarn)
+
+	if len(ConfigPath) > 0 {
+		cfg, err = config.LoadProfile(Profiles, ConfigPath...)
+		if err != nil {
+			return exitError(err, cmdutil.ExitConfig)
+		}
+
+		setLogHandler(cfg, log.LevelWarn)
+	} else {
+		cfg = config.Default()
+	}
+
+	if len(args) > 0 {
+		cfg.SetMetrics(args...)
+	}
+	// Dirs would record the names of the user's files, which aren't needed
+	// to reproduce anything the other metrics report.
+	cfg.Dirs = nil
+
+	vfs.Record()
+	_, err = metrics.Snapshot(cmd.Context(), cfg)
+	names := vfs.Recorded()
+
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return
+	} else if err != nil {
+		log.Warn("Snapshot incomplete", "error", err)
+	}
+
+	var w io.Writer
+	if SnapshotOutput == "-" {
+		w = cmd.OutOrStdout()
+	} else {
+		f, err := os.OpenFile(SnapshotOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	n, err := writeSnapshot(w, metrics.DefaultsOf(cfg).Root, names)
+	if err != nil {
+		return
+	}
+
+	if SnapshotOutput != "-" {
+		cmd.Printf("Wrote %d files to %s\n", n, SnapshotOutput)
+	}
+
+	return nil
+}
+
+// writeSnapshot writes the recorded names under /proc and /sys of root to w as
+// a gzipped tarball, returning the number of regular files written. Names that
+// can't be read, such as those that no longer exist, are skipped.
+func writeSnapshot(w io.Writer, root *vfs.Root, names []string) (n int, err error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "/proc/") && !strings.HasPrefix(name, "/sys/") {
+			continue
+		}
+
+		fi, err := os.Stat(root.Abs(name))
+		if err != nil {
+			continue
+		}
+
+		hdr := &tar.Header{
+			Name:    strings.TrimPrefix(name, "/"),
+			ModTime: now,
+		}
+
+		var b []byte
+		if fi.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0o755
+		} else if fi.Mode().IsRegular() {
+			// Files in procfs and sysfs report a size that doesn't match their
+			// contents, so they must be read in full before writing the header.
+			if b, err = os.ReadFile(root.Abs(name)); err != nil {
+				log.Debug("Skipping unreadable file", "name", name, "error", err)
+				continue
+			}
+
+			b = sanitize(name, b)
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = 0o644
+			hdr.Size = int64(len(b))
+			n++
+		} else {
+			continue
+		}
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return n, err
+		}
+
+		if _, err = tw.Write(b); err != nil {
+			return n, err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return
+	}
+
+	return n, gz.Close()
+}
+
+// sanitize replaces any identifying contents of the named file, such as serial
+// numbers, keeping the format so that the file can still be parsed.
+func sanitize(name string, b []byte) []byte {
+	switch path.Base(name) {
+	case "serial", "serial_number", "product_serial", "board_serial", "chassis_serial":
+		return []byte("0000000000\n")
+	case "uuid", "product_uuid", "wwid":
+		return []byte("00000000-0000-0000-0000-000000000000\n")
+	case "address", "perm_address":
+		if strings.HasPrefix(name, "/sys/class/net/") || strings.HasPrefix(name, "/sys/devices/") {
+			return []byte("00:00:00:00:00:00\n")
+		}
+	case "cpuinfo":
+		return sanitizeLines(b, "Serial")
+	}
+
+	return b
+}
+
+// sanitizeLines replaces the values of the "key: value" lines in b that start
+// with any of the given keys.
+func sanitizeLines(b []byte, keys ...string) []byte {
+	lines := bytes.SplitAfter(b, []byte{'\n'})
+
+	for i, line := range lines {
+		for _, key := range keys {
+			if !bytes.HasPrefix(line, []byte(key)) {
+				continue
+			}
+
+			j := bytes.IndexByte(line, ':')
+			if j < 0 {
+				continue
+			}
+
+			lines[i] = fmt.Appendf(line[:j+1:j+1], " %s\n", strings.Repeat("0", 16))
+		}
+	}
+
+	return bytes.Join(lines, nil)
+}
+
+// useFixture returns the directory of the snapshot at name, to be used as the
+// root of the files read by the metrics. If name is a tarball written by
+// writeSnapshot, it is extracted to a temporary directory that is removed on
+// cleanup.
+func useFixture(name string) (string, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir := name
+	if !fi.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		if dir, err = os.MkdirTemp("", "mqttop-fixture-"); err != nil {
+			return "", err
+		}
+		AddCleanup(func() { os.RemoveAll(dir) })
+
+		if err = extractSnapshot(f, dir); err != nil {
+			return "", fmt.Errorf("fixture %s: %w", name, err)
+		}
+	}
+
+	log.Info("Using fixture", "path", name)
+
+	return dir, nil
+}
+
+// extractSnapshot extracts the gzipped tarball read from r into dir. Only
+// directories and regular files are extracted.
+func extractSnapshot(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("invalid name %q", hdr.Name)
+		}
+
+		name := filepath.Join(dir, hdr.Name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, 0o755)
+		case tar.TypeReg:
+			err = extractFile(name, tr)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
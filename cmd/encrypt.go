@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/encrypt"
+	"github.com/lone-faerie/mqttop/sign"
+)
+
+// Flags for mqttop keygen
+var (
+	KeygenOutput string // Path to write the private key to
+	KeygenSign   bool   // Generate an Ed25519 key pair for signing payloads
+)
+
+// Flags for mqttop decrypt
+var (
+	DecryptIdentity string // Path of the private key
+	DecryptTopic    string // Topic the payload was published to
+)
+
+// NewCmdKeygen returns the [cobra.Command] used for generating a key pair for
+// the encryption or signing of payloads.
+//
+// The private key is printed along with a comment of its public key. For
+// encryption, the public key is added to the recipients of the encryption
+// config. For signing, the private key is the key of the signing config.
+//
+// Usage:
+//
+//	mqttop keygen [flags]
+//
+// Flags:
+//
+//	-o, --output string   Path to write the private key to
+//	    --sign            Generate an Ed25519 key pair for signing payloads
+//	-h, --help            help for keygen
+func NewCmdKeygen() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a key pair for encrypting or signing payloads",
+		Long: `Generate a key pair for the end-to-end encryption of payloads.
+
+The private key is printed along with a comment of its public key, as an age
+X25519 key pair like age-keygen. Add the public key to the "recipients" of the
+encryption config, and keep the private key with the consumer of the
+payloads, which decrypts them with "mqttop decrypt", the encrypt package or
+any age implementation.
+
+With --sign, generate an Ed25519 key pair for signing payloads instead. Set
+the private key as the "key" of the signing config, with the "ed25519"
+method, and give the public key to the consumers, which verify the payloads
+with "mqttop verify" or the sign package.`,
+		Args: cobra.NoArgs,
+		RunE: runKeygen,
+	}
+
+	cmd.Flags().StringVarP(&KeygenOutput, "output", "o", "", "Path to write the private key to")
+	cmd.MarkFlagFilename("output")
+	cmd.Flags().BoolVar(&KeygenSign, "sign", false, "Generate an Ed25519 key pair for signing payloads")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+// generateKey returns a new private key and its public key, for signing if
+// KeygenSign is set and otherwise for encryption.
+func generateKey() (priv, pub string, err error) {
+	if KeygenSign {
+		k, err := sign.GenerateKey()
+		if err != nil {
+			return "", "", err
+		}
+
+		return sign.FormatPrivateKey(k), sign.FormatPublicKey(k.Public().(ed25519.PublicKey)), nil
+	}
+
+	k, err := encrypt.GenerateKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	return encrypt.FormatPrivateKey(k), encrypt.FormatPublicKey(k.Recipient()), nil
+}
+
+func runKeygen(cmd *cobra.Command, _ []string) error {
+	priv, pub, err := generateKey()
+	if err != nil {
+		return err
+	}
+
+	data := fmt.Sprintf("# public key: %s\n%s\n", pub, priv)
+
+	if KeygenOutput == "" {
+		_, err = io.WriteString(cmd.OutOrStdout(), data)
+		return err
+	}
+
+	f, err := os.OpenFile(KeygenOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	cmd.PrintErrln("Public key:", pub)
+
+	return nil
+}
+
+// NewCmdDecrypt returns the [cobra.Command] used for decrypting an encrypted
+// payload.
+//
+// The payload is read from the file, or stdin if not given, and decrypted with
+// the private key of --identity, as written by mqttop keygen. The ciphertext
+// is bound to the topic, so the topic the payload was published to must be
+// given.
+//
+// Usage:
+//
+//	mqttop decrypt [flags] [file]
+//
+// Flags:
+//
+//	-i, --identity string   Path of the private key
+//	-t, --topic string      Topic the payload was published to
+//	-h, --help              help for decrypt
+func NewCmdDecrypt() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt [flags] [file]",
+		Short: "Decrypt an encrypted payload",
+		Long: `Decrypt a payload encrypted for a recipient of the encryption config.
+
+The payload is read from the file, or stdin if not given, such as
+
+  mosquitto_sub -t mqttop/metric/cpu -C 1 | mqttop decrypt -i key.txt -t mqttop/metric/cpu
+
+The ciphertext is bound to the topic, so the topic the payload was published
+to must be given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runDecrypt,
+	}
+
+	cmd.Flags().StringVarP(&DecryptIdentity, "identity", "i", "", "Path of the private key")
+	cmd.Flags().StringVarP(&DecryptTopic, "topic", "t", "", "Topic the payload was published to")
+	cmd.MarkFlagFilename("identity")
+	cmd.MarkFlagRequired("identity")
+	cmd.MarkFlagRequired("topic")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+// readKey reads the key of the file at name, which is its first line that
+// isn't blank or a comment.
+func readKey(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		return line, nil
+	}
+
+	return "", errors.New("no key in " + name)
+}
+
+// readPayload reads the payload of the file of args, or stdin if there is
+// none.
+func readPayload(cmd *cobra.Command, args []string) ([]byte, error) {
+	if len(args) == 0 {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		return bytes.TrimSpace(data), err
+	}
+
+	data, err := os.ReadFile(args[0])
+
+	return bytes.TrimSpace(data), err
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	key, err := readKey(DecryptIdentity)
+	if err != nil {
+		return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+	}
+
+	k, err := encrypt.ParsePrivateKey(key)
+	if err != nil {
+		return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+	}
+
+	data, err := readPayload(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := encrypt.Decrypt(k, DecryptTopic, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.OutOrStdout().Write(append(plaintext, '\n'))
+
+	return err
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/metrics"
+	"github.com/lone-faerie/mqttop/tracing"
+	"github.com/lone-faerie/mqttop/transport"
+)
+
+// NewCmdFeatures returns the [cobra.Command] used for printing which collectors
+// were compiled in.
+//
+// Usage:
+//
+//	mqttop features
+//
+// Flags:
+//
+//	-h, --help   help for features
+func NewCmdFeatures() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "features",
+		Short: "List features compiled in",
+		Long: `List the collectors and optional features compiled in.
+
+Features may be excluded at build time to reduce the size and dependencies of
+the binary. The TAG column is the build tag that excludes the feature:
+
+  nogpu     excludes GPU metrics (NVML)
+  nowatch   excludes watching directories for changes (fsnotify)
+  notrace   excludes tracing of updates (OpenTelemetry)
+  nonats    excludes the NATS transport (nats.go)
+  nokafka   excludes the Kafka transport (franz-go)`,
+		Args: cobra.NoArgs,
+		RunE: printFeatures,
+	}
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func printFeatures(cmd *cobra.Command, _ []string) error {
+	features := append(metrics.Features(),
+		metrics.Feature{Name: "tracing (otlp)", Tag: "notrace", Enabled: tracing.Supported},
+		metrics.Feature{Name: "transport (nats)", Tag: "nonats", Enabled: transport.NATSSupported},
+		metrics.Feature{Name: "transport (kafka)", Tag: "nokafka", Enabled: transport.KafkaSupported},
+	)
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FEATURE\tENABLED\tTAG")
+
+	for _, f := range features {
+		enabled := "no"
+		if f.Enabled {
+			enabled = "yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Name, enabled, f.Tag)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/bridge"
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// NewCmdInit returns the [cobra.Command] used for interactively writing a config.
+//
+// The available metrics are detected and printed, then the broker details and
+// which metrics to enable are asked for. The config is written to the first
+// config path, after which a test publish and discovery may be performed.
+//
+// Usage:
+//
+//	mqttop init [flags]
+//
+// Flags:
+//
+//	-c, --config strings   Path(s) to config file/directory
+//	-h, --help             help for init
+func NewCmdInit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init [flags]",
+		Short: "Interactively write a config",
+		Long: `Interactively write a config tailored to this system.
+
+The available metrics, such as the GPU, battery, disks and network interfaces,
+are detected and printed. The broker details, discovery, and which of the
+detected metrics to enable are then asked for, and the config is written to the
+first config path, annotated with the documentation of each option. Finally,
+the metrics and discovery may be published once to test the config.
+
+Press enter to accept the default answer, shown in brackets, or answer "?" to
+print the documentation of the option.`,
+		Example: `  mqttop init
+  mqttop init --config /etc/mqttop.yaml`,
+		Args: cobra.NoArgs,
+		RunE: runInit,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+// prompter asks questions on w and reads the answers from r.
+type prompter struct {
+	r  *bufio.Reader
+	w  io.Writer
+	in io.Reader
+}
+
+func newPrompter(cmd *cobra.Command) *prompter {
+	return &prompter{
+		r:  bufio.NewReader(cmd.InOrStdin()),
+		w:  cmd.OutOrStdout(),
+		in: cmd.InOrStdin(),
+	}
+}
+
+func (p *prompter) readLine() string {
+	line, _ := p.r.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// ask returns the answer to question, or def if the answer is blank.
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.w, "%s: ", question)
+	}
+
+	if s := p.readLine(); s != "" {
+		return s
+	}
+
+	return def
+}
+
+// askOption is like ask for the config option key, printing the documentation
+// of the option from the config reference if the answer is "?".
+func (p *prompter) askOption(key, question, def string) string {
+	for {
+		s := p.ask(question, def)
+		if s != "?" {
+			return s
+		}
+
+		if o, ok := config.Lookup(key); ok {
+			fmt.Fprintln(p.w, o.Doc)
+		}
+	}
+}
+
+// confirm returns the yes or no answer to question, or def if the answer is
+// blank or neither.
+func (p *prompter) confirm(question string, def bool) bool {
+	opts := "y/N"
+	if def {
+		opts = "Y/n"
+	}
+
+	fmt.Fprintf(p.w, "%s [%s]: ", question, opts)
+
+	switch strings.ToLower(p.readLine()) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+
+	return def
+}
+
+// secret returns the answer to question without echoing it, if reading from a
+// terminal.
+func (p *prompter) secret(question string) string {
+	fmt.Fprintf(p.w, "%s: ", question)
+
+	f, ok := p.in.(*os.File)
+	if !ok {
+		return p.readLine()
+	}
+
+	fd := int(f.Fd())
+
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return p.readLine()
+	}
+
+	noEcho := *termios
+	noEcho.Lflag &^= unix.ECHO
+
+	if err = unix.IoctlSetTermios(fd, unix.TCSETS, &noEcho); err != nil {
+		return p.readLine()
+	}
+
+	defer func() {
+		unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+		fmt.Fprintln(p.w)
+	}()
+
+	return p.readLine()
+}
+
+func runInit(cmd *cobra.Command, _ []string) error {
+	// Metrics that aren't available are expected while detecting them, and
+	// any other errors are returned.
+	log.SetLogLevel(log.LevelDisabled)
+	findConfig()
+
+	p := newPrompter(cmd)
+	w := cmd.OutOrStdout()
+
+	fmt.Fprintln(w, "Detecting available metrics...")
+	fmt.Fprintln(w)
+
+	probe := config.Default()
+	probe.SetMetrics("all")
+
+	mm := metrics.New(probe)
+	slices.SortFunc(mm, func(a, b metrics.Metric) int {
+		return strings.Compare(a.Type(), b.Type())
+	})
+	printMetrics(w, mm, nil)
+	metrics.Stop(mm...)
+
+	fmt.Fprintln(w)
+
+	tpl := config.Template()
+
+	for {
+		broker, err := config.ParseBroker(p.askOption("mqtt.broker", "MQTT broker address", "tcp://localhost"), 0)
+		if err == nil {
+			tpl.MQTT.Broker = broker
+			break
+		}
+
+		fmt.Fprintln(w, err)
+	}
+
+	tpl.MQTT.Username = p.askOption("mqtt.username", "MQTT username", "")
+	if tpl.MQTT.Username != "" {
+		tpl.MQTT.Password = p.secret("MQTT password")
+	} else {
+		tpl.MQTT.Password = ""
+	}
+
+	tpl.BaseTopic = p.askOption("base_topic", "Base topic", tpl.BaseTopic)
+
+	tpl.Discovery.Enabled = p.confirm("Enable Home Assistant discovery?", true)
+	if tpl.Discovery.Enabled {
+		tpl.Discovery.Prefix = p.askOption("discovery.prefix", "Discovery prefix", tpl.Discovery.Prefix)
+	}
+
+	var enabled []string
+
+	for _, m := range mm {
+		if p.confirm("Enable "+m.Type()+"?", true) {
+			enabled = append(enabled, m.Type())
+		}
+	}
+
+	tpl.SetMetrics(enabled...)
+
+	path := p.ask("Config path", ConfigPath[0])
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, "mqttop.yaml")
+	}
+
+	if _, err := os.Stat(path); err == nil && !p.confirm(path+" already exists, overwrite it?", false) {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	if err := writeInitConfig(path, tpl); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Wrote config to", path)
+
+	if len(enabled) > 0 && p.confirm("Publish the metrics and discovery once to test the config?", true) {
+		if err := testInitConfig(cmd.Context(), w, path); err != nil {
+			fmt.Fprintln(w, "Test failed:", err)
+		} else {
+			fmt.Fprintln(w, "Test succeeded")
+		}
+	}
+
+	fmt.Fprintf(w, `
+Next steps:
+  Check the permissions of the broker:  mqttop check broker --config %[1]s
+  Start the bridge:                     mqttop run --config %[1]s
+`, path)
+
+	return nil
+}
+
+func writeInitConfig(path string, cfg *config.Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if err = cfg.WriteAnnotated(f, false); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// testInitConfig loads the config at path, then publishes the state of each
+// of its metrics and the discovery payload once.
+func testInitConfig(ctx context.Context, w io.Writer, path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	m := metrics.New(cfg)
+	defer metrics.Stop(m...)
+
+	opts := auxClientOptions(cfg, "_init")
+
+	client := mqtt.NewClient(opts)
+
+	t := client.Connect()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.Done():
+	}
+
+	if err = t.Error(); err != nil {
+		return exitError(err, cmdutil.ExitBroker)
+	}
+
+	defer client.Disconnect(250)
+
+	fmt.Fprintln(w, "Connected to", cfg.MQTT.Broker)
+
+	b := bridge.New(cfg, bridge.WithClient(client), bridge.WithMetrics(m...))
+
+	var errs []error
+
+	for _, mm := range m {
+		if err := mm.Update(); err != nil && !errors.Is(err, metrics.ErrNoChange) {
+			errs = append(errs, fmt.Errorf("%s: %w", mm.Type(), err))
+			continue
+		}
+
+		if err := b.Publish(ctx, mm); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mm.Type(), err))
+			continue
+		}
+
+		fmt.Fprintln(w, "Published", mm.Type(), "to", mm.Topic())
+	}
+
+	if cfg.Discovery.Enabled {
+		d, err := discovery.New(&cfg.Discovery)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		for _, mm := range m {
+			if dd, ok := mm.(discovery.Discoverer); ok {
+				dd.Discover(d)
+			}
+		}
+
+		b.Discover(d)
+
+		if err = d.Publish(ctx, client, false); err != nil {
+			errs = append(errs, fmt.Errorf("discovery: %w", err))
+		} else {
+			fmt.Fprintln(w, "Published discovery to", cfg.Discovery.Prefix)
+		}
+	}
+
+	return errors.Join(errs...)
+}
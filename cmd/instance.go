@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// pidFile is the file in the data path of the instance that contains the pid of
+// the running bridge. It is locked for as long as the bridge is running, so a
+// pid left behind by a bridge that was killed is never mistaken for a running
+// one.
+const pidFile = "mqttop.pid"
+
+var (
+	// errInstanceRunning is returned by lockInstance if another bridge holds
+	// the lock of the data path.
+	errInstanceRunning = errors.New("already running")
+	// errNotRunning is returned by instancePID if no bridge holds the lock of
+	// the data path.
+	errNotRunning = errors.New("bridge is not running")
+)
+
+// findInstanceData sets DataPath to the data path of the instance of cfg, which
+// is a subdirectory of the data path if the instance is named.
+func findInstanceData() {
+	findData()
+
+	if DataPath != "" {
+		DataPath = filepath.Join(DataPath, cfg.Instance)
+	}
+}
+
+// lockInstance locks the data path of the instance and writes the pid of the
+// process to it for as long as the bridge is running, so that a second bridge
+// with the same instance name fails to start instead of both publishing to the
+// same topics with the same client id.
+func lockInstance() error {
+	name := filepath.Join(DataPath, pidFile)
+
+	f, err := openLocked(name)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		if pid, err := instancePID(DataPath); err == nil {
+			return instanceConflict(fmt.Errorf("%w (pid %d)", errInstanceRunning, pid))
+		}
+
+		return instanceConflict(errInstanceRunning)
+	} else if err != nil {
+		return err
+	}
+
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	log.Debug("Locked instance", "path", name)
+
+	AddCleanup(func() {
+		// Remove before unlocking, so that the removed file is never locked
+		// by another bridge.
+		os.Remove(name)
+		f.Close()
+	})
+
+	return nil
+}
+
+// openLocked opens and exclusively locks the file name, creating it if needed.
+// If the file was removed by the previous holder of the lock while waiting for
+// it, the new file is opened instead.
+func openLocked(name string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		var st1, st2 unix.Stat_t
+
+		if unix.Fstat(int(f.Fd()), &st1) == nil && unix.Stat(name, &st2) == nil && st1.Ino == st2.Ino {
+			return f, nil
+		}
+
+		f.Close()
+	}
+}
+
+// instancePID returns the pid of the bridge running with the data path dir. If
+// no bridge is running, [errNotRunning] is returned.
+func instancePID(dir string) (int, error) {
+	f, err := os.Open(filepath.Join(dir, pidFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, errNotRunning
+	} else if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	if err = unix.Flock(int(f.Fd()), unix.LOCK_SH|unix.LOCK_NB); err == nil {
+		// Nothing holds the lock, so the pid is stale
+		return 0, errNotRunning
+	} else if !errors.Is(err, unix.EWOULDBLOCK) {
+		return 0, err
+	}
+
+	var b [32]byte
+
+	n, err := f.Read(b[:])
+	if err != nil {
+		return 0, fmt.Errorf("unable to read pid: %w", err)
+	}
+
+	return strconv.Atoi(string(bytes.TrimSpace(b[:n])))
+}
+
+// checkDetached returns an error if the config can't be loaded or a bridge with
+// the instance of the config is already running, so that running detached fails
+// before the bridge is started in the background, where the error would go
+// unseen.
+func checkDetached() error {
+	c, err := loadConfig()
+	if err != nil {
+		return exitError(err, cmdutil.ExitConfig)
+	}
+
+	cfg = c
+	findInstanceData()
+
+	pid, err := instancePID(DataPath)
+	if err != nil {
+		return nil
+	}
+
+	return instanceConflict(fmt.Errorf("%w (pid %d)", errInstanceRunning, pid))
+}
+
+// instanceError returns err as an [ExitError] with the code
+// [cmdutil.ExitRunning] if the instance is already running, or
+// [cmdutil.ExitFailure] otherwise.
+func instanceError(err error) error {
+	if errors.Is(err, errInstanceRunning) {
+		return exitError(err, cmdutil.ExitRunning)
+	}
+
+	return exitError(err, cmdutil.ExitFailure)
+}
+
+// instanceConflict returns the error for another process using the data path of
+// the instance, which is most likely another bridge with the same instance name.
+func instanceConflict(err error) error {
+	if cfg.Instance == "" {
+		return fmt.Errorf("bridge %w with data path %s, set a different instance in the config to run multiple bridges", err, DataPath)
+	}
+
+	return fmt.Errorf("bridge with instance %q %w", cfg.Instance, err)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+)
+
+func TestMissingConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	a := filepath.Join(dir, "a.yaml")
+	if err := os.WriteFile(a, []byte("instance: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := filepath.Join(dir, "missing.yaml")
+
+	var tests = []struct {
+		name string
+		cmd  func() *cobra.Command
+		args []string
+	}{
+		{"Stop", NewCmdStop, []string{"-c", a, "-c", missing}},
+		{"Stop/First", NewCmdStop, []string{"-c", missing, "-c", a}},
+		{"Status", NewCmdStatus, []string{"-c", a, "-c", missing}},
+		{"Status/First", NewCmdStatus, []string{"-c", missing, "-c", a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { ConfigPath, DataPath = nil, "" })
+
+			c := tt.cmd()
+			c.SetArgs(tt.args)
+			c.SetOut(io.Discard)
+			c.SetErr(io.Discard)
+
+			err := c.Execute()
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("want error wrapping %v, got %v", os.ErrNotExist, err)
+			}
+			if code := cmdutil.ExitCode(err); code != cmdutil.ExitConfig {
+				t.Errorf("ExitCode: want %d, got %d", cmdutil.ExitConfig, code)
+			}
+		})
+	}
+}
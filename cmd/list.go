@@ -9,6 +9,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/lone-faerie/mqttop/metrics"
@@ -39,6 +40,7 @@ var listHelp string
 // Flags:
 //
 //	-c, --config strings   Path(s) to config file/directory
+//	    --profile strings  Config profile(s) to activate
 //	-s, --summary          Display a summary of available metrics
 //	-h, --help             help for list
 func NewCmdList() *cobra.Command {
@@ -49,7 +51,7 @@ func NewCmdList() *cobra.Command {
 		Long:    listHelp,
 		ValidArgs: []cobra.Completion{
 			cobra.CompletionWithDesc("all", "all metrics"),
-			"cpu", "memory", "disks", "net", "battery", "dirs", "gpu",
+			"cpu", "memory", "disks", "net", "battery", "fans", "audio", "idle", "processes", "system", "smart", "energy", "dirs", "gpu",
 		},
 		Args: cobra.OnlyValidArgs,
 		RunE: listMetrics,
@@ -57,6 +59,7 @@ func NewCmdList() *cobra.Command {
 
 	cmd.Flags().SortFlags = false
 	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
 	cmd.Flags().BoolVarP(&ListSummary, "summary", "s", false, "Display a summary of available metrics")
 
 	cmd.MarkFlagFilename("config", "yaml", "yml")
@@ -114,9 +117,9 @@ func listMetrics(cmd *cobra.Command, args []string) (err error) {
 	log.SetLogLevel(log.LevelWarn)
 
 	if len(ConfigPath) > 0 {
-		cfg, err = config.Load(ConfigPath...)
+		cfg, err = config.LoadProfile(Profiles, ConfigPath...)
 		if err != nil {
-			return
+			return exitError(err, cmdutil.ExitConfig)
 		}
 
 		setLogHandler(cfg, log.LevelWarn)
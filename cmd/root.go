@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 	"github.com/lone-faerie/mqttop/internal/build"
 	"github.com/spf13/cobra"
 )
 
-var cleanup []func()
-
 func init() {
 	cobra.EnableCommandSorting = false
 }
 
+// Flags for mqttop
+var (
+	JSONErrors bool // Print errors to stderr as JSON
+)
+
 // NewCmdRoot returns the root [cobra.Command] of the program.
 //
 // Usage:
@@ -20,33 +29,48 @@ func init() {
 // Commands:
 //
 //	run         Run the metrics bridge
+//	init        Interactively write a config
 //
 // Additional Commands:
 //
 //	stop        Stop running bridge
+//	status      Show whether the bridge is running
+//	service     Manage the bridge as a service
 //	list        List available metrics
+//	config      Manage config files
+//	features    List features compiled in
+//	schema      Print the JSON Schema of metric payloads
+//	keygen      Generate a key pair for encrypting or signing payloads
+//	decrypt     Decrypt an encrypted payload
+//	verify      Verify a signed payload
+//	check       Check the setup of the bridge
+//	debug       Debugging tools
 //	help        Help about any command
 //
 // Flags:
 //
-//	-h, --help      help for mqttop
-//	-v, --version   version for mqttop
+//	    --json-errors   Print errors to stderr as JSON
+//	-h, --help          help for mqttop
+//	-v, --version       version for mqttop
+//
+// The exit code is one of the codes of [cmdutil], such as
+// [cmdutil.ExitConfig] if the config is invalid.
 func NewCmdRoot() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "mqttop",
-		Short:   "A bridge to provide system metrics over MQTT.",
-		Long:    `A bridge to provide system metrics over MQTT.`,
-		Version: build.Version(),
-		PersistentPostRun: func(_ *cobra.Command, _ []string) {
-			for _, f := range cleanup {
-				f()
-			}
-		},
+		Use:               "mqttop",
+		Short:             "A bridge to provide system metrics over MQTT.",
+		Long:              `A bridge to provide system metrics over MQTT.`,
+		Version:           build.Version(),
 		CompletionOptions: cobra.CompletionOptions{HiddenDefaultCmd: true},
 		SilenceErrors:     true,
 		SilenceUsage:      true,
 	}
 
+	cmd.PersistentFlags().BoolVar(&JSONErrors, "json-errors", false, "Print errors to stderr as JSON")
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+	})
+
 	cmd.SetVersionTemplate(BannerTemplate())
 	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
 	cmd.AddGroup(
@@ -54,30 +78,74 @@ func NewCmdRoot() *cobra.Command {
 	)
 
 	cmd.AddCommand(NewCmdRun())
+	cmd.AddCommand(NewCmdInit())
 	cmd.AddCommand(NewCmdStop())
+	cmd.AddCommand(NewCmdStatus())
+	cmd.AddCommand(NewCmdService())
 	cmd.AddCommand(NewCmdList())
+	cmd.AddCommand(NewCmdConfig())
+	cmd.AddCommand(NewCmdFeatures())
+	cmd.AddCommand(NewCmdSchema())
+	cmd.AddCommand(NewCmdKeygen())
+	cmd.AddCommand(NewCmdDecrypt())
+	cmd.AddCommand(NewCmdVerify())
+	cmd.AddCommand(NewCmdCheck())
+	cmd.AddCommand(NewCmdDebug())
+
+	usageArgs(cmd)
 
 	return cmd
 }
 
-// AddCleanup adds function(s) to be run as part of the PersistentPostRun of
-// [RootCommand]
+// usageArgs wraps the args validation of cmd and its subcommands, so that
+// invalid args exit with [cmdutil.ExitUsage].
+func usageArgs(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+			}
+
+			return nil
+		}
+	}
+
+	for _, c := range cmd.Commands() {
+		usageArgs(c)
+	}
+}
+
+// AddCleanup adds function(s) to be run when the executed command exits, in the
+// reverse order they were added. See [cmdutil.AddCleanup].
 func AddCleanup(f ...func()) {
-	cleanup = append(cleanup, f...)
+	cmdutil.AddCleanup(f...)
 }
 
 var cmd *cobra.Command
 
 func Execute() (err error) {
-	cmd, err = NewCmdRoot().ExecuteC()
+	cmd, err = cmdutil.Execute(context.Background(), NewCmdRoot())
+	if err != nil && strings.HasPrefix(err.Error(), "unknown command") {
+		// Unknown commands are found before any args are validated
+		err = &ExitError{Err: err, Code: cmdutil.ExitUsage}
+	}
+	if err != nil && !JSONErrors {
+		// The flags may not have been parsed, such as for unknown commands
+		JSONErrors = slices.Contains(os.Args[1:], "--json-errors") || slices.Contains(os.Args[1:], "--json-errors=true")
+	}
 	return err
 }
 
-// Error calls [cobra.Command.PrintErrln] of the executed command.
+// Error calls [cobra.Command.PrintErrln] of the executed command. If the
+// --json-errors flag is set, err is printed as a [cmdutil.JSONError] instead.
 func Error(err error) {
 	if cmd == nil {
 		return
 	}
+	if JSONErrors {
+		cmdutil.WriteJSONError(cmd.ErrOrStderr(), err)
+		return
+	}
 	cmd.PrintErrln("Error:", err)
 }
 
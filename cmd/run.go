@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -20,8 +21,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lone-faerie/mqttop/bridge"
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/internal/sched"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/lone-faerie/mqttop/metrics"
 )
@@ -29,6 +32,7 @@ import (
 // Flags for mqttop run
 var (
 	ConfigPath []string      // Path(s) to config file/directory (default is first of $MQTTOP_CONFIG_PATH, $XDG_CONFIG_HOME/mqttop.yaml, $HOME/.config/mqttop.yaml)
+	Profiles   []string      // Config profile(s) to activate (default is $MQTTOP_PROFILE)
 	DataPath   string        // Path to data directory (default is first of $MQTTOP_DATA_PATH, $XDG_DATA_HOME/mqttop, $HOME/.local/share/mqttop)
 	Broker     string        // MQTT broker address
 	Port       int           // MQTT broker port
@@ -40,6 +44,7 @@ var (
 	Discovery  string        // Discovery prefix, or 'disabled' to disable
 	LogLevel   string        // Log level
 	Detach     bool          // Run detached (in background)
+	Fixture    string        // Path to a recorded snapshot to read metrics from (default is $MQTTOP_FIXTURE_PATH)
 )
 
 var cfg *config.Config
@@ -59,11 +64,15 @@ var runHelp string
 //   - username: $MQTTOP_BROKER_USERNAME
 //   - password: $MQTTOP_BROKER_PASSWORD
 //
+// Named profiles defined under the "profiles" key of the config may be activated with --profile or the comma-separated list of $MQTTOP_PROFILE.
+//
 // Enabled metrics may be supplied as arguments, which will ignore the enabled metrics of the config. The special argument 'all' may be supplied to enable all metrics. The valid arguments include:
 //
-//   - all, cpu, memory, disks, net, battery, dirs, gpu
+//   - all, cpu, memory, disks, net, battery, fans, audio, idle, processes, system, smart, energy, dirs, gpu
+//
+// A snapshot recorded by "mqttop debug snapshot" may be replayed with --fixture or $MQTTOP_FIXTURE_PATH, either as the tarball or the directory it was extracted to. The metrics will then read their files from the snapshot instead of /proc and /sys.
 //
-// All of the flags, if specified, will override the equivalent values in the config. The format of --broker should be scheme://host:port Where "scheme" is one of "tcp", "ssl", or "ws", "host" is the ip-address (or hostname) and "port" is the port on which the broker is accepting connections. If "scheme" is not defined, it defaults to "tcp" and if "port" is not defined, it will use the value of --port (default 1883).
+// All of the flags, if specified, will override the equivalent values in the config. The format of --broker should be scheme://host:port Where "scheme" is one of "tcp" (or "mqtt"), "ssl" (or "mqtts"), "ws", or "wss", "host" is the ip-address (or hostname) and "port" is the port on which the broker is accepting connections. If "scheme" is not defined, it defaults to "tcp" and if "port" is not defined, it will use the value of --port, or the default port of the scheme (1883 for "tcp", 8883 for "ssl", 80 for "ws" and 443 for "wss"). IPv6 addresses must be in brackets if followed by a port, such as [::1]:1883.
 //
 // Usage:
 //
@@ -82,8 +91,9 @@ var runHelp string
 // Flags:
 //
 //	-c, --config strings      Path(s) to config file/directory
+//	    --profile strings     Config profile(s) to activate
 //	-b, --broker string       MQTT broker address
-//	-p, --port int            MQTT broker port (default 1883)
+//	-p, --port int            MQTT broker port, if not in the address (default 1883, or 8883 for ssl)
 //	    --username string     MQTT client username
 //	    --password string     MQTT client password
 //	    --cert string         MQTT TLS certificate file (PEM encoded)
@@ -93,6 +103,7 @@ var runHelp string
 //	    --data string         Path to data directory
 //	-l, --log string          Log level
 //	-d, --detach              Run detached (in background)
+//	    --fixture string      Path to a recorded snapshot to read metrics from
 //	-h, --help                help for run
 func NewCmdRun() *cobra.Command {
 	cmd := &cobra.Command{
@@ -106,7 +117,7 @@ func NewCmdRun() *cobra.Command {
 		GroupID: "commands",
 		ValidArgs: []cobra.Completion{
 			cobra.CompletionWithDesc("all", "all metrics"),
-			"cpu", "memory", "disks", "net", "battery", "dirs", "gpu",
+			"cpu", "memory", "disks", "net", "battery", "fans", "audio", "idle", "processes", "system", "smart", "energy", "dirs", "gpu",
 		},
 		Args: cobra.OnlyValidArgs,
 		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
@@ -115,12 +126,14 @@ func NewCmdRun() *cobra.Command {
 			}
 
 			if Detach {
-				var code int
-				if err = runDetached(cmd, args); err != nil {
-					code = 1
+				if err = checkDetached(); err == nil {
+					err = runDetached(cmd, args)
+				}
+				if err != nil {
+					return instanceError(err)
 				}
 
-				return &ExitError{err, code}
+				return &ExitError{Code: cmdutil.ExitOK}
 			}
 
 			if err = PrintBanner(cmd); err != nil {
@@ -128,22 +141,53 @@ func NewCmdRun() *cobra.Command {
 				return
 			}
 
-			findConfig()
-			findData()
+			var fixtureRoot string
+
+			findFixture()
+			if Fixture != "" {
+				if fixtureRoot, err = useFixture(Fixture); err != nil {
+					return
+				}
+			}
+
+			cfg, err = loadConfig()
+			if err != nil {
+				return exitError(err, cmdutil.ExitConfig)
+			}
+
+			// Each instance has its own data, such as the discovery store
+			findInstanceData()
 			if DataPath != "" {
-				err = os.MkdirAll(DataPath, 0660)
+				err = os.MkdirAll(DataPath, 0755)
 				if err != nil {
 					return
 				}
+
+				if err = lockInstance(); err != nil {
+					return instanceError(err)
+				}
+
+				if cfg.MQTT.StoreEnabled && cfg.MQTT.StorePath == "" {
+					cfg.MQTT.StorePath = filepath.Join(DataPath, mqttStoreDir)
+				}
 			}
 
-			cfg, err = config.Load(ConfigPath...)
-			if err != nil && !errors.Is(err, os.ErrNotExist) {
-				return
+			if cfg.MQTT.StoreEnabled {
+				if cfg.MQTT.StorePath == "" {
+					return &ExitError{Err: errors.New("store_path is required if the data path is blank"), Code: cmdutil.ExitConfig}
+				}
+
+				if err = os.MkdirAll(cfg.MQTT.StorePath, 0o700); err != nil {
+					return
+				}
 			}
 
 			if err = flagsToConfig(cfg, args); err != nil {
-				return
+				return exitError(err, cmdutil.ExitConfig)
+			}
+
+			if fixtureRoot != "" {
+				cfg.SetRootFS(fixtureRoot)
 			}
 
 			log.Info("Config loaded")
@@ -159,8 +203,9 @@ func NewCmdRun() *cobra.Command {
 
 	cmd.Flags().SortFlags = false
 	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
 	cmd.Flags().StringVarP(&Broker, "broker", "b", "", "MQTT broker address")
-	cmd.Flags().IntVarP(&Port, "port", "p", 1883, "MQTT broker port")
+	cmd.Flags().IntVarP(&Port, "port", "p", 0, "MQTT broker port, if not in the address (default 1883, or 8883 for ssl)")
 	cmd.Flags().StringVar(&Username, "username", "", "MQTT client username")
 	cmd.Flags().StringVar(&Password, "password", "", "MQTT client password")
 	cmd.Flags().StringVar(&CertFile, "cert", "", "MQTT TLS certificate file (PEM encoded)")
@@ -170,12 +215,14 @@ func NewCmdRun() *cobra.Command {
 	cmd.Flags().StringVar(&DataPath, "data", "", "Path to data directory")
 	cmd.Flags().StringVarP(&LogLevel, "log", "l", "", "Log level")
 	cmd.Flags().BoolVarP(&Detach, "detach", "d", false, "Run detached (in background)")
+	cmd.Flags().StringVar(&Fixture, "fixture", "", "Path to a recorded snapshot to read metrics from")
 	cmd.Flags().String("pingback", "", "Pingback (hidden)")
 
 	cmd.Flags().Lookup("pingback").Hidden = true
 
 	cmd.MarkFlagFilename("config", "yaml", "yml")
 	cmd.MarkFlagDirname("config")
+	cmd.MarkFlagFilename("fixture", "tar.gz", "tgz")
 
 	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
 
@@ -264,30 +311,89 @@ func handlePingbackConn(conn net.Conn, expect []byte) error {
 	return nil
 }
 
-func getDiscovery(mm []metrics.Metric) (d *discovery.Discovery, migrate bool, err error) {
+const selectionModeFile = "selection_mode.json"
+
+// selectionModeState is the selection mode of the CPU persisted in the data path,
+// along with the configured selection mode at the time it was saved.
+type selectionModeState struct {
+	Config string `json:"config"`
+	Mode   string `json:"mode"`
+}
+
+// restoreSelectionMode sets the selection mode of c to the one persisted in the
+// data path, unless the configured selection mode has changed since it was saved.
+func restoreSelectionMode(c *metrics.CPU, configured string) {
+	data, err := os.ReadFile(filepath.Join(DataPath, selectionModeFile))
+	if err != nil {
+		return
+	}
+
+	var state selectionModeState
+
+	if err = json.Unmarshal(data, &state); err != nil {
+		log.WarnError("Unable to load selection mode", err)
+		return
+	}
+
+	if state.Config != configured {
+		log.Debug("Selection mode config changed, ignoring saved mode", "saved", state.Mode)
+		return
+	}
+
+	if err = c.SetSelectionMode(state.Mode); err != nil {
+		log.WarnError("Unable to restore selection mode", err)
+	}
+}
+
+// saveSelectionMode persists the selection mode of c in the data path.
+func saveSelectionMode(c *metrics.CPU, configured string) error {
+	data, err := json.Marshal(selectionModeState{
+		Config: configured,
+		Mode:   c.SelectionMode(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(DataPath, selectionModeFile), data, 0644)
+}
+
+// getDiscovery returns the discovery of the metrics mm. If there is no saved
+// discovery to migrate from, the returned legacy discovery has the metrics
+// discovered with the unique ids of older versions so that they may be removed,
+// see [discovery.Discovery.Legacy].
+func getDiscovery(store *discovery.Store, mm []metrics.Metric) (d, legacy *discovery.Discovery, migrate bool, err error) {
 	if d, err = discovery.New(&cfg.Discovery); err != nil {
 		return
 	}
 
+	legacy = d.Legacy()
+
 	for _, m := range mm {
 		if dd, ok := m.(discovery.Discoverer); ok {
 			dd.Discover(d)
+
+			if legacy != nil {
+				dd.Discover(legacy)
+			}
 		}
 	}
 
 	var old *discovery.Discovery
 
-	path := filepath.Join(filepath.Dir(ConfigPath[0]), "discovery.json")
-	old, err = discovery.Load(path)
+	old, err = store.Load()
 
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			err = nil
+		} else {
+			legacy = nil
 		}
 
 		return
 	}
 
+	legacy = nil
 	migrate = d.Diff(old)
 
 	return
@@ -301,42 +407,112 @@ func runBridge(cmd *cobra.Command, args []string) error {
 		ctx = context.Background()
 	}
 
+	// The bridge is stopped gracefully once the context is canceled
+	cmdutil.Graceful()
+
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if err := sched.Apply(&cfg.Runtime); err != nil {
+		log.WarnError("Unable to apply runtime config", err)
+	}
+
 	m := metrics.New(cfg)
+	if len(m) == 0 {
+		return &ExitError{Err: bridge.ErrNoMetrics, Code: cmdutil.ExitNoMetrics}
+	}
+
 	defer metrics.Stop(m...)
 
+	// cfg is cleared once the bridge is ready, so the config of the state is
+	// captured for saving the state later.
+	state := metricState{
+		mode:       cfg.CPU.SelectionMode,
+		prediction: cfg.Disks.Prediction.Enabled,
+		accounting: cfg.Net.Accounting.Enabled,
+	}
+
+	state.restore(m)
+
 	opts := []bridge.Option{
 		bridge.WithMetrics(m...),
 		bridge.WithLogLevel(cfg.MQTT.LogLevel),
+		bridge.WithOnSelectionMode(func(c *metrics.CPU) {
+			if err := saveSelectionMode(c, state.mode); err != nil {
+				log.Debug("Unable to save selection mode", "err", err)
+			}
+		}),
 	}
 
+	var d, legacy *discovery.Discovery
+
 	if cfg.Discovery.Enabled {
-		d, migrate, err := getDiscovery(m)
+		var (
+			migrate bool
+			err     error
+		)
+
+		var legacyPath []string
+		if cfg.Instance == "" {
+			// Older versions wrote the discovery next to the config
+			legacyPath = append(legacyPath, filepath.Join(filepath.Dir(ConfigPath[0]), "discovery.json"))
+		}
+
+		store, err := discovery.OpenStore(DataPath, legacyPath...)
+		if err != nil {
+			return &ExitError{Err: err, Code: 1}
+		}
+
+		d, legacy, migrate, err = getDiscovery(store, m)
 		if err == nil {
-			opts = append(opts, bridge.WithDiscovery(d, migrate))
+			if mg := d.Migration(); mg != nil {
+				log.Info("Migrating discovery", "from", mg.From, "to", mg.To, "cleared", len(mg.Cleared()))
+				log.Debug(mg.String())
+			}
+
+			opts = append(opts, bridge.WithDiscovery(d, migrate), bridge.WithDiscoveryStore(store))
 			AddCleanup(func() {
 				log.Debug("Writing discovery")
-				err := d.Write(filepath.Join(DataPath, "discovery.json"))
+				err := store.Save(d)
 				log.Debug("Done writing discovery", "err", err)
+				store.Close()
 			})
+		} else {
+			store.Close()
 		}
 	}
 
 	b := bridge.New(cfg, opts...)
 
-	if err := b.Start(ctx); err != nil {
+	// The metrics are looked up once the bridge exits, since it replaces the
+	// metrics it restarts.
+	AddCleanup(func() {
+		state.save(b.Metrics())
+	})
+
+	if legacy != nil {
+		// Remove the components published before unique ids included the device id
+		b.Discover(legacy)
+		d.Diff(legacy)
+	}
+
+	if err := b.Start(ctx); errors.Is(err, bridge.ErrInvalidConfig) {
+		return &ExitError{Err: err, Code: cmdutil.ExitConfig}
+	} else if err != nil {
 		log.Error("Not connected.", err)
-		return &ExitError{err, 1}
+		return &ExitError{Err: err, Code: cmdutil.ExitBroker}
 	}
 
 	log.Debug("Connected")
 
+	// Stopped even if ctx is done before the bridge is ready, since it's
+	// still connected
+	defer b.Stop()
+
 	select {
 	case <-b.Ready():
 		if err := b.Error(); err != nil {
-			return &ExitError{err, 1}
+			return &ExitError{Err: err, Code: cmdutil.ExitBroker}
 		}
 	case <-ctx.Done():
 		return nil
@@ -344,24 +520,22 @@ func runBridge(cmd *cobra.Command, args []string) error {
 
 	cfg = nil
 
-	defer b.Stop()
-
 	if pingback, _ := cmd.Flags().GetString("pingback"); pingback != "" {
 		confirmationBytes, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return &ExitError{err, 1}
+			return &ExitError{Err: err, Code: 1}
 		}
 
 		conn, err := net.Dial("tcp", pingback)
 		if err != nil {
-			return &ExitError{err, 1}
+			return &ExitError{Err: err, Code: 1}
 		}
 
 		_, err = conn.Write(confirmationBytes)
 		conn.Close()
 
 		if err != nil {
-			return &ExitError{err, 1}
+			return &ExitError{Err: err, Code: 1}
 		}
 	}
 
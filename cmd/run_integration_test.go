@@ -0,0 +1,129 @@
+//go:build integration
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+const testTimeout = 5 * time.Second
+
+func TestRunBridge_SaveState(t *testing.T) {
+	srv := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.DiscardHandler),
+	})
+
+	if err := srv.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+
+	if err := srv.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { srv.Close() })
+
+	fixtures, err := filepath.Abs("../testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = config.Default()
+	cfg.SetRootFS(fixtures)
+	cfg.SetMetrics("net")
+	cfg.Net.Interval = 100 * time.Millisecond
+	cfg.MQTT.Broker = "tcp://" + tcp.Address()
+	cfg.MQTT.ClientID = "mqttop-test"
+	cfg.Discovery.Enabled = false
+
+	topic := cfg.Net.Topic
+
+	// The flags of the command are bound to the globals, such as DataPath
+	run := NewCmdRun()
+
+	DataPath = t.TempDir()
+	t.Cleanup(func() { DataPath = "" })
+
+	published := make(chan struct{}, 1)
+
+	// The broker's own client subscribes, so that no other MQTT client shares
+	// the global loggers of paho with the bridge
+	err = srv.Subscribe(topic, 1, func(*mochi.Client, packets.Subscription, packets.Packet) {
+		select {
+		case published <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatal("Unable to subscribe:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run.SetContext(ctx)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- runBridge(run, nil)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for bridge")
+	}
+
+	// The bridge is stopped once it is ready rather than while starting
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for the bridge to stop")
+	}
+
+	cmdutil.Cleanup()
+
+	data, err := os.ReadFile(filepath.Join(DataPath, countersFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counters map[string]map[string]metrics.Counter
+
+	if err := json.Unmarshal(data, &counters); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(counters["net"]) == 0 {
+		t.Errorf("want net counters, got %s", data)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// Flags for mqttop schema
+var (
+	SchemaOutput string // Directory to write each schema to
+)
+
+// NewCmdSchema returns the [cobra.Command] used for printing the JSON Schema
+// of the payload of each metric.
+//
+// If a metric is specified, only the schema of its payload is printed. Otherwise,
+// the schemas are printed as a single JSON object keyed by the type of each metric.
+//
+// If --output is specified, each schema is written to "<metric>.schema.json" in
+// the directory instead.
+//
+// Usage:
+//
+//	mqttop schema [metric] [flags]
+//
+// Flags:
+//
+//	-o, --output string   Directory to write each schema to
+//	-h, --help            help for schema
+func NewCmdSchema() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema [metric]",
+		Short: "Print the JSON Schema of metric payloads",
+		Long: `Print the JSON Schema of the payload of each metric.
+
+The schemas are generated from the payload types of the bridge, so that
+integrations can validate the published messages and generate bindings.
+If the "publish_schemas" option of the MQTT config is true, each schema is
+also published retained to the "/$schema" subtopic of its metric.`,
+		ValidArgs: payload.SchemaTypes(),
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		RunE:      printSchema,
+	}
+
+	cmd.Flags().StringVarP(&SchemaOutput, "output", "o", "", "Directory to write each schema to")
+	cmd.MarkFlagDirname("output")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func printSchema(cmd *cobra.Command, args []string) error {
+	types := args
+	if len(types) == 0 {
+		types = payload.SchemaTypes()
+	}
+
+	if SchemaOutput != "" {
+		if err := os.MkdirAll(SchemaOutput, 0755); err != nil {
+			return err
+		}
+
+		for _, typ := range types {
+			data, _ := payload.Schema(typ)
+			name := filepath.Join(SchemaOutput, typ+".schema.json")
+
+			if err := os.WriteFile(name, append(data, '\n'), 0644); err != nil {
+				return err
+			}
+
+			cmd.Println("Wrote", name)
+		}
+
+		return nil
+	}
+
+	if len(args) == 1 {
+		data, _ := payload.Schema(args[0])
+		_, err := cmd.OutOrStdout().Write(append(data, '\n'))
+
+		return err
+	}
+
+	schemas := make(map[string]json.RawMessage, len(types))
+	for _, typ := range types {
+		schemas[typ], _ = payload.Schema(typ)
+	}
+
+	data, err := json.MarshalIndent(schemas, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.OutOrStdout().Write(append(data, '\n'))
+
+	return err
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// Flags for mqttop service
+var (
+	ServiceSystem bool // Manage a system service instead of a user service
+)
+
+// errServiceUnsupported is returned by newServiceManager on platforms without a
+// supported service manager.
+var errServiceUnsupported = errors.New("services are not supported on this platform")
+
+// service is the bridge registered with the service manager of the platform.
+type service struct {
+	// Name is the name of the service, which includes the instance name of
+	// the config so that each instance may be registered.
+	Name        string
+	Description string
+	// Args is the command line of the bridge, starting with the absolute path
+	// of the executable.
+	Args []string
+	// System indicates whether the service is run by the system, rather than
+	// for the current user.
+	System bool
+}
+
+// serviceManager registers and controls a [service] with the service manager of
+// the platform, which is systemd on Linux.
+type serviceManager interface {
+	// Install registers the service to be started at boot, or at login for a
+	// user service. It doesn't start the service.
+	Install(s *service) error
+	// Uninstall stops the service and removes its registration.
+	Uninstall(s *service) error
+	Start(s *service) error
+	Stop(s *service) error
+	// Status writes the status of the service, as reported by the service
+	// manager, to w.
+	Status(s *service, w io.Writer) error
+}
+
+// NewCmdService returns the [cobra.Command] used for running the bridge as a
+// systemd unit on Linux. Services are not supported on other platforms.
+//
+// Usage:
+//
+//	mqttop service [command]
+//
+// Available Commands:
+//
+//	install     Register the bridge as a service
+//	uninstall   Remove the service of the bridge
+//	start       Start the service of the bridge
+//	stop        Stop the service of the bridge
+//	status      Show the status of the service of the bridge
+//
+// Flags:
+//
+//	-c, --config strings    Path(s) to config file/directory
+//	    --profile strings   Config profile(s) to activate
+//	    --system            Manage a system service instead of a user service
+//	-h, --help              help for service
+func NewCmdService() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the bridge as a service",
+		Long: `Manage the bridge as a systemd unit on Linux. Services are not supported on
+other platforms.
+
+The service runs "mqttop run" with the config and profiles given to install. By
+default the service is run for the current user, or with --system it is run by
+the system, which usually requires root. The name of the service includes the
+instance name of the config, so that each instance may be registered.`,
+		Example: `  mqttop service install --config /etc/mqttop.yaml --system
+  mqttop service start --system
+  mqttop service status`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.PersistentFlags().SortFlags = false
+	cmd.PersistentFlags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.PersistentFlags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
+	cmd.PersistentFlags().BoolVar(&ServiceSystem, "system", false, "Manage a system service instead of a user service")
+
+	cmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+	cmd.MarkPersistentFlagDirname("config")
+
+	cmd.AddCommand(
+		newCmdServiceAction("install", "Register the bridge as a service", "Installed", serviceManager.Install),
+		newCmdServiceAction("uninstall", "Remove the service of the bridge", "Uninstalled", serviceManager.Uninstall),
+		newCmdServiceAction("start", "Start the service of the bridge", "Started", serviceManager.Start),
+		newCmdServiceAction("stop", "Stop the service of the bridge", "Stopped", serviceManager.Stop),
+		newCmdServiceStatus(),
+	)
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func newCmdServiceAction(use, short, done string, action func(serviceManager, *service) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			m, s, err := loadService()
+			if err != nil {
+				return err
+			}
+
+			if err = action(m, s); err != nil {
+				return err
+			}
+
+			cmd.Println(done, s.Name)
+
+			return nil
+		},
+	}
+}
+
+func newCmdServiceStatus() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the service of the bridge",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			m, s, err := loadService()
+			if err != nil {
+				return err
+			}
+
+			return m.Status(s, cmd.OutOrStdout())
+		},
+	}
+}
+
+// loadService returns the service manager of the platform and the service of the
+// bridge with the config and profiles of the flags.
+func loadService() (serviceManager, *service, error) {
+	m, err := newServiceManager()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.SetLogLevel(log.LevelWarn)
+	cfg, err = loadConfig()
+	if err != nil {
+		return nil, nil, exitError(err, cmdutil.ExitConfig)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, nil, err
+	}
+
+	s := &service{
+		Name:        "mqttop",
+		Description: "MQTTop metrics bridge",
+		Args:        []string{exe, "run"},
+		System:      ServiceSystem,
+	}
+
+	if cfg.Instance != "" {
+		s.Name += "-" + cfg.Instance
+		s.Description += " (" + cfg.Instance + ")"
+	}
+
+	for _, path := range ConfigPath {
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, nil, err
+		}
+
+		s.Args = append(s.Args, "--config", path)
+	}
+
+	for _, p := range Profiles {
+		s.Args = append(s.Args, "--profile", p)
+	}
+
+	return m, s, nil
+}
+
+// runServiceCommand runs the command name of the service manager, with the
+// output of the command included in the returned error.
+func runServiceCommand(name string, args ...string) error {
+	log.Debug("Running service command", "name", name, "args", args)
+
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, out)
+	}
+
+	return nil
+}
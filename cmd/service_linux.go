@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// systemd manages the service as a systemd unit, either a user unit in
+// $XDG_CONFIG_HOME/systemd/user or a system unit in /etc/systemd/system.
+type systemd struct{}
+
+func newServiceManager() (serviceManager, error) {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return nil, errors.Join(errServiceUnsupported, err)
+	}
+
+	return systemd{}, nil
+}
+
+var systemdUnit = template.Must(template.New("unit").Funcs(template.FuncMap{
+	"quote": systemdQuote,
+}).Parse(`[Unit]
+Description={{.Description}}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{range $i, $arg := .Args}}{{if $i}} {{end}}{{quote $arg}}{{end}}
+Restart=on-failure
+RestartSec=10
+{{- if .System}}
+StateDirectory=mqttop
+Environment=MQTTOP_DATA_PATH=%S/mqttop
+{{- end}}
+
+[Install]
+WantedBy={{if .System}}multi-user.target{{else}}default.target{{end}}
+`))
+
+// systemdQuote quotes arg for the command line of a unit, if needed.
+func systemdQuote(arg string) string {
+	// Specifiers such as %h are expanded in the command line
+	arg = strings.ReplaceAll(arg, "%", "%%")
+
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;$") {
+		return arg
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`)
+
+	return `"` + r.Replace(arg) + `"`
+}
+
+func (systemd) unitPath(s *service) (string, error) {
+	if s.System {
+		return filepath.Join("/etc/systemd/system", s.Name+".service"), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "systemd", "user", s.Name+".service"), nil
+}
+
+func (systemd) systemctl(s *service, args ...string) []string {
+	if !s.System {
+		args = append([]string{"--user"}, args...)
+	}
+
+	return args
+}
+
+func (m systemd) Install(s *service) error {
+	path, err := m.unitPath(s)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = systemdUnit.Execute(f, s); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if err = runServiceCommand("systemctl", m.systemctl(s, "daemon-reload")...); err != nil {
+		return err
+	}
+
+	return runServiceCommand("systemctl", m.systemctl(s, "enable", s.Name)...)
+}
+
+func (m systemd) Uninstall(s *service) error {
+	path, err := m.unitPath(s)
+	if err != nil {
+		return err
+	}
+
+	if err = runServiceCommand("systemctl", m.systemctl(s, "disable", "--now", s.Name)...); err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil {
+		return err
+	}
+
+	return runServiceCommand("systemctl", m.systemctl(s, "daemon-reload")...)
+}
+
+func (m systemd) Start(s *service) error {
+	return runServiceCommand("systemctl", m.systemctl(s, "start", s.Name)...)
+}
+
+func (m systemd) Stop(s *service) error {
+	return runServiceCommand("systemctl", m.systemctl(s, "stop", s.Name)...)
+}
+
+func (m systemd) Status(s *service, w io.Writer) error {
+	c := exec.Command("systemctl", m.systemctl(s, "status", "--no-pager", s.Name)...)
+	c.Stdout = w
+	c.Stderr = w
+
+	return c.Run()
+}
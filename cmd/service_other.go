@@ -0,0 +1,7 @@
+//go:build !linux
+
+package cmd
+
+func newServiceManager() (serviceManager, error) {
+	return nil, errServiceUnsupported
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/metrics"
+)
+
+// State of the metrics persisted in the data path across restarts
+const (
+	countersFile    = "counters.json"
+	usageFile       = "usage.json"
+	diskSamplesFile = "disk_samples.json"
+	mqttStoreDir    = "mqtt_store"
+)
+
+// loadState decodes the JSON file name in the data path into v. It returns
+// false if the file doesn't exist or couldn't be decoded, in which case what
+// is logged as unable to be loaded.
+func loadState(name, what string, v any) bool {
+	data, err := os.ReadFile(filepath.Join(DataPath, name))
+	if err != nil {
+		return false
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		log.WarnError("Unable to load "+what, err)
+		return false
+	}
+
+	return true
+}
+
+// saveState encodes v as JSON to the file name in the data path.
+func saveState(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(DataPath, name), data, 0644)
+}
+
+// restoreCounters restores the counters of each metric in m that implements
+// [metrics.Counterer] from the data path, so that their totals keep increasing
+// across restarts.
+func restoreCounters(m []metrics.Metric) {
+	var counters map[string]map[string]metrics.Counter
+
+	if !loadState(countersFile, "counters", &counters) {
+		return
+	}
+
+	for _, mm := range m {
+		if c, ok := mm.(metrics.Counterer); ok {
+			c.SetCounters(counters[metrics.Key(mm)])
+		}
+	}
+}
+
+// saveCounters persists the counters of each metric in m that implements
+// [metrics.Counterer] in the data path.
+func saveCounters(m []metrics.Metric) error {
+	counters := make(map[string]map[string]metrics.Counter)
+
+	for _, mm := range m {
+		if c, ok := mm.(metrics.Counterer); ok {
+			counters[metrics.Key(mm)] = c.Counters()
+		}
+	}
+
+	if len(counters) == 0 {
+		return nil
+	}
+
+	return saveState(countersFile, counters)
+}
+
+// restoreUsage restores the data usage of n from the data path.
+func restoreUsage(n *metrics.Net) {
+	var usage map[string]metrics.Usage
+
+	if loadState(usageFile, "data usage", &usage) {
+		n.SetUsage(usage)
+	}
+}
+
+// saveUsage persists the data usage of n in the data path.
+func saveUsage(n *metrics.Net) error {
+	usage := n.Usage()
+	if len(usage) == 0 {
+		return nil
+	}
+
+	return saveState(usageFile, usage)
+}
+
+// restoreDiskSamples restores the samples of the used space of each disk of d
+// from the data path, which are used to predict when the disks will be full.
+func restoreDiskSamples(d *metrics.Disks) {
+	var samples map[string][]metrics.DiskSample
+
+	if loadState(diskSamplesFile, "disk samples", &samples) {
+		d.SetSamples(samples)
+	}
+}
+
+// saveDiskSamples persists the samples of the used space of each disk of d in
+// the data path.
+func saveDiskSamples(d *metrics.Disks) error {
+	samples := d.Samples()
+	if len(samples) == 0 {
+		return nil
+	}
+
+	return saveState(diskSamplesFile, samples)
+}
+
+// metricState is the config of the state of the metrics persisted in the data
+// path across runs.
+type metricState struct {
+	mode       string // configured selection mode of the CPU
+	prediction bool   // whether disk prediction is enabled
+	accounting bool   // whether net accounting is enabled
+}
+
+// restore restores the state of the metrics m from the data path.
+func (s metricState) restore(m []metrics.Metric) {
+	for _, mm := range m {
+		if c, ok := mm.(*metrics.CPU); ok {
+			restoreSelectionMode(c, s.mode)
+		}
+
+		if d, ok := mm.(*metrics.Disks); ok && s.prediction {
+			restoreDiskSamples(d)
+		}
+
+		if n, ok := mm.(*metrics.Net); ok && s.accounting {
+			restoreUsage(n)
+		}
+	}
+
+	restoreCounters(m)
+}
+
+// save persists the state of the metrics m in the data path.
+func (s metricState) save(m []metrics.Metric) {
+	for _, mm := range m {
+		if c, ok := mm.(*metrics.CPU); ok {
+			if err := saveSelectionMode(c, s.mode); err != nil {
+				log.Debug("Unable to save selection mode", "err", err)
+			}
+		}
+
+		if d, ok := mm.(*metrics.Disks); ok && s.prediction {
+			if err := saveDiskSamples(d); err != nil {
+				log.Debug("Unable to save disk samples", "err", err)
+			}
+		}
+
+		if n, ok := mm.(*metrics.Net); ok && s.accounting {
+			if err := saveUsage(n); err != nil {
+				log.Debug("Unable to save data usage", "err", err)
+			}
+		}
+	}
+
+	if err := saveCounters(m); err != nil {
+		log.Debug("Unable to save counters", "err", err)
+	}
+}
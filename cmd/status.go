@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// NewCmdStatus returns the [cobra.Command] used for querying whether a bridge
+// is running on this host.
+//
+// The status, pid, instance and data path of the bridge are printed. If the
+// bridge isn't running, the exit code is 6.
+//
+// Usage:
+//
+//	mqttop status [flags]
+//
+// Flags:
+//
+//	-c, --config strings    Path(s) to config file/directory
+//	    --profile strings   Config profile(s) to activate
+//	    --data string       Path to data directory
+//	-h, --help              help for status
+func NewCmdStatus() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [flags]",
+		Short: "Show whether the bridge is running",
+		Long: `Show whether a bridge with the instance of the config is running on this host,
+such as one started with "mqttop run --detach".
+
+The status, pid, instance and data path of the bridge are printed. If the
+bridge isn't running, the exit code is 6.`,
+		Example: `  mqttop status
+  mqttop status --config /etc/mqttop.yaml`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			log.SetLogLevel(log.LevelWarn)
+			cfg, err = loadConfig()
+			if err != nil {
+				return exitError(err, cmdutil.ExitConfig)
+			}
+
+			findInstanceData()
+			setLogHandler(cfg, log.LevelWarn)
+
+			return nil
+		},
+		RunE: runStatus,
+
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
+	cmd.Flags().StringVar(&DataPath, "data", "", "Path to data directory")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+	cmd.MarkFlagDirname("config")
+	cmd.MarkFlagDirname("data")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func runStatus(cmd *cobra.Command, _ []string) error {
+	pid, err := instancePID(DataPath)
+	if err != nil && !errors.Is(err, errNotRunning) {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+
+	if err == nil {
+		fmt.Fprintln(w, "Status:\trunning")
+		fmt.Fprintf(w, "PID:\t%d\n", pid)
+	} else {
+		fmt.Fprintln(w, "Status:\tstopped")
+	}
+
+	if cfg.Instance != "" {
+		fmt.Fprintf(w, "Instance:\t%s\n", cfg.Instance)
+	}
+
+	fmt.Fprintf(w, "Data path:\t%s\n", DataPath)
+
+	if werr := w.Flush(); werr != nil {
+		return werr
+	}
+
+	if err != nil {
+		return &ExitError{Err: err, Code: cmdutil.ExitNotRunning}
+	}
+
+	return nil
+}
@@ -2,83 +2,173 @@ package cmd
 
 import (
 	"errors"
-	"os"
+	"fmt"
 	"os/exec"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
 
-	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 	"github.com/lone-faerie/mqttop/log"
 )
 
+// Flags for mqttop stop
+var (
+	StopTimeout time.Duration // Time to wait for the bridge to stop
+)
+
+// NewCmdStop returns the [cobra.Command] used for stopping a running bridge.
+//
+// If a bridge with the instance of the config is running on this host, such as
+// one started with --detach, it is sent SIGINT and waited for. Otherwise an
+// empty message is published to the stop topic of the bridge.
+//
 // Usage:
-//   mqttop stop [flags]
+//
+//	mqttop stop [flags] [topic]
 //
 // Flags:
-//   -b, --broker string     MQTT broker address
-//   -c, --config strings    Path(s) to config file/directory
-//   -h, --help              help for stop
-//       --password string   MQTT client password
-//   -P, --pid int           PID of the process
-//   -p, --port int          MQTT broker port (default 1883)
-//       --username string   MQTT client username
+//
+//	-c, --config strings     Path(s) to config file/directory
+//	    --profile strings    Config profile(s) to activate
+//	    --data string        Path to data directory
+//	-b, --broker string      MQTT broker address
+//	-p, --port int           MQTT broker port, if not in the address (default 1883, or 8883 for ssl)
+//	    --username string    MQTT client username
+//	    --password string    MQTT client password
+//	-P, --pid int            PID of the process
+//	-t, --timeout duration   Time to wait for the bridge to stop (default 10s)
+//	-h, --help               help for stop
 func NewCmdStop() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "stop",
+		Use:   "stop [flags] [topic]",
 		Short: "Stop running bridge",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Stop a running bridge.
+
+If a bridge with the instance of the config is running on this host, such as
+one started with "mqttop run --detach", it is sent SIGINT and waited for until
+it has stopped. Otherwise, or if a topic is given, an empty message is published
+to the stop topic of the bridge, which is "<base_topic>/bridge/stop" by default.`,
+		Example: `  mqttop stop
+  mqttop stop --config /etc/mqttop.yaml
+  mqttop stop --broker 127.0.0.1:1883 mqttop/bridge/stop`,
+		Args: cobra.MaximumNArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
 			log.SetLogLevel(log.LevelWarn)
-			findConfig()
-			cfg, err = config.Load(ConfigPath...)
-			if err != nil && !errors.Is(err, os.ErrNotExist) {
-				return
+			cfg, err = loadConfig()
+			if err != nil {
+				return exitError(err, cmdutil.ExitConfig)
 			}
-			if err = flagsToConfig(cfg, args); err != nil {
-				return
+
+			findInstanceData()
+
+			if err = flagsToConfig(cfg, nil); err != nil {
+				return exitError(err, cmdutil.ExitConfig)
 			}
+
 			log.Info("Config loaded")
 			setLogHandler(cfg, log.LevelWarn)
 			log.Debug("MQTT broker", "addr", cfg.MQTT.Broker)
+
 			return
 		},
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if pid := cmd.Flags().Lookup("pid"); pid != nil && pid.Changed && pid.Value.String() != pid.DefValue {
-				c := "ps cax | grep -qe '" + pid.Value.String() + "[[:space:]].*mqttop' && kill -2 " + pid.Value.String()
-				log.Debug("Stopping", "pid", pid.Value)
-				if err := exec.Command("sh", "-c", c).Run(); err == nil {
-					return nil
-				}
-			}
-			opts := cfg.MQTT.ClientOptions()
-			client := mqtt.NewClient(opts)
-			t := client.Connect()
-			t.Wait()
-			if err := t.Error(); err != nil {
-				return err
-			}
-			defer client.Disconnect(500)
-			var topic string
-			if len(args) > 0 {
-				topic = args[1]
-			} else {
-				topic = "mqttop/bridge/stop"
-			}
-			t = client.Publish(topic, 0, false, []byte{})
-			t.Wait()
-			return t.Error()
-		},
+		RunE: runStop,
+
+		DisableFlagsInUseLine: true,
 	}
 
+	cmd.Flags().SortFlags = false
 	cmd.Flags().StringSliceVarP(&ConfigPath, "config", "c", nil, "Path(s) to config file/directory")
+	cmd.Flags().StringSliceVar(&Profiles, "profile", nil, "Config profile(s) to activate")
+	cmd.Flags().StringVar(&DataPath, "data", "", "Path to data directory")
 	cmd.Flags().StringVarP(&Broker, "broker", "b", "", "MQTT broker address")
-	cmd.Flags().IntVarP(&Port, "port", "p", 1883, "MQTT broker port")
+	cmd.Flags().IntVarP(&Port, "port", "p", 0, "MQTT broker port, if not in the address (default 1883, or 8883 for ssl)")
 	cmd.Flags().StringVar(&Username, "username", "", "MQTT client username")
 	cmd.Flags().StringVar(&Password, "password", "", "MQTT client password")
 	cmd.Flags().IntP("pid", "P", 0, "PID of the process")
+	cmd.Flags().DurationVarP(&StopTimeout, "timeout", "t", 10*time.Second, "Time to wait for the bridge to stop")
+
+	cmd.MarkFlagFilename("config", "yaml", "yml")
+	cmd.MarkFlagDirname("config")
+	cmd.MarkFlagDirname("data")
 
 	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
 
 	return cmd
 }
+
+func runStop(cmd *cobra.Command, args []string) error {
+	if pid := cmd.Flags().Lookup("pid"); pid != nil && pid.Changed && pid.Value.String() != pid.DefValue {
+		c := "ps cax | grep -qe '" + pid.Value.String() + "[[:space:]].*mqttop' && kill -2 " + pid.Value.String()
+		log.Debug("Stopping", "pid", pid.Value)
+		if err := exec.Command("sh", "-c", c).Run(); err == nil {
+			return nil
+		}
+	}
+
+	if len(args) == 0 {
+		pid, err := instancePID(DataPath)
+		if err == nil {
+			return stopInstance(cmd, pid)
+		} else if !errors.Is(err, errNotRunning) {
+			return err
+		}
+
+		log.Debug("Bridge not running on this host", "data", DataPath)
+	}
+
+	opts := auxClientOptions(cfg, "_stop")
+
+	client := mqtt.NewClient(opts)
+
+	t := client.Connect()
+	t.Wait()
+	if err := t.Error(); err != nil {
+		return exitError(err, cmdutil.ExitBroker)
+	}
+
+	defer client.Disconnect(500)
+
+	topic := cfg.BaseTopic + "/bridge/stop"
+	if len(args) > 0 {
+		topic = args[0]
+	}
+
+	t = client.Publish(topic, 0, false, []byte{})
+	t.Wait()
+
+	return t.Error()
+}
+
+// stopInstance sends SIGINT to the bridge of the instance with the given pid and
+// waits up to StopTimeout for it to release the lock of its data path.
+func stopInstance(cmd *cobra.Command, pid int) error {
+	log.Debug("Stopping", "pid", pid)
+
+	if err := unix.Kill(pid, unix.SIGINT); err != nil {
+		return fmt.Errorf("unable to stop bridge (pid %d): %w", pid, err)
+	}
+
+	deadline := time.Now().Add(StopTimeout)
+
+	for {
+		_, err := instancePID(DataPath)
+		if errors.Is(err, errNotRunning) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("bridge (pid %d) did not stop within %v", pid, StopTimeout)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cmd.Printf("Stopped bridge (pid %d)\n", pid)
+
+	return nil
+}
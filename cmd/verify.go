@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
+	"github.com/lone-faerie/mqttop/sign"
+)
+
+// Flags for mqttop verify
+var (
+	VerifyKey    string        // Path of the Ed25519 public key or HMAC secret
+	VerifyTopic  string        // Topic the payload was published to
+	VerifyMaxAge time.Duration // How old the payload may be
+)
+
+// NewCmdVerify returns the [cobra.Command] used for verifying a signed
+// payload.
+//
+// The payload is read from the file, or stdin if not given, and verified with
+// the key of --key, which is either an Ed25519 public key as printed by
+// mqttop keygen --sign, or the HMAC secret of the signing config. The original
+// payload is printed if the signature is valid.
+//
+// Usage:
+//
+//	mqttop verify [flags] [file]
+//
+// Flags:
+//
+//	-k, --key string         Path of the Ed25519 public key or HMAC secret
+//	-t, --topic string       Topic the payload was published to
+//	    --max-age duration   How old the payload may be, if not 0
+//	-h, --help               help for verify
+func NewCmdVerify() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [flags] [file]",
+		Short: "Verify a signed payload",
+		Long: `Verify a payload signed by the signing config, and print the original payload.
+
+The payload is read from the file, or stdin if not given, such as
+
+  mosquitto_sub -t mqttop/metric/cpu -C 1 | mqttop verify -k key.pub -t mqttop/metric/cpu
+
+The key file holds either the Ed25519 public key printed by "mqttop keygen --sign",
+or the HMAC secret of the signing config. The signature covers the topic, so the
+topic the payload was published to must be given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runVerify,
+	}
+
+	cmd.Flags().StringVarP(&VerifyKey, "key", "k", "", "Path of the Ed25519 public key or HMAC secret")
+	cmd.Flags().StringVarP(&VerifyTopic, "topic", "t", "", "Topic the payload was published to")
+	cmd.Flags().DurationVar(&VerifyMaxAge, "max-age", 0, "How old the payload may be, if not 0")
+	cmd.MarkFlagFilename("key")
+	cmd.MarkFlagRequired("key")
+	cmd.MarkFlagRequired("topic")
+
+	cmd.SetHelpTemplate(cmd.HelpTemplate() + "\n" + fullDocsFooter + "\n")
+
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	key, err := readKey(VerifyKey)
+	if err != nil {
+		return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+	}
+
+	var v *sign.Verifier
+
+	if strings.HasPrefix(key, sign.PublicKeyPrefix) {
+		pub, err := sign.ParsePublicKey(key)
+		if err != nil {
+			return &ExitError{Err: err, Code: cmdutil.ExitUsage}
+		}
+
+		v = sign.NewEd25519Verifier(pub)
+	} else {
+		v = sign.NewHMACVerifier([]byte(key))
+	}
+
+	v.MaxAge = VerifyMaxAge
+
+	data, err := readPayload(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	payload, _, err := v.Verify(VerifyTopic, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = cmd.OutOrStdout().Write(append(payload, '\n'))
+
+	return err
+}
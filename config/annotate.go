@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// docField is a documented yaml field of a config struct.
+type docField struct {
+	key    string   // yaml key
+	doc    string   // doc comment of the field
+	typ    string   // name of the config struct, if any
+	list   bool     // whether the field is a list of typ
+	kind   string   // type of the value, such as "int" or "[]string", if typ is blank
+	zero   string   // yaml encoding of the zero value, if typ is blank
+	values []string // documented values of the field, if any
+}
+
+// annotator writes a config annotated with the doc comments of its fields.
+type annotator struct {
+	w    *bufio.Writer
+	full bool
+}
+
+func (a *annotator) comment(doc string, indent int) {
+	for line := range strings.Lines(doc) {
+		a.w.WriteString(strings.Repeat(" ", indent))
+		a.w.WriteString(strings.TrimRight("# "+strings.TrimSuffix(line, "\n"), " "))
+		a.w.WriteByte('\n')
+	}
+}
+
+// scalar returns the yaml encoding of the value node n on a single line.
+func scalar(n *yaml.Node) (string, error) {
+	if n.Kind != yaml.ScalarNode {
+		n.Style = yaml.FlowStyle
+	}
+
+	b, err := yaml.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(b)), nil
+}
+
+// mapping writes the fields of the config struct typ with the values of the
+// mapping node m, which may be nil. Each key is prefixed by prefix, after which
+// the fields are indented by indent. If commented is true, every field is
+// commented out.
+func (a *annotator) mapping(typ string, m *yaml.Node, prefix string, indent int, commented bool) error {
+	for i, f := range configFields[typ] {
+		var v *yaml.Node
+		if m != nil {
+			v = value(m, f.key)
+		}
+
+		doc := f.doc
+		if doc == "" && f.typ != "" && !f.list {
+			doc = configDocs[f.typ]
+		}
+
+		if indent == 0 && i > 0 {
+			a.w.WriteByte('\n')
+		}
+
+		// The prefix may be the "- " of a list item
+		dash := strings.TrimLeft(prefix, " ")
+		spaces := prefix[:len(prefix)-len(dash)]
+
+		a.comment(doc, len(spaces))
+
+		c := commented || (v == nil && (f.list || !a.full))
+
+		line := prefix
+		if c {
+			line = spaces + "# " + dash
+		}
+
+		line += f.key + ":"
+
+		var err error
+
+		switch {
+		case f.list:
+			a.w.WriteString(line + "\n")
+			a.comment(configDocs[f.typ], indent+2)
+
+			err = a.mapping(f.typ, nil, strings.Repeat(" ", indent+2)+"- ", indent+4, true)
+		case f.typ != "":
+			a.w.WriteString(line + "\n")
+
+			err = a.mapping(f.typ, v, strings.Repeat(" ", indent+2), indent+2, c)
+		default:
+			val := f.zero
+			if v != nil {
+				val, err = scalar(v)
+			}
+
+			a.w.WriteString(line + " " + val + "\n")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		prefix = strings.Repeat(" ", indent)
+	}
+
+	return nil
+}
+
+// WriteDefault writes the default config to w, annotated with the documentation
+// of each field. Options without a default value are written commented out,
+// unless full is true. Lists, such as dirs, are always written as commented out
+// examples.
+func WriteDefault(w io.Writer, full bool) error {
+	return defaultCfg().WriteAnnotated(w, full)
+}
+
+// Template returns the default config before environment variables and the
+// base topic "~" are expanded, to be modified and written by [Config.WriteAnnotated].
+// The returned config must not be used otherwise.
+func Template() *Config {
+	return defaultCfg()
+}
+
+// WriteAnnotated writes cfg to w, annotated with the documentation of each field
+// as with [WriteDefault]. Options without a value are written commented out,
+// unless full is true. Lists, such as dirs, are always written as commented out
+// examples.
+func (cfg *Config) WriteAnnotated(w io.Writer, full bool) error {
+	m, _ := mapping(nil)
+
+	values := cfg.fieldValues()
+
+	for _, f := range configFields["Config"] {
+		if f.list {
+			continue
+		}
+
+		var v yaml.Node
+
+		if err := v.Encode(values[f.key]); err != nil {
+			return err
+		}
+
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}, &v)
+	}
+
+	a := &annotator{
+		w:    bufio.NewWriter(w),
+		full: full,
+	}
+
+	a.comment(`MQTTop configuration
+
+See https://github.com/lone-faerie/mqttop#configuration`, 0)
+	a.w.WriteByte('\n')
+
+	if err := a.mapping("Config", m, "", 0, false); err != nil {
+		return err
+	}
+
+	return a.w.Flush()
+}
@@ -0,0 +1,88 @@
+package config_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestWriteDefault(t *testing.T) {
+	var want bytes.Buffer
+	if err := config.Default().Write(&want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, full := range []bool{false, true} {
+		var b bytes.Buffer
+		if err := config.WriteDefault(&b, full); err != nil {
+			t.Fatal(err)
+		}
+
+		s := b.String()
+
+		for _, line := range []string{
+			"# Interval is the default update interval for all enabled metrics.\n",
+			"interval: 2s\n",
+			"mqtt:\n  # Broker is the URI of the broker.",
+			"cpu:\n  # Enabled indicates if the metric should be published.\n  enabled: true\n",
+			"# dirs:\n",
+			"  # - enabled: false\n",
+		} {
+			if !strings.Contains(s, line) {
+				t.Errorf("full=%v: want %q in config", full, line)
+			}
+		}
+
+		if commented := strings.Contains(s, "\n  # name_template: \"\"\n"); commented == full {
+			t.Errorf("full=%v: want optional fields commented %v, got %v", full, !full, commented)
+		}
+
+		cfg, err := config.Read(&b)
+		if err != nil {
+			t.Fatalf("full=%v: %v", full, err)
+		}
+
+		var got bytes.Buffer
+		if err := cfg.Write(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.String() != want.String() {
+			t.Errorf("full=%v: want default config\n%s\ngot\n%s", full, &want, &got)
+		}
+	}
+}
+
+func TestWriteAnnotated(t *testing.T) {
+	cfg := config.Template()
+	cfg.MQTT.Broker = "tcp://broker:1883"
+	cfg.GPU.Enabled = false
+
+	var b bytes.Buffer
+	if err := cfg.WriteAnnotated(&b, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s := b.String()
+
+	for _, line := range []string{
+		"  broker: tcp://broker:1883\n",
+		"  birth_lwt_topic: ~/bridge/status\n",
+		"gpu:\n  # Enabled indicates if the metric should be published.\n  enabled: false\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("want %q in config", line)
+		}
+	}
+
+	got, err := config.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.MQTT.Broker != cfg.MQTT.Broker || got.GPU.Enabled {
+		t.Errorf("want broker %q and gpu disabled, got %q and %v", cfg.MQTT.Broker, got.MQTT.Broker, got.GPU.Enabled)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// brokerSchemes maps each accepted scheme of a broker URI to the scheme used by
+// the MQTT client.
+var brokerSchemes = map[string]string{
+	"tcp":      "tcp",
+	"mqtt":     "tcp",
+	"ssl":      "ssl",
+	"tls":      "ssl",
+	"mqtts":    "ssl",
+	"mqtt+ssl": "ssl",
+	"tcps":     "ssl",
+	"ws":       "ws",
+	"wss":      "wss",
+}
+
+// brokerPorts is the default port of each scheme used by the MQTT client.
+var brokerPorts = map[string]int{
+	"tcp": 1883,
+	"ssl": 8883,
+	"ws":  80,
+	"wss": 443,
+}
+
+// ParseBroker returns the URI of the broker at addr in the form
+// scheme://host:port, as accepted by the MQTT client.
+//
+// If addr doesn't have a scheme, it defaults to "tcp". The scheme may also be
+// one of the aliases "mqtt" for "tcp", and "mqtts", "tls", "tcps" or
+// "mqtt+ssl" for "ssl". The host may be an IPv6 address, which must be in
+// brackets if followed by a port. If addr doesn't have a port, port is used, or
+// the default port of the scheme if port is 0, which is 1883 for "tcp", 8883
+// for "ssl", 80 for "ws" and 443 for "wss".
+func ParseBroker(addr string, port int) (string, error) {
+	if addr == "" {
+		return "", errors.New("broker address is empty")
+	}
+
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		scheme, rest = "tcp", addr
+	}
+
+	// A bare IPv6 address, such as ::1 or fe80::1%eth0, can't have a port
+	if net.ParseIP(stripZone(rest)) != nil && strings.Contains(rest, ":") {
+		rest = "[" + strings.Replace(rest, "%", "%25", 1) + "]"
+	}
+
+	u, err := url.Parse(strings.ToLower(scheme) + "://" + rest)
+	if err != nil {
+		return "", fmt.Errorf("invalid broker address %q: %w", addr, err)
+	}
+
+	s, ok := brokerSchemes[u.Scheme]
+	if !ok {
+		return "", fmt.Errorf("invalid broker address %q: unsupported scheme %q", addr, scheme)
+	}
+
+	u.Scheme = s
+
+	host := u.Hostname()
+	if host == "" {
+		// The MQTT client connects to localhost if only the port is given
+		host = "127.0.0.1"
+	}
+
+	p := u.Port()
+
+	switch {
+	case p != "":
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid broker address %q: invalid port %q", addr, p)
+		}
+	case port < 0 || port > 65535:
+		return "", fmt.Errorf("invalid broker port %d", port)
+	case port > 0:
+		p = strconv.Itoa(port)
+	default:
+		p = strconv.Itoa(brokerPorts[s])
+	}
+
+	u.Host = net.JoinHostPort(host, p)
+
+	return u.String(), nil
+}
+
+// stripZone returns host without the zone of an IPv6 address.
+func stripZone(host string) string {
+	host, _, _ = strings.Cut(host, "%")
+	return host
+}
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestParseBroker(t *testing.T) {
+	var tests = []struct {
+		addr string
+		port int
+		want string
+	}{
+		{"localhost", 0, "tcp://localhost:1883"},
+		{"localhost", 1884, "tcp://localhost:1884"},
+		{"localhost:1885", 1884, "tcp://localhost:1885"},
+		{"127.0.0.1", 0, "tcp://127.0.0.1:1883"},
+		{"tcp://broker.lan", 0, "tcp://broker.lan:1883"},
+		{"TCP://broker.lan:1883", 0, "tcp://broker.lan:1883"},
+		{"mqtt://broker.lan", 0, "tcp://broker.lan:1883"},
+		{"mqtts://broker.lan", 0, "ssl://broker.lan:8883"},
+		{"tls://broker.lan:8884", 0, "ssl://broker.lan:8884"},
+		{"ssl://broker.lan", 0, "ssl://broker.lan:8883"},
+		{"ws://broker.lan/mqtt", 0, "ws://broker.lan:80/mqtt"},
+		{"wss://broker.lan/mqtt", 0, "wss://broker.lan:443/mqtt"},
+		{"wss://broker.lan:8443/mqtt", 0, "wss://broker.lan:8443/mqtt"},
+		{":1883", 0, "tcp://127.0.0.1:1883"},
+		{"::1", 0, "tcp://[::1]:1883"},
+		{"::1", 1884, "tcp://[::1]:1884"},
+		{"2001:db8::1", 0, "tcp://[2001:db8::1]:1883"},
+		{"[2001:db8::1]:1884", 0, "tcp://[2001:db8::1]:1884"},
+		{"mqtts://[2001:db8::1]", 0, "ssl://[2001:db8::1]:8883"},
+		{"fe80::1%eth0", 0, "tcp://[fe80::1%25eth0]:1883"},
+		{"tcp://[fe80::1%25eth0]:1884", 0, "tcp://[fe80::1%25eth0]:1884"},
+		// A host ending in digits isn't mistaken for a port
+		{"broker1", 0, "tcp://broker1:1883"},
+		{"192.168.1.10", 1884, "tcp://192.168.1.10:1884"},
+	}
+	for _, tt := range tests {
+		got, err := config.ParseBroker(tt.addr, tt.port)
+		if err != nil {
+			t.Errorf("%q: %v", tt.addr, err)
+		} else if got != tt.want {
+			t.Errorf("%q: want %q, got %q", tt.addr, tt.want, got)
+		}
+	}
+
+	var invalid = []struct {
+		addr string
+		port int
+	}{
+		{"", 0},
+		{"http://broker.lan", 0},
+		{"tcp://broker.lan:port", 0},
+		{"tcp://broker.lan:70000", 0},
+		{"broker.lan", -1},
+		{"broker.lan", 70000},
+		{"[::1", 0},
+	}
+	for _, tt := range invalid {
+		if got, err := config.ParseBroker(tt.addr, tt.port); err == nil {
+			t.Errorf("%q, %d: want error, got %q", tt.addr, tt.port, got)
+		}
+	}
+}
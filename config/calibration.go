@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// Calibration is the correction of the readings of a sensor, for firmware
+// that reports skewed values. Each reading is multiplied by Multiplier, then
+// Offset is added.
+type Calibration struct {
+	// Offset is added to each reading after it's multiplied, in the unit of
+	// the reading, such as °C for temperatures.
+	Offset float64 `yaml:"offset,omitempty"`
+	// Multiplier is what each reading is multiplied by. If 0 (default) then
+	// the readings aren't multiplied.
+	Multiplier float64 `yaml:"multiplier,omitempty"`
+}
+
+// IsZero indicates whether cfg is the default value, which doesn't change
+// the readings.
+func (cfg Calibration) IsZero() bool {
+	return cfg == Calibration{}
+}
+
+// Apply returns v corrected by cfg.
+func (cfg Calibration) Apply(v float64) float64 {
+	if cfg.Multiplier != 0 {
+		v *= cfg.Multiplier
+	}
+
+	return v + cfg.Offset
+}
+
+func (cfg Calibration) validate(name string) error {
+	if math.IsNaN(cfg.Offset) || math.IsInf(cfg.Offset, 0) {
+		return fmt.Errorf("invalid %s offset %v, must be finite", name, cfg.Offset)
+	}
+
+	if math.IsNaN(cfg.Multiplier) || math.IsInf(cfg.Multiplier, 0) || cfg.Multiplier < 0 {
+		return fmt.Errorf("invalid %s multiplier %v, must be positive", name, cfg.Multiplier)
+	}
+
+	return nil
+}
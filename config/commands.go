@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// CommandConfig is the configuration of a local command that may be run by
+// publishing to its topic. The payload of the message is passed to the command
+// as stdin and as the environment variable $MQTTOP_PAYLOAD, unless it contains
+// a NUL byte.
+type CommandConfig struct {
+	// Name is the name of the command.
+	Name string `yaml:"name"`
+	// Topic is the topic the command is subscribed to. The default value
+	// is "~/command/<name>".
+	Topic string `yaml:"topic,omitempty"`
+	// Command is the program and its arguments. The program is run directly,
+	// not with a shell.
+	Command []string `yaml:"command"`
+	// Timeout is how long the command may run before it is killed. The default
+	// value is 1m.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// User is the name or ID of the user to run the command as. If blank
+	// (default) then the command is run as the same user as the bridge.
+	// Running as a different user requires root.
+	User string `yaml:"user,omitempty"`
+	// Env is the names of the environment variables of the bridge that are
+	// passed to the command. Otherwise the command only has $PATH, $HOME of
+	// the user it runs as, $MQTTOP_PAYLOAD and $MQTTOP_TOPIC, so that secrets
+	// in the environment of the bridge aren't leaked to the command.
+	Env []string `yaml:"env,omitempty"`
+}
+
+// DefaultCommandTimeout is the timeout of a command if not configured.
+const DefaultCommandTimeout = time.Minute
@@ -10,13 +10,33 @@
 //   - broker:   $MQTTOP_BROKER_ADDRESS
 //   - username: $MQTTOP_BROKER_USERNAME
 //   - password: $MQTTOP_BROKER_PASSWORD
+//
+// A config file may include other files with the "include" key, which is a path
+// or list of paths relative to the including file. The included files are read
+// first and the values of the including file override theirs. Mappings are merged
+// and all other values, including lists, are replaced.
+//
+//	include:
+//	  - common.yaml
+//	  - secrets/
+//
+// Named profiles may be defined with the "profiles" key. Each profile overrides the
+// rest of the config when activated with the --profile flag or $MQTTOP_PROFILE.
+//
+//	profiles:
+//	  laptop:
+//	    battery:
+//	      enabled: true
+//	  server:
+//	    interval: 10s
 package config
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"reflect"
 	"slices"
 	"strings"
 	"text/template"
@@ -25,7 +45,6 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/lone-faerie/mqttop/config/secrets"
-	"github.com/lone-faerie/mqttop/internal/file"
 	"github.com/lone-faerie/mqttop/log"
 )
 
@@ -45,30 +64,93 @@ type Config struct {
 	// For example if BaseTopic is "foo" then
 	// "~/bridge/status" becomes "foo/bridge/status"
 	BaseTopic string `yaml:"base_topic"`
-
-	MQTT      MQTTConfig      `yaml:"mqtt,omitempty"`
-	Discovery DiscoveryConfig `yaml:"discovery,omitempty"`
-	Log       LogConfig       `yaml:"log,omitempty"`
-	CPU       CPUConfig       `yaml:"cpu,omitempty"`
-	Memory    MemoryConfig    `yaml:"memory,omitempty"`
-	Disks     DisksConfig     `yaml:"disks,omitempty"`
-	Net       NetConfig       `yaml:"net,omitempty"`
-	Battery   BatteryConfig   `yaml:"battery,omitempty"`
-	Dirs      []DirConfig     `yaml:"dirs,omitempty"`
-	GPU       GPUConfig       `yaml:"gpu,omitempty"`
+	// Instance is the (optional) name of this instance, for running multiple
+	// instances on the same host, such as one per user or container. It may
+	// only consist of characters from [a-zA-Z0-9_-]. If set, the instance name
+	// is appended to the default client id, base topic, discovery device and
+	// data path, so that the instances don't conflict. For example if Instance
+	// is "alice" then the default base topic becomes "mqttop/alice".
+	Instance string `yaml:"instance,omitempty"`
+	// Precision is the default number of decimal places, from 1 to 6, of the
+	// fixed-point values in the payloads of the metrics, such as temperatures,
+	// frequencies and power. Insignificant trailing zeros are always trimmed.
+	// If -1 then the values are rounded to whole numbers. If 0 (default) then
+	// DefaultPrecision is used.
+	Precision int `yaml:"precision,omitempty"`
+	// RootFS is the (optional) directory the root of the host filesystem is
+	// mounted at, such as "/host" in a container, that /proc, /sys and /etc
+	// are read from. If blank (default) then the value of $MQTTOP_ROOTFS_PATH
+	// is used, otherwise "/".
+	RootFS string `yaml:"rootfs,omitempty"`
+	// MaxConcurrentUpdates is the maximum number of entities, such as disks,
+	// network interfaces and directory scans, that are updated concurrently
+	// across every metric. If 0 (default) then the maximum is the number of CPUs.
+	MaxConcurrentUpdates int `yaml:"max_concurrent_updates,omitempty"`
+
+	MQTT       MQTTConfig       `yaml:"mqtt,omitempty"`
+	Transport  TransportConfig  `yaml:"transport,omitempty"`
+	Encryption EncryptionConfig `yaml:"encryption,omitempty"`
+	Signing    SigningConfig    `yaml:"signing,omitempty"`
+	Tracing    TracingConfig    `yaml:"tracing,omitempty"`
+	Discovery  DiscoveryConfig  `yaml:"discovery,omitempty"`
+	Log        LogConfig        `yaml:"log,omitempty"`
+	Runtime    RuntimeConfig    `yaml:"runtime,omitempty"`
+	Stats      StatsConfig      `yaml:"stats,omitempty"`
+	Events     EventsConfig     `yaml:"events,omitempty"`
+	Watchdog   WatchdogConfig   `yaml:"watchdog,omitempty"`
+	Summary    SummaryConfig    `yaml:"summary,omitempty"`
+	Lazy       LazyConfig       `yaml:"lazy,omitempty"`
+	Controls   ControlsConfig   `yaml:"controls,omitempty"`
+	Power      PowerConfig      `yaml:"power_commands,omitempty"`
+	WOL        []WOLConfig      `yaml:"wol,omitempty"`
+	Commands   []CommandConfig  `yaml:"commands,omitempty"`
+	Derived    []DerivedConfig  `yaml:"derived,omitempty"`
+	CPU        CPUConfig        `yaml:"cpu,omitempty"`
+	Memory     MemoryConfig     `yaml:"memory,omitempty"`
+	Disks      DisksConfig      `yaml:"disks,omitempty"`
+	Net        NetConfig        `yaml:"net,omitempty"`
+	Battery    BatteryConfig    `yaml:"battery,omitempty"`
+	Fans       FansConfig       `yaml:"fans,omitempty"`
+	Audio      AudioConfig      `yaml:"audio,omitempty"`
+	Idle       IdleConfig       `yaml:"idle,omitempty"`
+	Processes  ProcessesConfig  `yaml:"processes,omitempty"`
+	System     SystemConfig     `yaml:"system,omitempty"`
+	Smart      SmartConfig      `yaml:"smart,omitempty"`
+	Energy     EnergyConfig     `yaml:"energy,omitempty"`
+	Dirs       []DirConfig      `yaml:"dirs,omitempty"`
+	GPU        GPUConfig        `yaml:"gpu,omitempty"`
 }
 
+const defaultBaseTopic = "mqttop"
+
+// DefaultPrecision is the number of decimal places of the fixed-point values
+// in payloads if no precision is configured.
+const DefaultPrecision = 3
+
 func defaultCfg() *Config {
 	return &Config{
 		Interval:  2 * time.Second,
-		BaseTopic: "mqttop",
+		BaseTopic: defaultBaseTopic,
 		MQTT:      DefaultMQTT,
 		Discovery: DefaultDiscovery,
+		Runtime:   DefaultRuntime,
+		Stats:     DefaultStats,
+		Watchdog:  DefaultWatchdog,
+		Summary:   DefaultSummary,
+		Lazy:      DefaultLazy,
+		Power:     DefaultPower,
 		CPU:       DefaultCPU,
 		Memory:    DefaultMemory,
 		Disks:     DefaultDisks,
 		Net:       DefaultNet,
 		Battery:   DefaultBattery,
+		Fans:      DefaultFans,
+		Audio:     DefaultAudio,
+		Idle:      DefaultIdle,
+		Processes: DefaultProcesses,
+		System:    DefaultSystem,
+		Smart:     DefaultSmart,
+		Energy:    DefaultEnergy,
 		GPU:       DefaultGPU,
 	}
 }
@@ -80,11 +162,24 @@ func defaultCfg() *Config {
 //		TopicPrefix: "mqttop",
 //		MQTT:        DefaultMQTT,
 //		Discovery:   DefaultDiscovery,
+//		Runtime:     DefaultRuntime,
+//		Stats:       DefaultStats,
+//		Watchdog:    DefaultWatchdog,
+//		Summary:     DefaultSummary,
+//		Lazy:        DefaultLazy,
+//		Power:       DefaultPower,
 //		CPU:         DefaultCPU,
 //		Memory:      DefaultMemory,
 //		Disks:       DefaultDisks,
 //		Net:         DefaultNet,
 //		Battery:     DefaultBattery,
+//		Fans:        DefaultFans,
+//		Audio:       DefaultAudio,
+//		Idle:        DefaultIdle,
+//		Processes:   DefaultProcesses,
+//		System:      DefaultSystem,
+//		Smart:       DefaultSmart,
+//		Energy:      DefaultEnergy,
 //		GPU:         DefaultGPU,
 //	}
 func Default() *Config {
@@ -94,16 +189,22 @@ func Default() *Config {
 	return cfg
 }
 
-// Read returns the Config parsed from the yaml encoded config from r.
-func Read(r io.Reader) (cfg *Config, err error) {
-	cfg = defaultCfg()
-	if err = yaml.NewDecoder(r).Decode(cfg); err != nil {
-		return
+// Read returns the Config parsed from the yaml encoded config from r. Any
+// files included by the config are relative to the current directory, and the
+// profiles listed in $MQTTOP_PROFILE are applied.
+func Read(r io.Reader) (*Config, error) {
+	m, err := decode(r)
+	if err != nil {
+		return nil, err
 	}
 
-	err = cfg.init()
+	var l fileLoader
 
-	return
+	if m, err = l.resolve(m, "."); err != nil {
+		return nil, err
+	}
+
+	return decodeNode(m, nil)
 }
 
 func hasNonYAML(filenames []string) bool {
@@ -120,11 +221,20 @@ func hasNonYAML(filenames []string) bool {
 
 // Load returns the Config parsed from the given yaml files. If the first file does
 // not exist, the default config is returned. If any of the given paths are
-// directories, all the files in the directory are read. If none of the given filenames
-// have an extension, they are assumed to be directories and only files with the
-// extensions ".yml" or ".yaml" will be read.
+// directories, all the files in the directory are read in lexical order. If none
+// of the given filenames have an extension, they are assumed to be directories and
+// only files with the extensions ".yml" or ".yaml" will be read. Each file overrides
+// the values of the files before it. The profiles listed in $MQTTOP_PROFILE are
+// applied, see [LoadProfile].
 func Load(filename ...string) (cfg *Config, err error) {
-	log.Info("Loading config", "path", filename)
+	return LoadProfile(nil, filename...)
+}
+
+// LoadProfile is like [Load] but applies the given profiles in order, overriding
+// the values of the files with the values of each profile. If profiles is nil,
+// the profiles listed in $MQTTOP_PROFILE are applied.
+func LoadProfile(profiles []string, filename ...string) (cfg *Config, err error) {
+	log.Info("Loading config", "path", filename, "profiles", profiles)
 
 	if len(filename) == 0 {
 		return Default(), nil
@@ -134,19 +244,14 @@ func Load(filename ...string) (cfg *Config, err error) {
 		return Default(), nil
 	}
 
-	r := file.NewMultiReader(filename...)
-	if !hasNonYAML(filename) {
-		r.WithExtension(".yml", ".yaml")
-	}
+	var l fileLoader
 
-	defer r.Close()
-	cfg, err = Read(r)
-
-	if err == io.EOF {
-		err = nil
+	m, err := l.load(filename, !hasNonYAML(filename))
+	if err != nil {
+		return
 	}
 
-	return
+	return decodeNode(m, profiles)
 }
 
 // ReplaceBase returns topic with the prefix and/or suffix "~" replaced with base.
@@ -165,7 +270,25 @@ func ReplaceBase(base, topic string) string {
 	return topic
 }
 
+//go:generate go run gen.go
+
 func (cfg *Config) init() (err error) {
+	if cfg.Instance = Expand(cfg.Instance); cfg.Instance != "" {
+		if !validInstance(cfg.Instance) {
+			return fmt.Errorf("invalid instance %q, may only consist of characters from [a-zA-Z0-9_-]", cfg.Instance)
+		}
+
+		if cfg.MQTT.ClientID == "" {
+			cfg.MQTT.ClientID = defaultBaseTopic + "_" + cfg.Instance
+		}
+
+		if cfg.BaseTopic == "" || cfg.BaseTopic == defaultBaseTopic {
+			cfg.BaseTopic = defaultBaseTopic + "/" + cfg.Instance
+		}
+	}
+
+	cfg.Discovery.Instance = cfg.Instance
+
 	if cfg.BaseTopic != "" {
 		log.Debug("Replacing base topic", "old", "~", "new", cfg.BaseTopic)
 
@@ -173,51 +296,100 @@ func (cfg *Config) init() (err error) {
 		cfg.Discovery.Availability = ReplaceBase(cfg.BaseTopic, cfg.Discovery.Availability)
 	}
 
-	var (
-		v = reflect.ValueOf(cfg).Elem()
-		n = v.NumField()
-	)
-	for i := 0; i < n; i++ {
-		cfg.forValue(v.Field(i), "")
+	cfg.initFields()
+
+	if !cfg.MQTT.CleanSession && cfg.MQTT.ClientID == "" {
+		return errors.New("client_id is required if clean_session is false")
 	}
 
-	return
-}
+	if cfg.MQTT.BirthPayload == "" {
+		cfg.MQTT.BirthPayload = DefaultMQTT.BirthPayload
+	}
 
-var topicFields = []string{
-	"BirthWillTopic", "Availability", "Topic",
-}
+	if cfg.MQTT.WillPayload == "" {
+		cfg.MQTT.WillPayload = DefaultMQTT.WillPayload
+	}
+
+	if cfg.MQTT.BirthQoS > 2 {
+		return fmt.Errorf("invalid birth_qos %d, must be 0, 1 or 2", cfg.MQTT.BirthQoS)
+	}
+
+	if cfg.MQTT.WillQoS > 2 {
+		return fmt.Errorf("invalid lwt_qos %d, must be 0, 1 or 2", cfg.MQTT.WillQoS)
+	}
+
+	if cfg.Precision < -1 || cfg.Precision > 6 {
+		return fmt.Errorf("invalid precision %d, must be from -1 to 6", cfg.Precision)
+	}
 
-func (cfg *Config) forValue(v reflect.Value, field string) {
-	switch v.Kind() {
-	case reflect.String:
-		s := Expand(v.String())
-		if s != "" && cfg.BaseTopic != "" && slices.Contains(topicFields, field) {
-			s = ReplaceBase(cfg.BaseTopic, s)
+	for _, c := range []struct {
+		name string
+		cal  Calibration
+	}{
+		{"cpu temperature_calibration", cfg.CPU.TemperatureCalibration},
+		{"gpu temperature_calibration", cfg.GPU.TemperatureCalibration},
+		{"battery capacity_calibration", cfg.Battery.CapacityCalibration},
+		{"battery voltage_calibration", cfg.Battery.VoltageCalibration},
+	} {
+		if err := c.cal.validate(c.name); err != nil {
+			return err
 		}
+	}
+
+	names := make(map[string]bool, len(cfg.Derived))
 
-		v.SetString(s)
-	case reflect.Struct:
-		iface := v.Addr().Interface()
-		if l, ok := iface.(loader); ok {
-			l.load(cfg)
+	for i := range cfg.Derived {
+		if err := cfg.Derived[i].validate(); err != nil {
+			return err
 		}
 
-		t := v.Type()
-		n := v.NumField()
+		if names[cfg.Derived[i].Name] {
+			return fmt.Errorf("duplicate derived value name %q", cfg.Derived[i].Name)
+		}
+
+		names[cfg.Derived[i].Name] = true
+	}
 
-		for i := 0; i < n; i++ {
-			f := t.Field(i)
-			cfg.forValue(v.FieldByIndex(f.Index), f.Name)
+	if cfg.RootFS != "" {
+		if info, err := os.Stat(cfg.RootFS); err != nil {
+			return fmt.Errorf("invalid rootfs: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid rootfs %q, must be a directory", cfg.RootFS)
 		}
-	case reflect.Slice, reflect.Array:
-		n := v.Len()
-		for i := 0; i < n; i++ {
-			cfg.forValue(v.Index(i), "")
+	}
+
+	cfg.Discovery.RootFS = cfg.RootFS
+
+	if cfg.MQTT.BirthWillEnabled {
+		cfg.Discovery.PayloadAvailable = cfg.MQTT.BirthPayload
+		cfg.Discovery.PayloadNotAvailable = cfg.MQTT.WillPayload
+	}
+
+	if cfg.MQTT.Broker != "" {
+		cfg.MQTT.Broker, err = ParseBroker(cfg.MQTT.Broker, 0)
+	}
+
+	return
+}
+
+// validInstance reports whether s only consists of characters from
+// [a-zA-Z0-9_-], so that it may be used in topics, ids and paths.
+func validInstance(s string) bool {
+	for _, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '-':
+		default:
+			return false
 		}
-	case reflect.Pointer:
-		cfg.forValue(v.Elem(), "")
 	}
+
+	return true
+}
+
+// expandTopic returns topic expanded by [Expand], with the prefix and/or suffix
+// "~" replaced with the base topic.
+func (cfg *Config) expandTopic(topic string) string {
+	return ReplaceBase(cfg.BaseTopic, Expand(topic))
 }
 
 // Expand replaces "!secret var" according to the file at /run/secret/<var>
@@ -241,54 +413,26 @@ func (cfg *Config) Write(w io.Writer) error {
 	return enc.Encode(cfg)
 }
 
-func setInterval(v reflect.Value, d time.Duration) {
-	switch v.Kind() {
-	case reflect.Pointer:
-		setInterval(v.Elem(), d)
-	case reflect.Slice:
-		n := v.Len()
-		for i := 0; i < n; i++ {
-			setInterval(v.Index(i), d)
-		}
-	case reflect.Struct:
-		if f := v.FieldByName("Interval"); f.IsValid() && f.Kind() == reflect.Int64 {
-			f.SetInt(int64(d))
-		}
-
-		n := v.NumField()
-		for i := 0; i < n; i++ {
-			setInterval(v.Field(i), d)
-		}
-	}
-}
-
 // SetInterval sets the update interval for every metric config.
 func (cfg *Config) SetInterval(d time.Duration) {
-	setInterval(reflect.ValueOf(cfg).Elem(), d)
+	cfg.setInterval(d)
+}
+
+// SetRootFS sets the root of the host filesystem that the metrics and the
+// discovery device are read from.
+func (cfg *Config) SetRootFS(dir string) {
+	cfg.RootFS = dir
+	cfg.Discovery.RootFS = dir
 }
 
 // SetMetrics enables each of the given metrics and disables all others.
 // If only the value "all" is given, all metrics will be enabled.
 func (cfg *Config) SetMetrics(name ...string) {
 	enableAll := len(name) == 1 && name[0] == "all"
-	v := reflect.ValueOf(cfg).Elem()
-	t := v.Type()
-	n := t.NumField()
-
-	for i := 0; i < n; i++ {
-		f := t.Field(i)
-		if f.Type.Kind() != reflect.Struct {
-			continue
-		}
-
-		if _, ok := f.Type.FieldByName("MetricConfig"); !ok {
-			continue
-		}
 
-		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
-		enabled := enableAll || slices.Contains(name, tag)
-		v.FieldByIndex(f.Index).FieldByName("MetricConfig").FieldByName("Enabled").SetBool(enabled)
-	}
+	cfg.setEnabled(func(metric string) bool {
+		return enableAll || slices.Contains(name, metric)
+	})
 }
 
 var customTemplateFuncs map[string]any
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// topicFields are the fields that should have "~" replaced with the base topic.
+var topicFields = []string{"BirthWillTopic", "Availability", "Topic"}
+
+// walkFields calls fn with the path of every exported field of v, appending a
+// zero element to every empty slice of structs or strings so that their fields
+// are also walked.
+func walkFields(v reflect.Value, path string, fn func(path, name string, v reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			fn(path+"."+f.Name, f.Name, v.Field(i))
+			walkFields(v.Field(i), path+"."+f.Name, fn)
+		}
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Struct, reflect.String:
+		default:
+			return
+		}
+
+		if v.Len() == 0 {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+
+		for i := range v.Len() {
+			fn(path+"[]", "", v.Index(i))
+			walkFields(v.Index(i), path+"[]", fn)
+		}
+	}
+}
+
+func TestInitFields(t *testing.T) {
+	t.Setenv("MQTTOP_TEST_FIELD", "expanded")
+
+	// The rootfs must be an existing directory
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("expanded", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := defaultCfg()
+	cfg.BaseTopic = "base"
+
+	walkFields(reflect.ValueOf(cfg).Elem(), "cfg", func(path, name string, v reflect.Value) {
+		if v.Kind() != reflect.String || name == "BaseTopic" {
+			return
+		}
+
+		if slices.Contains(topicFields, name) {
+			v.SetString("~/$MQTTOP_TEST_FIELD")
+		} else {
+			v.SetString("$MQTTOP_TEST_FIELD")
+		}
+	})
+
+	if err := cfg.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+
+	walkFields(reflect.ValueOf(cfg).Elem(), "cfg", func(path, name string, v reflect.Value) {
+		if v.Kind() != reflect.String || name == "BaseTopic" {
+			return
+		}
+
+		n++
+
+		want := "expanded"
+		if slices.Contains(topicFields, name) {
+			want = "base/expanded"
+		} else if name == "Broker" {
+			// The broker is parsed into a URI after expanding
+			want = "tcp://expanded:1883"
+		}
+
+		if got := v.String(); got != want {
+			t.Errorf("%s: want %q, got %q", path, want, got)
+		}
+	})
+
+	if n == 0 {
+		t.Fatal("no string fields")
+	}
+}
+
+func TestSetInterval(t *testing.T) {
+	cfg := defaultCfg()
+
+	// Walk once to add slice elements so they are set as well.
+	walkFields(reflect.ValueOf(cfg).Elem(), "cfg", func(string, string, reflect.Value) {})
+
+	cfg.SetInterval(time.Minute)
+
+	walkFields(reflect.ValueOf(cfg).Elem(), "cfg", func(path, name string, v reflect.Value) {
+		if name != "Interval" {
+			return
+		}
+
+		if got := time.Duration(v.Int()); got != time.Minute {
+			t.Errorf("%s: want %v, got %v", path, time.Minute, got)
+		}
+	})
+}
+
+// metricFields returns the yaml names and values of the metric configs of cfg.
+func metricFields(cfg *Config) (names []string, values []reflect.Value) {
+	v := reflect.ValueOf(cfg).Elem()
+
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if f.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		if _, ok := f.Type.FieldByName("MetricConfig"); ok {
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			names = append(names, name)
+			values = append(values, v.Field(i))
+		}
+	}
+
+	return
+}
+
+func enabledMetrics(cfg *Config) (enabled []string) {
+	names, values := metricFields(cfg)
+
+	for i, v := range values {
+		if v.FieldByName("Enabled").Bool() {
+			enabled = append(enabled, names[i])
+		}
+	}
+
+	return
+}
+
+func TestSetMetrics(t *testing.T) {
+	names, _ := metricFields(defaultCfg())
+
+	for _, name := range names {
+		cfg := defaultCfg()
+		cfg.SetMetrics(name)
+
+		if got := enabledMetrics(cfg); !slices.Equal(got, []string{name}) {
+			t.Errorf("SetMetrics(%q): want [%s], got %v", name, name, got)
+		}
+	}
+
+	cfg := defaultCfg()
+	cfg.SetMetrics("all")
+
+	if got := enabledMetrics(cfg); !slices.Equal(got, names) {
+		t.Errorf("SetMetrics(\"all\"): want %v, got %v", names, got)
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	cfg, err := Read(strings.NewReader("cpu:\n  name_template: '{{ .Name | toupper }}'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "INTEL", cfg.CPU.FormatName("intel"); got != want {
+		t.Errorf("FormatName: want %q, got %q", want, got)
+	}
+}
+
+// TestGenerated checks that config_gen.go is up to date with the config structs.
+func TestGenerated(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("Skipping generate:", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "config_gen.go")
+
+	if out, err := exec.Command("go", "run", "gen.go", "-output", output).CombinedOutput(); err != nil {
+		t.Fatalf("go run gen.go: %v\n%s", err, out)
+	}
+
+	want, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile("config_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("config_gen.go is out of date, run go generate ./config")
+	}
+}
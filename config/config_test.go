@@ -49,6 +49,120 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadInstance(t *testing.T) {
+	var tests = []struct {
+		yaml     string
+		clientID string
+		base     string
+		status   string
+	}{
+		{"instance: alice", "mqttop_alice", "mqttop/alice", "mqttop/alice/bridge/status"},
+		{"instance: alice\nbase_topic: foo", "mqttop_alice", "foo", "foo/bridge/status"},
+		{"instance: alice\nmqtt:\n  client_id: foo", "foo", "mqttop/alice", "mqttop/alice/bridge/status"},
+		{"base_topic: foo", "", "foo", "foo/bridge/status"},
+	}
+	for _, tt := range tests {
+		cfg, err := config.Read(strings.NewReader(tt.yaml))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.yaml, err)
+		}
+		if cfg.MQTT.ClientID != tt.clientID {
+			t.Errorf("%q: client id: want %q, got %q", tt.yaml, tt.clientID, cfg.MQTT.ClientID)
+		}
+		if cfg.BaseTopic != tt.base {
+			t.Errorf("%q: base topic: want %q, got %q", tt.yaml, tt.base, cfg.BaseTopic)
+		}
+		if cfg.MQTT.BirthWillTopic != tt.status {
+			t.Errorf("%q: status topic: want %q, got %q", tt.yaml, tt.status, cfg.MQTT.BirthWillTopic)
+		}
+		if cfg.Discovery.Instance != cfg.Instance {
+			t.Errorf("%q: discovery instance: want %q, got %q", tt.yaml, cfg.Instance, cfg.Discovery.Instance)
+		}
+	}
+
+	if _, err := config.Read(strings.NewReader("instance: a/b")); err == nil {
+		t.Error("invalid instance: want error, got nil")
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	cfg, err := config.Read(strings.NewReader("precision: 1\ncpu:\n  precision: -1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Precision != 1 {
+		t.Errorf("Precision: want 1, got %d", cfg.Precision)
+	}
+	if cfg.CPU.Precision != -1 {
+		t.Errorf("CPU precision: want -1, got %d", cfg.CPU.Precision)
+	}
+
+	for _, yaml := range []string{"precision: 7", "precision: -2"} {
+		if _, err := config.Read(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%q: want error, got nil", yaml)
+		}
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	cfg, err := config.Read(strings.NewReader("cpu:\n  temperature_calibration:\n    offset: -5\nbattery:\n  capacity_calibration:\n    multiplier: 1.25\n    offset: 2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		cal  config.Calibration
+		v    float64
+		want float64
+	}{
+		{"cpu", cfg.CPU.TemperatureCalibration, 50, 45},
+		{"gpu", cfg.GPU.TemperatureCalibration, 50, 50},
+		{"battery", cfg.Battery.CapacityCalibration, 40, 52},
+	}
+	for _, tt := range tests {
+		if got := tt.cal.Apply(tt.v); got != tt.want {
+			t.Errorf("%s: Apply(%v): want %v, got %v", tt.name, tt.v, tt.want, got)
+		}
+	}
+
+	if !cfg.GPU.TemperatureCalibration.IsZero() {
+		t.Error("gpu: want zero calibration")
+	}
+
+	for _, yaml := range []string{
+		"cpu:\n  temperature_calibration:\n    multiplier: -1",
+		"gpu:\n  temperature_calibration:\n    offset: .nan",
+		"battery:\n  voltage_calibration:\n    multiplier: .inf",
+	} {
+		if _, err := config.Read(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%q: want error, got nil", yaml)
+		}
+	}
+}
+
+func TestDerived(t *testing.T) {
+	cfg, err := config.Read(strings.NewReader("derived:\n  - name: vm_pressure\n    expression: cpu.usage*0.6 + memory.used/memory.total*40\n    unit: '%'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Derived) != 1 || cfg.Derived[0].Name != "vm_pressure" || cfg.Derived[0].Unit != "%" {
+		t.Errorf("want vm_pressure, got %+v", cfg.Derived)
+	}
+
+	for _, yaml := range []string{
+		"derived:\n  - expression: cpu.usage",
+		"derived:\n  - name: vm pressure\n    expression: cpu.usage",
+		"derived:\n  - name: vm_pressure\n    expression: sqrt(cpu.usage)",
+		"derived:\n  - name: a\n    expression: cpu.usage\n  - name: a\n    expression: memory.used",
+	} {
+		if _, err := config.Read(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%q: want error, got nil", yaml)
+		}
+	}
+}
+
 func TestReplaceBase(t *testing.T) {
 	var tests = []struct {
 		base  string
@@ -297,4 +411,59 @@ func TestMQTTClientOptions(t *testing.T) {
 			t.Errorf("ResumeSubs: wanted true, got false")
 		}
 	})
+
+	t.Run("Session", func(t *testing.T) {
+		cfg, err := config.Read(strings.NewReader("mqtt:\n  client_id: foo\n  clean_session: false\n  store_enabled: true\n  store_path: " + t.TempDir()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := cfg.MQTT.ClientOptions()
+
+		if got.CleanSession {
+			t.Errorf("CleanSession: wanted false, got true")
+		}
+		if _, ok := got.Store.(*mqtt.FileStore); !ok {
+			t.Errorf("Store: wanted *mqtt.FileStore, got %T", got.Store)
+		}
+
+		if _, err = config.Read(strings.NewReader("mqtt:\n  clean_session: false")); err == nil {
+			t.Error("clean_session without client_id: want error, got nil")
+		}
+	})
+
+	t.Run("Will", func(t *testing.T) {
+		cfg, err := config.Read(strings.NewReader("mqtt:\n  birth_payload: up\n  lwt_payload: down\n  lwt_qos: 2\n  lwt_retained: false"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := cfg.MQTT.ClientOptions()
+
+		if got.WillTopic != "mqttop/bridge/status" {
+			t.Errorf("WillTopic: wanted %q, got %q", "mqttop/bridge/status", got.WillTopic)
+		}
+		if string(got.WillPayload) != "down" {
+			t.Errorf("WillPayload: wanted %q, got %q", "down", got.WillPayload)
+		}
+		if got.WillQos != 2 {
+			t.Errorf("WillQos: wanted 2, got %d", got.WillQos)
+		}
+		if got.WillRetained {
+			t.Errorf("WillRetained: wanted false, got true")
+		}
+		if cfg.MQTT.BirthQoS != 1 || !cfg.MQTT.BirthRetained {
+			t.Errorf("Birth: wanted QoS 1 retained, got QoS %d retained %v", cfg.MQTT.BirthQoS, cfg.MQTT.BirthRetained)
+		}
+		if cfg.Discovery.PayloadAvailable != "up" {
+			t.Errorf("PayloadAvailable: wanted %q, got %q", "up", cfg.Discovery.PayloadAvailable)
+		}
+		if cfg.Discovery.PayloadNotAvailable != "down" {
+			t.Errorf("PayloadNotAvailable: wanted %q, got %q", "down", cfg.Discovery.PayloadNotAvailable)
+		}
+
+		if _, err = config.Read(strings.NewReader("mqtt:\n  birth_qos: 3")); err == nil {
+			t.Error("birth_qos 3: want error, got nil")
+		}
+	})
 }
@@ -0,0 +1,49 @@
+package config
+
+// ControlsConfig is the configuration for controls, which allow changing the
+// state of the system over MQTT. Every control is disabled by default, and most
+// require the bridge to be run as root.
+type ControlsConfig struct {
+	// Boost enables toggling the frequency boost (turbo) of the CPU by publishing
+	// "ON" or "OFF" to the "/boost/set" subtopic of the cpu metric.
+	Boost bool `yaml:"boost,omitempty"`
+	// Governor enables setting the scaling governor of every core of the CPU by
+	// publishing one of the available governors to the "/governor/set" subtopic
+	// of the cpu metric.
+	Governor bool `yaml:"governor,omitempty"`
+	// Volume enables setting the volume from 0 to 100 by publishing to the
+	// "/volume/set" subtopic of the audio metric, and muting by publishing "ON"
+	// or "OFF" to the "/mute/set" subtopic.
+	Volume bool `yaml:"volume,omitempty"`
+	// Fans enables setting the PWM duty cycle of fans, see [FanControlConfig].
+	Fans FanControlConfig `yaml:"fans,omitempty"`
+	// GPUPowerLimit enables setting the power limit of the GPU in W by publishing
+	// to the "/power_limit/set" subtopic of the gpu metric. The limit is clamped
+	// to the minimum and maximum limits of the GPU.
+	GPUPowerLimit bool `yaml:"gpu_power_limit,omitempty"`
+	// GPUPersistence enables toggling the persistence mode of the GPU by
+	// publishing "ON" or "OFF" to the "/persistence/set" subtopic of the gpu
+	// metric.
+	GPUPersistence bool `yaml:"gpu_persistence,omitempty"`
+}
+
+// FanControlConfig is the configuration for controlling the PWM duty cycle of
+// fans. A duty cycle from 0 to 255 may be published to the "/<fan>/pwm/set"
+// subtopic of the fans metric, which switches the fan to manual control. The
+// fan may be returned to automatic control by publishing "auto". When the
+// bridge stops, every fan is returned to the mode it was in before it was
+// first set.
+type FanControlConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MinPWM is the minimum duty cycle that may be set, any lower value is
+	// clamped to MinPWM. This should be high enough to keep the fans spinning.
+	MinPWM int `yaml:"min_pwm,omitempty"`
+	// MaxPWM is the maximum duty cycle that may be set, any higher value is
+	// clamped to MaxPWM. If 0 (default) then the maximum is 255.
+	MaxPWM int `yaml:"max_pwm,omitempty"`
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg ControlsConfig) IsZero() bool {
+	return cfg == ControlsConfig{}
+}
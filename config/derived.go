@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/lone-faerie/mqttop/internal/expr"
+)
+
+// DerivedConfig is the configuration for a value derived from the payloads of
+// the metrics by an expression, such as
+// "cpu.usage*0.6 + memory.used/memory.total*40". The derived values are
+// published together to the "derived" subtopic of the base topic, keyed by
+// their names, after each update of a metric they use.
+type DerivedConfig struct {
+	// Name is the name of the value, used as its key in the payload and for
+	// its sensor. It may only consist of characters from [a-zA-Z0-9_-].
+	Name string `yaml:"name"`
+	// Expression is the expression the value is evaluated from, which has the
+	// syntax of a Go expression. The payload of each metric is the variable
+	// named by its type, such as cpu or disks, and its fields are selected by
+	// name, such as cpu.usage, or by index, such as disks["root"].used. The
+	// functions abs, min, max, round, len and iif(cond, a, b) may be used.
+	Expression string `yaml:"expression"`
+	// Unit is the unit of measurement of the sensor of the value.
+	Unit string `yaml:"unit,omitempty"`
+	// DeviceClass is the device class of the sensor of the value.
+	// See https://www.home-assistant.io/integrations/sensor/#device-class
+	DeviceClass string `yaml:"device_class,omitempty"`
+	// Icon is the icon of the sensor of the value, such as "mdi:gauge".
+	Icon string `yaml:"icon,omitempty"`
+}
+
+// validate returns an error if cfg has no valid name or its expression can't
+// be parsed.
+func (cfg *DerivedConfig) validate() error {
+	if cfg.Name == "" {
+		return fmt.Errorf("derived value for %q has no name", cfg.Expression)
+	}
+
+	if !validInstance(cfg.Name) {
+		return fmt.Errorf("invalid derived value name %q, may only consist of characters from [a-zA-Z0-9_-]", cfg.Name)
+	}
+
+	if _, err := expr.Parse(cfg.Expression); err != nil {
+		return fmt.Errorf("invalid expression of derived value %q: %w", cfg.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package config
+
+// EncryptionConfig is the configuration for the end-to-end encryption of
+// metric payloads, for publishing through brokers that aren't trusted. Each
+// payload is encrypted for every recipient, so that only the holders of their
+// private keys, such as a companion add-on of Home Assistant, may decrypt it.
+// Discovery, availability and the other topics of the bridge aren't
+// encrypted.
+type EncryptionConfig struct {
+	// Recipients are the public keys the payloads are encrypted for, as
+	// printed by "mqttop keygen" or "age-keygen". If empty (default) then
+	// payloads aren't encrypted.
+	Recipients []string `yaml:"recipients,omitempty"`
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg EncryptionConfig) IsZero() bool {
+	return len(cfg.Recipients) == 0
+}
+
+// Enabled indicates if payloads are encrypted.
+func (cfg *EncryptionConfig) Enabled() bool {
+	return len(cfg.Recipients) > 0
+}
@@ -0,0 +1,18 @@
+package config
+
+// EventsConfig is the configuration for the connection events of the bridge,
+// which are published to the "bridge/events" subtopic of the base topic each
+// time the client connects, loses its connection or attempts to reconnect to
+// the broker, so that flaky links may be diagnosed. Events while the client
+// is disconnected are published once it reconnects. Events are only
+// published over MQTT.
+type EventsConfig struct {
+	// Enabled indicates if the connection events are published. The default
+	// value is false
+	Enabled bool `yaml:"enabled"`
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg EventsConfig) IsZero() bool {
+	return cfg == EventsConfig{}
+}
@@ -0,0 +1,586 @@
+//go:build ignore
+
+// This program generates config_gen.go, which traverses the fields of [Config]
+// without reflection. Run it with go generate after changing any config struct.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// topicFields are the names of the fields that have "~" replaced with the base topic.
+var topicFields = []string{
+	"BirthWillTopic", "Availability", "Topic",
+}
+
+// scalars are the types of fields that are not traversed.
+var scalars = []string{
+	"bool", "byte", "int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64",
+}
+
+type generator struct {
+	structs map[string]*ast.StructType
+	docs    map[string]string
+	loaders map[string]bool
+	buf     bytes.Buffer
+	depth   int
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) parse(dir string) error {
+	fset := token.NewFileSet()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == "gen.go" || name == "config_gen.go" {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						g.structs[ts.Name.Name] = st
+
+						if doc := ts.Doc; doc != nil {
+							g.docs[ts.Name.Name] = docText(doc)
+						} else if len(decl.Specs) == 1 && decl.Doc != nil {
+							g.docs[ts.Name.Name] = docText(decl.Doc)
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || decl.Name.Name != "load" {
+					continue
+				}
+
+				if star, ok := decl.Recv.List[0].Type.(*ast.StarExpr); ok {
+					if id, ok := star.X.(*ast.Ident); ok {
+						g.loaders[id.Name] = true
+					}
+				}
+			}
+		}
+	}
+
+	if g.structs["Config"] == nil {
+		return fmt.Errorf("no Config struct in %s", dir)
+	}
+
+	return nil
+}
+
+// docLink matches doc links, such as [Config] or [time.ParseDuration].
+var docLink = regexp.MustCompile(`\[([\w.*]+)\]`)
+
+// docText returns the text of doc with doc links replaced by their names.
+func docText(doc *ast.CommentGroup) string {
+	return strings.TrimSpace(docLink.ReplaceAllString(doc.Text(), "$1"))
+}
+
+// field is an exported field of a config struct.
+type field struct {
+	name   string
+	typ    ast.Expr
+	tag    string
+	inline bool
+	doc    string
+}
+
+func (g *generator) fields(typ string) []field {
+	var fields []field
+
+	for _, f := range g.structs[typ].Fields.List {
+		var (
+			tag, opts string
+			doc       string
+		)
+
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			tag, opts, _ = strings.Cut(reflect.StructTag(s).Get("yaml"), ",")
+		}
+
+		if f.Doc != nil {
+			doc = docText(f.Doc)
+		}
+
+		inline := slices.Contains(strings.Split(opts, ","), "inline")
+
+		if len(f.Names) == 0 {
+			if id, ok := f.Type.(*ast.Ident); ok {
+				fields = append(fields, field{id.Name, f.Type, tag, inline, doc})
+			}
+
+			continue
+		}
+
+		for _, name := range f.Names {
+			if name.IsExported() {
+				fields = append(fields, field{name.Name, f.Type, tag, inline, doc})
+			}
+		}
+	}
+
+	return fields
+}
+
+// kind returns "string", "duration", the name of a config struct, or "" if
+// typ is not traversed. Unknown types are fatal so that new fields aren't
+// silently skipped.
+func (g *generator) kind(typ ast.Expr) string {
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		switch {
+		case typ.Name == "string":
+			return "string"
+		case slices.Contains(scalars, typ.Name):
+			return ""
+		case g.structs[typ.Name] != nil:
+			return typ.Name
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := typ.X.(*ast.Ident); ok {
+			switch pkg.Name + "." + typ.Sel.Name {
+			case "time.Duration":
+				return "duration"
+			case "log.Level":
+				return ""
+			}
+		}
+	}
+
+	log.Fatalf("unsupported field type %s", exprString(typ))
+
+	return ""
+}
+
+func exprString(typ ast.Expr) string {
+	var b bytes.Buffer
+
+	format.Node(&b, token.NewFileSet(), typ)
+
+	return b.String()
+}
+
+// index returns the name of the index variable for the current loop depth.
+func (g *generator) index() string {
+	return "i" + strconv.Itoa(g.depth)
+}
+
+func (g *generator) initStruct(path, typ string) {
+	if g.loaders[typ] {
+		g.printf("%s.load(cfg)\n", path)
+	}
+
+	for _, f := range g.fields(typ) {
+		g.initField(path+"."+f.name, f)
+	}
+}
+
+func (g *generator) initField(path string, f field) {
+	if arr, ok := f.typ.(*ast.ArrayType); ok {
+		kind := g.kind(arr.Elt)
+		if kind == "" || kind == "duration" {
+			return
+		}
+
+		g.depth++
+		i := g.index()
+
+		g.printf("for %s := range %s {\n", i, path)
+
+		if kind == "string" {
+			g.printf("%s[%s] = Expand(%s[%s])\n", path, i, path, i)
+		} else {
+			g.initStruct(path+"["+i+"]", kind)
+		}
+
+		g.printf("}\n")
+		g.depth--
+
+		return
+	}
+
+	switch kind := g.kind(f.typ); kind {
+	case "", "duration":
+	case "string":
+		if slices.Contains(topicFields, f.name) {
+			g.printf("%s = cfg.expandTopic(%s)\n", path, path)
+		} else {
+			g.printf("%s = Expand(%s)\n", path, path)
+		}
+	default:
+		g.initStruct(path, kind)
+	}
+}
+
+func (g *generator) intervalStruct(path, typ string) {
+	for _, f := range g.fields(typ) {
+		p := path + "." + f.name
+
+		if arr, ok := f.typ.(*ast.ArrayType); ok {
+			kind := g.kind(arr.Elt)
+			if kind == "" || kind == "string" || kind == "duration" || !g.hasInterval(kind) {
+				continue
+			}
+
+			g.depth++
+			i := g.index()
+
+			g.printf("for %s := range %s {\n", i, p)
+			g.intervalStruct(p+"["+i+"]", kind)
+			g.printf("}\n")
+			g.depth--
+
+			continue
+		}
+
+		switch kind := g.kind(f.typ); kind {
+		case "", "string":
+		case "duration":
+			if f.name == "Interval" {
+				g.printf("%s = d\n", p)
+			}
+		default:
+			g.intervalStruct(p, kind)
+		}
+	}
+}
+
+// hasInterval reports whether typ or any of its fields have an Interval field.
+func (g *generator) hasInterval(typ string) bool {
+	for _, f := range g.fields(typ) {
+		if arr, ok := f.typ.(*ast.ArrayType); ok {
+			if kind := g.kind(arr.Elt); g.structs[kind] != nil && g.hasInterval(kind) {
+				return true
+			}
+
+			continue
+		}
+
+		switch kind := g.kind(f.typ); kind {
+		case "", "string":
+		case "duration":
+			if f.name == "Interval" {
+				return true
+			}
+		default:
+			if g.hasInterval(kind) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isMetric reports whether typ embeds MetricConfig.
+func (g *generator) isMetric(typ string) bool {
+	for _, f := range g.structs[typ].Fields.List {
+		if id, ok := f.Type.(*ast.Ident); ok && len(f.Names) == 0 && id.Name == "MetricConfig" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// zero returns the YAML encoding of the zero value of a field of kind.
+func zero(kind string, typ ast.Expr) string {
+	switch kind {
+	case "string":
+		return `""`
+	case "duration":
+		return "0s"
+	}
+
+	switch exprString(typ) {
+	case "bool":
+		return "false"
+	case "log.Level":
+		return "info"
+	}
+
+	return "0"
+}
+
+// levels are the values of log.Level fields.
+var levels = []string{"trace", "debug", "info", "warn", "error", "disabled"}
+
+// scalarKind returns the type of the option of a field of kind, one of
+// "string", "duration", "bool", "int", "float" or "level".
+func scalarKind(kind string, typ ast.Expr) string {
+	if kind != "" {
+		return kind
+	}
+
+	switch s := exprString(typ); {
+	case s == "bool":
+		return "bool"
+	case s == "log.Level":
+		return "level"
+	case strings.HasPrefix(s, "float"):
+		return "float"
+	}
+
+	return "int"
+}
+
+var (
+	// acceptable matches the start of a list of the acceptable values of a field.
+	acceptable = regexp.MustCompile(`(?i)acceptable\s+values`)
+	// quoted matches the quoted values of a field.
+	quoted = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// values returns the accepted values of a field documented by doc, which are
+// either the items of the list following "acceptable values", or the quoted
+// values following "either" or "one of" in the first sentence.
+func values(doc string) []string {
+	var vals []string
+
+	if loc := acceptable.FindStringIndex(doc); loc != nil {
+		_, rest, _ := strings.Cut(doc[loc[1]:], "\n")
+		indent := -1
+
+		for line := range strings.Lines(rest) {
+			item := strings.TrimSpace(line)
+			n := len(line) - len(strings.TrimLeft(line, " \t"))
+
+			if !strings.HasPrefix(item, "- ") {
+				if indent >= 0 && n <= indent {
+					break
+				}
+
+				continue
+			}
+
+			indent = n
+			item = item[2:]
+
+			if m := quoted.FindAllStringSubmatch(item, -1); m != nil {
+				for _, v := range m {
+					vals = append(vals, v[1])
+				}
+			} else if v, _, _ := strings.Cut(item, " "); v != "" {
+				vals = append(vals, v)
+			}
+		}
+
+		return vals
+	}
+
+	sentence, _, _ := strings.Cut(doc, ". ")
+	sentence, _, _ = strings.Cut(sentence, ".\n")
+
+	i := strings.Index(sentence, "either ")
+	if j := strings.Index(sentence, "one of "); i < 0 || (j >= 0 && j < i) {
+		i = j
+	}
+
+	if i < 0 {
+		return nil
+	}
+
+	for _, v := range quoted.FindAllStringSubmatch(sentence[i:], -1) {
+		vals = append(vals, v[1])
+	}
+
+	return vals
+}
+
+// docFields prints the documented yaml fields of typ, with the fields of inline
+// structs in place of the struct, and appends any config structs of the fields
+// to types.
+func (g *generator) docFields(typ string, types *[]string) {
+	for _, f := range g.fields(typ) {
+		if f.tag == "-" {
+			continue
+		}
+
+		if f.inline {
+			g.docFields(g.kind(f.typ), types)
+			continue
+		}
+
+		key := f.tag
+		if key == "" {
+			key = strings.ToLower(f.name)
+		}
+
+		g.printf("{key: %q", key)
+
+		if f.doc != "" {
+			g.printf(", doc: %q", f.doc)
+		}
+
+		elt, list := f.typ, false
+		if arr, ok := f.typ.(*ast.ArrayType); ok {
+			elt, list = arr.Elt, true
+		}
+
+		switch kind := g.kind(elt); {
+		case g.structs[kind] != nil:
+			g.printf(", typ: %q", kind)
+
+			if !slices.Contains(*types, kind) {
+				*types = append(*types, kind)
+			}
+
+			if list {
+				g.printf(", list: true")
+			}
+		case list:
+			g.printf(", kind: %q, zero: %q", "[]"+scalarKind(kind, elt), "[]")
+		default:
+			g.printf(", kind: %q, zero: %q", scalarKind(kind, elt), zero(kind, elt))
+		}
+
+		var vals []string
+
+		switch kind := scalarKind(g.kind(elt), elt); kind {
+		case "string", "int":
+			vals = values(f.doc)
+		case "level":
+			vals = levels
+		}
+
+		if len(vals) > 0 {
+			g.printf(", values: %#v", vals)
+		}
+
+		g.printf("},\n")
+	}
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.printf("// Code generated by \"go run gen.go\"; DO NOT EDIT.\n\n")
+	g.printf("package config\n\n")
+	g.printf("import \"time\"\n\n")
+
+	g.printf("// initFields expands every string field of cfg, and replaces \"~\" with the\n")
+	g.printf("// base topic in every topic field. Each config with a load method is loaded\n")
+	g.printf("// before its fields.\n")
+	g.printf("func (cfg *Config) initFields() {\n")
+
+	for _, f := range g.fields("Config") {
+		g.initField("cfg."+f.name, f)
+	}
+
+	g.printf("}\n\n")
+
+	g.printf("// setInterval sets every Interval field of cfg to d.\n")
+	g.printf("func (cfg *Config) setInterval(d time.Duration) {\n")
+	g.intervalStruct("cfg", "Config")
+	g.printf("}\n\n")
+
+	g.printf("// setEnabled enables each metric of cfg for which enabled returns true, and\n")
+	g.printf("// disables all others.\n")
+	g.printf("func (cfg *Config) setEnabled(enabled func(name string) bool) {\n")
+
+	for _, f := range g.fields("Config") {
+		if id, ok := f.typ.(*ast.Ident); ok && g.structs[id.Name] != nil && g.isMetric(id.Name) {
+			g.printf("cfg.%s.Enabled = enabled(%q)\n", f.name, f.tag)
+		}
+	}
+
+	g.printf("}\n\n")
+
+	g.printf("// fieldValues returns the fields of cfg by yaml key. This is used by\n")
+	g.printf("// [WriteDefault] since config structs equal to their default are omitted\n")
+	g.printf("// when encoded.\n")
+	g.printf("func (cfg *Config) fieldValues() map[string]any {\n")
+	g.printf("return map[string]any{\n")
+
+	for _, f := range g.fields("Config") {
+		g.printf("%q: cfg.%s,\n", f.tag, f.name)
+	}
+
+	g.printf("}\n")
+	g.printf("}\n\n")
+
+	types := []string{"Config"}
+
+	g.printf("// configFields are the yaml fields of each config struct, in order, used by\n")
+	g.printf("// [WriteDefault].\n")
+	g.printf("var configFields = map[string][]docField{\n")
+
+	for i := 0; i < len(types); i++ {
+		g.printf("%q: {\n", types[i])
+		g.docFields(types[i], &types)
+		g.printf("},\n")
+	}
+
+	g.printf("}\n\n")
+
+	g.printf("// configDocs are the doc comments of each config struct, used by [WriteDefault].\n")
+	g.printf("var configDocs = map[string]string{\n")
+
+	for _, typ := range types {
+		if doc := g.docs[typ]; doc != "" {
+			g.printf("%q: %q,\n", typ, doc)
+		}
+	}
+
+	g.printf("}\n")
+
+	return format.Source(g.buf.Bytes())
+}
+
+func main() {
+	output := flag.String("output", "config_gen.go", "output file")
+	flag.Parse()
+
+	g := &generator{
+		structs: make(map[string]*ast.StructType),
+		docs:    make(map[string]string),
+		loaders: make(map[string]bool),
+	}
+
+	if err := g.parse("."); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
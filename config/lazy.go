@@ -0,0 +1,38 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// LazyConfig is the configuration for lazy metrics, which are only started
+// while something is subscribed to them, to save CPU on hosts whose metrics
+// are only watched occasionally. Since MQTT 3.1.1 doesn't tell the bridge who
+// is subscribed, a subscriber asks for a lazy metric by publishing anything
+// to the "/subscribe" subtopic of the metric, and keeps asking at least every
+// Timeout. Once nothing has asked for Timeout, the metric is stopped until the
+// next request.
+type LazyConfig struct {
+	// Enabled indicates if metrics are lazy. The default value is false
+	Enabled bool `yaml:"enabled"`
+	// Metrics are the types of the lazy metrics, such as "cpu", or "dir" for
+	// every directory. If empty (default) then every metric is lazy.
+	Metrics []string `yaml:"metrics,omitempty"`
+	// Timeout is how long a lazy metric keeps updating after the last request
+	// to the "/subscribe" subtopic of the metric. The default value is 5m
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+var DefaultLazy = LazyConfig{
+	Timeout: 5 * time.Minute,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg LazyConfig) IsZero() bool {
+	return cfg.Enabled == DefaultLazy.Enabled && len(cfg.Metrics) == 0 && cfg.Timeout == DefaultLazy.Timeout
+}
+
+// IsLazy reports whether the metric of the given type is lazy.
+func (cfg *LazyConfig) IsLazy(metric string) bool {
+	return cfg.Enabled && (len(cfg.Metrics) == 0 || slices.Contains(cfg.Metrics, metric))
+}
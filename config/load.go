@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+const (
+	includeKey  = "include"
+	profilesKey = "profiles"
+)
+
+// ProfileEnv is the environment variable used for the comma-separated list of
+// profiles to activate if none are given to [LoadProfile].
+const ProfileEnv = "MQTTOP_PROFILE"
+
+// fileLoader reads and merges YAML config files into a single mapping node.
+type fileLoader struct {
+	// stack is the absolute paths of the files currently being read, used
+	// to detect include cycles.
+	stack []string
+}
+
+// mapping returns the mapping node at the root of doc, which is empty if doc is nil.
+func mapping(doc *yaml.Node) (*yaml.Node, error) {
+	if doc == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return mapping(nil)
+		}
+
+		doc = doc.Content[0]
+	}
+
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: config must be a mapping", doc.Line)
+	}
+
+	return doc, nil
+}
+
+// decode returns the root mapping node decoded from r.
+func decode(r io.Reader) (*yaml.Node, error) {
+	var doc yaml.Node
+
+	if err := yaml.NewDecoder(r).Decode(&doc); err == io.EOF {
+		return mapping(nil)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapping(&doc)
+}
+
+// cut removes the value of key from the mapping node m and returns it, or nil
+// if m does not contain key.
+func cut(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			m.Content = slices.Delete(m.Content, i, i+2)
+
+			return v
+		}
+	}
+
+	return nil
+}
+
+// value returns the value of key in the mapping node m, or nil if m does not
+// contain key.
+func value(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// merge merges src into dst. Mappings are merged recursively, and any other
+// values of src, including sequences, replace the values of dst.
+func merge(dst, src *yaml.Node) {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		*dst = *src
+		return
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i], src.Content[i+1]
+
+		if v := value(dst, key.Value); v != nil {
+			merge(v, val)
+		} else {
+			dst.Content = append(dst.Content, key, val)
+		}
+	}
+}
+
+// resolve replaces the include directive of the mapping node m read from dir
+// with the contents of the included files, which are overridden by m.
+func (l *fileLoader) resolve(m *yaml.Node, dir string) (*yaml.Node, error) {
+	include := cut(m, includeKey)
+	if include == nil {
+		return m, nil
+	}
+
+	var paths []string
+
+	switch include.Kind {
+	case yaml.ScalarNode:
+		paths = []string{include.Value}
+	case yaml.SequenceNode:
+		for _, n := range include.Content {
+			if n.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: include must be a path or list of paths", n.Line)
+			}
+
+			paths = append(paths, n.Value)
+		}
+	default:
+		return nil, fmt.Errorf("line %d: include must be a path or list of paths", include.Line)
+	}
+
+	for i, path := range paths {
+		path = Expand(path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		paths[i] = path
+	}
+
+	base, err := l.load(paths, true)
+	if err != nil {
+		return nil, err
+	}
+
+	merge(base, m)
+
+	return base, nil
+}
+
+// readFile returns the root mapping node of the file name, with includes resolved.
+func (l *fileLoader) readFile(name string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(l.stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(l.stack, " -> "), abs)
+	}
+
+	l.stack = append(l.stack, abs)
+	defer func() {
+		l.stack = l.stack[:len(l.stack)-1]
+	}()
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	log.Debug("Reading config", "file", abs)
+
+	m, err := decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return l.resolve(m, filepath.Dir(abs))
+}
+
+// files returns the files to read for name. If name is a directory, the files
+// in it are returned in lexical order, excluding any compose files. If yamlOnly
+// is true, files in the directory without the extension ".yml" or ".yaml" are
+// also excluded.
+func files(name string, yamlOnly bool) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	} else if err != nil {
+		return []string{name}, nil
+	}
+
+	var names []string
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		switch e.Name() {
+		case "docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml":
+			continue
+		}
+
+		switch filepath.Ext(e.Name()) {
+		case ".yml", ".yaml":
+		default:
+			if yamlOnly {
+				continue
+			}
+		}
+
+		names = append(names, filepath.Join(name, e.Name()))
+	}
+
+	return names, nil
+}
+
+// load returns the root mapping nodes of the given files merged in order, so
+// that each file overrides the files before it.
+func (l *fileLoader) load(filenames []string, yamlOnly bool) (*yaml.Node, error) {
+	m, _ := mapping(nil)
+
+	for _, name := range filenames {
+		if name == "" {
+			continue
+		}
+
+		names, err := files(name, yamlOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			n, err := l.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+
+			merge(m, n)
+		}
+	}
+
+	return m, nil
+}
+
+// envProfiles returns the profiles listed in $MQTTOP_PROFILE.
+func envProfiles() []string {
+	env := os.Getenv(ProfileEnv)
+	if env == "" {
+		return nil
+	}
+
+	return strings.Split(env, ",")
+}
+
+// applyProfiles removes the profiles from the mapping node m and merges each of
+// the given profiles into m in order.
+func applyProfiles(m *yaml.Node, profiles []string) error {
+	defined := cut(m, profilesKey)
+	if defined == nil {
+		defined, _ = mapping(nil)
+	} else if defined.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: profiles must be a mapping", defined.Line)
+	}
+
+	for _, name := range profiles {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		p := value(defined, name)
+		if p == nil {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+
+		log.Debug("Applying profile", "name", name)
+
+		if p.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: profile %q must be a mapping", p.Line, name)
+		}
+
+		merge(m, p)
+	}
+
+	return nil
+}
+
+// decodeNode returns the Config decoded from the mapping node m, with the
+// given profiles applied. If profiles is nil, the profiles listed in
+// $MQTTOP_PROFILE are applied.
+func decodeNode(m *yaml.Node, profiles []string) (cfg *Config, err error) {
+	if profiles == nil {
+		profiles = envProfiles()
+	}
+
+	if err = applyProfiles(m, profiles); err != nil {
+		return
+	}
+
+	cfg = defaultCfg()
+	if err = m.Decode(cfg); err != nil {
+		return
+	}
+
+	err = cfg.init()
+
+	return
+}
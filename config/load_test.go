@@ -0,0 +1,186 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+var includeFiles = map[string]string{
+	"mqttop.yaml": `
+include:
+  - common/base.yaml
+interval: 5s
+cpu:
+  name: Host CPU
+profiles:
+  laptop:
+    battery:
+      enabled: true
+    net:
+      exclude: [lo]
+  server:
+    base_topic: server
+    interval: 30s
+`,
+	"common/base.yaml": `
+include: mqtt.yaml
+base_topic: home
+interval: 1s
+cpu:
+  name: Base CPU
+  interval: 3s
+battery:
+  enabled: false
+net:
+  include: [eth0]
+  exclude: [docker0, veth0]
+`,
+	"common/mqtt.yaml": `
+mqtt:
+  broker: tcp://broker:1883
+`,
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := writeFiles(t, includeFiles)
+	t.Setenv(config.ProfileEnv, "")
+
+	cfg, err := config.Load(filepath.Join(dir, "mqttop.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.MQTT.Broker != "tcp://broker:1883" {
+		t.Errorf("Nested include: want \"tcp://broker:1883\", got %q", cfg.MQTT.Broker)
+	}
+	if cfg.BaseTopic != "home" {
+		t.Errorf("Include: want \"home\", got %q", cfg.BaseTopic)
+	}
+	if cfg.Interval != 5*time.Second {
+		t.Errorf("Override: want 5s, got %v", cfg.Interval)
+	}
+	if cfg.CPU.Name != "Host CPU" || cfg.CPU.Interval != 3*time.Second {
+		t.Errorf("Merge: want \"Host CPU\" every 3s, got %q every %v", cfg.CPU.Name, cfg.CPU.Interval)
+	}
+	if cfg.Battery.Enabled {
+		t.Error("Profile: want no profile applied, got battery enabled")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := writeFiles(t, includeFiles)
+	path := filepath.Join(dir, "mqttop.yaml")
+
+	var tests = []struct {
+		name     string
+		profiles []string
+		env      string
+		topic    string
+		interval time.Duration
+		battery  bool
+		exclude  []string
+		wantErr  bool
+	}{
+		{"None", nil, "", "home", 5 * time.Second, false, []string{"docker0", "veth0"}, false},
+		{"Laptop", []string{"laptop"}, "", "home", 5 * time.Second, true, []string{"lo"}, false},
+		{"Env", nil, "server", "server", 30 * time.Second, false, []string{"docker0", "veth0"}, false},
+		{"Both", nil, "laptop,server", "server", 30 * time.Second, true, []string{"lo"}, false},
+		{"FlagOverridesEnv", []string{"laptop"}, "server", "home", 5 * time.Second, true, []string{"lo"}, false},
+		{"Unknown", []string{"desktop"}, "", "", 0, false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.ProfileEnv, tt.env)
+
+			cfg, err := config.LoadProfile(tt.profiles, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Wanted error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if cfg.BaseTopic != tt.topic {
+				t.Errorf("BaseTopic: want %q, got %q", tt.topic, cfg.BaseTopic)
+			}
+			if want := tt.topic + "/metric/cpu"; cfg.CPU.Topic != want {
+				t.Errorf("CPU topic: want %q, got %q", want, cfg.CPU.Topic)
+			}
+			if cfg.Interval != tt.interval {
+				t.Errorf("Interval: want %v, got %v", tt.interval, cfg.Interval)
+			}
+			if cfg.Battery.Enabled != tt.battery {
+				t.Errorf("Battery: want %v, got %v", tt.battery, cfg.Battery.Enabled)
+			}
+			if !slices.Equal(cfg.Net.Exclude, tt.exclude) {
+				t.Errorf("Net exclude: want %v, got %v", tt.exclude, cfg.Net.Exclude)
+			}
+			if len(cfg.Net.Include) != 1 || cfg.Net.Include[0].Interface != "eth0" {
+				t.Errorf("Net include: want [eth0], got %v", cfg.Net.Include)
+			}
+		})
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"10-base.yaml":   "interval: 1s\nbase_topic: dir\n",
+		"20-local.yml":   "interval: 4s\n",
+		"compose.yaml":   "services: {}\n",
+		"README":         "not yaml",
+		"other/ignored":  "interval: 9s\n",
+		"other/cpu.yaml": "cpu:\n  name: Dir CPU\n",
+	})
+	t.Setenv(config.ProfileEnv, "")
+
+	cfg, err := config.Load(dir, filepath.Join(dir, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.BaseTopic != "dir" {
+		t.Errorf("BaseTopic: want \"dir\", got %q", cfg.BaseTopic)
+	}
+	if cfg.Interval != 4*time.Second {
+		t.Errorf("Interval: want 4s, got %v", cfg.Interval)
+	}
+	if cfg.CPU.Name != "Dir CPU" {
+		t.Errorf("CPU name: want \"Dir CPU\", got %q", cfg.CPU.Name)
+	}
+}
+
+func TestLoadIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "include: b.yaml\n",
+		"b.yaml": "include: [a.yaml]\n",
+	})
+
+	_, err := config.Load(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Wanted include cycle error, got %v", err)
+	}
+}
@@ -1,6 +1,10 @@
 package config
 
-import "github.com/lone-faerie/mqttop/log"
+import (
+	"time"
+
+	"github.com/lone-faerie/mqttop/log"
+)
 
 // LogConfig is the configuration for logging.
 type LogConfig struct {
@@ -17,4 +21,37 @@ type LogConfig struct {
 	// - "json"
 	// - "text"
 	Format string `yaml:"format"`
+	// Sampling is the configuration for sampling identical log messages.
+	Sampling LogSamplingConfig `yaml:"sampling,omitempty"`
+}
+
+// LogSamplingConfig is the configuration for sampling identical log messages,
+// such as the debug messages logged every update, so that they are logged at
+// most Limit times every Period. The first message logged after any were
+// dropped has the attribute "dropped" with how many were.
+type LogSamplingConfig struct {
+	// Limit is how many identical messages are logged every Period, after
+	// which they are dropped until the next Period. If 0 (default) then no
+	// messages are dropped.
+	Limit int `yaml:"limit"`
+	// Period is the period of Limit. If 0 then the default of 1m is used.
+	Period time.Duration `yaml:"period,omitempty"`
+	// Level is the maximum level of the sampled messages. Messages above
+	// Level are always logged. The default value is "info", so that warnings
+	// and errors are never dropped.
+	Level log.Level `yaml:"level,omitempty"`
+	// Keys are the attributes whose values, along with the level and message,
+	// identify identical messages, such as "interface" to limit the messages
+	// of each network interface separately.
+	Keys []string `yaml:"keys,omitempty"`
+}
+
+// Options returns the [log.SamplingOptions] of cfg.
+func (cfg *LogSamplingConfig) Options() log.SamplingOptions {
+	return log.SamplingOptions{
+		Limit:  cfg.Limit,
+		Period: cfg.Period,
+		Level:  cfg.Level,
+		Keys:   cfg.Keys,
+	}
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -14,6 +15,7 @@ type loader interface {
 
 // MetricConfig is the base configuration of any metric.
 type MetricConfig struct {
+	// Enabled indicates if the metric should be published.
 	Enabled bool `yaml:"enabled"`
 	// Interval is the update interval of the metric. If 0 then
 	// the Interval of the parent [Config] is used.
@@ -21,6 +23,10 @@ type MetricConfig struct {
 	// Topic is the topic updates for the metric are published to.
 	// The default value is "mqttop/metric/<metric_type>"
 	Topic string `yaml:"topic,omitempty"`
+	// Precision is the number of decimal places of the fixed-point values in
+	// the payload, such as temperatures, frequencies and power. If 0 then the
+	// Precision of the parent [Config] is used.
+	Precision int `yaml:"precision,omitempty"`
 }
 
 // CPUConfig is the configuration for the CPU metrics.
@@ -34,19 +40,55 @@ type CPUConfig struct {
 	// If not blank then the rendered value will override Name.
 	// See https://pkg.go.dev/text/template
 	NameTemplate string `yaml:"name_template,omitempty"`
-	// SelectionMode is the mode used to select the overall CPU temperature and frequency.
+	// SelectionMode is the mode used to select the overall CPU temperature
 	// and frequency. The acceptable values are:
-	//	- "auto"    (package temperature, frequency of first core)
-	//	- "first"   (values of first core)
-	//	- "average" (average of all cores)
-	//	- "max"     (maximum of all cores)
-	//	- "min"     (minimum of all cores)
-	//	- "random"  (value of random core)
+	//	- "auto"     (package temperature, frequency of first core)
+	//	- "first"    (values of first core)
+	//	- "average"  (average of all cores)
+	//	- "weighted" (average of all cores weighted by usage)
+	//	- "max"      (maximum of all cores)
+	//	- "min"      (minimum of all cores)
+	//	- "hottest"  (values of the hottest core)
+	//	- "random"   (value of random core)
 	SelectionMode string `yaml:"selection_mode,omitempty"`
+	// PerCore indicates if the per-core metrics are reported. If false then the
+	// cores are omitted from the payload and discovery, which shrinks the payload
+	// on machines with many cores. The overall CPU metrics are still calculated
+	// from all of the cores. The default value is true
+	PerCore bool `yaml:"per_core"`
+	// Cores limits which cores per-core metrics are reported for. The overall
+	// CPU metrics are always calculated from all of the cores.
+	Cores CoresConfig `yaml:"cores,omitempty"`
+	// MaxCores is the maximum number of cores per-core metrics are reported for,
+	// after applying Cores. If 0 (default) then there is no limit.
+	MaxCores int `yaml:"max_cores,omitempty"`
+	// SampleInterval is the interval the usage of the CPU is sampled at, which
+	// may be shorter than the update interval to include the minimum and maximum
+	// usage between updates in the payload. If 0 (default) then the usage is only
+	// sampled every update.
+	SampleInterval time.Duration `yaml:"sample_interval,omitempty"`
+	// Aggregate indicates if the rolling average and maximum of the usage and
+	// temperature over the last 1, 5, and 15 minutes should be included in the
+	// payload.
+	Aggregate bool `yaml:"aggregate,omitempty"`
+	// TemperatureCalibration is the correction of the temperatures of the
+	// CPU and its cores, in °C.
+	TemperatureCalibration Calibration `yaml:"temperature_calibration,omitempty"`
 
 	nameTemplate *template.Template
 }
 
+// CoresConfig is the configuration for which cores of the CPU to report. Cores
+// are identified by their logical processor number, as in /proc/cpuinfo.
+type CoresConfig struct {
+	// Include is a list of cores to include. If empty (default) then all
+	// cores are included.
+	Include []int `yaml:"include,omitempty"`
+	// Exclude is a list of cores to exclude. If defined then these cores will
+	// not be included.
+	Exclude []int `yaml:"exclude,omitempty"`
+}
+
 // MemoryConfig is the configuration for the memory metrics.
 type MemoryConfig struct {
 	MetricConfig `yaml:",inline"`
@@ -113,6 +155,13 @@ type DisksConfig struct {
 	// ShowIO indicates if IO operations (reads/writes) should be included in
 	// the metrics.
 	ShowIO bool `yaml:"show_io"`
+	// StaleTimeout is how long to wait for the filesystem of a disk to
+	// respond before it's reported as stale, such as a network share whose
+	// server is unreachable. If 0 (default) then the timeout is 5s.
+	StaleTimeout time.Duration `yaml:"stale_timeout,omitempty"`
+	// Prediction is the configuration for predicting when each disk will be
+	// full.
+	Prediction DiskPredictionConfig `yaml:"prediction,omitempty"`
 	// Disk is a list of configurations for each individual disk.
 	Disk []DiskConfig `yaml:"disk,omitempty"`
 
@@ -121,6 +170,18 @@ type DisksConfig struct {
 	diskMap        map[string]*DiskConfig
 }
 
+// DiskPredictionConfig is the configuration for predicting the number of days
+// until each disk is full, by a linear trend of the used space over a window of
+// recent samples. The samples are persisted in the data directory.
+type DiskPredictionConfig struct {
+	// Enabled indicates if the days until full should be included in the
+	// payload.
+	Enabled bool `yaml:"enabled"`
+	// Window is the duration of the samples the trend is fit to. If 0
+	// (default) then the window is 7 days.
+	Window time.Duration `yaml:"window,omitempty"`
+}
+
 // NetIfaceConfig is the configuration for an individual network interface.
 type NetIfaceConfig struct {
 	// Name is a custom name used for the interface. If blank (default)
@@ -130,7 +191,11 @@ type NetIfaceConfig struct {
 	// interface. If not blank then the rendered value will override Name.
 	// See https://pkg.go.dev/text/template
 	NameTemplate string `yaml:"name_template,omitempty"`
-	// Interface is the name of the interface as reported by the system.
+	// Interface is the name of the interface as reported by the system. It
+	// may also be a glob pattern, such as "eth*", or a regular expression
+	// enclosed in slashes, such as "/^en[ops]/", to match several interfaces,
+	// in which case Name should be blank.
+	// See https://pkg.go.dev/path#Match
 	Interface string `yaml:"interface,omitempty"`
 	// RateUnit is the unit to use when reporting the data rate. The default
 	// value is the RateUnit of the parent [NetConfig]. The acceptable
@@ -146,6 +211,76 @@ type NetIfaceConfig struct {
 	nameTemplate *template.Template
 }
 
+// NetGroupConfig is the configuration for a group of network interfaces, such
+// as the veth interfaces of containers, which are reported together as a
+// single interface named by the group instead of each on its own.
+type NetGroupConfig struct {
+	// Name is the name of the group, which is used as the name of the
+	// interface.
+	Name string `yaml:"name"`
+	// Interfaces is a list of the interfaces of the group, which may be
+	// names or patterns like the Interface of [NetIfaceConfig]. Excluded
+	// interfaces are not included in the group.
+	Interfaces []string `yaml:"interfaces"`
+	// RateUnit is the unit to use when reporting the data rate of the
+	// group. The default value is the RateUnit of the parent [NetConfig].
+	RateUnit string `yaml:"rate_unit,omitempty"`
+}
+
+// NetNamespaceConfig is the configuration for a network namespace other than
+// the one of the bridge, such as of a VPN or container. Every interface of the
+// namespace other than loopback is included, named with the prefix of the
+// namespace. Entering a namespace requires CAP_SYS_ADMIN.
+type NetNamespaceConfig struct {
+	// Name is the name of a namespace created by ip netns, which is mounted
+	// in /run/netns.
+	Name string `yaml:"name,omitempty"`
+	// PID is the process whose namespace is used, if Name is blank.
+	PID int `yaml:"pid,omitempty"`
+	// Prefix is prepended to the name of each interface of the namespace,
+	// separated by an underscore. If blank (default) then the prefix is the
+	// Name, or "pid<PID>".
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
+// NetWireGuardConfig is the configuration for the peer status of a WireGuard
+// interface. Reading the status of a WireGuard interface requires
+// CAP_NET_ADMIN.
+type NetWireGuardConfig struct {
+	// Interface is the name of the WireGuard interface, including the prefix
+	// if it's in another namespace.
+	Interface string `yaml:"interface"`
+	// Timeout is how long after the last handshake a peer is considered
+	// connected. If 0 (default) then the timeout is 3m, since a handshake
+	// happens at least every 2m while a peer is sending data.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Peers is a list of names for the peers of the interface. Any peer
+	// without a name is named by the start of its public key.
+	Peers []NetWireGuardPeerConfig `yaml:"peers,omitempty"`
+}
+
+// NetWireGuardPeerConfig is the configuration for the name of a peer of a
+// WireGuard interface.
+type NetWireGuardPeerConfig struct {
+	// PublicKey is the base64-encoded public key of the peer, as shown by
+	// "wg show".
+	PublicKey string `yaml:"public_key"`
+	// Name is the name of the peer in the payload.
+	Name string `yaml:"name"`
+}
+
+// NetAccountingConfig is the configuration for the data usage of each network
+// interface per calendar day and billing month, such as for metered connections.
+// The usage is the sum of the bytes received and transmitted, and is persisted
+// in the data directory.
+type NetAccountingConfig struct {
+	// Enabled indicates if the data usage should be included in the payload.
+	Enabled bool `yaml:"enabled"`
+	// ResetDay is the day of the month the billing month starts on, from 1 to
+	// 28. If 0 (default) then the usage is reset on the first of the month.
+	ResetDay int `yaml:"reset_day,omitempty"`
+}
+
 // NetConfig is the configuration for the network metrics.
 type NetConfig struct {
 	MetricConfig `yaml:",inline"`
@@ -175,8 +310,24 @@ type NetConfig struct {
 	// NetIfaceConfig will be the value from the list.
 	Include []NetIfaceConfig `yaml:"include,omitempty"`
 	// Exclude is a list of interfaces to exclude. If defined then these interfaces will
-	// not be included.
+	// not be included. Like Include, these may be patterns.
 	Exclude []string `yaml:"exclude,omitempty"`
+	// Groups is a list of groups of interfaces to report together as a single
+	// interface each, such as "veth*" of containers. The interfaces of a
+	// group are included regardless of Include, OnlyPhysical and IncludeBridge.
+	Groups []NetGroupConfig `yaml:"groups,omitempty"`
+	// Aggregate indicates if the rolling average and maximum of the data rates
+	// over the last 1, 5, and 15 minutes should be included in the payload.
+	Aggregate bool `yaml:"aggregate,omitempty"`
+	// Accounting is the configuration for the data usage of each interface per
+	// day and billing month.
+	Accounting NetAccountingConfig `yaml:"accounting,omitempty"`
+	// Namespaces is a list of other network namespaces to include the
+	// interfaces of, such as of VPNs and containers.
+	Namespaces []NetNamespaceConfig `yaml:"namespaces,omitempty"`
+	// WireGuard is a list of WireGuard interfaces to include the status of
+	// the peers of.
+	WireGuard []NetWireGuardConfig `yaml:"wireguard,omitempty"`
 
 	// RescanInterval is the interval parsed from Rescan
 	RescanInterval time.Duration `yaml:"-"`
@@ -186,10 +337,114 @@ type NetConfig struct {
 type BatteryConfig struct {
 	MetricConfig `yaml:",inline"`
 
-	// TimeFormat is the format used when rendering the amount of time
-	// remaining on the battery.
-	// See https://pkg.go.dev/time#pkg-constants
+	// TimeFormat is the unit of the time remaining and time to full sensors
+	// of the battery. The payload is always in seconds. The acceptable
+	// values are:
+	//	- "seconds" (default)
+	//	- "minutes"
 	TimeFormat string `yaml:"time_format,omitempty"`
+	// CapacityCalibration is the correction of the capacity of the battery,
+	// in %. The corrected capacity is kept from 0 to 100.
+	CapacityCalibration Calibration `yaml:"capacity_calibration,omitempty"`
+	// VoltageCalibration is the correction of the voltage of the battery, in
+	// V. The power is corrected as well if it's calculated from the voltage.
+	VoltageCalibration Calibration `yaml:"voltage_calibration,omitempty"`
+}
+
+// AudioConfig is the configuration for the audio metrics.
+type AudioConfig struct {
+	MetricConfig `yaml:",inline"`
+
+	// Backend is the backend used to get the volume. The acceptable values are:
+	//	- "auto"  (pulse if pactl is installed, otherwise alsa)
+	//	- "pulse" (PulseAudio or PipeWire, using pactl)
+	//	- "alsa"  (ALSA, using amixer)
+	Backend string `yaml:"backend,omitempty"`
+	// Control is the ALSA mixer control to use. The default value is "Master".
+	Control string `yaml:"control,omitempty"`
+	// NowPlaying indicates if the MPRIS metadata of the media that is playing
+	// should be included, using playerctl.
+	NowPlaying bool `yaml:"now_playing"`
+}
+
+// IdleConfig is the configuration for the idle metrics.
+type IdleConfig struct {
+	MetricConfig `yaml:",inline"`
+
+	// Backend is the backend used to get the idle time. The acceptable values are:
+	//	- "auto"   (x11 if $DISPLAY is set, otherwise logind, otherwise input)
+	//	- "x11"    (X11, using xprintidle)
+	//	- "logind" (the idle hint of the session, using loginctl)
+	//	- "input"  (the last access of the devices in /dev/input)
+	Backend string `yaml:"backend,omitempty"`
+	// Threshold is how long the user must be idle to no longer be considered
+	// active. The default value is 5m.
+	Threshold time.Duration `yaml:"threshold,omitempty"`
+}
+
+// ProcessesConfig is the configuration for the processes metric.
+type ProcessesConfig struct {
+	MetricConfig `yaml:",inline"`
+
+	// Count is the number of processes to report. The default value is 5.
+	Count int `yaml:"count,omitempty"`
+	// SortBy is the usage the processes are sorted by, of which the
+	// processes using the most are reported. The acceptable values are:
+	//	- "cpu" (default)
+	//	- "memory"
+	SortBy string `yaml:"sort_by,omitempty"`
+	// SizeUnit is the unit to use when reporting the memory of the
+	// processes. The default value is "MiB". The acceptable values are:
+	//	- "Bytes", "bytes", or "B"
+	//	- "KiB"
+	//	- "MiB"
+	//	- "GiB"
+	//	- "TiB"
+	//	- "PiB"
+	SizeUnit string `yaml:"size_unit,omitempty"`
+}
+
+// SystemConfig is the configuration for the system metric.
+type SystemConfig struct {
+	MetricConfig `yaml:",inline"`
+}
+
+// SmartConfig is the configuration for the SMART metric. The interval defaults
+// to 5m since the health of disks changes slowly.
+type SmartConfig struct {
+	MetricConfig `yaml:",inline"`
+
+	// Smartctl indicates if smartctl should be used to read the health and
+	// counters of the disks, which usually requires root. If false, or if
+	// smartctl isn't installed, only the temperatures reported in /sys are
+	// read. The default value is true.
+	Smartctl bool `yaml:"smartctl"`
+}
+
+// EnergyConfig is the configuration for the energy metric.
+type EnergyConfig struct {
+	MetricConfig `yaml:",inline"`
+}
+
+// FanConfig is the configuration for an individual fan.
+type FanConfig struct {
+	// Fan is the ID of the fan, in the form "<device>_fan<N>" such as
+	// "nct6798_fan1".
+	Fan string `yaml:"fan,omitempty"`
+	// Name is a custom name used for the fan. If blank (default) then the
+	// name will be the name of the hwmon device followed by the label of
+	// the fan.
+	Name string `yaml:"name,omitempty"`
+	// Exclude indicates if the fan should be excluded.
+	Exclude bool `yaml:"exclude,omitempty"`
+}
+
+// FansConfig is the configuration for the fan metrics.
+type FansConfig struct {
+	MetricConfig `yaml:",inline"`
+
+	// Fan is a list of configurations for each individual fan.
+	Fan []FanConfig `yaml:"fan,omitempty"`
 }
 
 // DirConfig is the configuration for directory metrics.
@@ -220,10 +475,45 @@ type DirConfig struct {
 	Watch bool `yaml:"watch"`
 	// Depth is the maximum depth to watch for updates in the directory.
 	Depth int `yaml:"depth,omitempty"`
+	// MaxWatches is the maximum number of subdirectories that are watched,
+	// since each uses one of the inotify watches limited by
+	// fs.inotify.max_user_watches. Once reached, the least recently changed
+	// subdirectories are polled every update interval instead. If 0 (default)
+	// then at most 4096 are watched, and if < 0 there is no limit.
+	MaxWatches int `yaml:"max_watches,omitempty"`
+	// OneFilesystem indicates if directories on other filesystems, such as
+	// mount points and bind mounts in the directory, are excluded the same as
+	// du -x.
+	OneFilesystem bool `yaml:"one_filesystem,omitempty"`
+	// Symlinks is how symlinks in the directory are counted. The acceptable
+	// values are:
+	//	- "count"  (the size of the symlink itself, default)
+	//	- "follow" (the size of the file or directory it links to)
+	//	- "ignore" (symlinks aren't counted)
+	Symlinks string `yaml:"symlinks,omitempty"`
+	// Clean is the configuration for the clean command of the directory.
+	Clean DirCleanConfig `yaml:"clean,omitempty"`
 
 	nameTemplate *template.Template
 }
 
+// DirCleanConfig is the configuration for the clean command of a directory,
+// which deletes the files in the directory that match any of the patterns and
+// are at least the minimum age, such as old downloads. Directories are never
+// deleted, and symlinks are deleted instead of the files they link to.
+type DirCleanConfig struct {
+	// Enabled indicates if the clean command should be available.
+	Enabled bool `yaml:"enabled"`
+	// Patterns is a list of patterns matched against the name of each file.
+	// If empty (default) then every file is matched, and MinAge must be set.
+	// See https://pkg.go.dev/path/filepath#Match
+	Patterns []string `yaml:"patterns,omitempty"`
+	// MinAge is how long ago a file must have been modified to be deleted.
+	// If 0 (default) then files of any age are deleted, and Patterns must be
+	// set.
+	MinAge time.Duration `yaml:"min_age,omitempty"`
+}
+
 // GPUConfig is the configuration for the GPU metrics.
 type GPUConfig struct {
 	MetricConfig `yaml:",inline"`
@@ -239,6 +529,9 @@ type GPUConfig struct {
 	// Platform is the platform of the GPU to use. The acceptable values are:
 	//	- "auto"
 	//	- "nvidia"
+	//	- "sysfs", for degraded metrics of an NVIDIA GPU without NVML, which
+	//	  are also used if NVML is unavailable
+	//	- "intel", for an Intel GPU driven by i915 or xe
 	Platform string `yaml:"platform,omitempty"`
 	// Index is the index of the GPU to use. The default value is 0.
 	Index int `yaml:"index,omitempty"`
@@ -256,6 +549,17 @@ type GPUConfig struct {
 	// be included in the metrics.
 	// TODO: not yet implemented
 	IncludeProcs bool `yaml:"include_proc"`
+	// Aggregate indicates if the rolling average and maximum of the utilization
+	// and temperature over the last 1, 5, and 15 minutes should be included in
+	// the payload.
+	Aggregate bool `yaml:"aggregate,omitempty"`
+	// Summary indicates if a summary across the GPUs should be included in the
+	// payload, with the total memory used, the maximum temperature and the
+	// total power, and discovered as its own sensors.
+	Summary bool `yaml:"summary,omitempty"`
+	// TemperatureCalibration is the correction of the temperature of the GPU,
+	// in °C. The maximum temperature isn't corrected.
+	TemperatureCalibration Calibration `yaml:"temperature_calibration,omitempty"`
 
 	nameTemplate *template.Template
 }
@@ -265,6 +569,7 @@ var DefaultCPU = CPUConfig{
 		Enabled: true,
 		Topic:   "~/metric/cpu",
 	},
+	PerCore: true,
 }
 
 var DefaultMemory = MemoryConfig{
@@ -298,6 +603,62 @@ var DefaultBattery = BatteryConfig{
 	},
 }
 
+var DefaultAudio = AudioConfig{
+	MetricConfig: MetricConfig{
+		Enabled: false,
+		Topic:   "~/metric/audio",
+	},
+	NowPlaying: true,
+}
+
+var DefaultIdle = IdleConfig{
+	MetricConfig: MetricConfig{
+		Enabled: false,
+		Topic:   "~/metric/idle",
+	},
+	Threshold: 5 * time.Minute,
+}
+
+var DefaultProcesses = ProcessesConfig{
+	MetricConfig: MetricConfig{
+		Enabled: false,
+		Topic:   "~/metric/processes",
+	},
+	Count:    5,
+	SortBy:   "cpu",
+	SizeUnit: "MiB",
+}
+
+var DefaultSystem = SystemConfig{
+	MetricConfig: MetricConfig{
+		Enabled: true,
+		Topic:   "~/metric/system",
+	},
+}
+
+var DefaultSmart = SmartConfig{
+	MetricConfig: MetricConfig{
+		Enabled:  false,
+		Interval: 5 * time.Minute,
+		Topic:    "~/metric/smart",
+	},
+	Smartctl: true,
+}
+
+var DefaultEnergy = EnergyConfig{
+	MetricConfig: MetricConfig{
+		Enabled: false,
+		Topic:   "~/metric/energy",
+	},
+}
+
+var DefaultFans = FansConfig{
+	MetricConfig: MetricConfig{
+		Enabled: true,
+		Topic:   "~/metric/fans",
+	},
+}
+
 var DefaultGPU = GPUConfig{
 	MetricConfig: MetricConfig{
 		Enabled: true,
@@ -339,6 +700,10 @@ func (cfg *CPUConfig) load(_ *Config) error {
 // FormatName returns the name rendered from the [CPUConfig].NameTemplate, if defined.
 // If the template is not defined, FormatName returns name.
 func (cfg *CPUConfig) FormatName(name string) string {
+	if cfg.nameTemplate == nil && cfg.NameTemplate != "" {
+		cfg.nameTemplate, _ = loadTemplate("cpu_name", cfg.NameTemplate)
+	}
+
 	if cfg.nameTemplate == nil {
 		return name
 	}
@@ -393,15 +758,56 @@ func (cfg *DisksConfig) load(c *Config) (err error) {
 
 // Excluded returns if the configuration for mnt is set to be excluded.
 func (cfg *DisksConfig) Excluded(mnt string) bool {
-	dcfg, ok := cfg.diskMap[mnt]
-	return ok && dcfg.Exclude
+	dcfg := cfg.ConfigFor(mnt)
+	return dcfg != nil && dcfg.Exclude
 }
 
 // ConfigFor returns the configuration for mnt.
 func (cfg *DisksConfig) ConfigFor(mnt string) *DiskConfig {
+	if cfg.diskMap == nil {
+		for i := range cfg.Disk {
+			if cfg.Disk[i].MountPoint == mnt {
+				return &cfg.Disk[i]
+			}
+		}
+
+		return nil
+	}
+
 	return cfg.diskMap[mnt]
 }
 
+// UnmarshalYAML implements [yaml.Unmarshaler]. If node is a mapping then cfg is
+// unmarshaled normally. Otherwise cfg is unmarshalled as a string, and cfg.Fan
+// is set to the value of node.
+func (cfg *FanConfig) UnmarshalYAML(node *yaml.Node) error {
+	type Wrapped FanConfig
+
+	if node.Kind&yaml.MappingNode != 0 {
+		return node.Decode((*Wrapped)(cfg))
+	}
+
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+
+	cfg.Fan = s
+
+	return nil
+}
+
+// ConfigFor returns the configuration for the fan with the given id.
+func (cfg *FansConfig) ConfigFor(id string) *FanConfig {
+	for i := range cfg.Fan {
+		if cfg.Fan[i].Fan == id {
+			return &cfg.Fan[i]
+		}
+	}
+
+	return nil
+}
+
 // UnmarshalYAML implements [yaml.Unmarshaler]. If node is a mapping then cfg is
 // unmarshaled normally. Otherwise cfg is unmarshalled as a string, and cfg.Interface
 // is set to the value of node.
@@ -429,6 +835,10 @@ func (cfg *NetIfaceConfig) FormatName(name string) string {
 		return cfg.Name
 	}
 
+	if cfg.nameTemplate == nil && cfg.NameTemplate != "" {
+		cfg.nameTemplate, _ = loadTemplate("net_"+cfg.Interface, cfg.NameTemplate)
+	}
+
 	if cfg.nameTemplate == nil {
 		return name
 	}
@@ -482,6 +892,10 @@ func (cfg *DirConfig) FormatName(name string) string {
 		return cfg.Name
 	}
 
+	if cfg.nameTemplate == nil && cfg.NameTemplate != "" {
+		cfg.nameTemplate, _ = loadTemplate("dir_"+cfg.Path, cfg.NameTemplate)
+	}
+
 	if cfg.nameTemplate == nil {
 		return name
 	}
@@ -537,6 +951,10 @@ func (cfg *GPUConfig) FormatName(name string) string {
 		return cfg.Name
 	}
 
+	if cfg.nameTemplate == nil && cfg.NameTemplate != "" {
+		cfg.nameTemplate, _ = loadTemplate("gpu_name", cfg.NameTemplate)
+	}
+
 	if cfg.nameTemplate == nil {
 		return name
 	}
@@ -551,7 +969,26 @@ func (cfg *GPUConfig) FormatName(name string) string {
 
 // IsZero indicates whether cfg is the default value.
 func (cfg CPUConfig) IsZero() bool {
-	return cfg == DefaultCPU
+	return cfg.MetricConfig == DefaultCPU.MetricConfig &&
+		cfg.Name == DefaultCPU.Name &&
+		cfg.NameTemplate == DefaultCPU.NameTemplate &&
+		cfg.SelectionMode == DefaultCPU.SelectionMode &&
+		cfg.Cores.IsZero() &&
+		cfg.MaxCores == DefaultCPU.MaxCores
+}
+
+// IsZero indicates whether cfg includes all cores.
+func (cfg CoresConfig) IsZero() bool {
+	return len(cfg.Include) == 0 && len(cfg.Exclude) == 0
+}
+
+// Contains indicates whether core is included by cfg.
+func (cfg CoresConfig) Contains(core int) bool {
+	if len(cfg.Include) > 0 && !slices.Contains(cfg.Include, core) {
+		return false
+	}
+
+	return !slices.Contains(cfg.Exclude, core)
 }
 
 // IsZero indicates whether cfg is the default value.
@@ -577,7 +1014,8 @@ func (cfg NetConfig) IsZero() bool {
 		cfg.Rescan == DefaultNet.Rescan &&
 		cfg.RateUnit == DefaultNet.RateUnit &&
 		len(cfg.Include) == 0 &&
-		len(cfg.Exclude) == 0
+		len(cfg.Exclude) == 0 &&
+		len(cfg.Groups) == 0
 }
 
 // IsZero indicates whether cfg is the default value.
@@ -585,6 +1023,41 @@ func (cfg BatteryConfig) IsZero() bool {
 	return cfg == DefaultBattery
 }
 
+// IsZero indicates whether cfg is the default value.
+func (cfg AudioConfig) IsZero() bool {
+	return cfg == DefaultAudio
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg IdleConfig) IsZero() bool {
+	return cfg == DefaultIdle
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg ProcessesConfig) IsZero() bool {
+	return cfg == DefaultProcesses
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg SystemConfig) IsZero() bool {
+	return cfg == DefaultSystem
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg SmartConfig) IsZero() bool {
+	return cfg == DefaultSmart
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg EnergyConfig) IsZero() bool {
+	return cfg == DefaultEnergy
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg FansConfig) IsZero() bool {
+	return cfg.MetricConfig == DefaultFans.MetricConfig && len(cfg.Fan) == 0
+}
+
 // IsZero indicates whether cfg is the default value.
 func (cfg GPUConfig) IsZero() bool {
 	return cfg == DefaultGPU
@@ -13,9 +13,12 @@ import (
 // See [mqtt.ClientOptions]
 type MQTTConfig struct {
 	// Broker is the URI of the broker. The format should be scheme://host:port
-	// where "scheme" is one of "tcp", "ssl", or "ws", "host" is the ip-address
-	// (or hostname) and "port" is the port on which the broker is accepting
-	// connections.
+	// where "scheme" is one of "tcp" (or "mqtt"), "ssl" (or "mqtts"), "ws", or
+	// "wss", "host" is the ip-address (or hostname) and "port" is the port on
+	// which the broker is accepting connections. If "scheme" is not defined, it
+	// defaults to "tcp", and if "port" is not defined, it defaults to that of
+	// the scheme, which is 1883 for "tcp" and 8883 for "ssl". IPv6 addresses
+	// must be in brackets if followed by a port, such as [::1]:1883.
 	Broker string `yaml:"broker"`
 	// ClientID is the (optional) client ID used when connecting to the broker.
 	ClientID string `yaml:"client_id,omitempty"`
@@ -46,11 +49,68 @@ type MQTTConfig struct {
 	// before unblocking with a timeout error. A duration of 0 means the client will never
 	// time out.
 	WriteTimeout time.Duration `yaml:"write_timeout,omitempty"`
+	// PublishTimeout is the duration that the bridge will wait for the broker to
+	// acknowledge a publish, subscribe or unsubscribe before giving up with a
+	// timeout error, which is retried by the next update. If 0 (default) then
+	// WriteTimeout is used, or 30s if that is also 0. A negative duration means
+	// the bridge will wait until it's stopped.
+	PublishTimeout time.Duration `yaml:"publish_timeout,omitempty"`
+	// CommandResults indicates if the result of each command, such as the
+	// "/update" topic of a metric or the "bridge/power/set" topic, is published
+	// to the "/result" subtopic of the command topic, such as
+	// {"status": "error", "error": "..."}. The status is one of "ok", "pending",
+	// "busy" or "error".
+	CommandResults bool `yaml:"command_results"`
+	// PublishSchemas indicates if the JSON Schema of the payload of each metric
+	// is published retained to the "/$schema" subtopic of the metric when it's
+	// started, which is also printed by "mqttop schema".
+	PublishSchemas bool `yaml:"publish_schemas"`
+	// CleanSession indicates if the broker discards the session of the client
+	// when it disconnects. If false, the broker keeps the subscriptions of the
+	// client and queues its QoS 1 and 2 messages while it is disconnected, which
+	// requires ClientID to be set. The default value is true.
+	CleanSession bool `yaml:"clean_session"`
+	// StoreEnabled indicates if the in-flight QoS 1 and 2 messages of the client
+	// are stored in files under StorePath, so that they survive restarts. Since
+	// the store is cleared when connecting with a clean session, this is only
+	// useful if CleanSession is false. If false (default) then they are only
+	// stored in memory.
+	StoreEnabled bool `yaml:"store_enabled"`
+	// StorePath is the directory of the message store if StoreEnabled is true.
+	// If blank (default) then the "mqtt_store" directory of the data path is used.
+	StorePath string `yaml:"store_path,omitempty"`
 	// BirthWillEnabled indicates if the Birth and Last Will and Testament messages are enabled.
 	BirthWillEnabled bool `yaml:"birth_lwt_enabled"`
 	// BirthWillTopic is the topic to publish the Birth and Last Will and Testament messages to
 	// if enabled. The default value is "mqttop/bridge/status"
 	BirthWillTopic string `yaml:"birth_lwt_topic"`
+	// BirthPayload is the payload of the Birth message, which is published to
+	// BirthWillTopic after connecting to the broker, before any metrics are
+	// started. The default value is "online"
+	BirthPayload string `yaml:"birth_payload"`
+	// BirthQoS is the Quality of Service used for the Birth message and the states
+	// of the metrics published after it. The acceptable values are:
+	// - 0 (at most once)
+	// - 1 (at least once, default)
+	// - 2 (exactly once)
+	BirthQoS byte `yaml:"birth_qos"`
+	// BirthRetained indicates if the Birth message and the states of the metrics
+	// published after it should be retained at the broker. The default value is true
+	BirthRetained bool `yaml:"birth_retained"`
+	// WillPayload is the payload of the Last Will and Testament message, which is
+	// published to BirthWillTopic by the broker if the client disconnects
+	// unexpectedly, and by the client before disconnecting. The default value
+	// is "offline"
+	WillPayload string `yaml:"lwt_payload"`
+	// WillQoS is the Quality of Service used for the Last Will and Testament
+	// message. The acceptable values are:
+	// - 0 (at most once)
+	// - 1 (at least once, default)
+	// - 2 (exactly once)
+	WillQoS byte `yaml:"lwt_qos"`
+	// WillRetained indicates if the Last Will and Testament message should be
+	// retained at the broker. The default value is true
+	WillRetained bool `yaml:"lwt_retained"`
 	// LogLevel is the log level to provide to the backing MQTT client package.
 	// See [mqtt.Logger]
 	LogLevel log.Level `yaml:"log_level"`
@@ -80,11 +140,38 @@ type DiscoveryConfig struct {
 	// is "MQTTop" and the special value "hostname" means the device name will be
 	// the hostname of the system, as determined by the contents of /etc/hostname.
 	DeviceName string `yaml:"device_name,omitempty"`
+	// DeviceID is the identifier of the device used for discovery, which is
+	// also part of the unique_id of each component so that multiple hosts may
+	// be discovered by the same Home Assistant. It may only consist of characters
+	// from [a-zA-Z0-9_-]. If blank (default) then the identifier is derived from
+	// the machine id of the system, as determined by the contents of /etc/machine-id.
+	DeviceID string `yaml:"device_id,omitempty"`
+	// SuggestedArea is the (optional) area suggested for the device when it's
+	// first added to Home Assistant, such as "Office".
+	SuggestedArea string `yaml:"suggested_area,omitempty"`
+	// ConfigurationURL is the (optional) URL linked from the device in Home
+	// Assistant, such as the Cockpit or Proxmox web interface of the host. It
+	// must be an absolute http, https or homeassistant URL.
+	ConfigurationURL string `yaml:"configuration_url,omitempty"`
+	// DirsDevice indicates if the components of the dir metrics are discovered
+	// as a separate device, connected via the device of the host, instead of
+	// as part of it. The default value is false
+	DirsDevice bool `yaml:"dirs_device,omitempty"`
 	// NodeID is the (optional) node_id part of the discovery topic in the form
 	// <discovery_prefix>/<component>/[<node_id>/]<object_id>/config. It may only
 	// consist of characters from [a-zA-Z0-9_-]. If Method is "nodes" or "metrics"
 	// then the node_id part of the topic will be the value <node_id>_<metric_type>.
 	NodeID string `yaml:"node_id,omitempty"`
+	// CoreNodes is how the per-core components of the CPU are grouped if Method is
+	// "nodes" or "metrics". The acceptable values are:
+	//	- "cpu" (default)
+	//	- "cores"
+	//	- "each"
+	// If CoreNodes is "cpu" then the per-core components are in the "cpu" node with
+	// the rest of the CPU components. If CoreNodes is "cores" then the per-core
+	// components are in a separate "cores" node. If CoreNodes is "each" then the
+	// components of each core are in their own "cpu_core_<n>" node.
+	CoreNodes string `yaml:"core_nodes,omitempty"`
 	// Availability is the topic used for reporting component availability. The default
 	// value is "mqttop/bridge/status"
 	Availability string `yaml:"availability_topic,omitempty"`
@@ -103,14 +190,34 @@ type DiscoveryConfig struct {
 	// WaitPayload is the (optional) payload to wait for on WaitTopic. If blank
 	// then wait for any payload.
 	WaitPayload string `yaml:"wait_payload"`
+
+	// Instance is the name of the instance, set from the top-level instance
+	// option.
+	Instance string `yaml:"-"`
+	// RootFS is the root of the host filesystem the device is read from, set
+	// from the top-level rootfs option.
+	RootFS string `yaml:"-"`
+	// PayloadAvailable is the payload of the Birth message, set from the
+	// birth_payload option of the MQTT config.
+	PayloadAvailable string `yaml:"-"`
+	// PayloadNotAvailable is the payload of the Last Will and Testament
+	// message, set from the lwt_payload option of the MQTT config.
+	PayloadNotAvailable string `yaml:"-"`
 }
 
 var DefaultMQTT = MQTTConfig{
 	Broker:           "$MQTTOP_BROKER_ADDRESS",
 	Username:         "$MQTTOP_BROKER_USERNAME",
 	Password:         "$MQTTOP_BROKER_PASSWORD",
+	CleanSession:     true,
 	BirthWillEnabled: true,
 	BirthWillTopic:   "~/bridge/status",
+	BirthPayload:     "online",
+	BirthQoS:         1,
+	BirthRetained:    true,
+	WillPayload:      "offline",
+	WillQoS:          1,
+	WillRetained:     true,
 	LogLevel:         log.LevelDisabled,
 }
 
@@ -132,6 +239,11 @@ func (cfg *MQTTConfig) ClientOptions() *mqtt.ClientOptions {
 	o.SetClientID(cfg.ClientID)
 	o.SetUsername(cfg.Username).SetPassword(cfg.Password)
 	o.SetResumeSubs(true)
+	o.SetCleanSession(cfg.CleanSession)
+
+	if cfg.StoreEnabled && cfg.StorePath != "" {
+		o.SetStore(mqtt.NewFileStore(cfg.StorePath))
+	}
 
 	if cfg.KeepAlive > 0 {
 		o.SetKeepAlive(cfg.KeepAlive)
@@ -154,7 +266,7 @@ func (cfg *MQTTConfig) ClientOptions() *mqtt.ClientOptions {
 	}
 
 	if cfg.BirthWillEnabled {
-		o.SetWill(cfg.BirthWillTopic, "offline", 1, true)
+		o.SetWill(cfg.BirthWillTopic, cfg.WillPayload, cfg.WillQoS, cfg.WillRetained)
 	}
 
 	if cfg.CertFile != "" && cfg.KeyFile != "" {
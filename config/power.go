@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+// PowerConfig is the configuration for power commands, which allow
+// powering off, rebooting, suspending, or hibernating the system by publishing
+// the action to "<base_topic>/bridge/power/set". Power commands are disabled
+// unless at least one action is allowed.
+type PowerConfig struct {
+	// Allow is the list of actions that may be executed. The acceptable values
+	// are:
+	//	- "poweroff"
+	//	- "reboot"
+	//	- "suspend"
+	//	- "hibernate"
+	Allow []string `yaml:"allow,omitempty"`
+	// Confirm requires each action to be published twice within ConfirmTimeout
+	// before it is executed.
+	Confirm bool `yaml:"confirm,omitempty"`
+	// ConfirmTimeout is how long the first publish of an action waits to be
+	// confirmed when Confirm is true. The default value is 10s.
+	ConfirmTimeout time.Duration `yaml:"confirm_timeout,omitempty"`
+}
+
+var DefaultPower = PowerConfig{
+	ConfirmTimeout: 10 * time.Second,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg PowerConfig) IsZero() bool {
+	return len(cfg.Allow) == 0 &&
+		cfg.Confirm == DefaultPower.Confirm &&
+		cfg.ConfirmTimeout == DefaultPower.ConfirmTimeout
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Option is an option of the config reference returned by [Reference].
+type Option struct {
+	// Key is the dotted path of the option, such as "mqtt.broker". The
+	// options of the items of a list are under the key of the list suffixed
+	// with "[]", such as "dirs[].path".
+	Key string `json:"key" yaml:"key"`
+	// Type is the type of the value, one of "string", "bool", "int", "float",
+	// "duration", "level" or "object", or a list of one such as "[]string".
+	Type string `json:"type" yaml:"type"`
+	// Default is the default value, or nil if the option has none. Durations
+	// and levels are formatted as strings, such as "2s" or "info".
+	Default any `json:"default,omitempty" yaml:"default,omitempty"`
+	// Values are the documented values of the option, if any. Options whose
+	// doc mentions other values, such as a path, may accept those as well.
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+	// Doc is the documentation of the option.
+	Doc string `json:"doc,omitempty" yaml:"doc,omitempty"`
+}
+
+// reference appends the options of the config struct typ to opts, with the
+// default values of the mapping node m, which may be nil.
+func reference(opts []Option, typ string, m *yaml.Node, prefix string) []Option {
+	for _, f := range configFields[typ] {
+		var v *yaml.Node
+		if m != nil {
+			v = value(m, f.key)
+		}
+
+		o := Option{
+			Key:    prefix + f.key,
+			Type:   f.kind,
+			Values: f.values,
+			Doc:    f.doc,
+		}
+
+		if f.typ != "" {
+			o.Type = "object"
+			if f.list {
+				o.Type = "[]object"
+			}
+
+			if o.Doc == "" {
+				o.Doc = configDocs[f.typ]
+			}
+		}
+
+		if v != nil && v.Kind == yaml.ScalarNode {
+			v.Decode(&o.Default)
+
+			// Levels are encoded in upper case, but documented in lower case
+			if s, ok := o.Default.(string); ok && f.kind == "level" {
+				o.Default = strings.ToLower(s)
+			}
+		}
+
+		opts = append(opts, o)
+
+		switch {
+		case f.list:
+			opts = reference(opts, f.typ, nil, o.Key+"[].")
+		case f.typ != "":
+			opts = reference(opts, f.typ, v, o.Key+".")
+		}
+	}
+
+	return opts
+}
+
+// Reference returns every option of the config in order, generated from the
+// config structs along with their defaults and documented values. It's used
+// by "mqttop config reference" for external documentation tooling.
+func Reference() []Option {
+	m, _ := mapping(nil)
+
+	for key, val := range defaultCfg().fieldValues() {
+		var v yaml.Node
+
+		if err := v.Encode(val); err != nil {
+			continue
+		}
+
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &v)
+	}
+
+	return reference(nil, "Config", m, "")
+}
+
+// Lookup returns the option of the config reference with the dotted key, such
+// as "mqtt.broker", and whether it exists.
+func Lookup(key string) (Option, bool) {
+	for _, o := range Reference() {
+		if o.Key == key {
+			return o, true
+		}
+	}
+
+	return Option{}, false
+}
+
+// ValueError is the error of an option set to a value other than one of its
+// accepted values.
+type ValueError struct {
+	Key    string
+	Value  string
+	Values []string
+}
+
+func (e *ValueError) Error() string {
+	if len(e.Values) == 0 {
+		return fmt.Sprintf("invalid %s %q", e.Key, e.Value)
+	}
+
+	values := strings.Join(e.Values[:len(e.Values)-1], ", ")
+	if values != "" {
+		values += " or "
+	}
+
+	return fmt.Sprintf("invalid %s %q, must be one of %s", e.Key, e.Value, values+e.Values[len(e.Values)-1])
+}
+
+// InvalidValue returns a [*ValueError] of the option with the dotted key set
+// to value, listing the documented values of the option in the reference.
+func InvalidValue(key, value string) error {
+	o, _ := Lookup(key)
+	return &ValueError{Key: key, Value: value, Values: o.Values}
+}
@@ -0,0 +1,75 @@
+package config_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestReference(t *testing.T) {
+	opts := config.Reference()
+
+	for _, o := range opts {
+		if o.Type == "" {
+			t.Errorf("%s: want type", o.Key)
+		}
+	}
+
+	tests := []struct {
+		key    string
+		typ    string
+		def    any
+		values []string
+	}{
+		{"interval", "duration", "2s", nil},
+		{"mqtt", "object", nil, nil},
+		{"mqtt.birth_qos", "int", 1, []string{"0", "1", "2"}},
+		{"mqtt.username", "string", "$MQTTOP_BROKER_USERNAME", nil},
+		{"transport.type", "string", nil, []string{"mqtt", "nats", "kafka"}},
+		{"signing.method", "string", nil, []string{"hmac", "ed25519"}},
+		{"log.level", "level", "info", []string{"trace", "debug", "info", "warn", "error", "disabled"}},
+		{"cpu.enabled", "bool", true, nil},
+		{"power_commands.allow", "[]string", nil, []string{"poweroff", "reboot", "suspend", "hibernate"}},
+		{"dirs", "[]object", nil, nil},
+		{"dirs[].path", "string", nil, nil},
+	}
+
+	for _, tt := range tests {
+		o, ok := config.Lookup(tt.key)
+		if !ok {
+			t.Errorf("%s: not found", tt.key)
+			continue
+		}
+
+		if o.Type != tt.typ {
+			t.Errorf("%s: want type %q, got %q", tt.key, tt.typ, o.Type)
+		}
+
+		if o.Default != tt.def {
+			t.Errorf("%s: want default %v, got %v", tt.key, tt.def, o.Default)
+		}
+
+		if !slices.Equal(o.Values, tt.values) {
+			t.Errorf("%s: want values %q, got %q", tt.key, tt.values, o.Values)
+		}
+	}
+
+	if _, ok := config.Lookup("mqtt.nonexistent"); ok {
+		t.Error("mqtt.nonexistent: want not found")
+	}
+}
+
+func TestInvalidValue(t *testing.T) {
+	err := config.InvalidValue("transport.type", "amqp")
+
+	var verr *config.ValueError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want *ValueError, got %T", err)
+	}
+
+	if want := `invalid transport.type "amqp", must be one of mqtt, nats or kafka`; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
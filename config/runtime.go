@@ -0,0 +1,36 @@
+package config
+
+// RuntimeConfig is the configuration for how the bridge process is scheduled
+// by the kernel. This may be used to reduce the impact of monitoring on
+// latency-sensitive hosts.
+type RuntimeConfig struct {
+	// CPUAffinity is the list of CPUs the bridge may run on. If empty (default)
+	// then the bridge may run on any CPU.
+	CPUAffinity []int `yaml:"cpu_affinity,omitempty"`
+	// Nice is the niceness of the bridge process, from -20 (highest priority) to
+	// 19 (lowest priority). The default value is 0, which leaves the niceness
+	// unchanged. Negative values require the CAP_SYS_NICE capability.
+	Nice int `yaml:"nice,omitempty"`
+	// IOClass is the I/O scheduling class of the bridge process. If blank (default)
+	// then the class is unchanged. The acceptable values are:
+	//	- "realtime" (requires the CAP_SYS_ADMIN capability)
+	//	- "best-effort"
+	//	- "idle"
+	IOClass string `yaml:"io_class,omitempty"`
+	// IOPriority is the priority within IOClass, from 0 (highest priority) to 7
+	// (lowest priority). It is ignored if IOClass is blank or "idle". The default
+	// value is 4.
+	IOPriority int `yaml:"io_priority,omitempty"`
+}
+
+var DefaultRuntime = RuntimeConfig{
+	IOPriority: 4,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg RuntimeConfig) IsZero() bool {
+	return len(cfg.CPUAffinity) == 0 &&
+		cfg.Nice == DefaultRuntime.Nice &&
+		cfg.IOClass == DefaultRuntime.IOClass &&
+		cfg.IOPriority == DefaultRuntime.IOPriority
+}
@@ -0,0 +1,32 @@
+package config
+
+// The methods of [SigningConfig].
+const (
+	SigningHMAC    = "hmac"
+	SigningEd25519 = "ed25519"
+)
+
+// SigningConfig is the configuration for signing metric payloads, so that
+// consumers on a shared broker can detect payloads published by anything but
+// the bridge. Each payload is wrapped in a JSON object with its signature,
+// which is checked with the sign package or "mqttop verify". Signing is
+// applied after encryption, if both are enabled.
+type SigningConfig struct {
+	// Method is the signing method, either "hmac" (default) for HMAC-SHA256 of
+	// a shared secret, or "ed25519", whose public key can verify but not sign
+	// payloads.
+	Method string `yaml:"method,omitempty"`
+	// Key is the HMAC secret, or the Ed25519 private key as printed by
+	// "mqttop keygen --sign". If blank (default) then payloads aren't signed.
+	Key string `yaml:"key,omitempty"`
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg SigningConfig) IsZero() bool {
+	return cfg.Method == "" && cfg.Key == ""
+}
+
+// Enabled indicates if payloads are signed.
+func (cfg *SigningConfig) Enabled() bool {
+	return cfg.Key != ""
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// StatsConfig is the configuration for the statistics of the traffic between
+// the bridge and the broker, such as the number of messages published. The
+// statistics are published to the "bridge/stats" subtopic of the base topic
+// and logged every PublishInterval.
+type StatsConfig struct {
+	// Enabled indicates if the statistics are published and logged. The default
+	// value is false
+	Enabled bool `yaml:"enabled"`
+	// PublishInterval is how often the statistics are published and logged.
+	// Unlike the update interval of the metrics, it isn't set by the --interval
+	// flag. The default value is 5m
+	PublishInterval time.Duration `yaml:"publish_interval,omitempty"`
+}
+
+var DefaultStats = StatsConfig{
+	PublishInterval: 5 * time.Minute,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg StatsConfig) IsZero() bool {
+	return cfg == DefaultStats
+}
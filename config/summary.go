@@ -0,0 +1,61 @@
+package config
+
+// SummaryConfig is the configuration for the summary of the host, which is a
+// single sensor for those who don't want an entity for everything. Its state
+// is the overall health of the host, either "ok", "warn" or "critical", by the
+// worst of the usage of the CPU and memory, the hottest temperature and the
+// fullest disk compared to the thresholds, which are its attributes. The
+// summary is published to the "summary" subtopic of the base topic every
+// update interval.
+type SummaryConfig struct {
+	// Enabled indicates if the summary is published. The default value is
+	// false
+	Enabled bool `yaml:"enabled"`
+	// Warn are the thresholds the health is "warn" at or above. The default
+	// values are 80, except for the disk which is 85
+	Warn SummaryThresholds `yaml:"warn,omitempty"`
+	// Critical are the thresholds the health is "critical" at or above. The
+	// default values are 95
+	Critical SummaryThresholds `yaml:"critical,omitempty"`
+	// HardwareLimits indicates if the high and critical limits of the
+	// temperatures reported by the hardware, such as the max and crit of a
+	// hwmon sensor, are used in place of the temperature thresholds of the
+	// CPU and GPUs that report them. The default value is true
+	HardwareLimits bool `yaml:"hardware_limits"`
+}
+
+// SummaryThresholds are the thresholds of the health of the host, see
+// [SummaryConfig]. A threshold of 0 is ignored.
+type SummaryThresholds struct {
+	// CPU is the threshold of the usage of the CPU as a percent.
+	CPU int `yaml:"cpu,omitempty"`
+	// Memory is the threshold of the used memory as a percent.
+	Memory int `yaml:"memory,omitempty"`
+	// Temperature is the threshold of the hottest temperature of the CPU and
+	// GPUs in °C.
+	Temperature int `yaml:"temperature,omitempty"`
+	// Disk is the threshold of the used space of the fullest disk as a
+	// percent.
+	Disk int `yaml:"disk,omitempty"`
+}
+
+var DefaultSummary = SummaryConfig{
+	Warn: SummaryThresholds{
+		CPU:         80,
+		Memory:      80,
+		Temperature: 80,
+		Disk:        85,
+	},
+	Critical: SummaryThresholds{
+		CPU:         95,
+		Memory:      95,
+		Temperature: 95,
+		Disk:        95,
+	},
+	HardwareLimits: true,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg SummaryConfig) IsZero() bool {
+	return cfg == DefaultSummary
+}
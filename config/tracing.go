@@ -0,0 +1,26 @@
+package config
+
+// TracingConfig is the configuration for the OpenTelemetry tracing of the
+// update and publish pipeline, exported with OTLP over HTTP to a collector
+// such as Jaeger or Tempo. Each update of a metric is a trace, with spans for
+// the update, the serialization of its payload and its publish, so that slow
+// metrics or publishes may be found on busy hosts.
+type TracingConfig struct {
+	// Endpoint is the URL of the OTLP/HTTP endpoint the traces are exported
+	// to, such as "http://localhost:4318". The path defaults to "/v1/traces".
+	// If blank (default) then the updates aren't traced.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// SampleRatio is the ratio of the updates that are traced, from 0 to 1.
+	// If 0 (default) then every update is traced.
+	SampleRatio float64 `yaml:"sample_ratio,omitempty"`
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg TracingConfig) IsZero() bool {
+	return cfg == TracingConfig{}
+}
+
+// Enabled indicates if the updates are traced.
+func (cfg *TracingConfig) Enabled() bool {
+	return cfg.Endpoint != ""
+}
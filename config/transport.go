@@ -0,0 +1,48 @@
+package config
+
+// The types of [TransportConfig].
+const (
+	TransportMQTT  = "mqtt"
+	TransportNATS  = "nats"
+	TransportKafka = "kafka"
+)
+
+// TransportConfig is the configuration for publishing to a message system
+// other than MQTT, such as NATS or Kafka, so that the collectors may be reused
+// without a broker. Home Assistant discovery and the Last Will and Testament
+// are only available with MQTT, and are skipped by the other transports.
+type TransportConfig struct {
+	// Type is the type of the transport, either "mqtt", "nats" or "kafka". If
+	// "mqtt" (default), the MQTT config is used and the rest of this config is
+	// ignored.
+	Type string `yaml:"type,omitempty"`
+	// Servers are the addresses of the servers as host:port, such as
+	// "localhost:4222" for NATS or "localhost:9092" for Kafka. The servers are
+	// tried in order until one accepts the connection.
+	Servers []string `yaml:"servers,omitempty"`
+	// Username is the username used when connecting to the servers. For Kafka
+	// this uses SASL/PLAIN.
+	Username string `yaml:"username,omitempty"`
+	// Password is the password used when connecting to the servers.
+	Password string `yaml:"password,omitempty"`
+	// TLS indicates if TLS is used when connecting to the servers, verified
+	// against the system roots.
+	TLS bool `yaml:"tls,omitempty"`
+	// Topic is the Kafka topic every message is produced to, keyed by its
+	// MQTT topic, since MQTT topics aren't valid Kafka topics. Making the
+	// topic compacted keeps the last message of each key, like a retained
+	// message. The default value is "mqttop"
+	Topic string `yaml:"topic,omitempty"`
+}
+
+var DefaultTransport = TransportConfig{}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg TransportConfig) IsZero() bool {
+	return cfg.Type == "" && len(cfg.Servers) == 0 && cfg.Username == "" && cfg.Password == "" && !cfg.TLS && cfg.Topic == ""
+}
+
+// IsMQTT reports whether the transport is MQTT.
+func (cfg *TransportConfig) IsMQTT() bool {
+	return cfg.Type == "" || cfg.Type == TransportMQTT
+}
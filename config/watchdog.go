@@ -0,0 +1,23 @@
+package config
+
+// WatchdogConfig is the configuration for the watchdog of the bridge, which
+// restarts any metric that hasn't updated in MissedIntervals update intervals,
+// or whose updates stopped unexpectedly. A warning event is published to the
+// "bridge/watchdog" subtopic of the base topic for each restart.
+type WatchdogConfig struct {
+	// Enabled indicates if stuck metrics are restarted. The default value is
+	// false
+	Enabled bool `yaml:"enabled"`
+	// MissedIntervals is the number of update intervals a metric may go
+	// without updating before it is restarted. The default value is 3
+	MissedIntervals int `yaml:"missed_intervals,omitempty"`
+}
+
+var DefaultWatchdog = WatchdogConfig{
+	MissedIntervals: 3,
+}
+
+// IsZero indicates whether cfg is the default value.
+func (cfg WatchdogConfig) IsZero() bool {
+	return cfg == DefaultWatchdog
+}
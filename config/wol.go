@@ -0,0 +1,13 @@
+package config
+
+// WOLConfig is the configuration of a Wake-on-LAN target, which may be woken by
+// publishing its name or MAC address to "<base_topic>/bridge/wol".
+type WOLConfig struct {
+	// Name is the name of the target. If blank (default) then the name is MAC.
+	Name string `yaml:"name,omitempty"`
+	// MAC is the MAC address of the target.
+	MAC string `yaml:"mac"`
+	// Broadcast is the address the magic packet is sent to, with an optional
+	// port. The default value is "255.255.255.255:9".
+	Broadcast string `yaml:"broadcast,omitempty"`
+}
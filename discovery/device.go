@@ -4,10 +4,9 @@ import (
 	"encoding/base64"
 	"slices"
 
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
-
+	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/sysfs"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 // Connection is a tuple of the form [connnection_type, connection_identifier] used for
@@ -32,6 +31,7 @@ type Device struct {
 	SerialNumber     string       `json:"sn,omitempty"`
 	SuggestedArea    string       `json:"sa,omitempty"`
 	SWVersion        string       `json:"sw,omitempty"`
+	ViaDevice        string       `json:"via_device,omitempty"`
 }
 
 var defaultHostnames = []string{
@@ -40,26 +40,28 @@ var defaultHostnames = []string{
 }
 
 // NewDevice returns a new Device with an identifier equal to the sha256 sum of
-// the device's machine id, encoded in base64.
-func NewDevice() (*Device, error) {
+// the device's machine id, encoded in base64. The device is read from the
+// files of root, or of the host if root is nil.
+func NewDevice(root *vfs.Root) (*Device, error) {
 	d := &Device{}
+	sys := sysfs.NewFS(root)
 
-	id, err := sysfs.MachineID()
+	id, err := sys.MachineID()
 	if err != nil {
 		return nil, err
 	}
 
 	d.Identifiers = []string{base64.RawURLEncoding.EncodeToString(id)}
 
-	if name, err := sysfs.Hostname(); err == nil && !slices.Contains(defaultHostnames, name) {
-		d.Name = cases.Title(language.English).String(name)
+	if name, err := sys.Hostname(); err == nil && !slices.Contains(defaultHostnames, name) {
+		d.Name = byteutil.ToTitleString(name)
 	}
 
-	if r, err := sysfs.OSRelease(); err == nil {
+	if r, err := sys.OSRelease(); err == nil {
 		d.SWVersion = r
 	}
 
-	dmi, err := sysfs.DMI()
+	dmi, err := sys.DMI()
 	if err != nil {
 		return d, nil
 	}
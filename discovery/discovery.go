@@ -7,24 +7,30 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"iter"
 	"maps"
 	"math/rand/v2"
+	"net/url"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 
 	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/internal/mqttutil"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 // Home Assistant entity platforms
 const (
 	BinarySensor = "binary_sensor" // https://www.home-assistant.io/integrations/binary_sensor.mqtt/
 	Button       = "button"        // https://www.home-assistant.io/integrations/button.mqtt/
+	Number       = "number"        // https://www.home-assistant.io/integrations/number.mqtt/
 	Select       = "select"        // https://www.home-assistant.io/integrations/select.mqtt/
 	Sensor       = "sensor"        // https://www.home-assistant.io/integrations/sensor.mqtt/
 	Switch       = "switch"        // https://www.home-assistant.io/integrations/switch.mqtt/
@@ -36,6 +42,11 @@ const (
 	Diagnostic = "diagnostic"
 )
 
+// Child devices, which are each enabled by the discovery config.
+const (
+	ChildDirs = "dirs" // see [config.DiscoveryConfig].DirsDevice
+)
+
 const (
 	AvailabilityAll    = "all"
 	AvailabilityAny    = "any"
@@ -71,11 +82,24 @@ type Discovery struct {
 
 	cfg *config.DiscoveryConfig
 
-	AvailabilityTopic string              `json:"-"`
-	ObjectID          string              `json:"-"`
-	NodeID            string              `json:"-"`
-	Nodes             map[string][]string `json:"_nodes,omitempty"`
-	Method            string              `json:"_method,omitempty"`
+	AvailabilityTopic   string              `json:"-"`
+	PayloadAvailable    string              `json:"-"`
+	PayloadNotAvailable string              `json:"-"`
+	ObjectID            string              `json:"-"`
+	IDPrefix            string              `json:"-"`
+	NodeID              string              `json:"-"`
+	Nodes               map[string][]string `json:"_nodes,omitempty"`
+	// Children maps the name of each enabled child device to its components,
+	// which are discovered as part of the child device instead of Device. See
+	// [Discovery.AddChild].
+	Children  map[string][]string `json:"_children,omitempty"`
+	Method    string              `json:"_method,omitempty"`
+	CoreNodes string              `json:"-"`
+	// Timeout is how long to wait for the broker to acknowledge each message,
+	// see [config.MQTTConfig].PublishTimeout. If 0, the default of the client is used.
+	Timeout time.Duration `json:"-"`
+
+	migration *Migration
 }
 
 // Load returns the decoded value of a discovery payload at the file path.
@@ -99,12 +123,48 @@ func Load(path string) (*Discovery, error) {
 }
 
 // New returns a new Discovery struct initialized from the provided config.
+// If cfg.Instance is set, the instance name is appended to the identifier,
+// object id and unique ids of the device, so that each instance on a host is
+// discovered as a separate device.
 func New(cfg *config.DiscoveryConfig) (*Discovery, error) {
-	dev, err := NewDevice()
+	root, err := vfs.NewRoot(cfg.RootFS)
+	if err != nil {
+		return nil, err
+	}
+
+	dev, err := NewDevice(root)
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.DeviceID != "" {
+		if !validID(cfg.DeviceID) {
+			return nil, fmt.Errorf("invalid device_id %q, may only consist of characters from [a-zA-Z0-9_-]", cfg.DeviceID)
+		}
+
+		dev.Identifiers = []string{cfg.DeviceID}
+	}
+
+	if cfg.ConfigurationURL != "" {
+		if !validURL(cfg.ConfigurationURL) {
+			return nil, fmt.Errorf("invalid configuration_url %q, must be an absolute http, https or homeassistant URL", cfg.ConfigurationURL)
+		}
+
+		dev.ConfigurationURL = cfg.ConfigurationURL
+	}
+
+	dev.SuggestedArea = cfg.SuggestedArea
+
+	if !validID(cfg.Instance) {
+		return nil, fmt.Errorf("invalid instance %q, may only consist of characters from [a-zA-Z0-9_-]", cfg.Instance)
+	}
+
+	switch cfg.CoreNodes {
+	case "", "cpu", "cores", "each":
+	default:
+		return nil, config.InvalidValue("discovery.core_nodes", cfg.CoreNodes)
+	}
+
 	switch cfg.DeviceName {
 	case "", "hostname":
 	default:
@@ -115,20 +175,31 @@ func New(cfg *config.DiscoveryConfig) (*Discovery, error) {
 		dev.Name = "Mqttop"
 	}
 
+	if cfg.Instance != "" && (cfg.DeviceName == "" || cfg.DeviceName == "hostname") {
+		dev.Name += " (" + cfg.Instance + ")"
+	}
+
 	d := &Discovery{
-		Origin:            NewOrigin(),
-		Device:            dev,
-		Components:        make(map[string]Component),
-		NodeID:            cfg.NodeID,
-		AvailabilityTopic: cfg.Availability,
-		cfg:               cfg,
-		Method:            cfg.Method,
+		Origin:              NewOrigin(),
+		Device:              dev,
+		Components:          make(map[string]Component),
+		NodeID:              cfg.NodeID,
+		AvailabilityTopic:   cfg.Availability,
+		PayloadAvailable:    cfg.PayloadAvailable,
+		PayloadNotAvailable: cfg.PayloadNotAvailable,
+		cfg:                 cfg,
+		Method:              cfg.Method,
+		CoreNodes:           cfg.CoreNodes,
 	}
 
 	if d.Method == "nodes" || d.Method == "metrics" {
 		d.Nodes = make(map[string][]string)
 	}
 
+	if cfg.DirsDevice {
+		d.Children = map[string][]string{ChildDirs: nil}
+	}
+
 	if d.NodeID == "" {
 		d.NodeID = "mqttop"
 	}
@@ -148,9 +219,195 @@ func New(cfg *config.DiscoveryConfig) (*Discovery, error) {
 		return nil, errors.New("no object id")
 	}
 
+	if cfg.DeviceID != "" {
+		d.IDPrefix = d.Origin.Name + "_" + cfg.DeviceID
+	} else if id := dev.Identifiers[0]; len(id) > deviceIDLen {
+		d.IDPrefix = d.Origin.Name + "_" + id[:deviceIDLen]
+	} else {
+		d.IDPrefix = d.Origin.Name + "_" + id
+	}
+
+	if cfg.Instance != "" {
+		// Each instance is discovered as a separate device
+		dev.Identifiers[0] += "_" + cfg.Instance
+		d.ObjectID += "_" + cfg.Instance
+		d.IDPrefix += "_" + cfg.Instance
+	}
+
 	return d, nil
 }
 
+// deviceIDLen is the length of the machine id used in unique ids, which is
+// shortened since it only needs to be unique among the devices of a single
+// Home Assistant instance.
+const deviceIDLen = 8
+
+// validID reports whether id may be used as part of a discovery topic.
+func validID(id string) bool {
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// validURL reports whether u may be used as the configuration_url of a device.
+func validURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return parsed.Host != ""
+	case "homeassistant":
+		return true
+	}
+
+	return false
+}
+
+// ChildDevice returns the device of the named child of d.Device, which is
+// connected via d.Device and identified by its identifier followed by name.
+func (d *Discovery) ChildDevice(name string) *Device {
+	parent := d.ObjectID
+	if len(d.Device.Identifiers) > 0 {
+		parent = d.Device.Identifiers[0]
+	}
+
+	return &Device{
+		ConfigurationURL: d.Device.ConfigurationURL,
+		Identifiers:      []string{parent + "_" + name},
+		Name:             d.Device.Name + " " + name,
+		SuggestedArea:    d.Device.SuggestedArea,
+		ViaDevice:        parent,
+	}
+}
+
+// AddChild adds the components to the named child device, if it's enabled. The
+// components are then discovered as part of the child device instead of
+// d.Device.
+func (d *Discovery) AddChild(name string, components ...string) {
+	cmps, ok := d.Children[name]
+	if !ok {
+		return
+	}
+
+	for _, c := range components {
+		if !slices.Contains(cmps, c) {
+			cmps = append(cmps, c)
+		}
+	}
+
+	d.Children[name] = cmps
+}
+
+// childOf returns the name of the child device that the component belongs to,
+// or a blank string if it belongs to d.Device.
+func (d *Discovery) childOf(component string) string {
+	for name, cmps := range d.Children {
+		if slices.Contains(cmps, component) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// devicePayload is a device discovery payload, which is published to the
+// device discovery topic with the object id.
+type devicePayload struct {
+	objectID string
+	payload  *Discovery
+}
+
+// devicePayloads splits the components of d by the device they belong to,
+// returning the device discovery payload of each device with components. The
+// payload of d.Device is only left out if all of the components belong to
+// child devices.
+func (d *Discovery) devicePayloads() []devicePayload {
+	payloads := []devicePayload{{
+		objectID: d.ObjectID,
+		payload: &Discovery{
+			Origin:     d.Origin,
+			Device:     d.Device,
+			Components: make(map[string]Component, len(d.Components)),
+		},
+	}}
+
+	children := make(map[string]int, len(d.Children))
+
+	for name, cmp := range d.Components {
+		child := d.childOf(name)
+		if child == "" {
+			payloads[0].payload.Components[name] = cmp
+			continue
+		}
+
+		i, ok := children[child]
+		if !ok {
+			i = len(payloads)
+			children[child] = i
+			payloads = append(payloads, devicePayload{
+				objectID: d.ObjectID + "_" + child,
+				payload: &Discovery{
+					Origin:     d.Origin,
+					Device:     d.ChildDevice(child),
+					Components: make(map[string]Component),
+				},
+			})
+		}
+
+		payloads[i].payload.Components[name] = cmp
+	}
+
+	if len(payloads) > 1 && len(payloads[0].payload.Components) == 0 {
+		payloads = payloads[1:]
+	}
+
+	return payloads
+}
+
+// ID returns the unique id of the component with the given name, which is also
+// its key in d.Components. The name is prefixed by d.IDPrefix, so that the
+// components of multiple devices discovered by the same Home Assistant don't
+// collide, for example "mqttop_1a2B3c4D_cpu". If d.IDPrefix is blank, the
+// origin name is used as the prefix.
+func (d *Discovery) ID(name string) string {
+	if d.IDPrefix == "" {
+		return d.Origin.Name + "_" + name
+	}
+
+	return d.IDPrefix + "_" + name
+}
+
+// Legacy returns an empty copy of d that uses the unique ids of older versions,
+// which were prefixed only by the origin name. Discovering components into it
+// and passing it to [Discovery.Diff] removes those components, such as when no
+// previous discovery payload was saved. If d already uses the legacy unique ids,
+// Legacy returns nil.
+func (d *Discovery) Legacy() *Discovery {
+	if d.IDPrefix == "" || d.IDPrefix == d.Origin.Name {
+		return nil
+	}
+
+	l := *d
+	l.IDPrefix = ""
+	l.Components = make(map[string]Component)
+	l.Children = d.emptyChildren()
+
+	if d.Nodes != nil {
+		l.Nodes = make(map[string][]string)
+	}
+
+	return &l
+}
+
 // Topic returns the topic to publish the discovery payload to using the provided prefix.
 func (d *Discovery) Topic(prefix, component, nodeID, objectID string) string {
 	if objectID == "" {
@@ -168,6 +425,105 @@ func (d *Discovery) Topic(prefix, component, nodeID, objectID string) string {
 	return strings.Join(elems, "/")
 }
 
+// Topics returns the sorted topics that [Discovery.Publish] publishes the
+// discovery payload to with the method of d, not including those of a migration.
+func (d *Discovery) Topics() []string {
+	return d.topics(d.Method, d.Components, d.Nodes, d.Children)
+}
+
+// topics returns the sorted topics that the discovery payload of components,
+// nodes and child devices is published to with method.
+func (d *Discovery) topics(method string, components map[string]Component, nodes, children map[string][]string) []string {
+	var topics []string
+
+	inChild := func(c string) bool {
+		for _, cmps := range children {
+			if slices.Contains(cmps, c) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// childTopics appends the topic of each child device with any of cmps.
+	childTopics := func(nodeID string, cmps []string) {
+		for child, ccmps := range children {
+			if slices.ContainsFunc(cmps, func(c string) bool { return slices.Contains(ccmps, c) }) {
+				topics = append(topics, d.Topic(d.cfg.Prefix, "device", nodeID, d.ObjectID+"_"+child))
+			}
+		}
+	}
+
+	switch normalizeMethod(method) {
+	case MethodComponents:
+		for name, cmp := range components {
+			platform, _ := cmp[Platform].(string)
+			topics = append(topics, d.Topic(d.cfg.Prefix, platform, d.NodeID, name))
+		}
+	case MethodNodes:
+		for node, cmps := range nodes {
+			if len(cmps) == 0 {
+				continue
+			}
+
+			// Nodes of only child components have no payload of d.Device.
+			if slices.ContainsFunc(cmps, func(c string) bool { return !inChild(c) }) {
+				topics = append(topics, d.Topic(d.cfg.Prefix, "device", d.NodeID+"_"+node, d.ObjectID))
+			}
+
+			childTopics(d.NodeID+"_"+node, cmps)
+		}
+	default:
+		topics = append(topics, d.Topic(d.cfg.Prefix, "device", d.NodeID, d.ObjectID))
+		childTopics(d.NodeID, slices.Collect(maps.Keys(components)))
+	}
+
+	slices.Sort(topics)
+
+	return topics
+}
+
+// WaitTopic returns the topic subscribed to by [Discovery.Wait] and
+// [Discovery.Subscribe], which may be blank.
+func (d *Discovery) WaitTopic() string {
+	return d.cfg.WaitTopic
+}
+
+// CoreNode returns the node that the components of the given CPU core belong to
+// if d.Nodes is not nil. See [config.DiscoveryConfig.CoreNodes].
+func (d *Discovery) CoreNode(core int) string {
+	switch d.CoreNodes {
+	case "cores":
+		return "cores"
+	case "each":
+		return "cpu_core_" + strconv.Itoa(core)
+	}
+
+	return "cpu"
+}
+
+// AvailabilityTemplate returns the template of the availability of components
+// with d.AvailabilityTopic. The payload of the will, d.PayloadNotAvailable, is
+// mapped to "offline", and any other payload, such as the birth, to "online".
+// If topic is not blank, the states of the metrics published to the
+// availability topic are mapped by the state of the metric with topic.
+func (d *Discovery) AvailabilityTemplate(topic string) string {
+	offline := d.PayloadNotAvailable
+	if offline == "" {
+		offline = "offline"
+	}
+
+	if topic == "" {
+		return fmt.Sprintf("{{ iif(value == %q, 'offline', 'online') }}", offline)
+	}
+
+	return fmt.Sprintf(
+		"{{ iif(value_json[%q]|default, 'online', 'offline') if value_json is mapping else iif(value == %q, 'offline', 'online') }}",
+		topic, offline,
+	)
+}
+
 // SetAvailability sets the availability of all components to the one provided.
 func (d *Discovery) SetAvailability(avail Component) {
 	for cmp := range d.Components {
@@ -207,25 +563,15 @@ func (d *Discovery) Wait(ctx context.Context, c mqtt.Client) error {
 		msg.Ack()
 
 		if d.cfg.WaitPayload == "" || string(msg.Payload()) == d.cfg.WaitPayload {
-			t := c.Unsubscribe(d.cfg.WaitTopic)
-			select {
-			case <-ctx.Done():
-			case <-t.Done():
-			}
+			err := d.wait(ctx, c.Unsubscribe(d.cfg.WaitTopic))
 			select {
-			case ch <- t.Error():
+			case ch <- err:
 			default:
 			}
 		}
 	})
 
-	select {
-	case <-ctx.Done():
-		return nil
-	case <-t.Done():
-	}
-
-	if err := t.Error(); err != nil {
+	if err := d.wait(ctx, t); err != nil {
 		return err
 	}
 
@@ -257,23 +603,21 @@ func (d *Discovery) publishDevice(ctx context.Context, c mqtt.Client, migrate bo
 	return nil
 }
 
+// publishDeviceNode publishes the device discovery payload of d.Device, and
+// that of each child device with components, to the node.
 func (d *Discovery) publishDeviceNode(ctx context.Context, c mqtt.Client, nodeID string) error {
-	payload, err := json.Marshal(d)
-	if err != nil {
-		return err
-	}
-
-	topic := d.Topic(d.cfg.Prefix, "device", nodeID, d.ObjectID)
-	t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, payload)
+	for _, p := range d.devicePayloads() {
+		payload, err := json.Marshal(p.payload)
+		if err != nil {
+			return err
+		}
 
-	select {
-	case <-ctx.Done():
-		return nil
-	case <-t.Done():
-	}
+		topic := d.Topic(d.cfg.Prefix, "device", nodeID, p.objectID)
+		t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, payload)
 
-	if err := t.Error(); err != nil {
-		return err
+		if err := d.wait(ctx, t); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -307,7 +651,12 @@ func (d *Discovery) publishComponents(ctx context.Context, c mqtt.Client, migrat
 		} else {
 			delete(cmp, Platform)
 			cmp[optOrigin] = d.Origin
-			cmp[optDevice] = d.Device
+
+			if child := d.childOf(name); child != "" {
+				cmp[optDevice] = d.ChildDevice(child)
+			} else {
+				cmp[optDevice] = d.Device
+			}
 			payload, err = json.Marshal(cmp)
 
 			if err != nil {
@@ -322,14 +671,12 @@ func (d *Discovery) publishComponents(ctx context.Context, c mqtt.Client, migrat
 		topic := d.Topic(d.cfg.Prefix, platform, d.NodeID, name)
 		t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, payload)
 
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-t.Done():
+		if err := d.wait(ctx, t); err != nil {
+			return err
 		}
 
-		if err := t.Error(); err != nil {
-			return err
+		if ctx.Err() != nil {
+			return nil
 		}
 	}
 
@@ -353,6 +700,7 @@ func (d *Discovery) publishNodes(ctx context.Context, c mqtt.Client, migrate boo
 		Device:     d.Device,
 		Components: make(map[string]Component),
 		ObjectID:   d.ObjectID,
+		Children:   d.Children,
 		cfg:        d.cfg,
 	}
 
@@ -391,10 +739,18 @@ func (d *Discovery) publishNodes(ctx context.Context, c mqtt.Client, migrate boo
 	return nil
 }
 
-// Publish publishes the discovery payload. If migrate is true, Publish migrates the discovery payload
+// Publish publishes the discovery payload. If migrate is true, Publish runs the migration found by
+// [Discovery.Diff], if any. Otherwise, if migrate is true, Publish migrates the discovery payload
 // either from a device discovery to individual component discoveries, or from individual component
 // discoveries to a device discovery.
 func (d *Discovery) Publish(ctx context.Context, c mqtt.Client, migrate bool, args ...string) (err error) {
+	if migrate && d.migration != nil {
+		m := d.migration
+		d.migration = nil
+
+		return m.Run(ctx, c)
+	}
+
 	method := d.Method
 	d.Method = ""
 
@@ -448,28 +804,18 @@ func (d *Discovery) SubscribeFunc(ctx context.Context, c mqtt.Client, f func(con
 		}
 	})
 
-	select {
-	case <-ctx.Done():
-		return nil
-	case <-t.Done():
-	}
-
-	return t.Error()
+	return d.wait(ctx, t)
 }
 
-func shouldMigrate(method, old string) bool {
-	switch old {
-	case "", "device":
-		return method == "components"
-	case "components":
-		return method == "" || method == "device"
-	}
-
-	return false
+// wait waits for t to be done, or until ctx is done or the timeout of d.
+func (d *Discovery) wait(ctx context.Context, t mqtt.Token) error {
+	return mqttutil.Wait(ctx, t, mqttutil.Timeout(d.Timeout))
 }
 
 // Diff adds an empty component to d for each component in old that
-// isn't already in d. Diff returns true if d should be migrated.
+// isn't already in d. Diff returns true if d should be migrated, in which case
+// the migration is returned by [Discovery.Migration] and run by the next call
+// to [Discovery.Publish] with migrate set to true.
 func (d *Discovery) Diff(old *Discovery) bool {
 	if old == nil {
 		return false
@@ -485,7 +831,124 @@ func (d *Discovery) Diff(old *Discovery) bool {
 		}
 	}
 
-	return shouldMigrate(d.Method, old.Method)
+	d.migration = d.MigrationFrom(old)
+
+	return d.migration != nil
+}
+
+// ComponentsOf returns the sorted names of the components that dd adds to the
+// discovery payload, without adding them to d.
+func (d *Discovery) ComponentsOf(dd Discoverer) []string {
+	tmp := *d
+	tmp.Components = make(map[string]Component)
+	tmp.Children = d.emptyChildren()
+
+	if d.Nodes != nil {
+		tmp.Nodes = make(map[string][]string)
+	}
+
+	dd.Discover(&tmp)
+
+	return slices.Sorted(maps.Keys(tmp.Components))
+}
+
+// Delete deletes the components from d without publishing their removal, such
+// as before d is first published. Any nodes that are left empty are deleted.
+func (d *Discovery) Delete(components ...string) {
+	for _, name := range components {
+		delete(d.Components, name)
+	}
+
+	for node, cmps := range d.Nodes {
+		cmps = slices.DeleteFunc(cmps, func(c string) bool {
+			return slices.Contains(components, c)
+		})
+
+		if len(cmps) == 0 {
+			delete(d.Nodes, node)
+		} else {
+			d.Nodes[node] = cmps
+		}
+	}
+
+	// Child devices are kept even without components, since they're enabled
+	// by the config.
+	for child, cmps := range d.Children {
+		d.Children[child] = slices.DeleteFunc(cmps, func(c string) bool {
+			return slices.Contains(components, c)
+		})
+	}
+}
+
+// emptyChildren returns the child devices enabled for d without components, or
+// nil if none are enabled.
+func (d *Discovery) emptyChildren() map[string][]string {
+	if d.Children == nil {
+		return nil
+	}
+
+	children := make(map[string][]string, len(d.Children))
+	for child := range d.Children {
+		children[child] = nil
+	}
+
+	return children
+}
+
+// Remove publishes the removal of the components and then deletes them from d.
+// Nodes that are left without components have their discovery payload removed.
+func (d *Discovery) Remove(ctx context.Context, c mqtt.Client, components ...string) error {
+	var nodes []string
+
+	for _, name := range components {
+		if cmp, ok := d.Components[name]; ok {
+			// A component with only a platform is removed by Home Assistant.
+			d.Components[name] = Component{
+				Platform: cmp[Platform],
+			}
+		}
+	}
+
+	for node, cmps := range d.Nodes {
+		n := 0
+
+		for _, name := range cmps {
+			if slices.Contains(components, name) {
+				n++
+			}
+		}
+
+		switch n {
+		case 0:
+		case len(cmps):
+			if err := d.removeDeviceNode(ctx, c, d.NodeID+"_"+node); err != nil {
+				return err
+			}
+		default:
+			nodes = append(nodes, node)
+		}
+	}
+
+	var err error
+
+	switch d.Method {
+	case "", "device":
+		err = d.Publish(ctx, c, false)
+	case "components":
+		err = d.removeComponents(ctx, c, components...)
+	default:
+		if len(nodes) > 0 {
+			err = d.Publish(ctx, c, false, nodes...)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	d.Delete(components...)
+
+	return nil
 }
 
 func (d *Discovery) Discover(dd ...Discoverer) {
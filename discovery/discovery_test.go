@@ -0,0 +1,246 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/mock"
+)
+
+func TestID(t *testing.T) {
+	d := &Discovery{
+		Origin:     NewOrigin(),
+		Components: make(map[string]Component),
+	}
+
+	if want, got := "mqttop_cpu", d.ID("cpu"); got != want {
+		t.Errorf("ID: want %q, got %q", want, got)
+	}
+	if l := d.Legacy(); l != nil {
+		t.Errorf("Legacy: want nil, got %v", l)
+	}
+
+	d.IDPrefix = "mqttop_host1"
+	d.Components[d.ID("cpu")] = Component{Platform: Sensor, Name: "CPU usage"}
+
+	if want, got := "mqttop_host1_cpu", d.ID("cpu"); got != want {
+		t.Errorf("ID: want %q, got %q", want, got)
+	}
+
+	l := d.Legacy()
+	if l == nil {
+		t.Fatal("Legacy: want discovery, got nil")
+	}
+	if len(l.Components) > 0 {
+		t.Errorf("Legacy: want no components, got %v", l.Components)
+	}
+
+	l.Components[l.ID("cpu")] = Component{Platform: Sensor, Name: "CPU usage"}
+	d.Diff(l)
+
+	if cmp, ok := d.Components["mqttop_cpu"]; !ok || len(cmp) != 1 {
+		t.Errorf("Diff: want legacy component removed, got %v", cmp)
+	}
+}
+
+func TestValidID(t *testing.T) {
+	var tests = []struct {
+		id   string
+		want bool
+	}{
+		{"host-1_A", true},
+		{"host 1", false},
+		{"host/1", false},
+		{"hôst", false},
+	}
+	for _, tt := range tests {
+		if got := validID(tt.id); got != tt.want {
+			t.Errorf("%q: want %v, got %v", tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	var tests = []struct {
+		url  string
+		want bool
+	}{
+		{"https://host.lan:9090", true},
+		{"http://192.168.1.10:8006/#v1:0:=node%2Fpve", true},
+		{"homeassistant://hassio/ingress/core_configurator", true},
+		{"host.lan:9090", false},
+		{"https://", false},
+		{"ftp://host.lan", false},
+	}
+	for _, tt := range tests {
+		if got := validURL(tt.url); got != tt.want {
+			t.Errorf("%q: want %v, got %v", tt.url, tt.want, got)
+		}
+	}
+}
+
+func TestNew_CoreNodes(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		ok    bool
+	}{
+		{"", true},
+		{"cpu", true},
+		{"cores", true},
+		{"each", true},
+		{"core", false},
+	} {
+		_, err := New(&config.DiscoveryConfig{CoreNodes: tt.value})
+
+		var verr *config.ValueError
+		if got := !errors.As(err, &verr); got != tt.ok {
+			t.Errorf("%q: want ok %v, got %v", tt.value, tt.ok, err)
+		}
+	}
+}
+
+func TestAvailabilityTemplate(t *testing.T) {
+	tests := []struct {
+		name  string
+		will  string
+		topic string
+		want  string
+	}{
+		{"Bridge", "", "", `{{ iif(value == "offline", 'offline', 'online') }}`},
+		{"Bridge/Will", "down", "", `{{ iif(value == "down", 'offline', 'online') }}`},
+		{
+			"Metric", "", "mqttop/cpu",
+			`{{ iif(value_json["mqttop/cpu"]|default, 'online', 'offline') if value_json is mapping else iif(value == "offline", 'offline', 'online') }}`,
+		},
+		{
+			"Metric/Will", "down", "mqttop/cpu",
+			`{{ iif(value_json["mqttop/cpu"]|default, 'online', 'offline') if value_json is mapping else iif(value == "down", 'offline', 'online') }}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Discovery{PayloadAvailable: "online", PayloadNotAvailable: tt.will}
+
+			if got := d.AvailabilityTemplate(tt.topic); got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChildren(t *testing.T) {
+	const (
+		device     = "homeassistant/device/host/mqttop/config"
+		dirs       = "homeassistant/device/host/mqttop_dirs/config"
+		cpuNode    = "homeassistant/device/host_cpu/mqttop/config"
+		memoryNode = "homeassistant/device/host_memory/mqttop/config"
+		dirsNode   = "homeassistant/device/host_dir/mqttop_dirs/config"
+		tmp        = "homeassistant/sensor/host/mqttop_dir_tmp/config"
+	)
+
+	tests := []struct {
+		method string
+		topics []string
+	}{
+		{"device", []string{device, dirs}},
+		{"nodes", []string{cpuNode, memoryNode, dirsNode}},
+	}
+
+	for _, tt := range tests {
+		d := testDiscovery(tt.method)
+		d.Device = &Device{Name: "Host", Identifiers: []string{"abc"}, SuggestedArea: "Office"}
+		d.Children = map[string][]string{ChildDirs: nil}
+		d.Components["mqttop_dir_tmp"] = Component{Platform: Sensor, Name: "Dir tmp"}
+		d.Nodes["dir"] = []string{"mqttop_dir_tmp"}
+		d.AddChild(ChildDirs, "mqttop_dir_tmp")
+		d.AddChild(ChildDirs, "mqttop_dir_tmp")
+		d.AddChild("unknown", "mqttop_cpu")
+
+		if want, got := []string{"mqttop_dir_tmp"}, d.Children[ChildDirs]; !slices.Equal(got, want) {
+			t.Errorf("%s: want children %q, got %q", tt.method, want, got)
+		}
+		if _, ok := d.Children["unknown"]; ok {
+			t.Errorf("%s: want child devices that aren't enabled ignored", tt.method)
+		}
+
+		if got := d.Topics(); !slices.Equal(got, slices.Sorted(slices.Values(tt.topics))) {
+			t.Errorf("%s: want topics %q, got %q", tt.method, tt.topics, got)
+		}
+
+		var b bytes.Buffer
+
+		c := mock.NewMockClient(mqtt.NewClientOptions(), &b)
+		if err := d.Publish(context.Background(), c, false); err != nil {
+			t.Fatal(err)
+		}
+
+		topics, payloads := published(t, &b)
+		if got := slices.Sorted(slices.Values(topics)); !slices.Equal(got, d.Topics()) {
+			t.Errorf("%s: want published %q, got %q", tt.method, d.Topics(), got)
+		}
+
+		for i, topic := range topics {
+			var p struct {
+				Device     Device               `json:"dev"`
+				Components map[string]Component `json:"cmps"`
+			}
+			if err := json.Unmarshal([]byte(payloads[i]), &p); err != nil {
+				t.Fatal(err)
+			}
+
+			_, hasDir := p.Components["mqttop_dir_tmp"]
+
+			if topic == dirs || topic == dirsNode {
+				want := Device{Name: "Host dirs", Identifiers: []string{"abc_dirs"}, SuggestedArea: "Office", ViaDevice: "abc"}
+				if !slices.Equal(p.Device.Identifiers, want.Identifiers) || p.Device.Name != want.Name ||
+					p.Device.ViaDevice != want.ViaDevice || p.Device.SuggestedArea != want.SuggestedArea {
+					t.Errorf("%s: want device %+v, got %+v", topic, want, p.Device)
+				}
+				if !hasDir || len(p.Components) != 1 {
+					t.Errorf("%s: want only the dir component, got %v", topic, p.Components)
+				}
+			} else if hasDir {
+				t.Errorf("%s: want dir component in child device, got %v", topic, p.Components)
+			}
+		}
+	}
+
+	d := testDiscovery("components")
+	d.Device = &Device{Name: "Host", Identifiers: []string{"abc"}}
+	d.Children = map[string][]string{ChildDirs: {"mqttop_dir_tmp"}}
+	d.Components["mqttop_dir_tmp"] = Component{Platform: Sensor, Name: "Dir tmp"}
+
+	var b bytes.Buffer
+
+	c := mock.NewMockClient(mqtt.NewClientOptions(), &b)
+	if err := d.Publish(context.Background(), c, false); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, payloads := published(t, &b)
+	for i, topic := range topics {
+		var p struct {
+			Device Device `json:"dev"`
+		}
+		if err := json.Unmarshal([]byte(payloads[i]), &p); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := topic == tmp; (p.Device.ViaDevice == "abc") != want {
+			t.Errorf("%s: want child device %v, got %+v", topic, want, p.Device)
+		}
+	}
+
+	d.Delete("mqttop_dir_tmp")
+	if cmps, ok := d.Children[ChildDirs]; !ok || len(cmps) != 0 {
+		t.Errorf("Delete: want empty child device kept, got %q (%v)", cmps, ok)
+	}
+}
@@ -5,15 +5,32 @@ package icon
 
 // Icon names
 const (
+	Alert         = "mdi:alert-circle-outline"
+	Application   = "mdi:application-cog"
 	Battery       = "mdi:battery"
+	BatteryCharge = "mdi:battery-charging"
+	BatteryClock  = "mdi:battery-clock"
+	Broom         = "mdi:broom"
 	CPU32Bit      = "mdi:cpu-32-bit"
 	CPU64Bit      = "mdi:cpu-64-bit"
+	Connection    = "mdi:connection"
 	Database      = "mdi:database"
 	ExpansionCard = "mdi:expansion-card"
+	Fan           = "mdi:fan"
 	Folder        = "mdi:folder"
+	Function      = "mdi:function-variant"
+	Gauge         = "mdi:gauge"
+	Handshake     = "mdi:handshake"
 	HardDisk      = "mdi:harddisk"
 	Memory        = "mdi:memory"
+	Music         = "mdi:music"
+	Power         = "mdi:power"
+	Restart       = "mdi:restart"
 	ServerNetwork = "mdi:server-network"
+	Sleep         = "mdi:power-sleep"
+	Timer         = "mdi:timer-outline"
+	VolumeHigh    = "mdi:volume-high"
+	VolumeOff     = "mdi:volume-off"
 )
 
 const bitCount = 32 << (^uint(0) >> 63)
@@ -2,14 +2,153 @@ package discovery
 
 import (
 	"context"
+	"maps"
 	"slices"
+	"strings"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
-func (d *Discovery) removeComponents(ctx context.Context, c mqtt.Client, components ...string) error {
-	payload := []byte{}
+// Methods of publishing the discovery payload, set by the method option of
+// the discovery config.
+const (
+	// MethodDevice publishes every component in a single device discovery
+	// payload. This is the default, also selected by a blank method.
+	MethodDevice = "device"
+	// MethodComponents publishes each component in its own discovery payload.
+	MethodComponents = "components"
+	// MethodNodes publishes the components of each metric in a device
+	// discovery payload of its own node. "metrics" is an alias.
+	MethodNodes = "nodes"
+)
+
+// normalizeMethod returns the method that method is an alias of.
+func normalizeMethod(method string) string {
+	switch method {
+	case "", MethodDevice:
+		return MethodDevice
+	case "metrics":
+		return MethodNodes
+	}
+
+	return method
+}
+
+// Migration is the migration of a published discovery payload from one method
+// to another, so that Home Assistant moves the existing entities to the new
+// discovery topics instead of orphaning them.
+//
+// A migration is run in three steps:
+//  1. `{"migrate_discovery": true}` is published to each of the Old topics,
+//     which keeps Home Assistant from removing the entities when the old
+//     discovery payload is cleared.
+//  2. The discovery payload is published to each of the New topics with the
+//     method To.
+//  3. Each of the Old topics that isn't also a New topic is cleared.
+type Migration struct {
+	From string // Method of the published discovery payload
+	To   string // Method of the discovery payload to publish
+
+	Old []string // Discovery topics to migrate and clear
+	New []string // Discovery topics to publish to
+
+	d *Discovery
+}
+
+// MigrationFrom returns the migration of old, the published discovery payload,
+// to the method of d. If old is nil or uses the same method and child devices
+// as d, MigrationFrom returns nil. Since the node and object ids of a saved
+// discovery payload are not encoded, those of d are used for the topics of old.
+func (d *Discovery) MigrationFrom(old *Discovery) *Migration {
+	if old == nil {
+		return nil
+	}
+
+	from, to := normalizeMethod(old.Method), normalizeMethod(d.Method)
+	if from == to && slices.Equal(slices.Sorted(maps.Keys(old.Children)), slices.Sorted(maps.Keys(d.Children))) {
+		return nil
+	}
+
+	return &Migration{
+		From: from,
+		To:   to,
+		Old:  d.topics(from, old.Components, old.Nodes, old.Children),
+		New:  d.Topics(),
+		d:    d,
+	}
+}
+
+// Migration returns the migration found by [Discovery.Diff], or nil if there
+// is none or it has already been run.
+func (d *Discovery) Migration() *Migration {
+	return d.migration
+}
+
+// Cleared returns the topics that are cleared by the last step of m.
+func (m *Migration) Cleared() []string {
+	var cleared []string
+
+	for _, topic := range m.Old {
+		if !slices.Contains(m.New, topic) {
+			cleared = append(cleared, topic)
+		}
+	}
+
+	return cleared
+}
+
+// String returns the steps of m, one topic per line, without running it.
+func (m *Migration) String() string {
+	var b strings.Builder
+
+	b.WriteString("migrate discovery from " + m.From + " to " + m.To + "\n")
+
+	for _, topic := range m.Old {
+		b.WriteString("  migrate " + topic + "\n")
+	}
+
+	for _, topic := range m.New {
+		b.WriteString("  publish " + topic + "\n")
+	}
+
+	for _, topic := range m.Cleared() {
+		b.WriteString("  clear   " + topic + "\n")
+	}
+
+	return b.String()
+}
 
+// Run runs the steps of m, publishing to c. The discovery payload of the
+// [Discovery] that m was returned from is published in the second step.
+func (m *Migration) Run(ctx context.Context, c mqtt.Client) error {
+	for _, topic := range m.Old {
+		if err := m.d.publishTopic(ctx, c, topic, migratePayload); err != nil {
+			return err
+		}
+	}
+
+	if err := m.d.Publish(ctx, c, false); err != nil {
+		return err
+	}
+
+	for _, topic := range m.Cleared() {
+		if err := m.d.publishTopic(ctx, c, topic, []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishTopic publishes payload to the discovery topic, returning nil if ctx
+// is done first.
+func (d *Discovery) publishTopic(ctx context.Context, c mqtt.Client, topic string, payload []byte) error {
+	t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, payload)
+
+	return d.wait(ctx, t)
+}
+
+func (d *Discovery) removeComponents(ctx context.Context, c mqtt.Client, components ...string) error {
 	for name, cmp := range d.Components {
 		if len(components) > 0 && !slices.Contains(components, name) {
 			continue
@@ -17,15 +156,8 @@ func (d *Discovery) removeComponents(ctx context.Context, c mqtt.Client, compone
 
 		platform := cmp[Platform].(string)
 		topic := d.Topic(d.cfg.Prefix, platform, d.NodeID, name)
-		t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, payload)
 
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-t.Done():
-		}
-
-		if err := t.Error(); err != nil {
+		if err := d.publishTopic(ctx, c, topic, []byte{}); err != nil {
 			return err
 		}
 	}
@@ -38,23 +170,35 @@ func (d *Discovery) removeDevice(ctx context.Context, c mqtt.Client) error {
 }
 
 func (d *Discovery) removeDeviceNode(ctx context.Context, c mqtt.Client, nodeID string) error {
-	topic := d.Topic(d.cfg.Prefix, "device", nodeID, d.ObjectID)
-	t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, []byte{})
+	for _, objectID := range d.deviceObjectIDs() {
+		topic := d.Topic(d.cfg.Prefix, "device", nodeID, objectID)
 
-	select {
-	case <-ctx.Done():
-		return nil
-	case <-t.Done():
+		if err := d.publishTopic(ctx, c, topic, []byte{}); err != nil {
+			return err
+		}
 	}
 
-	return t.Error()
+	return nil
+}
+
+// deviceObjectIDs returns the object ids of the device discovery payloads of
+// d.Device and each child device.
+func (d *Discovery) deviceObjectIDs() []string {
+	ids := []string{d.ObjectID}
+
+	for _, child := range slices.Sorted(maps.Keys(d.Children)) {
+		ids = append(ids, d.ObjectID+"_"+child)
+	}
+
+	return ids
 }
 
 var migratePayload = []byte("{\"migrate_discovery\": true}")
 
 // Migrate publishes `{"migrate_discovery": true}` to each component's
 // discovery topic. This is the first step required for migrating
-// component discoveries to a device discovery.
+// component discoveries to a device discovery. See [Migration] for
+// migrating between any two methods.
 func (d *Discovery) Migrate(ctx context.Context, c mqtt.Client) error {
 	return d.migrate(ctx, c, d.NodeID)
 }
@@ -63,15 +207,8 @@ func (d *Discovery) migrate(ctx context.Context, c mqtt.Client, nodeID string) e
 	for name, cmp := range d.Components {
 		platform := cmp[Platform].(string)
 		topic := d.Topic(d.cfg.Prefix, platform, nodeID, name)
-		t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, migratePayload)
-
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-t.Done():
-		}
 
-		if err := t.Error(); err != nil {
+		if err := d.publishTopic(ctx, c, topic, migratePayload); err != nil {
 			return err
 		}
 	}
@@ -81,20 +218,19 @@ func (d *Discovery) migrate(ctx context.Context, c mqtt.Client, nodeID string) e
 
 // Rollback publishes `{"migrate_discovery": true}` to the device discovery topic.
 // This is the first step required for rolling back a device discovery to individual
-// component discoveries.
+// component discoveries. See [Migration] for migrating between any two methods.
 func (d *Discovery) Rollback(ctx context.Context, c mqtt.Client) error {
 	return d.rollback(ctx, c, d.NodeID)
 }
 
 func (d *Discovery) rollback(ctx context.Context, c mqtt.Client, nodeID string) error {
-	topic := d.Topic(d.cfg.Prefix, "device", nodeID, d.ObjectID)
-	t := c.Publish(topic, d.cfg.QoS, d.cfg.Retained, migratePayload)
+	for _, objectID := range d.deviceObjectIDs() {
+		topic := d.Topic(d.cfg.Prefix, "device", nodeID, objectID)
 
-	select {
-	case <-ctx.Done():
-		return nil
-	case <-t.Done():
+		if err := d.publishTopic(ctx, c, topic, migratePayload); err != nil {
+			return err
+		}
 	}
 
-	return t.Error()
+	return nil
 }
@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/mock"
+)
+
+// testDiscovery returns a discovery with the cpu and memory components, in
+// nodes of their own, published with method.
+func testDiscovery(method string) *Discovery {
+	d := &Discovery{
+		Origin: NewOrigin(),
+		Device: &Device{Name: "Host"},
+		Components: map[string]Component{
+			"mqttop_cpu":    {Platform: Sensor, Name: "CPU usage"},
+			"mqttop_memory": {Platform: Sensor, Name: "Memory used"},
+		},
+		Nodes: map[string][]string{
+			"cpu":    {"mqttop_cpu"},
+			"memory": {"mqttop_memory"},
+		},
+		cfg:      &config.DiscoveryConfig{Prefix: "homeassistant"},
+		NodeID:   "host",
+		ObjectID: "mqttop",
+		Method:   method,
+	}
+
+	return d
+}
+
+// published decodes the messages written by a mock client to b, in order, with
+// the payloads compacted.
+func published(t *testing.T, b *bytes.Buffer) (topics []string, payloads []string) {
+	t.Helper()
+
+	dec := json.NewDecoder(b)
+
+	for {
+		var msg map[string]json.RawMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		for topic, payload := range msg {
+			var p bytes.Buffer
+			if err := json.Compact(&p, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			topics = append(topics, topic)
+			payloads = append(payloads, p.String())
+		}
+	}
+}
+
+func TestMigrationFrom(t *testing.T) {
+	const (
+		device     = "homeassistant/device/host/mqttop/config"
+		cpu        = "homeassistant/sensor/host/mqttop_cpu/config"
+		memory     = "homeassistant/sensor/host/mqttop_memory/config"
+		cpuNode    = "homeassistant/device/host_cpu/mqttop/config"
+		memoryNode = "homeassistant/device/host_memory/mqttop/config"
+	)
+
+	tests := []struct {
+		from, to string
+		old, new []string
+	}{
+		{"components", "device", []string{cpu, memory}, []string{device}},
+		{"", "components", []string{device}, []string{cpu, memory}},
+		{"device", "nodes", []string{device}, []string{cpuNode, memoryNode}},
+		{"metrics", "components", []string{cpuNode, memoryNode}, []string{cpu, memory}},
+		{"nodes", "device", []string{cpuNode, memoryNode}, []string{device}},
+	}
+
+	for _, tt := range tests {
+		d := testDiscovery(tt.to)
+		m := d.MigrationFrom(testDiscovery(tt.from))
+
+		if m == nil {
+			t.Errorf("%s to %s: want migration, got nil", tt.from, tt.to)
+			continue
+		}
+
+		if !slices.Equal(m.Old, tt.old) {
+			t.Errorf("%s to %s: want old topics %q, got %q", tt.from, tt.to, tt.old, m.Old)
+		}
+		if !slices.Equal(m.New, tt.new) {
+			t.Errorf("%s to %s: want new topics %q, got %q", tt.from, tt.to, tt.new, m.New)
+		}
+		if !slices.Equal(m.Cleared(), tt.old) {
+			t.Errorf("%s to %s: want cleared topics %q, got %q", tt.from, tt.to, tt.old, m.Cleared())
+		}
+	}
+
+	for _, tt := range []struct{ from, to string }{
+		{"", "device"},
+		{"nodes", "metrics"},
+		{"components", "components"},
+	} {
+		if m := testDiscovery(tt.to).MigrationFrom(testDiscovery(tt.from)); m != nil {
+			t.Errorf("%s to %s: want no migration, got\n%s", tt.from, tt.to, m)
+		}
+	}
+
+	// Enabling a child device moves its components to a new device payload.
+	d := testDiscovery("device")
+	d.Children = map[string][]string{ChildDirs: {"mqttop_memory"}}
+
+	m := d.MigrationFrom(testDiscovery("device"))
+	if m == nil {
+		t.Fatal("children: want migration, got nil")
+	}
+	if want := []string{"homeassistant/device/host/mqttop/config", "homeassistant/device/host/mqttop_dirs/config"}; !slices.Equal(m.New, want) {
+		t.Errorf("children: want new topics %q, got %q", want, m.New)
+	}
+	if len(m.Cleared()) != 0 {
+		t.Errorf("children: want no cleared topics, got %q", m.Cleared())
+	}
+
+	if m := testDiscovery("device").MigrationFrom(d); m == nil || !slices.Equal(m.Cleared(), []string{"homeassistant/device/host/mqttop_dirs/config"}) {
+		t.Errorf("children: want child device cleared, got\n%s", m)
+	}
+
+	if m := testDiscovery("device").MigrationFrom(nil); m != nil {
+		t.Errorf("nil: want no migration, got\n%s", m)
+	}
+}
+
+func TestMigration_Run(t *testing.T) {
+	var b bytes.Buffer
+
+	c := mock.NewMockClient(mqtt.NewClientOptions(), &b)
+
+	d := testDiscovery("components")
+	if !d.Diff(testDiscovery("device")) {
+		t.Fatal("Diff: want migration")
+	}
+
+	m := d.Migration()
+	if m == nil {
+		t.Fatal("Migration: want migration, got nil")
+	}
+
+	if err := d.Publish(context.Background(), c, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Migration() != nil {
+		t.Error("Migration: want nil after publishing")
+	}
+
+	topics, payloads := published(t, &b)
+
+	if len(topics) != 4 {
+		t.Fatalf("want 4 messages, got %q", topics)
+	}
+
+	device := "homeassistant/device/host/mqttop/config"
+	migrate := `{"migrate_discovery":true}`
+
+	if topics[0] != device || payloads[0] != migrate {
+		t.Errorf("first: want %s on %s, got %s on %s", migrate, device, payloads[0], topics[0])
+	}
+
+	if got := topics[1:3]; !slices.Equal(slices.Sorted(slices.Values(got)), m.New) {
+		t.Errorf("then: want %q, got %q", m.New, got)
+	}
+
+	for i, p := range payloads[1:3] {
+		if p == `""` || p == migrate {
+			t.Errorf("%s: want component payload, got %q", topics[i+1], p)
+		}
+	}
+
+	if topics[3] != device || payloads[3] != `""` {
+		t.Errorf("last: want %s cleared, got %q on %s", device, payloads[3], topics[3])
+	}
+}
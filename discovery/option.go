@@ -26,6 +26,7 @@ const (
 	HWVersion        Option = "hw"
 	SuggestedArea    Option = "sa"
 	SerialNumber     Option = "sn"
+	ViaDevice        Option = "via_device"
 )
 
 // Options for components
@@ -51,14 +52,17 @@ const (
 	MaxTemp                   Option = "max_temp"
 	Min                       Option = "min"
 	MinTemp                   Option = "min_temp"
+	Mode                      Option = "mode"
 	ObjectID                  Option = "obj_id"
 	Options                   Option = "ops"
 	Platform                  Option = "p"
 	Payload                   Option = "pl"
 	PayloadAvailable          Option = "pl_ avail"
 	PayloadNotAvailable       Option = "pl_not_avail"
+	PayloadPress              Option = "pl_prs"
 	Retain                    Option = "ret"
 	StateClass                Option = "stat_cla"
+	Step                      Option = "step"
 	StateTopic                Option = "stat_t"
 	StateTemplate             Option = "stat_tpl"
 	StateValueTemplate        Option = "stat_val_tpl"
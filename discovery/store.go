@@ -0,0 +1,180 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// StoreVersion is the version of the state written by [Store.Save]. State
+// written by older versions is upgraded when loaded, while state written by
+// newer versions is refused.
+const StoreVersion = 1
+
+const (
+	storeFile = "discovery.json"
+	lockFile  = "discovery.lock"
+)
+
+var (
+	// ErrLocked is returned by [OpenStore] if the store is already open in
+	// another process.
+	ErrLocked = errors.New("discovery store is locked by another process")
+	// ErrStoreVersion is returned by [Store.Load] if the state was written by a
+	// newer version.
+	ErrStoreVersion = errors.New("unsupported discovery store version")
+)
+
+// Store is the published discovery payload persisted in a directory across
+// restarts, so that it may be diffed and migrated by the next run. The store
+// is locked while open, so that it is only used by a single process.
+type Store struct {
+	dir  string
+	lock *os.File
+	mu   sync.Mutex
+}
+
+// storeState is the encoding of the state of a [Store]. Version 0, written
+// before the store existed, is the bare encoding of the discovery payload.
+type storeState struct {
+	Version   int        `json:"version"`
+	Discovery *Discovery `json:"discovery"`
+}
+
+// OpenStore opens the store in dir, creating dir if needed, and locks it. If
+// the store is already open in another process, [ErrLocked] is returned.
+//
+// If the store doesn't have any state yet, the state is moved from the first
+// of the legacy paths that exists, such as the discovery.json written next to
+// the config by older versions.
+func OpenStore(dir string, legacy ...string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, lockFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		f.Close()
+
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, dir)
+		}
+
+		return nil, err
+	}
+
+	s := &Store{dir: dir, lock: f}
+
+	if _, err = os.Stat(s.path()); errors.Is(err, os.ErrNotExist) {
+		s.moveLegacy(legacy)
+	}
+
+	return s, nil
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.dir, storeFile)
+}
+
+// moveLegacy moves the state from the first of the legacy paths that exists
+// into the store.
+func (s *Store) moveLegacy(legacy []string) {
+	for _, path := range legacy {
+		if path == s.path() {
+			continue
+		}
+
+		d, err := Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			log.WarnError("Unable to move legacy discovery", err, "path", path)
+			return
+		}
+
+		if err = s.Save(d); err != nil {
+			log.WarnError("Unable to move legacy discovery", err, "path", path)
+			return
+		}
+
+		log.Info("Moved legacy discovery", "from", path, "to", s.path())
+
+		if err = os.Remove(path); err != nil {
+			log.Debug("Unable to remove legacy discovery", "path", path, "err", err)
+		}
+
+		return
+	}
+}
+
+// Load returns the discovery payload saved in the store. If nothing has been
+// saved, the returned error wraps [os.ErrNotExist].
+func (s *Store) Load() (*Discovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return nil, err
+	}
+
+	var state struct {
+		Version   *int            `json:"version"`
+		Discovery json.RawMessage `json:"discovery"`
+	}
+
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	if state.Version == nil {
+		// Version 0 is the bare discovery payload
+		state.Discovery = data
+	} else if *state.Version > StoreVersion {
+		return nil, fmt.Errorf("%w %d", ErrStoreVersion, *state.Version)
+	}
+
+	d := &Discovery{}
+	if err = json.Unmarshal(state.Discovery, d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Save saves d in the store, replacing what was saved before.
+func (s *Store) Save(d *Discovery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(storeState{Version: StoreVersion, Discovery: d}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so that the state is never left
+	// partially written.
+	tmp := s.path() + ".tmp"
+
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path())
+}
+
+// Close unlocks the store. It must not be used after.
+func (s *Store) Close() error {
+	return s.lock.Close()
+}
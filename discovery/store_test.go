@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy := filepath.Join(t.TempDir(), "discovery.json")
+	if err := os.WriteFile(legacy, []byte(`{"cmps": {"mqttop_cpu": {"p": "sensor"}}, "_method": "components"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenStore(dir, filepath.Join(dir, "missing.json"), legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenStore(dir); !errors.Is(err, ErrLocked) {
+		t.Errorf("OpenStore: want %v, got %v", ErrLocked, err)
+	}
+
+	if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Legacy: want removed, got %v", err)
+	}
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.Components["mqttop_cpu"]; !ok || d.Method != "components" {
+		t.Errorf("Load: want legacy discovery, got %+v", d)
+	}
+
+	d.Method = "device"
+	if err := s.Save(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err = s.Load(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := d.Components["mqttop_cpu"]; !ok || d.Method != "device" {
+		t.Errorf("Load: want saved discovery, got %+v", d)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, storeFile), []byte(`{"version": 2, "discovery": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore: want unlocked after Close, got %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Load(); !errors.Is(err, ErrStoreVersion) {
+		t.Errorf("Load: want %v, got %v", ErrStoreVersion, err)
+	}
+}
+
+func TestStore_Empty(t *testing.T) {
+	s, err := OpenStore(filepath.Join(t.TempDir(), "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load: want %v, got %v", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,181 @@
+// Package encrypt implements the end-to-end encryption of metric payloads, for
+// publishing through brokers that aren't trusted. Each payload is encrypted
+// with age (https://age-encryption.org/v1) for the X25519 recipients of the
+// config, so any age implementation may decrypt it.
+//
+// Payloads are published as JSON, so that they may still be signed, with the
+// age file in its binary format and base64 encoded:
+//
+//	{"enc": "age", "ciphertext": "..."}
+//
+// The age plaintext is the topic the payload is published to, a NUL byte and
+// then the payload, which binds the ciphertext to the topic so that it can't
+// be replayed to another topic, since MQTT topics never contain NUL. Only the
+// holder of the identity of a recipient may decrypt the payload with
+// [Decrypt], given the topic.
+//
+// Keys are age keys, the public key of a recipient being "age1..." and its
+// private key "AGE-SECRET-KEY-1...", such as generated by age-keygen.
+package encrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Algorithm is the "enc" of the envelope of encrypted payloads.
+const Algorithm = "age"
+
+var (
+	// ErrNotRecipient is returned by [Decrypt] if the payload wasn't
+	// encrypted for the key.
+	ErrNotRecipient = errors.New("not a recipient of the payload")
+	// ErrInvalidKey is returned when parsing an invalid key.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrTopic is returned by [Decrypt] if the payload was encrypted for
+	// another topic.
+	ErrTopic = errors.New("payload encrypted for another topic")
+)
+
+// envelope is the JSON object of an encrypted payload.
+type envelope struct {
+	Enc string `json:"enc"`
+	// Ciphertext is the binary age file.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// GenerateKey returns a new random private key.
+func GenerateKey() (*age.X25519Identity, error) {
+	return age.GenerateX25519Identity()
+}
+
+// FormatPublicKey returns the encoding of k, as parsed by [ParsePublicKey].
+func FormatPublicKey(k *age.X25519Recipient) string {
+	return k.String()
+}
+
+// FormatPrivateKey returns the encoding of k, as parsed by [ParsePrivateKey].
+func FormatPrivateKey(k *age.X25519Identity) string {
+	return k.String()
+}
+
+// ParsePublicKey parses the public key s, as formatted by [FormatPublicKey].
+func ParsePublicKey(s string) (*age.X25519Recipient, error) {
+	k, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return k, nil
+}
+
+// ParsePrivateKey parses the private key s, as formatted by
+// [FormatPrivateKey].
+func ParsePrivateKey(s string) (*age.X25519Identity, error) {
+	k, err := age.ParseX25519Identity(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return k, nil
+}
+
+// Encrypter encrypts payloads for a set of recipients.
+type Encrypter struct {
+	recipients []age.Recipient
+}
+
+// NewEncrypter returns an [Encrypter] for the public keys of the recipients,
+// as formatted by [FormatPublicKey].
+func NewEncrypter(recipients ...string) (*Encrypter, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	e := &Encrypter{recipients: make([]age.Recipient, len(recipients))}
+
+	for i, s := range recipients {
+		k, err := ParsePublicKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i, err)
+		}
+
+		e.recipients[i] = k
+	}
+
+	return e, nil
+}
+
+// Encrypt returns the envelope of plaintext encrypted for the recipients,
+// bound to topic.
+func (e *Encrypter) Encrypt(topic string, plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := age.Encrypt(&buf, e.recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	io.WriteString(w, topic)
+	w.Write([]byte{0})
+	w.Write(plaintext)
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&envelope{Enc: Algorithm, Ciphertext: buf.Bytes()})
+}
+
+// Decrypt returns the plaintext of the envelope data encrypted for k and
+// published to topic. An error wrapping [ErrNotRecipient] is returned if the
+// payload wasn't encrypted for k, and [ErrTopic] if it was encrypted for
+// another topic.
+func Decrypt(k *age.X25519Identity, topic string, data []byte) ([]byte, error) {
+	var env envelope
+
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+
+	if env.Enc != Algorithm {
+		return nil, fmt.Errorf("unsupported encryption %q", env.Enc)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(env.Ciphertext), k)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("%w: %w", ErrNotRecipient, err)
+		}
+
+		return nil, err
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t, payload, ok := bytes.Cut(plaintext, []byte{0})
+	if !ok || string(t) != topic {
+		return nil, ErrTopic
+	}
+
+	return payload, nil
+}
+
+// IsEncrypted reports whether data is the envelope of an encrypted payload.
+func IsEncrypted(data []byte) bool {
+	var env struct {
+		Enc string `json:"enc"`
+	}
+
+	return json.Unmarshal(data, &env) == nil && env.Enc == Algorithm
+}
@@ -0,0 +1,171 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestEncrypt(t *testing.T) {
+	alice, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bob, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eve, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEncrypter(FormatPublicKey(alice.Recipient()), FormatPublicKey(bob.Recipient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte(`{"usage":12.5}`)
+
+	data, err := e.Encrypt("mqttop/metric/cpu", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, plaintext) {
+		t.Error("Encrypt: ciphertext contains plaintext")
+	}
+
+	if !IsEncrypted(data) || IsEncrypted(plaintext) {
+		t.Error("IsEncrypted: want true for envelope only")
+	}
+
+	for _, k := range []struct {
+		name string
+		ok   bool
+		key  string
+	}{
+		{"alice", true, FormatPrivateKey(alice)},
+		{"bob", true, FormatPrivateKey(bob)},
+		{"eve", false, FormatPrivateKey(eve)},
+	} {
+		priv, err := ParsePrivateKey(k.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Decrypt(priv, "mqttop/metric/cpu", data)
+		if !k.ok {
+			if !errors.Is(err, ErrNotRecipient) {
+				t.Errorf("%s: want %v, got %v", k.name, ErrNotRecipient, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", k.name, err)
+		} else if !bytes.Equal(got, plaintext) {
+			t.Errorf("%s: want %s, got %s", k.name, plaintext, got)
+		}
+	}
+
+	// Decrypting for another topic fails
+	if _, err := Decrypt(alice, "mqttop/metric/memory", data); !errors.Is(err, ErrTopic) {
+		t.Errorf("Topic: want %v, got %v", ErrTopic, err)
+	}
+
+	// Tampering with the ciphertext fails to decrypt
+	var env envelope
+
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+
+	env.Ciphertext[len(env.Ciphertext)-1] ^= 1
+
+	tampered, _ := json.Marshal(&env)
+
+	if _, err := Decrypt(alice, "mqttop/metric/cpu", tampered); err == nil {
+		t.Error("Tampered: want error")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	k, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := ParsePublicKey(FormatPublicKey(k.Recipient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pub.String() != k.Recipient().String() {
+		t.Error("ParsePublicKey: want round trip")
+	}
+
+	if _, err := ParsePublicKey(FormatPrivateKey(k)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Private as public: want %v, got %v", ErrInvalidKey, err)
+	}
+
+	if _, err := ParsePublicKey("age1short"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Short: want %v, got %v", ErrInvalidKey, err)
+	}
+
+	if _, err := NewEncrypter(); err == nil {
+		t.Error("No recipients: want error")
+	}
+}
+
+// The known answer of the envelope format, which must keep decrypting.
+const (
+	vectorKey      = "AGE-SECRET-KEY-132C9D7FGJCR6USJ2QZYX9S0YLW9Y6X7FDJHYE2YS3S7XNYDRJUKQ9C5JV9"
+	vectorTopic    = "mqttop/metric/cpu"
+	vectorPayload  = `{"usage":12.5}`
+	vectorEnvelope = `{"enc":"age","ciphertext":"YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBtUzRsNytBVHlMVmJOL21yaFd4M05pMUNwT2wwSVlJNDQza1NXSi9LaUdVCjhwR1R0Y3NIdUZlbXFFbTlxRGN3SHB0cW1aMFZEUXUwamVIRU9kREFoSWcKLS0tIHExUnBKTTU0eDR5NStrQVpTanZ5WkNnRGdsU0JJQnY2bTJxVXBRVjloNDAKohaqCRiLHal080ZcI0P9SZnylDhhf6mkz5NLfDo6xDbE12o+3/WjbYRIjK5lEXjPyEY99LrXJfLOnV9o+5IlQw=="}`
+)
+
+func TestDecrypt_Vector(t *testing.T) {
+	k, err := ParsePrivateKey(vectorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decrypt(k, vectorTopic, []byte(vectorEnvelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != vectorPayload {
+		t.Errorf("want %s, got %s", vectorPayload, got)
+	}
+
+	// The ciphertext is a plain age file of the topic, NUL and the payload
+	var env envelope
+
+	if err := json.Unmarshal([]byte(vectorEnvelope), &env); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(env.Ciphertext), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := vectorTopic + "\x00" + vectorPayload; string(plaintext) != want {
+		t.Errorf("Plaintext: want %q, got %q", want, plaintext)
+	}
+}
@@ -5,11 +5,9 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"math"
 	"slices"
 	"strconv"
-
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 func lower(c byte) byte {
@@ -18,7 +16,10 @@ func lower(c byte) byte {
 
 // Btou is a naive base 10 implementation of [strconv.ParseUint] that assumes
 // all the bytes of b are numerical characters, and ignores any that aren't.
+// If the value overflows a uint64, [math.MaxUint64] is returned.
 func Btou(b []byte) uint64 {
+	const cutoff = math.MaxUint64/10 + 1
+
 	var u uint64
 
 	for _, c := range b {
@@ -27,13 +28,22 @@ func Btou(b []byte) uint64 {
 			continue
 		}
 
-		u = 10*u + uint64(c)
+		if u >= cutoff {
+			return math.MaxUint64
+		}
+
+		u *= 10
+		if u+uint64(c) < u {
+			return math.MaxUint64
+		}
+
+		u += uint64(c)
 	}
 
 	return u
 }
 
-// Btou is a naive base 10 implementation of [strconv.ParseInt] that assumes
+// Btoi is a naive base 10 implementation of [strconv.ParseInt] that assumes
 // all the bytes of b are numerical characters, and ignores any that aren't.
 func Btoi(b []byte) int64 {
 	var neg bool
@@ -56,14 +66,39 @@ loop:
 	u := Btou(b)
 
 	if neg {
-		u = ^u + 1
+		if u > 1<<63 {
+			return math.MinInt64
+		}
+
+		return int64(-u)
+	}
+
+	if u > math.MaxInt64 {
+		return math.MaxInt64
 	}
 
 	return int64(u)
 }
 
-// Btou is a naive base 16 implementation of [strconv.ParseUint] that assumes
+// Btoint is the same as [Btoi] but returns an int. If the value overflows an
+// int, which may only be 32 bits (see [strconv.IntSize]), then [math.MaxInt] or
+// [math.MinInt] is returned.
+func Btoint(b []byte) int {
+	i := Btoi(b)
+
+	switch {
+	case i > math.MaxInt:
+		return math.MaxInt
+	case i < math.MinInt:
+		return math.MinInt
+	}
+
+	return int(i)
+}
+
+// Btox is a naive base 16 implementation of [strconv.ParseUint] that assumes
 // all the bytes of b are numerical characters, and ignores any that aren't.
+// If the value overflows a uint64, [math.MaxUint64] is returned.
 func Btox(b []byte) uint64 {
 loop:
 	for i, c := range b {
@@ -89,11 +124,15 @@ loop:
 		case '0' <= c && c <= '9':
 			c = c - '0'
 		case 'a' <= lower(c) && lower(c) <= 'f':
-			c = c - 'a' + 10
+			c = lower(c) - 'a' + 10
 		default:
 			continue
 		}
 
+		if u > math.MaxUint64>>4 {
+			return math.MaxUint64
+		}
+
 		u = (u << 4) + uint64(c)
 	}
 
@@ -115,12 +154,12 @@ func Field(b []byte) (key, val []byte) {
 	return
 }
 
-// Column splits b by the first space and returns the subslice
+// Column splits b by the first space or tab and returns the subslice
 // of b before the space and the remainder of b after the space
 // with spaces trimmed.
 func Column(b []byte) (col, rest []byte) {
 	b = bytes.TrimSpace(b)
-	i := bytes.IndexByte(b, ' ')
+	i := indexBlank(b)
 
 	if i < 0 {
 		return b, b[:0]
@@ -132,6 +171,18 @@ func Column(b []byte) (col, rest []byte) {
 	return
 }
 
+// indexBlank returns the index of the first space or tab in b, or -1 if
+// there is none.
+func indexBlank(b []byte) int {
+	for i, c := range b {
+		if c == ' ' || c == '\t' {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // ColumnString is the same as [Column] but returns the subslice before the
 // space as a string
 func ColumnString(b []byte) (col string, rest []byte) {
@@ -188,8 +239,18 @@ func ToLower(b []byte) []byte {
 // returns the extended buffer. The value appended will be padded
 // with 0's to reach the desired decimal places pow.
 func AppendDecimal(b []byte, v int64, pow int) []byte {
+	if v < 0 {
+		// The magnitude of math.MinInt64 doesn't fit in an int64, but
+		// it does in a uint64.
+		return appendDecimal(append(b, '-'), uint64(-v), pow)
+	}
+
+	return appendDecimal(b, uint64(v), pow)
+}
+
+func appendDecimal(b []byte, v uint64, pow int) []byte {
 	n := len(b)
-	b = strconv.AppendInt(b, v, 10)
+	b = strconv.AppendUint(b, v, 10)
 
 	if pow == 0 {
 		return b
@@ -226,6 +287,61 @@ func AppendDecimal(b []byte, v int64, pow int) []byte {
 	return b
 }
 
+// AppendDecimalTrim is like [AppendDecimal] but trims the insignificant
+// trailing 0's after the decimal point, and the decimal point itself if
+// there are no places left, such as "2.5" instead of "2.500" and "0"
+// instead of "0.000".
+func AppendDecimalTrim(b []byte, v int64, pow int) []byte {
+	n := len(b)
+	b = AppendDecimal(b, v, pow)
+
+	if pow == 0 {
+		return b
+	}
+
+	i := len(b)
+	for i > n && b[i-1] == '0' {
+		i--
+	}
+
+	if b[i-1] == '.' {
+		i--
+	}
+
+	return b[:i]
+}
+
+// RoundDecimal rounds the fixed-point number v, with pow places after the
+// decimal point, to prec places, rounding half away from zero. The result
+// still has pow places after the decimal point. If prec is negative or not
+// less than pow, v is returned unchanged.
+func RoundDecimal(v int64, pow, prec int) int64 {
+	if prec < 0 || prec >= pow {
+		return v
+	}
+
+	unit := int64(1)
+	for ; prec < pow; prec++ {
+		unit *= 10
+	}
+
+	r := v % unit
+	v -= r
+
+	switch {
+	case r >= unit-r:
+		if v <= math.MaxInt64-unit {
+			v += unit
+		}
+	case -r >= unit+r:
+		if v >= math.MinInt64+unit {
+			v -= unit
+		}
+	}
+
+	return v
+}
+
 // WriteDecimal writes the output of [AppendDecimal] to w.
 func WriteDecimal(w io.Writer, v int64, pow int) (n int, err error) {
 	var b []byte
@@ -242,6 +358,72 @@ func WriteDecimal(w io.Writer, v int64, pow int) (n int, err error) {
 	return w.Write(b)
 }
 
+// ParseDecimal parses the decimal number in b as a fixed-point number with
+// pow places after the decimal point. It is the inverse of [AppendDecimal].
+// Any places after pow are truncated. If the value overflows an int64, the
+// returned error has Err set to [strconv.ErrRange].
+func ParseDecimal(b []byte, pow int) (int64, error) {
+	const cutoff = (1 << 63) / 10
+
+	var (
+		v    uint64
+		neg  bool
+		frac = -1
+		s    = b
+	)
+
+	if len(s) > 0 && s[0] == '-' {
+		neg = true
+		s = s[1:]
+	}
+
+	if len(s) == 0 {
+		return 0, &strconv.NumError{Func: "ParseDecimal", Num: string(b), Err: strconv.ErrSyntax}
+	}
+
+	for _, c := range s {
+		switch {
+		case c == '.' && frac < 0:
+			frac = 0
+
+			continue
+		case c < '0' || c > '9':
+			return 0, &strconv.NumError{Func: "ParseDecimal", Num: string(b), Err: strconv.ErrSyntax}
+		case frac >= pow:
+			continue
+		case frac >= 0:
+			frac++
+		}
+
+		if v > cutoff {
+			return 0, &strconv.NumError{Func: "ParseDecimal", Num: string(b), Err: strconv.ErrRange}
+		}
+
+		v = 10*v + uint64(c-'0')
+	}
+
+	if frac < 0 {
+		frac = 0
+	}
+
+	for ; frac < pow; frac++ {
+		if v > cutoff {
+			return 0, &strconv.NumError{Func: "ParseDecimal", Num: string(b), Err: strconv.ErrRange}
+		}
+
+		v *= 10
+	}
+
+	switch {
+	case neg && v <= 1<<63:
+		return int64(-v), nil
+	case !neg && v <= math.MaxInt64:
+		return int64(v), nil
+	}
+
+	return 0, &strconv.NumError{Func: "ParseDecimal", Num: string(b), Err: strconv.ErrRange}
+}
+
 // TrimByte returns the subslice of b with all leading and trailing
 // occurrences of c sliced off.
 func TrimByte(b []byte, c byte) []byte {
@@ -265,13 +447,3 @@ func TrimByte(b []byte, c byte) []byte {
 
 	return b[start:end]
 }
-
-// ToTitle returns the title case representation of b.
-func ToTitle(b []byte) []byte {
-	return cases.Title(language.English).Bytes(b)
-}
-
-// ToTitleString returns the title case representation of s.
-func ToTitleString(s string) string {
-	return cases.Title(language.English).String(s)
-}
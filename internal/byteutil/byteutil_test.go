@@ -2,6 +2,7 @@ package byteutil
 
 import (
 	"bytes"
+	"math"
 	"testing"
 	"unsafe"
 )
@@ -17,6 +18,9 @@ func TestBtou(t *testing.T) {
 		{[]byte{'-', '1', '2', '3'}, 123},
 		{[]byte{'-', ' ', '1', '2', '3'}, 123},
 		{[]byte{'-', 'f', '1', 'o', '2', 'o', '3'}, 123},
+		{[]byte("18446744073709551615"), math.MaxUint64},
+		{[]byte("18446744073709551616"), math.MaxUint64},
+		{[]byte("99999999999999999999"), math.MaxUint64},
 	}
 	for _, tt := range tests {
 		if u := Btou(tt.b); u != tt.u {
@@ -36,6 +40,10 @@ func TestBtoi(t *testing.T) {
 		{[]byte{'-', '1', '2', '3'}, -123},
 		{[]byte{'-', ' ', '1', '2', '3'}, -123},
 		{[]byte{'-', 'f', '1', 'o', '2', 'o', '3'}, -123},
+		{[]byte("9223372036854775807"), math.MaxInt64},
+		{[]byte("9223372036854775808"), math.MaxInt64},
+		{[]byte("-9223372036854775808"), math.MinInt64},
+		{[]byte("-9223372036854775809"), math.MinInt64},
 	}
 	for _, tt := range tests {
 		if i := Btoi(tt.b); i != tt.i {
@@ -44,6 +52,26 @@ func TestBtoi(t *testing.T) {
 	}
 }
 
+func TestBtoint(t *testing.T) {
+	var tests = []struct {
+		b []byte
+		i int
+	}{
+		{[]byte("123"), 123},
+		{[]byte("-123"), -123},
+		{[]byte("2147483647"), math.MaxInt32},
+		{[]byte("-2147483648"), math.MinInt32},
+		{[]byte("2147483648"), aboveInt32},
+		{[]byte("-2147483649"), belowInt32},
+		{[]byte("9223372036854775808"), math.MaxInt},
+	}
+	for _, tt := range tests {
+		if i := Btoint(tt.b); i != tt.i {
+			t.Errorf("%s: Wanted %v, got %v", tt.b, tt.i, i)
+		}
+	}
+}
+
 func TestBtox(t *testing.T) {
 	var tests = []struct {
 		b []byte
@@ -58,6 +86,10 @@ func TestBtox(t *testing.T) {
 		{[]byte{'-', '0', 'x', '1', '2', '3'}, 291},
 		{[]byte{'0', 'x', '-', ' ', '1', '2', '3'}, 291},
 		{[]byte{'-', 'i', '0', 'x', '1', 'o', '2', 'o', '3'}, 291},
+		{[]byte("0xffffffffffffffff"), math.MaxUint64},
+		{[]byte("0x10000000000000000"), math.MaxUint64},
+		{[]byte("0xFF"), 255},
+		{[]byte("0XaB"), 171},
 	}
 	for _, tt := range tests {
 		if u := Btox(tt.b); u != tt.u {
@@ -95,6 +127,8 @@ func TestColumn(t *testing.T) {
 		{[]byte("foo bar baz"), "foo", "bar baz"},
 		{[]byte("  foo    bar       baz   "), "foo", "bar       baz"},
 		{[]byte("foo"), "foo", ""},
+		{[]byte("foo\tbar baz"), "foo", "bar baz"},
+		{[]byte("\tfoo \t bar"), "foo", "bar"},
 	}
 	for _, tt := range tests {
 		col, rest := Column(tt.b)
@@ -145,6 +179,12 @@ func TestToTitle(t *testing.T) {
 	}{
 		{[]byte("hello, world!"), []byte("Hello, World!")},
 		{[]byte("123 abc"), []byte("123 Abc")},
+		{[]byte("IT'S 3RD-PARTY"), []byte("It's 3rd-Party")},
+		{[]byte("o’neil_foo.bar"), []byte("O’neil_Foo.Bar")},
+		{[]byte("ÉCOLE ȧbc"), []byte("École Ȧbc")},
+		{[]byte("ǆemal"), []byte("ǅemal")},
+		{[]byte("bad\xffUTF8"), []byte("Bad\xffutf8")},
+		{[]byte(""), []byte("")},
 	}
 	for _, tt := range tests {
 		title := ToTitle(tt.b)
@@ -153,3 +193,102 @@ func TestToTitle(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendDecimal(t *testing.T) {
+	var tests = []struct {
+		v    int64
+		pow  int
+		want string
+	}{
+		{12345, 3, "12.345"},
+		{97, 3, "0.097"},
+		{0, 3, "0.000"},
+		{-1500, 3, "-1.500"},
+		{-500, 3, "-0.500"},
+		{-5, 3, "-0.005"},
+		{-42, 0, "-42"},
+		{math.MinInt64, 3, "-9223372036854775.808"},
+	}
+	for _, tt := range tests {
+		if got := string(AppendDecimal(nil, tt.v, tt.pow)); got != tt.want {
+			t.Errorf("%d: Wanted %s, got %s", tt.v, tt.want, got)
+		}
+	}
+}
+
+func TestAppendDecimalTrim(t *testing.T) {
+	var tests = []struct {
+		v    int64
+		pow  int
+		want string
+	}{
+		{12345, 3, "12.345"},
+		{12340, 3, "12.34"},
+		{0, 3, "0"},
+		{-1500, 3, "-1.5"},
+		{3124000, 6, "3.124"},
+		{800000, 6, "0.8"},
+		{100, 0, "100"},
+		{-10000, 3, "-10"},
+	}
+	for _, tt := range tests {
+		if got := string(AppendDecimalTrim([]byte("x"), tt.v, tt.pow)); got != "x"+tt.want {
+			t.Errorf("%d: Wanted x%s, got %s", tt.v, tt.want, got)
+		}
+	}
+}
+
+func TestRoundDecimal(t *testing.T) {
+	var tests = []struct {
+		v    int64
+		pow  int
+		prec int
+		want int64
+	}{
+		{3124402, 6, 3, 3124000},
+		{3124500, 6, 3, 3125000},
+		{-3124500, 6, 3, -3125000},
+		{-3124499, 6, 3, -3124000},
+		{81499, 3, 0, 81000},
+		{81500, 3, 0, 82000},
+		{12345, 3, 3, 12345},
+		{12345, 3, -1, 12345},
+		{math.MaxInt64, 3, 0, math.MaxInt64 - 807},
+		{math.MinInt64, 3, 0, math.MinInt64 + 808},
+	}
+	for _, tt := range tests {
+		if got := RoundDecimal(tt.v, tt.pow, tt.prec); got != tt.want {
+			t.Errorf("%d to %d places: Wanted %d, got %d", tt.v, tt.prec, tt.want, got)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	var tests = []struct {
+		b   []byte
+		pow int
+		v   int64
+	}{
+		{[]byte("12.345"), 3, 12345},
+		{[]byte("0.097"), 3, 97},
+		{[]byte("-1.5"), 3, -1500},
+		{[]byte("3.124402"), 6, 3124402},
+		{[]byte("81"), 3, 81000},
+		{[]byte("1.23456"), 2, 123},
+		{[]byte("9223372036854775.807"), 3, math.MaxInt64},
+		{[]byte("-9223372036854775.808"), 3, math.MinInt64},
+	}
+	for _, tt := range tests {
+		v, err := ParseDecimal(tt.b, tt.pow)
+		if err != nil {
+			t.Errorf("%s: Error %v", tt.b, err)
+		} else if v != tt.v {
+			t.Errorf("%s: Wanted %v, got %v", tt.b, tt.v, v)
+		}
+	}
+	for _, b := range []string{"", "-", "1.2.3", "abc", "9223372036854775.808", "9223372036854775808", "99999999999999999999"} {
+		if _, err := ParseDecimal([]byte(b), 3); err == nil {
+			t.Errorf("%q: Wanted error", b)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package byteutil
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// isDigits reports whether b is a non-empty run of characters in digits.
+func isDigits(b []byte, digits string) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, c := range b {
+		if !bytes.ContainsRune([]byte(digits), rune(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func FuzzBtou(f *testing.F) {
+	for _, s := range []string{"0", "123", " 123 kB", "-5", "1.5", "18446744073709551615", "18446744073709551616", "99999999999999999999"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		u := Btou(b)
+
+		// Non-numerical characters are ignored
+		digits := bytes.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+
+			return r
+		}, b)
+
+		if got := Btou(digits); got != u {
+			t.Errorf("%q: want %d, got %d of digits %q", b, u, got, digits)
+		}
+
+		if !isDigits(b, "0123456789") {
+			return
+		}
+
+		want, err := strconv.ParseUint(string(b), 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			t.Fatal(err)
+		}
+
+		if u != want {
+			t.Errorf("%q: want %d, got %d", b, want, u)
+		}
+	})
+}
+
+func FuzzBtoi(f *testing.F) {
+	for _, s := range []string{"0", "-0", "123", "-123", "+123", "9223372036854775807", "9223372036854775808", "-9223372036854775808", "-9223372036854775809"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		i := Btoi(b)
+
+		if n := Btoint(b); int64(n) != i && n != math.MaxInt && n != math.MinInt {
+			t.Errorf("%q: Btoint want %d, got %d", b, i, n)
+		}
+
+		want, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return
+		}
+
+		if i != want {
+			t.Errorf("%q: want %d, got %d", b, want, i)
+		}
+	})
+}
+
+func FuzzBtox(f *testing.F) {
+	for _, s := range []string{"0", "ff", "FF", "0x1f", "0X1F", "deadBEEF", "ffffffffffffffff", "10000000000000000"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		u := Btox(b)
+
+		if !isDigits(b, "0123456789abcdefABCDEF") {
+			return
+		}
+
+		want, err := strconv.ParseUint(string(b), 16, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			t.Fatal(err)
+		}
+
+		if u != want {
+			t.Errorf("%q: want %d, got %d", b, want, u)
+		}
+
+		prefixed := append([]byte("0x"), b...)
+		if got := Btox(prefixed); got != want {
+			t.Errorf("%q: want %d, got %d", prefixed, want, got)
+		}
+	})
+}
+
+func FuzzField(f *testing.F) {
+	for _, s := range []string{"key: val", "  key  :val", "key", ":", "key: val: val2", "MemTotal:       16384 kB"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		key, val := Field(b)
+
+		i := bytes.IndexByte(b, ':')
+		if i < 0 {
+			if !bytes.Equal(key, b) || val != nil {
+				t.Errorf("%q: want key=%q, val=nil, got key=%q, val=%q", b, b, key, val)
+			}
+
+			return
+		}
+
+		if bytes.IndexByte(key, ':') >= 0 {
+			t.Errorf("%q: key %q contains ':'", b, key)
+		}
+		if !bytes.Equal(val, b[i+1:]) {
+			t.Errorf("%q: want val=%q, got %q", b, b[i+1:], val)
+		}
+	})
+}
+
+func FuzzColumn(f *testing.F) {
+	for _, s := range []string{"foo bar baz", "  foo    bar  ", "foo", "", " ", "foo\tbar", "cpu0 1 2 3 4"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		col, rest := Column(b)
+
+		if indexBlank(col) >= 0 {
+			t.Errorf("%q: col %q contains a space", b, col)
+		}
+		if len(col) == 0 && len(bytes.TrimSpace(b)) > 0 {
+			t.Errorf("%q: want non-empty col", b)
+		}
+		if len(col)+len(rest) > len(b) {
+			t.Errorf("%q: col %q and rest %q longer than b", b, col, rest)
+		}
+		if !bytes.Equal(rest, bytes.TrimSpace(rest)) {
+			t.Errorf("%q: rest %q not trimmed", b, rest)
+		}
+
+		var c1, c2 []byte
+		if n, _ := Columns(b, &c1, &c2); n < 1 || n > 2 || !bytes.Equal(c1, col) {
+			t.Errorf("%q: Columns want %q, got %d columns %q", b, col, n, c1)
+		}
+	})
+}
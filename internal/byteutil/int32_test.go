@@ -0,0 +1,11 @@
+//go:build 386 || arm || mips || mipsle
+
+package byteutil
+
+import "math"
+
+// Expected values of [Btoint] for values just outside the range of int32.
+const (
+	aboveInt32 = math.MaxInt
+	belowInt32 = math.MinInt
+)
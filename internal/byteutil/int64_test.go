@@ -0,0 +1,9 @@
+//go:build !(386 || arm || mips || mipsle)
+
+package byteutil
+
+// Expected values of [Btoint] for values just outside the range of int32.
+const (
+	aboveInt32 = 1 << 31
+	belowInt32 = -1<<31 - 1
+)
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"math/bits"
 	"strconv"
 )
@@ -27,6 +28,10 @@ const UnknownSize ByteSize = -1
 // SizeOf returns the largest human-readable ByteSize that can be used to
 // represent v.
 func SizeOf(v uint64) ByteSize {
+	if v == 0 {
+		return Bytes
+	}
+
 	size := ByteSize((bits.Len64(v-1)-1)/10) * 10
 	if size > PiB {
 		size = PiB
@@ -95,11 +100,74 @@ func (s ByteSize) MarshalJSON() ([]byte, error) {
 	return nil, fmt.Errorf("unknown ByteSize %d", s)
 }
 
+// ScaleSize returns v bytes scaled to size as a fixed-point number with 3 decimal
+// places. If size is negative, [SizeOf](v) is used. If the result overflows a
+// uint64, [math.MaxUint64] is returned.
+func ScaleSize(v uint64, size ByteSize) uint64 {
+	if size < 0 {
+		size = SizeOf(v)
+	}
+	// Multiplying a large v before shifting will cause overflow, but shifting a small v
+	// before multiplying can lose the fractional part, so the integer and fractional
+	// parts are scaled separately.
+	whole := v >> size
+	if whole > math.MaxUint64/1000 {
+		return math.MaxUint64
+	}
+
+	frac := ((v & (1<<size - 1)) * 1000) >> size
+
+	return 1000*whole + frac
+}
+
+// UnscaleSize is the inverse of [ScaleSize] and returns the number of bytes
+// represented by the fixed-point number v scaled to size. Since ScaleSize only
+// keeps 3 decimal places, the result may be less precise than the original value,
+// but is rounded such that passing it back to ScaleSize gives the same output.
+// If the result overflows a uint64, [math.MaxUint64] is returned.
+func UnscaleSize(v uint64, size ByteSize) uint64 {
+	if size <= Bytes {
+		return v / 1000
+	}
+	// Same as ScaleSize, the integer and fractional parts are unscaled separately
+	// to avoid overflow.
+	whole := v / 1000
+	if whole > math.MaxUint64>>size {
+		return math.MaxUint64
+	}
+
+	whole <<= size
+	frac := ((v%1000)<<size + 999) / 1000
+
+	if whole+frac < whole {
+		return math.MaxUint64
+	}
+
+	return whole + frac
+}
+
+// AppendScaled appends the string representation of the fixed-point number v
+// with 3 decimal places, without any insignificant trailing zeros.
+func AppendScaled(b []byte, v uint64) []byte {
+	b = strconv.AppendUint(b, v/1000, 10)
+
+	frac := v % 1000
+	if frac == 0 {
+		return b
+	}
+
+	b = append(b, '.', byte('0'+frac/100), byte('0'+frac/10%10), byte('0'+frac%10))
+
+	for b[len(b)-1] == '0' {
+		b = b[:len(b)-1]
+	}
+
+	return b
+}
+
 // AppendSize appends the string representation of v bytes scaled to size, with
 // 3 decimal places of precision.
 func AppendSize(b []byte, v uint64, size ByteSize) []byte {
-	const overflow = ((1 << 64) - 1) / 1000
-
 	if size < 0 {
 		size = SizeOf(v)
 	}
@@ -107,23 +175,24 @@ func AppendSize(b []byte, v uint64, size ByteSize) []byte {
 	if size == Bytes {
 		return strconv.AppendUint(b, v, 10)
 	}
-	// Multiplying a large v before shifting will cause overflow, but shifting a small v
-	// before multiplying can make v zero, so we need to determine the order of operations.
-	if v > overflow {
-		v = 1000 * (v >> size)
-	} else {
-		v = (1000 * v) >> size
-	}
 
-	if v == 0 {
-		return append(b, '0')
+	return AppendScaled(b, ScaleSize(v, size))
+}
+
+// ParseSizeValue parses the output of [AppendSize] and returns the number of
+// bytes it represents when scaled to size. See [UnscaleSize] for the precision
+// of the result.
+func ParseSizeValue(b []byte, size ByteSize) (uint64, error) {
+	v, err := ParseDecimal(b, 3)
+	if err != nil {
+		return 0, err
 	}
-	// If the decimal places of v are all zero, just append the integer value of v.
-	if v%1000 == 0 {
-		return strconv.AppendUint(b, v/1000, 10)
+
+	if v < 0 {
+		return 0, &strconv.NumError{Func: "ParseSizeValue", Num: string(b), Err: strconv.ErrRange}
 	}
 
-	return AppendDecimal(b, int64(v), 3)
+	return UnscaleSize(uint64(v), size), nil
 }
 
 // WriteSize writes the output of [AppendSize] to w followed by the string
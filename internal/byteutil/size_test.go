@@ -13,6 +13,7 @@ func TestByteSize(t *testing.T) {
 		valstr  string
 		sizestr string
 	}{
+		{0, Bytes, Bytes, "0", "B"},
 		{100, Bytes, Bytes, "100", "B"},
 		{100, Bytes, KiB, "0.097", "KiB"},
 		{100, Bytes, MiB, "0", "MiB"},
@@ -24,6 +25,7 @@ func TestByteSize(t *testing.T) {
 		{4 * 1099511627776 / 3, TiB, TiB, "1.333", "TiB"},
 		{(1 << 50) + 1, PiB, PiB, "1", "PiB"},
 		{(1 << 60) + 1, PiB, PiB, "1024", "PiB"},
+		{1<<64 - 1, PiB, PiB, "16383.999", "PiB"},
 	}
 	t.Run("SizeOf", func(t *testing.T) {
 		for _, tt := range tests {
@@ -50,6 +52,18 @@ func TestByteSize(t *testing.T) {
 			}
 		}
 	})
+	t.Run("ParseSizeValue", func(t *testing.T) {
+		for _, tt := range tests {
+			v, err := ParseSizeValue([]byte(tt.valstr), tt.size)
+			if err != nil {
+				t.Errorf("%q: Error %v", tt.valstr, err)
+				continue
+			}
+			if s := string(AppendSize(nil, v, tt.size)); s != tt.valstr {
+				t.Errorf("%q: Wanted %s, got %s", tt.valstr, tt.valstr, s)
+			}
+		}
+	})
 	t.Run("WriteSize", func(t *testing.T) {
 		for _, tt := range tests {
 			var b strings.Builder
@@ -0,0 +1,50 @@
+package byteutil
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToTitle returns the title case representation of b. The first letter of
+// each word is title case and the rest are lower case, such as "It's 3rd-Party"
+// for "IT'S 3RD-PARTY". Words are separated by any character other than a
+// letter, digit, mark or apostrophe. The case mapping doesn't depend on the
+// locale, and any invalid UTF-8 is kept as is.
+func ToTitle(b []byte) []byte {
+	t := make([]byte, 0, len(b))
+	start := true
+
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+
+		switch {
+		case r == utf8.RuneError && n <= 1:
+			t = append(t, b[0])
+			start = false
+		case unicode.IsLetter(r):
+			if start {
+				r = unicode.ToTitle(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+
+			t = utf8.AppendRune(t, r)
+			start = false
+		case r == '\'' || r == '’' || unicode.IsDigit(r) || unicode.IsMark(r):
+			t = append(t, b[:n]...)
+			start = false
+		default:
+			t = append(t, b[:n]...)
+			start = true
+		}
+
+		b = b[n:]
+	}
+
+	return t
+}
+
+// ToTitleString returns the title case representation of s. See [ToTitle].
+func ToTitleString(s string) string {
+	return string(ToTitle([]byte(s)))
+}
@@ -0,0 +1,452 @@
+// Package expr implements the expressions of derived values, which have the
+// syntax of Go expressions and are evaluated over the JSON-decoded payloads of
+// the metrics, such as "cpu.usage*0.6 + memory.used/memory.total*40".
+//
+// The identifiers of an expression are the variables it's evaluated with, and
+// the fields of objects are selected by name or index, such as
+// disks["/"].used or cpu.cores[0].usage. Numbers support the arithmetic and
+// comparison operators, bools the logical operators, and strings comparison
+// for equality. The functions are:
+//
+//   - abs(x), the absolute value of x
+//   - min(x, ...) and max(x, ...), the minimum and maximum of the numbers
+//   - round(x) and round(x, places), x rounded to the number of decimal places
+//   - len(x), the length of a list, object or string
+//   - iif(cond, a, b), a if cond is true, otherwise b, evaluating only one
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ErrUndefined is returned when evaluating an expression that refers to a
+// variable or field that's missing, such as of a metric that hasn't updated.
+var ErrUndefined = errors.New("undefined")
+
+// arity is the number of arguments of each function, or -1 for any number
+// of at least one.
+var arity = map[string][2]int{
+	"abs":   {1, 1},
+	"min":   {1, -1},
+	"max":   {1, -1},
+	"round": {1, 2},
+	"len":   {1, 1},
+	"iif":   {3, 3},
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root ast.Expr
+	refs []string
+}
+
+// Parse parses src, returning an error if it isn't a valid expression or it
+// uses any operator or function not supported.
+func Parse(src string) (*Expr, error) {
+	root, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Expr{src: src, root: root}
+
+	if err := e.check(root); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(e.refs)
+	e.refs = slices.Compact(e.refs)
+
+	return e, nil
+}
+
+// check returns an error if n isn't supported, adding the variables it refers
+// to to the refs of e.
+func (e *Expr) check(n ast.Expr) error {
+	switch n := n.(type) {
+	case *ast.Ident:
+		if n.Name != "true" && n.Name != "false" {
+			e.refs = append(e.refs, n.Name)
+		}
+	case *ast.BasicLit:
+		if n.Kind == token.CHAR || n.Kind == token.IMAG {
+			return fmt.Errorf("unsupported literal %s", n.Value)
+		}
+	case *ast.ParenExpr:
+		return e.check(n.X)
+	case *ast.SelectorExpr:
+		return e.check(n.X)
+	case *ast.IndexExpr:
+		if err := e.check(n.X); err != nil {
+			return err
+		}
+
+		return e.check(n.Index)
+	case *ast.UnaryExpr:
+		switch n.Op {
+		case token.ADD, token.SUB, token.NOT:
+		default:
+			return fmt.Errorf("unsupported operator %s", n.Op)
+		}
+
+		return e.check(n.X)
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO, token.REM,
+			token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ,
+			token.LAND, token.LOR:
+		default:
+			return fmt.Errorf("unsupported operator %s", n.Op)
+		}
+
+		if err := e.check(n.X); err != nil {
+			return err
+		}
+
+		return e.check(n.Y)
+	case *ast.CallExpr:
+		fn, ok := n.Fun.(*ast.Ident)
+		if !ok {
+			return errors.New("unsupported call")
+		}
+
+		a, ok := arity[fn.Name]
+		if !ok {
+			return fmt.Errorf("unknown function %s", fn.Name)
+		}
+
+		if len(n.Args) < a[0] || (a[1] >= 0 && len(n.Args) > a[1]) || n.Ellipsis.IsValid() {
+			return fmt.Errorf("wrong number of arguments to %s", fn.Name)
+		}
+
+		for _, arg := range n.Args {
+			if err := e.check(arg); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported expression %T", n)
+	}
+
+	return nil
+}
+
+// Refs returns the sorted names of the variables e refers to.
+func (e *Expr) Refs() []string {
+	return e.refs
+}
+
+// String returns the source of e.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates e with vars, which are JSON-decoded values as by
+// [encoding/json.Unmarshal] into an any. The result must be a number, and
+// an error wrapping [ErrUndefined] is returned if e refers to anything
+// missing from vars.
+func (e *Expr) Eval(vars map[string]any) (float64, error) {
+	v, err := eval(e.root, vars)
+	if err != nil {
+		return 0, err
+	}
+
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("result is %s, not a number", typeOf(v))
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("result is %v", f)
+	}
+
+	return f, nil
+}
+
+// typeOf returns the JSON type of v.
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a bool"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+func number(n ast.Expr, vars map[string]any) (float64, error) {
+	v, err := eval(n, vars)
+	if err != nil {
+		return 0, err
+	}
+
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s is %s, not a number", types.ExprString(n), typeOf(v))
+	}
+
+	return f, nil
+}
+
+func boolean(n ast.Expr, vars map[string]any) (bool, error) {
+	v, err := eval(n, vars)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s is %s, not a bool", types.ExprString(n), typeOf(v))
+	}
+
+	return b, nil
+}
+
+func eval(n ast.Expr, vars map[string]any) (any, error) {
+	switch n := n.(type) {
+	case *ast.Ident:
+		switch n.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+
+		v, ok := vars[n.Name]
+		if !ok || v == nil {
+			return nil, fmt.Errorf("%s %w", n.Name, ErrUndefined)
+		}
+
+		return v, nil
+	case *ast.BasicLit:
+		if n.Kind == token.STRING {
+			return strconv.Unquote(n.Value)
+		}
+
+		return strconv.ParseFloat(n.Value, 64)
+	case *ast.ParenExpr:
+		return eval(n.X, vars)
+	case *ast.SelectorExpr:
+		return field(n.X, n.Sel.Name, vars)
+	case *ast.IndexExpr:
+		idx, err := eval(n.Index, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		switch idx := idx.(type) {
+		case string:
+			return field(n.X, idx, vars)
+		case float64:
+			return element(n.X, idx, vars)
+		}
+
+		return nil, fmt.Errorf("index %s is %s", types.ExprString(n.Index), typeOf(idx))
+	case *ast.UnaryExpr:
+		if n.Op == token.NOT {
+			b, err := boolean(n.X, vars)
+			return !b, err
+		}
+
+		f, err := number(n.X, vars)
+		if n.Op == token.SUB {
+			f = -f
+		}
+
+		return f, err
+	case *ast.BinaryExpr:
+		return binary(n, vars)
+	case *ast.CallExpr:
+		return call(n, vars)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %T", n)
+}
+
+// field returns the field of the object x with the given name.
+func field(x ast.Expr, name string, vars map[string]any) (any, error) {
+	v, err := eval(x, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, not an object", types.ExprString(x), typeOf(v))
+	}
+
+	f, ok := obj[name]
+	if !ok || f == nil {
+		return nil, fmt.Errorf("%s[%q] %w", types.ExprString(x), name, ErrUndefined)
+	}
+
+	return f, nil
+}
+
+// element returns the element of the list x at index i.
+func element(x ast.Expr, i float64, vars map[string]any) (any, error) {
+	v, err := eval(x, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, not a list", types.ExprString(x), typeOf(v))
+	}
+
+	if i != math.Trunc(i) || i < 0 || int(i) >= len(list) || list[int(i)] == nil {
+		return nil, fmt.Errorf("%s[%v] %w", types.ExprString(x), i, ErrUndefined)
+	}
+
+	return list[int(i)], nil
+}
+
+func binary(n *ast.BinaryExpr, vars map[string]any) (any, error) {
+	switch n.Op {
+	case token.LAND, token.LOR:
+		x, err := boolean(n.X, vars)
+		if err != nil || x == (n.Op == token.LOR) {
+			return x, err
+		}
+
+		return boolean(n.Y, vars)
+	case token.EQL, token.NEQ:
+		x, err := eval(n.X, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := eval(n.Y, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		switch x.(type) {
+		case float64, string, bool:
+		default:
+			return nil, fmt.Errorf("%s is %s, which can't be compared", types.ExprString(n.X), typeOf(x))
+		}
+
+		return (x == y) == (n.Op == token.EQL), nil
+	}
+
+	x, err := number(n.X, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := number(n.Y, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case token.ADD:
+		return x + y, nil
+	case token.SUB:
+		return x - y, nil
+	case token.MUL:
+		return x * y, nil
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, fmt.Errorf("%s: division by zero", types.ExprString(n))
+		}
+
+		if n.Op == token.REM {
+			return math.Mod(x, y), nil
+		}
+
+		return x / y, nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %s", n.Op)
+}
+
+func call(n *ast.CallExpr, vars map[string]any) (any, error) {
+	switch name := n.Fun.(*ast.Ident).Name; name {
+	case "iif":
+		cond, err := boolean(n.Args[0], vars)
+		if err != nil {
+			return nil, err
+		}
+
+		if cond {
+			return eval(n.Args[1], vars)
+		}
+
+		return eval(n.Args[2], vars)
+	case "len":
+		v, err := eval(n.Args[0], vars)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := v.(type) {
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		case string:
+			return float64(len(v)), nil
+		}
+
+		return nil, fmt.Errorf("%s is %s, which has no length", types.ExprString(n.Args[0]), typeOf(v))
+	default:
+		args := make([]float64, len(n.Args))
+
+		for i := range n.Args {
+			f, err := number(n.Args[i], vars)
+			if err != nil {
+				return nil, err
+			}
+
+			args[i] = f
+		}
+
+		switch name {
+		case "abs":
+			return math.Abs(args[0]), nil
+		case "min":
+			return slices.Min(args), nil
+		case "max":
+			return slices.Max(args), nil
+		case "round":
+			if len(args) == 1 {
+				return math.Round(args[0]), nil
+			}
+
+			p := math.Pow(10, math.Trunc(args[1]))
+
+			return math.Round(args[0]*p) / p, nil
+		}
+	}
+
+	return nil, errors.New("unsupported call")
+}
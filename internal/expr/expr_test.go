@@ -0,0 +1,129 @@
+package expr
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+const testVars = `{
+	"cpu": {"usage": 50, "temperature": 61.5, "cores": [{"usage": 20}, {"usage": 80}]},
+	"memory": {"total": 16, "used": 4, "swapTotal": 0},
+	"disks": {"/": {"used": 30, "total": 120, "stale": false}},
+	"battery": {"status": "charging", "capacity": null}
+}`
+
+func testEnv(t *testing.T) map[string]any {
+	t.Helper()
+
+	var vars map[string]any
+	if err := json.Unmarshal([]byte(testVars), &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	return vars
+}
+
+func TestEval(t *testing.T) {
+	vars := testEnv(t)
+
+	var tests = []struct {
+		src  string
+		want float64
+	}{
+		{"cpu.usage*0.6 + memory.used/memory.total*40", 40},
+		{`disks["/"].used / disks["/"].total * 100`, 25},
+		{"cpu.cores[1].usage - cpu.cores[0].usage", 60},
+		{"-cpu.temperature", -61.5},
+		{"round(cpu.temperature)", 62},
+		{"round(memory.used / 3, 2)", 1.33},
+		{"min(cpu.usage, 10, 30) + max(1, 2)", 12},
+		{"abs(memory.used - memory.total)", 12},
+		{"len(cpu.cores) + len(disks)", 3},
+		{"7 % 4", 3},
+		{`iif(battery.status == "charging", 1, 0)`, 1},
+		{"iif(cpu.usage > 90 || memory.swapTotal == 0, 1, 0)", 1},
+		// Only the branch taken is evaluated.
+		{"iif(!true && false, battery.capacity, 2)", 2},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+
+		got, err := e.Eval(vars)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+		} else if got != tt.want {
+			t.Errorf("%s: want %v, got %v", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestEval_Error(t *testing.T) {
+	vars := testEnv(t)
+
+	var tests = []struct {
+		src       string
+		undefined bool
+	}{
+		{"gpu.temperature", true},
+		{"cpu.power", true},
+		{"battery.capacity", true},
+		{"cpu.cores[2].usage", true},
+		{"cpu.usage / memory.swapTotal", false},
+		{"battery.status", false},
+		{"cpu.usage > 10", false},
+		{"cpu.cores + 1", false},
+		{"memory.used.total", false},
+		{`iif(cpu.usage, 1, 0)`, false},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+
+		_, err = e.Eval(vars)
+		if err == nil {
+			t.Errorf("%s: want error", tt.src)
+		} else if errors.Is(err, ErrUndefined) != tt.undefined {
+			t.Errorf("%s: want undefined %v, got %v", tt.src, tt.undefined, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	e, err := Parse(`cpu.usage + iif(memory.total > 0, memory.used, disks["/"].used)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []string{"cpu", "disks", "memory"}, e.Refs(); !slices.Equal(got, want) {
+		t.Errorf("Refs: want %q, got %q", want, got)
+	}
+
+	for _, src := range []string{
+		"cpu.usage +",
+		"cpu.usage << 1",
+		"cpu.usage & 1",
+		"sqrt(cpu.usage)",
+		"round()",
+		"iif(true, 1)",
+		"min(cpu.cores...)",
+		"cpu.usage.String()",
+		"'a'",
+		"func() {}",
+		"cpu[1:2]",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("%q: want error", src)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package mqttutil provides helpers for waiting on the tokens of an MQTT
+// client without blocking forever if the broker stalls.
+package mqttutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// DefaultTimeout is the timeout of a token if none is configured, which is
+// the same as the default write timeout of the client.
+const DefaultTimeout = 30 * time.Second
+
+// ErrTimeout is returned by [Wait] if the broker doesn't acknowledge a token
+// in time. It wraps [os.ErrDeadlineExceeded], so it's a timeout that may be
+// retried, and the operation may still complete later.
+var ErrTimeout = fmt.Errorf("broker didn't respond: %w", os.ErrDeadlineExceeded)
+
+// Wait waits for the first of ctx.Done() or t.Done() and returns t.Error(), or
+// nil if ctx.Done() finished first. If t isn't done within timeout, a warning
+// is logged and an error wrapping [ErrTimeout] is returned. If timeout <= 0,
+// Wait waits as long as ctx.
+func Wait(ctx context.Context, t mqtt.Token, timeout time.Duration) error {
+	var timeoutC <-chan time.Time
+
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		timeoutC = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-t.Done():
+		return t.Error()
+	case <-timeoutC:
+	}
+
+	op := opOf(t)
+
+	log.Warn("Timed out waiting for broker", "op", op, "timeout", timeout)
+
+	return fmt.Errorf("%s: %w", op, ErrTimeout)
+}
+
+// Timeout returns timeout, or [DefaultTimeout] if timeout is 0.
+func Timeout(timeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return DefaultTimeout
+	}
+
+	return timeout
+}
+
+func opOf(t mqtt.Token) string {
+	switch t.(type) {
+	case *mqtt.ConnectToken:
+		return "connect"
+	case *mqtt.PublishToken:
+		return "publish"
+	case *mqtt.SubscribeToken:
+		return "subscribe"
+	case *mqtt.UnsubscribeToken:
+		return "unsubscribe"
+	case *mqtt.DisconnectToken:
+		return "disconnect"
+	}
+
+	return "wait"
+}
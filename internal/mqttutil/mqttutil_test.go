@@ -0,0 +1,38 @@
+package mqttutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// stalledToken is a token that is never done.
+type stalledToken struct {
+	mqtt.DummyToken
+}
+
+func (stalledToken) Done() <-chan struct{} {
+	return nil
+}
+
+func TestWait(t *testing.T) {
+	if err := Wait(context.Background(), &mqtt.DummyToken{}, time.Second); err != nil {
+		t.Errorf("Done: want nil, got %v", err)
+	}
+
+	err := Wait(context.Background(), &stalledToken{}, 10*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Stalled: want %v, got %v", ErrTimeout, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Wait(ctx, &stalledToken{}, 0); err != nil {
+		t.Errorf("Canceled: want nil, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package sched sets the scheduling attributes of the running process, such as
+// CPU affinity, niceness, and I/O priority.
+//
+// On Linux, these attributes belong to each thread rather than the process as a
+// whole. Since the Go runtime schedules goroutines on many threads, the attributes
+// are applied to every thread of the process. Threads created afterwards inherit
+// the attributes from the thread that created them.
+package sched
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// I/O scheduling classes, see ioprio_set(2).
+const (
+	ioClassNone = iota
+	ioClassRealtime
+	ioClassBestEffort
+	ioClassIdle
+)
+
+const (
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// ParseIOClass parses s for the I/O scheduling class. A blank s is valid and
+// means the class should be unchanged.
+func ParseIOClass(s string) (int, error) {
+	switch s {
+	case "":
+		return ioClassNone, nil
+	case "realtime", "rt":
+		return ioClassRealtime, nil
+	case "best-effort", "be":
+		return ioClassBestEffort, nil
+	case "idle":
+		return ioClassIdle, nil
+	}
+
+	return ioClassNone, fmt.Errorf("unknown I/O class %q", s)
+}
+
+// Apply applies the scheduling attributes of cfg to every thread of the running
+// process. Any attribute left at its default value is unchanged.
+func Apply(cfg *config.RuntimeConfig) error {
+	if cfg.IsZero() {
+		return nil
+	}
+
+	var set *unix.CPUSet
+
+	if len(cfg.CPUAffinity) > 0 {
+		set = new(unix.CPUSet)
+
+		for _, cpu := range cfg.CPUAffinity {
+			if cpu < 0 || cpu >= len(set)*strconv.IntSize {
+				return fmt.Errorf("invalid CPU %d", cpu)
+			}
+
+			set.Set(cpu)
+		}
+	}
+
+	if cfg.Nice < -20 || cfg.Nice > 19 {
+		return fmt.Errorf("invalid nice %d", cfg.Nice)
+	}
+
+	class, err := ParseIOClass(cfg.IOClass)
+	if err != nil {
+		return err
+	}
+
+	if cfg.IOPriority < 0 || cfg.IOPriority > 7 {
+		return fmt.Errorf("invalid I/O priority %d", cfg.IOPriority)
+	}
+
+	var ioprio int
+
+	switch class {
+	case ioClassNone:
+	case ioClassIdle:
+		ioprio = class << ioprioClassShift
+	default:
+		ioprio = class<<ioprioClassShift | cfg.IOPriority
+	}
+
+	tids, err := threads()
+	if err != nil {
+		return err
+	}
+
+	for _, tid := range tids {
+		if set != nil {
+			if err := ignoreExited(unix.SchedSetaffinity(tid, set)); err != nil {
+				return fmt.Errorf("unable to set CPU affinity: %w", err)
+			}
+		}
+
+		if cfg.Nice != 0 {
+			if err := ignoreExited(unix.Setpriority(unix.PRIO_PROCESS, tid, cfg.Nice)); err != nil {
+				return fmt.Errorf("unable to set nice: %w", err)
+			}
+		}
+
+		if ioprio != 0 {
+			if err := ignoreExited(ioprioSet(tid, ioprio)); err != nil {
+				return fmt.Errorf("unable to set I/O priority: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// threads returns the thread IDs of the running process.
+func threads() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+
+	tids := make([]int, 0, len(entries))
+
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+
+	return tids, nil
+}
+
+// ignoreExited returns nil if err indicates the thread has exited since
+// listing the threads of the process.
+func ignoreExited(err error) error {
+	if errors.Is(err, unix.ESRCH) {
+		return nil
+	}
+
+	return err
+}
+
+func ioprioSet(tid, ioprio int) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package sched
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestParseIOClass(t *testing.T) {
+	var tests = []struct {
+		s     string
+		class int
+	}{
+		{"", ioClassNone},
+		{"realtime", ioClassRealtime},
+		{"best-effort", ioClassBestEffort},
+		{"idle", ioClassIdle},
+	}
+	for _, tt := range tests {
+		class, err := ParseIOClass(tt.s)
+		if err != nil {
+			t.Errorf("%q: Error %v", tt.s, err)
+		} else if class != tt.class {
+			t.Errorf("%q: Wanted %d, got %d", tt.s, tt.class, class)
+		}
+	}
+	if _, err := ParseIOClass("foo"); err == nil {
+		t.Error("foo: Wanted error")
+	}
+}
+
+func TestApply(t *testing.T) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		t.Fatal(err)
+	}
+
+	var cpu int
+	for !set.IsSet(cpu) {
+		cpu++
+	}
+
+	cfg := config.DefaultRuntime
+	cfg.CPUAffinity = []int{cpu}
+	cfg.IOClass = "best-effort"
+
+	if err := Apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		t.Fatal(err)
+	}
+	if n := set.Count(); n != 1 || !set.IsSet(cpu) {
+		t.Errorf("CPUAffinity: Wanted only CPU %d, got %d CPUs", cpu, n)
+	}
+
+	for _, cfg := range []config.RuntimeConfig{
+		{CPUAffinity: []int{-1}},
+		{Nice: 20},
+		{IOClass: "foo"},
+		{IOClass: "idle", IOPriority: 8},
+	} {
+		if err := Apply(&cfg); err == nil {
+			t.Errorf("%+v: Wanted error", cfg)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// Package wireguard reads the status of WireGuard interfaces with the generic
+// netlink API of the kernel module, the same as "wg show".
+package wireguard
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sizeofGenlmsghdr is the size of struct genlmsghdr, which precedes the
+// attributes of a generic netlink message.
+const sizeofGenlmsghdr = 4
+
+// Key is the public key of a WireGuard interface or peer.
+type Key [32]byte
+
+// Peer is the status of a peer of a WireGuard interface.
+type Peer struct {
+	PublicKey Key
+	Endpoint  netip.AddrPort
+	// LastHandshake is the time of the last handshake with the peer, which is
+	// zero if there never was one.
+	LastHandshake time.Time
+	RxBytes       uint64
+	TxBytes       uint64
+}
+
+// Device is the status of a WireGuard interface.
+type Device struct {
+	Name       string
+	PublicKey  Key
+	ListenPort int
+	Peers      []Peer
+}
+
+// ErrMalformed is returned if a netlink message can't be parsed.
+var ErrMalformed = errors.New("malformed netlink message")
+
+// Client is a generic netlink socket used to read WireGuard interfaces. The
+// socket is of the network namespace of the thread that opened it.
+type Client struct {
+	fd     int
+	family uint16
+	seq    uint32
+	buf    []byte
+}
+
+// Open opens a new [Client]. If the WireGuard module isn't loaded, the error
+// wraps [os.ErrNotExist].
+func Open() (*Client, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	c := &Client{fd: fd, buf: make([]byte, 1<<16)}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		c.Close()
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	msgs, err := c.execute(unix.GENL_ID_CTRL, unix.NLM_F_REQUEST, unix.CTRL_CMD_GETFAMILY, 1,
+		attr(unix.CTRL_ATTR_FAMILY_NAME, append([]byte(unix.WG_GENL_NAME), 0)))
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		err = parseAttrs(msg, func(typ uint16, data []byte) error {
+			if typ == unix.CTRL_ATTR_FAMILY_ID && len(data) >= 2 {
+				c.family = binary.NativeEndian.Uint16(data)
+			}
+
+			return nil
+		})
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if c.family == 0 {
+		c.Close()
+		return nil, ErrMalformed
+	}
+
+	return c, nil
+}
+
+// Close closes the socket of c.
+func (c *Client) Close() error {
+	return unix.Close(c.fd)
+}
+
+// Device returns the status of the WireGuard interface with the given name.
+func (c *Client) Device(name string) (*Device, error) {
+	msgs, err := c.execute(c.family, unix.NLM_F_REQUEST|unix.NLM_F_DUMP, unix.WG_CMD_GET_DEVICE, unix.WG_GENL_VERSION,
+		attr(unix.WGDEVICE_A_IFNAME, append([]byte(name), 0)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDevice(msgs)
+}
+
+// execute sends a generic netlink request and returns the attributes of each
+// message of the response.
+func (c *Client) execute(typ, flags uint16, cmd, version uint8, attrs ...[]byte) ([][]byte, error) {
+	c.seq++
+
+	req := make([]byte, unix.SizeofNlMsghdr+sizeofGenlmsghdr)
+	req[unix.SizeofNlMsghdr] = cmd
+	req[unix.SizeofNlMsghdr+1] = version
+
+	for _, a := range attrs {
+		req = append(req, a...)
+	}
+
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], typ)
+	binary.NativeEndian.PutUint16(req[6:8], flags)
+	binary.NativeEndian.PutUint32(req[8:12], c.seq)
+
+	if err := unix.Sendto(c.fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var msgs [][]byte
+
+	for {
+		n, _, err := unix.Recvfrom(c.fd, c.buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+
+		done, err := parseMessages(c.buf[:n], c.seq, flags&unix.NLM_F_DUMP == 0, func(data []byte) {
+			msgs = append(msgs, append([]byte(nil), data...))
+		})
+		if err != nil || done {
+			return msgs, err
+		}
+	}
+}
+
+// parseMessages calls fn with the attributes of each generic netlink message
+// in b with the given sequence number, and reports whether the response is
+// done, which is after the first message if single.
+func parseMessages(b []byte, seq uint32, single bool, fn func(attrs []byte)) (done bool, err error) {
+	for len(b) >= unix.SizeofNlMsghdr {
+		l := int(binary.NativeEndian.Uint32(b[0:4]))
+		typ := binary.NativeEndian.Uint16(b[4:6])
+
+		if l < unix.SizeofNlMsghdr || l > len(b) {
+			return true, ErrMalformed
+		}
+
+		msg := b[unix.SizeofNlMsghdr:l]
+		msgSeq := binary.NativeEndian.Uint32(b[8:12])
+		b = b[min(align(l), len(b)):]
+
+		if msgSeq != seq {
+			continue
+		}
+
+		switch typ {
+		case unix.NLMSG_DONE:
+			return true, nil
+		case unix.NLMSG_ERROR:
+			if len(msg) < 4 {
+				return true, ErrMalformed
+			}
+
+			if errno := -int32(binary.NativeEndian.Uint32(msg)); errno != 0 {
+				return true, unix.Errno(errno)
+			}
+
+			return true, nil
+		}
+
+		if len(msg) < sizeofGenlmsghdr {
+			return true, ErrMalformed
+		}
+
+		fn(msg[sizeofGenlmsghdr:])
+
+		if single {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parseDevice parses the attributes of each message of a WG_CMD_GET_DEVICE
+// response. A device with many peers is split across messages, and so may a
+// peer with many allowed IPs, in which case its attributes are merged.
+func parseDevice(msgs [][]byte) (*Device, error) {
+	dev := new(Device)
+
+	for _, msg := range msgs {
+		err := parseAttrs(msg, func(typ uint16, data []byte) error {
+			switch typ {
+			case unix.WGDEVICE_A_IFNAME:
+				dev.Name = string(trimNul(data))
+			case unix.WGDEVICE_A_PUBLIC_KEY:
+				if len(data) == len(dev.PublicKey) {
+					dev.PublicKey = Key(data)
+				}
+			case unix.WGDEVICE_A_LISTEN_PORT:
+				if len(data) >= 2 {
+					dev.ListenPort = int(binary.NativeEndian.Uint16(data))
+				}
+			case unix.WGDEVICE_A_PEERS:
+				return parseAttrs(data, func(_ uint16, data []byte) error {
+					peer, err := parsePeer(data)
+					if err != nil {
+						return err
+					}
+
+					if n := len(dev.Peers); n > 0 && dev.Peers[n-1].PublicKey == peer.PublicKey {
+						dev.Peers[n-1].merge(&peer)
+					} else {
+						dev.Peers = append(dev.Peers, peer)
+					}
+
+					return nil
+				})
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dev, nil
+}
+
+func parsePeer(b []byte) (peer Peer, err error) {
+	err = parseAttrs(b, func(typ uint16, data []byte) error {
+		switch typ {
+		case unix.WGPEER_A_PUBLIC_KEY:
+			if len(data) != len(peer.PublicKey) {
+				return ErrMalformed
+			}
+
+			peer.PublicKey = Key(data)
+		case unix.WGPEER_A_ENDPOINT:
+			peer.Endpoint = parseSockaddr(data)
+		case unix.WGPEER_A_LAST_HANDSHAKE_TIME:
+			// struct __kernel_timespec
+			if len(data) < 16 {
+				return ErrMalformed
+			}
+
+			sec := int64(binary.NativeEndian.Uint64(data[0:8]))
+			nsec := int64(binary.NativeEndian.Uint64(data[8:16]))
+
+			if sec != 0 || nsec != 0 {
+				peer.LastHandshake = time.Unix(sec, nsec)
+			}
+		case unix.WGPEER_A_RX_BYTES:
+			if len(data) < 8 {
+				return ErrMalformed
+			}
+
+			peer.RxBytes = binary.NativeEndian.Uint64(data)
+		case unix.WGPEER_A_TX_BYTES:
+			if len(data) < 8 {
+				return ErrMalformed
+			}
+
+			peer.TxBytes = binary.NativeEndian.Uint64(data)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// merge sets the attributes of p that are in other, which is the rest of p
+// from another message.
+func (p *Peer) merge(other *Peer) {
+	if other.Endpoint.IsValid() {
+		p.Endpoint = other.Endpoint
+	}
+
+	if !other.LastHandshake.IsZero() {
+		p.LastHandshake = other.LastHandshake
+	}
+
+	if other.RxBytes != 0 {
+		p.RxBytes = other.RxBytes
+	}
+
+	if other.TxBytes != 0 {
+		p.TxBytes = other.TxBytes
+	}
+}
+
+// parseSockaddr parses a struct sockaddr_in or sockaddr_in6.
+func parseSockaddr(b []byte) netip.AddrPort {
+	if len(b) < 4 {
+		return netip.AddrPort{}
+	}
+
+	family := binary.NativeEndian.Uint16(b[0:2])
+	port := binary.BigEndian.Uint16(b[2:4])
+
+	switch {
+	case family == unix.AF_INET && len(b) >= unix.SizeofSockaddrInet4:
+		return netip.AddrPortFrom(netip.AddrFrom4([4]byte(b[4:8])), port)
+	case family == unix.AF_INET6 && len(b) >= unix.SizeofSockaddrInet6:
+		return netip.AddrPortFrom(netip.AddrFrom16([16]byte(b[8:24])), port)
+	}
+
+	return netip.AddrPort{}
+}
+
+// parseAttrs calls fn with the type and data of each netlink attribute in b,
+// until fn returns an error.
+func parseAttrs(b []byte, fn func(typ uint16, data []byte) error) error {
+	for len(b) >= unix.SizeofNlAttr {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4]) &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+
+		if l < unix.SizeofNlAttr || l > len(b) {
+			return ErrMalformed
+		}
+
+		if err := fn(typ, b[unix.SizeofNlAttr:l]); err != nil {
+			return err
+		}
+
+		b = b[min(align(l), len(b)):]
+	}
+
+	return nil
+}
+
+// attr returns the netlink attribute of the given type and data.
+func attr(typ uint16, data []byte) []byte {
+	l := unix.SizeofNlAttr + len(data)
+	b := make([]byte, align(l))
+
+	binary.NativeEndian.PutUint16(b[0:2], uint16(l))
+	binary.NativeEndian.PutUint16(b[2:4], typ)
+	copy(b[unix.SizeofNlAttr:], data)
+
+	return b
+}
+
+func align(n int) int {
+	return (n + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
+
+func trimNul(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+
+	return b
+}
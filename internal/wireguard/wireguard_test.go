@@ -0,0 +1,158 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func nested(typ uint16, attrs ...[]byte) []byte {
+	var data []byte
+	for _, a := range attrs {
+		data = append(data, a...)
+	}
+
+	return attr(typ|unix.NLA_F_NESTED, data)
+}
+
+func u64(v uint64) []byte {
+	return binary.NativeEndian.AppendUint64(nil, v)
+}
+
+func message(seq uint32, typ uint16, attrs ...[]byte) []byte {
+	b := make([]byte, unix.SizeofNlMsghdr+sizeofGenlmsghdr)
+	for _, a := range attrs {
+		b = append(b, a...)
+	}
+
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], typ)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+
+	return b
+}
+
+func TestParseDevice(t *testing.T) {
+	key1, key2 := Key{1}, Key{2}
+	handshake := time.Unix(1700000000, 500)
+
+	// struct sockaddr_in of 192.0.2.1:51820
+	endpoint := make([]byte, unix.SizeofSockaddrInet4)
+	binary.NativeEndian.PutUint16(endpoint[0:2], unix.AF_INET)
+	binary.BigEndian.PutUint16(endpoint[2:4], 51820)
+	copy(endpoint[4:8], []byte{192, 0, 2, 1})
+
+	timespec := append(u64(uint64(handshake.Unix())), u64(uint64(handshake.Nanosecond()))...)
+
+	// The second peer is split across both messages
+	buf := append(message(1, 0x20,
+		attr(unix.WGDEVICE_A_IFNAME, []byte("wg0\x00")),
+		nested(unix.WGDEVICE_A_PEERS,
+			nested(0,
+				attr(unix.WGPEER_A_PUBLIC_KEY, key1[:]),
+				attr(unix.WGPEER_A_ENDPOINT, endpoint),
+				attr(unix.WGPEER_A_LAST_HANDSHAKE_TIME, timespec),
+				attr(unix.WGPEER_A_RX_BYTES, u64(100)),
+				attr(unix.WGPEER_A_TX_BYTES, u64(200)),
+			),
+			nested(1,
+				attr(unix.WGPEER_A_PUBLIC_KEY, key2[:]),
+				attr(unix.WGPEER_A_LAST_HANDSHAKE_TIME, make([]byte, 16)),
+				attr(unix.WGPEER_A_RX_BYTES, u64(300)),
+			),
+		),
+	), message(1, 0x20,
+		attr(unix.WGDEVICE_A_IFNAME, []byte("wg0\x00")),
+		nested(unix.WGDEVICE_A_PEERS,
+			nested(0,
+				attr(unix.WGPEER_A_PUBLIC_KEY, key2[:]),
+				attr(unix.WGPEER_A_ALLOWEDIPS, nil),
+			),
+		),
+	)...)
+	buf = append(buf, message(2, 0x20)...)
+	buf = append(buf, message(1, unix.NLMSG_DONE)...)
+
+	var msgs [][]byte
+
+	done, err := parseMessages(buf, 1, false, func(attrs []byte) {
+		msgs = append(msgs, attrs)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done || len(msgs) != 2 {
+		t.Fatalf("parseMessages: want 2 messages and done, got %d, %v", len(msgs), done)
+	}
+
+	dev, err := parseDevice(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dev.Name != "wg0" {
+		t.Errorf("Name: want wg0, got %q", dev.Name)
+	}
+	if len(dev.Peers) != 2 {
+		t.Fatalf("Peers: want 2, got %d", len(dev.Peers))
+	}
+
+	want := []Peer{
+		{
+			PublicKey:     key1,
+			Endpoint:      netip.MustParseAddrPort("192.0.2.1:51820"),
+			LastHandshake: handshake,
+			RxBytes:       100,
+			TxBytes:       200,
+		},
+		{PublicKey: key2, RxBytes: 300},
+	}
+	for i := range want {
+		if got := dev.Peers[i]; got != want[i] {
+			t.Errorf("Peer %d: want %+v, got %+v", i, want[i], got)
+		}
+	}
+}
+
+func TestParseMessages_Error(t *testing.T) {
+	msg := make([]byte, unix.SizeofNlMsghdr+4)
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:6], unix.NLMSG_ERROR)
+	binary.NativeEndian.PutUint32(msg[8:12], 1)
+	errno := -int32(unix.ENODEV)
+	binary.NativeEndian.PutUint32(msg[16:20], uint32(errno))
+
+	if _, err := parseMessages(msg, 1, false, func([]byte) {}); !errors.Is(err, unix.ENODEV) {
+		t.Errorf("want %v, got %v", unix.ENODEV, err)
+	}
+	if _, err := parseMessages(msg[:8], 1, false, func([]byte) {}); err != nil {
+		t.Errorf("Short: want nil, got %v", err)
+	}
+
+	binary.NativeEndian.PutUint32(msg[0:4], 100)
+
+	if _, err := parseMessages(msg, 1, false, func([]byte) {}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Length: want %v, got %v", ErrMalformed, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	c, err := Open()
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.EPERM) || errors.Is(err, unix.EAFNOSUPPORT) {
+		t.Skip("WireGuard is not available:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if _, err := c.Device("mqttop-missing"); !errors.Is(err, unix.ENODEV) {
+		t.Errorf("Device: want %v, got %v", unix.ENODEV, err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build 386 || arm || mips || mipsle
+
+package log
+
+// levelAboveDisabled is the largest Level, which is LevelDisabled when int
+// is only 32 bits.
+const levelAboveDisabled = LevelDisabled
@@ -0,0 +1,6 @@
+//go:build !(386 || arm || mips || mipsle)
+
+package log
+
+// levelAboveDisabled is a Level greater than LevelDisabled.
+const levelAboveDisabled = LevelDisabled + 1
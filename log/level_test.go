@@ -12,7 +12,7 @@ func TestLevelString(t *testing.T) {
 		want string
 	}{
 		{LevelDisabled, "DISABLED"},
-		{LevelDisabled + 1, "DISABLED"},
+		{levelAboveDisabled, "DISABLED"},
 		{LevelError, slog.LevelError.String()},
 		{LevelError + 2, (slog.LevelError + 2).String()},
 		{LevelError - 2, (slog.LevelError - 2).String()},
@@ -94,7 +94,7 @@ func TestLevelMarshalText(t *testing.T) {
 		want string
 	}{
 		{LevelDisabled, "DISABLED"},
-		{LevelDisabled + 1, "DISABLED"},
+		{levelAboveDisabled, "DISABLED"},
 		{LevelError, slog.LevelError.String()},
 		{LevelError + 2, (slog.LevelError + 2).String()},
 		{LevelError - 2, (slog.LevelError - 2).String()},
@@ -123,7 +123,7 @@ func TestLevelMarshalJSON(t *testing.T) {
 		want string
 	}{
 		{LevelDisabled, "DISABLED"},
-		{LevelDisabled + 1, "DISABLED"},
+		{levelAboveDisabled, "DISABLED"},
 		{LevelError, slog.LevelError.String()},
 		{LevelError + 2, (slog.LevelError + 2).String()},
 		{LevelError - 2, (slog.LevelError - 2).String()},
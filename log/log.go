@@ -25,8 +25,10 @@ type Logger interface {
 
 type logger struct {
 	*slog.Logger
-	with  []any
-	group string
+	with     []any
+	group    string
+	handler  Handler
+	sampling SamplingOptions
 }
 
 var defaultLogger = &logger{
@@ -46,6 +48,20 @@ func WithGroup(name string) {
 	defaultLogger.group = name
 }
 
+// SetSampling sets the sampling of the default logger's handler with opts, see
+// [NewSamplingHandler]. If the Limit of opts is 0, messages are no longer
+// sampled.
+func SetSampling(opts SamplingOptions) {
+	defaultLogger.sampling = opts
+
+	h := defaultLogger.handler
+	if h == nil {
+		h = slog.Default().Handler()
+	}
+
+	SetHandler(h)
+}
+
 // DefaultLogger returns the default logger.
 func DefaultLogger() Logger {
 	return defaultLogger
@@ -28,7 +28,9 @@ func (h debugHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 // SetHandler sets the default logger's handler to the one given.
 func SetHandler(h Handler) {
-	l := slog.New(debugHandler{h}).With(defaultLogger.with...).WithGroup(defaultLogger.group)
+	defaultLogger.handler = h
+
+	l := slog.New(debugHandler{NewSamplingHandler(h, defaultLogger.sampling)}).With(defaultLogger.with...).WithGroup(defaultLogger.group)
 	defaultLogger.Logger = l
 }
 
@@ -9,7 +9,9 @@ func Debug(_ string, _ ...any) {}
 
 // SetHandler sets the default logger's handler to the one given.
 func SetHandler(h Handler) {
-	l := slog.New(h).With(defaultLogger.with...).WithGroup(defaultLogger.group)
+	defaultLogger.handler = h
+
+	l := slog.New(NewSamplingHandler(h, defaultLogger.sampling)).With(defaultLogger.with...).WithGroup(defaultLogger.group)
 	defaultLogger.Logger = l
 }
 
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SamplingOptions are the options of a [SamplingHandler].
+type SamplingOptions struct {
+	// Limit is how many identical messages are handled each Period, after
+	// which they are dropped until the next Period. If 0, no messages are
+	// dropped.
+	Limit int
+	// Period is the period of Limit. If 0, it's one minute.
+	Period time.Duration
+	// Level is the maximum level of the sampled messages. Messages above
+	// Level are always handled.
+	Level Level
+	// Keys are the keys of the attributes whose values, along with the level
+	// and message, identify identical messages, such as "interface" to limit
+	// the messages of each interface separately.
+	Keys []string
+}
+
+// sample is the count of the messages of a key in the current period.
+type sample struct {
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// sampler is the state shared by a [SamplingHandler] and the handlers
+// derived from it.
+type sampler struct {
+	opts SamplingOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	samples map[string]*sample
+	swept   time.Time
+}
+
+// allow reports whether the message of key may be handled at now, and how
+// many messages of key were dropped in the previous period if so.
+func (s *sampler) allow(key string, now time.Time) (ok bool, dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget the keys that weren't seen for a period, so that messages
+	// with many distinct keys don't grow the map without bound.
+	if now.Sub(s.swept) >= s.opts.Period {
+		for k, c := range s.samples {
+			if now.Sub(c.start) >= 2*s.opts.Period {
+				delete(s.samples, k)
+			}
+		}
+
+		s.swept = now
+	}
+
+	c := s.samples[key]
+	if c == nil {
+		c = &sample{start: now}
+		s.samples[key] = c
+	} else if now.Sub(c.start) >= s.opts.Period {
+		dropped = c.dropped
+		*c = sample{start: now}
+	}
+
+	if c.count >= s.opts.Limit {
+		c.dropped++
+		return false, 0
+	}
+
+	c.count++
+
+	return true, dropped
+}
+
+// SamplingHandler is a [Handler] that drops identical messages once more than
+// Limit of them were handled in a period, such as noisy debug messages that
+// are logged every update. The first message handled after any were dropped
+// has the attribute "dropped" with how many were.
+type SamplingHandler struct {
+	h     Handler
+	s     *sampler
+	attrs []Attr
+}
+
+// NewSamplingHandler returns a [SamplingHandler] of h with opts. If the Limit
+// of opts is 0, h is returned instead.
+func NewSamplingHandler(h Handler, opts SamplingOptions) Handler {
+	if opts.Limit <= 0 {
+		return h
+	}
+
+	if opts.Period <= 0 {
+		opts.Period = time.Minute
+	}
+
+	return &SamplingHandler{
+		h: h,
+		s: &sampler{
+			opts:    opts,
+			now:     time.Now,
+			samples: make(map[string]*sample),
+		},
+	}
+}
+
+// Enabled implements [Handler].
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// key returns the key identifying the messages identical to r.
+func (h *SamplingHandler) key(r slog.Record) string {
+	var b strings.Builder
+
+	b.WriteString(r.Level.String())
+	b.WriteByte(0)
+	b.WriteString(r.Message)
+
+	for _, k := range h.s.opts.Keys {
+		b.WriteByte(0)
+
+		found := false
+
+		r.Attrs(func(a Attr) bool {
+			if a.Key == k {
+				b.WriteString(a.Value.String())
+				found = true
+			}
+
+			return !found
+		})
+
+		for i := len(h.attrs) - 1; !found && i >= 0; i-- {
+			if a := h.attrs[i]; a.Key == k {
+				b.WriteString(a.Value.String())
+				found = true
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// Handle implements [Handler].
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if Level(r.Level) > h.s.opts.Level {
+		return h.h.Handle(ctx, r)
+	}
+
+	now := r.Time
+	if now.IsZero() {
+		now = h.s.now()
+	}
+
+	ok, dropped := h.s.allow(h.key(r), now)
+	if !ok {
+		return nil
+	}
+
+	if dropped > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("dropped", dropped))
+	}
+
+	return h.h.Handle(ctx, r)
+}
+
+// WithAttrs implements [Handler].
+func (h *SamplingHandler) WithAttrs(attrs []Attr) Handler {
+	return &SamplingHandler{
+		h:     h.h.WithAttrs(attrs),
+		s:     h.s,
+		attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+// WithGroup implements [Handler].
+func (h *SamplingHandler) WithGroup(name string) Handler {
+	return &SamplingHandler{
+		h:     h.h.WithGroup(name),
+		s:     h.s,
+		attrs: h.attrs,
+	}
+}
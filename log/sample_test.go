@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// recordHandler records the messages it handles.
+type recordHandler struct {
+	records *[]slog.Record
+}
+
+func (recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h recordHandler) Handle(_ context.Context, r slog.Record) error {
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h recordHandler) WithAttrs([]Attr) Handler { return h }
+func (h recordHandler) WithGroup(string) Handler { return h }
+
+func TestSamplingHandler(t *testing.T) {
+	var records []slog.Record
+
+	h := NewSamplingHandler(recordHandler{&records}, SamplingOptions{
+		Limit: 2,
+		Level: LevelDebug,
+		Keys:  []string{"interface"},
+	})
+
+	start := time.Now()
+
+	log := func(d time.Duration, level slog.Level, msg, iface string) {
+		r := slog.NewRecord(start.Add(d), level, msg, 0)
+		r.AddAttrs(slog.String("interface", iface), slog.Int("rx", int(d)))
+		h.Handle(context.Background(), r)
+	}
+
+	for i := range 5 {
+		d := time.Duration(i) * time.Second
+
+		log(d, slog.LevelDebug, "Updated interface", "eth0")
+		log(d, slog.LevelDebug, "Updated interface", "wlan0")
+		log(d, slog.LevelInfo, "Published", "eth0")
+	}
+
+	count := func(msg string) (n int) {
+		for _, r := range records {
+			if r.Message == msg {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	if n := count("Updated interface"); n != 4 {
+		t.Errorf("Debug: want 4 messages, got %d", n)
+	}
+
+	if n := count("Published"); n != 5 {
+		t.Errorf("Info: want 5 messages, got %d", n)
+	}
+
+	records = records[:0]
+
+	log(time.Minute, slog.LevelDebug, "Updated interface", "eth0")
+
+	if len(records) != 1 {
+		t.Fatalf("Next period: want 1 message, got %d", len(records))
+	}
+
+	var dropped int64
+
+	records[0].Attrs(func(a Attr) bool {
+		if a.Key == "dropped" {
+			dropped = a.Value.Int64()
+		}
+
+		return true
+	})
+
+	if dropped != 3 {
+		t.Errorf("Next period: want 3 dropped, got %d", dropped)
+	}
+}
+
+func TestSamplingHandlerDisabled(t *testing.T) {
+	h := recordHandler{new([]slog.Record)}
+
+	if got := NewSamplingHandler(h, SamplingOptions{}); got != Handler(h) {
+		t.Errorf("Limit 0: want handler unchanged, got %T", got)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
@@ -8,21 +9,30 @@ import (
 	"runtime"
 
 	"github.com/lone-faerie/mqttop/cmd"
+	"github.com/lone-faerie/mqttop/cmd/cmdutil"
 )
 
 func main() {
 	runtime.MemProfileRate = 1
+	srv := &http.Server{Addr: "localhost:6060"}
 	go func() {
 		log.Println("starting pprof")
-		log.Println(http.ListenAndServe("localhost:6060", nil))
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+		}
 	}()
+	cmd.AddCleanup(func() { srv.Close() })
 	if err := cmd.Execute(); err != nil {
-		cmd.Error(err)
-		if exit, ok := err.(*cmd.ExitError); ok {
-			os.Exit(exit.Code)
+		var exit *cmd.ExitError
+		if !errors.As(err, &exit) || exit.Err != nil {
+			cmd.Error(err)
+		}
+
+		code := cmdutil.ExitCode(err)
+		if code == cmdutil.ExitUsage && !cmd.JSONErrors {
+			cmd.Usage()
 		}
 
-//		cmd.Error(err)
-		cmd.Usage()
+		os.Exit(code)
 	}
 }
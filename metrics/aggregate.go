@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// aggregateWindows are the windows of a [rolling] aggregate, from shortest to
+// longest.
+var aggregateWindows = [...]time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+type rollingSample struct {
+	t time.Time
+	v int64
+}
+
+// rolling is the rolling average and maximum of a value over each of the
+// aggregateWindows. The values are fixed-point numbers with 3 decimal places,
+// and samples older than the longest window are discarded.
+type rolling struct {
+	samples []rollingSample
+}
+
+// add adds the value v sampled at t, which must not be before the last sample.
+func (r *rolling) add(t time.Time, v int64) {
+	cutoff := t.Add(-aggregateWindows[len(aggregateWindows)-1])
+
+	i := 0
+	for i < len(r.samples) && !r.samples[i].t.After(cutoff) {
+		i++
+	}
+
+	if i > 0 {
+		r.samples = append(r.samples[:0], r.samples[i:]...)
+	}
+
+	r.samples = append(r.samples, rollingSample{t, v})
+}
+
+// aggregate returns the average and maximum over each window, ending at the
+// last sample. The second return value is false if there are no samples.
+func (r *rolling) aggregate() (payload.Aggregate, bool) {
+	var (
+		avgs, maxs [len(aggregateWindows)]int64
+		sum, n     int64
+		peak       int64
+		w          int
+	)
+
+	if len(r.samples) == 0 {
+		return payload.Aggregate{}, false
+	}
+
+	last := r.samples[len(r.samples)-1].t
+
+	for i := len(r.samples) - 1; i >= 0; i-- {
+		s := r.samples[i]
+
+		for w < len(aggregateWindows) && !s.t.After(last.Add(-aggregateWindows[w])) {
+			avgs[w], maxs[w] = sum/n, peak
+			w++
+		}
+
+		if w == len(aggregateWindows) {
+			break
+		}
+
+		if n == 0 || s.v > peak {
+			peak = s.v
+		}
+
+		sum += s.v
+		n++
+	}
+
+	for ; w < len(aggregateWindows); w++ {
+		avgs[w], maxs[w] = sum/n, peak
+	}
+
+	return payload.Aggregate{
+		Avg1m:  payload.Milli(avgs[0]),
+		Max1m:  payload.Milli(maxs[0]),
+		Avg5m:  payload.Milli(avgs[1]),
+		Max5m:  payload.Milli(maxs[1]),
+		Avg15m: payload.Milli(avgs[2]),
+		Max15m: payload.Milli(maxs[2]),
+	}, true
+}
+
+// aggregateOf returns the aggregate of r rounded to prec places as a
+// [payload.Optional], which is invalid if r is nil or has no samples.
+func aggregateOf(r *rolling, prec int) payload.Optional[payload.Aggregate] {
+	if r == nil {
+		return payload.Optional[payload.Aggregate]{}
+	}
+
+	a, ok := r.aggregate()
+
+	return payload.Maybe(a.Round(prec), ok)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+func TestRolling(t *testing.T) {
+	var r rolling
+
+	if _, ok := r.aggregate(); ok {
+		t.Error("aggregate: want none, got ok")
+	}
+
+	start := time.Unix(0, 0)
+
+	// One sample a minute for 20 minutes, with values 1, 2, ..., 20
+	for i := range 20 {
+		r.add(start.Add(time.Duration(i)*time.Minute), int64(i+1)*1000)
+	}
+
+	if want, got := 15, len(r.samples); got != want {
+		t.Errorf("samples: want %d, got %d", want, got)
+	}
+
+	want := payload.Aggregate{
+		Avg1m:  20000,
+		Max1m:  20000,
+		Avg5m:  18000,
+		Max5m:  20000,
+		Avg15m: 13000,
+		Max15m: 20000,
+	}
+
+	if got, ok := r.aggregate(); !ok || got != want {
+		t.Errorf("aggregate: want %+v, got %+v", want, got)
+	}
+
+	r.add(start.Add(20*time.Minute), 0)
+
+	want = payload.Aggregate{
+		Avg1m:  0,
+		Max1m:  0,
+		Avg5m:  14800,
+		Max5m:  20000,
+		Avg15m: 12600,
+		Max15m: 20000,
+	}
+
+	if got, _ := r.aggregate(); got != want {
+		t.Errorf("aggregate: want %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,555 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// audioTimeout is the maximum time each command of the audio metric may run.
+const audioTimeout = 2 * time.Second
+
+// audioBackend gets and sets the volume of the default output.
+type audioBackend interface {
+	volume(ctx context.Context) (vol int, muted bool, err error)
+	setVolume(ctx context.Context, vol int) error
+	setMuted(ctx context.Context, muted bool) error
+}
+
+// percent returns the first percentage in b, such as "40" from "[40%]".
+func percent(b []byte) (int, bool) {
+	end := bytes.IndexByte(b, '%')
+	if end < 0 {
+		return 0, false
+	}
+
+	start := end
+	for start > 0 && '0' <= b[start-1] && b[start-1] <= '9' {
+		start--
+	}
+
+	if start == end {
+		return 0, false
+	}
+
+	v, err := strconv.Atoi(string(b[start:end]))
+
+	return v, err == nil
+}
+
+// pulseBackend uses pactl, which works with both PulseAudio and PipeWire.
+type pulseBackend struct{}
+
+func (pulseBackend) volume(ctx context.Context) (vol int, muted bool, err error) {
+	out, err := runCommand(ctx, "pactl", "get-sink-volume", "@DEFAULT_SINK@")
+	if err != nil {
+		return
+	}
+
+	vol, ok := percent(out)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected pactl output %q", out)
+	}
+
+	if out, err = runCommand(ctx, "pactl", "get-sink-mute", "@DEFAULT_SINK@"); err != nil {
+		return
+	}
+
+	_, val, _ := bytes.Cut(out, []byte{':'})
+	muted = string(bytes.TrimSpace(val)) == "yes"
+
+	return
+}
+
+func (pulseBackend) setVolume(ctx context.Context, vol int) error {
+	_, err := runCommand(ctx, "pactl", "set-sink-volume", "@DEFAULT_SINK@", strconv.Itoa(vol)+"%")
+	return err
+}
+
+func (pulseBackend) setMuted(ctx context.Context, muted bool) error {
+	arg := "0"
+	if muted {
+		arg = "1"
+	}
+
+	_, err := runCommand(ctx, "pactl", "set-sink-mute", "@DEFAULT_SINK@", arg)
+
+	return err
+}
+
+// alsaBackend uses amixer with the mixer control.
+type alsaBackend struct {
+	control string
+}
+
+func (a alsaBackend) volume(ctx context.Context) (vol int, muted bool, err error) {
+	out, err := runCommand(ctx, "amixer", "get", a.control)
+	if err != nil {
+		return
+	}
+
+	// The volume and switch of the first channel, e.g.
+	//	Front Left: Playback 35 [40%] [-39.00dB] [on]
+	for line := range bytes.Lines(out) {
+		if bytes.Contains(line, []byte("Playback")) && bytes.Contains(line, []byte("%]")) {
+			vol, _ = percent(line)
+			muted = bytes.Contains(line, []byte("[off]"))
+
+			return
+		}
+	}
+
+	return 0, false, fmt.Errorf("unexpected amixer output %q", out)
+}
+
+func (a alsaBackend) setVolume(ctx context.Context, vol int) error {
+	_, err := runCommand(ctx, "amixer", "-q", "set", a.control, strconv.Itoa(vol)+"%")
+	return err
+}
+
+func (a alsaBackend) setMuted(ctx context.Context, muted bool) error {
+	arg := "unmute"
+	if muted {
+		arg = "mute"
+	}
+
+	_, err := runCommand(ctx, "amixer", "-q", "set", a.control, arg)
+
+	return err
+}
+
+// nowPlaying is the MPRIS metadata of the media that is playing.
+type nowPlaying struct {
+	player string
+	status string
+	artist string
+	title  string
+	album  string
+}
+
+const playerctlFormat = "{{playerName}}\t{{lc(status)}}\t{{artist}}\t{{title}}\t{{album}}"
+
+// readNowPlaying returns the metadata of the current player using playerctl.
+// If there are no players, the metadata is empty.
+func readNowPlaying(ctx context.Context) (np nowPlaying, err error) {
+	out, err := runCommand(ctx, "playerctl", "metadata", "--format", playerctlFormat)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// playerctl exits with 1 if there are no players.
+			return np, nil
+		}
+
+		return
+	}
+
+	f := bytes.SplitN(bytes.TrimRight(out, "\n"), []byte{'\t'}, 5)
+	if len(f) < 5 {
+		return np, fmt.Errorf("unexpected playerctl output %q", out)
+	}
+
+	np = nowPlaying{
+		player: string(f[0]),
+		status: string(f[1]),
+		artist: string(f[2]),
+		title:  string(f[3]),
+		album:  string(f[4]),
+	}
+
+	return
+}
+
+// Audio implements the [Metric] interface to provide the audio metrics of a
+// desktop. This includes the volume and mute state of the default output and,
+// if playerctl is installed, the MPRIS metadata of the media that is playing.
+// If enabled by the controls config, the volume and mute state may be set.
+type Audio struct {
+	backend    audioBackend
+	nowPlaying bool
+	control    bool
+
+	volume  int
+	muted   bool
+	playing nowPlaying
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewAudio returns a new [Audio] initialized from cfg. If neither pactl or amixer
+// are installed, a non-nil error that wraps [ErrNotSupported] is returned.
+func NewAudio(cfg *config.Config) (*Audio, error) {
+	return NewAudioFromConfig(cfg.Audio, DefaultsOf(cfg))
+}
+
+// NewAudioFromConfig is like [NewAudio] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewAudioFromConfig(cfg config.AudioConfig, d Defaults) (*Audio, error) {
+	a := &Audio{}
+
+	pulse := func() bool {
+		if _, err := lookPath("pactl"); err != nil {
+			return false
+		}
+
+		a.backend = pulseBackend{}
+
+		return true
+	}
+	alsa := func() bool {
+		if _, err := lookPath("amixer"); err != nil {
+			return false
+		}
+
+		control := cfg.Control
+		if control == "" {
+			control = "Master"
+		}
+
+		a.backend = alsaBackend{control}
+
+		return true
+	}
+
+	switch cfg.Backend {
+	case "", "auto":
+		_ = pulse() || alsa()
+	case "pulse", "pulseaudio", "pipewire":
+		pulse()
+	case "alsa":
+		alsa()
+	default:
+		return nil, errNotSupported(a.Type(), fmt.Errorf("unknown audio backend %q", cfg.Backend))
+	}
+
+	if a.backend == nil {
+		return nil, errNotSupported(a.Type(), exec.ErrNotFound)
+	}
+
+	if cfg.NowPlaying {
+		if _, err := lookPath("playerctl"); err == nil {
+			a.nowPlaying = true
+		} else {
+			log.Debug("playerctl not found, not including now playing")
+		}
+	}
+
+	a.control = d.Controls.Volume
+
+	if cfg.Interval > 0 {
+		a.interval = cfg.Interval
+	} else {
+		a.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		a.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		a.topic = d.BaseTopic + "/metric/audio"
+	} else {
+		a.topic = "mqttop/metric/audio"
+	}
+
+	return a, nil
+}
+
+// Type returns the metric type, "audio".
+func (*Audio) Type() string {
+	return "audio"
+}
+
+// Topic returns the topic to publish audio metrics to.
+func (a *Audio) Topic() string {
+	return a.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (a *Audio) SetInterval(d time.Duration) {
+	a.mu.Lock()
+
+	if a.tick != nil && d != a.interval {
+		a.tick.Reset(d)
+	}
+
+	a.interval = d
+
+	a.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (a *Audio) Interval() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.interval
+}
+
+func (a *Audio) loop(ctx context.Context) {
+	defer recoverLoop(a.Type())
+
+	a.mu.Lock()
+	a.tick = time.NewTicker(a.interval)
+	a.mu.Unlock()
+
+	defer a.tick.Stop()
+	defer close(a.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("audio started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.tick.C:
+			err = a.Update()
+			if err == ErrNoChange {
+				log.Debug("audio updated, no change")
+			} else {
+				log.Debug("audio updated")
+			}
+
+			ch = a.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the audio updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (a *Audio) Start(ctx context.Context) (err error) {
+	if a.interval == 0 {
+		log.Warn("Audio interval is 0, not starting")
+		return
+	}
+
+	a.once.Do(func() {
+		ctx, a.stop = context.WithCancel(ctx)
+		a.ch = make(chan error)
+
+		go a.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the audio metric to update. The returned error will not
+// be sent on the channel returned by [Audio.Updated] unlike updates that
+// happen automatically every update interval.
+func (a *Audio) Update() (err error) {
+	defer errUpdate(a.Type(), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), audioTimeout)
+	defer cancel()
+
+	vol, muted, err := a.backend.volume(ctx)
+	if err != nil {
+		return err
+	}
+
+	var np nowPlaying
+
+	if a.nowPlaying {
+		if np, err = readNowPlaying(ctx); err != nil {
+			log.WarnError("can't update now playing", err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if vol == a.volume && muted == a.muted && np == a.playing {
+		return ErrNoChange
+	}
+
+	a.volume = vol
+	a.muted = muted
+	a.playing = np
+
+	return nil
+}
+
+// SetVolume sets the volume of the default output, clamped from 0 to 100. An
+// error wrapping [ErrNotPermitted] is returned if volume control is not enabled.
+func (a *Audio) SetVolume(vol int) error {
+	if !a.control {
+		return errNotPermitted("volume")
+	}
+
+	vol = min(max(vol, 0), 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), audioTimeout)
+	defer cancel()
+
+	if err := a.backend.setVolume(ctx, vol); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.volume = vol
+	a.mu.Unlock()
+
+	return nil
+}
+
+// SetMuted mutes or unmutes the default output. An error wrapping [ErrNotPermitted]
+// is returned if volume control is not enabled.
+func (a *Audio) SetMuted(muted bool) error {
+	if !a.control {
+		return errNotPermitted("volume")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), audioTimeout)
+	defer cancel()
+
+	if err := a.backend.setMuted(ctx, muted); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.muted = muted
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Commands implements [Commander]. The volume and mute state may only be set
+// if enabled by the controls config.
+func (a *Audio) Commands() map[string]Command {
+	if !a.control {
+		return nil
+	}
+
+	return map[string]Command{
+		"volume/set": func(payload []byte) error {
+			vol, err := strconv.ParseFloat(string(bytes.TrimSpace(payload)), 64)
+			if err != nil {
+				return fmt.Errorf("invalid volume payload %q", payload)
+			}
+
+			return a.SetVolume(int(vol + 0.5))
+		},
+		"mute/set": func(payload []byte) error {
+			muted, err := ParseSwitch(payload)
+			if err != nil {
+				return err
+			}
+
+			return a.SetMuted(muted)
+		},
+	}
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (a *Audio) Updated() <-chan error {
+	return a.ch
+}
+
+// Stop stops the Audio from continuing to update. Once stopped, the Audio
+// may not be restarted.
+func (a *Audio) Stop() {
+	a.mu.Lock()
+
+	if a.stop != nil {
+		a.stop()
+	}
+
+	a.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the volume and the media that is
+// playing, if any.
+func (a *Audio) String() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	s := strconv.Itoa(a.volume) + "%"
+	if a.muted {
+		s += " (muted)"
+	}
+
+	if a.playing.title != "" {
+		s += ", " + a.playing.status + ": " + a.playing.title
+	}
+
+	return s
+}
+
+func (a *Audio) toPayload(p *payload.Audio) {
+	p.Volume = a.volume
+	p.Muted = a.muted
+	p.Player = a.playing.player
+	p.Status = a.playing.status
+	p.Artist = a.playing.artist
+	p.Title = a.playing.title
+	p.Album = a.playing.album
+}
+
+func (a *Audio) fromPayload(p *payload.Audio) {
+	a.volume = p.Volume
+	a.muted = p.Muted
+	a.playing = nowPlaying{
+		player: p.Player,
+		status: p.Status,
+		artist: p.Artist,
+		title:  p.Title,
+		album:  p.Album,
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of a to b.
+func (a *Audio) AppendText(b []byte) ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var p payload.Audio
+
+	a.toPayload(&p)
+
+	return p.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Audio.AppendText](nil).
+func (a *Audio) MarshalJSON() ([]byte, error) {
+	return a.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of audio, as produced by [Audio.MarshalJSON], into a.
+func (a *Audio) UnmarshalJSON(data []byte) error {
+	var p payload.Audio
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.fromPayload(&p)
+	a.mu.Unlock()
+
+	return nil
+}
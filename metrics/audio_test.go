@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+const (
+	pactlVolume = "Volume: front-left: 26214 /  40% / -23.88 dB,   front-right: 26214 /  40% / -23.88 dB\n        balance 0.00\n"
+	amixerGet   = `Simple mixer control 'Master',0
+  Capabilities: pvolume pswitch pswitch-joined
+  Playback channels: Front Left - Front Right
+  Limits: Playback 0 - 87
+  Mono:
+  Front Left: Playback 61 [70%] [-19.50dB] [off]
+  Front Right: Playback 61 [70%] [-19.50dB] [off]
+`
+)
+
+func TestNewAudio(t *testing.T) {
+	var tests = []struct {
+		name       string
+		backend    string
+		installed  []string
+		want       audioBackend
+		nowPlaying bool
+		wantErr    error
+	}{
+		{"Auto", "", []string{"pactl", "amixer", "playerctl"}, pulseBackend{}, true, nil},
+		{"AutoALSA", "auto", []string{"amixer"}, alsaBackend{"Master"}, false, nil},
+		{"PipeWire", "pipewire", []string{"pactl"}, pulseBackend{}, false, nil},
+		{"ALSA", "alsa", []string{"pactl", "amixer"}, alsaBackend{"Master"}, false, nil},
+		{"NotInstalled", "alsa", []string{"pactl"}, nil, false, ErrNotSupported},
+		{"None", "", nil, nil, false, ErrNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeExec{installed: tt.installed}
+			f.install(t)
+
+			cfg := config.Default()
+			cfg.Audio.Backend = tt.backend
+
+			a, err := NewAudio(cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wanted error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if a.backend != tt.want {
+				t.Errorf("Wanted backend %#v, got %#v", tt.want, a.backend)
+			}
+			if a.nowPlaying != tt.nowPlaying {
+				t.Errorf("Wanted now playing %v, got %v", tt.nowPlaying, a.nowPlaying)
+			}
+		})
+	}
+
+	cfg := config.Default()
+	cfg.Audio.Backend = "oss"
+
+	if _, err := NewAudio(cfg); err == nil {
+		t.Error("Wanted error for unknown backend, got nil")
+	}
+}
+
+func TestAudio_Update(t *testing.T) {
+	f := &fakeExec{
+		installed: []string{"pactl", "playerctl"},
+		out: map[string]string{
+			"pactl get-sink-volume @DEFAULT_SINK@":           pactlVolume,
+			"pactl get-sink-mute @DEFAULT_SINK@":             "Mute: no\n",
+			"playerctl metadata --format " + playerctlFormat: "spotify\tplaying\tBoards of Canada\tRoygbiv\tMusic Has the Right to Children\n",
+		},
+	}
+	f.install(t)
+
+	a, err := NewAudio(config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "40%, playing: Roygbiv", a.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+
+	b, err := a.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Audio
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	want := payload.Audio{
+		Volume: 40,
+		Player: "spotify",
+		Status: "playing",
+		Artist: "Boards of Canada",
+		Title:  "Roygbiv",
+		Album:  "Music Has the Right to Children",
+	}
+	if p != want {
+		t.Errorf("MarshalJSON: want %+v, got %+v", want, p)
+	}
+
+	if err := a.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	// No players
+	delete(f.out, "playerctl metadata --format "+playerctlFormat)
+	f.out["pactl get-sink-mute @DEFAULT_SINK@"] = "Mute: yes\n"
+
+	runCommand = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		if name == "playerctl" {
+			return nil, &exec.ExitError{}
+		}
+
+		return []byte(f.out[strings.Join(append([]string{name}, arg...), " ")]), nil
+	}
+
+	if err := a.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "40% (muted)", a.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+}
+
+func TestAudio_UpdateALSA(t *testing.T) {
+	f := &fakeExec{
+		installed: []string{"amixer"},
+		out: map[string]string{
+			"amixer get PCM": amixerGet,
+		},
+	}
+	f.install(t)
+
+	cfg := config.Default()
+	cfg.Audio.Control = "PCM"
+
+	a, err := NewAudio(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "70% (muted)", a.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+}
+
+func TestAudio_Commands(t *testing.T) {
+	f := &fakeExec{
+		installed: []string{"pactl"},
+		out: map[string]string{
+			"pactl set-sink-volume @DEFAULT_SINK@ 0%":   "",
+			"pactl set-sink-volume @DEFAULT_SINK@ 43%":  "",
+			"pactl set-sink-volume @DEFAULT_SINK@ 100%": "",
+			"pactl set-sink-mute @DEFAULT_SINK@ 1":      "",
+		},
+	}
+	f.install(t)
+
+	cfg := config.Default()
+
+	a, err := NewAudio(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmds := a.Commands(); len(cmds) != 0 {
+		t.Errorf("Commands: want none without volume control, got %d", len(cmds))
+	}
+	if err := a.SetVolume(50); !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("SetVolume: want %v, got %v", ErrNotPermitted, err)
+	}
+
+	cfg.Controls.Volume = true
+
+	if a, err = NewAudio(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := a.Commands()
+
+	var tests = []struct {
+		cmd     string
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{"volume/set", "42.6", "pactl set-sink-volume @DEFAULT_SINK@ 43%", false},
+		{"volume/set", "150", "pactl set-sink-volume @DEFAULT_SINK@ 100%", false},
+		{"volume/set", "-5", "pactl set-sink-volume @DEFAULT_SINK@ 0%", false},
+		{"volume/set", "loud", "", true},
+		{"mute/set", "ON", "pactl set-sink-mute @DEFAULT_SINK@ 1", false},
+		{"mute/set", "maybe", "", true},
+	}
+	for _, tt := range tests {
+		f.ran = nil
+
+		err := cmds[tt.cmd]([]byte(tt.payload))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %q: Wanted error %v, got %v", tt.cmd, tt.payload, tt.wantErr, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if len(f.ran) != 1 || f.ran[0] != tt.want {
+			t.Errorf("%s %q: Wanted command %q, got %q", tt.cmd, tt.payload, tt.want, f.ran)
+		}
+	}
+
+	if want, got := "0% (muted)", a.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+}
@@ -2,15 +2,15 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
 	"github.com/lone-faerie/mqttop/sysfs"
 )
 
@@ -25,6 +25,7 @@ const (
 	batteryVoltage
 	batteryTime
 	batteryStatus
+	batteryTimeToFull
 )
 
 func (f batteryFlag) Has(flags batteryFlag) bool {
@@ -66,12 +67,16 @@ func (f batteryFlag) String() string {
 		s = append(s, "status")
 	}
 
+	if f.Has(batteryTimeToFull) {
+		s = append(s, "time_to_full")
+	}
+
 	return fmt.Sprintf("%s (%08b)", strings.Join(s, "|"), f)
 }
 
 // Battery implements the [Metric] interface to provide the system battery
-// metrics. This includes the kind, status, capacity, power, and time remaining
-// of the battery.
+// metrics. This includes the kind, status, capacity, power, voltage, current,
+// and time remaining of the battery.
 type Battery struct {
 	bat *sysfs.Batt
 
@@ -86,6 +91,7 @@ type Battery struct {
 	voltage       int64
 	status        string
 	timeRemaining time.Duration
+	timeToFull    time.Duration
 
 	flags   batteryFlag
 	updates batteryFlag
@@ -94,6 +100,10 @@ type Battery struct {
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
+	prec     int           // precision of the power, see [places]
+	timeUnit time.Duration // unit of the time sensors, see [config.BatteryConfig]
+	capCal   config.Calibration
+	voltCal  config.Calibration
 
 	mu   sync.RWMutex
 	once sync.Once
@@ -104,9 +114,15 @@ type Battery struct {
 // NewBattery returns a new [Battery] initialized from cfg. If there is no
 // battery on the system, a non-nil error that wraps [ErrNotSupported] is returned.
 func NewBattery(cfg *config.Config) (*Battery, error) {
+	return NewBatteryFromConfig(cfg.Battery, DefaultsOf(cfg))
+}
+
+// NewBatteryFromConfig is like [NewBattery] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewBatteryFromConfig(cfg config.BatteryConfig, d Defaults) (*Battery, error) {
 	b := &Battery{}
 
-	bat, err := sysfs.GetBattery()
+	bat, err := sysfs.NewFS(d.Root).GetBattery()
 	if err != nil {
 		return nil, errNotSupported(b.Type(), err)
 	}
@@ -115,20 +131,34 @@ func NewBattery(cfg *config.Config) (*Battery, error) {
 
 	b.setFlags()
 
-	if cfg.Battery.Interval > 0 {
-		b.interval = cfg.Battery.Interval
-	} else {
+	if cfg.Interval > 0 {
 		b.interval = cfg.Interval
+	} else {
+		b.interval = d.Interval
 	}
 
-	if cfg.Battery.Topic != "" {
-		b.topic = cfg.Battery.Topic
-	} else if cfg.BaseTopic != "" {
-		b.topic = cfg.BaseTopic + "/metric/battery"
+	if cfg.Topic != "" {
+		b.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		b.topic = d.BaseTopic + "/metric/battery"
 	} else {
 		b.topic = "mqttop/metric/battery"
 	}
 
+	b.prec = d.precision(cfg.Precision)
+	b.capCal = cfg.CapacityCalibration
+	b.voltCal = cfg.VoltageCalibration
+
+	switch cfg.TimeFormat {
+	case "", "seconds":
+		b.timeUnit = time.Second
+	case "minutes":
+		b.timeUnit = time.Minute
+	default:
+		log.WarnError("Using seconds for battery time", config.InvalidValue("battery.time_format", cfg.TimeFormat))
+		b.timeUnit = time.Second
+	}
+
 	return b, nil
 }
 
@@ -165,6 +195,17 @@ func (b *Battery) setFlags() {
 	b.setFlag(b.bat.HasVoltage, batteryVoltage)
 	b.setFlag(b.bat.HasTimeRemaining, batteryTime)
 	b.setFlag(b.bat.HasStatus, batteryStatus)
+
+	// The time to full is estimated from the energy and power, or the
+	// charge and current.
+	const (
+		energyPower   = batteryEnergy | batteryPower
+		chargeCurrent = batteryCharge | batteryCurrent
+	)
+
+	if b.flags&energyPower == energyPower || b.flags&chargeCurrent == chargeCurrent {
+		b.flags |= batteryTimeToFull
+	}
 }
 
 // Type returns the metric type, "battery".
@@ -190,7 +231,17 @@ func (b *Battery) SetInterval(d time.Duration) {
 	b.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (b *Battery) Interval() time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.interval
+}
+
 func (b *Battery) loop(ctx context.Context) {
+	defer recoverLoop(b.Type())
+
 	b.mu.Lock()
 	b.tick = time.NewTicker(b.interval)
 	b.mu.Unlock()
@@ -251,11 +302,13 @@ func (b *Battery) updateCapacity() (err error) {
 			return
 		}
 
-		if int(now) != b.capacity {
+		capacity := b.calibrateCapacity(now)
+
+		if capacity != b.capacity {
 			b.changes |= batteryCapacity
 		}
 
-		b.capacity = int(now)
+		b.capacity = capacity
 		b.updates |= batteryCapacity
 
 		return nil
@@ -277,11 +330,21 @@ func (b *Battery) updateCapacity() (err error) {
 		return nil
 	}
 
-	b.capacity = int(100 * now / full)
+	b.capacity = b.calibrateCapacity(100 * now / full)
 
 	return nil
 }
 
+// calibrateCapacity returns capacity corrected by the capacity calibration of
+// b, kept from 0 to 100.
+func (b *Battery) calibrateCapacity(capacity int64) int {
+	if b.capCal.IsZero() {
+		return int(capacity)
+	}
+
+	return int(min(max(calibrate(b.capCal, capacity, 1), 0), 100))
+}
+
 func (b *Battery) updateCharge() error {
 	if b.updates.Has(batteryCharge) {
 		return nil
@@ -374,6 +437,8 @@ func (b *Battery) updateVoltage() error {
 		return err
 	}
 
+	v = calibrate(b.voltCal, v, 1e6)
+
 	if v != b.voltage {
 		b.changes |= batteryVoltage
 	}
@@ -384,12 +449,26 @@ func (b *Battery) updateVoltage() error {
 	return nil
 }
 
-func (b *Battery) updateTimeRemaining() error {
+// estimate returns the time to drain or fill x at the rate of y per hour, or
+// -1 if y is 0.
+func estimate(x, y uint64) time.Duration {
 	const (
 		scale    = uint64(time.Hour)
 		overflow = uint64(5124096)
 	)
 
+	if y == 0 {
+		return -1
+	}
+
+	if x < overflow {
+		return time.Duration(scale * x / y)
+	}
+
+	return time.Duration(scale / y * x)
+}
+
+func (b *Battery) updateTimeRemaining() error {
 	var x, y uint64
 
 	switch {
@@ -428,25 +507,57 @@ func (b *Battery) updateTimeRemaining() error {
 
 		b.timeRemaining = rem
 		b.updates |= batteryTime
-	}
 
-	if y == 0 {
-		b.timeRemaining = -1
 		return nil
 	}
 
-	if x < overflow {
-		b.timeRemaining = time.Duration(scale * x / y)
-	} else {
-		b.timeRemaining = time.Duration(scale / y * x)
+	b.timeRemaining = estimate(x, y)
+
+	return nil
+}
+
+// updateTimeToFull estimates the time until the battery is full from the
+// energy or charge it's missing and the power or current it's charging at.
+func (b *Battery) updateTimeToFull() error {
+	var x, y int64
+
+	switch {
+	case b.flags.Has(batteryEnergy) && b.flags.Has(batteryPower):
+		if err := b.updateEnergy(); err != nil {
+			return err
+		}
+
+		if err := b.updatePower(); err != nil {
+			return err
+		}
+
+		x = b.energyFull - b.energyNow
+		y = b.power
+	case b.flags.Has(batteryCharge) && b.flags.Has(batteryCurrent):
+		if err := b.updateCharge(); err != nil {
+			return err
+		}
+
+		if err := b.updateCurrent(); err != nil {
+			return err
+		}
+
+		x = b.chargeFull - b.chargeNow
+		y = b.current
 	}
+
+	// Some batteries report the current as negative while charging
+	b.timeToFull = estimate(uint64(max(x, 0)), uint64(max(y, -y)))
+
 	return nil
 }
 
 // Update forces the battery metric to update. The returned error will not
 // be sent on the channel returned by [Battery.Updated] unlike updates that
 // happen automatically every update interval.
-func (b *Battery) Update() error {
+func (b *Battery) Update() (err error) {
+	defer errUpdate(b.Type(), time.Now(), &err)
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -468,7 +579,23 @@ func (b *Battery) Update() error {
 
 	b.status = s
 
-	if s != "charging" && s != "full" {
+	// Only the time of the current status is estimated, so that neither
+	// is left over from the last time the status was different.
+	switch s {
+	case "charging":
+		b.timeRemaining = 0
+
+		if b.flags.Has(batteryTimeToFull) {
+			if err := b.updateTimeToFull(); err != nil {
+				return err
+			}
+		}
+	case "full":
+		b.timeRemaining = 0
+		b.timeToFull = 0
+	default:
+		b.timeToFull = 0
+
 		if err := b.updateTimeRemaining(); err != nil {
 			return err
 		}
@@ -479,7 +606,7 @@ func (b *Battery) Update() error {
 		if err := b.updatePower(); err != nil {
 			return err
 		}
-	case b.flags.Has(batteryCurrent | batteryVoltage):
+	case b.flags.Has(batteryCurrent) && b.flags.Has(batteryVoltage):
 		if err := b.updateCurrent(); err != nil {
 			return err
 		}
@@ -497,6 +624,20 @@ func (b *Battery) Update() error {
 		}
 	}
 
+	// The voltage and current are included whenever supported, even if the
+	// power is read directly.
+	if b.flags.Has(batteryVoltage) {
+		if err := b.updateVoltage(); err != nil {
+			return err
+		}
+	}
+
+	if b.flags.Has(batteryCurrent) {
+		if err := b.updateCurrent(); err != nil {
+			return err
+		}
+	}
+
 	if b.changes == 0 {
 		return ErrNoChange
 	}
@@ -531,37 +672,93 @@ func (bat *Battery) String() string {
 	return bat.bat.Kind
 }
 
-// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
-// representation of bat to b.
-func (bat *Battery) AppendText(b []byte) ([]byte, error) {
-	bat.mu.RLock()
-	defer bat.mu.RUnlock()
+func (bat *Battery) toPayload(p *payload.Battery) {
+	p.Kind = bat.bat.Kind
+	p.Status = bat.status
+	p.Capacity = payload.Maybe(bat.capacity, bat.hasCapacity())
+	p.Power = payload.Maybe(payload.Micro(bat.power).Round(places(bat.prec)), bat.flags.Has(batteryPower))
+	p.Voltage = payload.Maybe(payload.Micro(bat.voltage).Round(places(bat.prec)), bat.flags.Has(batteryVoltage))
+	p.Current = payload.Maybe(payload.Micro(bat.current).Round(places(bat.prec)), bat.flags.Has(batteryCurrent))
+	p.TimeRemaining = payload.Maybe(
+		int64(bat.timeRemaining/time.Second),
+		bat.hasTimeRemaining() && bat.timeRemaining > 0,
+	)
+	p.TimeToFull = payload.Maybe(
+		int64(bat.timeToFull/time.Second),
+		bat.flags.Has(batteryTimeToFull) && bat.timeToFull > 0,
+	)
+}
+
+func (bat *Battery) fromPayload(p *payload.Battery) {
+	if bat.bat == nil {
+		bat.bat = new(sysfs.Batt)
+	}
+
+	bat.bat.Kind = p.Kind
+	bat.kind = p.Kind
+	bat.status = p.Status
+
+	if p.Capacity.Valid {
+		bat.capacity = p.Capacity.Value
+		bat.flags |= batteryCapacity
+	}
+
+	if p.Power.Valid {
+		bat.power = int64(p.Power.Value)
+		bat.flags |= batteryPower
+	}
 
-	b = append(b, "{\"kind\": \""...)
-	b = append(b, bat.bat.Kind...)
-	b = append(b, "\", \"status\": \""...)
-	b = append(b, bat.status...)
-	b = append(b, '"')
+	if p.Voltage.Valid {
+		bat.voltage = int64(p.Voltage.Value)
+		bat.flags |= batteryVoltage
+	}
 
-	if bat.hasCapacity() {
-		b = append(b, ", \"capacity\": "...)
-		b = strconv.AppendInt(b, int64(bat.capacity), 10)
+	if p.Current.Valid {
+		bat.current = int64(p.Current.Value)
+		bat.flags |= batteryCurrent
 	}
 
-	if bat.flags.Has(batteryPower) {
-		b = append(b, ", \"power\": "...)
-		b = byteutil.AppendDecimal(b, bat.power, 6)
+	if p.TimeRemaining.Valid {
+		bat.timeRemaining = time.Duration(p.TimeRemaining.Value) * time.Second
+		bat.flags |= batteryTime
 	}
 
-	if bat.hasTimeRemaining() && bat.timeRemaining > 0 {
-		b = append(b, ", \"timeRemaining\": "...)
-		b = strconv.AppendInt(b, int64(bat.timeRemaining/time.Second), 10)
+	if p.TimeToFull.Valid {
+		bat.timeToFull = time.Duration(p.TimeToFull.Value) * time.Second
+		bat.flags |= batteryTimeToFull
 	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of bat to b.
+func (bat *Battery) AppendText(b []byte) ([]byte, error) {
+	bat.mu.RLock()
+	defer bat.mu.RUnlock()
 
-	return append(b, '}'), nil
+	var p payload.Battery
+
+	bat.toPayload(&p)
+
+	return p.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [Battery.AppendText](nil).
 func (bat *Battery) MarshalJSON() ([]byte, error) {
 	return bat.AppendText(nil)
 }
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of a battery, as produced by [Battery.MarshalJSON], into bat.
+func (bat *Battery) UnmarshalJSON(data []byte) error {
+	var p payload.Battery
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	bat.mu.Lock()
+	bat.fromPayload(&p)
+	bat.mu.Unlock()
+
+	return nil
+}
@@ -2,22 +2,20 @@ package metrics
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/discovery"
 )
 
 func testBattery(t *testing.T) (*Battery, *config.Config) {
 	t.Helper()
 
-	err := file.SetRoot("testdata/fixtures")
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
 
 	bat, err := NewBattery(cfg)
 	if err != nil {
@@ -43,7 +41,7 @@ func TestBattery(t *testing.T) {
 		t.Errorf("Interval: want %v, got %v", want, got)
 	}
 
-	flags := batteryCapacity | batteryEnergy | batteryPower | batteryStatus | batteryVoltage
+	flags := batteryCapacity | batteryEnergy | batteryPower | batteryStatus | batteryVoltage | batteryTimeToFull
 	if want, got := flags, bat.flags; got != want {
 		t.Errorf("Flags: want %v, got %v", want, got)
 	}
@@ -66,6 +64,9 @@ func TestBattery_Update(t *testing.T) {
 	if want, got := int64(4830000), bat.power; got != want {
 		t.Errorf("Power: want %v, got %v", want, got)
 	}
+	if want, got := int64(12229000), bat.voltage; got != want {
+		t.Errorf("Voltage: want %v, got %v", want, got)
+	}
 	if want, got := time.Duration(36857112450000), bat.timeRemaining; got != want {
 		t.Errorf("Time Remaining: want %v, got %v", want, got)
 	}
@@ -79,7 +80,7 @@ func TestBattery_MarshalJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	want := `{"kind":"Li-ion","status":"","capacity":0,"power":0.000000}`
+	want := `{"kind":"Li-ion","status":"","capacity":0,"power":0,"voltage":0}`
 
 	if got := string(data); got != want {
 		var i int
@@ -95,3 +96,268 @@ func TestBattery_MarshalJSON(t *testing.T) {
 		t.Errorf("result differs at char %d\nwant %q\ngot  %q", i, want[:i+1], got[:i+1])
 	}
 }
+
+func TestBattery_UnmarshalJSON(t *testing.T) {
+	bat, _ := testBattery(t)
+
+	if err := bat.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := json.Marshal(bat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Battery
+
+	if err = json.Unmarshal(want, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(want) {
+		t.Errorf("round trip differs\nwant %s\ngot  %s", want, data)
+	}
+}
+
+func TestBattery_TimeToFull(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "sys/class/power_supply/BAT0")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(files map[string]string) {
+		t.Helper()
+
+		for name, v := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(v+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 15 Wh charging at 10 W is 1.5 h until full
+	write(map[string]string{
+		"present":     "1",
+		"type":        "Battery",
+		"status":      "Charging",
+		"energy_now":  "35000000",
+		"energy_full": "50000000",
+		"power_now":   "10000000",
+	})
+
+	cfg := config.Default()
+	cfg.RootFS = root
+	cfg.Battery.TimeFormat = "minutes"
+
+	bat, err := NewBattery(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bat.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 90*time.Minute, bat.timeToFull; got != want {
+		t.Errorf("Time to Full: want %v, got %v", want, got)
+	}
+	if got := bat.timeRemaining; got != 0 {
+		t.Errorf("Time Remaining: want 0 while charging, got %v", got)
+	}
+
+	write(map[string]string{"status": "Discharging"})
+
+	if err := bat.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 210*time.Minute, bat.timeRemaining; got != want {
+		t.Errorf("Time Remaining: want %v, got %v", want, got)
+	}
+	if got := bat.timeToFull; got != 0 {
+		t.Errorf("Time to Full: want 0 while discharging, got %v", got)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	bat.Discover(d)
+
+	for _, id := range []string{d.ID("battery_time_remaining"), d.ID("battery_time_to_full")} {
+		cmp, ok := d.Components[id]
+		if !ok {
+			t.Errorf("%s: want component", id)
+			continue
+		}
+
+		if want, got := "min", cmp[discovery.UnitOfMeasurement]; got != want {
+			t.Errorf("%s: want unit %q, got %q", id, want, got)
+		}
+	}
+}
+
+func TestBattery_Calibration(t *testing.T) {
+	var tests = []struct {
+		name     string
+		files    map[string]string
+		capCal   config.Calibration
+		voltCal  config.Calibration
+		capacity int
+		voltage  int64 // µV
+		power    int64 // µW
+	}{
+		{
+			"Capacity",
+			map[string]string{"capacity": "87", "status": "Full"},
+			config.Calibration{Multiplier: 0.5},
+			config.Calibration{},
+			44, 0, 0,
+		},
+		{
+			"CapacityClamped",
+			map[string]string{"capacity": "87", "status": "Full"},
+			config.Calibration{Offset: 20},
+			config.Calibration{},
+			100, 0, 0,
+		},
+		{
+			// The power calculated from the voltage is corrected as well.
+			"Charge",
+			map[string]string{"charge_now": "3000000", "charge_full": "4000000", "current_now": "1500000", "voltage_now": "12000000", "status": "Discharging"},
+			config.Calibration{Offset: -5},
+			config.Calibration{Offset: 0.5},
+			70, 12500000, 18750000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			const dir = "sys/class/power_supply/BAT0/"
+
+			files := map[string]string{dir + "present": "1\n", dir + "type": "Battery\n"}
+			for name, v := range tt.files {
+				files[dir+name] = v + "\n"
+			}
+
+			writeFiles(t, root, files)
+
+			cfg := config.Default()
+			cfg.RootFS = root
+			cfg.Battery.CapacityCalibration = tt.capCal
+			cfg.Battery.VoltageCalibration = tt.voltCal
+
+			bat, err := NewBattery(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := bat.Update(); err != nil && err != ErrNoChange {
+				t.Fatal(err)
+			}
+
+			if bat.capacity != tt.capacity {
+				t.Errorf("Capacity: want %d, got %d", tt.capacity, bat.capacity)
+			}
+			if bat.voltage != tt.voltage {
+				t.Errorf("Voltage: want %d, got %d", tt.voltage, bat.voltage)
+			}
+			if bat.power != tt.power {
+				t.Errorf("Power: want %d, got %d", tt.power, bat.power)
+			}
+		})
+	}
+}
+
+func TestBattery_Flags(t *testing.T) {
+	var tests = []struct {
+		name     string
+		files    map[string]string
+		flags    batteryFlag
+		capacity int
+	}{
+		{
+			"Capacity",
+			map[string]string{"capacity": "87", "status": "Full"},
+			batteryCapacity | batteryStatus,
+			87,
+		},
+		{
+			"Charge",
+			map[string]string{"charge_now": "3000000", "charge_full": "4000000", "current_now": "1500000", "voltage_now": "12000000", "status": "Discharging"},
+			batteryCharge | batteryCurrent | batteryVoltage | batteryStatus | batteryTimeToFull,
+			75,
+		},
+		{
+			"Energy",
+			map[string]string{"energy_now": "20000000", "energy_full": "50000000", "power_now": "10000000", "status": "Charging"},
+			batteryEnergy | batteryPower | batteryStatus | batteryTimeToFull,
+			40,
+		},
+		{
+			// The capacity is preferred over the charge and energy.
+			"CapacityAndEnergy",
+			map[string]string{"capacity": "55", "energy_now": "20000000", "energy_full": "50000000", "status": "Full"},
+			batteryCapacity | batteryEnergy | batteryStatus,
+			55,
+		},
+		{
+			// Without both now and full, the charge and energy aren't used.
+			"Partial",
+			map[string]string{"charge_now": "3000000", "energy_full": "50000000", "time_to_empty": "3600", "status": "Discharging"},
+			batteryTime | batteryStatus,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "sys/class/power_supply/BAT0")
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			files := map[string]string{"present": "1", "type": "Battery"}
+			for name, v := range tt.files {
+				files[name] = v
+			}
+
+			for name, v := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(v+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg := config.Default()
+			cfg.RootFS = root
+
+			bat, err := NewBattery(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bat.flags != tt.flags {
+				t.Errorf("Flags: want %09b, got %09b", tt.flags, bat.flags)
+			}
+
+			if err := bat.Update(); err != nil && err != ErrNoChange {
+				t.Fatal(err)
+			}
+
+			if bat.capacity != tt.capacity {
+				t.Errorf("Capacity: want %d, got %d", tt.capacity, bat.capacity)
+			}
+		})
+	}
+}
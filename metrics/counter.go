@@ -0,0 +1,28 @@
+package metrics
+
+// Counter is the monotonically increasing total of a cumulative counter of the
+// kernel, such as the bytes received by a network interface. Unlike the counter
+// itself, the total keeps increasing if the counter is reset, and may be saved
+// and restored with [Counterer] so that it keeps increasing across restarts.
+type Counter struct {
+	// Total is the total increase of the counter.
+	Total uint64 `json:"total"`
+	// Last is the last value of the counter.
+	Last uint64 `json:"last"`
+}
+
+// Update adds the increase from the last value of the counter to v to the
+// total and returns the increase. If v is less than the last value, the
+// counter is assumed to have been reset, such as by a reboot, and all of v is
+// the increase.
+func (c *Counter) Update(v uint64) uint64 {
+	d := v
+	if v >= c.Last {
+		d = v - c.Last
+	}
+
+	c.Total += d
+	c.Last = v
+
+	return d
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import "testing"
+
+func TestCounter(t *testing.T) {
+	var c Counter
+
+	for _, tt := range []struct {
+		v, delta, total uint64
+	}{
+		{100, 100, 100},
+		{150, 50, 150},
+		{150, 0, 150},
+		{20, 20, 170}, // reset
+		{30, 10, 180},
+	} {
+		if got := c.Update(tt.v); got != tt.delta {
+			t.Errorf("Update(%d): want %d, got %d", tt.v, tt.delta, got)
+		}
+		if c.Total != tt.total {
+			t.Errorf("Update(%d): want total %d, got %d", tt.v, tt.total, c.Total)
+		}
+	}
+}
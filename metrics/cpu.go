@@ -3,8 +3,10 @@ package metrics
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"math/rand/v2"
 	"runtime"
 	"slices"
@@ -16,6 +18,7 @@ import (
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
 	"github.com/lone-faerie/mqttop/procfs"
 	"github.com/lone-faerie/mqttop/sysfs"
 )
@@ -23,15 +26,14 @@ import (
 type cpuCore struct {
 	logical  int
 	physical int
-	baseFreq int64
-	currFreq int64
-	minFreq  int64
-	maxFreq  int64
 	freq     sysfs.CPUFreq
 	temp     *sysfs.Sensor
 	total    uint64
 	idle     uint64
 	percent  int
+
+	windowTotal uint64
+	windowIdle  uint64
 }
 
 var (
@@ -44,6 +46,8 @@ const (
 	cpuTemperature cpuFlag = 1 << iota
 	cpuFrequency
 	cpuUsage
+	cpuBoost
+	cpuGovernor
 )
 
 func (f cpuFlag) Has(flags cpuFlag) bool {
@@ -54,26 +58,62 @@ func (f cpuFlag) Has(flags cpuFlag) bool {
 // metrics. This includes the usage, frequency, and temperature of the CPU
 // and each of its cores.
 type CPU struct {
-	Name    string
-	cores   []cpuCore
-	temps   []sysfs.Sensor
-	temp    *sysfs.Sensor
-	coremap []int
+	Name  string
+	cores []cpuCore
+	shown []int // indices of the reported cores, or nil if per-core data is disabled
+	temps []sysfs.Sensor
+	temp  *sysfs.Sensor
+	index map[int]int // index of each core by its logical id
 
 	total   uint64
 	idle    uint64
 	percent int
 
+	// The usage is sampled every sample interval, and the usage of each update
+	// is the average since the jiffies at the start of the window.
+	windowTotal uint64
+	windowIdle  uint64
+	samples     int
+	sampleMin   int
+	sampleMax   int
+	usageMin    int
+	usageMax    int
+
+	// The rolling aggregates are nil unless enabled
+	usageAggregate *rolling
+	tempAggregate  *rolling
+
 	flags cpuFlag
 
+	boost        *sysfs.CPUBoost
+	boostEnabled bool
+	boostControl bool
+
+	governor        string
+	governors       []string
+	governorControl bool
+
+	proc procfs.FS
+	sys  sysfs.FS
+
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
+	prec     int // precision of the temperatures and frequencies, see [places]
+	tempCal  config.Calibration
+
+	sampleInterval time.Duration
+	sampleTick     *time.Ticker
 
-	selectFn   func() (temp, freq int64)
+	selectFn   func(*CPU) (temp, freq int64)
 	selectMode string
 	rand       *rand.Rand
 
+	payload payload.CPU
+	info    payload.CPUInfo
+	last    []byte // encoded payload of the last update
+	buf     []byte
+
 	mu   sync.RWMutex
 	once sync.Once
 	stop context.CancelFunc
@@ -84,36 +124,73 @@ type CPU struct {
 // encountered while initializing the CPU, a non-nil error that wraps [ErrNotSupported]
 // is returned.
 func NewCPU(cfg *config.Config) (*CPU, error) {
+	return NewCPUFromConfig(cfg.CPU, DefaultsOf(cfg))
+}
+
+// NewCPUFromConfig is like [NewCPU] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewCPUFromConfig(cfg config.CPUConfig, d Defaults) (*CPU, error) {
 	c := &CPU{
-		Name:  cfg.CPU.Name,
-		cores: make([]cpuCore, coreCount),
+		Name:  cfg.Name,
+		cores: make([]cpuCore, 0, coreCount),
+		proc:  procfs.NewFS(d.Root),
+		sys:   sysfs.NewFS(d.Root),
 	}
 
 	if err := c.init(); err != nil {
 		return nil, errNotSupported(c.Type(), err)
 	}
 
-	c.setSelectionMode(cfg.CPU.SelectionMode)
-	if c.selectFn == nil {
-		c.selectMode = "auto"
-		c.selectFn = c.SelectAuto
+	c.filterCores(&cfg)
+
+	if c.flags.Has(cpuBoost) && d.Controls.Boost {
+		if c.boost.Writable() {
+			c.boostControl = true
+		} else {
+			log.Warn("Boost control is enabled but not permitted, try running as root", "path", c.boost.Path)
+		}
 	}
 
-	if cfg.CPU.Interval > 0 {
-		c.interval = cfg.CPU.Interval
-	} else {
+	if c.flags.Has(cpuGovernor) && d.Controls.Governor {
+		if c.governorWritable() {
+			c.governorControl = true
+		} else {
+			log.Warn("Governor control is enabled but not permitted, try running as root")
+		}
+	}
+
+	if !c.setSelectionMode(strings.ToLower(cfg.SelectionMode)) {
+		log.Warn("Unknown selection mode, using auto", "mode", cfg.SelectionMode)
+		c.setSelectionMode("auto")
+	}
+
+	if cfg.Interval > 0 {
 		c.interval = cfg.Interval
+	} else {
+		c.interval = d.Interval
+	}
+
+	c.prec = d.precision(cfg.Precision)
+	c.tempCal = cfg.TemperatureCalibration
+
+	if cfg.SampleInterval > 0 && c.flags.Has(cpuUsage) {
+		c.sampleInterval = cfg.SampleInterval
 	}
 
-	if cfg.CPU.Topic != "" {
-		c.topic = cfg.CPU.Topic
-	} else if cfg.BaseTopic != "" {
-		c.topic = cfg.BaseTopic + "/metric/cpu"
+	if cfg.Aggregate {
+		c.usageAggregate = new(rolling)
+		c.tempAggregate = new(rolling)
+	}
+
+	if cfg.Topic != "" {
+		c.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		c.topic = d.BaseTopic + "/metric/cpu"
 	} else {
 		c.topic = "mqttop/metric/cpu"
 	}
 
-	c.Name = cfg.CPU.FormatName(c.Name)
+	c.Name = cfg.FormatName(c.Name)
 
 	return c, nil
 }
@@ -133,11 +210,48 @@ func (c *CPU) init() (err error) {
 
 	c.flags |= cpuUsage
 
+	if c.boost, err = c.sys.FindCPUBoost(); err == nil {
+		c.flags |= cpuBoost
+	}
+
+	if c.flags.Has(cpuFrequency) && len(c.cores) > 0 {
+		if c.governors, err = c.cores[0].freq.AvailableGovernors(); err == nil {
+			c.flags |= cpuGovernor
+		}
+	}
+
 	return nil
 }
 
+// filterCores sets the indices of the cores that are reported in the payload
+// and discovery, according to cfg.PerCore, cfg.Cores and cfg.MaxCores.
+func (c *CPU) filterCores(cfg *config.CPUConfig) {
+	if !cfg.PerCore {
+		c.shown = nil
+		log.Debug("filterCores", "hidden", len(c.cores))
+
+		return
+	}
+
+	c.shown = make([]int, 0, len(c.cores))
+
+	for i := range c.cores {
+		if cfg.MaxCores > 0 && len(c.shown) >= cfg.MaxCores {
+			break
+		}
+
+		if cfg.Cores.Contains(c.cores[i].logical) {
+			c.shown = append(c.shown, i)
+		}
+	}
+
+	if n := len(c.cores) - len(c.shown); n > 0 {
+		log.Debug("filterCores", "hidden", n)
+	}
+}
+
 func (c *CPU) parseInfo() error {
-	info, err := procfs.CPUInfo()
+	info, err := c.proc.CPUInfo()
 	if err != nil {
 		return err
 	}
@@ -146,13 +260,40 @@ func (c *CPU) parseInfo() error {
 	defer info.Close()
 
 	var (
-		logical  int
-		physical int
+		logical   int
+		physical  int
+		socket    int
+		processor bool
+		sockets   = make(map[int]struct{})
+		cores     = make(map[[2]int]struct{})
 	)
 
+	c.info = payload.CPUInfo{}
+
+	// Each processor is a paragraph ended by a blank line, although the last
+	// one may not be, and there may be paragraphs that aren't of a processor,
+	// such as the "Hardware" of some ARM cpus.
+	addCore := func() {
+		if !processor {
+			return
+		}
+
+		c.cores = append(c.cores, cpuCore{
+			logical:  logical,
+			physical: physical,
+		})
+
+		sockets[socket] = struct{}{}
+		cores[[2]int{socket, physical}] = struct{}{}
+		c.info.Threads++
+
+		logical, physical, socket, processor = 0, 0, 0, false
+	}
+
 	for {
 		line, err := info.ReadLine()
 		if err == io.EOF {
+			addCore()
 			break
 		}
 
@@ -160,45 +301,92 @@ func (c *CPU) parseInfo() error {
 			return err
 		}
 
-		if len(line) == 0 {
-			if n := logical + 1; n > len(c.cores) {
-				c.cores = slices.Grow(c.cores, n-len(c.cores))
-			}
-
-			core := &c.cores[logical]
-			core.logical = logical
-			core.physical = physical
+		if len(bytes.TrimSpace(line)) == 0 {
+			addCore()
+			continue
 		}
 
 		key, val := byteutil.Field(line)
 
 		switch string(key) {
 		case "processor":
-			logical = int(byteutil.Btou(val))
+			logical = byteutil.Btoint(val)
+			processor = true
 		case "model name":
 			if len(c.Name) == 0 {
 				c.Name = string(bytes.TrimSpace(val))
 			}
+
+			if c.info.Threads == 0 {
+				c.info.Model = string(bytes.TrimSpace(val))
+			}
 		case "core id":
-			physical = int(byteutil.Btou(val))
+			physical = byteutil.Btoint(val)
+		case "physical id":
+			socket = byteutil.Btoint(val)
+		}
+
+		// The static info is the same for every processor, so only the first
+		// needs to be parsed.
+		if c.info.Threads > 0 {
+			continue
+		}
+
+		switch string(key) {
+		case "vendor_id":
+			c.info.Vendor = string(bytes.TrimSpace(val))
+		case "cpu family":
+			c.info.Family = byteutil.Btoint(val)
+		case "model":
+			c.info.ModelID = byteutil.Btoint(val)
+		case "stepping":
+			c.info.Stepping = byteutil.Btoint(val)
+		case "cache size":
+			c.info.CacheSize = byteutil.Btoint(val)
+		case "flags":
+			c.info.Virtualization = virtualization(val)
 		}
 	}
 
+	c.info.Sockets = len(sockets)
+	c.info.Cores = len(cores)
+
 	slices.SortFunc(c.cores, func(a, b cpuCore) int {
 		return a.logical - b.logical
 	})
 
-	c.coremap = make([]int, len(c.cores))
+	c.indexCores()
+
+	return nil
+}
+
+// indexCores indexes the cores by their logical id, which may not match
+// their index if any cores are offline.
+func (c *CPU) indexCores() {
+	c.index = make(map[int]int, len(c.cores))
 
 	for i := range c.cores {
-		c.coremap[i] = c.cores[i].physical
+		c.index[c.cores[i].logical] = i
 	}
+}
 
-	return nil
+// virtualization returns the hardware virtualization extension in the
+// cpuinfo flags, if any.
+func virtualization(flags []byte) string {
+	for _, f := range bytes.Fields(flags) {
+		switch string(f) {
+		case "vmx":
+			return "VT-x"
+		case "svm":
+			return "AMD-V"
+		}
+	}
+
+	return ""
 }
 
 func (c *CPU) findSensors() error {
-	sensors, err := sysfs.HWMonSensors()
+	sensors, err := c.sys.HWMonSensors()
 	if err != nil {
 		return err
 	}
@@ -220,7 +408,7 @@ func (c *CPU) findSensors() error {
 
 	if c.temp == nil {
 		log.Debug("No hwmon sensors found")
-		sensors, err = sysfs.ThermalSensors()
+		sensors, err = c.sys.ThermalSensors()
 		if err != nil {
 			return err
 		}
@@ -265,7 +453,7 @@ func (c *CPU) findSensors() error {
 }
 
 func (c *CPU) findFreqs() error {
-	freqs, err := sysfs.CPUFreqs()
+	freqs, err := c.sys.CPUFreqs()
 	if err != nil {
 		return err
 	}
@@ -311,19 +499,41 @@ func (c *CPU) SetInterval(d time.Duration) {
 	c.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (c *CPU) Interval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.interval
+}
+
 func (c *CPU) loop(ctx context.Context) {
+	defer recoverLoop(c.Type())
+
 	c.mu.Lock()
 	c.tick = time.NewTicker(c.interval)
+
+	if c.sampleInterval > 0 && c.sampleInterval < c.interval {
+		c.sampleTick = time.NewTicker(c.sampleInterval)
+	}
+
 	c.mu.Unlock()
 
 	defer c.tick.Stop()
-	defer close(c.ch)
 
 	var (
-		err error
-		ch  chan error
+		err     error
+		ch      chan error
+		sampleC <-chan time.Time
 	)
 
+	if c.sampleTick != nil {
+		sampleC = c.sampleTick.C
+		defer c.sampleTick.Stop()
+	}
+
+	defer close(c.ch)
+
 	log.Debug("cpu started")
 
 	for {
@@ -340,6 +550,8 @@ func (c *CPU) loop(ctx context.Context) {
 			}
 
 			ch = c.ch
+		case <-sampleC:
+			c.Sample()
 		case ch <- err:
 			ch = nil
 		}
@@ -365,19 +577,13 @@ func (c *CPU) Start(ctx context.Context) (err error) {
 }
 
 func (c *CPU) updateUsage() error {
-	stat, err := procfs.Stat()
+	stat, err := c.proc.Stat()
 	if err != nil {
 		return err
 	}
 
 	defer stat.Close()
 
-	var (
-		name   []byte
-		buf    []byte
-		cpuNum int
-	)
-
 	for {
 		line, err := stat.ReadLine()
 		if err == io.EOF {
@@ -392,79 +598,167 @@ func (c *CPU) updateUsage() error {
 			continue
 		}
 
-		if line[0] != 'c' {
+		// The cpu lines are first, so the rest of the file is skipped.
+		if !bytes.HasPrefix(line, cpuPrefix) {
 			break
 		}
 
-		name, line = byteutil.Column(line)
+		cpuNum, total, idle, ok := parseCPUStat(line)
+		if !ok {
+			continue
+		}
 
-		if len(name) > 3 {
-			cpuNum = int(byteutil.Btoi(name[3:]))
+		if cpuNum == -1 {
+			c.percent = usagePercent(&c.total, &c.idle, total, idle, c.percent)
 		} else {
-			cpuNum = -1
+			i, ok := c.index[cpuNum]
+			if !ok {
+				continue
+			}
+
+			core := &c.cores[i]
+			core.percent = usagePercent(&core.total, &core.idle, total, idle, core.percent)
 		}
+	}
 
-		var (
-			times         [8]uint64
-			val           uint64
-			total, idle   uint64
-			dTotal, dIdle uint64
-		)
+	return nil
+}
 
-		for i := 0; len(line) > 0 && i < len(times); i++ {
-			buf, line = byteutil.Column(line)
-			val = byteutil.Btou(buf)
-			total += val
-			times[i] = val
-		}
+var cpuPrefix = []byte("cpu")
 
-		idle = times[3] + times[4]
+// parseCPUStat parses a cpu line of /proc/stat, returning the number of the
+// cpu, or -1 for the total of every cpu, and its total and idle jiffies. If the
+// line isn't a cpu line, ok is false. The jiffies saturate at [math.MaxUint64]
+// instead of overflowing.
+func parseCPUStat(line []byte) (cpuNum int, total, idle uint64, ok bool) {
+	name, line := byteutil.Column(line)
+	if !bytes.HasPrefix(name, cpuPrefix) {
+		return -1, 0, 0, false
+	}
 
-		if cpuNum == -1 {
-			if total > c.total {
-				dTotal = total - c.total
-			}
+	cpuNum = -1
 
-			if idle > c.idle {
-				dIdle = idle - c.idle
+	if num := name[len(cpuPrefix):]; len(num) > 0 {
+		for _, c := range num {
+			if c < '0' || c > '9' {
+				return -1, 0, 0, false
 			}
+		}
 
-			c.total = total
-			c.idle = idle
-			c.percent = int(100 * (dTotal - dIdle) / dTotal)
-		} else {
-			core := &c.cores[cpuNum]
+		cpuNum = byteutil.Btoint(num)
+	}
 
-			if total > core.total {
-				dTotal = total - core.total
-			}
+	var (
+		buf   []byte
+		times [8]uint64
+	)
 
-			if idle > core.idle {
-				dIdle = idle - core.idle
-			}
+	for i := 0; len(line) > 0 && i < len(times); i++ {
+		buf, line = byteutil.Column(line)
+		times[i] = byteutil.Btou(buf)
+		total = addSaturating(total, times[i])
+	}
 
-			core.total = total
-			core.idle = idle
-			core.percent = int(100 * (dTotal - dIdle) / dTotal)
+	idle = addSaturating(times[3], times[4])
 
-			if core.percent < 0 {
-				core.percent = 0
-			}
-		}
+	return cpuNum, total, idle, true
+}
+
+// addSaturating returns a + b, or [math.MaxUint64] if it overflows.
+func addSaturating(a, b uint64) uint64 {
+	if a+b < a {
+		return math.MaxUint64
 	}
 
-	return nil
+	return a + b
+}
+
+// usagePercent returns the usage as a percent between the jiffies *lastTotal
+// and *lastIdle and the jiffies total and idle, which are then stored. If no
+// time passed, such as when updated twice in quick succession, percent is
+// returned.
+func usagePercent(lastTotal, lastIdle *uint64, total, idle uint64, percent int) int {
+	var dTotal, dIdle uint64
+
+	if total > *lastTotal {
+		dTotal = total - *lastTotal
+	}
+
+	if idle > *lastIdle {
+		dIdle = idle - *lastIdle
+	}
+
+	*lastTotal = total
+	*lastIdle = idle
+
+	switch {
+	case dTotal == 0:
+		return percent
+	case dIdle > dTotal:
+		return 0
+	}
+
+	return int(100 * (dTotal - dIdle) / dTotal)
+}
+
+// sample samples the usage of the CPU between updates. c.mu must be held.
+func (c *CPU) sample() {
+	if err := c.updateUsage(); err != nil {
+		log.WarnError("can't sample CPU usage", err)
+		return
+	}
+
+	if c.samples == 0 || c.percent < c.sampleMin {
+		c.sampleMin = c.percent
+	}
+
+	if c.samples == 0 || c.percent > c.sampleMax {
+		c.sampleMax = c.percent
+	}
+
+	c.samples++
+}
+
+// Sample samples the usage of the CPU, which is included in the minimum and
+// maximum usage of the next update. This happens automatically every sample
+// interval once the CPU is started.
+func (c *CPU) Sample() {
+	c.mu.Lock()
+	c.sample()
+	c.mu.Unlock()
+}
+
+// updateWindow sets the usage of the CPU and each of its cores to the average
+// since the last update, and the minimum and maximum usage to those of the
+// samples since the last update. c.mu must be held.
+func (c *CPU) updateWindow() {
+	c.sample()
+
+	c.percent = usagePercent(&c.windowTotal, &c.windowIdle, c.total, c.idle, c.percent)
+
+	for i := range c.cores {
+		core := &c.cores[i]
+		core.percent = usagePercent(&core.windowTotal, &core.windowIdle, core.total, core.idle, core.percent)
+	}
+
+	c.usageMin, c.usageMax = c.sampleMin, c.sampleMax
+	c.samples = 0
 }
 
 // Update forces the cpu metric to update. The returned error will not
 // be sent on the channel returned by [CPU.Updated] unlike updates that
-// happen automatically every update interval.
+// happen automatically every update interval. If the payload of the CPU
+// hasn't changed since the last update, [ErrNoChange] is returned.
 func (c *CPU) Update() (err error) {
+	defer errUpdate(c.Type(), time.Now(), &err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.flags.Has(cpuUsage) {
-		if err := c.updateUsage(); err != nil {
+		if c.sampleInterval > 0 {
+			c.updateWindow()
+		} else if err := c.updateUsage(); err != nil {
 			log.WarnError("can't update CPU usage", err)
 
 			c.flags &^= cpuUsage
@@ -472,20 +766,165 @@ func (c *CPU) Update() (err error) {
 	}
 
 	if c.temp != nil {
-		c.temp.Read()
+		c.readTemperature(c.temp)
 	}
 
 	for i := range c.temps {
-		c.temps[i].Read()
+		c.readTemperature(&c.temps[i])
 	}
 
 	for i := range c.cores {
 		c.cores[i].freq.Read()
 	}
 
+	if c.flags.Has(cpuGovernor) {
+		if c.governor, err = c.cores[0].freq.Governor(); err != nil {
+			log.WarnError("can't update CPU governor", err)
+
+			c.flags &^= cpuGovernor
+			err = nil
+		}
+	}
+
+	if c.flags.Has(cpuBoost) {
+		if c.boostEnabled, err = c.boost.Enabled(); err != nil {
+			log.WarnError("can't update CPU boost", err)
+
+			c.flags &^= cpuBoost
+			err = nil
+		}
+	}
+
+	c.addAggregates(time.Now())
+
+	// Compare the encoded payloads, since the values are only as precise as
+	// they are encoded.
+	c.toPayload(&c.payload)
+	c.buf, _ = c.payload.AppendText(c.buf[:0])
+
+	if bytes.Equal(c.buf, c.last) {
+		return ErrNoChange
+	}
+
+	c.last, c.buf = c.buf, c.last
+
 	return
 }
 
+// addAggregates adds the current usage and selected temperature, sampled at
+// t, to the rolling aggregates if enabled. c.mu must be held.
+func (c *CPU) addAggregates(t time.Time) {
+	if c.usageAggregate != nil && c.flags.Has(cpuUsage) {
+		c.usageAggregate.add(t, int64(c.percent)*1000)
+	}
+
+	if c.tempAggregate != nil && c.hasTemperature() {
+		temp, _ := c.selectFn(c)
+		c.tempAggregate.add(t, temp)
+	}
+}
+
+// SetBoost enables or disables the frequency boost (turbo) of the CPU. An error
+// wrapping [ErrNotPermitted] is returned if boost control is not enabled.
+func (c *CPU) SetBoost(enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.boostControl {
+		return errNotPermitted("boost")
+	}
+
+	if err := c.boost.SetEnabled(enabled); err != nil {
+		return err
+	}
+
+	c.boostEnabled = enabled
+
+	return nil
+}
+
+// governorWritable reports whether the scaling governor of every core may be
+// changed by the current process.
+func (c *CPU) governorWritable() bool {
+	for i := range c.cores {
+		if c.cores[i].freq.Path != "" && !c.cores[i].freq.GovernorWritable() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Governors returns the scaling governors available for the CPU.
+func (c *CPU) Governors() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Clone(c.governors)
+}
+
+// SetGovernor sets the scaling governor of every core of the CPU. An error
+// is returned if governor is not one of [CPU.Governors], and an error wrapping
+// [ErrNotPermitted] is returned if governor control is not enabled.
+func (c *CPU) SetGovernor(governor string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.governorControl {
+		return errNotPermitted("governor")
+	}
+
+	if !slices.Contains(c.governors, governor) {
+		return fmt.Errorf("unknown governor %q", governor)
+	}
+
+	for i := range c.cores {
+		if c.cores[i].freq.Path == "" {
+			continue
+		}
+
+		if err := c.cores[i].freq.SetGovernor(governor); err != nil {
+			return err
+		}
+	}
+
+	c.governor = governor
+
+	return nil
+}
+
+// Commands implements [Commander]. The selection mode may always be set, while
+// the boost and governor may only be set if enabled by the controls config.
+func (c *CPU) Commands() map[string]Command {
+	cmds := map[string]Command{
+		"selection_mode/set": func(payload []byte) error {
+			return c.SetSelectionMode(string(bytes.TrimSpace(payload)))
+		},
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.boostControl {
+		cmds["boost/set"] = func(payload []byte) error {
+			enabled, err := ParseSwitch(payload)
+			if err != nil {
+				return err
+			}
+
+			return c.SetBoost(enabled)
+		}
+	}
+
+	if c.governorControl {
+		cmds["governor/set"] = func(payload []byte) error {
+			return c.SetGovernor(string(bytes.TrimSpace(payload)))
+		}
+	}
+
+	return cmds
+}
+
 // Updated returns the channel that updates will be sent on. A received value
 // of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
 // error is the first error encountered during updating and indicates a failed update.
@@ -517,198 +956,224 @@ func (c *CPU) String() string {
 	return fmt.Sprintf("%s\n%d cores", c.Name, len(c.cores))
 }
 
-func (c *cpuCore) AppendText(b []byte, flags cpuFlag) []byte {
-	b = append(b, "{\"id\": "...)
-	b = strconv.AppendInt(b, int64(c.logical), 10)
+// readTemperature reads the temperature of s, corrected by the temperature
+// calibration of c.
+func (c *CPU) readTemperature(s *sysfs.Sensor) {
+	if v, err := s.Read(); err == nil && !c.tempCal.IsZero() {
+		s.SetValue(calibrate(c.tempCal, v, 1000))
+	}
+}
 
-	if c.temp != nil {
-		b = append(b, ", \"temperature\": "...)
-		b = byteutil.AppendDecimal(b, c.temp.Value(), 3)
+// TemperatureLimits implements [TemperatureLimiter] and returns the limits of
+// the sensor of the temperature of the CPU.
+func (c *CPU) TemperatureLimits() (high, crit int64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.temp == nil {
+		return 0, 0
 	}
 
-	if flags.Has(cpuFrequency) {
-		b = append(b, ", \"frequency\": "...)
-		b = byteutil.AppendDecimal(b, c.freq.Curr(), 6)
+	return c.temp.High, c.temp.Crit
+}
+
+// temperature returns the temperature of the core, and whether it has a valid
+// temperature. The temperature may be invalid if the core has no sensor, or if
+// its sensor couldn't be read.
+func (c *cpuCore) temperature() (int64, bool) {
+	if !c.temp.Valid() {
+		return 0, false
 	}
 
-	if flags.Has(cpuUsage) {
-		b = append(b, ", \"usage\": "...)
-		b = strconv.AppendInt(b, int64(c.percent), 10)
+	return c.temp.Value(), true
+}
+
+func (c *cpuCore) toPayload(p *payload.Core, flags cpuFlag, prec int) {
+	p.ID = c.logical
+	p.Core = c.physical
+
+	if t, ok := c.temperature(); ok {
+		p.Temperature = payload.Some(payload.Milli(t).Round(prec))
+	} else {
+		p.Temperature = payload.Optional[payload.Milli]{}
 	}
 
-	return append(b, '}')
+	p.Frequency = payload.Maybe(payload.Micro(c.freq.Curr()).Round(prec), flags.Has(cpuFrequency))
+	p.BaseFrequency = payload.Maybe(payload.Micro(c.freq.Base).Round(prec), c.freq.Base > 0)
+	p.MinFrequency = payload.Maybe(payload.Micro(c.freq.Min).Round(prec), c.freq.Min > 0)
+	p.MaxFrequency = payload.Maybe(payload.Micro(c.freq.Max).Round(prec), c.freq.Max > 0)
+	p.Usage = payload.Maybe(c.percent, flags.Has(cpuUsage))
 }
 
-// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
-// representation of c to b.
-func (c *CPU) AppendText(b []byte) ([]byte, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (c *cpuCore) fromPayload(p *payload.Core) {
+	c.logical = p.ID
+	c.physical = p.Core
 
-	b = append(b, "{\"name\": \""...)
-	b = append(b, c.Name...)
-	b = append(b, '"')
-	temp, freq := c.selectFn()
+	if p.Temperature.Valid {
+		if c.temp == nil {
+			c.temp = new(sysfs.Sensor)
+		}
 
-	if c.temp != nil {
-		b = append(b, ", \"temperature\": "...)
-		b = byteutil.AppendDecimal(b, temp, 3)
+		c.temp.SetValue(int64(p.Temperature.Value))
 	}
 
-	if c.flags.Has(cpuFrequency) {
-		b = append(b, ", \"frequency\": "...)
-		b = byteutil.AppendDecimal(b, freq, 6)
+	if p.Frequency.Valid {
+		c.freq.SetCurr(int64(p.Frequency.Value))
 	}
 
-	if c.flags.Has(cpuTemperature | cpuFrequency) {
-		b = append(b, ", \"selection_mode\": \""...)
-		b = append(b, c.selectMode...)
-		b = append(b, '"')
-	}
+	c.freq.Base = int64(p.BaseFrequency.Value)
+	c.freq.Min = int64(p.MinFrequency.Value)
+	c.freq.Max = int64(p.MaxFrequency.Value)
 
-	if c.flags.Has(cpuUsage) {
-		b = append(b, ", \"usage\": "...)
-		b = strconv.AppendInt(b, int64(c.percent), 10)
+	if p.Usage.Valid {
+		c.percent = p.Usage.Value
 	}
+}
 
-	b = append(b, ", \"cores\": ["...)
+func (c *CPU) toPayload(p *payload.CPU) {
+	p.Name = c.Name
+	temp, freq := c.selectFn(c)
 
-	for i := range c.cores {
-		b = c.cores[i].AppendText(b, c.flags)
+	prec := places(c.prec)
 
-		if i < len(c.cores)-1 {
-			b = append(b, ',', ' ')
-		}
+	p.Temperature = payload.Maybe(payload.Milli(temp).Round(prec), c.hasTemperature())
+	p.TemperatureAggregate = aggregateOf(c.tempAggregate, prec)
+	p.Frequency = payload.Maybe(payload.Micro(freq).Round(prec), c.flags.Has(cpuFrequency))
+
+	if c.flags.Has(cpuTemperature | cpuFrequency) {
+		p.SelectionMode = c.selectMode
+	} else {
+		p.SelectionMode = ""
 	}
 
-	return append(b, ']', '}'), nil
-}
+	p.Usage = payload.Maybe(c.percent, c.flags.Has(cpuUsage))
+	p.UsageMin = payload.Maybe(c.usageMin, c.flags.Has(cpuUsage) && c.sampleInterval > 0)
+	p.UsageMax = payload.Maybe(c.usageMax, c.flags.Has(cpuUsage) && c.sampleInterval > 0)
+	p.UsageAggregate = aggregateOf(c.usageAggregate, prec)
+	p.Boost = payload.Maybe(c.boostEnabled, c.flags.Has(cpuBoost))
 
-// MarshalJSON implements [json.Marshaler] and is equivalent to [CPU.AppendText](nil).
-func (c *CPU) MarshalJSON() ([]byte, error) {
-	return c.AppendText(nil)
-}
+	if c.flags.Has(cpuGovernor) {
+		p.Governor = c.governor
+	} else {
+		p.Governor = ""
+	}
+	if c.shown == nil {
+		p.Cores = nil
+		return
+	}
 
-// SelectAuto returns the package temperature and frequency of the first core.
-func (c *CPU) SelectAuto() (temp, freq int64) {
-	if c.temp == nil {
-		return c.SelectFirst()
+	if p.Cores == nil {
+		p.Cores = make([]payload.Core, 0, len(c.shown))
 	}
 
-	temp = c.temp.Value()
+	p.Cores = slices.Grow(p.Cores[:0], len(c.shown))[:len(c.shown)]
 
-	if len(c.cores) > 0 {
-		freq = c.cores[0].freq.Curr()
+	for i, j := range c.shown {
+		c.cores[j].toPayload(&p.Cores[i], c.flags, prec)
 	}
-
-	return
 }
 
-// SelectMin returns the temperature and frequency of the first core.
-func (c *CPU) SelectFirst() (temp, freq int64) {
-	if len(c.cores) == 0 {
-		return
+func (c *CPU) fromPayload(p *payload.CPU) {
+	c.Name = p.Name
+
+	if p.Temperature.Valid {
+		if c.temp == nil {
+			c.temp = new(sysfs.Sensor)
+		}
+
+		c.temp.SetValue(int64(p.Temperature.Value))
+		c.flags |= cpuTemperature
 	}
 
-	if c.cores[0].temp != nil {
-		temp = c.cores[0].temp.Value()
+	if p.Frequency.Valid {
+		c.flags |= cpuFrequency
 	}
 
-	freq = c.cores[0].freq.Curr()
+	if p.Usage.Valid {
+		c.percent = p.Usage.Value
+		c.flags |= cpuUsage
+	}
 
-	return
-}
+	if p.Boost.Valid {
+		c.boostEnabled = p.Boost.Value
+		c.flags |= cpuBoost
+	}
 
-// SelectMin returns the average temperature and frequency of all cores.
-func (c *CPU) SelectAvg() (temp, freq int64) {
-	for i := range c.cores {
-		if c.cores[i].temp != nil {
-			temp += c.cores[i].temp.Value()
-		}
+	if p.Governor != "" {
+		c.governor = p.Governor
+		c.flags |= cpuGovernor
+	}
 
-		freq += c.cores[i].freq.Curr()
+	if n := len(p.Cores); n > len(c.cores) {
+		c.cores = slices.Grow(c.cores, n-len(c.cores))[:n]
 	}
 
-	temp /= int64(len(c.cores))
-	freq /= int64(len(c.cores))
+	if p.Cores == nil {
+		c.shown = nil
+	} else {
+		c.shown = make([]int, 0, len(p.Cores))
+	}
 
-	return
-}
+	for i := range p.Cores {
+		c.cores[i].fromPayload(&p.Cores[i])
+		c.shown = append(c.shown, i)
+	}
 
-// SelectMin returns the maximum temperature and frequency of all cores.
-func (c *CPU) SelectMax() (temp, freq int64) {
-	for i := range c.cores {
-		if c.cores[i].temp != nil {
-			if t := c.cores[i].temp.Value(); t > temp {
-				temp = t
-			}
-		}
+	c.indexCores()
 
-		if f := c.cores[i].freq.Curr(); f > freq {
-			freq = f
-		}
+	if p.SelectionMode != "" {
+		c.setSelectionMode(strings.ToLower(p.SelectionMode))
 	}
 
-	return
+	if c.selectFn == nil {
+		c.setSelectionMode("auto")
+	}
 }
 
-// SelectMin returns the minimum temperature and frequency of all cores.
-func (c *CPU) SelectMin() (temp, freq int64) {
-	for i := range c.cores {
-		if c.cores[i].temp != nil {
-			if t := c.cores[i].temp.Value(); t < temp || temp == 0 {
-				temp = t
-			}
-		}
+// Info returns the static information of the CPU parsed from /proc/cpuinfo.
+func (c *CPU) Info() payload.CPUInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-		if f := c.cores[i].freq.Curr(); f < freq || freq == 0 {
-			freq = f
-		}
-	}
+	return c.info
+}
 
-	return
+// AppendInfo implements [Informer] and appends the JSON-encoded representation
+// of the static information of c to b.
+func (c *CPU) AppendInfo(b []byte) ([]byte, error) {
+	return c.Info().AppendText(b)
 }
 
-// SelectRand returns the temperature and frequency of a random core.
-func (c *CPU) SelectRand() (temp, freq int64) {
-	i := c.rand.IntN(len(c.cores))
-	if c.cores[i].temp != nil {
-		temp = c.cores[i].temp.Value()
-	}
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of c to b.
+func (c *CPU) AppendText(b []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	freq = c.cores[i].freq.Curr()
+	c.toPayload(&c.payload)
 
-	return
+	return c.payload.AppendText(b)
 }
 
-func (c *CPU) setSelectionMode(mode string) {
-	switch mode {
-	case "", "auto":
-		c.selectMode = "auto"
-		c.selectFn = c.SelectAuto
-	case "first":
-		c.selectMode = "first"
-		c.selectFn = c.SelectFirst
-	case "avg", "average":
-		c.selectMode = "average"
-		c.selectFn = c.SelectAvg
-	case "max", "maximum":
-		c.selectMode = "maximum"
-		c.selectFn = c.SelectMax
-	case "min", "minimum":
-		c.selectMode = "minimum"
-		c.selectFn = c.SelectMin
-	case "rand", "random":
-		c.selectMode = "random"
-		c.selectFn = c.SelectRand
-		if c.rand != nil {
-			c.rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
-		}
-	}
-}
-
-func (c *CPU) SetSelectionMode(mode string) {
+// MarshalJSON implements [json.Marshaler] and is equivalent to [CPU.AppendText](nil).
+func (c *CPU) MarshalJSON() ([]byte, error) {
+	return c.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of a cpu, as produced by [CPU.MarshalJSON], into c. The
+// top-level temperature is stored as the package temperature, while the
+// top-level frequency is ignored since it is selected from the cores.
+func (c *CPU) UnmarshalJSON(data []byte) error {
+	var p payload.CPU
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
 	c.mu.Lock()
-	c.setSelectionMode(strings.ToLower(mode))
+	c.fromPayload(&p)
 	c.mu.Unlock()
+
+	return nil
 }
@@ -0,0 +1,285 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// selectionMode is a mode used to select the overall temperature and frequency
+// of the CPU from its cores.
+type selectionMode struct {
+	name    string
+	aliases []string
+	fn      func(*CPU) (temp, freq int64)
+}
+
+var selectionModes = []selectionMode{
+	{"auto", []string{""}, (*CPU).SelectAuto},
+	{"first", nil, (*CPU).SelectFirst},
+	{"average", []string{"avg"}, (*CPU).SelectAvg},
+	{"weighted", []string{"weighted-average"}, (*CPU).SelectWeighted},
+	{"maximum", []string{"max"}, (*CPU).SelectMax},
+	{"minimum", []string{"min"}, (*CPU).SelectMin},
+	{"hottest", []string{"hottest-core"}, (*CPU).SelectHottest},
+	{"random", []string{"rand"}, (*CPU).SelectRand},
+}
+
+// SelectionModes are the valid selection modes of the CPU, as returned by
+// [CPU.SelectionMode].
+var SelectionModes = func() []string {
+	modes := make([]string, len(selectionModes))
+	for i := range selectionModes {
+		modes[i] = selectionModes[i].name
+	}
+
+	return modes
+}()
+
+// SelectAuto returns the package temperature and frequency of the first core.
+// If there is no package temperature, this is the same as [CPU.SelectFirst].
+func (c *CPU) SelectAuto() (temp, freq int64) {
+	if !c.temp.Valid() {
+		return c.SelectFirst()
+	}
+
+	temp = c.temp.Value()
+
+	if len(c.cores) > 0 {
+		freq = c.cores[0].freq.Curr()
+	}
+
+	return
+}
+
+// SelectFirst returns the temperature and frequency of the first core.
+func (c *CPU) SelectFirst() (temp, freq int64) {
+	if len(c.cores) == 0 {
+		return
+	}
+
+	temp, _ = c.cores[0].temperature()
+	freq = c.cores[0].freq.Curr()
+
+	return
+}
+
+// SelectAvg returns the average temperature and frequency of all cores. Only
+// the cores with a temperature are included in the average temperature.
+func (c *CPU) SelectAvg() (temp, freq int64) {
+	if len(c.cores) == 0 {
+		return
+	}
+
+	var temps int64
+
+	for i := range c.cores {
+		if t, ok := c.cores[i].temperature(); ok {
+			temp += t
+			temps++
+		}
+
+		freq += c.cores[i].freq.Curr()
+	}
+
+	if temps > 0 {
+		temp /= temps
+	}
+
+	freq /= int64(len(c.cores))
+
+	return
+}
+
+// SelectWeighted returns the average temperature and frequency of all cores,
+// weighted by the usage of each core. If none of the cores have any usage,
+// this is the same as [CPU.SelectAvg], and if none of the cores with usage
+// have a temperature, the temperature is that of [CPU.SelectAvg].
+func (c *CPU) SelectWeighted() (temp, freq int64) {
+	var tempWeight, freqWeight int64
+
+	for i := range c.cores {
+		w := int64(c.cores[i].percent)
+
+		if t, ok := c.cores[i].temperature(); ok {
+			temp += w * t
+			tempWeight += w
+		}
+
+		freq += w * c.cores[i].freq.Curr()
+		freqWeight += w
+	}
+
+	if freqWeight == 0 {
+		return c.SelectAvg()
+	}
+
+	if tempWeight > 0 {
+		temp /= tempWeight
+	} else {
+		temp, _ = c.SelectAvg()
+	}
+
+	freq /= freqWeight
+
+	return
+}
+
+// SelectMax returns the maximum temperature and frequency of all cores.
+func (c *CPU) SelectMax() (temp, freq int64) {
+	var found bool
+
+	for i := range c.cores {
+		if t, ok := c.cores[i].temperature(); ok && (t > temp || !found) {
+			temp = t
+			found = true
+		}
+
+		if f := c.cores[i].freq.Curr(); f > freq {
+			freq = f
+		}
+	}
+
+	return
+}
+
+// SelectMin returns the minimum temperature and frequency of all cores.
+func (c *CPU) SelectMin() (temp, freq int64) {
+	var found bool
+
+	for i := range c.cores {
+		if t, ok := c.cores[i].temperature(); ok && (t < temp || !found) {
+			temp = t
+			found = true
+		}
+
+		if f := c.cores[i].freq.Curr(); f < freq || freq == 0 {
+			freq = f
+		}
+	}
+
+	return
+}
+
+// SelectHottest returns the temperature and frequency of the hottest core.
+// Unlike [CPU.SelectMax], both values are from the same core. If none of the
+// cores have a temperature, this is the same as [CPU.SelectFirst].
+func (c *CPU) SelectHottest() (temp, freq int64) {
+	hottest := -1
+
+	for i := range c.cores {
+		if t, ok := c.cores[i].temperature(); ok && (hottest < 0 || t > temp) {
+			temp = t
+			hottest = i
+		}
+	}
+
+	if hottest < 0 {
+		return c.SelectFirst()
+	}
+
+	freq = c.cores[hottest].freq.Curr()
+
+	return
+}
+
+// SelectRand returns the temperature and frequency of a random core. The
+// random source may be set with [CPU.SetRandSource], otherwise a randomly
+// seeded source is used.
+func (c *CPU) SelectRand() (temp, freq int64) {
+	if len(c.cores) == 0 {
+		return
+	}
+
+	if c.rand == nil {
+		c.rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	i := c.rand.IntN(len(c.cores))
+	temp, _ = c.cores[i].temperature()
+	freq = c.cores[i].freq.Curr()
+
+	return
+}
+
+// hasTemperature reports whether the temperature selected from c is valid,
+// which is either the package temperature in auto mode, or the temperature
+// of any of the cores.
+func (c *CPU) hasTemperature() bool {
+	if c.selectMode == "auto" && c.temp.Valid() {
+		return true
+	}
+
+	for i := range c.cores {
+		if c.cores[i].temp.Valid() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetRandSource sets the source of the random numbers used by [CPU.SelectRand],
+// such as to make the selection deterministic. If src is nil, a randomly seeded
+// source is used.
+func (c *CPU) SetRandSource(src rand.Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if src == nil {
+		c.rand = nil
+	} else {
+		c.rand = rand.New(src)
+	}
+}
+
+func (c *CPU) setSelectionMode(mode string) bool {
+	for i := range selectionModes {
+		m := &selectionModes[i]
+
+		if mode == m.name || slices.Contains(m.aliases, mode) {
+			c.selectMode = m.name
+			c.selectFn = m.fn
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetSelectionMode sets the mode used to select the overall temperature and
+// frequency of the CPU. If mode is not one of [SelectionModes], or one of their
+// abbreviations, the mode is unchanged and an error is returned.
+func (c *CPU) SetSelectionMode(mode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.setSelectionMode(strings.ToLower(mode)) {
+		return fmt.Errorf("unknown selection mode %q", mode)
+	}
+
+	return nil
+}
+
+// SelectionMode returns the current selection mode of the CPU.
+func (c *CPU) SelectionMode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.selectMode
+}
+
+// AppendSelectionMode appends the JSON-encoded representation of the current
+// selection mode and the valid selection modes to b.
+func (c *CPU) AppendSelectionMode(b []byte) ([]byte, error) {
+	p := payload.SelectionMode{
+		Mode:    c.SelectionMode(),
+		Options: SelectionModes,
+	}
+
+	return p.AppendText(b)
+}
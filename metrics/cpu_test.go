@@ -2,22 +2,29 @@ package metrics
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/sysfs"
 )
 
 func testCPU(t *testing.T) (*CPU, *config.Config) {
 	t.Helper()
 
-	err := file.SetRoot("testdata/fixtures")
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
 
 	cpu, err := NewCPU(cfg)
 
@@ -47,7 +54,7 @@ func TestCPU(t *testing.T) {
 		t.Errorf("Interval: want %v, got %v", want, got)
 	}
 
-	if want, got := cpuTemperature|cpuFrequency|cpuUsage, cpu.flags; got != want {
+	if want, got := cpuTemperature|cpuFrequency|cpuUsage|cpuBoost|cpuGovernor, cpu.flags; got != want {
 		t.Errorf("Flags: want %v, got %v", want, got)
 	}
 
@@ -103,7 +110,7 @@ func TestCPU_Update(t *testing.T) {
 		{67000, 2879295},
 	}
 
-	temp, freq := cpu.selectFn()
+	temp, freq := cpu.selectFn(cpu)
 	if want, got := int64(81000), temp; got != want {
 		t.Errorf("Temperature: want %v, got %v", want, got)
 	}
@@ -134,9 +141,11 @@ func TestCPU_Update(t *testing.T) {
 		{"SelectAvg", cpu.SelectAvg, 71750, 2708181},
 		{"SelectMax", cpu.SelectMax, 81000, 3124402},
 		{"SelectMin", cpu.SelectMin, 67000, 800000},
+		{"SelectWeighted", cpu.SelectWeighted, 71750, 2659823},
+		{"SelectHottest", cpu.SelectHottest, 81000, 800000},
 		{"SelectRand", cpu.SelectRand, 81000, 800000}, // cpu.rand.IntN(8) should return 2
 	}
-	cpu.rand = rand.New(&rand.PCG{})
+	cpu.SetRandSource(&rand.PCG{})
 
 	for _, s := range selects {
 		t.Run(s.name, func(t *testing.T) {
@@ -159,7 +168,7 @@ func TestCPU_MarshalJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	want := `{"name":"Intel(R) Core(TM) i7-8650U CPU @ 1.90GHz","temperature":0.000,"frequency":0.000000,"selection_mode":"auto","usage":0,"cores":[{"id":0,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":1,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":2,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":3,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":4,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":5,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":6,"temperature":0.000,"frequency":0.000000,"usage":0},{"id":7,"temperature":0.000,"frequency":0.000000,"usage":0}]}`
+	want := `{"name":"Intel(R) Core(TM) i7-8650U CPU @ 1.90GHz","frequency":0,"selection_mode":"auto","usage":0,"boost":false,"cores":[{"id":0,"core":0,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":1,"core":1,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":2,"core":2,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":3,"core":3,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":4,"core":0,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":5,"core":1,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":6,"core":2,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0},{"id":7,"core":3,"frequency":0,"base_frequency":2.8,"min_frequency":0.8,"max_frequency":3.8,"usage":0}]}`
 
 	if got := string(data); got != want {
 		var i int
@@ -175,3 +184,766 @@ func TestCPU_MarshalJSON(t *testing.T) {
 		t.Errorf("result differs at char %d\nwant %q\ngot  %q", i, want[:i+1], got[:i+1])
 	}
 }
+
+func TestCPU_UnmarshalJSON(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := json.Marshal(cpu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got CPU
+
+	if err = json.Unmarshal(want, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := cpu.Name, got.Name; got != want {
+		t.Errorf("Name: want %q, got %q", want, got)
+	}
+	if want, got := len(cpu.cores), len(got.cores); got != want {
+		t.Errorf("Cores: want %v, got %v", want, got)
+	}
+
+	data, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(want) {
+		t.Errorf("round trip differs\nwant %s\ngot  %s", want, data)
+	}
+}
+
+func TestCPU_DiscoverCoreNodes(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	core := cpu.cores[len(cpu.cores)-1].logical
+	coreID := "mqttop_cpu_core_" + strconv.Itoa(core)
+
+	var tests = []struct {
+		coreNodes string
+		node      string
+	}{
+		{"", "cpu"},
+		{"cpu", "cpu"},
+		{"cores", "cores"},
+		{"each", "cpu_core_" + strconv.Itoa(core)},
+	}
+	for _, tt := range tests {
+		d := &discovery.Discovery{
+			Origin:     discovery.NewOrigin(),
+			Components: make(map[string]discovery.Component),
+			Nodes:      make(map[string][]string),
+			CoreNodes:  tt.coreNodes,
+		}
+		cpu.Discover(d)
+
+		if !slices.Contains(d.Nodes["cpu"], "mqttop_cpu") {
+			t.Errorf("%q: Wanted mqttop_cpu in cpu node, got %v", tt.coreNodes, d.Nodes["cpu"])
+		}
+		if !slices.Contains(d.Nodes[tt.node], coreID) {
+			t.Errorf("%q: Wanted %s in %s node, got %v", tt.coreNodes, coreID, tt.node, d.Nodes[tt.node])
+		}
+		if tt.node != "cpu" && slices.Contains(d.Nodes["cpu"], coreID) {
+			t.Errorf("%q: Wanted %s not in cpu node", tt.coreNodes, coreID)
+		}
+	}
+}
+
+func TestCPU_FilterCores(t *testing.T) {
+	cpu, cfg := testCPU(t)
+
+	var tests = []struct {
+		name  string
+		cores config.CoresConfig
+		max   int
+		want  []int
+	}{
+		{"All", config.CoresConfig{}, 0, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"Include", config.CoresConfig{Include: []int{1, 3, 8}}, 0, []int{1, 3}},
+		{"Exclude", config.CoresConfig{Exclude: []int{0, 7}}, 0, []int{1, 2, 3, 4, 5, 6}},
+		{"Both", config.CoresConfig{Include: []int{2, 4, 6}, Exclude: []int{4}}, 0, []int{2, 6}},
+		{"Max", config.CoresConfig{}, 2, []int{0, 1}},
+		{"ExcludeMax", config.CoresConfig{Exclude: []int{0}}, 3, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		cfg.CPU.Cores = tt.cores
+		cfg.CPU.MaxCores = tt.max
+		cpu.filterCores(&cfg.CPU)
+
+		cpu.toPayload(&cpu.payload)
+
+		got := make([]int, len(cpu.payload.Cores))
+		for i := range cpu.payload.Cores {
+			got[i] = cpu.payload.Cores[i].ID
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Wanted payload cores %v, got %v", tt.name, tt.want, got)
+		}
+
+		d := &discovery.Discovery{
+			Origin:     discovery.NewOrigin(),
+			Components: make(map[string]discovery.Component),
+		}
+		cpu.Discover(d)
+
+		for _, core := range tt.want {
+			id := "mqttop_cpu_core_" + strconv.Itoa(core) + "_temperature"
+			cmp, ok := d.Components[id]
+			if !ok {
+				t.Errorf("%s: Wanted component %s", tt.name, id)
+				continue
+			}
+			want := "{{ (value_json.cores | selectattr('id', 'eq', " + strconv.Itoa(core) + ") | first).temperature | default(none) }}"
+			if got := cmp[discovery.ValueTemplate]; got != want {
+				t.Errorf("%s: Wanted template %q, got %q", tt.name, want, got)
+			}
+		}
+		var n int
+		for id := range d.Components {
+			if strings.HasPrefix(id, "mqttop_cpu_core_") {
+				n++
+			}
+		}
+		if want, got := 3*len(tt.want), n; got != want {
+			t.Errorf("%s: Wanted %d core components, got %d", tt.name, want, got)
+		}
+	}
+
+	cfg.CPU.Cores = config.CoresConfig{}
+	cfg.CPU.MaxCores = 0
+	cfg.CPU.PerCore = false
+	cpu.filterCores(&cfg.CPU)
+
+	cpu.toPayload(&cpu.payload)
+
+	if cpu.payload.Cores != nil {
+		t.Errorf("PerCore: Wanted nil payload cores, got %v", cpu.payload.Cores)
+	}
+	if b, _ := cpu.payload.AppendText(nil); strings.Contains(string(b), `"cores"`) {
+		t.Errorf("PerCore: Wanted payload without cores, got %s", b)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	cpu.Discover(d)
+
+	for id := range d.Components {
+		if strings.HasPrefix(id, "mqttop_cpu_core_") {
+			t.Errorf("PerCore: Wanted no core components, got %s", id)
+		}
+	}
+}
+
+func TestCPU_TemperatureLimits(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if high, crit := cpu.TemperatureLimits(); high != 100000 || crit != 100000 {
+		t.Errorf("TemperatureLimits: want 100000 100000, got %d %d", high, crit)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	cpu.Discover(d)
+
+	want := "{{ {'max': 100, 'critical': 100} | tojson }}"
+	for _, id := range []string{"mqttop_cpu_temperature", "mqttop_cpu_core_0_temperature"} {
+		cmp := d.Components[id]
+		if got := cmp[discovery.JSONAttributesTemplate]; got != want {
+			t.Errorf("%s: want attributes %q, got %q", id, want, got)
+		}
+		if got := cmp[discovery.JSONAttributesTopic]; got != cpu.Topic() {
+			t.Errorf("%s: want attributes topic %q, got %q", id, cpu.Topic(), got)
+		}
+	}
+}
+
+func TestCPU_Info(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	want := payload.CPUInfo{
+		Vendor:         "GenuineIntel",
+		Model:          "Intel(R) Core(TM) i7-8650U CPU @ 1.90GHz",
+		Family:         6,
+		ModelID:        142,
+		Stepping:       10,
+		CacheSize:      8192,
+		Sockets:        1,
+		Cores:          4,
+		Threads:        8,
+		Virtualization: "VT-x",
+	}
+	if got := cpu.Info(); got != want {
+		t.Errorf("Info: want %+v, got %+v", want, got)
+	}
+
+	var _ Informer = cpu
+}
+
+func TestCPU_SetSelectionMode(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	var tests = []struct {
+		mode string
+		want string
+		err  bool
+	}{
+		{"first", "first", false},
+		{"AVG", "average", false},
+		{"Maximum", "maximum", false},
+		{"bogus", "maximum", true},
+		{"hottest-core", "hottest", false},
+		{"rand", "random", false},
+		{"", "auto", false},
+	}
+	for _, tt := range tests {
+		err := cpu.SetSelectionMode(tt.mode)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: Wanted error %v, got %v", tt.mode, tt.err, err)
+		}
+		if got := cpu.SelectionMode(); got != tt.want {
+			t.Errorf("%q: Wanted mode %q, got %q", tt.mode, tt.want, got)
+		}
+	}
+
+	data, err := cpu.AppendSelectionMode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"selection_mode": "auto", "options": ["auto", "first", "average", "weighted", "maximum", "minimum", "hottest", "random"]}`
+	if got := string(data); got != want {
+		t.Errorf("AppendSelectionMode: want %s, got %s", want, got)
+	}
+}
+
+func TestCPU_SelectRand(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new CPU has no random source until one is needed.
+	if err := cpu.SetSelectionMode("random"); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu.rand = nil
+
+	if _, freq := cpu.selectFn(cpu); freq == 0 {
+		t.Error("Frequency: want non-zero")
+	}
+
+	cpu.SetRandSource(rand.NewPCG(1, 2))
+	want := make([]int64, 16)
+	for i := range want {
+		want[i], _ = cpu.SelectRand()
+	}
+
+	cpu.SetRandSource(rand.NewPCG(1, 2))
+	for i := range want {
+		if got, _ := cpu.SelectRand(); got != want[i] {
+			t.Fatalf("Temperature %d: want %v, got %v", i, want[i], got)
+		}
+	}
+}
+
+func TestCPU_Boost(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := true, cpu.boostEnabled; got != want {
+		t.Errorf("Boost: want %v, got %v", want, got)
+	}
+	if _, ok := cpu.Commands()["boost/set"]; ok {
+		t.Error("Commands: want no boost/set without boost control")
+	}
+	if err := cpu.SetBoost(false); !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("SetBoost: want %v, got %v", ErrNotPermitted, err)
+	}
+
+	// Copy the boost file so the fixture isn't modified.
+	root := t.TempDir()
+	path := filepath.Join(root, cpu.boost.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	boost, err := sysfs.NewFS(testRoot(t, root)).FindCPUBoost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu.boost = boost
+
+	if !cpu.boost.Writable() {
+		t.Fatal("Writable: want true")
+	}
+
+	cpu.boostControl = true
+
+	cmd, ok := cpu.Commands()["boost/set"]
+	if !ok {
+		t.Fatal("Commands: want boost/set with boost control")
+	}
+
+	var tests = []struct {
+		payload string
+		want    string
+		boost   bool
+		err     bool
+	}{
+		{"OFF", "1", false, false},
+		{"on", "0", true, false},
+		{"maybe", "0", true, true},
+	}
+	for _, tt := range tests {
+		err := cmd([]byte(tt.payload))
+		if (err != nil) != tt.err {
+			t.Errorf("%q: Wanted error %v, got %v", tt.payload, tt.err, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != tt.want {
+			t.Errorf("%q: Wanted no_turbo %q, got %q", tt.payload, tt.want, got)
+		}
+		if got := cpu.boostEnabled; got != tt.boost {
+			t.Errorf("%q: Wanted boost %v, got %v", tt.payload, tt.boost, got)
+		}
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	cpu.Discover(d)
+
+	if want, got := discovery.Switch, d.Components["mqttop_cpu_boost"][discovery.Platform]; got != want {
+		t.Errorf("Boost platform: want %v, got %v", want, got)
+	}
+}
+
+func TestCPU_Governor(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "powersave", cpu.governor; got != want {
+		t.Errorf("Governor: want %q, got %q", want, got)
+	}
+	if want, got := []string{"performance", "powersave"}, cpu.Governors(); !slices.Equal(got, want) {
+		t.Errorf("Governors: want %v, got %v", want, got)
+	}
+	if err := cpu.SetGovernor("performance"); !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("SetGovernor: want %v, got %v", ErrNotPermitted, err)
+	}
+
+	// Copy the governor files so the fixtures aren't modified.
+	root := t.TempDir()
+	for i := range cpu.cores {
+		copyFixtures(t, root, filepath.Dir(cpu.cores[i].freq.Path))
+	}
+
+	freqs, err := sysfs.NewFS(testRoot(t, root)).CPUFreqs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(freqs) != len(cpu.cores) {
+		t.Fatalf("CPUFreqs: want %d, got %d", len(cpu.cores), len(freqs))
+	}
+	for i := range cpu.cores {
+		cpu.cores[i].freq = freqs[i]
+	}
+
+	if !cpu.governorWritable() {
+		t.Fatal("Writable: want true")
+	}
+
+	cpu.governorControl = true
+
+	cmd, ok := cpu.Commands()["governor/set"]
+	if !ok {
+		t.Fatal("Commands: want governor/set with governor control")
+	}
+	if err := cmd([]byte("ondemand")); err == nil {
+		t.Error("ondemand: Wanted error for unavailable governor")
+	}
+	if err := cmd([]byte("performance\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range cpu.cores {
+		data, err := os.ReadFile(filepath.Join(root, filepath.Dir(cpu.cores[i].freq.Path), "scaling_governor"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "performance", strings.TrimSpace(string(data)); got != want {
+			t.Errorf("Core %d governor: want %q, got %q", i, want, got)
+		}
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	cpu.Discover(d)
+
+	cmp := d.Components["mqttop_cpu_governor"]
+	if want, got := discovery.Select, cmp[discovery.Platform]; got != want {
+		t.Errorf("Governor platform: want %v, got %v", want, got)
+	}
+}
+
+func TestCPU_OfflineCore(t *testing.T) {
+	cpu, cfg := testCPU(t)
+
+	// Take logical core 1 offline, so that the logical ids no longer match
+	// the indices of the cores.
+	cpu.cores = slices.Delete(cpu.cores, 1, 2)
+	cpu.indexCores()
+	cpu.filterCores(&cfg.CPU)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu.toPayload(&cpu.payload)
+
+	for i, core := range cpu.payload.Cores {
+		if want, got := cpu.cores[i].logical, core.ID; got != want {
+			t.Errorf("Core %d: want id %d, got %d", i, want, got)
+		}
+		if want, got := cpu.cores[i].physical, core.Core; got != want {
+			t.Errorf("Core %d: want core %d, got %d", i, want, got)
+		}
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	cpu.Discover(d)
+
+	if _, ok := d.Components["mqttop_cpu_core_1"]; ok {
+		t.Error("Wanted no component for offline core 1")
+	}
+
+	want := "{{ (value_json.cores | selectattr('id', 'eq', 7) | first).usage | default(none) }}"
+	if got := d.Components["mqttop_cpu_core_7"][discovery.ValueTemplate]; got != want {
+		t.Errorf("Wanted template %q, got %q", want, got)
+	}
+}
+
+func TestCPU_MissingTemperature(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sub-zero temperatures, with the package sensor and the sensor of the
+	// first physical core missing.
+	cpu.temp.Path = "missing"
+	cpu.temp.Read()
+	cpu.temps[0].Path = "missing"
+	cpu.temps[0].Read()
+
+	for i := 1; i < len(cpu.temps); i++ {
+		cpu.temps[i].SetValue(int64(-1000 * i))
+	}
+
+	var selects = []struct {
+		name string
+		fn   func() (temp, freq int64)
+		temp int64
+	}{
+		{"SelectAuto", cpu.SelectAuto, 0},
+		{"SelectAvg", cpu.SelectAvg, -2000},
+		{"SelectMax", cpu.SelectMax, -1000},
+		{"SelectMin", cpu.SelectMin, -3000},
+		{"SelectHottest", cpu.SelectHottest, -1000},
+	}
+	for _, s := range selects {
+		if temp, _ := s.fn(); temp != s.temp {
+			t.Errorf("%s: want %v, got %v", s.name, s.temp, temp)
+		}
+	}
+
+	// Only a core without a sensor has usage, so the temperature is the
+	// average of the cores with one, until a core with one has usage.
+	for i := range cpu.cores {
+		cpu.cores[i].percent = 0
+	}
+
+	cpu.cores[0].percent = 50
+
+	if temp, _ := cpu.SelectWeighted(); temp != -2000 {
+		t.Errorf("SelectWeighted/NoSensor: want %v, got %v", -2000, temp)
+	}
+
+	cpu.cores[1].percent = 50
+
+	if temp, _ := cpu.SelectWeighted(); temp != -1000 {
+		t.Errorf("SelectWeighted/Mixed: want %v, got %v", -1000, temp)
+	}
+
+	cpu.toPayload(&cpu.payload)
+
+	if !cpu.payload.Temperature.Valid {
+		t.Error("Temperature: want valid from cores")
+	}
+	if cpu.payload.Cores[0].Temperature.Valid {
+		t.Errorf("Core 0 temperature: want missing, got %v", cpu.payload.Cores[0].Temperature.Value)
+	}
+
+	data, err := cpu.payload.Cores[1].AppendText(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"temperature": -1,`) {
+		t.Errorf("Core 1: want temperature -1, got %s", data)
+	}
+
+	for i := range cpu.temps {
+		cpu.temps[i].Path = "missing"
+		cpu.temps[i].Read()
+	}
+
+	cpu.toPayload(&cpu.payload)
+
+	if cpu.payload.Temperature.Valid {
+		t.Errorf("Temperature: want missing, got %v", cpu.payload.Temperature.Value)
+	}
+}
+
+func TestCPU_TemperatureCalibration(t *testing.T) {
+	raw, cfg := testCPU(t)
+
+	if err := raw.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.CPU.TemperatureCalibration = config.Calibration{Offset: -5, Multiplier: 1}
+
+	cpu, err := NewCPU(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := raw.temp.Value()-5000, cpu.temp.Value(); got != want {
+		t.Errorf("Temperature: want %d, got %d", want, got)
+	}
+
+	if len(cpu.temps) == 0 {
+		t.Fatal("want core temperatures")
+	}
+
+	for i := range cpu.temps {
+		if want, got := raw.temps[i].Value()-5000, cpu.temps[i].Value(); got != want {
+			t.Errorf("%s: want %d, got %d", cpu.temps[i].Label, want, got)
+		}
+	}
+}
+
+func TestCPU_NoChange(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cpu.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	if err := cpu.SetSelectionMode("minimum"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cpu.Update(); err != nil {
+		t.Errorf("Update: want changed, got %v", err)
+	}
+	if err := cpu.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+}
+
+func TestCPU_SampleInterval(t *testing.T) {
+	cpu, _ := testCPU(t)
+	cpu.sampleInterval = time.Second
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fixture doesn't change, so rewind the counters to fake the usage
+	// between samples.
+	total, idle := cpu.total, cpu.idle
+
+	for _, busy := range []uint64{20, 90, 40} {
+		cpu.total, cpu.idle = total-100, idle-(100-busy)
+		cpu.Sample()
+	}
+
+	cpu.total, cpu.idle = total-100, idle-70
+	cpu.windowTotal, cpu.windowIdle = total-400, idle-230
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.CPU
+	cpu.toPayload(&p)
+
+	if want, got := payload.Some(20), p.UsageMin; got != want {
+		t.Errorf("UsageMin: want %v, got %v", want, got)
+	}
+	if want, got := payload.Some(90), p.UsageMax; got != want {
+		t.Errorf("UsageMax: want %v, got %v", want, got)
+	}
+	if want, got := payload.Some(42), p.Usage; got != want {
+		t.Errorf("Usage: want %v, got %v", want, got)
+	}
+
+	cpu.sampleInterval = 0
+	cpu.toPayload(&p)
+
+	if p.UsageMin.Valid || p.UsageMax.Valid {
+		t.Errorf("UsageMin, UsageMax: want none, got %v, %v", p.UsageMin, p.UsageMax)
+	}
+}
+
+func TestCPU_Aggregate(t *testing.T) {
+	cpu, _ := testCPU(t)
+
+	var p payload.CPU
+	cpu.toPayload(&p)
+
+	if p.UsageAggregate.Valid || p.TemperatureAggregate.Valid {
+		t.Errorf("Aggregate: want none when disabled, got %v, %v", p.UsageAggregate, p.TemperatureAggregate)
+	}
+
+	cpu.usageAggregate = new(rolling)
+	cpu.tempAggregate = new(rolling)
+
+	if err := cpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu.toPayload(&p)
+
+	if want, got := payload.Milli(p.Usage.Value*1000), p.UsageAggregate.Value.Max15m; !p.UsageAggregate.Valid || got != want {
+		t.Errorf("UsageAggregate: want max %v, got %v", want, p.UsageAggregate)
+	}
+	if want, got := p.Temperature.Value, p.TemperatureAggregate.Value.Avg1m; !p.TemperatureAggregate.Valid || got != want {
+		t.Errorf("TemperatureAggregate: want avg %v, got %v", want, p.TemperatureAggregate)
+	}
+}
+
+func TestParseCPUStat(t *testing.T) {
+	var tests = []struct {
+		line  string
+		cpu   int
+		total uint64
+		idle  uint64
+		ok    bool
+	}{
+		{"cpu  10 0 20 100 5 0 1 0 0 0", -1, 136, 105, true},
+		{"cpu3 1 2 3 4 5 6 7 8", 3, 36, 9, true},
+		{"cpu1\t1 2 3 4", 1, 10, 4, true},
+		{"cpu 18446744073709551615 1 0 18446744073709551615 1", -1, math.MaxUint64, math.MaxUint64, true},
+		{"cpu-1 1 2 3 4", -1, 0, 0, false},
+		{"cpux 1 2 3 4", -1, 0, 0, false},
+		{"ctxt 123456", -1, 0, 0, false},
+		{"cpu", -1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		cpu, total, idle, ok := parseCPUStat([]byte(tt.line))
+		if cpu != tt.cpu || total != tt.total || idle != tt.idle || ok != tt.ok {
+			t.Errorf("%q: want (%d, %d, %d, %v), got (%d, %d, %d, %v)", tt.line, tt.cpu, tt.total, tt.idle, tt.ok, cpu, total, idle, ok)
+		}
+	}
+}
+
+func FuzzParseCPUStat(f *testing.F) {
+	for _, s := range []string{"cpu  10 0 20 100 5 0 1 0 0 0", "cpu0 1 2 3 4 5 6 7 8", "cpu-1 1", "cpu 99999999999999999999 -1", "intr 1 2 3"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, line []byte) {
+		cpu, total, idle, ok := parseCPUStat(line)
+		if !ok {
+			return
+		}
+
+		if cpu < -1 {
+			t.Errorf("%q: negative cpu %d", line, cpu)
+		}
+		if idle > total {
+			t.Errorf("%q: idle %d greater than total %d", line, idle, total)
+		}
+	})
+}
+
+// FuzzCPU_ParseInfo parses arbitrary /proc/cpuinfo files.
+func FuzzCPU_ParseInfo(f *testing.F) {
+	for _, s := range []string{
+		"processor\t: 0\nmodel name\t: Test CPU\ncore id\t\t: 0\nphysical id\t: 0\n\nprocessor\t: 1\ncore id\t\t: 1\n\n",
+		"processor\t: 0\nBogoMIPS\t: 108.00\n\nprocessor\t: 1\nBogoMIPS\t: 108.00\n\nHardware\t: BCM2835\nRevision\t: c03111\n",
+		"processor : 0",
+		"\n\n\n",
+		"processor: -1\ncore id: 99999999999999999999\n",
+	} {
+		f.Add([]byte(s))
+	}
+
+	root := f.TempDir()
+	path := filepath.Join(root, "proc", "cpuinfo")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		f.Fatal(err)
+	}
+
+	proc := procfs.NewFS(testRoot(f, root))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := CPU{proc: proc}
+		if err := c.parseInfo(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(c.cores) != c.info.Threads {
+			t.Errorf("want %d cores, got %d", c.info.Threads, len(c.cores))
+		}
+		if c.info.Cores > c.info.Threads || c.info.Sockets > c.info.Cores {
+			t.Errorf("want sockets <= cores <= threads, got %d, %d, %d", c.info.Sockets, c.info.Cores, c.info.Threads)
+		}
+		if len(c.index) > len(c.cores) {
+			t.Errorf("want at most %d indexed cores, got %d", len(c.cores), len(c.index))
+		}
+	})
+}
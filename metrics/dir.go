@@ -2,24 +2,57 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
-
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/vfs"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
 )
 
+// defaultMaxWatches is the maximum number of directories watched if the max
+// watches of the config is 0.
+const defaultMaxWatches = 4096
+
+// Symlink policies of a [Dir], see [config.DirConfig].
+const (
+	symlinksCount  = "count"
+	symlinksFollow = "follow"
+	symlinksIgnore = "ignore"
+)
+
+// fileID identifies a directory, so that one that is bind mounted or linked
+// to more than once is only counted once.
+type fileID struct {
+	dev, ino uint64
+}
+
+func fileIDOf(info os.FileInfo) (id fileID) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		id = fileID{dev: uint64(stat.Dev), ino: stat.Ino}
+	}
+
+	return
+}
+
 type dirEntry struct {
 	size   uint64
 	parent *dirEntry
-	childs []dirEntry
+	childs []*dirEntry
+	path   string // joined with the root
+	id     fileID
+	depth  int
+	used   uint64 // the clock of the Dir when the entry last changed
 }
 
 // Dir implements the [Metric] interface to provide the metrics for a
@@ -30,10 +63,25 @@ type Dir struct {
 
 	dirEntry
 	depth    int
+	oneFS    bool
+	symlinks string
+	sizeUnit string
 	byteSize byteutil.ByteSize
-
-	watched map[string]*dirEntry
-	watcher *fsnotify.Watcher
+	clean    dirClean
+
+	// ready is closed once the initial size is scanned, see [Dir.scan].
+	ready   chan struct{}
+	scanErr error
+
+	// The watched directories are keyed by their path joined with root. Once
+	// maxWatches directories are watched, the subtrees of any others are
+	// polled instead.
+	watched    map[string]*dirEntry
+	polled     map[string]*dirEntry
+	maxWatches int
+	clock      uint64
+	watcher    *dirWatcher
+	root       *vfs.Root
 
 	interval time.Duration
 	tick     *time.Ticker
@@ -62,13 +110,34 @@ func NewDir(path string, cfg *config.Config) (*Dir, error) {
 		return nil, errNotSupported(path, ErrDisabled)
 	}
 
-	return newDir(dcfg, cfg)
+	return newDir(dcfg, DefaultsOf(cfg))
+}
+
+// NewDirFromConfig is like [NewDir] but is initialized from the config of the
+// dir and d instead of a full [config.Config].
+func NewDirFromConfig(dcfg config.DirConfig, d Defaults) (*Dir, error) {
+	return newDir(&dcfg, d)
+}
+
+func newDir(dcfg *config.DirConfig, defaults Defaults) (*Dir, error) {
+	d, err := openDir(dcfg, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.scan(); err != nil {
+		return nil, errNotSupported(d.path, err)
+	}
+
+	return d, nil
 }
 
-func newDir(dcfg *config.DirConfig, cfg *config.Config) (*Dir, error) {
+// openDir is like newDir but doesn't scan the initial size of the directory,
+// which must be done by [Dir.scan] before it's started.
+func openDir(dcfg *config.DirConfig, defaults Defaults) (*Dir, error) {
 	path := filepath.Clean(dcfg.Path)
 
-	info, err := file.Stat(path)
+	info, err := defaults.Root.Stat(path)
 	if err != nil {
 		return nil, errNotSupported(path, err)
 	}
@@ -78,20 +147,38 @@ func newDir(dcfg *config.DirConfig, cfg *config.Config) (*Dir, error) {
 		path: path,
 		dirEntry: dirEntry{
 			size: uint64(info.Size()),
+			id:   fileIDOf(info),
 		},
-		depth: -1,
+		depth:    -1,
+		oneFS:    dcfg.OneFilesystem,
+		symlinks: dcfg.Symlinks,
+		sizeUnit: dcfg.SizeUnit,
+		ready:    make(chan struct{}),
+		root:     defaults.Root,
+	}
+
+	switch d.symlinks {
+	case "":
+		d.symlinks = symlinksCount
+	case symlinksCount, symlinksFollow, symlinksIgnore:
+	default:
+		return nil, errNotSupported(path, fmt.Errorf("unknown symlink policy %q", d.symlinks))
+	}
+
+	if d.clean, err = newDirClean(&dcfg.Clean); err != nil {
+		return nil, errNotSupported(path, fmt.Errorf("clean: %w", err))
 	}
 
 	if dcfg.Interval > 0 {
 		d.interval = dcfg.Interval
 	} else {
-		d.interval = cfg.Interval
+		d.interval = defaults.Interval
 	}
 
 	if dcfg.Topic != "" {
 		d.topic = dcfg.Topic
-	} else if cfg.BaseTopic != "" {
-		d.topic = cfg.BaseTopic + "/metric/dir/" + d.Slug()
+	} else if defaults.BaseTopic != "" {
+		d.topic = defaults.BaseTopic + "/metric/dir/" + d.Slug()
 	} else {
 		d.topic = "mqttop/metric/dir/" + d.Slug()
 	}
@@ -100,39 +187,125 @@ func newDir(dcfg *config.DirConfig, cfg *config.Config) (*Dir, error) {
 		d.depth = dcfg.Depth
 	}
 
-	if !dcfg.Watch {
-		d.size = uint64(info.Size()) + dirSize(d.path, 0, d.depth)
-		log.Debug("Dir initial size", "path", d.path, "size", d.size)
-		d.byteSize = byteSize(dcfg.SizeUnit, d.size)
-		d.size = 0
-		log.Debug("Unwatched dir", "path", d.path)
+	if dcfg.Watch && !watchSupported {
+		log.Warn("Dir watching was not compiled in, polling instead", "path", path, "tag", "nowatch")
+	}
 
+	if !dcfg.Watch || !watchSupported {
+		log.Debug("Unwatched dir", "path", d.path)
 		return d, nil
 	}
 
+	d.maxWatches = dcfg.MaxWatches
+	if d.maxWatches == 0 {
+		d.maxWatches = defaultMaxWatches
+	}
+
+	d.dirEntry.path = d.root.Abs(path)
 	d.watched = map[string]*dirEntry{
-		path: &d.dirEntry,
+		d.dirEntry.path: &d.dirEntry,
+	}
+	d.polled = make(map[string]*dirEntry)
+
+	return d, nil
+}
+
+// scan scans the initial size of d, and then closes d.ready. Any error is
+// also sent as the first update once d is started.
+func (d *Dir) scan() (err error) {
+	d.mu.Lock()
+
+	defer func() {
+		d.scanErr = err
+		d.mu.Unlock()
+		close(d.ready)
+	}()
+
+	if d.watched == nil {
+		d.size += d.dirSize(d.path, 0, map[fileID]bool{d.id: true})
+		log.Debug("Dir initial size", "path", d.path, "size", d.size)
+	} else if err = d.init(); err != nil {
+		return
+	}
+
+	if len(d.polled) > 0 {
+		log.Warn("Dir exceeds max watches, polling subdirectories instead", "path", d.path, "watched", len(d.watched), "polled", len(d.polled))
+	}
+
+	d.byteSize = byteSize(d.sizeUnit, d.size)
+
+	return
+}
+
+// scanDirs scans the initial size of every dir concurrently, bounded by the
+// shared scheduler of updates, see [SetMaxConcurrentUpdates].
+func scanDirs(dirs []*Dir) {
+	var group updateGroup
+
+	for _, d := range dirs {
+		group.Go(func() error {
+			defer recoverLoop(d.path)
+
+			if err := d.scan(); err != nil {
+				log.Error("Couldn't scan dir", err, "path", d.path)
+			}
+
+			return nil
+		})
+	}
+
+	group.Wait()
+}
+
+// stat returns the info of the file f with the given name in a directory of
+// d, or if f is nil the named file, following it if it's a symlink that d
+// follows. walk reports whether the file is a directory that d walks, and ok
+// whether the file is counted at all.
+func (d *Dir) stat(name string, f os.DirEntry) (info os.FileInfo, walk, ok bool) {
+	var err error
+
+	if f != nil {
+		info, err = f.Info()
+	} else {
+		info, err = d.root.Lstat(name)
 	}
 
-	files, err := file.ReadDir(path)
 	if err != nil {
-		return nil, err
+		return nil, false, false
 	}
 
-	for _, f := range files {
-		if f.IsDir() {
-			d.init(path+file.Separator+f.Name(), &d.dirEntry, 1)
-			continue
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch d.symlinks {
+		case symlinksIgnore:
+			return nil, false, false
+		case symlinksFollow:
+			if info, err = d.root.Stat(name); err != nil {
+				return nil, false, false
+			}
 		}
+	}
 
-		if info, err := f.Info(); err == nil {
-			d.size += uint64(info.Size())
-		}
+	if !info.IsDir() {
+		return info, false, true
 	}
 
-	d.byteSize = byteSize(dcfg.SizeUnit, d.size)
+	if d.oneFS && fileIDOf(info).dev != d.id.dev {
+		return nil, false, false
+	}
 
-	return d, nil
+	return info, true, true
+}
+
+// unit returns the size unit of d, waiting for the initial size to be scanned
+// if the unit is determined automatically.
+func (d *Dir) unit() byteutil.ByteSize {
+	if size, err := byteutil.ParseSize(d.sizeUnit); err == nil {
+		return size
+	}
+
+	<-d.ready
+
+	return d.byteSize
 }
 
 func byteSize(s string, b uint64) byteutil.ByteSize {
@@ -144,41 +317,183 @@ func byteSize(s string, b uint64) byteutil.ByteSize {
 	return size
 }
 
-func (d *Dir) init(path string, parent *dirEntry, depth int) {
-	if depth > d.depth && d.depth > 0 {
-		return
+// init adds the subdirectories of d breadth first, so that once the max watches
+// are reached it's the deepest subtrees that are polled instead of watched.
+func (d *Dir) init() error {
+	queue := []*dirEntry{&d.dirEntry}
+	seen := map[fileID]bool{d.id: true}
+
+	for i := 0; i < len(queue); i++ {
+		entry := queue[i]
+
+		files, err := d.root.ReadDir(entry.path)
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+
+			continue
+		}
+
+		for _, f := range files {
+			name := entry.path + vfs.Separator + f.Name()
+
+			info, walk, ok := d.stat(name, f)
+			if !ok {
+				continue
+			}
+
+			if !walk {
+				entry.size += uint64(info.Size())
+				continue
+			}
+
+			child := &dirEntry{
+				parent: entry,
+				path:   name,
+				id:     fileIDOf(info),
+				depth:  entry.depth + 1,
+			}
+
+			if (d.depth > 0 && entry.depth >= d.depth) || seen[child.id] {
+				continue
+			}
+
+			seen[child.id] = true
+
+			if !d.watchable() {
+				entry.childs = append(entry.childs, child)
+				d.poll(child)
+				entry.size += child.size
+
+				continue
+			}
+
+			child.size = uint64(info.Size())
+			entry.childs = append(entry.childs, child)
+			d.watched[child.path] = child
+			queue = append(queue, child)
+		}
 	}
 
-	info, err := file.Stat(path)
+	// Add the size of every subdirectory to its parent, deepest first.
+	for i := len(queue) - 1; i > 0; i-- {
+		queue[i].parent.size += queue[i].size
+	}
+
+	return nil
+}
+
+// watchable reports whether another directory may be watched without
+// exceeding the max watches. d.mu must be held.
+func (d *Dir) watchable() bool {
+	return d.maxWatches < 0 || len(d.watched) < d.maxWatches
+}
+
+// poll polls the subtree of entry every update instead of watching it, and
+// sets its size. entry must not be watched. d.mu must be held.
+func (d *Dir) poll(entry *dirEntry) {
+	entry.childs = nil
+	entry.size, _ = d.pollSize(entry)
+	d.polled[entry.path] = entry
+
+	log.Debug("Polling dir", "path", entry.path)
+}
+
+// pollSize returns the size of the subtree of the polled entry, which is the
+// same as if its subdirectories were watched.
+func (d *Dir) pollSize(entry *dirEntry) (uint64, error) {
+	info, err := d.root.Stat(entry.path)
 	if err != nil {
-		return
+		return 0, err
 	}
 
-	i := len(parent.childs)
-	parent.childs = append(parent.childs, dirEntry{
-		size:   uint64(info.Size()),
-		parent: parent,
-	})
-	entry := &parent.childs[i]
-	d.watched[path] = entry
+	return uint64(info.Size()) + d.treeSize(entry.path, entry.depth, map[fileID]bool{entry.id: true}), nil
+}
 
-	files, err := file.ReadDir(path)
+// treeSize returns the size of the files and subdirectories in the directory
+// at path and depth, unlike dirSize which doesn't include the subdirectories
+// themselves.
+func (d *Dir) treeSize(path string, depth int, seen map[fileID]bool) (size uint64) {
+	files, err := d.root.ReadDir(path)
 	if err != nil {
 		return
 	}
 
 	for _, f := range files {
-		if f.IsDir() {
-			d.init(path+file.Separator+f.Name(), entry, depth+1)
+		name := path + vfs.Separator + f.Name()
+
+		info, walk, ok := d.stat(name, f)
+		if !ok {
+			continue
+		}
+
+		if !walk {
+			size += uint64(info.Size())
+			continue
+		}
+
+		id := fileIDOf(info)
+		if (d.depth > 0 && depth >= d.depth) || seen[id] {
 			continue
 		}
 
-		if info, err := f.Info(); err == nil {
-			entry.size += uint64(info.Size())
+		seen[id] = true
+
+		size += uint64(info.Size()) + d.treeSize(name, depth+1, seen)
+	}
+
+	return
+}
+
+// updatePolled updates the size of every polled subtree, and reports whether
+// any changed. d.mu must be held.
+func (d *Dir) updatePolled() (changed bool) {
+	for _, entry := range d.polled {
+		if d.updatePoll(entry) {
+			changed = true
 		}
 	}
 
-	parent.size += entry.size
+	return
+}
+
+// updatePoll updates the size of the polled entry, or removes it if it no
+// longer exists, and reports whether it changed. d.mu must be held.
+func (d *Dir) updatePoll(entry *dirEntry) bool {
+	size, err := d.pollSize(entry)
+	if err != nil {
+		d.remove(entry)
+		return true
+	}
+
+	if size == entry.size {
+		return false
+	}
+
+	for parent := entry.parent; parent != nil; parent = parent.parent {
+		parent.size += size - entry.size
+	}
+
+	entry.size = size
+
+	return true
+}
+
+// remove removes entry and its subtree from d, and its size from every parent.
+// d.mu must be held.
+func (d *Dir) remove(entry *dirEntry) {
+	d.unwatch(entry)
+
+	for parent := entry.parent; parent != nil; parent = parent.parent {
+		parent.size -= entry.size
+	}
+
+	if parent := entry.parent; parent != nil {
+		parent.childs = slices.DeleteFunc(parent.childs, func(e *dirEntry) bool {
+			return e == entry
+		})
+	}
 }
 
 // Type returns the metric type, "dir".
@@ -195,8 +510,8 @@ func (d *Dir) Topic() string {
 // and the leading separator removed.
 func (d *Dir) Slug() string {
 	return strings.ReplaceAll(
-		strings.TrimPrefix(d.Name, file.Separator),
-		file.Separator,
+		strings.TrimPrefix(d.Name, vfs.Separator),
+		vfs.Separator,
 		"_",
 	)
 }
@@ -216,89 +531,22 @@ func (dir *Dir) SetInterval(d time.Duration) {
 	dir.mu.Unlock()
 }
 
-func (d *Dir) loopWatch(ctx context.Context) {
-	updates := make(map[string]fsnotify.Op)
-
-	defer d.watcher.Close()
-
-	var (
-		err error
-		ch  chan error
-	)
+// Interval returns the update interval of the metric, or 0 if the directory
+// is watched, since it is then only updated after changes.
+func (d *Dir) Interval() time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-	select {
-	case <-ctx.Done():
-		d.Stop()
-		return
-	case <-d.tick.C:
-		d.ch <- nil
+	if d.watcher != nil {
+		return 0
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			d.Stop()
-			return
-		case e, ok := <-d.watcher.Errors:
-			if !ok {
-				return
-			}
-
-			err = e
-			ch = d.ch
-		case e, ok := <-d.watcher.Events:
-			if !ok {
-				return
-			}
-
-			path := e.Name
-
-			d.mu.Lock()
-
-			_, ok = d.watched[e.Name]
-			if !ok && !file.IsDir(e.Name) {
-				e.Op = 0
-				path = filepath.Dir(e.Name)
-				_, ok = d.watched[path]
-			}
-
-			d.mu.Unlock()
-
-			if !ok && !e.Has(fsnotify.Remove) {
-				if err := d.add(path); err != nil {
-					break
-				}
-			}
-
-			if _, ok = updates[path]; !ok {
-				updates[path] = e.Op
-			}
-
-			log.Debug("dir updated", "path", path)
-		case <-d.tick.C:
-			if len(updates) == 0 {
-				break
-			}
-
-			d.mu.Lock()
-
-			for path, op := range updates {
-				d.update(path, op)
-			}
-
-			d.mu.Unlock()
-
-			clear(updates)
-
-			err = nil
-			ch = d.ch
-		case ch <- err:
-			ch = nil
-		}
-	}
+	return d.interval
 }
 
 func (d *Dir) loop(ctx context.Context) {
+	defer recoverLoop(d.path)
+
 	d.mu.Lock()
 	d.tick = time.NewTicker(d.interval)
 	d.mu.Unlock()
@@ -308,15 +556,40 @@ func (d *Dir) loop(ctx context.Context) {
 
 	log.Debug("dir started", "path", d.path)
 
+	select {
+	case <-ctx.Done():
+		return
+	case <-d.ready:
+	}
+
+	err := d.scanErr
+
+	if err == nil && d.watched != nil {
+		if err = d.startWatch(ctx); err != nil {
+			log.WarnError("Unable to watch dir, polling instead", err, "path", d.path)
+		}
+	}
+
+	// The watcher is closed before the updates, even if the Dir is stopped
+	// before the initial size is sent, so its inotify watches are gone once
+	// the updates are closed.
+	if w := d.watcher; w != nil {
+		defer w.Close()
+	}
+
+	// Send the initial size as soon as it's scanned
+	select {
+	case <-ctx.Done():
+		return
+	case d.ch <- err:
+	}
+
 	if d.watcher != nil {
 		d.loopWatch(ctx)
 		return
 	}
 
-	var (
-		ch  chan error
-		err error
-	)
+	var ch chan error
 
 	for {
 		select {
@@ -332,36 +605,15 @@ func (d *Dir) loop(ctx context.Context) {
 	}
 }
 
-func (d *Dir) startWatch(ctx context.Context) error {
-	w, err := fsnotify.NewWatcher()
-	if err != nil {
-		return err
-	}
-
-	for path := range d.watched {
-		w.Add(path)
-		log.Debug("Watching dir", "path", path)
-	}
-
-	d.watcher = w
-
-	return nil
-}
-
-// Start starts the directory updating. If ctx is cancelled or
-// times out, the metric will stop and may not be restarted.
+// Start starts the directory updating. The initial size is sent once it's
+// scanned, which may be after Start returns. If ctx is cancelled or times
+// out, the metric will stop and may not be restarted.
 func (d *Dir) Start(ctx context.Context) (err error) {
 	if d.interval == 0 {
 		log.Warn("Dir interval is 0, not starting", "path", d.path)
 		return
 	}
 
-	if d.watched != nil {
-		if err = d.startWatch(ctx); err != nil {
-			return
-		}
-	}
-
 	d.once.Do(func() {
 		ctx, d.stop = context.WithCancel(ctx)
 		d.ch = make(chan error)
@@ -372,161 +624,47 @@ func (d *Dir) Start(ctx context.Context) (err error) {
 	return
 }
 
-func dirSize(path string, depth, maxDepth int) (size uint64) {
-	if depth >= maxDepth && maxDepth > 0 {
+func (d *Dir) dirSize(path string, depth int, seen map[fileID]bool) (size uint64) {
+	if depth >= d.depth && d.depth > 0 {
 		return
 	}
 
-	files, err := file.ReadDir(path)
+	files, err := d.root.ReadDir(path)
 	if err != nil {
 		return
 	}
 
 	for _, f := range files {
-		if f.IsDir() {
-			size += dirSize(path+file.Separator+f.Name(), depth+1, maxDepth)
+		name := path + vfs.Separator + f.Name()
+
+		info, walk, ok := d.stat(name, f)
+		if !ok {
 			continue
 		}
 
-		if info, err := f.Info(); err == nil {
+		if !walk {
 			size += uint64(info.Size())
-		}
-	}
-
-	log.Debug("Dir size", "path", path, "size", size)
-
-	return
-}
-
-func hasParent(path, parent string) bool {
-	if path == parent {
-		return true
-	}
-
-	for {
-		pathParent := filepath.Dir(path)
-		if pathParent == parent {
-			return true
-		}
-
-		if pathParent == path {
-			return false
-		}
-
-		path = pathParent
-	}
-}
-
-func (d *Dir) add(path string) error {
-	var (
-		parentPath = filepath.Dir(path)
-		parent     *dirEntry
-	)
-
-	d.mu.Lock()
-
-	for path, dir := range d.watched {
-		if hasParent(path, parentPath) {
-			parent = dir
-			break
-		}
-	}
-
-	if parent == nil || (d.depth > 0 && parent.depth() > d.depth) {
-		d.mu.Unlock()
-		return ErrMaxDepth
-	}
-
-	i := len(parent.childs)
-	parent.childs = append(parent.childs, dirEntry{parent: parent})
-	d.watched[path] = &parent.childs[i]
-
-	d.mu.Unlock()
-
-	return d.watcher.Add(path)
-}
-
-func (d *dirEntry) depth() int {
-	parent := d.parent
-	n := 1
-
-	for parent != nil {
-		n++
-		parent = parent.parent
-	}
-
-	return n
-}
-
-func (d *Dir) update(path string, op fsnotify.Op) error {
-	dir, ok := d.watched[path]
-	if !ok {
-		return errNotSupported(path, nil)
-	}
-
-	if op.Has(fsnotify.Remove) {
-		log.Debug("Removing watch", "path", path)
-		clear(dir.childs)
-		parent := dir.parent
-
-		for parent != nil {
-			parent.size -= dir.size
-			parent = parent.parent
-		}
-
-		delete(d.watched, path)
-
-		return nil
-	}
-
-	info, err := file.Stat(path)
-	if err != nil {
-		return nil
-	}
-
-	size := uint64(info.Size())
-
-	files, err := file.ReadDir(path)
-	if err != nil {
-		return err
-	}
-
-	if !ok {
-		return nil
-	}
-
-	for _, f := range files {
-		if f.IsDir() {
 			continue
 		}
 
-		if info, err := f.Info(); err == nil {
-			size += uint64(info.Size())
+		if id := fileIDOf(info); !seen[id] {
+			seen[id] = true
+			size += d.dirSize(name, depth+1, seen)
 		}
 	}
 
-	for i := range dir.childs {
-		size += dir.childs[i].size
-	}
-
-	parent := dir.parent
-	for parent != nil {
-		parent.size += size - dir.size
-		parent = parent.parent
-	}
-
-	dir.size = size
+	log.Debug("Dir size", "path", path, "size", size)
 
-	return nil
+	return
 }
 
 func (d *Dir) updateSlow() error {
-	info, err := file.Stat(d.path)
+	info, err := d.root.Stat(d.path)
 	if err != nil {
 		return err
 	}
 
-	size := uint64(info.Size()) + dirSize(d.path, 0, d.depth)
+	size := uint64(info.Size()) + d.dirSize(d.path, 0, map[fileID]bool{d.id: true})
 	if size == d.size {
 		return ErrNoChange
 	}
@@ -539,18 +677,28 @@ func (d *Dir) updateSlow() error {
 // Update forces the directory metric to update. The returned error will not
 // be sent on the channel returned by [Dir.Updated] unlike updates that
 // happen automatically every update interval.
-func (d *Dir) Update() error {
+func (d *Dir) Update() (err error) {
+	defer errUpdate(d.path, time.Now(), &err)
+
+	<-d.ready
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	// Publish the space reclaimed by a clean, even if the size didn't change
+	cleaned := d.clean.changed
+	d.clean.changed = false
+
 	if d.watched == nil {
-		return d.updateSlow()
-	}
+		if err = d.updateSlow(); err == ErrNoChange && cleaned {
+			err = nil
+		}
 
-	for path := range d.watched {
-		d.update(path, fsnotify.Write)
+		return
 	}
 
+	d.updateWatched()
+
 	return nil
 }
 
@@ -578,23 +726,49 @@ func (d *Dir) String() string {
 	return d.path
 }
 
+func (d *Dir) toPayload(p *payload.Dir) {
+	p.Path = d.path
+	p.Size = payload.Size(byteutil.ScaleSize(d.size, d.byteSize))
+	p.Watched = payload.Maybe(len(d.watched), d.watched != nil)
+	p.Polled = payload.Maybe(len(d.polled), d.watched != nil)
+	p.Reclaimed = payload.Maybe(payload.Size(byteutil.ScaleSize(d.clean.reclaimed, d.byteSize)), d.clean.cleaned)
+}
+
+func (d *Dir) fromPayload(p *payload.Dir) {
+	d.path = p.Path
+	d.size = byteutil.UnscaleSize(uint64(p.Size), d.byteSize)
+}
+
 // AppendText implements [encoding/TextAppender] and appends the JSON-encoded
 // representation of d to b.
 func (d *Dir) AppendText(b []byte) ([]byte, error) {
-	d.mu.RLock()
-
-	b = append(b, "{\"path\": \""...)
-	b = append(b, d.path...)
-	b = append(b, "\", \"size\": "...)
-	b = byteutil.AppendSize(b, d.size, d.byteSize)
-	b = append(b, '}')
+	var p payload.Dir
 
+	d.mu.RLock()
+	d.toPayload(&p)
 	d.mu.RUnlock()
 
-	return b, nil
+	return p.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [Dir.AppendText](nil).
 func (d *Dir) MarshalJSON() ([]byte, error) {
 	return d.AppendText(nil)
 }
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of a directory, as produced by [Dir.MarshalJSON], into d. The
+// size is interpreted in the size unit d was configured with.
+func (d *Dir) UnmarshalJSON(data []byte) error {
+	var p payload.Dir
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.fromPayload(&p)
+	d.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// dirClean is the clean command of a [Dir], see [config.DirCleanConfig].
+type dirClean struct {
+	enabled  bool
+	patterns []string
+	minAge   time.Duration
+
+	// reclaimed is the size of the files deleted by the last clean, which is
+	// only included in the payload once cleaned. changed is set by each clean
+	// so that the next update is published even if the size didn't change.
+	reclaimed uint64
+	cleaned   bool
+	changed   bool
+}
+
+// newDirClean returns the clean command of the given config, or an error if
+// any of its patterns are malformed. Since a clean without patterns or a
+// minimum age would delete every file in the directory, it is an error to
+// enable one.
+func newDirClean(cfg *config.DirCleanConfig) (dirClean, error) {
+	if cfg.Enabled && len(cfg.Patterns) == 0 && cfg.MinAge <= 0 {
+		return dirClean{}, errors.New("patterns or min_age must be set, refusing to delete every file")
+	}
+
+	for _, pattern := range cfg.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return dirClean{}, err
+		}
+	}
+
+	return dirClean{
+		enabled:  cfg.Enabled,
+		patterns: cfg.Patterns,
+		minAge:   cfg.MinAge,
+	}, nil
+}
+
+// match reports whether the file with the given name and info should be
+// deleted by a clean at now.
+func (c *dirClean) match(name string, info os.FileInfo, now time.Time) bool {
+	if c.minAge > 0 && now.Sub(info.ModTime()) < c.minAge {
+		return false
+	}
+
+	if len(c.patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Commands implements [Commander]. The "clean" command is only available if
+// enabled by the clean config of the directory, and ignores its payload.
+func (d *Dir) Commands() map[string]Command {
+	if !d.clean.enabled {
+		return nil
+	}
+
+	return map[string]Command{
+		"clean": func([]byte) error {
+			_, err := d.Clean()
+			return err
+		},
+	}
+}
+
+// Clean deletes the files in the directory that match the clean config, and
+// returns the total size of the deleted files. Subdirectories are walked the
+// same as when the size is counted, except that symlinks are never followed,
+// and files that can't be deleted are skipped.
+func (d *Dir) Clean() (uint64, error) {
+	if !d.clean.enabled {
+		return 0, errNotPermitted("clean")
+	}
+
+	<-d.ready
+
+	reclaimed := d.cleanDir(d.path, 0, time.Now(), map[fileID]bool{d.id: true})
+
+	log.Info("Dir cleaned", "path", d.path, "reclaimed", reclaimed)
+
+	d.mu.Lock()
+
+	d.clean.reclaimed = reclaimed
+	d.clean.cleaned = true
+	d.clean.changed = true
+
+	d.mu.Unlock()
+
+	return reclaimed, nil
+}
+
+func (d *Dir) cleanDir(path string, depth int, now time.Time, seen map[fileID]bool) (reclaimed uint64) {
+	if depth >= d.depth && d.depth > 0 {
+		return
+	}
+
+	files, err := d.root.ReadDir(path)
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		name := path + vfs.Separator + f.Name()
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		if info.IsDir() {
+			if d.oneFS && fileIDOf(info).dev != d.id.dev {
+				continue
+			}
+
+			if id := fileIDOf(info); !seen[id] {
+				seen[id] = true
+				reclaimed += d.cleanDir(name, depth+1, now, seen)
+			}
+
+			continue
+		}
+
+		if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		if !d.clean.match(f.Name(), info, now) {
+			continue
+		}
+
+		if err := d.root.Remove(name); err != nil {
+			log.WarnError("Unable to clean file", err, "path", name)
+			continue
+		}
+
+		log.Debug("Dir file cleaned", "path", name, "size", info.Size())
+
+		reclaimed += uint64(info.Size())
+	}
+
+	return
+}
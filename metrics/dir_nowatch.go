@@ -0,0 +1,26 @@
+//go:build nowatch
+
+package metrics
+
+import (
+	"context"
+	"errors"
+)
+
+// watchSupported indicates whether directories may be watched for changes
+// instead of polled.
+const watchSupported = false
+
+type dirWatcher struct{}
+
+func (*dirWatcher) Close() error { return nil }
+
+func (d *Dir) loopWatch(_ context.Context) {}
+
+func (d *Dir) startWatch(_ context.Context) error {
+	return errNotSupported(d.path, errors.New("built with nowatch"))
+}
+
+func (d *Dir) updateWatched() {}
+
+func (d *Dir) unwatch(_ *dirEntry) {}
@@ -1,15 +1,19 @@
 package metrics
 
 import (
+	"errors"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/discovery"
 )
 
 func fillTestDir(t *testing.T, name string) (size uint64, err error) {
@@ -38,8 +42,6 @@ func fillTestDir(t *testing.T, name string) (size uint64, err error) {
 func testDir(t *testing.T) (*Dir, *config.Config) {
 	t.Helper()
 
-	file.SetRoot("/")
-
 	tmp := t.TempDir()
 	t.Logf("TempDir: %s", tmp)
 
@@ -82,6 +84,30 @@ func TestDir(t *testing.T) {
 	}
 }
 
+func TestDir_Discover(t *testing.T) {
+	dir, _ := testDir(t)
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	dir.Discover(d)
+
+	if len(d.Components) == 0 {
+		t.Fatal("want components")
+	}
+	if d.Children != nil {
+		t.Errorf("want no child devices unless enabled, got %v", d.Children)
+	}
+
+	d.Children = map[string][]string{discovery.ChildDirs: nil}
+	dir.Discover(d)
+
+	if want, got := slices.Sorted(maps.Keys(d.Components)), slices.Sorted(slices.Values(d.Children[discovery.ChildDirs])); !slices.Equal(got, want) {
+		t.Errorf("want components %q in dirs device, got %q", want, got)
+	}
+}
+
 func TestDir_Update(t *testing.T) {
 	dir, _ := testDir(t)
 
@@ -99,3 +125,242 @@ func TestDir_Update(t *testing.T) {
 		t.Errorf("Size: want %v, got %v", want, got)
 	}
 }
+
+func TestDir_Symlinks(t *testing.T) {
+	tmp := t.TempDir()
+	out := t.TempDir()
+
+	for name, n := range map[string]int{
+		"dir/file": 100,
+		"file":     1000,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmp, name), make([]byte, n), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(out, "file"), make([]byte, 10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The size of a symlink is the length of its target
+	links := map[string]string{
+		"link_file": "file",
+		"link_dir":  "dir",
+		"link_out":  out,
+		"dir/loop":  "..",
+	}
+	var linkSize uint64
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(tmp, name)); err != nil {
+			t.Fatal(err)
+		}
+		linkSize += uint64(len(target))
+	}
+
+	outInfo, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := func(symlinks string, watch bool) uint64 {
+		t.Helper()
+
+		cfg := config.Default()
+		cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+			MetricConfig: config.MetricConfig{
+				Enabled: true,
+			},
+			Path:     tmp,
+			Watch:    watch,
+			Depth:    -1,
+			Symlinks: symlinks,
+		})
+
+		dir, err := NewDir(tmp, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dir.Update(); err != nil && err != ErrNoChange {
+			t.Fatal(err)
+		}
+
+		return dir.size
+	}
+
+	for _, watch := range []bool{false, true} {
+		ignored := size("ignore", watch)
+
+		// The followed link to dir and the loop are already counted
+		followed := uint64(1000 + 10000)
+		if watch && watchSupported {
+			followed += uint64(outInfo.Size())
+		}
+
+		if want, got := ignored+linkSize, size("", watch); got != want {
+			t.Errorf("Count (watch %v): want %d, got %d", watch, want, got)
+		}
+		if want, got := ignored+followed, size("follow", watch); got != want {
+			t.Errorf("Follow (watch %v): want %d, got %d", watch, want, got)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+		Path:     tmp,
+		Symlinks: "sometimes",
+	})
+
+	if _, err := NewDir(tmp, cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Unknown policy: want %v, got %v", ErrNotSupported, err)
+	}
+}
+
+func TestDir_OneFilesystem(t *testing.T) {
+	root, err := os.Stat("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc, err := os.Stat("/proc")
+	if err != nil || fileIDOf(proc).dev == fileIDOf(root).dev {
+		t.Skip("/proc is not a mount point")
+	}
+
+	d := &Dir{dirEntry: dirEntry{id: fileIDOf(root)}, symlinks: symlinksCount}
+
+	if _, walk, _ := d.stat("/proc", nil); !walk {
+		t.Error("/proc: want walked")
+	}
+
+	d.oneFS = true
+
+	if _, _, ok := d.stat("/proc", nil); ok {
+		t.Error("/proc: want skipped on another filesystem")
+	}
+	if _, walk, _ := d.stat("/etc", nil); !walk {
+		t.Error("/etc: want walked")
+	}
+}
+
+func TestDir_Clean(t *testing.T) {
+	tmp := t.TempDir()
+	out := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+
+	files := map[string]struct {
+		size    int
+		old     bool
+		deleted bool
+	}{
+		"old.iso":       {1000, true, true},
+		"new.iso":       {100, false, false},
+		"old.txt":       {10, true, false},
+		"sub/old.iso":   {10000, true, true},
+		"sub/keep.part": {1, true, false},
+	}
+	for name, f := range files {
+		path := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if f.old {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Files a symlink links to are never deleted
+	if err := os.WriteFile(filepath.Join(out, "linked.iso"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(out, filepath.Join(tmp, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+		MetricConfig: config.MetricConfig{
+			Enabled: true,
+		},
+		Path:     tmp,
+		SizeUnit: "B",
+		Symlinks: "follow",
+	})
+
+	dir, err := NewDir(tmp, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.Commands() != nil {
+		t.Error("Commands: want none if not enabled")
+	}
+	if _, err := dir.Clean(); !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("Clean: want %v, got %v", ErrNotPermitted, err)
+	}
+
+	cfg.Dirs[0].Clean = config.DirCleanConfig{
+		Enabled:  true,
+		Patterns: []string{"*.iso"},
+		MinAge:   24 * time.Hour,
+	}
+
+	if dir, err = NewDir(tmp, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Update(); err != nil && err != ErrNoChange {
+		t.Fatal(err)
+	}
+
+	size := dir.size
+
+	cmd, ok := dir.Commands()["clean"]
+	if !ok {
+		t.Fatal("Commands: want clean")
+	}
+	if err := cmd(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, f := range files {
+		if _, err := os.Stat(filepath.Join(tmp, name)); os.IsNotExist(err) != f.deleted {
+			t.Errorf("%s: want deleted %v, got %v", name, f.deleted, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "linked.iso")); err != nil {
+		t.Errorf("linked.iso: want not deleted, got %v", err)
+	}
+
+	// The reclaimed size is published even if the size wouldn't change
+	if err := dir.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := size-11000, dir.size; got != want {
+		t.Errorf("Size: want %d, got %d", want, got)
+	}
+
+	b, err := dir.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"reclaimed": 11000}`; !strings.HasSuffix(string(b), want) {
+		t.Errorf("MarshalJSON: want suffix %s, got %s", want, b)
+	}
+
+	cfg.Dirs[0].Clean = config.DirCleanConfig{Enabled: true}
+
+	if _, err := NewDir(tmp, cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("No patterns or min age: want %v, got %v", ErrNotSupported, err)
+	}
+
+	cfg.Dirs[0].Clean.Patterns = []string{"["}
+
+	if _, err := NewDir(tmp, cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Bad pattern: want %v, got %v", ErrNotSupported, err)
+	}
+}
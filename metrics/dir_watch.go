@@ -0,0 +1,344 @@
+//go:build !nowatch
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// watchSupported indicates whether directories may be watched for changes
+// instead of polled.
+const watchSupported = true
+
+func (d *Dir) loopWatch(ctx context.Context) {
+	updates := make(map[string]fsnotify.Op)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.Stop()
+			return
+		case e, ok := <-d.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			err = e
+			ch = d.ch
+		case e, ok := <-d.watcher.Events:
+			if !ok {
+				return
+			}
+
+			path := e.Name
+
+			d.mu.Lock()
+
+			_, ok = d.watched[e.Name]
+			if !ok {
+				_, ok = d.polled[e.Name]
+			}
+
+			if !ok {
+				if _, walk, _ := d.stat(e.Name, nil); !walk {
+					e.Op = 0
+					path = filepath.Dir(e.Name)
+					_, ok = d.watched[path]
+				}
+			}
+
+			d.mu.Unlock()
+
+			if !ok && !e.Has(fsnotify.Remove) {
+				if err := d.add(path); err != nil {
+					break
+				}
+			}
+
+			if _, ok = updates[path]; !ok {
+				updates[path] = e.Op
+			}
+
+			log.Debug("dir updated", "path", path)
+		case <-d.tick.C:
+			d.mu.Lock()
+
+			changed := d.updatePolled()
+
+			for path, op := range updates {
+				if entry, ok := d.watched[path]; ok {
+					d.clock++
+					entry.used = d.clock
+				}
+
+				d.update(path, op)
+			}
+
+			d.mu.Unlock()
+
+			if len(updates) == 0 && !changed {
+				break
+			}
+
+			clear(updates)
+
+			err = nil
+			ch = d.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+func (d *Dir) startWatch(ctx context.Context) error {
+	w, err := sharedWatcher.watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.watcher = w
+
+	var failed []*dirEntry
+
+	for path, entry := range d.watched {
+		if err := w.Add(path); err != nil {
+			log.Debug("Unable to watch dir", "path", path, "err", err)
+
+			if errors.Is(err, unix.ENOSPC) {
+				failed = append(failed, entry)
+			}
+
+			continue
+		}
+
+		log.Debug("Watching dir", "path", path)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	// Poll the shallowest directories that couldn't be watched, which polls
+	// their subtrees as well.
+	slices.SortFunc(failed, func(a, b *dirEntry) int {
+		return a.depth - b.depth
+	})
+
+	if failed[0] == &d.dirEntry {
+		w.Close()
+		d.watcher = nil
+
+		return errNotSupported(d.path, unix.ENOSPC)
+	}
+
+	for _, entry := range failed {
+		if _, ok := d.watched[entry.path]; ok {
+			d.unwatch(entry)
+			d.poll(entry)
+		}
+	}
+
+	d.exhausted()
+
+	return nil
+}
+
+// exhausted limits the max watches of d to the directories already watched,
+// after the inotify watches of the user are exhausted. d.mu must be held.
+func (d *Dir) exhausted() {
+	log.Warn("Inotify watches exhausted, polling subdirectories instead", "path", d.path, "watched", len(d.watched), "polled", len(d.polled), "limit", "fs.inotify.max_user_watches")
+
+	d.maxWatches = len(d.watched)
+}
+
+// add adds the watch of a new directory at path. If the max watches are
+// reached, the least recently changed subtree is polled instead to make room
+// for it, otherwise the directory itself is polled.
+func (d *Dir) add(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parent, ok := d.watched[filepath.Dir(path)]
+	if !ok || (d.depth > 0 && parent.depth >= d.depth) {
+		return ErrMaxDepth
+	}
+
+	info, err := d.root.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	entry := &dirEntry{
+		parent: parent,
+		path:   path,
+		id:     fileIDOf(info),
+		depth:  parent.depth + 1,
+	}
+
+	// A symlink to a parent would be walked forever
+	for p := parent; p != nil; p = p.parent {
+		if p.id == entry.id {
+			return errNotSupported(path, errors.New("symlink loop"))
+		}
+	}
+
+	parent.childs = append(parent.childs, entry)
+
+	if !d.watchable() {
+		d.demote(entry)
+	}
+
+	if !d.watchable() {
+		d.poll(entry)
+		return nil
+	}
+
+	if err := d.watcher.Add(path); err != nil {
+		if !errors.Is(err, unix.ENOSPC) {
+			parent.childs = parent.childs[:len(parent.childs)-1]
+			return err
+		}
+
+		d.exhausted()
+		d.poll(entry)
+
+		return nil
+	}
+
+	d.watched[path] = entry
+
+	return nil
+}
+
+// demote polls the least recently changed watched subtree, preferring the
+// deepest, that doesn't contain entry. d.mu must be held.
+func (d *Dir) demote(entry *dirEntry) {
+	var lru *dirEntry
+
+	for _, dir := range d.watched {
+		if dir == &d.dirEntry || dir.contains(entry) {
+			continue
+		}
+
+		if lru == nil || dir.used < lru.used || (dir.used == lru.used && dir.depth > lru.depth) {
+			lru = dir
+		}
+	}
+
+	if lru == nil {
+		return
+	}
+
+	log.Debug("Demoting dir to polling", "path", lru.path)
+
+	d.unwatch(lru)
+	d.poll(lru)
+}
+
+// contains reports whether entry is in the subtree of d.
+func (d *dirEntry) contains(entry *dirEntry) bool {
+	for ; entry != nil; entry = entry.parent {
+		if entry == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unwatch removes the watches of entry and its subtree. d.mu must be held.
+func (d *Dir) unwatch(entry *dirEntry) {
+	if _, ok := d.watched[entry.path]; ok {
+		delete(d.watched, entry.path)
+
+		if d.watcher != nil {
+			d.watcher.Remove(entry.path)
+		}
+	}
+
+	delete(d.polled, entry.path)
+
+	for _, child := range entry.childs {
+		d.unwatch(child)
+	}
+}
+
+// update updates the size of the directory at path after an event with op.
+// d.mu must be held.
+func (d *Dir) update(path string, op fsnotify.Op) error {
+	if entry, ok := d.polled[path]; ok {
+		d.updatePoll(entry)
+		return nil
+	}
+
+	dir, ok := d.watched[path]
+	if !ok {
+		return errNotSupported(path, nil)
+	}
+
+	if op.Has(fsnotify.Remove) {
+		log.Debug("Removing watch", "path", path)
+		d.remove(dir)
+
+		return nil
+	}
+
+	info, err := d.root.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	size := uint64(info.Size())
+
+	files, err := d.root.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if info, walk, ok := d.stat(path+vfs.Separator+f.Name(), f); ok && !walk {
+			size += uint64(info.Size())
+		}
+	}
+
+	for _, child := range dir.childs {
+		size += child.size
+	}
+
+	parent := dir.parent
+	for parent != nil {
+		parent.size += size - dir.size
+		parent = parent.parent
+	}
+
+	dir.size = size
+
+	return nil
+}
+
+// updateWatched updates the size of every watched directory and polled
+// subtree. d.mu must be held.
+func (d *Dir) updateWatched() {
+	d.updatePolled()
+
+	for path := range d.watched {
+		d.update(path, fsnotify.Write)
+	}
+}
@@ -0,0 +1,268 @@
+//go:build !nowatch
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestDir_MaxWatches(t *testing.T) {
+	tmp := t.TempDir()
+
+	for _, name := range []string{"a/b", "c"} {
+		if err := os.MkdirAll(filepath.Join(tmp, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fillTestDir(t, filepath.Join(tmp, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newDir := func(maxWatches int) *Dir {
+		t.Helper()
+
+		cfg := config.Default()
+		cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+			MetricConfig: config.MetricConfig{
+				Enabled: true,
+			},
+			Path:       tmp,
+			Watch:      true,
+			MaxWatches: maxWatches,
+		})
+
+		dir, err := NewDir(tmp, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return dir
+	}
+
+	dir := newDir(2)
+
+	// The root and a are watched, and the subtrees of a/b and c are polled
+	if want, got := 2, len(dir.watched); got != want {
+		t.Errorf("Watched: want %d, got %d", want, got)
+	}
+	if want, got := 2, len(dir.polled); got != want {
+		t.Errorf("Polled: want %d, got %d", want, got)
+	}
+	if want, got := newDir(-1).size, dir.size; got != want {
+		t.Errorf("Size: want %d, got %d", want, got)
+	}
+
+	if err := dir.startWatch(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { dir.watcher.Close() })
+
+	// Adding d demotes a, the only watched subdirectory
+	path := filepath.Join(tmp, "d")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fillTestDir(t, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.add(dir.root.Abs(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{tmp, path} {
+		if _, ok := dir.watched[dir.root.Abs(name)]; !ok {
+			t.Errorf("Watched: want %s", name)
+		}
+	}
+	for _, name := range []string{"a", "c"} {
+		if _, ok := dir.polled[dir.root.Abs(filepath.Join(tmp, name))]; !ok {
+			t.Errorf("Polled: want %s", name)
+		}
+	}
+	if want, got := 2, len(dir.polled); got != want {
+		t.Errorf("Polled: want %d, got %d", want, got)
+	}
+	if want, got := newDir(-1).size, dir.size; got != want {
+		t.Errorf("Size: want %d, got %d", want, got)
+	}
+
+	b, err := dir.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"watched": 2, "polled": 2`) {
+		t.Errorf("MarshalJSON: want watched and polled, got %s", b)
+	}
+}
+
+func TestDir_SharedWatcher(t *testing.T) {
+	cfg := config.Default()
+	cfg.Interval = 10 * time.Millisecond
+
+	var sizes []uint64
+
+	for range 2 {
+		tmp := t.TempDir()
+
+		size, err := fillTestDir(t, tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sizes = append(sizes, size)
+		cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+			MetricConfig: config.MetricConfig{
+				Enabled: true,
+			},
+			Path:  tmp,
+			Watch: true,
+		})
+	}
+
+	var dirs []*Dir
+
+	for i := range cfg.Dirs {
+		dir, err := openDir(&cfg.Dirs[i], DefaultsOf(cfg))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// The dirs are started before they're scanned
+	for _, dir := range dirs {
+		if err := dir.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go scanDirs(dirs)
+
+	updated := func(dir *Dir) {
+		t.Helper()
+
+		select {
+		case err := <-dir.Updated():
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Timed out waiting for update", dir.path)
+		}
+	}
+
+	for i, dir := range dirs {
+		updated(dir)
+
+		if want, got := sizes[i], dir.size; got != want {
+			t.Errorf("%s: Size want %d, got %d", dir.path, want, got)
+		}
+	}
+
+	sharedWatcher.mu.Lock()
+	if want, got := 2, len(sharedWatcher.watchers); got != want {
+		t.Errorf("Watchers: want %d, got %d", want, got)
+	}
+	sharedWatcher.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(dirs[1].path, "new"), make([]byte, 123), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated(dirs[1])
+
+	dirs[1].mu.RLock()
+	if want, got := sizes[1]+123, dirs[1].size; got != want {
+		t.Errorf("%s: Size want %d, got %d", dirs[1].path, want, got)
+	}
+	dirs[1].mu.RUnlock()
+
+	cancel()
+
+	for _, dir := range dirs {
+		for range dir.Updated() {
+		}
+	}
+
+	sharedWatcher.mu.Lock()
+	if sharedWatcher.watcher != nil {
+		t.Error("Watcher: want closed after every dir stopped")
+	}
+	sharedWatcher.mu.Unlock()
+}
+
+func TestDir_StopContext(t *testing.T) {
+	tmp := t.TempDir()
+
+	if _, err := fillTestDir(t, tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Interval = 10 * time.Millisecond
+	cfg.Dirs = append(cfg.Dirs, config.DirConfig{
+		MetricConfig: config.MetricConfig{
+			Enabled: true,
+		},
+		Path:  tmp,
+		Watch: true,
+	})
+
+	goroutines := runtime.NumGoroutine()
+	fds := countFDs(t)
+
+	// Fast restarts close the inotify instance of the shared watcher every
+	// time, including when stopped before the initial size is received.
+	for i := range 5 {
+		dir, err := openDir(&cfg.Dirs[0], DefaultsOf(cfg))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dir.Start(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		scanDirs([]*Dir{dir})
+
+		if i%2 == 0 {
+			<-dir.Updated()
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+
+		if err := StopContext(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+
+		cancel()
+
+		sharedWatcher.mu.Lock()
+		if sharedWatcher.watcher != nil {
+			t.Errorf("Watcher: want closed once stopped, %d watchers left", len(sharedWatcher.watchers))
+		}
+		sharedWatcher.mu.Unlock()
+	}
+
+	waitGoroutines(t, goroutines)
+
+	if got := countFDs(t); got > fds {
+		t.Errorf("FDs: want at most %d, got %d", fds, got)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build !nowatch
+
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// sharedWatcher is the inotify watcher shared by every watched [Dir], so that
+// only one inotify instance is used no matter how many are configured. It's
+// opened by the first Dir that is started, and closed once every Dir stops.
+var sharedWatcher watcherGroup
+
+// watcherGroup is a [fsnotify.Watcher] shared by a group of [dirWatcher]. The
+// events of each directory are sent to every dirWatcher watching it.
+type watcherGroup struct {
+	mu       sync.Mutex
+	watcher  *fsnotify.Watcher
+	paths    map[string][]*dirWatcher
+	watchers map[*dirWatcher]struct{}
+}
+
+// dirWatcher watches directories for a single [Dir] with a shared
+// [watcherGroup]. It has the same Events and Errors as [fsnotify.Watcher],
+// which only include the directories it watches.
+type dirWatcher struct {
+	Events chan fsnotify.Event
+	Errors chan error
+
+	group *watcherGroup
+	done  <-chan struct{}
+}
+
+// watch returns a new [dirWatcher] of g, which is closed once ctx is done.
+func (g *watcherGroup) watch(ctx context.Context) (*dirWatcher, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+
+		g.watcher = w
+		g.paths = make(map[string][]*dirWatcher)
+		g.watchers = make(map[*dirWatcher]struct{})
+
+		go g.loop(w)
+	}
+
+	w := &dirWatcher{
+		Events: make(chan fsnotify.Event, 64),
+		Errors: make(chan error, 1),
+		group:  g,
+		done:   ctx.Done(),
+	}
+
+	g.watchers[w] = struct{}{}
+
+	return w, nil
+}
+
+// loop sends the events and errors of w to each dirWatcher of g, until w
+// is closed.
+func (g *watcherGroup) loop(w *fsnotify.Watcher) {
+	defer recoverLoop("dir watcher")
+
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+
+			// An event is of the watched directory itself or a file in it
+			g.mu.Lock()
+			watchers := slices.Concat(g.paths[e.Name], g.paths[filepath.Dir(e.Name)])
+			g.mu.Unlock()
+
+			for i, dw := range watchers {
+				if slices.Contains(watchers[:i], dw) {
+					continue
+				}
+
+				select {
+				case dw.Events <- e:
+				case <-dw.done:
+				}
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+
+			g.mu.Lock()
+
+			for dw := range g.watchers {
+				select {
+				case dw.Errors <- err:
+				default:
+				}
+			}
+
+			g.mu.Unlock()
+		}
+	}
+}
+
+// Add starts watching the directory at path, if it isn't already watched by
+// another dirWatcher of the group.
+func (w *dirWatcher) Add(path string) error {
+	g := w.group
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	watchers := g.paths[path]
+	if slices.Contains(watchers, w) {
+		return nil
+	}
+
+	if len(watchers) == 0 {
+		if err := g.watcher.Add(path); err != nil {
+			return err
+		}
+	}
+
+	g.paths[path] = append(watchers, w)
+
+	return nil
+}
+
+// Remove stops watching the directory at path, unless it's watched by another
+// dirWatcher of the group.
+func (w *dirWatcher) Remove(path string) error {
+	g := w.group
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.remove(w, path)
+}
+
+func (g *watcherGroup) remove(w *dirWatcher, path string) error {
+	watchers := slices.DeleteFunc(g.paths[path], func(dw *dirWatcher) bool {
+		return dw == w
+	})
+
+	if len(watchers) > 0 {
+		g.paths[path] = watchers
+		return nil
+	}
+
+	delete(g.paths, path)
+
+	return g.watcher.Remove(path)
+}
+
+// Close stops watching every directory of w, and closes the watcher of the
+// group if w was the last dirWatcher.
+func (w *dirWatcher) Close() error {
+	g := w.group
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.watchers[w]; !ok {
+		return nil
+	}
+
+	for path, watchers := range g.paths {
+		if slices.Contains(watchers, w) {
+			g.remove(w, path)
+		}
+	}
+
+	delete(g.watchers, w)
+
+	if len(g.watchers) > 0 {
+		return nil
+	}
+
+	err := g.watcher.Close()
+	g.watcher = nil
+	g.paths = nil
+	g.watchers = nil
+
+	return err
+}
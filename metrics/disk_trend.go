@@ -0,0 +1,99 @@
+package metrics
+
+import "time"
+
+const (
+	// defaultPredictionWindow is the window of a [diskTrend] if not configured.
+	defaultPredictionWindow = 7 * 24 * time.Hour
+	// diskTrendSamples is the maximum number of samples in the window of a
+	// [diskTrend], which are evenly spaced regardless of the update interval.
+	diskTrendSamples = 168
+)
+
+// DiskSample is the used space of a disk at a point in time.
+type DiskSample struct {
+	Time time.Time `json:"time"`
+	Used uint64    `json:"used"`
+}
+
+// diskTrend is the linear trend of the used space of a disk over a window of
+// recent samples.
+type diskTrend struct {
+	window  time.Duration
+	samples []DiskSample
+}
+
+func newDiskTrend(window time.Duration) *diskTrend {
+	if window <= 0 {
+		window = defaultPredictionWindow
+	}
+
+	return &diskTrend{window: window}
+}
+
+// add adds the used space sampled at t, unless it is too soon after the last
+// sample. Samples outside of the window are discarded.
+func (d *diskTrend) add(t time.Time, used uint64) {
+	cutoff := t.Add(-d.window)
+
+	i := 0
+	for i < len(d.samples) && d.samples[i].Time.Before(cutoff) {
+		i++
+	}
+
+	if i > 0 {
+		d.samples = append(d.samples[:0], d.samples[i:]...)
+	}
+
+	if n := len(d.samples); n > 0 && t.Sub(d.samples[n-1].Time) < d.window/diskTrendSamples {
+		return
+	}
+
+	d.samples = append(d.samples, DiskSample{Time: t, Used: used})
+}
+
+// rate returns the rate the used space is increasing in bytes per second, by a
+// least squares fit of the samples. The second return value is false if there
+// are not enough samples.
+func (d *diskTrend) rate() (float64, bool) {
+	if len(d.samples) < 2 {
+		return 0, false
+	}
+
+	var (
+		t0       = d.samples[0].Time
+		n        = float64(len(d.samples))
+		sx, sy   float64
+		sxx, sxy float64
+		used0    = float64(d.samples[0].Used)
+	)
+
+	for _, s := range d.samples {
+		x := s.Time.Sub(t0).Seconds()
+		y := float64(s.Used) - used0
+
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, false
+	}
+
+	return (n*sxy - sx*sy) / den, true
+}
+
+// daysUntilFull returns the number of days until the free space is used at
+// the current rate. The second return value is false if the used space is not
+// increasing.
+func (d *diskTrend) daysUntilFull(free uint64) (float64, bool) {
+	rate, ok := d.rate()
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+
+	return float64(free) / rate / (24 * 60 * 60), true
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDiskTrend(t *testing.T) {
+	d := newDiskTrend(0)
+	if want, got := defaultPredictionWindow, d.window; got != want {
+		t.Errorf("window: want %v, got %v", want, got)
+	}
+
+	d = newDiskTrend(10 * time.Hour)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := d.daysUntilFull(1000); ok {
+		t.Error("daysUntilFull: want none without samples, got ok")
+	}
+
+	// 1 GB a day, sampled every hour for a day
+	for i := range 24 {
+		d.add(start.Add(time.Duration(i)*time.Hour), uint64(i)*1e9/24)
+	}
+
+	if want, got := 11, len(d.samples); got != want {
+		t.Errorf("samples: want %d, got %d", want, got)
+	}
+
+	days, ok := d.daysUntilFull(5e9)
+	if !ok || math.Abs(days-5) > 0.01 {
+		t.Errorf("daysUntilFull: want 5, got %v, %v", days, ok)
+	}
+
+	// Too soon after the last sample, but the first sample is now outside of
+	// the window
+	d.add(start.Add(23*time.Hour+time.Minute), 0)
+
+	if want, got := 10, len(d.samples); got != want {
+		t.Errorf("samples: want %d, got %d", want, got)
+	}
+
+	// Freeing space
+	for i := range 10 {
+		d.add(start.Add(time.Duration(24+i)*time.Hour), 1e9-uint64(i)*1e8)
+	}
+
+	if _, ok := d.daysUntilFull(5e9); ok {
+		t.Error("daysUntilFull: want none when decreasing, got ok")
+	}
+}
@@ -2,23 +2,41 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/sys/unix"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
 	"github.com/lone-faerie/mqttop/procfs"
 	"github.com/lone-faerie/mqttop/sysfs"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
+// defaultStaleTimeout is how long to wait for the filesystem of a disk to
+// respond if the stale timeout of the config is 0.
+const defaultStaleTimeout = 5 * time.Second
+
+// errStale is returned by [Disk.statfs] if the filesystem doesn't respond
+// before the stale timeout.
+var errStale = errors.New("filesystem not responding")
+
+type statfsResult struct {
+	stat unix.Statfs_t
+	err  error
+}
+
 // Disk holds the data for each disk monitored by [Disks]
 type Disk struct {
 	procfs.Mount
@@ -33,7 +51,22 @@ type Disk struct {
 	ticks  int64
 	showIO bool
 
-	err error
+	readOnly bool
+	stale    bool
+	timeout  time.Duration
+	// pending is the result of a statfs that hasn't returned yet, which is
+	// waited on by the next update instead of starting another.
+	pending chan statfsResult
+
+	readCounter  Counter
+	writeCounter Counter
+
+	// The trend is nil unless prediction is enabled
+	trend *diskTrend
+	prec  int // precision of the days until full, see [places]
+
+	root *vfs.Root
+	err  error
 }
 
 // Disks implements the [Metric] interface to provide the system disks
@@ -43,10 +76,15 @@ type Disks struct {
 	disks  map[string]*Disk
 	showIO bool
 
+	payload payload.Disks
+
 	cfg      *config.DisksConfig
+	proc     procfs.FS
+	sys      sysfs.FS
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
+	prec     int // precision of the disks without their own, see [places]
 
 	rescanInterval time.Duration
 	rescanTick     *time.Ticker
@@ -58,7 +96,11 @@ type Disks struct {
 }
 
 func (d *Disks) newDisk(mnt *procfs.Mount, cfg *config.DiskConfig) *Disk {
-	disk := &Disk{Mount: *mnt}
+	disk := &Disk{Mount: *mnt, root: d.sys.Root(), timeout: defaultStaleTimeout}
+
+	if d.cfg != nil && d.cfg.StaleTimeout > 0 {
+		disk.timeout = d.cfg.StaleTimeout
+	}
 
 	if cfg != nil && cfg.Name != "" {
 		disk.Name = cfg.Name
@@ -69,18 +111,63 @@ func (d *Disks) newDisk(mnt *procfs.Mount, cfg *config.DiskConfig) *Disk {
 	}
 
 	if d.showIO || (cfg != nil && cfg.ShowIO) {
-		disk.BlockIO = sysfs.BlockStat(mnt)
+		disk.BlockIO = d.sys.BlockStat(mnt)
 		disk.showIO = disk.BlockIO.IsValid()
 	}
 
+	if d.cfg != nil && d.cfg.Prediction.Enabled {
+		disk.trend = newDiskTrend(d.cfg.Prediction.Window)
+	}
+
+	if cfg != nil && cfg.Precision != 0 {
+		disk.prec = cfg.Precision
+	} else {
+		disk.prec = d.prec
+	}
+
 	return disk
 }
 
+// uniqueName returns name suffixed with "_2", "_3", etc. if another disk
+// already has the name, such as two mounts with the same base name.
+func (d *Disks) uniqueName(name string) string {
+	taken := func(name string) bool {
+		for _, disk := range d.disks {
+			if disk.Name == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !taken(name) {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		if s := name + "_" + strconv.Itoa(i); !taken(s) {
+			return s
+		}
+	}
+}
+
 // NewCPU returns a new [Disks] initialized from cfg. If there is any error
 // encountered while initializing the Disks, a non-nil error that wraps
 // [ErrNotSupported] is returned.
 func NewDisks(cfg *config.Config) (*Disks, error) {
-	d := &Disks{cfg: &cfg.Disks}
+	return NewDisksFromConfig(cfg.Disks, DefaultsOf(cfg))
+}
+
+// NewDisksFromConfig is like [NewDisks] but is initialized from the config of
+// the metric and defaults instead of a full [config.Config].
+func NewDisksFromConfig(cfg config.DisksConfig, defaults Defaults) (*Disks, error) {
+	d := &Disks{
+		cfg:  &cfg,
+		proc: procfs.NewFS(defaults.Root),
+		sys:  sysfs.NewFS(defaults.Root),
+		prec: defaults.precision(cfg.Precision),
+	}
 
 	if err := d.rescan(true); err != nil {
 		return nil, errNotSupported(d.Type(), err)
@@ -88,25 +175,25 @@ func NewDisks(cfg *config.Config) (*Disks, error) {
 
 	log.Info("Found disks", "count", len(d.disks))
 
-	if cfg.Disks.Interval > 0 {
-		d.interval = cfg.Disks.Interval
-	} else {
+	if cfg.Interval > 0 {
 		d.interval = cfg.Interval
+	} else {
+		d.interval = defaults.Interval
 	}
 
-	if cfg.Disks.Topic != "" {
-		d.topic = cfg.Disks.Topic
-	} else if cfg.BaseTopic != "" {
-		d.topic = cfg.BaseTopic + "/metric/disks"
+	if cfg.Topic != "" {
+		d.topic = cfg.Topic
+	} else if defaults.BaseTopic != "" {
+		d.topic = defaults.BaseTopic + "/metric/disks"
 	} else {
 		d.topic = "mqttop/metric/disks"
 	}
 
-	if cfg.Disks.RescanInterval > 0 {
-		d.rescanInterval = cfg.Disks.RescanInterval
+	if cfg.RescanInterval > 0 {
+		d.rescanInterval = cfg.RescanInterval
 	}
 
-	d.showIO = cfg.Disks.ShowIO
+	d.showIO = cfg.ShowIO
 
 	return d, nil
 }
@@ -134,7 +221,17 @@ func (dsk *Disks) SetInterval(d time.Duration) {
 	dsk.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (dsk *Disks) Interval() time.Duration {
+	dsk.mu.RLock()
+	defer dsk.mu.RUnlock()
+
+	return dsk.interval
+}
+
 func (d *Disks) loop(ctx context.Context) {
+	defer recoverLoop(d.Type())
+
 	d.mu.Lock()
 
 	d.tick = time.NewTicker(d.interval)
@@ -227,7 +324,7 @@ func (d *Disks) Start(ctx context.Context) (err error) {
 }
 
 func (d *Disks) rescan(firstRun bool) error {
-	mnts, err := procfs.MountInfo(d.cfg.UseFSTab)
+	mnts, err := d.proc.MountInfo(d.cfg.UseFSTab)
 	if err != nil {
 		return err
 	}
@@ -240,14 +337,17 @@ func (d *Disks) rescan(firstRun bool) error {
 
 	var changed bool
 
-	for name, mnt := range mnts {
+	// Disks are added in order of their mount point so that any names
+	// suffixed by uniqueName are the same each time.
+	for _, name := range slices.Sorted(maps.Keys(mnts)) {
 		if d.cfg.Excluded(name) {
 			continue
 		}
 
 		if _, ok := d.disks[name]; !ok {
 			dcfg := d.cfg.ConfigFor(name)
-			disk := d.newDisk(mnt, dcfg)
+			disk := d.newDisk(mnts[name], dcfg)
+			disk.Name = d.uniqueName(disk.Name)
 
 			if err := disk.Update(); err != nil {
 				log.Error("can't add disk", err, "path", disk.Mnt)
@@ -306,11 +406,13 @@ func (d *Disks) Rescan() error {
 // Update forces the disks metric to update. The returned error will not
 // be sent on the channel returned by [Disks.Updated] unlike updates that
 // happen automatically every update interval.
-func (d *Disks) Update() error {
+func (d *Disks) Update() (err error) {
+	defer errUpdate(d.Type(), time.Now(), &err)
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	var group errgroup.Group
+	var group updateGroup
 
 	for name := range d.disks {
 		group.Go(d.disks[name].Update)
@@ -371,46 +473,158 @@ func (d *Disks) String() string {
 	return b.String()
 }
 
-// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
-// representation of d to b.
-func (d *Disks) AppendText(b []byte) ([]byte, error) {
-	b = append(b, '{')
+func (disk *Disk) toPayload(p *payload.Disk) {
+	p.Mnt = disk.Mnt
+	p.Total = payload.Size(byteutil.ScaleSize(disk.total, disk.size))
+	p.Free = payload.Size(byteutil.ScaleSize(disk.free, disk.size))
+	p.Used = payload.Size(byteutil.ScaleSize(disk.used, disk.size))
+	p.Reads = payload.Maybe(disk.reads, disk.showIO)
+	p.Writes = payload.Maybe(disk.writes, disk.showIO)
+	p.ReadTotal = payload.Maybe(disk.readCounter.Total, disk.showIO)
+	p.WriteTotal = payload.Maybe(disk.writeCounter.Total, disk.showIO)
+	p.DaysUntilFull = payload.Optional[payload.Milli]{}
+	p.ReadOnly = disk.readOnly
+	p.Stale = disk.stale
+
+	if disk.trend != nil {
+		if days, ok := disk.trend.daysUntilFull(disk.free); ok {
+			p.DaysUntilFull = payload.Some(payload.Milli(days * 1000).Round(places(disk.prec)))
+		}
+	}
+}
 
-	first := true
+func (disk *Disk) fromPayload(p *payload.Disk) {
+	disk.total = byteutil.UnscaleSize(uint64(p.Total), disk.size)
+	disk.free = byteutil.UnscaleSize(uint64(p.Free), disk.size)
+	disk.used = byteutil.UnscaleSize(uint64(p.Used), disk.size)
+
+	if p.Reads.Valid && p.Writes.Valid {
+		disk.reads = p.Reads.Value
+		disk.writes = p.Writes.Value
+		disk.showIO = true
+	}
+
+	disk.readCounter.Total = p.ReadTotal.Value
+	disk.writeCounter.Total = p.WriteTotal.Value
+	disk.readOnly = p.ReadOnly
+	disk.stale = p.Stale
+}
+
+// Counters implements [Counterer] and returns the counters of the bytes read
+// and written by each disk, keyed by "<mount>:read" and "<mount>:write".
+func (d *Disks) Counters() map[string]Counter {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	counters := make(map[string]Counter, 2*len(d.disks))
+
+	for mnt, disk := range d.disks {
+		if !disk.showIO {
+			continue
+		}
+
+		counters[mnt+":read"] = disk.readCounter
+		counters[mnt+":write"] = disk.writeCounter
+	}
+
+	return counters
+}
+
+// SetCounters implements [Counterer] and restores the counters returned by
+// [Disks.Counters]. This should be called before d is started.
+func (d *Disks) SetCounters(counters map[string]Counter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for mnt, disk := range d.disks {
+		if c, ok := counters[mnt+":read"]; ok {
+			disk.readCounter = c
+		}
+
+		if c, ok := counters[mnt+":write"]; ok {
+			disk.writeCounter = c
+		}
+	}
+}
+
+// Samples returns the samples of the used space of each disk that are used to
+// predict when the disk will be full, keyed by the mount point of the disk. If
+// prediction is not enabled, the result is empty.
+func (d *Disks) Samples() map[string][]DiskSample {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	samples := make(map[string][]DiskSample)
+
+	for mnt, disk := range d.disks {
+		if disk.trend != nil {
+			samples[mnt] = slices.Clone(disk.trend.samples)
+		}
+	}
+
+	return samples
+}
+
+// SetSamples restores the samples returned by [Disks.Samples]. Samples outside
+// of the prediction window are discarded on the next update. This should be
+// called before d is started.
+func (d *Disks) SetSamples(samples map[string][]DiskSample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for mnt, disk := range d.disks {
+		if s, ok := samples[mnt]; ok && disk.trend != nil {
+			// Samples taken since d was created are more recent
+			disk.trend.samples = append(slices.Clone(s), disk.trend.samples...)
+		}
+	}
+}
+
+func (d *Disks) toPayload(p payload.Disks) {
+	clear(p)
 
 	for _, disk := range d.disks {
 		if disk.err != nil {
 			continue
 		}
 
-		if !first {
-			b = append(b, ',', ' ')
-		}
+		var dp payload.Disk
+
+		disk.toPayload(&dp)
+		p[disk.Name] = dp
+	}
+}
+
+func (d *Disks) fromPayload(p payload.Disks) {
+	if d.disks == nil {
+		d.disks = make(map[string]*Disk, len(p))
+	}
 
-		b = append(b, '"')
-		b = append(b, disk.Name...)
-		b = append(b, "\": {\"mnt\": \""...)
-		b = append(b, disk.Mnt...)
-		b = append(b, "\", \"total\": "...)
-		b = byteutil.AppendSize(b, disk.total, disk.size)
-		b = append(b, ", \"free\": "...)
-		b = byteutil.AppendSize(b, disk.free, disk.size)
-		b = append(b, ", \"used\": "...)
-		b = byteutil.AppendSize(b, disk.used, disk.size)
-
-		if disk.showIO {
-			b = append(b, ", \"reads\": "...)
-			b = strconv.AppendInt(b, disk.reads, 10)
-			b = append(b, ", \"writes\": "...)
-			b = strconv.AppendInt(b, disk.writes, 10)
+	for name, dp := range p {
+		disk, ok := d.disks[dp.Mnt]
+		if !ok {
+			disk = &Disk{Mount: procfs.Mount{Mnt: dp.Mnt}}
+			d.disks[dp.Mnt] = disk
 		}
 
-		b = append(b, '}')
+		disk.Name = name
+		disk.fromPayload(&dp)
+	}
+}
 
-		first = false
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of d to b.
+func (d *Disks) AppendText(b []byte) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.payload == nil {
+		d.payload = make(payload.Disks, len(d.disks))
 	}
 
-	return append(b, '}'), nil
+	d.toPayload(d.payload)
+
+	return d.payload.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [CPU.AppendText](nil).
@@ -418,14 +632,42 @@ func (d *Disks) MarshalJSON() ([]byte, error) {
 	return d.AppendText(nil)
 }
 
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of disks, as produced by [Disks.MarshalJSON], into d. Disks
+// are matched by their mount point, and the sizes of each disk are interpreted
+// in the size units it was configured with. Any disks not already in d are
+// added with sizes in bytes.
+func (d *Disks) UnmarshalJSON(data []byte) error {
+	var p payload.Disks
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.fromPayload(p)
+	d.mu.Unlock()
+
+	return nil
+}
+
 // Update forces the individual disk to update. The returned error will not
 // be sent on the channel returned by [Disks.Updated] unlike updates that
 // happen automatically every update interval.
 func (d *Disk) Update() (err error) {
 	d.err = nil
 
-	stat, err := file.Statfs(d.Mnt)
-	if err != nil {
+	stat, err := d.statfs()
+	if err == errStale {
+		if d.stale {
+			return ErrNoChange
+		}
+
+		log.Warn("Disk not responding", "mnt", d.Mnt, "timeout", d.timeout)
+		d.stale = true
+
+		return nil
+	} else if err != nil {
 		d.err = err
 		return
 	}
@@ -433,14 +675,25 @@ func (d *Disk) Update() (err error) {
 	total := stat.Blocks * uint64(stat.Frsize)
 	free := stat.Bavail * uint64(stat.Frsize)
 	used := total - free
+	readOnly := stat.Flags&unix.ST_RDONLY != 0
 
-	if d.used == used && d.free == free && d.total == total {
+	if d.used == used && d.free == free && d.total == total && d.readOnly == readOnly && !d.stale {
 		err = ErrNoChange
 	}
 
+	if readOnly && !d.readOnly && d.total != 0 {
+		log.Warn("Disk remounted read-only", "mnt", d.Mnt)
+	}
+
 	d.total = total
 	d.free = free
 	d.used = used
+	d.readOnly = readOnly
+	d.stale = false
+
+	if d.trend != nil {
+		d.trend.add(time.Now(), used)
+	}
 
 	if !d.showIO {
 		return
@@ -462,5 +715,41 @@ func (d *Disk) Update() (err error) {
 	d.writes = w
 	d.ticks = t
 
+	rt, wt := d.BlockIO.Total()
+	d.readCounter.Update(uint64(rt))
+	d.writeCounter.Update(uint64(wt))
+
 	return
 }
+
+// statfs stats the filesystem of d, or returns errStale if it doesn't respond
+// within the stale timeout. A system call on a filesystem that stopped
+// responding can't be interrupted, so it's left to return in the background
+// and is waited on again by the next call instead of starting another.
+func (d *Disk) statfs() (unix.Statfs_t, error) {
+	if d.timeout <= 0 {
+		return d.root.Statfs(d.Mnt)
+	}
+
+	if d.pending == nil {
+		ch := make(chan statfsResult, 1)
+
+		go func() {
+			stat, err := d.root.Statfs(d.Mnt)
+			ch <- statfsResult{stat, err}
+		}()
+
+		d.pending = ch
+	}
+
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-d.pending:
+		d.pending = nil
+		return res.stat, res.err
+	case <-timer.C:
+		return unix.Statfs_t{}, errStale
+	}
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+)
+
+func TestDisks_UniqueName(t *testing.T) {
+	d := &Disks{disks: make(map[string]*Disk)}
+
+	var tests = []struct {
+		mnt  string
+		want string
+	}{
+		{"/", "root"},
+		{"/mnt/data", "data"},
+		{"/media/data", "data_2"},
+		{"/srv/data", "data_3"},
+		{"/data_2", "data_2_2"},
+	}
+	for _, tt := range tests {
+		disk := d.newDisk(&procfs.Mount{Mnt: tt.mnt}, nil)
+		disk.Name = d.uniqueName(disk.Name)
+
+		if disk.Name != tt.want {
+			t.Errorf("%s: want name %q, got %q", tt.mnt, tt.want, disk.Name)
+		}
+
+		d.disks[tt.mnt] = disk
+	}
+}
+
+func TestDisk_Stale(t *testing.T) {
+	disk := &Disk{Mount: procfs.Mount{Mnt: t.TempDir()}, timeout: 10 * time.Millisecond}
+
+	stat, err := disk.root.Statfs(disk.Mnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A statfs that hasn't returned is waited on again instead of starting another
+	pending := make(chan statfsResult, 1)
+	disk.pending = pending
+
+	if err := disk.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if !disk.stale {
+		t.Error("Update: want stale")
+	}
+	if err := disk.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	stat.Flags |= unix.ST_RDONLY
+	pending <- statfsResult{stat: stat}
+
+	if err := disk.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if disk.pending != nil {
+		t.Error("Update: want no pending statfs")
+	}
+
+	var p payload.Disk
+	disk.toPayload(&p)
+
+	if p.Stale {
+		t.Error("Stale: want false")
+	}
+	if !p.ReadOnly {
+		t.Error("ReadOnly: want true")
+	}
+}
+
+func TestDisks_Rescan(t *testing.T) {
+	const mounts = "/dev/sda1 / ext4 rw,relatime 0 0\n" +
+		"/dev/sda2 /mnt/data ext4 rw 0 0\n" +
+		"/dev/sdb1 /media/data xfs rw 0 0\n" +
+		"/dev/sdc1 /mnt/gone ext4 rw 0 0\n" +
+		"tmpfs /run tmpfs rw 0 0\n"
+
+	var tests = []struct {
+		name string
+		yaml string
+		want map[string]string // names by mount point
+	}{
+		{
+			// The mount point of a disk is statted within the root, so
+			// /mnt/gone, which doesn't exist, isn't added.
+			"Default",
+			"disks:\n  use_fstab: false",
+			map[string]string{"/": "root", "/media/data": "data", "/mnt/data": "data_2"},
+		},
+		{
+			"Config",
+			"disks:\n  use_fstab: false\n  disk:\n    - mount: /media/data\n      exclude: true\n    - mount: /mnt/data\n      name: storage",
+			map[string]string{"/": "root", "/mnt/data": "storage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			writeFiles(t, dir, map[string]string{
+				"proc/filesystems": "nodev\ttmpfs\n\text4\n\txfs\n",
+				"proc/1/mounts":    mounts,
+			})
+
+			for _, mnt := range []string{"mnt/data", "media/data"} {
+				if err := os.MkdirAll(filepath.Join(dir, mnt), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := config.Read(strings.NewReader(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewDisksFromConfig(cfg.Disks, Defaults{Root: testRoot(t, dir)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := slices.Sorted(maps.Keys(d.disks)); !slices.Equal(got, slices.Sorted(maps.Keys(tt.want))) {
+				t.Fatalf("want disks %q, got %q", slices.Sorted(maps.Keys(tt.want)), got)
+			}
+
+			for mnt, disk := range d.disks {
+				if want := tt.want[mnt]; disk.Name != want {
+					t.Errorf("%s: want name %q, got %q", mnt, want, disk.Name)
+				}
+				if disk.total == 0 {
+					t.Errorf("%s: want total", mnt)
+				}
+			}
+
+			if err := d.Rescan(); err != ErrNoChange {
+				t.Errorf("Rescan: want %v, got %v", ErrNoChange, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sysfs"
+)
+
+var energyNow = time.Now
+
+// microjoulesPerWh is the number of µJ in a Wh, which is the unit of the energy
+// in the payload since it's in kWh with 3 decimal places.
+const microjoulesPerWh = 3.6e9
+
+// energyZone is a single RAPL zone of [Energy].
+type energyZone struct {
+	sysfs.RAPLZone
+
+	last  int64 // last value of the counter in µJ
+	total int64 // energy since the metric started in µJ
+	power int64 // µW
+}
+
+func (z *energyZone) update(elapsed time.Duration) (changed bool, err error) {
+	e, err := z.ReadEnergy()
+	if err != nil {
+		return false, err
+	}
+
+	delta := e - z.last
+
+	// The counter wraps around to 0 once it reaches the max energy range. If
+	// the range is unknown, the counter is assumed to have restarted from 0.
+	if delta < 0 {
+		if z.MaxEnergy > 0 {
+			delta += z.MaxEnergy
+		} else {
+			delta = e
+		}
+	}
+
+	z.last = e
+	z.total += delta
+
+	var power int64
+	if elapsed > 0 {
+		power = int64(float64(delta) / elapsed.Seconds())
+	}
+
+	changed = delta != 0 || power != z.power
+	z.power = power
+
+	return
+}
+
+// Energy implements the [Metric] interface to provide the power and energy of
+// the RAPL (Running Average Power Limit) zones of the CPU, such as the package,
+// cores and DRAM. The energy is accumulated from when the metric is created, and
+// the power is the average power since the last update.
+type Energy struct {
+	zones []energyZone
+	prec  int // precision of the power, see [places]
+	last  time.Time
+
+	payload payload.Energy
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewEnergy returns a new [Energy] initialized from cfg. If there are no RAPL
+// zones or their energy counters can't be read, which usually requires root, a
+// non-nil error that wraps [ErrNotSupported] is returned.
+func NewEnergy(cfg *config.Config) (*Energy, error) {
+	return NewEnergyFromConfig(cfg.Energy, DefaultsOf(cfg))
+}
+
+// NewEnergyFromConfig is like [NewEnergy] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewEnergyFromConfig(cfg config.EnergyConfig, d Defaults) (*Energy, error) {
+	e := &Energy{
+		prec: d.precision(cfg.Precision),
+	}
+
+	zones, err := sysfs.NewFS(d.Root).RAPLZones()
+	if err != nil {
+		return nil, errNotSupported(e.Type(), err)
+	}
+
+	e.zones = make([]energyZone, 0, len(zones))
+
+	for i := range zones {
+		zone := energyZone{RAPLZone: zones[i]}
+
+		// The counter is read once so that the first update only accounts for
+		// the energy used since the metric was created.
+		zone.last, err = zone.ReadEnergy()
+		if err != nil {
+			log.WarnError("Unable to read RAPL energy, try running as root", err, "zone", zone.Name)
+			continue
+		}
+
+		e.zones = append(e.zones, zone)
+	}
+
+	if len(e.zones) == 0 {
+		if err == nil {
+			err = ErrNotFound
+		}
+
+		return nil, errNotSupported(e.Type(), err)
+	}
+
+	e.last = energyNow()
+
+	if cfg.Interval > 0 {
+		e.interval = cfg.Interval
+	} else {
+		e.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		e.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		e.topic = d.BaseTopic + "/metric/energy"
+	} else {
+		e.topic = "mqttop/metric/energy"
+	}
+
+	return e, nil
+}
+
+// Type returns the metric type, "energy".
+func (*Energy) Type() string {
+	return "energy"
+}
+
+// Topic returns the topic to publish energy metrics to.
+func (e *Energy) Topic() string {
+	return e.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (e *Energy) SetInterval(d time.Duration) {
+	e.mu.Lock()
+
+	if e.tick != nil && d != e.interval {
+		e.tick.Reset(d)
+	}
+
+	e.interval = d
+
+	e.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (e *Energy) Interval() time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.interval
+}
+
+func (e *Energy) loop(ctx context.Context) {
+	defer recoverLoop(e.Type())
+
+	e.mu.Lock()
+	e.tick = time.NewTicker(e.interval)
+	e.mu.Unlock()
+
+	defer e.tick.Stop()
+	defer close(e.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("energy started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.tick.C:
+			err = e.Update()
+			if err == ErrNoChange {
+				log.Debug("energy updated, no change")
+			} else {
+				log.Debug("energy updated")
+			}
+
+			ch = e.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the energy metric updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (e *Energy) Start(ctx context.Context) (err error) {
+	if e.interval == 0 {
+		log.Warn("Energy interval is 0, not starting")
+		return
+	}
+
+	e.once.Do(func() {
+		ctx, e.stop = context.WithCancel(ctx)
+		e.ch = make(chan error)
+
+		go e.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the energy metric to update. The returned error will not
+// be sent on the channel returned by [Energy.Updated] unlike updates that
+// happen automatically every update interval.
+func (e *Energy) Update() (err error) {
+	defer errUpdate(e.Type(), time.Now(), &err)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := energyNow()
+	elapsed := now.Sub(e.last)
+	e.last = now
+
+	var changed bool
+
+	for i := range e.zones {
+		c, err := e.zones[i].update(elapsed)
+		if err != nil {
+			return err
+		}
+
+		changed = changed || c
+	}
+
+	if !changed {
+		return ErrNoChange
+	}
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (e *Energy) Updated() <-chan error {
+	return e.ch
+}
+
+// Stop stops the Energy from continuing to update. Once stopped, the Energy
+// may not be restarted.
+func (e *Energy) Stop() {
+	e.mu.Lock()
+
+	if e.stop != nil {
+		e.stop()
+	}
+
+	e.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the number of zones and the
+// power of the first zone, which is usually the first CPU package.
+func (e *Energy) String() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.zones) == 0 {
+		return "0 zones"
+	}
+
+	z := &e.zones[0]
+
+	return fmt.Sprintf("%d zones (%s %.1f W)", len(e.zones), z.Name, payload.Micro(z.power).Float64())
+}
+
+func (e *Energy) toPayload(p payload.Energy) {
+	clear(p)
+
+	for i := range e.zones {
+		z := &e.zones[i]
+
+		// The energy isn't rounded so that it's always reported in Wh.
+		p[z.Name] = payload.EnergyZone{
+			Power:  payload.Micro(z.power).Round(places(e.prec)),
+			Energy: payload.Milli(float64(z.total) / microjoulesPerWh),
+		}
+	}
+}
+
+func (e *Energy) fromPayload(p payload.Energy) {
+	for name, v := range p {
+		i := 0
+		for i < len(e.zones) && e.zones[i].Name != name {
+			i++
+		}
+
+		if i == len(e.zones) {
+			e.zones = append(e.zones, energyZone{RAPLZone: sysfs.RAPLZone{Name: name}})
+		}
+
+		e.zones[i].power = int64(v.Power)
+		e.zones[i].total = int64(float64(v.Energy) * microjoulesPerWh)
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of e to b.
+func (e *Energy) AppendText(b []byte) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.payload == nil {
+		e.payload = make(payload.Energy, len(e.zones))
+	}
+
+	e.toPayload(e.payload)
+
+	return e.payload.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Energy.AppendText](nil).
+func (e *Energy) MarshalJSON() ([]byte, error) {
+	return e.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of energy, as produced by [Energy.MarshalJSON], into e. Any
+// zones not already in e are added.
+func (e *Energy) UnmarshalJSON(data []byte) error {
+	var p payload.Energy
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.fromPayload(p)
+	e.mu.Unlock()
+
+	return nil
+}
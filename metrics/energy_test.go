@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+func TestEnergy(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	energyNow = func() time.Time { return now }
+	t.Cleanup(func() { energyNow = time.Now })
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sys/class/powercap/intel-rapl/enabled":                    "1\n",
+		"sys/class/powercap/intel-rapl:0/name":                     "package-0\n",
+		"sys/class/powercap/intel-rapl:0/energy_uj":                "1000000\n",
+		"sys/class/powercap/intel-rapl:0/max_energy_range_uj":      "262143328850\n",
+		"sys/class/powercap/intel-rapl:0:0/name":                   "core\n",
+		"sys/class/powercap/intel-rapl:0:0/energy_uj":              "262143000000\n",
+		"sys/class/powercap/intel-rapl:0:0/max_energy_range_uj":    "262143328850\n",
+		"sys/class/powercap/intel-rapl-mmio:0/name":                "package-0\n",
+		"sys/class/powercap/intel-rapl-mmio:0/energy_uj":           "5\n",
+		"sys/class/powercap/intel-rapl-mmio:0/max_energy_range_uj": "262143328850\n",
+	})
+
+	d := Defaults{Root: testRoot(t, dir), Interval: time.Second}
+
+	e, err := NewEnergyFromConfig(config.EnergyConfig{}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "energy", e.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := "mqttop/metric/energy", e.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+
+	if err := e.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	// 25 J over 2 s, and the core counter wraps around after 328850 µJ.
+	now = now.Add(2 * time.Second)
+	writeFiles(t, dir, map[string]string{
+		"sys/class/powercap/intel-rapl:0/energy_uj":   "26000000\n",
+		"sys/class/powercap/intel-rapl:0:0/energy_uj": "9671150\n",
+	})
+
+	if err := e.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "2 zones (package-0 12.5 W)", e.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+
+	// 7.2 kJ over an hour is 2 W and 2 Wh.
+	now = now.Add(time.Hour)
+	writeFiles(t, dir, map[string]string{
+		"sys/class/powercap/intel-rapl:0/energy_uj": "7226000000\n",
+	})
+
+	if err := e.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := e.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Energy
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	want := payload.Energy{
+		"package-0":      {Power: 2000000, Energy: 2},
+		"package-0_core": {Power: 0, Energy: 0},
+	}
+
+	if len(p) != len(want) {
+		t.Errorf("want %d zones, got %d: %s", len(want), len(p), b)
+	}
+	for name, w := range want {
+		if got := p[name]; got != w {
+			t.Errorf("%s: want %+v, got %+v", name, w, got)
+		}
+	}
+
+	var ee Energy
+	if err := json.Unmarshal(b, &ee); err != nil {
+		t.Fatal(err)
+	}
+	var pp payload.Energy
+	if bb, err := ee.MarshalJSON(); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(bb, &pp); err != nil {
+		t.Fatal(err)
+	}
+	for name, w := range p {
+		if got := pp[name]; got != w {
+			t.Errorf("round trip %s: want %+v, got %+v", name, w, got)
+		}
+	}
+
+	disc := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	e.Discover(disc)
+
+	if want, got := 4, len(disc.Components); got != want {
+		t.Errorf("want %d components, got %d", want, got)
+	}
+
+	cmp, ok := disc.Components["mqttop_energy_package-0_energy"]
+	if !ok {
+		t.Fatal("missing component mqttop_energy_package-0_energy")
+	}
+	if want, got := "total_increasing", cmp[discovery.StateClass]; got != want {
+		t.Errorf("state class: want %v, got %v", want, got)
+	}
+	if want, got := "kWh", cmp[discovery.UnitOfMeasurement]; got != want {
+		t.Errorf("unit: want %v, got %v", want, got)
+	}
+}
+
+func TestEnergy_Unsupported(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sys/class/powercap/dtpm/name": "dtpm\n",
+	})
+
+	_, err := NewEnergyFromConfig(config.DefaultEnergy, Defaults{Root: testRoot(t, dir)})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("want %v, got %v", ErrNotSupported, err)
+	}
+}
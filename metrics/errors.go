@@ -3,6 +3,12 @@ package metrics
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/lone-faerie/mqttop/log"
 )
 
 var (
@@ -11,18 +17,173 @@ var (
 	ErrMaxDepth       = errors.New("max depth exceeded")
 	ErrNoChange       = errors.New("no change")
 	ErrNotFound       = errors.New("not found")
+	ErrNotPermitted   = errors.New("not permitted")
 	ErrNotSupported   = errors.New("not supported")
 	ErrRescanned      = errors.New("rescanned")
 )
 
+// The kinds of [Error]. ErrPermission and ErrMissingHardware wrap
+// [ErrNotSupported], so any error of either kind is also not supported.
+var (
+	// ErrPermission is the kind of error caused by insufficient permission to
+	// read the metric, which won't succeed if retried.
+	ErrPermission = &kindError{"permission denied", ErrNotSupported}
+	// ErrMissingHardware is the kind of error caused by the hardware of the
+	// metric not being present, such as a system without a battery or a device
+	// that has been removed.
+	ErrMissingHardware = &kindError{"missing hardware", ErrNotSupported}
+	// ErrTransient is the kind of error of an update that may succeed if
+	// retried, such as a failed read.
+	ErrTransient = errors.New("temporary failure")
+)
+
+// kindError is a kind of error that wraps a more general kind.
+type kindError struct {
+	msg    string
+	parent error
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.parent }
+
+// Error is the error returned by the constructors and Update methods of the
+// metrics. It wraps both its kind, which is one of [ErrNotSupported],
+// [ErrPermission], [ErrMissingHardware] or [ErrTransient], and the error that
+// caused it, so either may be checked with [errors.Is]. For example:
+//
+//	switch {
+//	case errors.Is(err, metrics.ErrTransient):
+//		// Retry the update
+//	case errors.Is(err, metrics.ErrPermission):
+//		// Disable the metric
+//	case errors.Is(err, metrics.ErrNotSupported):
+//		// Skip the metric
+//	}
+type Error struct {
+	Op     string // "new" or "update"
+	Metric string // Type of the metric, or the path of a directory
+	Kind   error  // Kind of the error
+	Err    error  // Error that caused it, which may be nil
+}
+
+func (e *Error) Error() string {
+	var s string
+
+	if e.Op == "update" {
+		s = e.Metric + " update failed"
+		if e.Kind != nil && e.Kind != ErrTransient {
+			s += ": " + e.Kind.Error()
+		}
+	} else {
+		s = e.Metric + " is " + ErrNotSupported.Error()
+		if e.Kind != nil && e.Kind != ErrNotSupported {
+			s += ": " + e.Kind.Error()
+		}
+	}
+
+	if e.Err != nil {
+		s += " (" + e.Err.Error() + ")"
+	}
+
+	return s
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+
+	return []error{e.Kind, e.Err}
+}
+
+// kindOf returns the kind of error caused by err, or def if it is none of the
+// more specific kinds.
+func kindOf(err error, def error) error {
+	switch {
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, ErrPermission):
+		return ErrPermission
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, ErrNotFound), errors.Is(err, ErrMissingHardware),
+		errors.Is(err, syscall.ENODEV), errors.Is(err, syscall.ENXIO):
+		return ErrMissingHardware
+	case errors.Is(err, ErrTransient):
+		return ErrTransient
+	}
+
+	return def
+}
+
+// PanicError is the cause of an error of a metric that panicked, such as while
+// parsing an unexpected format of a file. The panic is recovered so that only
+// the metric fails instead of the whole process.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover recovers a panic and sets *err to a [*PanicError] of it. It must be
+// deferred directly by the function that calls into a metric, such as
+//
+//	defer metrics.Recover(&err)
+func Recover(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// recoverLoop recovers a panic in the update loop of metric and logs it. It is
+// meant to be deferred first by the loops, so that the channel of updates is
+// still closed and the metric stops.
+func recoverLoop(metric string) {
+	if r := recover(); r != nil {
+		err := &PanicError{Value: r, Stack: debug.Stack()}
+		log.Error("Recovered panic in "+metric+" loop", err, "stack", string(err.Stack))
+	}
+}
+
 func errAlreadyRunning(metric string) error {
 	return fmt.Errorf("%s is %w", metric, ErrAlreadyRunning)
 }
 
+// errNotSupported returns an [Error] of a constructor of metric, which is of
+// the kind of err, or of [ErrNotSupported] if none.
 func errNotSupported(metric string, err error) error {
-	return fmt.Errorf("%s is %w (%w)", metric, ErrNotSupported, err)
+	return &Error{Op: "new", Metric: metric, Kind: kindOf(err, ErrNotSupported), Err: err}
+}
+
+// errUpdate sets *err to an [Error] of an update of metric, which is of the
+// kind of *err, or of [ErrTransient] if none. It is meant to be deferred by
+// Update methods, with the time the update started, which is recorded for
+// [LastUpdate]. If *err is nil, [ErrNoChange] or [ErrRescanned], or is
+// already an [Error], it is left as is. A panic during the update is recovered
+// as an [ErrTransient] caused by a [*PanicError].
+func errUpdate(metric string, start time.Time, err *error) {
+	lastUpdates.Store(metric, [2]time.Time{start, time.Now()})
+
+	if r := recover(); r != nil {
+		*err = &Error{Op: "update", Metric: metric, Kind: ErrTransient, Err: &PanicError{Value: r, Stack: debug.Stack()}}
+		return
+	}
+
+	switch *err {
+	case nil, ErrNoChange, ErrRescanned:
+		return
+	}
+
+	if _, ok := (*err).(*Error); ok {
+		return
+	}
+
+	*err = &Error{Op: "update", Metric: metric, Kind: kindOf(*err, ErrTransient), Err: *err}
 }
 
 func errNotFound(metric string) error {
 	return fmt.Errorf("%s was %w", metric, ErrNotFound)
 }
+
+func errNotPermitted(control string) error {
+	return fmt.Errorf("%s control is %w", control, ErrNotPermitted)
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestErrNotSupported(t *testing.T) {
+	var tests = []struct {
+		name string
+		err  error
+		kind error
+		msg  string
+	}{
+		{"Permission", fs.ErrPermission, ErrPermission, "cpu is not supported: permission denied (permission denied)"},
+		{"NotExist", fmt.Errorf("open /sys/class/power_supply: %w", fs.ErrNotExist), ErrMissingHardware, "cpu is not supported: missing hardware (open /sys/class/power_supply: file does not exist)"},
+		{"NotFound", errNotFound("fans"), ErrMissingHardware, "cpu is not supported: missing hardware (fans was not found)"},
+		{"NoDevice", syscall.ENODEV, ErrMissingHardware, "cpu is not supported: missing hardware (no such device)"},
+		{"Executable", exec.ErrNotFound, ErrNotSupported, "cpu is not supported (executable file not found in $PATH)"},
+		{"Nil", nil, ErrNotSupported, "cpu is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errNotSupported("cpu", tt.err)
+
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("want *Error, got %T", err)
+			}
+			if e.Kind != tt.kind {
+				t.Errorf("Kind: want %v, got %v", tt.kind, e.Kind)
+			}
+			if !errors.Is(err, ErrNotSupported) {
+				t.Errorf("want error wrapping %v, got %v", ErrNotSupported, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("want error wrapping %v, got %v", tt.err, err)
+			}
+			if got := err.Error(); got != tt.msg {
+				t.Errorf("Error: want %q, got %q", tt.msg, got)
+			}
+		})
+	}
+}
+
+func TestErrUpdate(t *testing.T) {
+	for _, want := range []error{nil, ErrNoChange, ErrRescanned} {
+		err := want
+		if errUpdate("memory", time.Now(), &err); err != want {
+			t.Errorf("want %v, got %v", want, err)
+		}
+	}
+
+	var tests = []struct {
+		name string
+		err  error
+		kind error
+		msg  string
+	}{
+		{"Transient", syscall.EIO, ErrTransient, "memory update failed (input/output error)"},
+		{"Permission", fs.ErrPermission, ErrPermission, "memory update failed: permission denied (permission denied)"},
+		{"Missing", syscall.ENXIO, ErrMissingHardware, "memory update failed: missing hardware (no such device or address)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err
+			errUpdate("memory", time.Now(), &err)
+
+			if !errors.Is(err, tt.kind) || !errors.Is(err, tt.err) {
+				t.Errorf("want error wrapping %v and %v, got %v", tt.kind, tt.err, err)
+			}
+			if got := errors.Is(err, ErrNotSupported); got == (tt.kind == ErrTransient) {
+				t.Errorf("Is ErrNotSupported: want %v, got %v", !got, got)
+			}
+			if got := err.Error(); got != tt.msg {
+				t.Errorf("Error: want %q, got %q", tt.msg, got)
+			}
+
+			// An update error is not wrapped again
+			wrapped := err
+			if errUpdate("memory", time.Now(), &err); err != wrapped {
+				t.Errorf("want %v, got %v", wrapped, err)
+			}
+		})
+	}
+}
+
+func TestErrUpdate_Panic(t *testing.T) {
+	update := func() (err error) {
+		defer errUpdate("memory", time.Now(), &err)
+
+		var fields []string
+		_ = fields[1]
+
+		return nil
+	}
+
+	err := update()
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("want *PanicError, got %v", err)
+	}
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("want error wrapping %v, got %v", ErrTransient, err)
+	}
+	if len(pe.Stack) == 0 {
+		t.Error("Stack: want stack trace")
+	}
+
+	want := "memory update failed (panic: runtime error: index out of range [1] with length 0)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error: want %q, got %q", want, got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	ch := make(chan error)
+
+	go func() {
+		defer close(ch)
+		defer recoverLoop("memory")
+
+		panic("loop")
+	}()
+
+	if _, ok := <-ch; ok {
+		t.Error("want closed channel after panic")
+	}
+
+	encode := func() (err error) {
+		defer Recover(&err)
+
+		panic("encode")
+	}
+
+	var pe *PanicError
+	if err := encode(); !errors.As(err, &pe) || pe.Value != "encode" {
+		t.Errorf("want *PanicError of %q, got %v", "encode", err)
+	}
+}
+
+func TestLastUpdate(t *testing.T) {
+	start := time.Now()
+
+	update := func() (err error) {
+		defer errUpdate("memory", start, &err)
+		return nil
+	}
+
+	update()
+
+	gotStart, end, ok := LastUpdate(&Memory{})
+	if !ok {
+		t.Fatal("want updated")
+	}
+
+	if !gotStart.Equal(start) || end.Before(start) {
+		t.Errorf("want %v to end after it, got %v to %v", start, gotStart, end)
+	}
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// runCommand runs the named program and returns its output. The locale is set
+// to C so the output may be parsed.
+var runCommand = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+
+	return cmd.Output()
+}
+
+// lookPath returns the path of the named program, see [exec.LookPath].
+var lookPath = exec.LookPath
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// fakeExec replaces the commands run by metrics. Each command is
+// looked up by its name and arguments joined with spaces. Commands that aren't
+// found in out fail with exec.ErrNotFound, and every command run is recorded.
+type fakeExec struct {
+	installed []string
+	out       map[string]string
+	ran       []string
+}
+
+func (f *fakeExec) install(t *testing.T) {
+	t.Helper()
+
+	run, look := runCommand, lookPath
+	t.Cleanup(func() {
+		runCommand, lookPath = run, look
+	})
+
+	runCommand = func(_ context.Context, name string, arg ...string) ([]byte, error) {
+		cmd := strings.Join(append([]string{name}, arg...), " ")
+		f.ran = append(f.ran, cmd)
+
+		out, ok := f.out[cmd]
+		if !ok {
+			return nil, exec.ErrNotFound
+		}
+
+		return []byte(out), nil
+	}
+	lookPath = func(file string) (string, error) {
+		for _, name := range f.installed {
+			if name == file {
+				return "/usr/bin/" + name, nil
+			}
+		}
+
+		return "", exec.ErrNotFound
+	}
+}
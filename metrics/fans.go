@@ -0,0 +1,559 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sysfs"
+)
+
+// fan is a single fan of [Fans].
+type fan struct {
+	sysfs.Fan
+
+	id    string
+	name  string // configured name, if any
+	speed int64
+	pwm   int64
+	mode  int64
+
+	// restore is the mode of the fan before it was first set to manual
+	// control, or -1 if it hasn't been set.
+	restore int64
+	control bool
+}
+
+func (f *fan) update() (changed bool, err error) {
+	speed, err := f.ReadSpeed()
+	if err != nil {
+		return
+	}
+
+	changed = speed != f.speed
+	f.speed = speed
+
+	if !f.HasPWM() {
+		return
+	}
+
+	pwm, err := f.ReadPWM()
+	if err != nil {
+		return
+	}
+
+	mode, err := f.ReadPWMMode()
+	if err != nil {
+		mode, err = -1, nil
+	}
+
+	changed = changed || pwm != f.pwm || mode != f.mode
+	f.pwm = pwm
+	f.mode = mode
+
+	return
+}
+
+func pwmMode(mode int64) string {
+	switch {
+	case mode < sysfs.PWMFull:
+		return ""
+	case mode == sysfs.PWMFull:
+		return "full"
+	case mode == sysfs.PWMManual:
+		return "manual"
+	}
+
+	return "auto"
+}
+
+// Fans implements the [Metric] interface to provide the fan metrics of every
+// hwmon device. This includes the speed of each fan, and the PWM duty cycle and
+// mode of fans with PWM control. If enabled by the controls config, the duty
+// cycle of the fans may also be set.
+type Fans struct {
+	fans []fan
+
+	minPWM int
+	maxPWM int
+
+	payload payload.Fans
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewFans returns a new [Fans] initialized from cfg. If there are no fans on
+// the system, a non-nil error that wraps [ErrNotSupported] is returned.
+func NewFans(cfg *config.Config) (*Fans, error) {
+	return NewFansFromConfig(cfg.Fans, DefaultsOf(cfg))
+}
+
+// NewFansFromConfig is like [NewFans] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewFansFromConfig(cfg config.FansConfig, d Defaults) (*Fans, error) {
+	f := &Fans{}
+
+	fans, err := sysfs.NewFS(d.Root).HWMonFans()
+	if err != nil {
+		return nil, errNotSupported(f.Type(), err)
+	}
+
+	f.fans = make([]fan, 0, len(fans))
+	seen := make(map[string]int, len(fans))
+
+	for i := range fans {
+		id := fans[i].ID()
+		if n := seen[id]; n > 0 {
+			seen[id]++
+			id += "_" + strconv.Itoa(n+1)
+		} else {
+			seen[id] = 1
+		}
+
+		var name string
+
+		if fcfg := cfg.ConfigFor(id); fcfg != nil {
+			if fcfg.Exclude {
+				continue
+			}
+
+			name = fcfg.Name
+		}
+
+		f.fans = append(f.fans, fan{Fan: fans[i], id: id, name: name, mode: -1, restore: -1})
+	}
+
+	if len(f.fans) == 0 {
+		return nil, errNotSupported(f.Type(), ErrNotFound)
+	}
+
+	if ctl := d.Controls.Fans; ctl.Enabled {
+		f.minPWM = min(max(ctl.MinPWM, 0), 255)
+
+		if ctl.MaxPWM > 0 {
+			f.maxPWM = max(min(ctl.MaxPWM, 255), f.minPWM)
+		} else {
+			f.maxPWM = 255
+		}
+
+		for i := range f.fans {
+			if !f.fans[i].HasPWM() {
+				continue
+			}
+
+			if f.fans[i].Writable() {
+				f.fans[i].control = true
+			} else {
+				log.Warn("Fan control is enabled but not permitted, try running as root", "path", f.fans[i].PWM)
+			}
+		}
+	}
+
+	if cfg.Interval > 0 {
+		f.interval = cfg.Interval
+	} else {
+		f.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		f.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		f.topic = d.BaseTopic + "/metric/fans"
+	} else {
+		f.topic = "mqttop/metric/fans"
+	}
+
+	return f, nil
+}
+
+// Type returns the metric type, "fans".
+func (*Fans) Type() string {
+	return "fans"
+}
+
+// Topic returns the topic to publish fan metrics to.
+func (f *Fans) Topic() string {
+	return f.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (f *Fans) SetInterval(d time.Duration) {
+	f.mu.Lock()
+
+	if f.tick != nil && d != f.interval {
+		f.tick.Reset(d)
+	}
+
+	f.interval = d
+
+	f.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (f *Fans) Interval() time.Duration {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.interval
+}
+
+func (f *Fans) loop(ctx context.Context) {
+	defer recoverLoop(f.Type())
+
+	f.mu.Lock()
+	f.tick = time.NewTicker(f.interval)
+	f.mu.Unlock()
+
+	defer f.tick.Stop()
+	defer close(f.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("fans started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.tick.C:
+			err = f.Update()
+			if err == ErrNoChange {
+				log.Debug("fans updated, no change")
+			} else {
+				log.Debug("fans updated")
+			}
+
+			ch = f.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the fans updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (f *Fans) Start(ctx context.Context) (err error) {
+	if f.interval == 0 {
+		log.Warn("Fans interval is 0, not starting")
+		return
+	}
+
+	f.once.Do(func() {
+		ctx, f.stop = context.WithCancel(ctx)
+		f.ch = make(chan error)
+
+		go f.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the fans metric to update. The returned error will not
+// be sent on the channel returned by [Fans.Updated] unlike updates that
+// happen automatically every update interval.
+func (f *Fans) Update() (err error) {
+	defer errUpdate(f.Type(), time.Now(), &err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var changed bool
+
+	for i := range f.fans {
+		c, err := f.fans[i].update()
+		if err != nil {
+			return err
+		}
+
+		changed = changed || c
+	}
+
+	if !changed {
+		return ErrNoChange
+	}
+
+	return nil
+}
+
+func (f *Fans) find(id string) (*fan, error) {
+	for i := range f.fans {
+		if f.fans[i].id == id {
+			return &f.fans[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown fan %q", id)
+}
+
+// SetPWM sets the PWM duty cycle of the fan with the given ID, switching it to
+// manual control if needed. The duty cycle is clamped to the minimum and maximum
+// of the controls config. An error wrapping [ErrNotPermitted] is returned if fan
+// control is not enabled for the fan.
+func (f *Fans) SetPWM(id string, pwm int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fan, err := f.find(id)
+	if err != nil {
+		return err
+	}
+
+	if !fan.control {
+		return errNotPermitted("fan")
+	}
+
+	pwm = min(max(pwm, f.minPWM), f.maxPWM)
+
+	mode, err := fan.ReadPWMMode()
+	if err != nil {
+		return err
+	}
+
+	if mode != sysfs.PWMManual {
+		if err := fan.SetPWMMode(sysfs.PWMManual); err != nil {
+			return err
+		}
+
+		if fan.restore < 0 {
+			fan.restore = mode
+		}
+	}
+
+	fan.mode = sysfs.PWMManual
+
+	if err := fan.Fan.SetPWM(pwm); err != nil {
+		return err
+	}
+
+	fan.pwm = int64(pwm)
+
+	return nil
+}
+
+// SetAuto returns the fan with the given ID to the mode it was in before it was
+// first set with [Fans.SetPWM], or to automatic control if it hasn't been set.
+// An error wrapping [ErrNotPermitted] is returned if fan control is not enabled
+// for the fan.
+func (f *Fans) SetAuto(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fan, err := f.find(id)
+	if err != nil {
+		return err
+	}
+
+	if !fan.control {
+		return errNotPermitted("fan")
+	}
+
+	mode := fan.restore
+	if mode < 0 {
+		mode = sysfs.PWMAuto
+	}
+
+	if err := fan.SetPWMMode(mode); err != nil {
+		return err
+	}
+
+	fan.mode = mode
+	fan.restore = -1
+
+	return nil
+}
+
+// restore returns every fan set with [Fans.SetPWM] to the mode it was in before.
+func (f *Fans) restore() {
+	for i := range f.fans {
+		fan := &f.fans[i]
+		if fan.restore < 0 {
+			continue
+		}
+
+		if err := fan.SetPWMMode(fan.restore); err != nil {
+			log.WarnError("Unable to restore fan mode", err, "fan", fan.id)
+			continue
+		}
+
+		fan.mode = fan.restore
+		fan.restore = -1
+	}
+}
+
+// parsePWM parses the payload of a PWM command, which may be a decimal number
+// as published by a Home Assistant number.
+func parsePWM(payload []byte) (int, error) {
+	v, err := strconv.ParseFloat(string(bytes.TrimSpace(payload)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PWM payload %q", payload)
+	}
+
+	return int(math.Round(v)), nil
+}
+
+// Commands implements [Commander]. The duty cycle of each fan with control
+// enabled may be set on the "/<fan>/pwm/set" subtopic, or "auto" may be
+// published to return the fan to automatic control.
+func (f *Fans) Commands() map[string]Command {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	cmds := make(map[string]Command)
+
+	for i := range f.fans {
+		if !f.fans[i].control {
+			continue
+		}
+
+		id := f.fans[i].id
+
+		cmds[id+"/pwm/set"] = func(payload []byte) error {
+			if bytes.EqualFold(bytes.TrimSpace(payload), []byte("auto")) {
+				return f.SetAuto(id)
+			}
+
+			pwm, err := parsePWM(payload)
+			if err != nil {
+				return err
+			}
+
+			return f.SetPWM(id, pwm)
+		}
+	}
+
+	return cmds
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (f *Fans) Updated() <-chan error {
+	return f.ch
+}
+
+// Stop stops the Fans from continuing to update, and returns any fans that were
+// set to manual control to the mode they were in before. Once stopped, the Fans
+// may not be restarted.
+func (f *Fans) Stop() {
+	f.mu.Lock()
+
+	if f.stop != nil {
+		f.stop()
+	}
+
+	f.restore()
+
+	f.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the number of fans.
+func (f *Fans) String() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var pwm int
+
+	for i := range f.fans {
+		if f.fans[i].HasPWM() {
+			pwm++
+		}
+	}
+
+	return fmt.Sprintf("%d fans (%d with PWM)", len(f.fans), pwm)
+}
+
+func (f *Fans) toPayload(p payload.Fans) {
+	clear(p)
+
+	for i := range f.fans {
+		fan := &f.fans[i]
+
+		p[fan.id] = payload.Fan{
+			Label: fan.Label,
+			Speed: fan.speed,
+			PWM:   payload.Maybe(int(fan.pwm), fan.HasPWM()),
+			Mode:  pwmMode(fan.mode),
+		}
+	}
+}
+
+func (f *Fans) fromPayload(p payload.Fans) {
+	for id, pf := range p {
+		ff, err := f.find(id)
+		if err != nil {
+			f.fans = append(f.fans, fan{id: id, mode: -1, restore: -1})
+			ff = &f.fans[len(f.fans)-1]
+		}
+
+		ff.Label = pf.Label
+		ff.speed = pf.Speed
+
+		if pf.PWM.Valid {
+			ff.pwm = int64(pf.PWM.Value)
+		}
+
+		switch pf.Mode {
+		case "full":
+			ff.mode = sysfs.PWMFull
+		case "manual":
+			ff.mode = sysfs.PWMManual
+		case "auto":
+			ff.mode = sysfs.PWMAuto
+		}
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of f to b.
+func (f *Fans) AppendText(b []byte) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.payload == nil {
+		f.payload = make(payload.Fans, len(f.fans))
+	}
+
+	f.toPayload(f.payload)
+
+	return f.payload.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Fans.AppendText](nil).
+func (f *Fans) MarshalJSON() ([]byte, error) {
+	return f.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of fans, as produced by [Fans.MarshalJSON], into f. Any fans
+// not already in f are added without PWM control.
+func (f *Fans) UnmarshalJSON(data []byte) error {
+	var p payload.Fans
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.fromPayload(p)
+	f.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sysfs"
+)
+
+func testFans(t *testing.T) (*Fans, *config.Config) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+
+	fans, err := NewFans(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fans == nil {
+		t.Fatal("fans is nil")
+	}
+
+	return fans, cfg
+}
+
+func TestFans(t *testing.T) {
+	fans, cfg := testFans(t)
+
+	if want, got := "fans", fans.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := cfg.Fans.Topic, fans.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := "3 fans (2 with PWM)", fans.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+	if cmds := fans.Commands(); len(cmds) != 0 {
+		t.Errorf("Commands: want none without fan control, got %d", len(cmds))
+	}
+}
+
+func TestFans_Config(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Fans.Fan = []config.FanConfig{
+		{Fan: "nct6798_fan1", Name: "CPU fan"},
+		{Fan: "nct6798_fan3", Exclude: true},
+	}
+
+	fans, err := NewFans(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "2 fans (2 with PWM)", fans.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	fans.Discover(d)
+
+	var tests = []struct {
+		id   string
+		name string
+	}{
+		{"mqttop_fan_nct6798_fan1_speed", "CPU fan speed"},
+		{"mqttop_fan_nct6798_fan1_pwm", "CPU fan PWM"},
+		{"mqttop_fan_nct6798_fan2_speed", "nct6798 fan2 speed"},
+	}
+	for _, tt := range tests {
+		if got := d.Components[tt.id][discovery.Name]; got != tt.name {
+			t.Errorf("%s: Wanted name %q, got %q", tt.id, tt.name, got)
+		}
+	}
+
+	if _, ok := d.Components["mqttop_fan_nct6798_fan3_speed"]; ok {
+		t.Error("fan3: want excluded")
+	}
+
+	cfg.Fans.Fan = []config.FanConfig{{Fan: "nct6798_fan1", Exclude: true}, {Fan: "nct6798_fan2", Exclude: true}, {Fan: "nct6798_fan3", Exclude: true}}
+
+	if _, err := NewFans(cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("want error wrapping %v, got %v", ErrNotSupported, err)
+	}
+}
+
+func TestFans_Limits(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"sys/class/hwmon/hwmon0/name":       "nct6798\n",
+		"sys/class/hwmon/hwmon0/fan1_input": "1200\n",
+		"sys/class/hwmon/hwmon0/fan1_min":   "300\n",
+		"sys/class/hwmon/hwmon0/fan1_max":   "2400\n",
+		"sys/class/hwmon/hwmon0/fan2_input": "900\n",
+	})
+
+	cfg := config.Default()
+	cfg.RootFS = root
+
+	fans, err := NewFans(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	fans.Discover(d)
+
+	if want, got := "{{ {'min': 300, 'max': 2400} | tojson }}", d.Components["mqttop_fan_nct6798_fan1_speed"][discovery.JSONAttributesTemplate]; got != want {
+		t.Errorf("fan1: want attributes %q, got %q", want, got)
+	}
+	if got, ok := d.Components["mqttop_fan_nct6798_fan2_speed"][discovery.JSONAttributesTemplate]; ok {
+		t.Errorf("fan2: want no attributes, got %q", got)
+	}
+}
+
+func TestFans_Update(t *testing.T) {
+	fans, _ := testFans(t)
+
+	if err := fans.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fans.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Fans
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		id   string
+		want payload.Fan
+	}{
+		{"nct6798_fan1", payload.Fan{Label: "fan1", Speed: 1180, PWM: payload.Some(102), Mode: "auto"}},
+		{"nct6798_fan2", payload.Fan{Label: "fan2", Speed: 842, PWM: payload.Some(77), Mode: "manual"}},
+		{"nct6798_fan3", payload.Fan{Label: "fan3"}},
+	}
+	for _, tt := range tests {
+		if got := p[tt.id]; got != tt.want {
+			t.Errorf("%s: Wanted %+v, got %+v", tt.id, tt.want, got)
+		}
+	}
+
+	if err := fans.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+}
+
+func TestFans_SetPWM(t *testing.T) {
+	fans, _ := testFans(t)
+
+	if err := fans.SetPWM("nct6798_fan1", 128); !errors.Is(err, ErrNotPermitted) {
+		t.Errorf("SetPWM: want %v, got %v", ErrNotPermitted, err)
+	}
+
+	// Copy the hwmon device so the fixtures aren't modified.
+	fan, _ := fans.find("nct6798_fan1")
+	dir := strings.TrimPrefix(filepath.Dir(fan.PWM), testRoot(t, "testdata/fixtures").Dir())
+	root := t.TempDir()
+	copyFixtures(t, root, dir)
+	if err := os.MkdirAll(filepath.Join(root, "sys", "class", "hwmon"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, dir), filepath.Join(root, "sys", "class", "hwmon", filepath.Base(dir))); err != nil {
+		t.Fatal(err)
+	}
+
+	hwmon, err := sysfs.NewFS(testRoot(t, root)).HWMonFans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range hwmon {
+		if filepath.Base(hwmon[i].PWM) == filepath.Base(fan.PWM) {
+			fan.Fan = hwmon[i]
+		}
+	}
+
+	path := fan.PWM
+
+	if !fan.Writable() {
+		t.Fatal("Writable: want true")
+	}
+
+	fan.control = true
+	fans.minPWM, fans.maxPWM = 60, 200
+
+	cmd, ok := fans.Commands()["nct6798_fan1/pwm/set"]
+	if !ok {
+		t.Fatal("Commands: want nct6798_fan1/pwm/set with fan control")
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	var tests = []struct {
+		payload string
+		pwm     string
+		mode    string
+		err     bool
+	}{
+		{"128", "128", "1", false},
+		{"12.6", "60", "1", false},
+		{"255", "200", "1", false},
+		{"fast", "200", "1", true},
+		{"auto", "200", "5", false},
+	}
+	for _, tt := range tests {
+		err := cmd([]byte(tt.payload))
+		if (err != nil) != tt.err {
+			t.Errorf("%q: Wanted error %v, got %v", tt.payload, tt.err, err)
+		}
+		if got := read(path); got != tt.pwm {
+			t.Errorf("%q: Wanted pwm %q, got %q", tt.payload, tt.pwm, got)
+		}
+		if got := read(path + "_enable"); got != tt.mode {
+			t.Errorf("%q: Wanted mode %q, got %q", tt.payload, tt.mode, got)
+		}
+	}
+
+	if err := cmd([]byte("90")); err != nil {
+		t.Fatal(err)
+	}
+
+	fans.Stop()
+
+	if want, got := "5", read(path+"_enable"); got != want {
+		t.Errorf("Stop: want mode %q, got %q", want, got)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	fans.Discover(d)
+
+	cmp := d.Components["mqttop_fan_nct6798_fan1_pwm"]
+	if want, got := discovery.Number, cmp[discovery.Platform]; got != want {
+		t.Errorf("PWM platform: want %v, got %v", want, got)
+	}
+	if want, got := 60, cmp[discovery.Min]; got != want {
+		t.Errorf("PWM min: want %v, got %v", want, got)
+	}
+	if want, got := discovery.Sensor, d.Components["mqttop_fan_nct6798_fan2_pwm"][discovery.Platform]; got != want {
+		t.Errorf("PWM platform: want %v, got %v", want, got)
+	}
+	if _, ok := d.Components["mqttop_fan_nct6798_fan3_pwm"]; ok {
+		t.Error("fan3: want no PWM component")
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+// Feature is a collector, or an optional part of one, and whether it was
+// compiled in. Features with a non-empty Tag may be excluded by building
+// with that tag, e.g. "nogpu" to exclude the NVML dependency.
+type Feature struct {
+	Name    string
+	Tag     string
+	Enabled bool
+}
+
+// Features returns the features of the metrics and whether each was
+// compiled in.
+func Features() []Feature {
+	return []Feature{
+		{Name: "cpu", Enabled: true},
+		{Name: "memory", Enabled: true},
+		{Name: "disks", Enabled: true},
+		{Name: "net", Enabled: true},
+		{Name: "battery", Enabled: true},
+		{Name: "fans", Enabled: true},
+		{Name: "audio", Enabled: true},
+		{Name: "idle", Enabled: true},
+		{Name: "processes", Enabled: true},
+		{Name: "system", Enabled: true},
+		{Name: "smart", Enabled: true},
+		{Name: "energy", Enabled: true},
+		{Name: "dirs", Enabled: true},
+		{Name: "dirs (watch)", Tag: "nowatch", Enabled: watchSupported},
+		{Name: "gpu (nvidia)", Tag: "nogpu", Enabled: gpuSupported},
+		{Name: "gpu (sysfs)", Enabled: true},
+		{Name: "gpu (intel)", Enabled: true},
+	}
+}
@@ -2,6 +2,45 @@
 
 package metrics
 
-import "github.com/lone-faerie/mqttop/config"
+import (
+	"errors"
 
-func appendGPU(m []Metric, _ *config.Config) []Metric { return m }
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// gpuSupported indicates whether GPU metrics were compiled in.
+const gpuSupported = false
+
+// newGPU returns the degraded [SysfsGPU] of cfg if the platform is "sysfs",
+// or the [IntelGPU] if the platform is "intel", since NVML is not compiled in.
+func newGPU(cfg *config.Config) (Metric, error) {
+	switch cfg.GPU.Platform {
+	case "sysfs":
+		return constructor(NewSysfsGPU)(cfg)
+	case "intel":
+		return constructor(NewIntelGPU)(cfg)
+	}
+
+	return nil, errNotSupported("gpu", errors.New("not compiled in"))
+}
+
+// appendGPU appends the GPU of cfg to m if the platform is "sysfs" or
+// "intel". Since NVML is not compiled in, any other GPU is skipped without an
+// error.
+func appendGPU(m []Metric, cfg *config.Config) ([]Metric, error) {
+	if cfg.GPU.Platform == "sysfs" || cfg.GPU.Platform == "intel" {
+		gpu, err := newGPU(cfg)
+		if err != nil {
+			return m, err
+		}
+
+		return append(m, gpu), nil
+	}
+
+	if cfg.GPU.Platform != "" {
+		log.Warn("GPU platform configured but GPU support was not compiled in", "platform", cfg.GPU.Platform, "tag", "nogpu")
+	}
+
+	return m, nil
+}
@@ -0,0 +1,423 @@
+package metrics
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/sysfs"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+var intelGPUNow = time.Now
+
+// IntelGPU implements the [Metric] interface to provide metrics of an Intel
+// GPU driven by i915 or xe. The frequency and RC6 residency are read from the
+// sysfs attributes of the driver, and the utilization of the GPU is the time
+// it was not in RC6. The utilization of each engine class is read from the
+// fdinfo of the DRM clients of the GPU, where the driver reports it.
+type IntelGPU struct {
+	Name string
+
+	driver string
+	pdev   string // PCI bus location
+
+	clock sysfsGPUValue // MHz
+	rc6   sysfsGPUValue // ms
+
+	rc6Pct   uint32
+	rc6Time  time.Time // time rc6 was last read
+	rc6Ready bool      // whether rc6 has been read twice
+	util     uint32
+
+	clients     map[uint64]map[string]procfs.DRMEngine // by client id
+	clientsTime time.Time
+	engines     map[string]uint32 // %
+
+	index    int
+	sys      sysfs.FS
+	proc     procfs.FS
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewIntelGPU returns a new [IntelGPU] initialized from cfg. If there is any
+// error encountered while initializing the GPU, a non-nil error that wraps
+// [ErrNotSupported] is returned.
+func NewIntelGPU(cfg *config.Config) (*IntelGPU, error) {
+	return NewIntelGPUFromConfig(cfg.GPU, DefaultsOf(cfg))
+}
+
+// NewIntelGPUFromConfig is like [NewIntelGPU] but is initialized from the
+// config of the metric and d instead of a full [config.Config].
+func NewIntelGPUFromConfig(cfg config.GPUConfig, d Defaults) (*IntelGPU, error) {
+	g := &IntelGPU{index: cfg.Index, sys: sysfs.NewFS(d.Root), proc: procfs.NewFS(d.Root)}
+
+	devs, err := g.sys.GPUDevices(sysfs.Intel)
+	if err != nil {
+		return nil, errNotSupported(g.Type(), err)
+	}
+
+	if g.index < 0 || g.index >= len(devs) {
+		return nil, errNotSupported(g.Type(), errNotFound("gpu "+strconv.Itoa(g.index)))
+	}
+
+	dev := devs[g.index]
+
+	g.driver = g.sys.DeviceDriver(dev)
+	if g.driver != "i915" && g.driver != "xe" {
+		return nil, errNotSupported(g.Type(), errNotFound("i915 or xe driver"))
+	}
+
+	g.init(&cfg, dev)
+
+	if cfg.Interval > 0 {
+		g.interval = cfg.Interval
+	} else {
+		g.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		g.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		g.topic = d.BaseTopic + "/metric/gpu"
+	} else {
+		g.topic = "mqttop/metric/gpu"
+	}
+
+	return g, nil
+}
+
+func (g *IntelGPU) init(cfg *config.GPUConfig, dev string) {
+	g.Name = cfg.FormatName("Intel GPU")
+	g.pdev = filepath.Base(dev)
+
+	exists := func(v *sysfsGPUValue, paths ...string) {
+		for _, path := range paths {
+			if g.sys.Root().Exists(path) {
+				v.path = path
+				return
+			}
+		}
+	}
+
+	switch g.driver {
+	case "i915":
+		card := g.sys.DeviceDRMCard(dev)
+		if card == "" {
+			break
+		}
+
+		gt := card + vfs.Separator + "gt" + vfs.Separator + "gt0"
+
+		exists(&g.clock, gt+vfs.Separator+"rps_act_freq_mhz", card+vfs.Separator+"gt_act_freq_mhz", card+vfs.Separator+"gt_cur_freq_mhz")
+		exists(&g.rc6, gt+vfs.Separator+"rc6_residency_ms", card+vfs.Separator+"power"+vfs.Separator+"rc6_residency_ms")
+	case "xe":
+		gt := dev + vfs.Separator + "tile0" + vfs.Separator + "gt0"
+
+		exists(&g.clock, gt+vfs.Separator+"freq0"+vfs.Separator+"act_freq")
+		exists(&g.rc6, gt+vfs.Separator+"gtidle"+vfs.Separator+"idle_residency_ms")
+	}
+
+	log.Debug("Intel GPU initialized", "device", dev, "driver", g.driver, "clock", g.clock.path, "rc6", g.rc6.path)
+}
+
+// Type returns the metric type, "gpu".
+func (g *IntelGPU) Type() string {
+	return "gpu"
+}
+
+// Topic returns the topic to publish gpu metrics to.
+func (g *IntelGPU) Topic() string {
+	return g.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (g *IntelGPU) SetInterval(d time.Duration) {
+	g.mu.Lock()
+
+	if g.tick != nil && d != g.interval {
+		g.tick.Reset(d)
+	}
+
+	g.interval = d
+
+	g.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (g *IntelGPU) Interval() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.interval
+}
+
+func (g *IntelGPU) loop(ctx context.Context) {
+	defer recoverLoop(g.Type())
+
+	g.mu.Lock()
+	g.tick = time.NewTicker(g.interval)
+	g.mu.Unlock()
+
+	defer g.tick.Stop()
+	defer close(g.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("gpu started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.tick.C:
+			err = g.Update()
+			if err == ErrNoChange {
+				log.Debug("gpu updated, no change")
+			} else {
+				log.Debug("gpu updated")
+			}
+
+			ch = g.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the gpu updating. If ctx is cancelled or
+// times out, the metric will stop and may not be restarted.
+func (g *IntelGPU) Start(ctx context.Context) error {
+	if g.interval == 0 {
+		log.Warn("GPU interval is 0, not starting")
+		return nil
+	}
+
+	g.once.Do(func() {
+		ctx, g.stop = context.WithCancel(ctx)
+		g.ch = make(chan error)
+
+		go g.loop(ctx)
+	})
+
+	return nil
+}
+
+// percentOf returns the percent of n in total, at most 100.
+func percentOf(n, total uint64) uint32 {
+	if total == 0 {
+		return 0
+	}
+
+	return uint32(min(100*n/total, 100))
+}
+
+// updateRC6 reads the RC6 residency and updates the percent of time the GPU
+// was in RC6 since it was last read.
+func (g *IntelGPU) updateRC6(now time.Time) bool {
+	prev := g.rc6.value
+
+	if changed := g.rc6.read(g.sys.Root()); !g.rc6.valid() {
+		return changed
+	}
+
+	last := g.rc6Time
+	g.rc6Time = now
+
+	if last.IsZero() {
+		return false
+	}
+
+	g.rc6Ready = true
+
+	var pct uint32
+
+	if elapsed := now.Sub(last).Milliseconds(); elapsed > 0 && g.rc6.value >= prev {
+		pct = percentOf(g.rc6.value-prev, uint64(elapsed))
+	}
+
+	changed := pct != g.rc6Pct || g.util != 100-pct
+	g.rc6Pct, g.util = pct, 100-pct
+
+	return changed
+}
+
+// updateEngines reads the usage of the DRM clients of the GPU and updates the
+// utilization of each engine class since it was last read. Only the clients
+// present in both reads are counted.
+func (g *IntelGPU) updateEngines(now time.Time) bool {
+	procs, err := g.proc.Procs()
+	if err != nil {
+		log.Debug("Unable to read processes", "err", err)
+		return false
+	}
+
+	clients := make(map[uint64]map[string]procfs.DRMEngine)
+
+	for _, p := range procs {
+		cs, err := g.proc.DRMClients(p)
+		if err != nil {
+			continue
+		}
+
+		for _, c := range cs {
+			if c.Driver == g.driver && c.PDev == g.pdev && len(c.Engines) > 0 {
+				clients[c.ID] = c.Engines
+			}
+		}
+	}
+
+	type usage struct {
+		busy, total, capacity uint64
+	}
+
+	var (
+		usages  = make(map[string]usage)
+		elapsed = uint64(now.Sub(g.clientsTime).Nanoseconds())
+	)
+
+	for id, engines := range clients {
+		for class, e := range engines {
+			u := usages[class]
+			u.capacity = max(u.capacity, e.Capacity)
+
+			if p, ok := g.clients[id][class]; ok && e.Busy >= p.Busy && e.Total >= p.Total {
+				u.busy += e.Busy - p.Busy
+				u.total = max(u.total, e.Total-p.Total)
+			}
+
+			usages[class] = u
+		}
+	}
+
+	first := g.clients == nil
+	g.clients, g.clientsTime = clients, now
+
+	if first {
+		return false
+	}
+
+	engines := make(map[string]uint32, max(len(g.engines), len(usages)))
+
+	// Classes stay once seen, so that their utilization drops to 0 when
+	// their clients exit.
+	for class := range g.engines {
+		engines[class] = 0
+	}
+
+	for class, u := range usages {
+		if u.total > 0 {
+			engines[class] = percentOf(u.busy, u.total)
+		} else {
+			engines[class] = percentOf(u.busy, elapsed*u.capacity)
+		}
+	}
+
+	changed := !maps.Equal(engines, g.engines)
+	g.engines = engines
+
+	return changed
+}
+
+// Update forces the gpu metric to update. The returned error will not
+// be sent on the channel returned by [IntelGPU.Updated] unlike updates that
+// happen automatically every update interval.
+func (g *IntelGPU) Update() (err error) {
+	defer errUpdate(g.Type(), time.Now(), &err)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := intelGPUNow()
+
+	changed := g.clock.read(g.sys.Root())
+
+	if g.updateRC6(now) {
+		changed = true
+	}
+
+	if g.updateEngines(now) {
+		changed = true
+	}
+
+	if !changed {
+		return ErrNoChange
+	}
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (g *IntelGPU) Updated() <-chan error {
+	return g.ch
+}
+
+// Stop stops the GPU from continuing to update. Once stopped, the GPU
+// may not be restarted.
+func (g *IntelGPU) Stop() {
+	g.mu.Lock()
+
+	if g.stop != nil {
+		g.stop()
+	}
+
+	g.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the name of the GPU.
+func (g *IntelGPU) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.Name
+}
+
+func (g *IntelGPU) toPayload(p *payload.GPU) {
+	p.Name = g.Name
+
+	rc6 := g.rc6.valid() && g.rc6Ready
+
+	p.Utilization = payload.Maybe(payload.GPUUtilization{GPU: g.util}, rc6)
+	p.Clock = payload.Maybe(uint32(g.clock.value), g.clock.valid())
+	p.RC6 = payload.Maybe(g.rc6Pct, rc6)
+
+	if len(g.engines) > 0 {
+		p.Engines = maps.Clone(g.engines)
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of g to b.
+func (g *IntelGPU) AppendText(b []byte) ([]byte, error) {
+	var p payload.GPU
+
+	g.mu.RLock()
+	g.toPayload(&p)
+	g.mu.RUnlock()
+
+	return p.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [IntelGPU.AppendText](nil).
+func (g *IntelGPU) MarshalJSON() ([]byte, error) {
+	return g.AppendText(nil)
+}
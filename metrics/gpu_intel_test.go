@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+)
+
+// writeFiles writes each of files, by their path in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const intelDevice = "sys/bus/pci/devices/0000:00:02.0/"
+
+func intelGPUFiles(driver string) map[string]string {
+	return map[string]string{
+		intelDevice + "class":  "0x030000\n",
+		intelDevice + "vendor": "0x8086\n",
+		intelDevice + "uevent": "DRIVER=" + driver + "\nPCI_CLASS=30000\nPCI_SLOT_NAME=0000:00:02.0\n",
+	}
+}
+
+func testIntelGPU(t *testing.T, dir string) *IntelGPU {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.GPU.Platform = "intel"
+
+	gpu, err := NewIntelGPUFromConfig(cfg.GPU, Defaults{Root: testRoot(t, dir), Interval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gpu
+}
+
+func TestIntelGPU(t *testing.T) {
+	now := time.Unix(1000, 0)
+	intelGPUNow = func() time.Time { return now }
+	t.Cleanup(func() { intelGPUNow = time.Now })
+
+	dir := t.TempDir()
+
+	files := intelGPUFiles("i915")
+	files[intelDevice+"drm/card0/gt_act_freq_mhz"] = "300\n"
+	files[intelDevice+"drm/card0/power/rc6_residency_ms"] = "10000\n"
+	files[intelDevice+"drm/renderD128/dev"] = "226:128\n"
+	files["proc/100/fdinfo/0"] = "pos:\t0\nflags:\t02\n"
+	files["proc/100/fdinfo/4"] = "drm-driver:\ti915\ndrm-client-id:\t7\ndrm-pdev:\t0000:00:02.0\ndrm-engine-render:\t1000000000 ns\ndrm-engine-copy:\t0 ns\ndrm-engine-video:\t0 ns\ndrm-engine-capacity-video:\t2\n"
+	files["proc/101/fdinfo/4"] = "drm-driver:\ti915\ndrm-client-id:\t8\ndrm-pdev:\t0000:01:00.0\ndrm-engine-render:\t0 ns\n"
+	writeFiles(t, dir, files)
+
+	gpu := testIntelGPU(t, dir)
+
+	if want, got := "gpu", gpu.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := "Intel GPU", gpu.String(); got != want {
+		t.Errorf("Name: want %q, got %q", want, got)
+	}
+
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := gpu.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := `{"name": "Intel GPU", "clock": 300}`, string(b); got != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, got)
+	}
+
+	now = now.Add(time.Second)
+
+	writeFiles(t, dir, map[string]string{
+		intelDevice + "drm/card0/power/rc6_residency_ms": "10750\n",
+		"proc/100/fdinfo/4": "drm-driver:\ti915\ndrm-client-id:\t7\ndrm-pdev:\t0000:00:02.0\ndrm-engine-render:\t1200000000 ns\ndrm-engine-copy:\t0 ns\ndrm-engine-video:\t100000000 ns\ndrm-engine-capacity-video:\t2\n",
+	})
+
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = gpu.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name": "Intel GPU", "utilization": {"gpu": 25, "memory": 0}, "clock": 300, "rc6": 75, "engines": {"copy": 0, "render": 20, "video": 5}}`
+	if got := string(b); got != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, got)
+	}
+
+	now = now.Add(time.Second)
+
+	writeFiles(t, dir, map[string]string{
+		intelDevice + "drm/card0/power/rc6_residency_ms": "11500\n",
+		"proc/100/fdinfo/4": "drm-driver:\ti915\ndrm-client-id:\t7\ndrm-pdev:\t0000:00:02.0\ndrm-engine-render:\t1400000000 ns\ndrm-engine-copy:\t0 ns\ndrm-engine-video:\t200000000 ns\ndrm-engine-capacity-video:\t2\n",
+	})
+
+	if err := gpu.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	gpu.Discover(d)
+
+	for _, id := range []string{"mqttop_gpu_0", "mqttop_gpu_0_rc6", "mqttop_gpu_0_clock"} {
+		if _, ok := d.Components[id]; !ok {
+			t.Errorf("Discover: want component %s", id)
+		}
+	}
+}
+
+func TestIntelGPU_Xe(t *testing.T) {
+	now := time.Unix(1000, 0)
+	intelGPUNow = func() time.Time { return now }
+	t.Cleanup(func() { intelGPUNow = time.Now })
+
+	dir := t.TempDir()
+
+	files := intelGPUFiles("xe")
+	files[intelDevice+"tile0/gt0/freq0/act_freq"] = "1100\n"
+	files[intelDevice+"tile0/gt0/gtidle/idle_residency_ms"] = "0\n"
+	files["proc/100/fdinfo/4"] = "drm-driver:\txe\ndrm-client-id:\t3\ndrm-pdev:\t0000:00:02.0\ndrm-total-cycles-rcs:\t1000\ndrm-cycles-rcs:\t0\n"
+	writeFiles(t, dir, files)
+
+	gpu := testIntelGPU(t, dir)
+
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Second)
+
+	writeFiles(t, dir, map[string]string{
+		intelDevice + "tile0/gt0/gtidle/idle_residency_ms": "1000\n",
+		// The same client may be open in more than one process.
+		"proc/100/fdinfo/4": "drm-driver:\txe\ndrm-client-id:\t3\ndrm-pdev:\t0000:00:02.0\ndrm-total-cycles-rcs:\t5000\ndrm-cycles-rcs:\t1000\n",
+		"proc/102/fdinfo/9": "drm-driver:\txe\ndrm-client-id:\t3\ndrm-pdev:\t0000:00:02.0\ndrm-total-cycles-rcs:\t5000\ndrm-cycles-rcs:\t1000\n",
+	})
+
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := gpu.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name": "Intel GPU", "utilization": {"gpu": 50, "memory": 0}, "clock": 1100, "rc6": 50, "engines": {"rcs": 25}}`
+	if got := string(b); got != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, got)
+	}
+}
+
+func TestIntelGPU_Driver(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, intelGPUFiles("vfio-pci"))
+
+	cfg := config.Default()
+
+	_, err := NewIntelGPUFromConfig(cfg.GPU, Defaults{Root: testRoot(t, dir)})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("want error wrapping %v, got %v", ErrNotSupported, err)
+	}
+}
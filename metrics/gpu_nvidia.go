@@ -3,7 +3,10 @@
 package metrics
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -14,9 +17,13 @@ import (
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
 	"github.com/lone-faerie/mqttop/sysfs"
 )
 
+// gpuSupported indicates whether GPU metrics were compiled in.
+const gpuSupported = true
+
 type gpuFlag uint32
 
 const (
@@ -30,6 +37,7 @@ const (
 	gpuMemory
 	gpuMemoryV2
 	gpuProcs
+	gpuPersistence
 	gpuAll = gpuFlag(1<<32-1) &^ gpuMemory
 )
 
@@ -51,6 +59,7 @@ type NvidiaGPU struct {
 	Name     string
 	maxPower uint32
 	maxTemp  uint32
+	slowTemp uint32
 	rx       uint32
 	tx       uint32
 	util     nvml.Utilization
@@ -66,6 +75,20 @@ type NvidiaGPU struct {
 	memSize byteutil.ByteSize
 	procs   []nvmlProcess
 
+	// The constraints of the power limit in mW
+	minPowerLimit uint32
+	maxPowerLimit uint32
+	powerControl  bool
+
+	persistence        bool
+	persistenceControl bool
+
+	// The rolling aggregates are nil unless enabled
+	utilAggregate *rolling
+	tempAggregate *rolling
+
+	summary bool
+
 	index  int
 	flags  gpuFlag
 	device nvml.Device
@@ -73,6 +96,8 @@ type NvidiaGPU struct {
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
+	prec     int // precision of the power and aggregates, see [places]
+	tempCal  config.Calibration
 
 	mu        sync.RWMutex
 	once      sync.Once
@@ -91,63 +116,94 @@ type NvidiaGPU struct {
 // may be done by either calling [NvidiaGPU.Stop] or cancelling the [context.Context]
 // given to [NvidiaGPU.Start].
 func NewNvidiaGPU(cfg *config.Config) (*NvidiaGPU, error) {
+	return NewNvidiaGPUFromConfig(cfg.GPU, DefaultsOf(cfg))
+}
+
+// NewNvidiaGPUFromConfig is like [NewNvidiaGPU] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewNvidiaGPUFromConfig(cfg config.GPUConfig, d Defaults) (*NvidiaGPU, error) {
 	g := &NvidiaGPU{flags: gpuAll}
 
-	_, err := sysfs.GPUVendor()
+	_, err := sysfs.NewFS(d.Root).GPUVendor()
 	if err != nil {
 		return nil, errNotSupported(g.Type(), err)
 	}
 
-	if cfg.GPU.Interval > 0 {
-		g.interval = cfg.GPU.Interval
-	} else {
+	if cfg.Interval > 0 {
 		g.interval = cfg.Interval
+	} else {
+		g.interval = d.Interval
 	}
 
-	if cfg.GPU.Topic != "" {
-		g.topic = cfg.GPU.Topic
-	} else if cfg.BaseTopic != "" {
-		g.topic = cfg.BaseTopic + "/metric/gpu"
+	if cfg.Topic != "" {
+		g.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		g.topic = d.BaseTopic + "/metric/gpu"
 	} else {
 		g.topic = "mqttop/metric/gpu"
 	}
 
-	g.index = cfg.GPU.Index
+	g.index = cfg.Index
+	g.prec = d.precision(cfg.Precision)
+	g.tempCal = cfg.TemperatureCalibration
 
 	if err := nvml.Init(); err != nvml.SUCCESS {
 		log.Debug("Error initializing nvml", "err", err)
-		return nil, errNotSupported(g.Type(), err)
+		return nil, errNVML(g.Type(), err)
 	}
 
 	log.Info("nvml initialized")
 
-	if err := g.init(cfg); err != nvml.SUCCESS {
+	if err := g.init(&cfg); err != nvml.SUCCESS {
 		g.shutdown()
 		return nil, errNotSupported(g.Type(), err)
 	}
 
-	size, err := byteutil.ParseSize(cfg.GPU.SizeUnit)
+	size, err := byteutil.ParseSize(cfg.SizeUnit)
 	if err != nil {
 		size = byteutil.MiB
 	}
 
 	g.memSize = size
 
+	if cfg.Aggregate {
+		g.utilAggregate = new(rolling)
+		g.tempAggregate = new(rolling)
+	}
+
+	g.summary = cfg.Summary
+
+	if d.Controls.GPUPowerLimit {
+		if g.maxPowerLimit > 0 {
+			g.powerControl = true
+		} else {
+			log.Warn("GPU power limit control is enabled but the limit constraints are not available")
+		}
+	}
+
+	if d.Controls.GPUPersistence {
+		if g.flags.Has(gpuPersistence) {
+			g.persistenceControl = true
+		} else {
+			log.Warn("GPU persistence control is enabled but persistence mode is not supported")
+		}
+	}
+
 	return g, nil
 }
 
-func (g *NvidiaGPU) init(cfg *config.Config) error {
+func (g *NvidiaGPU) init(cfg *config.GPUConfig) error {
 	dev, err := nvml.DeviceGetHandleByIndex(g.index)
 	if err != nvml.SUCCESS {
-		return errNotSupported("DeviceGetHandleByIndex", err)
+		return errNVML("DeviceGetHandleByIndex", err)
 	}
 
 	name, err := dev.GetName()
 	if err != nvml.SUCCESS {
-		return errNotSupported("GetName", err)
+		return errNVML("GetName", err)
 	}
 
-	g.Name = cfg.GPU.FormatName(name)
+	g.Name = cfg.FormatName(name)
 
 	pow, err := dev.GetPowerManagementLimit()
 	if err != nvml.SUCCESS {
@@ -158,16 +214,46 @@ func (g *NvidiaGPU) init(cfg *config.Config) error {
 		g.maxPower = pow
 	}
 
+	if lo, hi, err := dev.GetPowerManagementLimitConstraints(); err == nvml.SUCCESS {
+		g.minPowerLimit, g.maxPowerLimit = lo, hi
+	}
+
+	if mode, err := dev.GetPersistenceMode(); err == nvml.SUCCESS {
+		g.persistence = mode == nvml.FEATURE_ENABLED
+	} else {
+		g.flags &^= gpuPersistence
+	}
+
 	tmp, err := dev.GetTemperatureThreshold(nvml.TEMPERATURE_THRESHOLD_SHUTDOWN)
 	if err == nvml.SUCCESS {
 		g.maxTemp = tmp
 	}
 
+	tmp, err = dev.GetTemperatureThreshold(nvml.TEMPERATURE_THRESHOLD_SLOWDOWN)
+	if err == nvml.SUCCESS {
+		g.slowTemp = tmp
+	}
+
 	g.device = dev
 
 	return nvml.SUCCESS
 }
 
+// errNVML returns an [Error] of the constructor of metric caused by ret, which
+// is of the kind of ret.
+func errNVML(metric string, ret nvml.Return) error {
+	var kind error = ErrNotSupported
+
+	switch ret {
+	case nvml.ERROR_NO_PERMISSION:
+		kind = ErrPermission
+	case nvml.ERROR_NOT_FOUND, nvml.ERROR_GPU_IS_LOST, nvml.ERROR_DRIVER_NOT_LOADED, nvml.ERROR_LIBRARY_NOT_FOUND:
+		kind = ErrMissingHardware
+	}
+
+	return &Error{Op: "new", Metric: metric, Kind: kind, Err: ret}
+}
+
 // Type returns the metric type, "gpu".
 func (g *NvidiaGPU) Type() string {
 	return "gpu"
@@ -191,7 +277,17 @@ func (g *NvidiaGPU) SetInterval(d time.Duration) {
 	g.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (g *NvidiaGPU) Interval() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.interval
+}
+
 func (g *NvidiaGPU) loop(ctx context.Context) {
+	defer recoverLoop(g.Type())
+
 	g.mu.Lock()
 	g.tick = time.NewTicker(g.interval)
 	g.mu.Unlock()
@@ -258,8 +354,11 @@ func (g *NvidiaGPU) getThroughput(u nvml.PcieUtilCounter, p *uint32) (err error)
 // Update forces the gpu metric to update. The returned error will not
 // be sent on the channel returned by [GPU.Updated] unlike updates that
 // happen automatically every update interval.
-func (g *NvidiaGPU) Update() error {
+func (g *NvidiaGPU) Update() (err error) {
+	defer errUpdate(g.Type(), time.Now(), &err)
+
 	g.mu.Lock()
+	defer g.mu.Unlock()
 
 	var (
 		changes gpuFlag
@@ -335,8 +434,21 @@ func (g *NvidiaGPU) Update() error {
 		}
 	}
 
+	if g.flags.Has(gpuPersistence) {
+		if m, err := g.device.GetPersistenceMode(); err == nvml.SUCCESS {
+			if enabled := m == nvml.FEATURE_ENABLED; enabled != g.persistence {
+				changes |= gpuPersistence
+				g.persistence = enabled
+			}
+		} else {
+			g.flags &^= gpuPersistence
+		}
+	}
+
 	if g.flags.Has(gpuTemperature) {
 		if t, err := g.device.GetTemperature(nvml.TEMPERATURE_GPU); err == nvml.SUCCESS {
+			t = uint32(max(calibrate(g.tempCal, int64(t), 1), 0))
+
 			if t != g.temp {
 				changes |= gpuTemperature
 			}
@@ -388,7 +500,7 @@ func (g *NvidiaGPU) Update() error {
 		}
 	}
 
-	g.mu.Unlock()
+	changes |= g.addAggregates(time.Now())
 
 	if changes == 0 {
 		return ErrNoChange
@@ -397,6 +509,108 @@ func (g *NvidiaGPU) Update() error {
 	return nil
 }
 
+// addAggregates adds the current utilization and temperature, sampled at t, to
+// the rolling aggregates if enabled, and returns the flags of the aggregates
+// that changed. g.mu must be held.
+func (g *NvidiaGPU) addAggregates(t time.Time) (changes gpuFlag) {
+	add := func(r *rolling, v int64, flag gpuFlag) {
+		if r == nil || !g.flags.Has(flag) {
+			return
+		}
+
+		prev, _ := r.aggregate()
+		r.add(t, v)
+
+		if curr, _ := r.aggregate(); curr != prev {
+			changes |= flag
+		}
+	}
+
+	add(g.utilAggregate, int64(g.util.Gpu)*1000, gpuUtilization)
+	add(g.tempAggregate, int64(g.temp)*1000, gpuTemperature)
+
+	return changes
+}
+
+// SetPowerLimit sets the power limit of the GPU to watts, clamped to the
+// minimum and maximum limits of the GPU. An error wrapping [ErrNotPermitted]
+// is returned if power limit control is not enabled.
+func (g *NvidiaGPU) SetPowerLimit(watts float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.powerControl {
+		return errNotPermitted("power limit")
+	}
+
+	limit := min(max(uint32(max(watts, 0)*1000), g.minPowerLimit), g.maxPowerLimit)
+
+	if err := g.device.SetPowerManagementLimit(limit); err != nvml.SUCCESS {
+		return fmt.Errorf("unable to set power limit: %w", err)
+	}
+
+	g.maxPower = limit
+
+	return nil
+}
+
+// SetPersistence enables or disables the persistence mode of the GPU. An error
+// wrapping [ErrNotPermitted] is returned if persistence control is not enabled.
+func (g *NvidiaGPU) SetPersistence(enabled bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.persistenceControl {
+		return errNotPermitted("persistence")
+	}
+
+	mode := nvml.FEATURE_DISABLED
+	if enabled {
+		mode = nvml.FEATURE_ENABLED
+	}
+
+	if err := g.device.SetPersistenceMode(mode); err != nvml.SUCCESS {
+		return fmt.Errorf("unable to set persistence mode: %w", err)
+	}
+
+	g.persistence = enabled
+
+	return nil
+}
+
+// Commands implements [Commander]. The power limit and persistence mode may
+// only be set if enabled by the controls config.
+func (g *NvidiaGPU) Commands() map[string]Command {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	cmds := make(map[string]Command)
+
+	if g.powerControl {
+		cmds["power_limit/set"] = func(payload []byte) error {
+			watts, err := strconv.ParseFloat(string(bytes.TrimSpace(payload)), 64)
+			if err != nil {
+				return fmt.Errorf("invalid power limit payload %q", payload)
+			}
+
+			return g.SetPowerLimit(watts)
+		}
+	}
+
+	if g.persistenceControl {
+		cmds["persistence/set"] = func(payload []byte) error {
+			enabled, err := ParseSwitch(payload)
+			if err != nil {
+				return err
+			}
+
+			return g.SetPersistence(enabled)
+		}
+	}
+
+	return cmds
+}
+
 // Updated returns the channel that updates will be sent on. A received value
 // of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
 // error is the first error encountered during updating and indicates a failed update.
@@ -437,69 +651,117 @@ func (g *NvidiaGPU) String() string {
 	return g.Name
 }
 
-// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
-// representation of g to b.
-func (g *NvidiaGPU) AppendText(b []byte) ([]byte, error) {
-	g.mu.RLock()
+// TemperatureLimits implements [TemperatureLimiter] and returns the slowdown
+// and shutdown temperatures of the GPU.
+func (g *NvidiaGPU) TemperatureLimits() (high, crit int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	b = append(b, "{\"name\": \""...)
-	b = append(b, g.Name...)
-	b = append(b, '"')
+	return int64(g.slowTemp) * 1000, int64(g.maxTemp) * 1000
+}
 
-	if g.flags.Has(gpuThroughput) {
-		b = append(b, ", \"rx\": "...)
-		b = strconv.AppendUint(b, uint64(g.rx), 10)
-		b = append(b, ", \"tx\": "...)
-		b = strconv.AppendUint(b, uint64(g.tx), 10)
+func (g *NvidiaGPU) toPayload(p *payload.GPU) {
+	p.Name = g.Name
+	prec := places(g.prec)
+
+	throughput := g.flags.Has(gpuThroughput)
+	p.Rx = payload.Maybe(g.rx, throughput)
+	p.Tx = payload.Maybe(g.tx, throughput)
+	p.Utilization = payload.Maybe(payload.GPUUtilization{
+		GPU:    g.util.Gpu,
+		Memory: g.util.Memory,
+	}, g.flags.Has(gpuUtilization))
+	p.UtilizationAggregate = aggregateOf(g.utilAggregate, prec)
+	p.Clock = payload.Maybe(g.clock, g.flags.Has(gpuClock))
+	p.MemClock = payload.Maybe(g.memClock, g.flags.Has(gpuMemClock))
+
+	power := g.flags.Has(gpuPower)
+	p.Power = payload.Maybe(payload.Milli(g.power).Round(prec), power)
+	p.MaxPower = payload.Maybe(payload.Milli(g.maxPower).Round(prec), power)
+	p.Persistence = payload.Maybe(g.persistence, g.flags.Has(gpuPersistence))
+
+	temp := g.flags.Has(gpuTemperature)
+	p.Temperature = payload.Maybe(g.temp, temp)
+	p.TemperatureAggregate = aggregateOf(g.tempAggregate, prec)
+	p.MaxTemp = payload.Maybe(g.maxTemp, temp)
+
+	p.Memory = payload.Maybe(payload.GPUMemory{
+		Total: payload.Size(byteutil.ScaleSize(g.memTotal, g.memSize)),
+		Free:  payload.Size(byteutil.ScaleSize(g.memFree, g.memSize)),
+		Used:  payload.Size(byteutil.ScaleSize(g.memUsed, g.memSize)),
+	}, g.flags.Has(gpuMemoryV2|gpuMemory))
+
+	p.Summary = payload.Optional[payload.GPUSummary]{}
+
+	if g.summary {
+		var s payload.GPUSummary
+
+		s.Add(p)
+		p.Summary = payload.Some(s)
 	}
+}
 
-	if g.flags.Has(gpuUtilization) {
-		b = append(b, ", \"utilization\": {\"gpu\": "...)
-		b = strconv.AppendUint(b, uint64(g.util.Gpu), 10)
-		b = append(b, ", \"memory\": "...)
-		b = strconv.AppendUint(b, uint64(g.util.Memory), 10)
-		b = append(b, '}')
+func (g *NvidiaGPU) fromPayload(p *payload.GPU) {
+	g.Name = p.Name
+
+	if p.Rx.Valid && p.Tx.Valid {
+		g.rx, g.tx = p.Rx.Value, p.Tx.Value
+		g.flags |= gpuThroughput
 	}
 
-	if g.flags.Has(gpuClock) {
-		b = append(b, ", \"clock\": "...)
-		b = strconv.AppendUint(b, uint64(g.clock), 10)
+	if p.Utilization.Valid {
+		g.util.Gpu, g.util.Memory = p.Utilization.Value.GPU, p.Utilization.Value.Memory
+		g.flags |= gpuUtilization
 	}
 
-	if g.flags.Has(gpuMemClock) {
-		b = append(b, ", \"memClock\": "...)
-		b = strconv.AppendUint(b, uint64(g.memClock), 10)
+	if p.Clock.Valid {
+		g.clock = p.Clock.Value
+		g.flags |= gpuClock
 	}
 
-	if g.flags.Has(gpuPower) {
-		b = append(b, ", \"power\": "...)
-		b = byteutil.AppendDecimal(b, int64(g.power), 3)
-		b = append(b, ", \"maxPower\": "...)
-		b = byteutil.AppendDecimal(b, int64(g.maxPower), 3)
+	if p.MemClock.Valid {
+		g.memClock = p.MemClock.Value
+		g.flags |= gpuMemClock
 	}
 
-	if g.flags.Has(gpuTemperature) {
-		b = append(b, ", \"temperature\": "...)
-		b = strconv.AppendUint(b, uint64(g.temp), 10)
-		b = append(b, ", \"maxTemp\": "...)
-		b = strconv.AppendInt(b, int64(g.maxTemp), 10)
+	if p.Power.Valid {
+		g.power, g.maxPower = uint32(p.Power.Value), uint32(p.MaxPower.Value)
+		g.flags |= gpuPower
+	}
+
+	if p.Persistence.Valid {
+		g.persistence = p.Persistence.Value
+		g.flags |= gpuPersistence
+	}
+
+	if p.Temperature.Valid {
+		g.temp, g.maxTemp = p.Temperature.Value, p.MaxTemp.Value
+		g.flags |= gpuTemperature
 	}
 
-	if g.flags.Has(gpuMemoryV2 | gpuMemory) {
-		b = append(b, ", \"memory\": {\"total\": "...)
-		b = byteutil.AppendSize(b, g.memTotal, g.memSize)
-		b = append(b, ", \"free\": "...)
-		b = byteutil.AppendSize(b, g.memFree, g.memSize)
-		b = append(b, ", \"used\": "...)
-		b = byteutil.AppendSize(b, g.memUsed, g.memSize)
-		b = append(b, '}')
+	if p.Memory.Valid {
+		g.memTotal = byteutil.UnscaleSize(uint64(p.Memory.Value.Total), g.memSize)
+		g.memFree = byteutil.UnscaleSize(uint64(p.Memory.Value.Free), g.memSize)
+		g.memUsed = byteutil.UnscaleSize(uint64(p.Memory.Value.Used), g.memSize)
+
+		if !g.flags.Has(gpuMemoryV2) {
+			g.flags |= gpuMemory
+		}
 	}
 
-	b = append(b, '}')
+	g.summary = p.Summary.Valid
+}
 
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of g to b.
+func (g *NvidiaGPU) AppendText(b []byte) ([]byte, error) {
+	var p payload.GPU
+
+	g.mu.RLock()
+	g.toPayload(&p)
 	g.mu.RUnlock()
 
-	return b, nil
+	return p.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [GPU.AppendText](nil).
@@ -507,10 +769,52 @@ func (g *NvidiaGPU) MarshalJSON() ([]byte, error) {
 	return g.AppendText(nil)
 }
 
-func appendGPU(m []Metric, cfg *config.Config) []Metric {
-	if gpu, err := NewNvidiaGPU(cfg); err == nil {
-		m = append(m, gpu)
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of a GPU, as produced by [NvidiaGPU.MarshalJSON], into g. The
+// memory sizes are interpreted in the size unit g was configured with.
+func (g *NvidiaGPU) UnmarshalJSON(data []byte) error {
+	var p payload.GPU
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.fromPayload(&p)
+	g.mu.Unlock()
+
+	return nil
+}
+
+// newGPU returns the GPU of cfg using NVML. If NVML is unavailable, or the
+// platform is "sysfs", the degraded [SysfsGPU] is returned instead. If the
+// platform is "intel", the [IntelGPU] is returned.
+func newGPU(cfg *config.Config) (Metric, error) {
+	switch cfg.GPU.Platform {
+	case "sysfs":
+		return constructor(NewSysfsGPU)(cfg)
+	case "intel":
+		return constructor(NewIntelGPU)(cfg)
+	}
+
+	gpu, err := NewNvidiaGPU(cfg)
+	if err == nil {
+		return gpu, nil
+	}
+
+	if fallback, ferr := NewSysfsGPU(cfg); ferr == nil {
+		log.Warn("NVML unavailable, using degraded GPU metrics", "err", err)
+		return fallback, nil
+	}
+
+	return nil, err
+}
+
+func appendGPU(m []Metric, cfg *config.Config) ([]Metric, error) {
+	gpu, err := newGPU(cfg)
+	if err != nil {
+		return m, err
 	}
 
-	return m
+	return append(m, gpu), nil
 }
@@ -8,17 +8,11 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
 	"github.com/lone-faerie/mqttop/config"
-	"github.com/lone-faerie/mqttop/internal/file"
 )
 
 func testNvidiaGPU(t *testing.T) (*NvidiaGPU, *config.Config) {
 	t.Helper()
 
-	err := file.SetRoot("/")
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	cfg := config.Default()
 
 	gpu, err := NewNvidiaGPU(cfg)
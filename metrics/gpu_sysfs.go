@@ -0,0 +1,375 @@
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/sysfs"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// sysfsGPUValue is a value of a [SysfsGPU] read from a file, which is only
+// read while path is not empty.
+type sysfsGPUValue struct {
+	path  string
+	value uint64
+}
+
+// read reads the value of v in root and returns whether it changed. If the
+// value can't be read, v is no longer read.
+func (v *sysfsGPUValue) read(root *vfs.Root) bool {
+	if v.path == "" {
+		return false
+	}
+
+	n, err := root.ReadUint(v.path)
+	if err != nil {
+		log.Debug("Unable to read GPU value", "path", v.path, "err", err)
+		v.path = ""
+
+		return true
+	}
+
+	changed := n != v.value
+	v.value = n
+
+	return changed
+}
+
+func (v *sysfsGPUValue) valid() bool {
+	return v.path != ""
+}
+
+// SysfsGPU implements the [Metric] interface to provide degraded metrics of
+// an NVIDIA GPU without NVML, such as in a container without the NVIDIA
+// Container Toolkit. The name of the GPU is read from /proc/driver/nvidia/gpus,
+// and the utilization, memory, temperature and power are read from the DRM
+// attributes and hwmon of the PCI device, if the driver provides them.
+type SysfsGPU struct {
+	Name string
+
+	util     sysfsGPUValue // %
+	memTotal sysfsGPUValue // Bytes
+	memUsed  sysfsGPUValue // Bytes
+	temp     sysfsGPUValue // m°C
+	maxTemp  sysfsGPUValue // m°C
+	highTemp sysfsGPUValue // m°C
+	power    sysfsGPUValue // µW
+	maxPower sysfsGPUValue // µW
+
+	memSize byteutil.ByteSize
+
+	index    int
+	sys      sysfs.FS
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+	prec     int // precision of the power, see [places]
+	tempCal  config.Calibration
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewSysfsGPU returns a new [SysfsGPU] initialized from cfg. If there is any
+// error encountered while initializing the GPU, a non-nil error that wraps
+// [ErrNotSupported] is returned.
+func NewSysfsGPU(cfg *config.Config) (*SysfsGPU, error) {
+	return NewSysfsGPUFromConfig(cfg.GPU, DefaultsOf(cfg))
+}
+
+// NewSysfsGPUFromConfig is like [NewSysfsGPU] but is initialized from the
+// config of the metric and d instead of a full [config.Config].
+func NewSysfsGPUFromConfig(cfg config.GPUConfig, d Defaults) (*SysfsGPU, error) {
+	g := &SysfsGPU{index: cfg.Index, sys: sysfs.NewFS(d.Root)}
+
+	devs, err := g.sys.GPUDevices(sysfs.Nvidia)
+	if err != nil {
+		return nil, errNotSupported(g.Type(), err)
+	}
+
+	if g.index < 0 || g.index >= len(devs) {
+		return nil, errNotSupported(g.Type(), errNotFound("gpu "+strconv.Itoa(g.index)))
+	}
+
+	g.init(&cfg, devs[g.index])
+
+	if cfg.Interval > 0 {
+		g.interval = cfg.Interval
+	} else {
+		g.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		g.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		g.topic = d.BaseTopic + "/metric/gpu"
+	} else {
+		g.topic = "mqttop/metric/gpu"
+	}
+
+	g.prec = d.precision(cfg.Precision)
+	g.tempCal = cfg.TemperatureCalibration
+
+	return g, nil
+}
+
+func (g *SysfsGPU) init(cfg *config.GPUConfig, dev string) {
+	name := "NVIDIA GPU"
+
+	if gpus, err := procfs.NewFS(g.sys.Root()).NvidiaGPUs(); err == nil {
+		for i := range gpus {
+			if gpus[i].Bus == filepath.Base(dev) && gpus[i].Model != "" {
+				name = gpus[i].Model
+				break
+			}
+		}
+	} else {
+		log.Debug("Unable to read NVIDIA driver GPUs", "err", err)
+	}
+
+	g.Name = cfg.FormatName(name)
+
+	exists := func(v *sysfsGPUValue, dir string, names ...string) {
+		if dir == "" {
+			return
+		}
+
+		for _, name := range names {
+			if path := dir + vfs.Separator + name; g.sys.Root().Exists(path) {
+				v.path = path
+				return
+			}
+		}
+	}
+
+	exists(&g.util, dev, "gpu_busy_percent")
+	exists(&g.memTotal, dev, "mem_info_vram_total")
+	exists(&g.memUsed, dev, "mem_info_vram_used")
+
+	hwmon := g.sys.DeviceHWMon(dev)
+	exists(&g.temp, hwmon, "temp1_input")
+	exists(&g.maxTemp, hwmon, "temp1_crit")
+	exists(&g.highTemp, hwmon, "temp1_max")
+	exists(&g.power, hwmon, "power1_average", "power1_input")
+	exists(&g.maxPower, hwmon, "power1_cap")
+
+	if !g.memTotal.valid() || !g.memUsed.valid() {
+		g.memTotal.path, g.memUsed.path = "", ""
+	}
+
+	g.memTotal.read(g.sys.Root())
+	g.maxTemp.read(g.sys.Root())
+	g.highTemp.read(g.sys.Root())
+	g.maxPower.read(g.sys.Root())
+
+	size, err := byteutil.ParseSize(cfg.SizeUnit)
+	if err != nil {
+		size = byteutil.SizeOf(g.memTotal.value)
+	}
+
+	g.memSize = size
+
+	log.Debug("GPU initialized without NVML", "device", dev, "hwmon", hwmon)
+}
+
+// Type returns the metric type, "gpu".
+func (g *SysfsGPU) Type() string {
+	return "gpu"
+}
+
+// Topic returns the topic to publish gpu metrics to.
+func (g *SysfsGPU) Topic() string {
+	return g.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (g *SysfsGPU) SetInterval(d time.Duration) {
+	g.mu.Lock()
+
+	if g.tick != nil && d != g.interval {
+		g.tick.Reset(d)
+	}
+
+	g.interval = d
+
+	g.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (g *SysfsGPU) Interval() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.interval
+}
+
+func (g *SysfsGPU) loop(ctx context.Context) {
+	defer recoverLoop(g.Type())
+
+	g.mu.Lock()
+	g.tick = time.NewTicker(g.interval)
+	g.mu.Unlock()
+
+	defer g.tick.Stop()
+	defer close(g.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("gpu started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.tick.C:
+			err = g.Update()
+			if err == ErrNoChange {
+				log.Debug("gpu updated, no change")
+			} else {
+				log.Debug("gpu updated")
+			}
+
+			ch = g.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the gpu updating. If ctx is cancelled or
+// times out, the metric will stop and may not be restarted.
+func (g *SysfsGPU) Start(ctx context.Context) error {
+	if g.interval == 0 {
+		log.Warn("GPU interval is 0, not starting")
+		return nil
+	}
+
+	g.once.Do(func() {
+		ctx, g.stop = context.WithCancel(ctx)
+		g.ch = make(chan error)
+
+		go g.loop(ctx)
+	})
+
+	return nil
+}
+
+// Update forces the gpu metric to update. The returned error will not
+// be sent on the channel returned by [SysfsGPU.Updated] unlike updates that
+// happen automatically every update interval.
+func (g *SysfsGPU) Update() (err error) {
+	defer errUpdate(g.Type(), time.Now(), &err)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var changed bool
+
+	for _, v := range []*sysfsGPUValue{&g.util, &g.memTotal, &g.memUsed, &g.temp, &g.power} {
+		if v.read(g.sys.Root()) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return ErrNoChange
+	}
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (g *SysfsGPU) Updated() <-chan error {
+	return g.ch
+}
+
+// Stop stops the GPU from continuing to update. Once stopped, the GPU
+// may not be restarted.
+func (g *SysfsGPU) Stop() {
+	g.mu.Lock()
+
+	if g.stop != nil {
+		g.stop()
+	}
+
+	g.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the name of the GPU.
+func (g *SysfsGPU) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.Name
+}
+
+// TemperatureLimits implements [TemperatureLimiter] and returns the max and
+// crit temperatures of the hwmon of the GPU.
+func (g *SysfsGPU) TemperatureLimits() (high, crit int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return int64(g.highTemp.value), int64(g.maxTemp.value)
+}
+
+func (g *SysfsGPU) toPayload(p *payload.GPU) {
+	p.Name = g.Name
+
+	mem := g.memTotal.valid() && g.memUsed.valid()
+
+	var memUtil uint32
+	if mem && g.memTotal.value > 0 {
+		memUtil = uint32(100 * g.memUsed.value / g.memTotal.value)
+	}
+
+	p.Utilization = payload.Maybe(payload.GPUUtilization{
+		GPU:    uint32(g.util.value),
+		Memory: memUtil,
+	}, g.util.valid())
+
+	prec := places(g.prec)
+
+	p.Power = payload.Maybe(payload.Milli(g.power.value/1000).Round(prec), g.power.valid())
+	p.MaxPower = payload.Maybe(payload.Milli(g.maxPower.value/1000).Round(prec), g.power.valid() && g.maxPower.valid())
+	p.Temperature = payload.Maybe(uint32(max(calibrate(g.tempCal, int64(g.temp.value), 1000), 0)/1000), g.temp.valid())
+	p.MaxTemp = payload.Maybe(uint32(g.maxTemp.value/1000), g.temp.valid() && g.maxTemp.valid())
+
+	p.Memory = payload.Maybe(payload.GPUMemory{
+		Total: payload.Size(byteutil.ScaleSize(g.memTotal.value, g.memSize)),
+		Free:  payload.Size(byteutil.ScaleSize(g.memTotal.value-min(g.memUsed.value, g.memTotal.value), g.memSize)),
+		Used:  payload.Size(byteutil.ScaleSize(g.memUsed.value, g.memSize)),
+	}, mem)
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of g to b.
+func (g *SysfsGPU) AppendText(b []byte) ([]byte, error) {
+	var p payload.GPU
+
+	g.mu.RLock()
+	g.toPayload(&p)
+	g.mu.RUnlock()
+
+	return p.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [SysfsGPU.AppendText](nil).
+func (g *SysfsGPU) MarshalJSON() ([]byte, error) {
+	return g.AppendText(nil)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+func testSysfsGPU(t *testing.T) (*SysfsGPU, *config.Config) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.GPU.SizeUnit = "MiB"
+
+	gpu, err := NewSysfsGPU(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpu == nil {
+		t.Fatal("gpu is nil")
+	}
+
+	return gpu, cfg
+}
+
+func TestSysfsGPU(t *testing.T) {
+	gpu, cfg := testSysfsGPU(t)
+
+	if want, got := "gpu", gpu.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := cfg.GPU.Topic, gpu.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := "NVIDIA GeForce RTX 3080", gpu.String(); got != want {
+		t.Errorf("Name: want %q, got %q", want, got)
+	}
+
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gpu.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	b, err := gpu.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name": "NVIDIA GeForce RTX 3080", "utilization": {"gpu": 37, "memory": 20}, "power": 220.5, "maxPower": 320, "temperature": 64, "maxTemp": 98, "memory": {"total": 10240, "free": 8192, "used": 2048}}`
+	if got := string(b); got != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, got)
+	}
+
+	d := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	gpu.Discover(d)
+
+	for _, id := range []string{"mqttop_gpu_0", "mqttop_gpu_0_power", "mqttop_gpu_0_temperature", "mqttop_gpu_0_memory"} {
+		if _, ok := d.Components[id]; !ok {
+			t.Errorf("Discover: want component %s", id)
+		}
+	}
+}
+
+func TestSysfsGPU_Precision(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Precision = 2
+	cfg.GPU.Precision = -1
+
+	gpu, err := NewSysfsGPU(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gpu.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.GPU
+	gpu.toPayload(&p)
+
+	if want, got := payload.Milli(221000), p.Power.Value; got != want {
+		t.Errorf("Power: want %v, got %v", want, got)
+	}
+	if want, got := payload.Milli(320000), p.MaxPower.Value; got != want {
+		t.Errorf("MaxPower: want %v, got %v", want, got)
+	}
+}
+
+func TestSysfsGPU_Index(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.GPU.Index = 1
+
+	_, err := NewSysfsGPU(cfg)
+	if !errors.Is(err, ErrMissingHardware) {
+		t.Errorf("want error wrapping %v, got %v", ErrMissingHardware, err)
+	}
+}
@@ -0,0 +1,420 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// idleTimeout is the maximum time each command of the idle metric may run.
+const idleTimeout = 2 * time.Second
+
+var idleNow = time.Now
+
+// idleBackend gets how long the user has been idle.
+type idleBackend interface {
+	idle(ctx context.Context) (time.Duration, error)
+}
+
+// x11Backend uses xprintidle, which prints the idle time in milliseconds.
+type x11Backend struct{}
+
+func (x11Backend) idle(ctx context.Context) (time.Duration, error) {
+	out, err := runCommand(ctx, "xprintidle")
+	if err != nil {
+		return 0, err
+	}
+
+	ms, err := strconv.ParseInt(string(bytes.TrimSpace(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected xprintidle output %q", out)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// logindBackend uses loginctl to get the idle hint of the session, which is set
+// by most desktops, including on Wayland, once they consider the user idle.
+type logindBackend struct {
+	session string
+}
+
+func (l logindBackend) idle(ctx context.Context) (time.Duration, error) {
+	out, err := runCommand(ctx, "loginctl", "show-session", l.session, "--property=IdleHint", "--property=IdleSinceHint")
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		idle  bool
+		since int64 = -1
+	)
+
+	for line := range bytes.Lines(out) {
+		key, val, _ := bytes.Cut(bytes.TrimSpace(line), []byte{'='})
+
+		switch string(key) {
+		case "IdleHint":
+			idle = string(val) == "yes"
+		case "IdleSinceHint":
+			since, _ = strconv.ParseInt(string(val), 10, 64)
+		}
+	}
+
+	if since < 0 {
+		return 0, fmt.Errorf("unexpected loginctl output %q", out)
+	}
+
+	if !idle || since == 0 {
+		return 0, nil
+	}
+
+	return max(idleNow().Sub(time.UnixMicro(since)), 0), nil
+}
+
+// inputBackend uses the last time any of the event devices in /dev/input were
+// accessed, which happens whenever there is input.
+type inputBackend struct {
+	root *vfs.Root
+}
+
+func (b inputBackend) idle(context.Context) (time.Duration, error) {
+	d, err := b.root.OpenDir("/dev/input")
+	if err != nil {
+		return 0, err
+	}
+
+	defer d.Close()
+
+	names, err := d.ReadNames()
+	if err != nil {
+		return 0, err
+	}
+
+	var last int64
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "event") {
+			continue
+		}
+
+		path := "/dev/input/" + name
+
+		if sec, _, err := b.root.AccessTime(path); err == nil && sec > last {
+			last = sec
+		}
+
+		if sec, _, err := b.root.ModifyTime(path); err == nil && sec > last {
+			last = sec
+		}
+	}
+
+	if last == 0 {
+		return 0, errors.New("no input devices")
+	}
+
+	return max(idleNow().Sub(time.Unix(last, 0)), 0), nil
+}
+
+// Idle implements the [Metric] interface to provide the idle time of the user
+// of a desktop. The user is considered active if they have been idle for less
+// than the threshold.
+type Idle struct {
+	backend   idleBackend
+	threshold time.Duration
+
+	idle   int64
+	active bool
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewIdle returns a new [Idle] initialized from cfg. If there is no way of getting
+// the idle time, a non-nil error that wraps [ErrNotSupported] is returned.
+func NewIdle(cfg *config.Config) (*Idle, error) {
+	return NewIdleFromConfig(cfg.Idle, DefaultsOf(cfg))
+}
+
+// NewIdleFromConfig is like [NewIdle] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewIdleFromConfig(cfg config.IdleConfig, d Defaults) (*Idle, error) {
+	i := &Idle{}
+
+	x11 := func() bool {
+		if _, err := lookPath("xprintidle"); err != nil {
+			return false
+		}
+
+		i.backend = x11Backend{}
+
+		return true
+	}
+	logind := func() bool {
+		if _, err := lookPath("loginctl"); err != nil {
+			return false
+		}
+
+		session := os.Getenv("XDG_SESSION_ID")
+		if session == "" {
+			session = "auto"
+		}
+
+		i.backend = logindBackend{session}
+
+		return true
+	}
+	input := func() bool {
+		if !d.Root.IsDir("/dev/input") {
+			return false
+		}
+
+		i.backend = inputBackend{d.Root}
+
+		return true
+	}
+
+	switch cfg.Backend {
+	case "", "auto":
+		_ = (os.Getenv("DISPLAY") != "" && x11()) ||
+			(os.Getenv("XDG_SESSION_ID") != "" && logind()) ||
+			input()
+	case "x11":
+		x11()
+	case "logind", "wayland":
+		logind()
+	case "input":
+		input()
+	default:
+		return nil, errNotSupported(i.Type(), fmt.Errorf("unknown idle backend %q", cfg.Backend))
+	}
+
+	if i.backend == nil {
+		return nil, errNotSupported(i.Type(), exec.ErrNotFound)
+	}
+
+	if cfg.Threshold > 0 {
+		i.threshold = cfg.Threshold
+	} else {
+		i.threshold = config.DefaultIdle.Threshold
+	}
+
+	if cfg.Interval > 0 {
+		i.interval = cfg.Interval
+	} else {
+		i.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		i.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		i.topic = d.BaseTopic + "/metric/idle"
+	} else {
+		i.topic = "mqttop/metric/idle"
+	}
+
+	return i, nil
+}
+
+// Type returns the metric type, "idle".
+func (*Idle) Type() string {
+	return "idle"
+}
+
+// Topic returns the topic to publish idle metrics to.
+func (i *Idle) Topic() string {
+	return i.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (i *Idle) SetInterval(d time.Duration) {
+	i.mu.Lock()
+
+	if i.tick != nil && d != i.interval {
+		i.tick.Reset(d)
+	}
+
+	i.interval = d
+
+	i.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (i *Idle) Interval() time.Duration {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.interval
+}
+
+func (i *Idle) loop(ctx context.Context) {
+	defer recoverLoop(i.Type())
+
+	i.mu.Lock()
+	i.tick = time.NewTicker(i.interval)
+	i.mu.Unlock()
+
+	defer i.tick.Stop()
+	defer close(i.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("idle started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.tick.C:
+			err = i.Update()
+			if err == ErrNoChange {
+				log.Debug("idle updated, no change")
+			} else {
+				log.Debug("idle updated")
+			}
+
+			ch = i.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the idle updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (i *Idle) Start(ctx context.Context) (err error) {
+	if i.interval == 0 {
+		log.Warn("Idle interval is 0, not starting")
+		return
+	}
+
+	i.once.Do(func() {
+		ctx, i.stop = context.WithCancel(ctx)
+		i.ch = make(chan error)
+
+		go i.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the idle metric to update. The returned error will not
+// be sent on the channel returned by [Idle.Updated] unlike updates that
+// happen automatically every update interval.
+func (i *Idle) Update() (err error) {
+	defer errUpdate(i.Type(), time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), idleTimeout)
+	defer cancel()
+
+	d, err := i.backend.idle(ctx)
+	if err != nil {
+		return err
+	}
+
+	idle := int64(d / time.Second)
+	active := d < i.threshold
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if idle == i.idle && active == i.active {
+		return ErrNoChange
+	}
+
+	i.idle = idle
+	i.active = active
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (i *Idle) Updated() <-chan error {
+	return i.ch
+}
+
+// Stop stops the Idle from continuing to update. Once stopped, the Idle
+// may not be restarted.
+func (i *Idle) Stop() {
+	i.mu.Lock()
+
+	if i.stop != nil {
+		i.stop()
+	}
+
+	i.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the idle time and whether the
+// user is active.
+func (i *Idle) String() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	s := "idle for " + (time.Duration(i.idle) * time.Second).String()
+	if i.active {
+		s += " (active)"
+	}
+
+	return s
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of i to b.
+func (i *Idle) AppendText(b []byte) ([]byte, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	p := payload.Idle{
+		Idle:   i.idle,
+		Active: i.active,
+	}
+
+	return p.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Idle.AppendText](nil).
+func (i *Idle) MarshalJSON() ([]byte, error) {
+	return i.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of idle, as produced by [Idle.MarshalJSON], into i.
+func (i *Idle) UnmarshalJSON(data []byte) error {
+	var p payload.Idle
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	i.idle = p.Idle
+	i.active = p.Active
+	i.mu.Unlock()
+
+	return nil
+}
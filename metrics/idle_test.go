@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func setIdleNow(t *testing.T, now time.Time) {
+	t.Helper()
+
+	fn := idleNow
+	t.Cleanup(func() {
+		idleNow = fn
+	})
+
+	idleNow = func() time.Time { return now }
+}
+
+func TestIdle_X11(t *testing.T) {
+	f := &fakeExec{
+		installed: []string{"xprintidle", "loginctl"},
+		out: map[string]string{
+			"xprintidle": "42917\n",
+		},
+	}
+	f.install(t)
+
+	t.Setenv("DISPLAY", ":0")
+
+	cfg := config.Default()
+	cfg.Idle.Threshold = time.Minute
+
+	idle, err := NewIdle(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idle.backend.(x11Backend); !ok {
+		t.Fatalf("Wanted x11 backend, got %#v", idle.backend)
+	}
+
+	if err := idle.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "idle for 42s (active)", idle.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+	if err := idle.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	f.out["xprintidle"] = "61000\n"
+
+	if err := idle.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := idle.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"idle": 61, "active": false}`; string(b) != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, b)
+	}
+}
+
+func TestIdle_Logind(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	setIdleNow(t, now)
+
+	f := &fakeExec{
+		installed: []string{"loginctl"},
+		out:       make(map[string]string),
+	}
+	f.install(t)
+
+	cfg := config.Default()
+	cfg.Idle.Backend = "logind"
+
+	t.Setenv("XDG_SESSION_ID", "c2")
+
+	idle, err := NewIdle(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const cmd = "loginctl show-session c2 --property=IdleHint --property=IdleSinceHint"
+
+	var tests = []struct {
+		name    string
+		out     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"Active", "IdleHint=no\nIdleSinceHint=1699999000000000\n", 0, false},
+		{"Idle", "IdleHint=yes\nIdleSinceHint=1699999400000000\n", 10 * time.Minute, false},
+		{"NeverIdle", "IdleHint=yes\nIdleSinceHint=0\n", 0, false},
+		{"Invalid", "Failed to get session\n", 0, true},
+	}
+	for _, tt := range tests {
+		f.out[cmd] = tt.out
+
+		got, err := idle.backend.idle(t.Context())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Wanted error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Wanted idle %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestIdle_Input(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "dev", "input")
+
+	if err := os.MkdirAll(filepath.Join(input, "by-id"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	setIdleNow(t, now)
+
+	for name, ago := range map[string]time.Duration{
+		"event0": time.Hour,
+		"event3": 90 * time.Second,
+		"mice":   time.Second,
+	} {
+		path := filepath.Join(input, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-ago), now.Add(-ago)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &fakeExec{}
+	f.install(t)
+
+	t.Setenv("DISPLAY", "")
+	t.Setenv("XDG_SESSION_ID", "")
+
+	cfg := config.Default()
+	cfg.RootFS = root
+
+	idle, err := NewIdle(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idle.backend.(inputBackend); !ok {
+		t.Fatalf("Wanted input backend, got %#v", idle.backend)
+	}
+
+	if err := idle.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "idle for 1m30s (active)", idle.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+}
+
+func TestNewIdle_NotSupported(t *testing.T) {
+	f := &fakeExec{}
+	f.install(t)
+
+	cfg := config.Default()
+	cfg.Idle.Backend = "x11"
+
+	if _, err := NewIdle(cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Wanted error %v, got %v", ErrNotSupported, err)
+	}
+
+	cfg.Idle.Backend = "screensaver"
+
+	if _, err := NewIdle(cfg); err == nil {
+		t.Error("Wanted error for unknown backend, got nil")
+	}
+}
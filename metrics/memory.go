@@ -2,13 +2,16 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
 	"io"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/procfs"
@@ -31,6 +34,7 @@ type Memory struct {
 	swapSize    byteutil.ByteSize
 	includeSwap bool
 
+	proc     procfs.FS
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
@@ -45,29 +49,35 @@ type Memory struct {
 // encountered while initializing the Memory, a non-nil error that wraps [ErrNotSupported]
 // is returned.
 func NewMemory(cfg *config.Config) (*Memory, error) {
-	m := &Memory{includeSwap: cfg.Memory.IncludeSwap}
+	return NewMemoryFromConfig(cfg.Memory, DefaultsOf(cfg))
+}
+
+// NewMemoryFromConfig is like [NewMemory] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewMemoryFromConfig(cfg config.MemoryConfig, d Defaults) (*Memory, error) {
+	m := &Memory{includeSwap: cfg.IncludeSwap, proc: procfs.NewFS(d.Root)}
 
 	if err := m.parseInfo(); err != nil {
 		return nil, errNotSupported(m.Type(), err)
 	}
 
-	if cfg.Memory.SizeUnit != "" {
-		size, err := byteutil.ParseSize(cfg.Memory.SizeUnit)
+	if cfg.SizeUnit != "" {
+		size, err := byteutil.ParseSize(cfg.SizeUnit)
 		if err == nil {
 			m.size = size
 		}
 	}
 
-	if cfg.Memory.Interval > 0 {
-		m.interval = cfg.Memory.Interval
-	} else {
+	if cfg.Interval > 0 {
 		m.interval = cfg.Interval
+	} else {
+		m.interval = d.Interval
 	}
 
-	if cfg.Memory.Topic != "" {
-		m.topic = cfg.Memory.Topic
-	} else if cfg.BaseTopic != "" {
-		m.topic = cfg.BaseTopic + "/metric/memory"
+	if cfg.Topic != "" {
+		m.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		m.topic = d.BaseTopic + "/metric/memory"
 	} else {
 		m.topic = "mqttop/metric/memory"
 	}
@@ -80,8 +90,24 @@ var (
 	swapKey  = []byte("SwapTotal")
 )
 
+// parseKiB parses the value of a field of /proc/meminfo in kibibytes, such as
+// "16384 kB", returning it in bytes. Negative values are 0, and values that
+// overflow are [math.MaxUint64].
+func parseKiB(val []byte) uint64 {
+	if byteutil.Btoi(val) < 0 {
+		return 0
+	}
+
+	u := byteutil.Btou(val)
+	if u > math.MaxUint64>>10 {
+		return math.MaxUint64
+	}
+
+	return u << 10
+}
+
 func (m *Memory) parseInfo() error {
-	info, err := procfs.MemInfo()
+	info, err := m.proc.MemInfo()
 	if err != nil {
 		return err
 	}
@@ -103,7 +129,7 @@ func (m *Memory) parseInfo() error {
 		key, val := byteutil.Field(line)
 
 		if byteutil.Equal(key, totalKey) {
-			m.total = byteutil.Btou(val) << 10
+			m.total = parseKiB(val)
 			m.size = byteutil.SizeOf(m.total)
 
 			if m.swapTotal > 0 {
@@ -113,7 +139,7 @@ func (m *Memory) parseInfo() error {
 
 		if byteutil.Equal(key, swapKey) {
 			includeSwap = true
-			m.swapTotal = uint64(byteutil.Btoi(val)) << 10
+			m.swapTotal = parseKiB(val)
 			m.swapSize = byteutil.SizeOf(m.swapTotal)
 
 			if m.total > 0 {
@@ -150,7 +176,17 @@ func (m *Memory) SetInterval(d time.Duration) {
 	m.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (m *Memory) Interval() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.interval
+}
+
 func (m *Memory) loop(ctx context.Context) {
+	defer recoverLoop(m.Type())
+
 	m.mu.Lock()
 	m.tick = time.NewTicker(m.interval)
 	m.mu.Unlock()
@@ -202,11 +238,13 @@ func (m *Memory) Start(ctx context.Context) (err error) {
 // Update forces the memory metric to update. The returned error will not
 // be sent on the channel returned by [Memory.Updated] unlike updates that
 // happen automatically every update interval.
-func (m *Memory) Update() error {
+func (m *Memory) Update() (err error) {
+	defer errUpdate(m.Type(), time.Now(), &err)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	info, err := procfs.MemInfo()
+	info, err := m.proc.MemInfo()
 	if err != nil {
 		return err
 	}
@@ -233,19 +271,19 @@ func (m *Memory) Update() error {
 
 		switch string(key) {
 		case "MemFree":
-			m.free = byteutil.Btou(val) << 10
+			m.free = parseKiB(val)
 		case "MemAvailable":
-			m.avail = byteutil.Btou(val) << 10
+			m.avail = parseKiB(val)
 			gotAvailable = true
 		case "Cached":
-			m.cached = byteutil.Btou(val) << 10
+			m.cached = parseKiB(val)
 		case "SwapTotal":
 			if m.includeSwap {
-				m.swapTotal = byteutil.Btou(val) << 10
+				m.swapTotal = parseKiB(val)
 			}
 		case "SwapFree":
 			if m.includeSwap {
-				m.swapFree = byteutil.Btou(val) << 10
+				m.swapFree = parseKiB(val)
 			}
 		}
 	}
@@ -255,13 +293,13 @@ func (m *Memory) Update() error {
 	}
 
 	if m.avail > m.total {
-		m.used = m.total - m.free
+		m.used = m.total - min(m.free, m.total)
 	} else {
 		m.used = m.total - m.avail
 	}
 
 	if m.swapTotal > 0 {
-		m.swapUsed = m.swapTotal - m.swapFree
+		m.swapUsed = m.swapTotal - min(m.swapFree, m.swapTotal)
 	}
 
 	return nil
@@ -299,36 +337,61 @@ func (m *Memory) String() string {
 	return b.String()
 }
 
+func (m *Memory) toPayload(p *payload.Memory) {
+	p.Total = payload.Size(byteutil.ScaleSize(m.total, m.size))
+	p.Used = payload.Size(byteutil.ScaleSize(m.used, m.size))
+	p.Available = payload.Size(byteutil.ScaleSize(m.avail, m.size))
+	p.Cached = payload.Size(byteutil.ScaleSize(m.cached, m.size))
+	p.Free = payload.Size(byteutil.ScaleSize(m.free, m.size))
+
+	hasSwap := m.swapTotal > 0
+	p.SwapTotal = payload.Maybe(payload.Size(byteutil.ScaleSize(m.swapTotal, m.swapSize)), hasSwap)
+	p.SwapUsed = payload.Maybe(payload.Size(byteutil.ScaleSize(m.swapUsed, m.swapSize)), hasSwap)
+	p.SwapFree = payload.Maybe(payload.Size(byteutil.ScaleSize(m.swapFree, m.swapSize)), hasSwap)
+}
+
+func (m *Memory) fromPayload(p *payload.Memory) {
+	m.total = byteutil.UnscaleSize(uint64(p.Total), m.size)
+	m.used = byteutil.UnscaleSize(uint64(p.Used), m.size)
+	m.avail = byteutil.UnscaleSize(uint64(p.Available), m.size)
+	m.cached = byteutil.UnscaleSize(uint64(p.Cached), m.size)
+	m.free = byteutil.UnscaleSize(uint64(p.Free), m.size)
+	m.swapTotal = byteutil.UnscaleSize(uint64(p.SwapTotal.Value), m.swapSize)
+	m.swapUsed = byteutil.UnscaleSize(uint64(p.SwapUsed.Value), m.swapSize)
+	m.swapFree = byteutil.UnscaleSize(uint64(p.SwapFree.Value), m.swapSize)
+}
+
 // AppendText implements [encoding/TextAppender] and appends the JSON-encoded
 // representation of m to b.
 func (m *Memory) AppendText(b []byte) ([]byte, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	b = append(b, "{\"total\": "...)
-	b = byteutil.AppendSize(b, m.total, m.size)
-	b = append(b, ", \"used\": "...)
-	b = byteutil.AppendSize(b, m.used, m.size)
-	b = append(b, ", \"available\": "...)
-	b = byteutil.AppendSize(b, m.avail, m.size)
-	b = append(b, ", \"cached\": "...)
-	b = byteutil.AppendSize(b, m.cached, m.size)
-	b = append(b, ", \"free\": "...)
-	b = byteutil.AppendSize(b, m.free, m.size)
+	var p payload.Memory
 
-	if m.swapTotal > 0 {
-		b = append(b, ", \"swapTotal\": "...)
-		b = byteutil.AppendSize(b, m.swapTotal, m.swapSize)
-		b = append(b, ", \"swapUsed\": "...)
-		b = byteutil.AppendSize(b, m.swapUsed, m.swapSize)
-		b = append(b, ", \"swapFree\": "...)
-		b = byteutil.AppendSize(b, m.swapFree, m.swapSize)
-	}
+	m.toPayload(&p)
 
-	return append(b, '}'), nil
+	return p.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [CPU.AppendText](nil).
 func (m *Memory) MarshalJSON() ([]byte, error) {
 	return m.AppendText(nil)
 }
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of memory, as produced by [Memory.MarshalJSON], into m. The
+// sizes are interpreted in the size units m was configured with.
+func (m *Memory) UnmarshalJSON(data []byte) error {
+	var p payload.Memory
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.fromPayload(&p)
+	m.mu.Unlock()
+
+	return nil
+}
@@ -2,22 +2,21 @@ package metrics
 
 import (
 	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/procfs"
 )
 
 func testMemory(t *testing.T) (*Memory, *config.Config) {
 	t.Helper()
 
-	err := file.SetRoot("testdata/fixtures")
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
 
 	mem, err := NewMemory(cfg)
 
@@ -101,7 +100,7 @@ func TestMemory_MarshalJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	want := `{"total":14.940,"used":0,"available":0,"cached":0,"free":0,"swapTotal":975.996,"swapUsed":0,"swapFree":0}`
+	want := `{"total":14.94,"used":0,"available":0,"cached":0,"free":0,"swapTotal":975.996,"swapUsed":0,"swapFree":0}`
 
 	if got := string(data); got != want {
 		var i int
@@ -117,3 +116,157 @@ func TestMemory_MarshalJSON(t *testing.T) {
 		t.Errorf("result differs at char %d\nwant %q\ngot  %q", i, want[:i+1], got[:i+1])
 	}
 }
+
+func TestMemory_UnmarshalJSON(t *testing.T) {
+	mem, _ := testMemory(t)
+
+	if err := mem.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := json.Marshal(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := testMemory(t)
+
+	if err = json.Unmarshal(want, got); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(want) {
+		t.Errorf("round trip differs\nwant %s\ngot  %s", want, data)
+	}
+}
+
+func TestMemory_UpdateMemInfo(t *testing.T) {
+	var tests = []struct {
+		name    string
+		meminfo string
+		// want are the total, available, used, swap total and swap used in
+		// KiB.
+		want [5]uint64
+	}{
+		{
+			"Available",
+			"MemTotal:       16384 kB\nMemFree:         2048 kB\nMemAvailable:   12288 kB\nBuffers:          512 kB\nCached:          8192 kB\nSwapCached:         0 kB\nSwapTotal:       4096 kB\nSwapFree:        1024 kB\nDirty:             64 kB\n",
+			[5]uint64{16384, 12288, 4096, 4096, 3072},
+		},
+		{
+			// Kernels before 3.14 don't report MemAvailable.
+			"NoAvailable",
+			"MemTotal:       16384 kB\nMemFree:         2048 kB\nBuffers:          512 kB\nCached:          8192 kB\nSwapTotal:       4096 kB\nSwapFree:        4096 kB\n",
+			[5]uint64{16384, 10240, 6144, 4096, 0},
+		},
+		{
+			// Containers may report more available than total.
+			"AvailableOverTotal",
+			"MemTotal:        4096 kB\nMemFree:         1024 kB\nMemAvailable:    8192 kB\nCached:           512 kB\nSwapTotal:          0 kB\nSwapFree:           0 kB\n",
+			[5]uint64{4096, 8192, 3072, 0, 0},
+		},
+		{
+			"SwapFreeOverTotal",
+			"MemTotal:        4096 kB\nMemFree:         1024 kB\nMemAvailable:    2048 kB\nSwapTotal:       1024 kB\nSwapFree:        2048 kB\n",
+			[5]uint64{4096, 2048, 2048, 1024, 0},
+		},
+		{
+			// Fields after Dirty aren't read.
+			"AfterDirty",
+			"MemTotal:        4096 kB\nMemFree:         1024 kB\nMemAvailable:    2048 kB\nSwapTotal:       1024 kB\nDirty:              0 kB\nSwapFree:        1024 kB\n",
+			[5]uint64{4096, 2048, 2048, 1024, 1024},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "proc", "meminfo")
+
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.meminfo), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := Memory{includeSwap: true, proc: procfs.NewFS(testRoot(t, dir))}
+			if err := m.parseInfo(); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Update(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := [5]uint64{m.total >> 10, m.avail >> 10, m.used >> 10, m.swapTotal >> 10, m.swapUsed >> 10}
+			if got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseKiB(t *testing.T) {
+	var tests = []struct {
+		val  string
+		want uint64
+	}{
+		{"       16384 kB", 16384 << 10},
+		{"0 kB", 0},
+		{"-1024 kB", 0},
+		{"18014398509481983 kB", 18014398509481983 << 10},
+		{"18014398509481984 kB", math.MaxUint64},
+		{"99999999999999999999 kB", math.MaxUint64},
+	}
+	for _, tt := range tests {
+		if got := parseKiB([]byte(tt.val)); got != tt.want {
+			t.Errorf("%q: want %d, got %d", tt.val, tt.want, got)
+		}
+	}
+}
+
+// FuzzMemory_Update parses arbitrary /proc/meminfo files.
+func FuzzMemory_Update(f *testing.F) {
+	for _, s := range []string{
+		"MemTotal:       16384 kB\nMemFree:         8192 kB\nMemAvailable:   12288 kB\nCached:          2048 kB\nSwapTotal:       4096 kB\nSwapFree:        1024 kB\n",
+		"MemTotal: 1024 kB\nMemFree: 4096 kB\nSwapTotal: 1 kB\nSwapFree: 99999999999999999999 kB\n",
+		"MemTotal: -1 kB\nSwapTotal: -5 kB\n",
+		"MemFree",
+	} {
+		f.Add([]byte(s))
+	}
+
+	root := f.TempDir()
+	path := filepath.Join(root, "proc", "meminfo")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		f.Fatal(err)
+	}
+
+	proc := procfs.NewFS(testRoot(f, root))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		m := Memory{includeSwap: true, proc: proc}
+		if err := m.parseInfo(); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Update(); err != nil && err != ErrNoChange {
+			t.Fatal(err)
+		}
+
+		if m.used > m.total {
+			t.Errorf("used %d greater than total %d", m.used, m.total)
+		}
+		if m.swapUsed > m.swapTotal {
+			t.Errorf("swap used %d greater than total %d", m.swapUsed, m.swapTotal)
+		}
+	})
+}
@@ -2,17 +2,32 @@
 package metrics
 
 import (
+	"bytes"
 	"context"
 	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 // Metric is the interface for providing a metric over MQTT.
+//
+// A metric does not need to be started to be used. Calling Update and then
+// encoding the metric, such as with MarshalJSON, gives a single snapshot of it
+// without any update interval, see [Snapshot]. Metrics that report a rate
+// measure it between consecutive updates, so the first update only provides
+// the baseline of the rate. Stop should still be called once the metric is no
+// longer needed, to release any resources held since it was created.
 type Metric interface {
 	// Type returns a constant string representing the type of the metric.
 	Type() string
@@ -24,6 +39,8 @@ type Metric interface {
 	// per metric. Any calls to Start after stopping the metric will do nothing.
 	Start(context.Context) error
 	// Update forces the metric to update regardless of the update interval.
+	// Any error other than [ErrNoChange] or [ErrRescanned] is an [*Error] of
+	// the kind [ErrTransient], [ErrPermission] or [ErrMissingHardware].
 	Update() error
 	// Updated returns the channel updates will be published to every update interval.
 	// There may not be anything sent on the channel if there were no changes between
@@ -38,16 +55,309 @@ type Metric interface {
 	json.Marshaler
 }
 
+// lastUpdates are the start and end times of the last update of each metric,
+// by the name its Update passes to errUpdate.
+var lastUpdates sync.Map
+
+// LastUpdate returns the times the last update of m started and ended, such as
+// to trace the updates of the bridge, and whether m was updated at all.
+func LastUpdate(m Metric) (start, end time.Time, ok bool) {
+	name := m.Type()
+	if d, isDir := m.(*Dir); isDir {
+		name = d.path
+	}
+
+	v, ok := lastUpdates.Load(name)
+	if !ok {
+		return
+	}
+
+	times := v.([2]time.Time)
+
+	return times[0], times[1], true
+}
+
+// Informer is implemented by metrics that have static information which does not
+// change between updates, such as the model of the hardware. The information is
+// published retained to the "/info" subtopic of the metric once it is started.
+type Informer interface {
+	// AppendInfo appends the JSON-encoded static information to b.
+	AppendInfo(b []byte) ([]byte, error)
+}
+
+// A Command handles a payload published to the command topic of a metric.
+type Command func(payload []byte) error
+
+// Commander is implemented by metrics that may be controlled over MQTT. Each
+// command is subscribed to on a subtopic of the metric, and the metric is updated
+// after a command is handled successfully.
+type Commander interface {
+	// Commands returns the commands of the metric keyed by the subtopic of the
+	// metric they are subscribed to, such as "boost/set".
+	Commands() map[string]Command
+}
+
+// Counterer is implemented by metrics with cumulative [Counter] totals, such as
+// the bytes received by each network interface. The counters may be saved when
+// the metric is stopped and restored before it is started, so that the totals
+// keep increasing across restarts.
+type Counterer interface {
+	// Counters returns the counters of the metric keyed by a name unique to
+	// the metric, such as "<interface>/rx".
+	Counters() map[string]Counter
+	// SetCounters restores the counters returned by Counters. Counters that
+	// are unknown to the metric are ignored.
+	SetCounters(map[string]Counter)
+}
+
+// Intervaler is implemented by metrics that send on their [Metric.Updated]
+// channel every update interval, so that a metric which stopped updating may
+// be noticed.
+type Intervaler interface {
+	// Interval returns the update interval of the metric, or 0 if the metric
+	// doesn't update at a regular interval.
+	Interval() time.Duration
+}
+
+// TemperatureLimiter is implemented by metrics whose temperature has limits
+// reported by the hardware, such as the max and crit of a hwmon sensor.
+type TemperatureLimiter interface {
+	// TemperatureLimits returns the high and critical limits of the
+	// temperature in m°C, each of which is 0 if not reported.
+	TemperatureLimits() (high, crit int64)
+}
+
+// ParseSwitch parses the payload of a command that turns something on or off,
+// as published by a Home Assistant switch.
+func ParseSwitch(payload []byte) (bool, error) {
+	switch strings.ToLower(string(bytes.TrimSpace(payload))) {
+	case "on", "true", "1":
+		return true, nil
+	case "off", "false", "0":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("invalid switch payload %q", payload)
+}
+
+// Defaults are the values of [config.Config] shared by all metrics. They are
+// used by the constructors that initialize a metric from only its own config,
+// such as [NewCPUFromConfig].
+type Defaults struct {
+	// Interval is the update interval of a metric with an interval of 0.
+	Interval time.Duration
+	// BaseTopic is the base of the topic of a metric without a topic, which
+	// is "<base_topic>/metric/<metric_type>". If blank, "mqttop" is used.
+	BaseTopic string
+	// Controls are the controls a metric may enable.
+	Controls config.ControlsConfig
+	// Precision is the precision of a metric with a precision of 0, as in
+	// [config.Config].Precision.
+	Precision int
+	// Root is the root that the files of a metric are read from. If nil, the
+	// root of the host is used, see [vfs.Host].
+	Root *vfs.Root
+}
+
+// DefaultsOf returns the [Defaults] of cfg.
+func DefaultsOf(cfg *config.Config) Defaults {
+	root, err := vfs.NewRoot(cfg.RootFS)
+	if err != nil {
+		log.WarnError("Unable to open rootfs, using host", err, "rootfs", cfg.RootFS)
+	}
+
+	return Defaults{
+		Interval:  cfg.Interval,
+		BaseTopic: cfg.BaseTopic,
+		Controls:  cfg.Controls,
+		Precision: cfg.Precision,
+		Root:      root,
+	}
+}
+
+// precision returns the precision of a metric configured with precision prec,
+// which is the precision of d if prec is 0.
+func (d Defaults) precision(prec int) int {
+	if prec == 0 {
+		return d.Precision
+	}
+
+	return prec
+}
+
+// places returns the number of decimal places of the fixed-point values of a
+// metric with precision prec. A precision of 0 is [config.DefaultPrecision],
+// and a negative precision is 0 places.
+func places(prec int) int {
+	switch {
+	case prec == 0:
+		return config.DefaultPrecision
+	case prec < 0:
+		return 0
+	}
+
+	return min(prec, 6)
+}
+
+// calibrate returns v corrected by cal, where v is in units of 1/scale of the
+// unit of cal, such as m°C for a calibration in °C with a scale of 1000.
+func calibrate(cal config.Calibration, v int64, scale float64) int64 {
+	if cal.IsZero() {
+		return v
+	}
+
+	return int64(math.Round(cal.Apply(float64(v)/scale) * scale))
+}
+
+// Constructors are the constructors of each metric configured by a single
+// section of [config.Config], keyed by the type of the metric returned by
+// [Metric.Type]. Dirs are not included since there may be many of them, see
+// [NewDir].
+var Constructors = map[string]func(cfg *config.Config) (Metric, error){
+	"cpu":       constructor(NewCPU),
+	"memory":    constructor(NewMemory),
+	"disks":     constructor(NewDisks),
+	"net":       constructor(NewNet),
+	"battery":   constructor(NewBattery),
+	"fans":      constructor(NewFans),
+	"audio":     constructor(NewAudio),
+	"idle":      constructor(NewIdle),
+	"processes": constructor(NewProcesses),
+	"system":    constructor(NewSystem),
+	"smart":     constructor(NewSmart),
+	"energy":    constructor(NewEnergy),
+	"gpu":       newGPU,
+}
+
+// Renew returns a new metric initialized from cfg to replace m, since a metric
+// may not be restarted once stopped. The interval of m is kept if it implements
+// [Intervaler] and has a regular interval, and the state of m is carried over,
+// see [carryState]. If m isn't configured by cfg, a non-nil error that wraps
+// [ErrNotSupported] is returned.
+func Renew(m Metric, cfg *config.Config) (Metric, error) {
+	var (
+		mm  Metric
+		err error
+	)
+
+	switch m := m.(type) {
+	case *Dir:
+		for i := range cfg.Dirs {
+			if filepath.Clean(cfg.Dirs[i].Path) == m.path {
+				mm, err = NewDirFromConfig(cfg.Dirs[i], DefaultsOf(cfg))
+				break
+			}
+		}
+
+		if mm == nil && err == nil {
+			err = errNotSupported(m.path, ErrDisabled)
+		}
+	default:
+		fn, ok := Constructors[m.Type()]
+		if !ok {
+			return nil, errNotSupported(m.Type(), ErrDisabled)
+		}
+
+		mm, err = fn(cfg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if im, ok := m.(Intervaler); ok {
+		if d := im.Interval(); d > 0 {
+			mm.SetInterval(d)
+		}
+	}
+
+	carryState(m, mm)
+
+	return mm, nil
+}
+
+// carryState carries the state of m that isn't read from the system over to
+// mm, which must be of the same type and not yet started. This is the counters
+// of a [Counterer], the selection mode of the CPU, the samples of the disks
+// and the data usage of the network.
+func carryState(m, mm Metric) {
+	if c, ok := m.(Counterer); ok {
+		if cc, ok := mm.(Counterer); ok {
+			cc.SetCounters(c.Counters())
+		}
+	}
+
+	switch m := m.(type) {
+	case *CPU:
+		if err := mm.(*CPU).SetSelectionMode(m.SelectionMode()); err != nil {
+			log.Debug("Unable to carry over selection mode", "err", err)
+		}
+	case *Disks:
+		mm.(*Disks).SetSamples(m.Samples())
+	case *Net:
+		mm.(*Net).SetUsage(m.Usage())
+	}
+}
+
+// constructor returns fn as a constructor of [Metric], so that a nil metric
+// is returned as a nil interface.
+func constructor[M Metric](fn func(*config.Config) (M, error)) func(*config.Config) (Metric, error) {
+	return func(cfg *config.Config) (Metric, error) {
+		m, err := fn(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return m, nil
+	}
+}
+
 // NewMetrics returns a slice of all the metrics enabled in the given config.
 // If any metric returns an error, it is simply ignored and will not be in the slice.
 func New(cfg *config.Config) []Metric {
-	var m []Metric
+	m, _ := NewWithUnsupported(cfg)
+	return m
+}
+
+// Unsupported is a metric enabled in the config that couldn't be created, and
+// the reason why.
+type Unsupported struct {
+	// Metric is the type of the metric, or the path of a directory.
+	Metric string `json:"-"`
+	// Kind is the kind of the error, one of "permission", "missing_hardware",
+	// "not_supported" or "error".
+	Kind string `json:"kind"`
+	// Error is the error returned by the constructor of the metric.
+	Error string `json:"error"`
+}
+
+// unsupported returns the [Unsupported] of metric caused by err.
+func unsupported(metric string, err error) Unsupported {
+	u := Unsupported{Metric: metric, Kind: "error", Error: err.Error()}
+
+	switch {
+	case errors.Is(err, ErrPermission):
+		u.Kind = "permission"
+	case errors.Is(err, ErrMissingHardware):
+		u.Kind = "missing_hardware"
+	case errors.Is(err, ErrNotSupported):
+		u.Kind = "not_supported"
+	}
+
+	return u
+}
+
+// NewWithUnsupported is like [New] but also returns the metrics enabled in the
+// given config that couldn't be created.
+func NewWithUnsupported(cfg *config.Config) (m []Metric, u []Unsupported) {
+	SetMaxConcurrentUpdates(cfg.MaxConcurrentUpdates)
 
 	if cfg.CPU.Enabled {
 		if cpu, err := NewCPU(cfg); err == nil {
 			m = append(m, cpu)
 		} else {
 			log.Error("Couldn't initialize CPU", err)
+			u = append(u, unsupported("cpu", err))
 		}
 	}
 
@@ -56,6 +366,7 @@ func New(cfg *config.Config) []Metric {
 			m = append(m, mem)
 		} else {
 			log.Error("Couldn't initialize memory", err)
+			u = append(u, unsupported("memory", err))
 		}
 	}
 
@@ -64,6 +375,7 @@ func New(cfg *config.Config) []Metric {
 			m = append(m, disks)
 		} else {
 			log.Error("Couldn't initialize disks", err)
+			u = append(u, unsupported("disks", err))
 		}
 	}
 
@@ -72,6 +384,7 @@ func New(cfg *config.Config) []Metric {
 			m = append(m, net)
 		} else {
 			log.Error("Couldn't initialize net", err)
+			u = append(u, unsupported("net", err))
 		}
 	}
 
@@ -80,6 +393,70 @@ func New(cfg *config.Config) []Metric {
 			m = append(m, bat)
 		} else {
 			log.Error("Couldn't initialize battery", err)
+			u = append(u, unsupported("battery", err))
+		}
+	}
+
+	if cfg.Fans.Enabled {
+		if fans, err := NewFans(cfg); err == nil {
+			m = append(m, fans)
+		} else {
+			log.Error("Couldn't initialize fans", err)
+			u = append(u, unsupported("fans", err))
+		}
+	}
+
+	if cfg.Audio.Enabled {
+		if audio, err := NewAudio(cfg); err == nil {
+			m = append(m, audio)
+		} else {
+			log.Error("Couldn't initialize audio", err)
+			u = append(u, unsupported("audio", err))
+		}
+	}
+
+	if cfg.Idle.Enabled {
+		if idle, err := NewIdle(cfg); err == nil {
+			m = append(m, idle)
+		} else {
+			log.Error("Couldn't initialize idle", err)
+			u = append(u, unsupported("idle", err))
+		}
+	}
+
+	if cfg.Processes.Enabled {
+		if processes, err := NewProcesses(cfg); err == nil {
+			m = append(m, processes)
+		} else {
+			log.Error("Couldn't initialize processes", err)
+			u = append(u, unsupported("processes", err))
+		}
+	}
+
+	if cfg.System.Enabled {
+		if system, err := NewSystem(cfg); err == nil {
+			m = append(m, system)
+		} else {
+			log.Error("Couldn't initialize system", err)
+			u = append(u, unsupported("system", err))
+		}
+	}
+
+	if cfg.Smart.Enabled {
+		if smart, err := NewSmart(cfg); err == nil {
+			m = append(m, smart)
+		} else {
+			log.Error("Couldn't initialize smart", err)
+			u = append(u, unsupported("smart", err))
+		}
+	}
+
+	if cfg.Energy.Enabled {
+		if energy, err := NewEnergy(cfg); err == nil {
+			m = append(m, energy)
+		} else {
+			log.Error("Couldn't initialize energy", err)
+			u = append(u, unsupported("energy", err))
 		}
 	}
 
@@ -87,19 +464,34 @@ func New(cfg *config.Config) []Metric {
 		m = slices.Grow(m, len(cfg.Dirs))
 	}
 
+	defaults := DefaultsOf(cfg)
+	dirs := make([]*Dir, 0, len(cfg.Dirs))
+
 	for i := range cfg.Dirs {
-		if dir, err := newDir(&cfg.Dirs[i], cfg); err == nil {
+		if dir, err := openDir(&cfg.Dirs[i], defaults); err == nil {
 			m = append(m, dir)
+			dirs = append(dirs, dir)
 		} else {
 			log.Error("Couldn't initialize dir", err)
+			u = append(u, unsupported(cfg.Dirs[i].Path, err))
 		}
 	}
 
+	// Scanning large directories may take a while, so their initial sizes
+	// are sent once scanned after they're started.
+	if len(dirs) > 0 {
+		go scanDirs(dirs)
+	}
+
 	if cfg.GPU.Enabled {
-		m = appendGPU(m, cfg)
+		var err error
+
+		if m, err = appendGPU(m, cfg); err != nil {
+			u = append(u, unsupported("gpu", err))
+		}
 	}
 
-	return m
+	return m, u
 }
 
 // SetInterval sets the update interval of the given metrics.
@@ -127,14 +519,54 @@ func Start(ctx context.Context, m ...Metric) error {
 	return err
 }
 
-// Stop stops the given metrics from listening to updates. The metrics may
-// not be restarted after stopping.
+// stopTimeout is how long [Stop] waits for the metrics to stop.
+var stopTimeout = 5 * time.Second
+
+// Stop stops the given metrics from listening to updates, and waits up to 5
+// seconds for them to stop, see [StopContext]. The metrics may not be
+// restarted after stopping.
 func Stop(m ...Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	if err := StopContext(ctx, m...); err != nil {
+		log.WarnError("Metrics didn't stop in time", err)
+	}
+}
+
+// StopContext stops the given metrics from listening to updates, and waits
+// until the update loop of each has exited, which closes the channel returned
+// by [Metric.Updated], along with any watcher of a [Dir]. Any updates that
+// weren't received are discarded. If ctx is done first, its error is
+// returned and the remaining metrics stop in the background. The metrics may
+// not be restarted after stopping.
+func StopContext(ctx context.Context, m ...Metric) error {
+	for _, mm := range m {
+		if mm != nil {
+			mm.Stop()
+		}
+	}
+
 	for _, mm := range m {
 		if mm == nil {
 			continue
 		}
 
-		mm.Stop()
+		// The updates are nil if the metric was never started.
+		ch := mm.Updated()
+		if ch == nil {
+			continue
+		}
+
+		for closed := false; !closed; {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case _, ok := <-ch:
+				closed = !ok
+			}
+		}
 	}
+
+	return nil
 }
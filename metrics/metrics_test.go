@@ -0,0 +1,357 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// testRoot returns the root of dir, such as a copy of the fixtures.
+func testRoot(t testing.TB, dir string) *vfs.Root {
+	t.Helper()
+
+	root, err := vfs.NewRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+// copyFixtures copies the files in the named directories of the fixtures to
+// the same directories in root, so they may be modified by a test.
+func copyFixtures(t testing.TB, root string, dirs ...string) {
+	t.Helper()
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join("testdata/fixtures", dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join("testdata/fixtures", dir, entry.Name()))
+			if err != nil {
+				continue // directories and dangling links
+			}
+
+			if err := os.WriteFile(filepath.Join(root, dir, entry.Name()), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	f := &fakeExec{}
+	f.install(t)
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+
+	for typ, fn := range Constructors {
+		m, err := fn(cfg)
+		if err != nil {
+			if !errors.Is(err, ErrNotSupported) {
+				t.Errorf("%s: want error %v, got %v", typ, ErrNotSupported, err)
+			}
+			if m != nil {
+				t.Errorf("%s: want nil metric, got %T", typ, m)
+			}
+
+			continue
+		}
+
+		if got := m.Type(); got != typ {
+			t.Errorf("%s: want type %q, got %q", typ, typ, got)
+		}
+
+		m.Stop()
+	}
+}
+
+func TestNewWithUnsupported(t *testing.T) {
+	f := &fakeExec{}
+	f.install(t)
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Dirs = []config.DirConfig{{Path: "/missing"}}
+
+	m, u := NewWithUnsupported(cfg)
+	t.Cleanup(func() { Stop(m...) })
+
+	var got *Unsupported
+	for i := range u {
+		if u[i].Metric == "/missing" {
+			got = &u[i]
+		}
+
+		if u[i].Kind == "" || u[i].Error == "" {
+			t.Errorf("%s: want kind and error, got %+v", u[i].Metric, u[i])
+		}
+	}
+
+	if got == nil {
+		t.Fatalf("want /missing unsupported, got %+v", u)
+	}
+	if want := "missing_hardware"; got.Kind != want {
+		t.Errorf("Kind: want %q, got %q", want, got.Kind)
+	}
+
+}
+
+func TestRenew(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+
+	mem, err := NewMemory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem.SetInterval(time.Minute)
+	mem.Stop()
+
+	m, err := Renew(mem, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(m.Stop)
+
+	if m == Metric(mem) || m.Topic() != mem.Topic() {
+		t.Errorf("want new metric with topic %q, got %q", mem.Topic(), m.Topic())
+	}
+	if got := m.(Intervaler).Interval(); got != time.Minute {
+		t.Errorf("Interval: want %v, got %v", time.Minute, got)
+	}
+
+	cpu, err := NewCPU(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode := SelectionModes[len(SelectionModes)-1]
+	if err := cpu.SetSelectionMode(mode); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu.Stop()
+
+	m, err = Renew(cpu, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(m.Stop)
+
+	if got := m.(*CPU).SelectionMode(); got != mode {
+		t.Errorf("SelectionMode: want %q, got %q", mode, got)
+	}
+
+	cfg.Dirs = nil
+
+	if _, err := Renew(&Dir{path: "/missing"}, cfg); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Dir: want error %v, got %v", ErrNotSupported, err)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	root := testRoot(t, "testdata/fixtures")
+
+	mem, err := NewMemoryFromConfig(config.MemoryConfig{}, Defaults{
+		Interval:  time.Minute,
+		BaseTopic: "host",
+		Root:      root,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "host/metric/memory", mem.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := time.Minute, mem.interval; got != want {
+		t.Errorf("Interval: want %v, got %v", want, got)
+	}
+
+	cpu, err := NewCPUFromConfig(config.CPUConfig{
+		MetricConfig: config.MetricConfig{
+			Interval: time.Second,
+			Topic:    "cpu/state",
+		},
+		NameTemplate: "{{ .Name | toupper }}",
+	}, Defaults{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "cpu/state", cpu.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := time.Second, cpu.interval; got != want {
+		t.Errorf("Interval: want %v, got %v", want, got)
+	}
+	if cpu.Name == "" || cpu.Name != strings.ToUpper(cpu.Name) {
+		t.Errorf("Name: want upper case, got %q", cpu.Name)
+	}
+
+	bat, err := NewBatteryFromConfig(config.BatteryConfig{}, Defaults{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "mqttop/metric/battery", bat.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	f := &fakeExec{}
+	f.install(t)
+
+	sample := snapshotSample
+	snapshotSample = time.Millisecond
+	t.Cleanup(func() { snapshotSample = sample })
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Disks.Enabled = false
+
+	snap, err := Snapshot(context.Background(), cfg)
+	if err != nil {
+		t.Logf("Snapshot: %v", err)
+	}
+
+	for _, key := range []string{"cpu", "memory", "battery"} {
+		b, ok := snap[key]
+		if !ok {
+			t.Errorf("%s: want snapshot, got none", key)
+			continue
+		}
+		if !json.Valid(b) {
+			t.Errorf("%s: want valid JSON, got %s", key, b)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Snapshot(ctx, cfg); err != context.Canceled {
+		t.Errorf("Canceled: want %v, got %v", context.Canceled, err)
+	}
+}
+
+// countFDs returns the number of open file descriptors of the process.
+func countFDs(t testing.TB) int {
+	t.Helper()
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("Can't count file descriptors:", err)
+	}
+
+	return len(fds)
+}
+
+// waitGoroutines waits up to a second for the number of goroutines to drop to
+// at most n, since exited goroutines may take a moment to be reaped.
+func waitGoroutines(t testing.TB, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Errorf("Goroutines: want at most %d, got %d", n, runtime.NumGoroutine())
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stuckMetric is a started metric whose updates are never closed.
+type stuckMetric struct {
+	Metric
+	ch chan error
+}
+
+func (m *stuckMetric) Stop() {}
+
+func (m *stuckMetric) Updated() <-chan error {
+	return m.ch
+}
+
+func TestStopContext(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Interval = 10 * time.Millisecond
+
+	goroutines := runtime.NumGoroutine()
+	fds := countFDs(t)
+
+	// Metrics are restarted quickly, such as by the watchdog, without leaking
+	// their loops.
+	for range 5 {
+		mem, err := NewMemory(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cpu, err := NewCPU(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The idle metric is never started.
+		idle := &Idle{}
+
+		if err := Start(t.Context(), mem, cpu); err != nil {
+			t.Fatal(err)
+		}
+
+		<-mem.Updated()
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+
+		if err := StopContext(ctx, mem, cpu, idle, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		cancel()
+
+		if _, ok := <-mem.Updated(); ok {
+			t.Error("Updated: want closed")
+		}
+	}
+
+	waitGoroutines(t, goroutines)
+
+	if got := countFDs(t); got > fds {
+		t.Errorf("FDs: want at most %d, got %d", fds, got)
+	}
+
+	stuck := &stuckMetric{ch: make(chan error)}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := StopContext(ctx, stuck); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stuck: want %v, got %v", context.DeadlineExceeded, err)
+	}
+}
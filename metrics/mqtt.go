@@ -2,27 +2,222 @@ package metrics
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lone-faerie/mqttop/discovery"
 	"github.com/lone-faerie/mqttop/discovery/icon"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
 )
 
-func availabilityTemplate(topic string) string {
-	return fmt.Sprintf(
-		"{{ iif(value_json[%q]|default, 'online', 'offline') if value_json is defined else value }}",
-		topic,
-	)
+// aggregateComponent returns a sensor, disabled by default, of the 5 minute
+// average of the rolling aggregate at path in the payload published to topic.
+// Every average and maximum of the aggregate is included as attributes.
+func aggregateComponent(d *discovery.Discovery, id, name, topic, path string, unit any) discovery.Component {
+	return discovery.Component{
+		discovery.Platform:               discovery.Sensor,
+		discovery.Name:                   name + " 5m average",
+		discovery.EntityCategory:         discovery.Diagnostic,
+		discovery.StateClass:             "measurement",
+		discovery.AvailabilityTopic:      d.AvailabilityTopic,
+		discovery.AvailabilityTemplate:   d.AvailabilityTemplate(topic),
+		discovery.StateTopic:             topic,
+		discovery.ValueTemplate:          "{{ " + path + ".avg_5m | default(none) }}",
+		discovery.UnitOfMeasurement:      unit,
+		discovery.JSONAttributesTopic:    topic,
+		discovery.JSONAttributesTemplate: "{{ " + path + " | default({}) | tojson }}",
+		discovery.UniqueID:               id,
+		discovery.EnabledByDefault:       false,
+	}
+}
+
+// limit is a limit of a sensor reported by the hardware, such as the crit of a
+// hwmon temperature, in units of 1/scale of the unit of the sensor.
+type limit struct {
+	name  string
+	value int64
+	scale float64
+}
+
+// addLimits adds the limits to the attributes of cmp, which is the sensor of
+// the payload published to topic. The limits don't change, so they are
+// constants of the template. Limits of 0 aren't reported and are omitted, and
+// nothing is added if every limit is.
+func addLimits(cmp discovery.Component, topic string, limits ...limit) {
+	var attrs []byte
+
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+
+		if attrs != nil {
+			attrs = append(attrs, ", "...)
+		}
+
+		attrs = append(attrs, '\'')
+		attrs = append(attrs, l.name...)
+		attrs = append(attrs, "': "...)
+		attrs = strconv.AppendFloat(attrs, float64(l.value)/l.scale, 'f', -1, 64)
+	}
+
+	if attrs == nil {
+		return
+	}
+
+	cmp[discovery.JSONAttributesTopic] = topic
+	cmp[discovery.JSONAttributesTemplate] = "{{ {" + string(attrs) + "} | tojson }}"
+}
+
+// Audio Discovery
+
+// Discover implements [discovery.Discoverer]. Adds a sensor for the volume and a
+// binary sensor for the mute state, or a number and switch if volume control is
+// enabled. If playerctl is installed, also adds sensors for the media that is playing.
+func (a *Audio) Discover(d *discovery.Discovery) {
+	id := d.ID("audio_volume")
+	avail := d.AvailabilityTemplate(a.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[a.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 4)
+		}
+
+		cmps = node
+	}
+
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Volume",
+		discovery.Icon:                 icon.VolumeHigh,
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           a.Topic(),
+		discovery.ValueTemplate:        "{{ value_json.volume }}",
+		discovery.UnitOfMeasurement:    "%",
+		discovery.UniqueID:             id,
+	}
+
+	if a.control {
+		d.Components[id][discovery.Platform] = discovery.Number
+		d.Components[id][discovery.EntityCategory] = discovery.Config
+		d.Components[id][discovery.CommandTopic] = a.Topic() + "/volume/set"
+		d.Components[id][discovery.Min] = 0
+		d.Components[id][discovery.Max] = 100
+		d.Components[id][discovery.Step] = 1
+		d.Components[id][discovery.Mode] = "slider"
+		delete(d.Components[id], discovery.StateClass)
+	}
+
+	id = d.ID("audio_muted")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.BinarySensor,
+		discovery.Name:                 "Muted",
+		discovery.Icon:                 icon.VolumeOff,
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           a.Topic(),
+		discovery.ValueTemplate:        "{{ iif(value_json.muted, 'ON', 'OFF') }}",
+		discovery.UniqueID:             id,
+	}
+
+	if a.control {
+		d.Components[id][discovery.Platform] = discovery.Switch
+		d.Components[id][discovery.EntityCategory] = discovery.Config
+		d.Components[id][discovery.CommandTopic] = a.Topic() + "/mute/set"
+	}
+
+	if a.nowPlaying {
+		id = d.ID("audio_now_playing")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:               discovery.Sensor,
+			discovery.Name:                   "Now playing",
+			discovery.Icon:                   icon.Music,
+			discovery.AvailabilityTopic:      d.AvailabilityTopic,
+			discovery.AvailabilityTemplate:   avail,
+			discovery.StateTopic:             a.Topic(),
+			discovery.ValueTemplate:          "{{ value_json.title|default('') }}",
+			discovery.JSONAttributesTopic:    a.Topic(),
+			discovery.JSONAttributesTemplate: "{{ {'artist': value_json.artist|default(''), 'album': value_json.album|default(''), 'player': value_json.player|default('')} | tojson }}",
+			discovery.UniqueID:               id,
+		}
+
+		id = d.ID("audio_media_status")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 "Media status",
+			discovery.Icon:                 icon.Music,
+			discovery.DeviceClass:          "enum",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           a.Topic(),
+			discovery.ValueTemplate:        "{{ value_json.status|default('stopped') }}",
+			discovery.Options:              []string{"playing", "paused", "stopped"},
+			discovery.UniqueID:             id,
+		}
+	}
+
+	if cmps != nil {
+		d.Nodes[a.Type()] = cmps
+	}
 }
 
 // Battery Discovery
 
+// timeComponent returns the duration sensor of the field of the payload of b
+// in seconds, in the time unit of b.
+func (b *Battery) timeComponent(d *discovery.Discovery, id, name, field string) discovery.Component {
+	unit := "s"
+	template := fmt.Sprintf("{{ value_json.%s | default(none) }}", field)
+
+	if b.timeUnit == time.Minute {
+		unit = "min"
+		template = fmt.Sprintf("{{ (value_json.%s / 60) | round(1) if value_json.%[1]s is defined else none }}", field)
+	}
+
+	return discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 name,
+		discovery.Icon:                 icon.BatteryClock,
+		discovery.DeviceClass:          "duration",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: d.AvailabilityTemplate(b.Topic()),
+		discovery.StateTopic:           b.Topic(),
+		discovery.ValueTemplate:        template,
+		discovery.UnitOfMeasurement:    unit,
+		discovery.UniqueID:             id,
+	}
+}
+
 // Discover implements [discovery.Discoverer]. Adds sensors for battery state,
-// battery level, battery power, and a binary sensor for battery charging.
+// battery level, battery power, voltage and current, the time remaining while
+// discharging and the time to full while charging, and a binary sensor for
+// battery charging.
 func (b *Battery) Discover(d *discovery.Discovery) {
-	id := d.Origin.Name + "_battery_state"
-	avail := availabilityTemplate(b.Topic())
+	id := d.ID("battery_state")
+	avail := d.AvailabilityTemplate(b.Topic())
 
 	var cmps []string
 
@@ -57,7 +252,7 @@ func (b *Battery) Discover(d *discovery.Discovery) {
 		discovery.UniqueID: id,
 	}
 
-	id = d.Origin.Name + "_battery_charging"
+	id = d.ID("battery_charging")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -76,7 +271,7 @@ func (b *Battery) Discover(d *discovery.Discovery) {
 	}
 
 	if b.hasCapacity() {
-		id = d.Origin.Name + "_battery_level"
+		id = d.ID("battery_level")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -100,8 +295,27 @@ func (b *Battery) Discover(d *discovery.Discovery) {
 		}
 	}
 
+	if b.hasTimeRemaining() {
+		id = d.ID("battery_time_remaining")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = b.timeComponent(d, id, "Battery time remaining", "timeRemaining")
+	}
+
+	if b.flags.Has(batteryTimeToFull) {
+		id = d.ID("battery_time_to_full")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = b.timeComponent(d, id, "Battery time to full", "timeToFull")
+		d.Components[id][discovery.Icon] = icon.BatteryCharge
+	}
+
 	if b.flags.Has(batteryPower) {
-		id = d.Origin.Name + "_battery_power"
+		id = d.ID("battery_power")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -121,6 +335,38 @@ func (b *Battery) Discover(d *discovery.Discovery) {
 		}
 	}
 
+	for _, s := range [...]struct {
+		flag                  batteryFlag
+		id, name, field, unit string
+	}{
+		{batteryVoltage, "battery_voltage", "Battery voltage", "voltage", "V"},
+		{batteryCurrent, "battery_current", "Battery current", "current", "A"},
+	} {
+		if !b.flags.Has(s.flag) {
+			continue
+		}
+
+		id = d.ID(s.id)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 s.name,
+			discovery.EntityCategory:       discovery.Diagnostic,
+			discovery.DeviceClass:          s.field,
+			discovery.StateClass:           "measurement",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           b.Topic(),
+			discovery.ValueTemplate:        "{{ value_json." + s.field + " | default(none) }}",
+			discovery.UnitOfMeasurement:    s.unit,
+			discovery.UniqueID:             id,
+			discovery.EnabledByDefault:     false,
+		}
+	}
+
 	if cmps != nil {
 		d.Nodes[b.Type()] = cmps
 	}
@@ -128,15 +374,31 @@ func (b *Battery) Discover(d *discovery.Discovery) {
 
 // CPU Discovery
 
+// coreTemplate returns the value template of the field of the payload core
+// with the logical id core. The core is selected by its id rather than its
+// index, so that the template stays correct regardless of which cores are
+// shown. A missing field, such as the temperature of a core without a sensor,
+// is none rather than 0.
+func coreTemplate(core int, field string) string {
+	return fmt.Sprintf("{{ (value_json.cores | selectattr('id', 'eq', %d) | first).%s | default(none) }}", core, field)
+}
+
+// discover adds the components of the overall CPU if core is -1, otherwise
+// the components of the core with the logical id core.
 func (c *CPU) discover(core int, d *discovery.Discovery) {
 	var (
 		id, name, template string
-		avail              = availabilityTemplate(c.Topic())
+		avail              = d.AvailabilityTemplate(c.Topic())
 		cmps               []string
+		nodeName           = c.Type()
 	)
 
+	if core != -1 {
+		nodeName = d.CoreNode(core)
+	}
+
 	if d.Nodes != nil {
-		node, ok := d.Nodes[c.Type()]
+		node, ok := d.Nodes[nodeName]
 		if !ok || node == nil {
 			node = make([]string, 0, 3)
 		}
@@ -146,13 +408,13 @@ func (c *CPU) discover(core int, d *discovery.Discovery) {
 
 	if c.flags.Has(cpuUsage) {
 		if core == -1 {
-			id = d.Origin.Name + "_cpu"
+			id = d.ID("cpu")
 			name = "CPU usage"
 			template = "{{ value_json.usage }}"
 		} else {
-			id = d.Origin.Name + "_cpu_core_" + strconv.Itoa(core)
+			id = d.ID("cpu_core_" + strconv.Itoa(core))
 			name = "Core " + strconv.Itoa(core) + " usage"
-			template = fmt.Sprintf("{{ value_json[%d].usage }}", core)
+			template = coreTemplate(core, "usage")
 		}
 
 		if cmps != nil {
@@ -172,17 +434,54 @@ func (c *CPU) discover(core int, d *discovery.Discovery) {
 			discovery.UniqueID:             id,
 			discovery.EnabledByDefault:     core == -1,
 		}
+
+		if core == -1 {
+			d.Components[id][discovery.JSONAttributesTopic] = c.Topic() + "/info"
+		}
+	}
+
+	if c.flags.Has(cpuUsage) && c.sampleInterval > 0 && core == -1 {
+		for _, field := range [...]string{"min", "max"} {
+			id = d.ID("cpu_usage_" + field)
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = discovery.Component{
+				discovery.Platform:             discovery.Sensor,
+				discovery.Name:                 "CPU usage " + field,
+				discovery.Icon:                 icon.CPU,
+				discovery.EntityCategory:       discovery.Diagnostic,
+				discovery.StateTopic:           c.Topic(),
+				discovery.AvailabilityTopic:    d.AvailabilityTopic,
+				discovery.AvailabilityTemplate: avail,
+				discovery.ValueTemplate:        "{{ value_json.usage_" + field + " | default(none) }}",
+				discovery.UnitOfMeasurement:    "%",
+				discovery.UniqueID:             id,
+				discovery.EnabledByDefault:     false,
+			}
+		}
+	}
+
+	if c.flags.Has(cpuUsage) && c.usageAggregate != nil && core == -1 {
+		id = d.ID("cpu_usage_aggregate")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = aggregateComponent(d, id, "CPU usage", c.Topic(), "value_json.usage_aggregate", "%")
+		d.Components[id][discovery.Icon] = icon.CPU
 	}
 
 	if c.flags.Has(cpuTemperature) {
 		if core == -1 {
-			id = d.Origin.Name + "_cpu_temperature"
+			id = d.ID("cpu_temperature")
 			name = "CPU temperature"
-			template = "{{ value_json.temperature }}"
+			template = "{{ value_json.temperature | default(none) }}"
 		} else {
-			id = d.Origin.Name + "_cpu_core_" + strconv.Itoa(core) + "_temperature"
+			id = d.ID("cpu_core_" + strconv.Itoa(core) + "_temperature")
 			name = "Core " + strconv.Itoa(core) + " temperature"
-			template = fmt.Sprintf("{{ value_json.cores[%d].temperature }}", core)
+			template = coreTemplate(core, "temperature")
 		}
 
 		if cmps != nil {
@@ -202,17 +501,40 @@ func (c *CPU) discover(core int, d *discovery.Discovery) {
 			discovery.UniqueID:             id,
 			discovery.EnabledByDefault:     core == -1,
 		}
+
+		sensor := c.temp
+		if core != -1 {
+			i := slices.IndexFunc(c.cores, func(c cpuCore) bool { return c.logical == core })
+			sensor = c.cores[i].temp
+		}
+
+		if sensor != nil {
+			addLimits(d.Components[id], c.Topic(),
+				limit{"max", sensor.High, 1000},
+				limit{"critical", sensor.Crit, 1000},
+			)
+		}
+	}
+
+	if c.flags.Has(cpuTemperature) && c.tempAggregate != nil && core == -1 {
+		id = d.ID("cpu_temperature_aggregate")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = aggregateComponent(d, id, "CPU temperature", c.Topic(), "value_json.temperature_aggregate", "°C")
+		d.Components[id][discovery.DeviceClass] = "temperature"
 	}
 
 	if c.flags.Has(cpuFrequency) {
 		if core == -1 {
-			id = d.Origin.Name + "_cpu_frequency"
+			id = d.ID("cpu_frequency")
 			name = "CPU frequency"
 			template = "{{ value_json.frequency }}"
 		} else {
-			id = d.Origin.Name + "_cpu_core_" + strconv.Itoa(core) + "_frequency"
+			id = d.ID("cpu_core_" + strconv.Itoa(core) + "_frequency")
 			name = "Core " + strconv.Itoa(core) + " frequency"
-			template = fmt.Sprintf("{{ value_json.cores[%d].frequency }}", core)
+			template = coreTemplate(core, "frequency")
 		}
 
 		if cmps != nil {
@@ -229,14 +551,67 @@ func (c *CPU) discover(core int, d *discovery.Discovery) {
 			discovery.AvailabilityTemplate:      avail,
 			discovery.ValueTemplate:             template,
 			discovery.UnitOfMeasurement:         "GHz",
-			discovery.SuggestedDisplayPrecision: 3,
+			discovery.SuggestedDisplayPrecision: min(3, places(c.prec)),
 			discovery.UniqueID:                  id,
 			discovery.EnabledByDefault:          core == -1,
 		}
 	}
 
+	if core == -1 && c.flags.Has(cpuBoost) {
+		id = d.ID("cpu_boost")
+
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.BinarySensor,
+			discovery.Name:                 "CPU boost",
+			discovery.Icon:                 icon.CPU,
+			discovery.EntityCategory:       discovery.Diagnostic,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           c.Topic(),
+			discovery.ValueTemplate:        "{{ iif(value_json.boost, 'ON', 'OFF') }}",
+			discovery.UniqueID:             id,
+		}
+
+		if c.boostControl {
+			d.Components[id][discovery.Platform] = discovery.Switch
+			d.Components[id][discovery.EntityCategory] = discovery.Config
+			d.Components[id][discovery.CommandTopic] = c.Topic() + "/boost/set"
+		}
+	}
+
+	if core == -1 && c.flags.Has(cpuGovernor) {
+		id = d.ID("cpu_governor")
+
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 "CPU governor",
+			discovery.Icon:                 icon.CPU,
+			discovery.EntityCategory:       discovery.Diagnostic,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           c.Topic(),
+			discovery.ValueTemplate:        "{{ value_json.governor }}",
+			discovery.UniqueID:             id,
+		}
+
+		if c.governorControl {
+			d.Components[id][discovery.Platform] = discovery.Select
+			d.Components[id][discovery.EntityCategory] = discovery.Config
+			d.Components[id][discovery.CommandTopic] = c.Topic() + "/governor/set"
+			d.Components[id][discovery.Options] = c.governors
+		}
+	}
+
 	if core == -1 && c.flags.Has(cpuTemperature|cpuFrequency) {
-		id = d.Origin.Name + "_cpu_select"
+		id = d.ID("cpu_select")
 
 		if cmps != nil {
 			cmps = append(cmps, id)
@@ -247,36 +622,51 @@ func (c *CPU) discover(core int, d *discovery.Discovery) {
 			discovery.Name:                 "CPU selection mode",
 			discovery.AvailabilityTopic:    d.AvailabilityTopic,
 			discovery.AvailabilityTemplate: avail,
-			discovery.CommandTopic:         c.Topic() + "/update",
-			discovery.CommandTemplate:      "{{ {'selection_mode': value} | tojson }}",
-			discovery.StateTopic:           c.Topic(),
+			discovery.CommandTopic:         c.Topic() + "/selection_mode/set",
+			discovery.CommandTemplate:      "{{ value | lower }}",
+			discovery.StateTopic:           c.Topic() + "/selection_mode",
 			discovery.ValueTemplate:        "{{ value_json.selection_mode | title }}",
-			discovery.Options:              []string{"Auto", "First", "Average", "Maximum", "Minimum", "Random"},
+			discovery.Options:              selectionOptions,
 			discovery.UniqueID:             id,
 		}
 	}
 
 	if cmps != nil {
-		d.Nodes[c.Type()] = cmps
+		d.Nodes[nodeName] = cmps
 	}
 }
 
+// selectionOptions are the [SelectionModes] in the format displayed by the
+// discovery select.
+var selectionOptions = func() []string {
+	opts := make([]string, len(SelectionModes))
+	for i, mode := range SelectionModes {
+		opts[i] = byteutil.ToTitleString(mode)
+	}
+
+	return opts
+}()
+
 // Discover implements [discovery.Discoverer]. Adds sensors for cpu and core usage,
-// cpu and core temperature, and cpu and core frequency.
+// cpu and core temperature, and cpu and core frequency. If the usage is sampled
+// between updates, also adds sensors for the minimum and maximum cpu usage, and
+// if aggregates are enabled, sensors for the rolling averages of the cpu usage
+// and temperature.
 func (c *CPU) Discover(d *discovery.Discovery) {
 	c.discover(-1, d)
 
-	for i := range c.cores {
+	for _, i := range c.shown {
 		c.discover(c.cores[i].logical, d)
 	}
 }
 
 // Directory Discovery
 
-// Discover implements [discovery.Discoverer]. Adds sensors for directory size.
+// Discover implements [discovery.Discoverer]. Adds sensors for directory size,
+// which are part of the dirs child device if enabled by the discovery config.
 func (d *Dir) Discover(disc *discovery.Discovery) {
-	id := disc.Origin.Name + "_dir_" + d.Slug()
-	avail := availabilityTemplate(d.Topic())
+	id := disc.ID("dir_" + d.Slug())
+	avail := disc.AvailabilityTemplate(d.Topic())
 
 	var cmps []string
 
@@ -293,6 +683,11 @@ func (d *Dir) Discover(disc *discovery.Discovery) {
 		cmps = append(cmps, id)
 	}
 
+	attrs := "{{ {'path': value_json.path} | tojson }}"
+	if d.watched != nil {
+		attrs = "{{ {'path': value_json.path, 'watched': value_json.watched, 'polled': value_json.polled} | tojson }}"
+	}
+
 	disc.Components[id] = discovery.Component{
 		discovery.Platform:               discovery.Sensor,
 		discovery.Name:                   "Dir " + d.Name,
@@ -303,12 +698,54 @@ func (d *Dir) Discover(disc *discovery.Discovery) {
 		discovery.AvailabilityTemplate:   avail,
 		discovery.StateTopic:             d.Topic(),
 		discovery.ValueTemplate:          "{{ value_json.size }}",
-		discovery.UnitOfMeasurement:      d.byteSize,
+		discovery.UnitOfMeasurement:      d.unit(),
 		discovery.JSONAttributesTopic:    d.Topic(),
-		discovery.JSONAttributesTemplate: "{{ {'path': value_json.path} | tojson }}",
+		discovery.JSONAttributesTemplate: attrs,
 		discovery.UniqueID:               id,
 	}
 
+	disc.AddChild(discovery.ChildDirs, id)
+
+	if d.clean.enabled {
+		id = disc.ID("dir_" + d.Slug() + "_clean")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		disc.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Button,
+			discovery.Name:                 "Clean " + d.Name,
+			discovery.Icon:                 icon.Broom,
+			discovery.AvailabilityTopic:    disc.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.CommandTopic:         d.Topic() + "/clean",
+			discovery.UniqueID:             id,
+		}
+
+		disc.AddChild(discovery.ChildDirs, id)
+
+		id = disc.ID("dir_" + d.Slug() + "_reclaimed")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		disc.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 "Dir " + d.Name + " reclaimed",
+			discovery.Icon:                 icon.Broom,
+			discovery.EntityCategory:       discovery.Diagnostic,
+			discovery.DeviceClass:          "data_size",
+			discovery.AvailabilityTopic:    disc.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           d.Topic(),
+			discovery.ValueTemplate:        "{{ value_json.reclaimed | default(none) }}",
+			discovery.UnitOfMeasurement:    d.unit(),
+			discovery.UniqueID:             id,
+		}
+
+		disc.AddChild(discovery.ChildDirs, id)
+	}
+
 	if cmps != nil {
 		disc.Nodes[d.Type()] = cmps
 	}
@@ -317,9 +754,9 @@ func (d *Dir) Discover(disc *discovery.Discovery) {
 // Disk Discovery
 
 func (d *Disk) discover(dsks *Disks, disc *discovery.Discovery) {
-	id := disc.Origin.Name + "_disk_" + d.Name
+	id := disc.ID("disk_" + d.Name)
 	name := "Disk " + d.Name
-	avail := availabilityTemplate(dsks.Topic())
+	avail := disc.AvailabilityTemplate(dsks.Topic())
 
 	var cmps []string
 
@@ -349,15 +786,57 @@ func (d *Disk) discover(dsks *Disks, disc *discovery.Discovery) {
 		discovery.SuggestedDisplayPrecision: 1,
 		discovery.JSONAttributesTopic:       dsks.Topic(),
 		discovery.JSONAttributesTemplate: fmt.Sprintf(
-			"{{ dict(value_json[%q]|items|rejectattr('0', 'in', ['reads', 'writes'])|list + [('size_unit', %q)]) | tojson }}",
+			"{{ dict(value_json[%q]|items|rejectattr('0', 'in', ['reads', 'writes', 'read_total', 'write_total', 'days_until_full', 'read_only', 'stale'])|list + [('size_unit', %q)]) | tojson }}",
 			d.Name,
 			d.size,
 		),
 		discovery.UniqueID: id,
 	}
 
+	id = disc.ID("disk_" + d.Name + "_problem")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	disc.Components[id] = discovery.Component{
+		discovery.Platform:               discovery.BinarySensor,
+		discovery.Name:                   name + " problem",
+		discovery.Icon:                   icon.HDD,
+		discovery.EntityCategory:         discovery.Diagnostic,
+		discovery.DeviceClass:            "problem",
+		discovery.AvailabilityTopic:      disc.AvailabilityTopic,
+		discovery.AvailabilityTemplate:   avail,
+		discovery.StateTopic:             dsks.Topic(),
+		discovery.ValueTemplate:          fmt.Sprintf("{{ iif(value_json[%[1]q].read_only | default(false) or value_json[%[1]q].stale | default(false), 'ON', 'OFF') }}", d.Name),
+		discovery.JSONAttributesTopic:    dsks.Topic(),
+		discovery.JSONAttributesTemplate: fmt.Sprintf("{{ {'read_only': value_json[%[1]q].read_only | default(false), 'stale': value_json[%[1]q].stale | default(false)} | tojson }}", d.Name),
+		discovery.UniqueID:               id,
+	}
+
+	if d.trend != nil {
+		id = disc.ID("disk_" + d.Name + "_days_until_full")
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		disc.Components[id] = discovery.Component{
+			discovery.Platform:                  discovery.Sensor,
+			discovery.Name:                      name + " days until full",
+			discovery.Icon:                      icon.HDD,
+			discovery.EntityCategory:            discovery.Diagnostic,
+			discovery.DeviceClass:               "duration",
+			discovery.AvailabilityTopic:         disc.AvailabilityTopic,
+			discovery.AvailabilityTemplate:      avail,
+			discovery.StateTopic:                dsks.Topic(),
+			discovery.ValueTemplate:             fmt.Sprintf("{{ value_json[%q].days_until_full | default(none) }}", d.Name),
+			discovery.UnitOfMeasurement:         "d",
+			discovery.SuggestedDisplayPrecision: min(1, places(d.prec)),
+			discovery.UniqueID:                  id,
+		}
+	}
+
 	if d.showIO {
-		id = disc.Origin.Name + "_disk_" + d.Name + "_rx"
+		id = disc.ID("disk_" + d.Name + "_rx")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -377,7 +856,7 @@ func (d *Disk) discover(dsks *Disks, disc *discovery.Discovery) {
 			discovery.EnabledByDefault:     false,
 		}
 
-		id = disc.Origin.Name + "_disk_" + d.Name + "_tx"
+		id = disc.ID("disk_" + d.Name + "_tx")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -396,6 +875,32 @@ func (d *Disk) discover(dsks *Disks, disc *discovery.Discovery) {
 			discovery.UniqueID:             id,
 			discovery.EnabledByDefault:     false,
 		}
+
+		for _, total := range [...]struct{ id, name, field string }{
+			{"read_total", "total read", "read_total"},
+			{"write_total", "total written", "write_total"},
+		} {
+			id = disc.ID("disk_" + d.Name + "_" + total.id)
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			disc.Components[id] = discovery.Component{
+				discovery.Platform:             discovery.Sensor,
+				discovery.Name:                 name + " " + total.name,
+				discovery.Icon:                 icon.HDD,
+				discovery.EntityCategory:       discovery.Diagnostic,
+				discovery.DeviceClass:          "data_size",
+				discovery.StateClass:           "total_increasing",
+				discovery.AvailabilityTopic:    disc.AvailabilityTopic,
+				discovery.AvailabilityTemplate: avail,
+				discovery.StateTopic:           dsks.Topic(),
+				discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q].%s }}", d.Name, total.field),
+				discovery.UnitOfMeasurement:    "B",
+				discovery.UniqueID:             id,
+				discovery.EnabledByDefault:     false,
+			}
+		}
 	}
 
 	if cmps != nil {
@@ -404,28 +909,31 @@ func (d *Disk) discover(dsks *Disks, disc *discovery.Discovery) {
 }
 
 // Discover implements [discovery.Discoverer]. Adds sensors for disk usage, disk reads,
-// and disk writes.
+// disk writes, and the total bytes read and written, and the days until each disk
+// is full if prediction is enabled.
 func (d *Disks) Discover(disc *discovery.Discovery) {
 	for _, dsk := range d.disks {
 		dsk.discover(d, disc)
 	}
 }
 
-// Memory Discovery
+// Fans Discovery
 
-// Discover implements [discovery.Discoverer]. Adds sensors for memory usage,
-// total memory, used memory, free memory, cached memory, swap usage,
-// total swap, used swap, and free swap.
-func (m *Memory) Discover(d *discovery.Discovery) {
-	id := d.Origin.Name + "_memory"
-	avail := availabilityTemplate(m.Topic())
+func (fan *fan) discover(f *Fans, d *discovery.Discovery) {
+	id := d.ID("fan_" + fan.id + "_speed")
+	avail := d.AvailabilityTemplate(f.Topic())
+
+	name := fan.name
+	if name == "" {
+		name = fan.Name + " " + fan.Label
+	}
 
 	var cmps []string
 
 	if d.Nodes != nil {
-		node, ok := d.Nodes[m.Type()]
+		node, ok := d.Nodes[f.Type()]
 		if !ok || node == nil {
-			node = make([]string, 0, 9)
+			node = make([]string, 0, 2)
 		}
 
 		cmps = node
@@ -436,14 +944,311 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 	}
 
 	d.Components[id] = discovery.Component{
-		discovery.Platform:                  discovery.Sensor,
-		discovery.Name:                      "Memory usage",
-		discovery.Icon:                      icon.Memory,
-		discovery.EntityCategory:            discovery.Diagnostic,
-		discovery.AvailabilityTopic:         d.AvailabilityTopic,
-		discovery.AvailabilityTemplate:      avail,
-		discovery.StateTopic:                m.Topic(),
-		discovery.ValueTemplate:             "{{ 100 * value_json.used / value_json.total }}",
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 name + " speed",
+		discovery.Icon:                 icon.Fan,
+		discovery.EntityCategory:       discovery.Diagnostic,
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           f.Topic(),
+		discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q].speed }}", fan.id),
+		discovery.UnitOfMeasurement:    "RPM",
+		discovery.UniqueID:             id,
+	}
+
+	addLimits(d.Components[id], f.Topic(),
+		limit{"min", fan.Min, 1},
+		limit{"max", fan.Max, 1},
+	)
+
+	if fan.HasPWM() {
+		id = d.ID("fan_" + fan.id + "_pwm")
+
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:               discovery.Sensor,
+			discovery.Name:                   name + " PWM",
+			discovery.Icon:                   icon.Fan,
+			discovery.EntityCategory:         discovery.Diagnostic,
+			discovery.StateClass:             "measurement",
+			discovery.AvailabilityTopic:      d.AvailabilityTopic,
+			discovery.AvailabilityTemplate:   avail,
+			discovery.StateTopic:             f.Topic(),
+			discovery.ValueTemplate:          fmt.Sprintf("{{ value_json[%q].pwm }}", fan.id),
+			discovery.JSONAttributesTopic:    f.Topic(),
+			discovery.JSONAttributesTemplate: fmt.Sprintf("{{ {'mode': value_json[%q].mode} | tojson }}", fan.id),
+			discovery.UniqueID:               id,
+			discovery.EnabledByDefault:       false,
+		}
+
+		if fan.control {
+			d.Components[id][discovery.Platform] = discovery.Number
+			d.Components[id][discovery.EntityCategory] = discovery.Config
+			d.Components[id][discovery.CommandTopic] = f.Topic() + "/" + fan.id + "/pwm/set"
+			d.Components[id][discovery.Min] = f.minPWM
+			d.Components[id][discovery.Max] = f.maxPWM
+			d.Components[id][discovery.Step] = 1
+			d.Components[id][discovery.Mode] = "slider"
+			d.Components[id][discovery.EnabledByDefault] = true
+			delete(d.Components[id], discovery.StateClass)
+		}
+	}
+
+	if cmps != nil {
+		d.Nodes[f.Type()] = cmps
+	}
+}
+
+// Discover implements [discovery.Discoverer]. Adds sensors for fan speed and
+// PWM duty cycle, or a number for the duty cycle if fan control is enabled.
+func (f *Fans) Discover(d *discovery.Discovery) {
+	for i := range f.fans {
+		f.fans[i].discover(f, d)
+	}
+}
+
+// GPU Discovery
+
+// Discover implements [discovery.Discoverer]. Adds sensors for the values of the
+// gpu that the driver provides, which may be any of the gpu usage, gpu power,
+// gpu temperature and gpu memory usage. The unique ids are the same as those of
+// [NvidiaGPU], so the entities are kept if NVML becomes available.
+func (g *SysfsGPU) Discover(d *discovery.Discovery) {
+	prefix := d.ID("gpu_" + strconv.Itoa(g.index))
+	avail := d.AvailabilityTemplate(g.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[g.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 4)
+		}
+
+		cmps = node
+	}
+
+	add := func(id string, cmp discovery.Component) {
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		cmp[discovery.Platform] = discovery.Sensor
+		cmp[discovery.EntityCategory] = discovery.Diagnostic
+		cmp[discovery.AvailabilityTopic] = d.AvailabilityTopic
+		cmp[discovery.AvailabilityTemplate] = avail
+		cmp[discovery.StateTopic] = g.Topic()
+		cmp[discovery.UniqueID] = id
+
+		d.Components[id] = cmp
+	}
+
+	if g.util.valid() {
+		add(prefix, discovery.Component{
+			discovery.Name:              g.Name + " usage",
+			discovery.Icon:              icon.GPU,
+			discovery.ValueTemplate:     "{{ value_json.utilization.gpu }}",
+			discovery.UnitOfMeasurement: "%",
+		})
+	}
+
+	if g.power.valid() {
+		add(prefix+"_power", discovery.Component{
+			discovery.Name:              g.Name + " power",
+			discovery.DeviceClass:       "power",
+			discovery.ValueTemplate:     "{{ value_json.power }}",
+			discovery.UnitOfMeasurement: "W",
+		})
+	}
+
+	if g.temp.valid() {
+		add(prefix+"_temperature", discovery.Component{
+			discovery.Name:              g.Name + " temperature",
+			discovery.DeviceClass:       "temperature",
+			discovery.ValueTemplate:     "{{ value_json.temperature | default(none) }}",
+			discovery.UnitOfMeasurement: "°C",
+		})
+
+		addLimits(d.Components[prefix+"_temperature"], g.Topic(),
+			limit{"max", int64(g.highTemp.value), 1000},
+			limit{"critical", int64(g.maxTemp.value), 1000},
+		)
+	}
+
+	if g.memTotal.valid() {
+		add(prefix+"_memory", discovery.Component{
+			discovery.Name:              g.Name + " memory",
+			discovery.Icon:              icon.Memory,
+			discovery.ValueTemplate:     "{{ 100 * value_json.memory.used / value_json.memory.total }}",
+			discovery.UnitOfMeasurement: "%",
+		})
+	}
+
+	if cmps != nil {
+		d.Nodes[g.Type()] = cmps
+	}
+}
+
+// Discover implements [discovery.Discoverer]. Adds sensors for the gpu usage,
+// with the usage of each engine class as attributes, the gpu frequency and
+// the RC6 residency, if the driver provides them.
+func (g *IntelGPU) Discover(d *discovery.Discovery) {
+	prefix := d.ID("gpu_" + strconv.Itoa(g.index))
+	avail := d.AvailabilityTemplate(g.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[g.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 3)
+		}
+
+		cmps = node
+	}
+
+	add := func(id string, cmp discovery.Component) {
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		cmp[discovery.Platform] = discovery.Sensor
+		cmp[discovery.EntityCategory] = discovery.Diagnostic
+		cmp[discovery.AvailabilityTopic] = d.AvailabilityTopic
+		cmp[discovery.AvailabilityTemplate] = avail
+		cmp[discovery.StateTopic] = g.Topic()
+		cmp[discovery.UniqueID] = id
+
+		d.Components[id] = cmp
+	}
+
+	if g.rc6.valid() {
+		add(prefix, discovery.Component{
+			discovery.Name:                   g.Name + " usage",
+			discovery.Icon:                   icon.GPU,
+			discovery.ValueTemplate:          "{{ value_json.utilization.gpu | default(none) }}",
+			discovery.UnitOfMeasurement:      "%",
+			discovery.JSONAttributesTopic:    g.Topic(),
+			discovery.JSONAttributesTemplate: "{{ value_json.engines | default({}) | tojson }}",
+		})
+
+		add(prefix+"_rc6", discovery.Component{
+			discovery.Name:              g.Name + " RC6 residency",
+			discovery.Icon:              icon.GPU,
+			discovery.ValueTemplate:     "{{ value_json.rc6 | default(none) }}",
+			discovery.UnitOfMeasurement: "%",
+		})
+	}
+
+	if g.clock.valid() {
+		add(prefix+"_clock", discovery.Component{
+			discovery.Name:              g.Name + " frequency",
+			discovery.DeviceClass:       "frequency",
+			discovery.ValueTemplate:     "{{ value_json.clock }}",
+			discovery.UnitOfMeasurement: "MHz",
+		})
+	}
+
+	if cmps != nil {
+		d.Nodes[g.Type()] = cmps
+	}
+}
+
+// Idle Discovery
+
+// Discover implements [discovery.Discoverer]. Adds a sensor for the idle time and
+// a binary sensor for whether the user is active.
+func (i *Idle) Discover(d *discovery.Discovery) {
+	id := d.ID("idle_time")
+	avail := d.AvailabilityTemplate(i.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[i.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 2)
+		}
+
+		cmps = node
+	}
+
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Idle time",
+		discovery.Icon:                 icon.Sleep,
+		discovery.DeviceClass:          "duration",
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           i.Topic(),
+		discovery.ValueTemplate:        "{{ value_json.idle }}",
+		discovery.UnitOfMeasurement:    "s",
+		discovery.UniqueID:             id,
+	}
+
+	id = d.ID("user_active")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.BinarySensor,
+		discovery.Name:                 "User active",
+		discovery.DeviceClass:          "presence",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           i.Topic(),
+		discovery.ValueTemplate:        "{{ iif(value_json.active, 'ON', 'OFF') }}",
+		discovery.UniqueID:             id,
+	}
+
+	if cmps != nil {
+		d.Nodes[i.Type()] = cmps
+	}
+}
+
+// Memory Discovery
+
+// Discover implements [discovery.Discoverer]. Adds sensors for memory usage,
+// total memory, used memory, free memory, cached memory, swap usage,
+// total swap, used swap, and free swap.
+func (m *Memory) Discover(d *discovery.Discovery) {
+	id := d.ID("memory")
+	avail := d.AvailabilityTemplate(m.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[m.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 9)
+		}
+
+		cmps = node
+	}
+
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:                  discovery.Sensor,
+		discovery.Name:                      "Memory usage",
+		discovery.Icon:                      icon.Memory,
+		discovery.EntityCategory:            discovery.Diagnostic,
+		discovery.AvailabilityTopic:         d.AvailabilityTopic,
+		discovery.AvailabilityTemplate:      avail,
+		discovery.StateTopic:                m.Topic(),
+		discovery.ValueTemplate:             "{{ 100 * value_json.used / value_json.total }}",
 		discovery.UnitOfMeasurement:         "%",
 		discovery.SuggestedDisplayPrecision: 1,
 		discovery.JSONAttributesTopic:       m.Topic(),
@@ -454,7 +1259,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 		discovery.UniqueID: id,
 	}
 
-	id = d.Origin.Name + "_memory_total"
+	id = d.ID("memory_total")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -474,7 +1279,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 		discovery.EnabledByDefault:     false,
 	}
 
-	id = d.Origin.Name + "_memory_used"
+	id = d.ID("memory_used")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -494,7 +1299,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 		discovery.EnabledByDefault:     false,
 	}
 
-	id = d.Origin.Name + "_memory_free"
+	id = d.ID("memory_free")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -514,7 +1319,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 		discovery.EnabledByDefault:     false,
 	}
 
-	id = d.Origin.Name + "_memory_cached"
+	id = d.ID("memory_cached")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -535,7 +1340,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 	}
 
 	if m.includeSwap {
-		id = d.Origin.Name + "_memory_swap"
+		id = d.ID("memory_swap")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -559,7 +1364,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 			discovery.UniqueID: id,
 		}
 
-		id = d.Origin.Name + "_memory_swap_total"
+		id = d.ID("memory_swap_total")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -579,7 +1384,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 			discovery.EnabledByDefault:     false,
 		}
 
-		id = d.Origin.Name + "_memory_swap_used"
+		id = d.ID("memory_swap_used")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -599,7 +1404,7 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 			discovery.EnabledByDefault:     false,
 		}
 
-		id = d.Origin.Name + "_memory_swap_free"
+		id = d.ID("memory_swap_free")
 		if cmps != nil {
 			cmps = append(cmps, id)
 		}
@@ -628,8 +1433,8 @@ func (m *Memory) Discover(d *discovery.Discovery) {
 // Network Discovery
 
 func (iface *NetInterface) discover(name string, n *Net, d *discovery.Discovery) {
-	id := d.Origin.Name + "_net_" + name + "_rx"
-	avail := availabilityTemplate(n.Topic())
+	id := d.ID("net_" + name + "_rx")
+	avail := d.AvailabilityTemplate(n.Topic())
 	attrsTemplate := fmt.Sprintf("{{ iif('ip' in value_json[%q], {'ip_address': value_json[%[1]q].ip}, {}) | tojson }}", name)
 
 	var cmps []string
@@ -682,7 +1487,7 @@ func (iface *NetInterface) discover(name string, n *Net, d *discovery.Discovery)
 		discovery.UniqueID:               id,
 	}
 
-	id = d.Origin.Name + "_net_" + name + "_rx_bytes"
+	id = d.ID("net_" + name + "_rx_bytes")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -704,7 +1509,7 @@ func (iface *NetInterface) discover(name string, n *Net, d *discovery.Discovery)
 		discovery.EnabledByDefault:       false,
 	}
 
-	id = d.Origin.Name + "_net_" + name + "_tx_bytes"
+	id = d.ID("net_" + name + "_tx_bytes")
 	if cmps != nil {
 		cmps = append(cmps, id)
 	}
@@ -726,15 +1531,493 @@ func (iface *NetInterface) discover(name string, n *Net, d *discovery.Discovery)
 		discovery.EnabledByDefault:       false,
 	}
 
+	for _, total := range [...]struct{ id, name, field string }{
+		{"rx_total", "rx total", "download_total"},
+		{"tx_total", "tx total", "upload_total"},
+	} {
+		id = d.ID("net_" + name + "_" + total.id)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:               discovery.Sensor,
+			discovery.Name:                   "Network " + name + " " + total.name,
+			discovery.Icon:                   icon.ServerNetwork,
+			discovery.EntityCategory:         discovery.Diagnostic,
+			discovery.DeviceClass:            "data_size",
+			discovery.StateClass:             "total_increasing",
+			discovery.AvailabilityTopic:      d.AvailabilityTopic,
+			discovery.AvailabilityTemplate:   avail,
+			discovery.StateTopic:             n.Topic(),
+			discovery.ValueTemplate:          fmt.Sprintf("{{ value_json[%q].%s | default(none) }}", name, total.field),
+			discovery.UnitOfMeasurement:      byteutil.Bytes,
+			discovery.JSONAttributesTopic:    n.Topic(),
+			discovery.JSONAttributesTemplate: attrsTemplate,
+			discovery.UniqueID:               id,
+		}
+	}
+
+	if iface.usage != nil {
+		for _, usage := range [...]struct{ id, name string }{
+			{"usage_today", "usage today"},
+			{"usage_month", "usage this month"},
+		} {
+			id = d.ID("net_" + name + "_" + usage.id)
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = discovery.Component{
+				discovery.Platform:             discovery.Sensor,
+				discovery.Name:                 "Network " + name + " " + usage.name,
+				discovery.Icon:                 icon.ServerNetwork,
+				discovery.DeviceClass:          "data_size",
+				discovery.StateClass:           "total_increasing",
+				discovery.AvailabilityTopic:    d.AvailabilityTopic,
+				discovery.AvailabilityTemplate: avail,
+				discovery.StateTopic:           n.Topic(),
+				discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q].%s | default(none) }}", name, usage.id),
+				discovery.UnitOfMeasurement:    byteutil.Bytes,
+				discovery.UniqueID:             id,
+			}
+		}
+	}
+
+	if iface.rxAggregate != nil {
+		for _, dir := range [...]struct{ id, name, field string }{
+			{"rx", "rx rate", "download_rate_aggregate"},
+			{"tx", "tx rate", "upload_rate_aggregate"},
+		} {
+			id = d.ID("net_" + name + "_" + dir.id + "_aggregate")
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = aggregateComponent(d, id, "Network "+name+" "+dir.name, n.Topic(), fmt.Sprintf("value_json[%q].%s", name, dir.field), iface.rate)
+			d.Components[id][discovery.DeviceClass] = "data_rate"
+		}
+	}
+
+	if iface.wg != nil {
+		for _, peer := range iface.wg.names {
+			if peer == "" {
+				continue
+			}
+
+			field := fmt.Sprintf("value_json[%q].peers[%q]", name, peer)
+
+			id = d.ID("net_" + name + "_" + peer + "_connected")
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = discovery.Component{
+				discovery.Platform:               discovery.BinarySensor,
+				discovery.Name:                   "Network " + name + " " + peer + " connected",
+				discovery.DeviceClass:            "connectivity",
+				discovery.AvailabilityTopic:      d.AvailabilityTopic,
+				discovery.AvailabilityTemplate:   avail,
+				discovery.StateTopic:             n.Topic(),
+				discovery.ValueTemplate:          fmt.Sprintf("{{ iif(%s is defined and %[1]s.connected, 'ON', 'OFF') }}", field),
+				discovery.JSONAttributesTopic:    n.Topic(),
+				discovery.JSONAttributesTemplate: fmt.Sprintf("{{ %s | default({}) | tojson }}", field),
+				discovery.UniqueID:               id,
+			}
+
+			id = d.ID("net_" + name + "_" + peer + "_last_handshake")
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = discovery.Component{
+				discovery.Platform:             discovery.Sensor,
+				discovery.Name:                 "Network " + name + " " + peer + " last handshake",
+				discovery.Icon:                 icon.Handshake,
+				discovery.EntityCategory:       discovery.Diagnostic,
+				discovery.DeviceClass:          "duration",
+				discovery.AvailabilityTopic:    d.AvailabilityTopic,
+				discovery.AvailabilityTemplate: avail,
+				discovery.StateTopic:           n.Topic(),
+				discovery.ValueTemplate:        fmt.Sprintf("{{ (%s | default({})).last_handshake | default(none) }}", field),
+				discovery.UnitOfMeasurement:    "s",
+				discovery.UniqueID:             id,
+			}
+		}
+	}
+
 	if cmps != nil {
 		d.Nodes[n.Type()] = cmps
 	}
 }
 
 // Discover implements [discovery.Discoverer]. Adds sensors for interface rx rate,
-// tx rate, rx bytes, and tx bytes.
+// tx rate, rx bytes, tx bytes, rx total, and tx total, the data usage today and
+// this month if accounting is enabled, the rolling averages of the rates if
+// aggregates are enabled, and whether each named peer of a WireGuard interface is
+// connected and the age of its last handshake.
 func (n *Net) Discover(d *discovery.Discovery) {
 	for name, iface := range n.interfaces {
 		iface.discover(name, n, d)
 	}
 }
+
+// Processes Discovery
+
+// Discover implements [discovery.Discoverer]. Adds sensors for the number of
+// processes, and the name, CPU usage and memory of the process using the most,
+// with every reported process as attributes.
+func (p *Processes) Discover(d *discovery.Discovery) {
+	id := d.ID("processes_count")
+	avail := d.AvailabilityTemplate(p.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[p.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 4)
+		}
+
+		cmps = node
+	}
+
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Processes",
+		discovery.Icon:                 icon.Application,
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           p.Topic(),
+		discovery.ValueTemplate:        "{{ value_json.count }}",
+		discovery.UniqueID:             id,
+	}
+
+	id = d.ID("processes_top")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:               discovery.Sensor,
+		discovery.Name:                   "Top process",
+		discovery.Icon:                   icon.Application,
+		discovery.AvailabilityTopic:      d.AvailabilityTopic,
+		discovery.AvailabilityTemplate:   avail,
+		discovery.StateTopic:             p.Topic(),
+		discovery.ValueTemplate:          "{{ (value_json.top | first | default({})).name | default(none) }}",
+		discovery.JSONAttributesTopic:    p.Topic(),
+		discovery.JSONAttributesTemplate: "{{ {'sort_by': value_json.sort_by, 'processes': value_json.top} | tojson }}",
+		discovery.UniqueID:               id,
+	}
+
+	id = d.ID("processes_top_cpu")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Top process CPU usage",
+		discovery.Icon:                 icon.CPU,
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           p.Topic(),
+		discovery.ValueTemplate:        "{{ (value_json.top | first | default({})).cpu | default(none) }}",
+		discovery.UnitOfMeasurement:    "%",
+		discovery.UniqueID:             id,
+	}
+
+	id = d.ID("processes_top_memory")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Top process memory",
+		discovery.Icon:                 icon.Memory,
+		discovery.DeviceClass:          "data_size",
+		discovery.StateClass:           "measurement",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           p.Topic(),
+		discovery.ValueTemplate:        "{{ (value_json.top | first | default({})).memory | default(none) }}",
+		discovery.UnitOfMeasurement:    p.size,
+		discovery.UniqueID:             id,
+	}
+
+	if cmps != nil {
+		d.Nodes[p.Type()] = cmps
+	}
+}
+
+// System Discovery
+
+// Discover implements [discovery.Discoverer]. Adds sensors for the uptime, the
+// boot time, and the 1, 5 and 15 minute load averages.
+func (s *System) Discover(d *discovery.Discovery) {
+	id := d.ID("uptime")
+	avail := d.AvailabilityTemplate(s.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[s.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 5)
+		}
+
+		cmps = node
+	}
+
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Uptime",
+		discovery.Icon:                 icon.Timer,
+		discovery.DeviceClass:          "duration",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           s.Topic(),
+		discovery.ValueTemplate:        "{{ value_json.uptime }}",
+		discovery.UnitOfMeasurement:    "s",
+		discovery.UniqueID:             id,
+	}
+
+	id = d.ID("boot_time")
+	if cmps != nil {
+		cmps = append(cmps, id)
+	}
+
+	d.Components[id] = discovery.Component{
+		discovery.Platform:             discovery.Sensor,
+		discovery.Name:                 "Boot time",
+		discovery.Icon:                 icon.Restart,
+		discovery.DeviceClass:          "timestamp",
+		discovery.AvailabilityTopic:    d.AvailabilityTopic,
+		discovery.AvailabilityTemplate: avail,
+		discovery.StateTopic:           s.Topic(),
+		discovery.ValueTemplate:        "{{ as_datetime(value_json.boot_time) }}",
+		discovery.UniqueID:             id,
+	}
+
+	for _, load := range [...]struct{ field, name string }{
+		{"load1", "Load average (1m)"},
+		{"load5", "Load average (5m)"},
+		{"load15", "Load average (15m)"},
+	} {
+		id = d.ID(load.field)
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 load.name,
+			discovery.Icon:                 icon.Gauge,
+			discovery.StateClass:           "measurement",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           s.Topic(),
+			discovery.ValueTemplate:        "{{ value_json." + load.field + " }}",
+			discovery.UniqueID:             id,
+		}
+	}
+
+	if cmps != nil {
+		d.Nodes[s.Type()] = cmps
+	}
+}
+
+// SMART Discovery
+
+func (disk *smartDisk) discover(s *Smart, d *discovery.Discovery) {
+	prefix := d.ID("smart_" + disk.Name)
+	avail := d.AvailabilityTemplate(s.Topic())
+
+	name := disk.Name
+	if disk.Model != "" {
+		name = disk.Model + " (" + disk.Name + ")"
+	}
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[s.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 4)
+		}
+
+		cmps = node
+	}
+
+	add := func(id string, cmp discovery.Component) {
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		if _, ok := cmp[discovery.Platform]; !ok {
+			cmp[discovery.Platform] = discovery.Sensor
+		}
+
+		cmp[discovery.EntityCategory] = discovery.Diagnostic
+		cmp[discovery.AvailabilityTopic] = d.AvailabilityTopic
+		cmp[discovery.AvailabilityTemplate] = avail
+		cmp[discovery.StateTopic] = s.Topic()
+		cmp[discovery.UniqueID] = id
+
+		d.Components[id] = cmp
+	}
+
+	add(prefix+"_temperature", discovery.Component{
+		discovery.Name:              name + " temperature",
+		discovery.DeviceClass:       "temperature",
+		discovery.StateClass:        "measurement",
+		discovery.ValueTemplate:     fmt.Sprintf("{{ value_json[%q].temperature | default(none) }}", disk.Name),
+		discovery.UnitOfMeasurement: "°C",
+	})
+
+	if !disk.smartctl {
+		if cmps != nil {
+			d.Nodes[s.Type()] = cmps
+		}
+
+		return
+	}
+
+	// The failure prediction is unknown until the health of a disk in standby
+	// has been read.
+	add(prefix+"_problem", discovery.Component{
+		discovery.Platform:               discovery.BinarySensor,
+		discovery.Name:                   name + " health",
+		discovery.Icon:                   icon.HDD,
+		discovery.DeviceClass:            "problem",
+		discovery.ValueTemplate:          fmt.Sprintf("{{ iif(value_json[%q].passed | default(none), 'OFF', 'ON', none) }}", disk.Name),
+		discovery.JSONAttributesTopic:    s.Topic(),
+		discovery.JSONAttributesTemplate: fmt.Sprintf("{{ value_json[%q] | tojson }}", disk.Name),
+	})
+
+	add(prefix+"_power_on_hours", discovery.Component{
+		discovery.Name:              name + " power on hours",
+		discovery.Icon:              icon.Timer,
+		discovery.DeviceClass:       "duration",
+		discovery.StateClass:        "total_increasing",
+		discovery.ValueTemplate:     fmt.Sprintf("{{ value_json[%q].power_on_hours | default(none) }}", disk.Name),
+		discovery.UnitOfMeasurement: "h",
+	})
+
+	// The counters depend on the protocol of the disk, and NVMe disks are the
+	// only ones named nvme*.
+	counters := [...]struct{ field, name string }{
+		{"reallocated_sectors", "reallocated sectors"},
+		{"pending_sectors", "pending sectors"},
+	}
+	if strings.HasPrefix(disk.Name, "nvme") {
+		counters = [...]struct{ field, name string }{
+			{"media_errors", "media errors"},
+			{"wear", "wear"},
+		}
+	}
+
+	for _, c := range counters {
+		cmp := discovery.Component{
+			discovery.Name:          name + " " + c.name,
+			discovery.Icon:          icon.HDD,
+			discovery.StateClass:    "measurement",
+			discovery.ValueTemplate: fmt.Sprintf("{{ value_json[%q].%s | default(none) }}", disk.Name, c.field),
+		}
+
+		if c.field == "wear" {
+			cmp[discovery.UnitOfMeasurement] = "%"
+		}
+
+		add(prefix+"_"+c.field, cmp)
+	}
+
+	if cmps != nil {
+		d.Nodes[s.Type()] = cmps
+	}
+}
+
+// Discover implements [discovery.Discoverer]. Adds a sensor for the temperature
+// of each disk and, if read with smartctl, a binary sensor for the failure
+// prediction along with sensors for the power on hours and the counters of wear
+// and failing sectors.
+func (s *Smart) Discover(d *discovery.Discovery) {
+	for i := range s.disks {
+		s.disks[i].discover(s, d)
+	}
+}
+
+// Discover implements [discovery.Discoverer]. Adds a sensor for the power and
+// a sensor for the energy of each RAPL zone, which may be added to the Home
+// Assistant Energy dashboard.
+func (e *Energy) Discover(d *discovery.Discovery) {
+	avail := d.AvailabilityTemplate(e.Topic())
+
+	var cmps []string
+
+	if d.Nodes != nil {
+		node, ok := d.Nodes[e.Type()]
+		if !ok || node == nil {
+			node = make([]string, 0, 2*len(e.zones))
+		}
+
+		cmps = node
+	}
+
+	for i := range e.zones {
+		z := &e.zones[i]
+		prefix := d.ID("energy_" + z.Name)
+
+		id := prefix + "_power"
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 "RAPL " + z.Name + " power",
+			discovery.DeviceClass:          "power",
+			discovery.StateClass:           "measurement",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           e.Topic(),
+			discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q].power }}", z.Name),
+			discovery.UnitOfMeasurement:    "W",
+			discovery.UniqueID:             id,
+		}
+
+		id = prefix + "_energy"
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Sensor,
+			discovery.Name:                 "RAPL " + z.Name + " energy",
+			discovery.DeviceClass:          "energy",
+			discovery.StateClass:           "total_increasing",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           e.Topic(),
+			discovery.ValueTemplate:        fmt.Sprintf("{{ value_json[%q].energy }}", z.Name),
+			discovery.UnitOfMeasurement:    "kWh",
+			discovery.UniqueID:             id,
+		}
+	}
+
+	if cmps != nil {
+		d.Nodes[e.Type()] = cmps
+	}
+}
@@ -13,11 +13,15 @@ import (
 
 // Discover implements [discovery.Discoverer]. Adds sensors for gpu usage,
 // gpu power, gpu temperature, gpu memory usage, total gpu memory, free
-// gpu memory, and used gpu memory.
+// gpu memory, and used gpu memory. If aggregates are enabled, also adds sensors
+// for the rolling averages of the gpu usage and temperature, and if the summary
+// is enabled, sensors for the summary across the GPUs. A number for the power
+// limit and a switch for the persistence mode are added if their controls are
+// enabled.
 func (g *NvidiaGPU) Discover(d *discovery.Discovery) {
-	prefix := d.Origin.Name + "_gpu_" + strconv.Itoa(g.index)
+	prefix := d.ID("gpu_" + strconv.Itoa(g.index))
 	id := prefix
-	avail := availabilityTemplate(g.Topic())
+	avail := d.AvailabilityTemplate(g.Topic())
 
 	var cmps []string
 
@@ -47,6 +51,16 @@ func (g *NvidiaGPU) Discover(d *discovery.Discovery) {
 			discovery.UnitOfMeasurement:    "%",
 			discovery.UniqueID:             id,
 		}
+
+		if g.utilAggregate != nil {
+			id = prefix + "_aggregate"
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = aggregateComponent(d, id, g.Name+" usage", g.Topic(), "value_json.utilizationAggregate", "%")
+			d.Components[id][discovery.Icon] = icon.GPU
+		}
 	}
 
 	if g.flags.Has(gpuPower) {
@@ -71,6 +85,57 @@ func (g *NvidiaGPU) Discover(d *discovery.Discovery) {
 		}
 	}
 
+	if g.powerControl {
+		id = prefix + "_power_limit"
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.Number,
+			discovery.Name:                 g.Name + " power limit",
+			discovery.EntityCategory:       discovery.Config,
+			discovery.DeviceClass:          "power",
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           g.Topic(),
+			discovery.ValueTemplate:        "{{ value_json.maxPower | default(none) }}",
+			discovery.CommandTopic:         g.Topic() + "/power_limit/set",
+			discovery.UnitOfMeasurement:    "W",
+			discovery.Min:                  g.minPowerLimit / 1000,
+			discovery.Max:                  g.maxPowerLimit / 1000,
+			discovery.Step:                 1,
+			discovery.Mode:                 "box",
+			discovery.UniqueID:             id,
+		}
+	}
+
+	if g.flags.Has(gpuPersistence) {
+		id = prefix + "_persistence"
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		d.Components[id] = discovery.Component{
+			discovery.Platform:             discovery.BinarySensor,
+			discovery.Name:                 g.Name + " persistence mode",
+			discovery.Icon:                 icon.GPU,
+			discovery.EntityCategory:       discovery.Diagnostic,
+			discovery.AvailabilityTopic:    d.AvailabilityTopic,
+			discovery.AvailabilityTemplate: avail,
+			discovery.StateTopic:           g.Topic(),
+			discovery.ValueTemplate:        "{{ iif(value_json.persistence, 'ON', 'OFF') }}",
+			discovery.UniqueID:             id,
+			discovery.EnabledByDefault:     g.persistenceControl,
+		}
+
+		if g.persistenceControl {
+			d.Components[id][discovery.Platform] = discovery.Switch
+			d.Components[id][discovery.EntityCategory] = discovery.Config
+			d.Components[id][discovery.CommandTopic] = g.Topic() + "/persistence/set"
+		}
+	}
+
 	if g.flags.Has(gpuTemperature) {
 		id = prefix + "_temperature"
 		if cmps != nil {
@@ -85,12 +150,22 @@ func (g *NvidiaGPU) Discover(d *discovery.Discovery) {
 			discovery.AvailabilityTopic:      d.AvailabilityTopic,
 			discovery.AvailabilityTemplate:   avail,
 			discovery.StateTopic:             g.Topic(),
-			discovery.ValueTemplate:          "{{ value_json.temperature }}",
+			discovery.ValueTemplate:          "{{ value_json.temperature | default(none) }}",
 			discovery.UnitOfMeasurement:      "°C",
 			discovery.JSONAttributesTopic:    g.Topic(),
 			discovery.JSONAttributesTemplate: "{{ {'max': value_json.maxTemp} | tojson }}",
 			discovery.UniqueID:               id,
 		}
+
+		if g.tempAggregate != nil {
+			id = prefix + "_temperature_aggregate"
+			if cmps != nil {
+				cmps = append(cmps, id)
+			}
+
+			d.Components[id] = aggregateComponent(d, id, g.Name+" temperature", g.Topic(), "value_json.temperatureAggregate", "°C")
+			d.Components[id][discovery.DeviceClass] = "temperature"
+		}
 	}
 
 	if g.flags.Has(gpuMemory | gpuMemoryV2 | gpuUtilization) {
@@ -223,7 +298,63 @@ func (g *NvidiaGPU) Discover(d *discovery.Discovery) {
 		}
 	}
 
+	if g.summary {
+		cmps = g.discoverSummary(d, avail, cmps)
+	}
+
 	if cmps != nil {
 		d.Nodes[g.Type()] = cmps
 	}
 }
+
+// discoverSummary adds sensors for the total gpu memory used, the maximum gpu
+// temperature and the total gpu power across the GPUs, appending their ids to
+// cmps if not nil.
+func (g *NvidiaGPU) discoverSummary(d *discovery.Discovery, avail string, cmps []string) []string {
+	prefix := d.ID("gpu_summary")
+
+	add := func(id string, cmp discovery.Component) {
+		if cmps != nil {
+			cmps = append(cmps, id)
+		}
+
+		cmp[discovery.Platform] = discovery.Sensor
+		cmp[discovery.EntityCategory] = discovery.Diagnostic
+		cmp[discovery.AvailabilityTopic] = d.AvailabilityTopic
+		cmp[discovery.AvailabilityTemplate] = avail
+		cmp[discovery.StateTopic] = g.Topic()
+		cmp[discovery.UniqueID] = id
+
+		d.Components[id] = cmp
+	}
+
+	if g.flags.Has(gpuMemory | gpuMemoryV2) {
+		add(prefix+"_memory_used", discovery.Component{
+			discovery.Name:              "GPU total memory used",
+			discovery.Icon:              icon.Memory,
+			discovery.DeviceClass:       "data_size",
+			discovery.ValueTemplate:     "{{ value_json.summary.memoryUsed | default(none) }}",
+			discovery.UnitOfMeasurement: g.memSize,
+		})
+	}
+
+	if g.flags.Has(gpuTemperature) {
+		add(prefix+"_temperature", discovery.Component{
+			discovery.Name:              "GPU max temperature",
+			discovery.DeviceClass:       "temperature",
+			discovery.ValueTemplate:     "{{ value_json.summary.temperature | default(none) }}",
+			discovery.UnitOfMeasurement: "°C",
+		})
+	}
+
+	if g.flags.Has(gpuPower) {
+		add(prefix+"_power", discovery.Component{
+			discovery.Name:              "GPU total power",
+			discovery.DeviceClass:       "power",
+			discovery.ValueTemplate:     "{{ value_json.summary.power | default(none) }}",
+			discovery.UnitOfMeasurement: "W",
+		})
+	}
+
+	return cmps
+}
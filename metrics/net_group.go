@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// namePattern matches the names of interfaces, either by a glob pattern or by
+// a regular expression enclosed in slashes. A name without any special
+// characters is a glob pattern matching only itself.
+type namePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// compileNamePattern returns the pattern of s, or an error if it's malformed.
+func compileNamePattern(s string) (namePattern, error) {
+	if len(s) > 1 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return namePattern{}, err
+		}
+
+		return namePattern{re: re}, nil
+	}
+
+	if _, err := path.Match(s, ""); err != nil {
+		return namePattern{}, fmt.Errorf("%q: %w", s, err)
+	}
+
+	return namePattern{glob: s}, nil
+}
+
+// compileNamePatterns returns the patterns of each of s.
+func compileNamePatterns(s []string) ([]namePattern, error) {
+	patterns := make([]namePattern, len(s))
+
+	for i := range s {
+		p, err := compileNamePattern(s[i])
+		if err != nil {
+			return nil, err
+		}
+
+		patterns[i] = p
+	}
+
+	return patterns, nil
+}
+
+func (p namePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+
+	ok, _ := path.Match(p.glob, name)
+
+	return ok
+}
+
+// matchAny reports whether name matches any of patterns.
+func matchAny(patterns []namePattern, name string) bool {
+	for _, p := range patterns {
+		if p.match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// netMatcher is the compiled patterns of the include, exclude and groups of a
+// [config.NetConfig].
+type netMatcher struct {
+	cfg     *config.NetConfig
+	include []namePattern
+	exclude []namePattern
+	groups  [][]namePattern
+}
+
+func newNetMatcher(cfg *config.NetConfig) (*netMatcher, error) {
+	m := &netMatcher{
+		cfg:     cfg,
+		include: make([]namePattern, len(cfg.Include)),
+		groups:  make([][]namePattern, len(cfg.Groups)),
+	}
+
+	var err error
+
+	for i := range cfg.Include {
+		if m.include[i], err = compileNamePattern(cfg.Include[i].Interface); err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+	}
+
+	if m.exclude, err = compileNamePatterns(cfg.Exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	for i := range cfg.Groups {
+		if cfg.Groups[i].Name == "" {
+			return nil, fmt.Errorf("group %d has no name", i)
+		}
+
+		if m.groups[i], err = compileNamePatterns(cfg.Groups[i].Interfaces); err != nil {
+			return nil, fmt.Errorf("group %s: %w", cfg.Groups[i].Name, err)
+		}
+	}
+
+	return m, nil
+}
+
+// excluded reports whether the interface of name is excluded.
+func (m *netMatcher) excluded(name string) bool {
+	return matchAny(m.exclude, name)
+}
+
+// includeFor returns the first include config matching the interface of name,
+// or nil if there are none.
+func (m *netMatcher) includeFor(name string) *config.NetIfaceConfig {
+	for i, p := range m.include {
+		if p.match(name) {
+			return &m.cfg.Include[i]
+		}
+	}
+
+	return nil
+}
+
+// groupFor returns the first group config matching the interface of name, or
+// nil if there are none.
+func (m *netMatcher) groupFor(name string) *config.NetGroupConfig {
+	for i, patterns := range m.groups {
+		if matchAny(patterns, name) {
+			return &m.cfg.Groups[i]
+		}
+	}
+
+	return nil
+}
+
+// netGroup is the member interfaces of a group, see [config.NetGroupConfig],
+// which are reported as a single interface.
+type netGroup struct {
+	members map[string]*NetInterface
+
+	// rx and tx are the sums of what the members received and transmitted,
+	// which only ever increase so that members may come and go without
+	// resetting the counters of the group.
+	rx uint64
+	tx uint64
+}
+
+// updateGroup updates the group iface from its members, which must have been
+// updated already. The group is running if any of its members are.
+func (iface *NetInterface) updateGroup() {
+	g := iface.group
+
+	var flags uint16
+
+	for _, m := range g.members {
+		g.rx += m.rx
+		g.tx += m.tx
+		flags |= m.flags & unix.IFF_RUNNING
+
+		// What the member received and transmitted is only counted once,
+		// even if it fails to update next time.
+		m.rx, m.tx = 0, 0
+	}
+
+	iface.flags = flags
+	iface.update(g.rx, g.tx)
+}
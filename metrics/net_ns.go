@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"net/netip"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// netNamespace is a network namespace other than the one of the bridge, whose
+// interfaces are read by entering it, see [config.NetNamespaceConfig].
+type netNamespace struct {
+	prefix string
+	path   string // joined with the root
+
+	// The interfaces of the namespace are keyed by their name within it.
+	interfaces map[string]*NetInterface
+}
+
+// netNSInterface is an interface read from within a [netNamespace]. The peers
+// of a WireGuard interface are read into the interface of the namespace.
+type netNSInterface struct {
+	procfs.NetDev
+	ip    netip.Addr
+	flags uint16
+}
+
+// newNetNamespace returns the namespace of cfg in root, or false if cfg
+// doesn't name a namespace.
+func newNetNamespace(cfg *config.NetNamespaceConfig, root *vfs.Root) (*netNamespace, bool) {
+	ns := &netNamespace{
+		prefix:     cfg.Prefix,
+		interfaces: make(map[string]*NetInterface),
+	}
+
+	switch {
+	case cfg.Name != "":
+		ns.path = root.Abs("/run/netns/" + cfg.Name)
+
+		if ns.prefix == "" {
+			ns.prefix = cfg.Name
+		}
+	case cfg.PID > 0:
+		ns.path = root.Abs(procfs.Path(strconv.Itoa(cfg.PID), "ns", "net"))
+
+		if ns.prefix == "" {
+			ns.prefix = "pid" + strconv.Itoa(cfg.PID)
+		}
+	default:
+		return nil, false
+	}
+
+	return ns, true
+}
+
+// name returns the name of the interface dev of ns in the payload.
+func (ns *netNamespace) name(dev string) string {
+	return ns.prefix + "_" + dev
+}
+
+// read returns the interfaces of ns other than loopback. The namespace is
+// entered by a locked thread that is never unlocked, so that it's discarded
+// instead of reused in case it can't return to the namespace of the bridge.
+func (ns *netNamespace) read() ([]netNSInterface, error) {
+	type result struct {
+		ifaces []netNSInterface
+		err    error
+	}
+
+	ch := make(chan result, 1)
+
+	go func() {
+		runtime.LockOSThread()
+
+		ifaces, err := ns.readLocked()
+		ch <- result{ifaces, err}
+	}()
+
+	res := <-ch
+
+	return res.ifaces, res.err
+}
+
+func (ns *netNamespace) readLocked() ([]netNSInterface, error) {
+	fd, err := unix.Open(ns.path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: ns.path, Err: err}
+	}
+
+	defer unix.Close(fd)
+
+	if err := unix.Setns(fd, unix.CLONE_NEWNET); err != nil {
+		return nil, os.NewSyscallError("setns", err)
+	}
+
+	// /proc/self/net is of the main thread, rather than the one that entered
+	// the namespace.
+	data, err := os.ReadFile("/proc/thread-self/net/dev")
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := procfs.ParseNetDev(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sock, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unix.Close(sock)
+
+	ifaces := make([]netNSInterface, 0, len(devs))
+
+	for _, dev := range devs {
+		if dev.Name == "lo" {
+			continue
+		}
+
+		iface := netNSInterface{NetDev: dev}
+
+		if ifreq, err := unix.NewIfreq(dev.Name); err == nil {
+			iface.flags, _ = getFlagsIfreq(sock, ifreq)
+			iface.ip, _ = getAddr4Ifreq(sock, ifreq)
+		}
+
+		// The WireGuard socket must also be opened within the namespace
+		if ni := ns.interfaces[dev.Name]; ni != nil && ni.wg != nil {
+			ni.wg.update(dev.Name)
+		}
+
+		ifaces = append(ifaces, iface)
+	}
+
+	return ifaces, nil
+}
+
+// parseNamespaces adds the interfaces of each namespace of n that aren't
+// already added, and removes those that no longer exist, and reports whether
+// any changed. A namespace that can't be read is skipped until the next rescan.
+func (n *Net) parseNamespaces() (changed bool) {
+	for _, ns := range n.namespaces {
+		ifaces, err := ns.read()
+		if err != nil {
+			log.WarnError("Unable to read network namespace", err, "path", ns.path)
+			continue
+		}
+
+		devs := make(map[string]bool, len(ifaces))
+
+		for i := range ifaces {
+			dev := ifaces[i].Name
+			name := ns.name(dev)
+			devs[dev] = true
+
+			if _, ok := ns.interfaces[dev]; ok || slices.Contains(n.cfg.Exclude, name) {
+				continue
+			}
+
+			log.Debug("Adding interface", "name", name, "namespace", ns.path)
+
+			iface := n.newInterface(name, ifaces[i].ip, n.cfg.RateUnit)
+			iface.ns = ns
+			iface.flags = ifaces[i].flags
+
+			ns.interfaces[dev] = iface
+			n.interfaces[name] = iface
+			changed = true
+		}
+
+		for dev := range ns.interfaces {
+			if devs[dev] {
+				continue
+			}
+
+			log.Debug("Deleting interface", "name", ns.name(dev), "namespace", ns.path)
+			delete(n.interfaces, ns.name(dev))
+			delete(ns.interfaces, dev)
+
+			changed = true
+		}
+	}
+
+	return
+}
+
+// update updates every interface of ns from a single read of the namespace.
+func (ns *netNamespace) update() error {
+	ifaces, err := ns.read()
+	if err != nil {
+		return err
+	}
+
+	for i := range ifaces {
+		iface, ok := ns.interfaces[ifaces[i].Name]
+		if !ok {
+			continue
+		}
+
+		iface.ip = ifaces[i].ip
+		iface.flags = ifaces[i].flags
+		iface.update(ifaces[i].RxBytes, ifaces[i].TxBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import "time"
+
+// Usage is the data usage of a network interface over a period, such as a
+// calendar day or a billing month.
+type Usage struct {
+	// Start is the start of the period.
+	Start time.Time `json:"start"`
+	// Bytes is the number of bytes received and transmitted during the period.
+	Bytes uint64 `json:"bytes"`
+}
+
+// add adds n bytes used at t, which starts a new period if start, the start of
+// the period of t, is not the start of u.
+func (u *Usage) add(start time.Time, n uint64) {
+	if !u.Start.Equal(start) {
+		*u = Usage{Start: start}
+	}
+
+	u.Bytes += n
+}
+
+// netUsage is the data usage of a network interface per calendar day and
+// billing month.
+type netUsage struct {
+	resetDay int
+	day      Usage
+	month    Usage
+}
+
+func newNetUsage(resetDay int) *netUsage {
+	return &netUsage{resetDay: min(max(resetDay, 1), 28)}
+}
+
+// dayStart returns the start of the calendar day of t.
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// monthStart returns the start of the billing month of t, which starts on
+// resetDay.
+func monthStart(t time.Time, resetDay int) time.Time {
+	y, m, d := t.Date()
+	if d < resetDay {
+		m--
+	}
+
+	return time.Date(y, m, resetDay, 0, 0, 0, 0, t.Location())
+}
+
+// add adds n bytes used at t.
+func (u *netUsage) add(t time.Time, n uint64) {
+	u.day.add(dayStart(t), n)
+	u.month.add(monthStart(t, u.resetDay), n)
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonthStart(t *testing.T) {
+	for _, tt := range []struct {
+		t        string
+		resetDay int
+		want     string
+	}{
+		{"2026-03-15", 1, "2026-03-01"},
+		{"2026-03-15", 15, "2026-03-15"},
+		{"2026-03-14", 15, "2026-02-15"},
+		{"2026-01-10", 28, "2025-12-28"},
+	} {
+		tm, _ := time.Parse(time.DateOnly, tt.t)
+		tm = tm.Add(13 * time.Hour)
+
+		if got := monthStart(tm, tt.resetDay).Format(time.DateOnly); got != tt.want {
+			t.Errorf("monthStart(%s, %d): want %s, got %s", tt.t, tt.resetDay, tt.want, got)
+		}
+	}
+}
+
+func TestNetUsage(t *testing.T) {
+	u := newNetUsage(0)
+	if want, got := 1, u.resetDay; got != want {
+		t.Errorf("resetDay: want %d, got %d", want, got)
+	}
+
+	start := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+
+	u.add(start, 100)
+	u.add(start.Add(30*time.Minute), 50)
+
+	if u.day.Bytes != 150 || u.month.Bytes != 150 {
+		t.Errorf("Usage: want 150, 150, got %d, %d", u.day.Bytes, u.month.Bytes)
+	}
+
+	// A new day and month
+	u.add(start.Add(2*time.Hour), 10)
+
+	if u.day.Bytes != 10 || u.month.Bytes != 10 {
+		t.Errorf("Usage: want 10, 10, got %d, %d", u.day.Bytes, u.month.Bytes)
+	}
+
+	// A new day in the same month
+	u.add(start.Add(26*time.Hour), 5)
+
+	if u.day.Bytes != 5 || u.month.Bytes != 15 {
+		t.Errorf("Usage: want 5, 15, got %d, %d", u.day.Bytes, u.month.Bytes)
+	}
+}
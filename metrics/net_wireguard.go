@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/internal/wireguard"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+// defaultWireGuardTimeout is the timeout of a peer if not configured, since a
+// handshake happens at least every 2 minutes while a peer is sending data.
+const defaultWireGuardTimeout = 3 * time.Minute
+
+// netWireGuard is the peer status of a WireGuard interface, see
+// [config.NetWireGuardConfig].
+type netWireGuard struct {
+	timeout time.Duration
+	names   map[wireguard.Key]string
+
+	peers   []wireguard.Peer
+	updated time.Time
+	err     error
+}
+
+// newNetWireGuard returns the peer status of the given config. Any peer with
+// a malformed public key is skipped.
+func newNetWireGuard(cfg *config.NetWireGuardConfig) *netWireGuard {
+	wg := &netWireGuard{
+		timeout: cfg.Timeout,
+		names:   make(map[wireguard.Key]string, len(cfg.Peers)),
+	}
+
+	if wg.timeout <= 0 {
+		wg.timeout = defaultWireGuardTimeout
+	}
+
+	for _, peer := range cfg.Peers {
+		key, err := base64.StdEncoding.DecodeString(peer.PublicKey)
+		if err != nil || len(key) != len(wireguard.Key{}) {
+			log.Warn("Malformed WireGuard public key, skipping", "interface", cfg.Interface, "key", peer.PublicKey)
+			continue
+		}
+
+		wg.names[wireguard.Key(key)] = peer.Name
+	}
+
+	return wg
+}
+
+// wireGuardConfig returns the WireGuard config of the interface with the
+// given name, or nil if it isn't configured.
+func (n *Net) wireGuardConfig(name string) *config.NetWireGuardConfig {
+	for i := range n.cfg.WireGuard {
+		if n.cfg.WireGuard[i].Interface == name {
+			return &n.cfg.WireGuard[i]
+		}
+	}
+
+	return nil
+}
+
+// name returns the name of the peer with the given key, which is the start of
+// the key if the peer isn't named.
+func (wg *netWireGuard) name(key wireguard.Key) string {
+	if name := wg.names[key]; name != "" {
+		return name
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key[:6])
+}
+
+// update reads the peers of the WireGuard interface dev, which is of the
+// namespace of the calling thread. If the interface can't be read, there are
+// no peers until the next update, and a warning is only logged the first time.
+func (wg *netWireGuard) update(dev string) {
+	err := wg.read(dev)
+	if err != nil {
+		if wg.err == nil {
+			log.WarnError("Unable to read WireGuard interface", err, "name", dev)
+		}
+
+		wg.peers = nil
+	}
+
+	wg.err = err
+}
+
+func (wg *netWireGuard) read(dev string) error {
+	c, err := wireguard.Open()
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	d, err := c.Device(dev)
+	if err != nil {
+		return err
+	}
+
+	wg.peers = d.Peers
+	wg.updated = time.Now()
+
+	return nil
+}
+
+// toPayload returns the payload of each peer, relative to the time they were
+// read.
+func (wg *netWireGuard) toPayload() map[string]payload.WireGuardPeer {
+	if len(wg.peers) == 0 {
+		return nil
+	}
+
+	p := make(map[string]payload.WireGuardPeer, len(wg.peers))
+
+	for i := range wg.peers {
+		peer := &wg.peers[i]
+
+		pp := payload.WireGuardPeer{
+			Endpoint: peer.Endpoint,
+			Received: peer.RxBytes,
+			Sent:     peer.TxBytes,
+		}
+
+		if !peer.LastHandshake.IsZero() {
+			age := max(wg.updated.Sub(peer.LastHandshake), 0)
+
+			pp.LastHandshake = payload.Some(int64(age / time.Second))
+			pp.Connected = age < wg.timeout
+		}
+
+		p[wg.name(peer.PublicKey)] = pp
+	}
+
+	return p
+}
@@ -1,20 +1,22 @@
 package metrics
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/netip"
 	"os"
 	"slices"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
 	"github.com/lone-faerie/mqttop/sysfs"
-	"golang.org/x/sync/errgroup"
 	"golang.org/x/sys/unix"
 )
 
@@ -26,12 +28,36 @@ type NetInterface struct {
 	tx     uint64
 	rxRate uint64
 	txRate uint64
-	rxLast uint64
-	txLast uint64
 	rate   byteutil.ByteRate
 
+	rxCounter Counter
+	txCounter Counter
+
+	// The data usage is nil unless accounting is enabled
+	usage *netUsage
+
+	// The rolling aggregates are nil unless enabled
+	rxAggregate *rolling
+	txAggregate *rolling
+
 	lastUpdate time.Time
 	sockfd     int
+	sys        sysfs.FS
+
+	// dev is the statistics of the interface read from /proc/net/dev for the
+	// next update, or nil if they're read from sysfs instead.
+	dev *procfs.NetDev
+
+	// ns is the namespace of the interface, or nil if it's in the namespace
+	// of the bridge.
+	ns *netNamespace
+
+	// wg is the peer status of a WireGuard interface, which is nil unless
+	// configured.
+	wg *netWireGuard
+
+	// group is the members of the interface if it's a group, or nil.
+	group *netGroup
 }
 
 func (iface *NetInterface) Running() bool {
@@ -40,8 +66,13 @@ func (iface *NetInterface) Running() bool {
 
 type Net struct {
 	interfaces map[string]*NetInterface
+	namespaces []*netNamespace
+	payload    payload.Net
 
 	cfg      *config.NetConfig
+	match    *netMatcher
+	sys      sysfs.FS
+	proc     procfs.FS
 	interval time.Duration
 	tick     *time.Ticker
 	topic    string
@@ -56,28 +87,49 @@ type Net struct {
 }
 
 func NewNet(cfg *config.Config) (*Net, error) {
-	n := &Net{cfg: &cfg.Net}
+	return NewNetFromConfig(cfg.Net, DefaultsOf(cfg))
+}
+
+// NewNetFromConfig is like [NewNet] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewNetFromConfig(cfg config.NetConfig, d Defaults) (*Net, error) {
+	n := &Net{cfg: &cfg, sys: sysfs.NewFS(d.Root), proc: procfs.NewFS(d.Root)}
+
+	var err error
+	if n.match, err = newNetMatcher(&cfg); err != nil {
+		return nil, errNotSupported(n.Type(), err)
+	}
+
+	for i := range cfg.Namespaces {
+		ns, ok := newNetNamespace(&cfg.Namespaces[i], d.Root)
+		if !ok {
+			log.Warn("Network namespace has no name or pid, skipping", "index", i)
+			continue
+		}
+
+		n.namespaces = append(n.namespaces, ns)
+	}
 
 	if err := n.parseInterfaces(true); err != nil {
-		return nil, err
+		return nil, errNotSupported(n.Type(), err)
 	}
 
-	if cfg.Net.Interval > 0 {
-		n.interval = cfg.Net.Interval
-	} else {
+	if cfg.Interval > 0 {
 		n.interval = cfg.Interval
+	} else {
+		n.interval = d.Interval
 	}
 
-	if cfg.Net.Topic != "" {
-		n.topic = cfg.Net.Topic
-	} else if cfg.BaseTopic != "" {
-		n.topic = cfg.BaseTopic + "/metric/net"
+	if cfg.Topic != "" {
+		n.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		n.topic = d.BaseTopic + "/metric/net"
 	} else {
 		n.topic = "mqttop/metric/net"
 	}
 
-	if cfg.Net.RescanInterval > 0 {
-		n.rescanInterval = cfg.Net.RescanInterval
+	if cfg.RescanInterval > 0 {
+		n.rescanInterval = cfg.RescanInterval
 	}
 
 	return n, nil
@@ -113,7 +165,7 @@ func getFlagsIfreq(sock int, ifreq *unix.Ifreq) (flags uint16, err error) {
 }
 
 func (n *Net) skipInterface(iface string) bool {
-	if slices.Contains(n.cfg.Exclude, iface) {
+	if n.match.excluded(iface) {
 		return true
 	}
 
@@ -121,7 +173,7 @@ func (n *Net) skipInterface(iface string) bool {
 		return false
 	}
 
-	nd, err := sysfs.NetDevice(iface)
+	nd, err := n.sys.NetDevice(iface)
 	if err != nil {
 		log.Debug("skipInterface", "Error opening", iface)
 		return true
@@ -129,9 +181,7 @@ func (n *Net) skipInterface(iface string) bool {
 
 	defer nd.Close()
 
-	if slices.ContainsFunc(n.cfg.Include, func(i config.NetIfaceConfig) bool {
-		return i.Interface == iface
-	}) {
+	if n.match.includeFor(iface) != nil {
 		return false
 	} else if len(n.cfg.Include) > 0 {
 		return true
@@ -153,7 +203,7 @@ func (n *Net) skipInterface(iface string) bool {
 }
 
 func (n *Net) parseInterfaces(firstRun bool) error {
-	dir, err := sysfs.Net()
+	dir, err := n.sys.Net()
 	if err != nil {
 		log.Debug("Error opening /sys/class/net", "err", err)
 		return err
@@ -174,11 +224,31 @@ func (n *Net) parseInterfaces(firstRun bool) error {
 
 	if firstRun {
 		n.interfaces = make(map[string]*NetInterface, len(interfaces))
+
+		// Every group is included even without any members yet, so that
+		// it's discovered before any of its interfaces exist.
+		for i := range n.cfg.Groups {
+			g := &n.cfg.Groups[i]
+
+			iface := n.newInterface(g.Name, netip.Addr{}, cmp.Or(g.RateUnit, n.cfg.RateUnit))
+			iface.group = &netGroup{members: make(map[string]*NetInterface)}
+
+			n.interfaces[g.Name] = iface
+		}
 	}
 
 	var changed bool
 
 	for _, name := range interfaces {
+		if n.addMember(name) {
+			continue
+		}
+
+		if iface := n.interfaces[name]; iface != nil && iface.group != nil {
+			log.Debug("Interface has the name of a group, skipping", "name", name)
+			continue
+		}
+
 		if iface, ok := n.interfaces[name]; !ok || !firstRun {
 			addr, err := getAddr4(sock, name)
 			if err != nil {
@@ -187,13 +257,9 @@ func (n *Net) parseInterfaces(firstRun bool) error {
 
 			var ratestr string
 
-			for i := range n.cfg.Include {
-				if n.cfg.Include[i].Interface != name {
-					continue
-				}
-
-				name = n.cfg.Include[i].FormatName(name)
-				ratestr = n.cfg.Include[i].RateUnit
+			if inc := n.match.includeFor(name); inc != nil {
+				name = inc.FormatName(name)
+				ratestr = inc.RateUnit
 			}
 
 			if n.skipInterface(name) {
@@ -210,18 +276,9 @@ func (n *Net) parseInterfaces(firstRun bool) error {
 					ratestr = n.cfg.RateUnit
 				}
 
-				rate, err := byteutil.ParseRate(ratestr)
-				if err != nil {
-					rate = byteutil.MiBps
-				}
-
 				log.Debug("Adding interface", "name", name)
 
-				n.interfaces[name] = &NetInterface{
-					name: name,
-					ip:   addr,
-					rate: rate,
-				}
+				n.interfaces[name] = n.newInterface(name, addr, ratestr)
 				changed = true
 			} else {
 				if addr != iface.ip {
@@ -231,12 +288,29 @@ func (n *Net) parseInterfaces(firstRun bool) error {
 		}
 	}
 
+	if n.parseNamespaces() {
+		changed = true
+	}
+
 	if firstRun {
 		return nil
 	}
 
-	for name := range n.interfaces {
-		if !slices.Contains(interfaces, name) {
+	for _, iface := range n.interfaces {
+		if iface.group == nil {
+			continue
+		}
+
+		for name := range iface.group.members {
+			if !slices.Contains(interfaces, name) {
+				log.Debug("Removing interface from group", "name", name, "group", iface.name)
+				delete(iface.group.members, name)
+			}
+		}
+	}
+
+	for name, iface := range n.interfaces {
+		if iface.ns == nil && iface.group == nil && !slices.Contains(interfaces, name) {
 			log.Debug("Deleting interface", "name", name)
 			delete(n.interfaces, name)
 
@@ -251,6 +325,60 @@ func (n *Net) parseInterfaces(firstRun bool) error {
 	return nil
 }
 
+// addMember adds the interface of name to its group, if it's in one and not
+// excluded, and reports whether it is. Adding members doesn't change the
+// interfaces of n, since only the group is included.
+func (n *Net) addMember(name string) bool {
+	if n.match.excluded(name) {
+		return false
+	}
+
+	g := n.match.groupFor(name)
+	if g == nil {
+		return false
+	}
+
+	group := n.interfaces[g.Name].group
+	if _, ok := group.members[name]; !ok {
+		log.Debug("Adding interface to group", "name", name, "group", g.Name)
+
+		group.members[name] = &NetInterface{name: name, sys: n.sys}
+	}
+
+	return true
+}
+
+// newInterface returns a new interface of n with the given name, address and
+// rate unit.
+func (n *Net) newInterface(name string, addr netip.Addr, ratestr string) *NetInterface {
+	rate, err := byteutil.ParseRate(ratestr)
+	if err != nil {
+		rate = byteutil.MiBps
+	}
+
+	iface := &NetInterface{
+		name: name,
+		ip:   addr,
+		rate: rate,
+		sys:  n.sys,
+	}
+
+	if n.cfg.Aggregate {
+		iface.rxAggregate = new(rolling)
+		iface.txAggregate = new(rolling)
+	}
+
+	if n.cfg.Accounting.Enabled {
+		iface.usage = newNetUsage(n.cfg.Accounting.ResetDay)
+	}
+
+	if cfg := n.wireGuardConfig(name); cfg != nil {
+		iface.wg = newNetWireGuard(cfg)
+	}
+
+	return iface
+}
+
 func (n *Net) Type() string {
 	return "net"
 }
@@ -271,7 +399,17 @@ func (n *Net) SetInterval(d time.Duration) {
 	n.mu.Unlock()
 }
 
+// Interval returns the update interval of the metric.
+func (n *Net) Interval() time.Duration {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.interval
+}
+
 func (n *Net) loop(ctx context.Context) {
+	defer recoverLoop(n.Type())
+
 	n.mu.Lock()
 
 	n.tick = time.NewTicker(n.interval)
@@ -368,7 +506,9 @@ func (n *Net) Rescan() error {
 // Update forces the net metric to update. The returned error will not
 // be sent on the channel returned by [Net.Updated] unlike updates that
 // happen automatically every update interval.
-func (n *Net) Update() error {
+func (n *Net) Update() (err error) {
+	defer errUpdate(n.Type(), time.Now(), &err)
+
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
@@ -378,14 +518,74 @@ func (n *Net) Update() error {
 	}
 	defer unix.Close(sock)
 
-	var group errgroup.Group
+	devs := n.netDev()
+
+	var group updateGroup
 
 	for _, iface := range n.interfaces {
+		if iface.ns != nil {
+			continue
+		}
+
+		if iface.group != nil {
+			// A member may be removed before the next rescan, which
+			// only leaves it out of the group.
+			for _, m := range iface.group.members {
+				m.sockfd = sock
+				m.dev = devs[m.name]
+				group.Go(func() error {
+					if err := m.Update(); err != nil {
+						log.Debug("Couldn't update interface of group", "name", m.name, "err", err)
+					}
+
+					return nil
+				})
+			}
+
+			continue
+		}
+
 		iface.sockfd = sock
+		iface.dev = devs[iface.name]
 		group.Go(iface.Update)
 	}
 
-	return group.Wait()
+	// Every interface of a namespace is read at once
+	for _, ns := range n.namespaces {
+		group.Go(ns.update)
+	}
+
+	err = group.Wait()
+
+	// The groups are updated once all of their members are
+	for _, iface := range n.interfaces {
+		if iface.group != nil {
+			iface.updateGroup()
+		}
+	}
+
+	return err
+}
+
+// netDev returns the statistics of every interface in /proc/net/dev, keyed by
+// name. Reading them all at once is far cheaper than opening the statistics of
+// each interface in sysfs, such as with many veth or bridge interfaces. If
+// /proc/net/dev can't be read, the result is empty and each interface reads
+// its own statistics instead.
+func (n *Net) netDev() map[string]*procfs.NetDev {
+	devs, err := n.proc.NetDev()
+	if err != nil {
+		log.Debug("Couldn't read /proc/net/dev", "err", err)
+		return nil
+	}
+
+	m := make(map[string]*procfs.NetDev, len(devs))
+
+	for i := range devs {
+		m[devs[i].Name] = &devs[i]
+	}
+
+	return m
 }
 
 // Updated returns the channel that updates will be sent on. A received value
@@ -408,6 +608,83 @@ func (n *Net) Stop() {
 	n.mu.Unlock()
 }
 
+// Counters implements [Counterer] and returns the counters of the bytes
+// received and transmitted by each interface, keyed by "<interface>/rx" and
+// "<interface>/tx".
+func (n *Net) Counters() map[string]Counter {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	counters := make(map[string]Counter, 2*len(n.interfaces))
+
+	for name, iface := range n.interfaces {
+		counters[name+"/rx"] = iface.rxCounter
+		counters[name+"/tx"] = iface.txCounter
+	}
+
+	return counters
+}
+
+// SetCounters implements [Counterer] and restores the counters returned by
+// [Net.Counters]. This should be called before n is started.
+func (n *Net) SetCounters(counters map[string]Counter) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for name, iface := range n.interfaces {
+		if c, ok := counters[name+"/rx"]; ok {
+			iface.rxCounter = c
+		}
+
+		if c, ok := counters[name+"/tx"]; ok {
+			iface.txCounter = c
+		}
+	}
+}
+
+// Usage returns the data usage of the current day and billing month of each
+// interface, keyed by "<interface>/day" and "<interface>/month". If accounting
+// is not enabled, the result is empty.
+func (n *Net) Usage() map[string]Usage {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	usage := make(map[string]Usage)
+
+	for name, iface := range n.interfaces {
+		if iface.usage == nil {
+			continue
+		}
+
+		usage[name+"/day"] = iface.usage.day
+		usage[name+"/month"] = iface.usage.month
+	}
+
+	return usage
+}
+
+// SetUsage restores the data usage returned by [Net.Usage]. Usage of a day or
+// billing month that has since ended is discarded on the next update. This
+// should be called before n is started.
+func (n *Net) SetUsage(usage map[string]Usage) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for name, iface := range n.interfaces {
+		if iface.usage == nil {
+			continue
+		}
+
+		if u, ok := usage[name+"/day"]; ok {
+			iface.usage.day = u
+		}
+
+		if u, ok := usage[name+"/month"]; ok {
+			iface.usage.month = u
+		}
+	}
+}
+
 func (n *Net) String() string {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
@@ -423,63 +700,107 @@ func (n *Net) String() string {
 	return fmt.Sprintf("%d interfaces (%d running)", len(n.interfaces), running)
 }
 
-func (n *Net) AppendText(b []byte) ([]byte, error) {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
+func (iface *NetInterface) toPayload(p *payload.Interface) {
+	size := byteutil.ByteSize(iface.rate)
+
+	p.Running = iface.Running()
+	p.IP = iface.ip
+	p.Download = iface.rx
+	p.Upload = iface.tx
+	p.DownloadRate = payload.Size(byteutil.ScaleSize(iface.rxRate, size))
+	p.UploadRate = payload.Size(byteutil.ScaleSize(iface.txRate, size))
+	p.DownloadTotal = payload.Some(iface.rxCounter.Total)
+	p.UploadTotal = payload.Some(iface.txCounter.Total)
+
+	if iface.usage != nil {
+		p.UsageToday = payload.Some(iface.usage.day.Bytes)
+		p.UsageMonth = payload.Some(iface.usage.month.Bytes)
+	} else {
+		p.UsageToday = payload.Optional[uint64]{}
+		p.UsageMonth = payload.Optional[uint64]{}
+	}
 
-	b = append(b, '{')
+	// The aggregates have the full precision of the rates, which are sizes
+	// rather than fixed-point values.
+	p.DownloadRateAggregate = aggregateOf(iface.rxAggregate, 3)
+	p.UploadRateAggregate = aggregateOf(iface.txAggregate, 3)
 
-	first := true
+	if iface.wg != nil {
+		p.Peers = iface.wg.toPayload()
+	}
+}
+
+func (iface *NetInterface) fromPayload(p *payload.Interface) {
+	size := byteutil.ByteSize(iface.rate)
+
+	if p.Running {
+		iface.flags |= unix.IFF_RUNNING
+	} else {
+		iface.flags &^= unix.IFF_RUNNING
+	}
+
+	iface.ip = p.IP
+	iface.rx = p.Download
+	iface.tx = p.Upload
+	iface.rxRate = byteutil.UnscaleSize(uint64(p.DownloadRate), size)
+	iface.txRate = byteutil.UnscaleSize(uint64(p.UploadRate), size)
+	iface.rxCounter.Total = p.DownloadTotal.Value
+	iface.txCounter.Total = p.UploadTotal.Value
+}
+
+func (n *Net) toPayload(p payload.Net) {
+	clear(p)
 
 	for name, iface := range n.interfaces {
 		if n.cfg.OnlyRunning && !iface.Running() {
 			continue
 		}
 
-		if !first {
-			b = append(b, ',', ' ')
-		}
+		var ip payload.Interface
 
-		b = append(b, '"')
-		b = append(b, name...)
-		b = append(b, "\": {\"running\": "...)
+		iface.toPayload(&ip)
+		p[name] = ip
+	}
+}
 
-		if iface.Running() {
-			b = append(b, "true, "...)
-		} else {
-			b = append(b, "false, "...)
-		}
+func (n *Net) fromPayload(p payload.Net) {
+	if n.cfg == nil {
+		n.cfg = new(config.NetConfig)
+	}
 
-		if iface.ip.IsValid() {
-			b = append(b, "\"ip\": \""...)
-			b = iface.ip.AppendTo(b)
-			b = append(b, '"', ',', ' ')
-		}
+	if n.interfaces == nil {
+		n.interfaces = make(map[string]*NetInterface, len(p))
+	}
 
-		if !iface.Running() {
-			b = append(b[:len(b)-2], '}')
-			first = false
+	for name, ip := range p {
+		iface, ok := n.interfaces[name]
+		if !ok {
+			rate, err := byteutil.ParseRate(n.cfg.RateUnit)
+			if err != nil {
+				rate = byteutil.MiBps
+			}
 
-			continue
+			iface = &NetInterface{name: name, rate: rate}
+			n.interfaces[name] = iface
 		}
 
-		b = append(b, "\"download\": "...)
-		b = strconv.AppendUint(b, iface.rx, 10)
-		b = append(b, ", \"upload\": "...)
-		b = strconv.AppendUint(b, iface.tx, 10)
-
-		size := byteutil.ByteSize(iface.rate)
+		iface.fromPayload(&ip)
+	}
+}
 
-		b = append(b, ", \"download_rate\": "...)
-		b = byteutil.AppendSize(b, iface.rxRate, size)
-		b = append(b, ", \"upload_rate\": "...)
-		b = byteutil.AppendSize(b, iface.txRate, size)
-		b = append(b, '}')
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of n to b.
+func (n *Net) AppendText(b []byte) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-		first = false
+	if n.payload == nil {
+		n.payload = make(payload.Net, len(n.interfaces))
 	}
 
-	return append(b, '}'), nil
+	n.toPayload(n.payload)
+
+	return n.payload.AppendText(b)
 }
 
 // MarshalJSON implements [json.Marshaler] and is equivalent to [Net.AppendText](nil).
@@ -487,6 +808,24 @@ func (n *Net) MarshalJSON() ([]byte, error) {
 	return n.AppendText(nil)
 }
 
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of network interfaces, as produced by [Net.MarshalJSON], into n.
+// The rates of each interface are interpreted in the rate units it was configured
+// with. Any interfaces not already in n are added with the configured rate unit.
+func (n *Net) UnmarshalJSON(data []byte) error {
+	var p payload.Net
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	n.fromPayload(p)
+	n.mu.Unlock()
+
+	return nil
+}
+
 func updateIfreq(sockfd int, name string) (ip netip.Addr, flags uint16, err error) {
 	ifreq, err := unix.NewIfreq(name)
 	if err != nil {
@@ -517,24 +856,57 @@ func (iface *NetInterface) Update() error {
 		}
 	}
 
-	rx, tx, err := sysfs.NetStatistics(iface.name)
-	if err != nil {
-		return &os.PathError{Op: "open", Path: iface.name, Err: err}
+	if dev := iface.dev; dev != nil {
+		iface.dev = nil
+		iface.update(dev.RxBytes, dev.TxBytes)
+	} else {
+		rx, tx, err := iface.sys.NetStatistics(iface.name)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: iface.name, Err: err}
+		}
+
+		iface.update(rx, tx)
+	}
+
+	if iface.wg != nil {
+		iface.wg.update(iface.name)
 	}
 
+	return nil
+}
+
+// update updates the counters and rates of iface from the total bytes
+// received and transmitted.
+func (iface *NetInterface) update(rx, tx uint64) {
+	// The first update only provides the baseline of the counters, unless
+	// they were restored from a previous run.
+	first := iface.rxCounter.Last == 0 && iface.txCounter.Last == 0
+
 	now := time.Now()
-	iface.rx = rx - iface.rxLast
-	iface.tx = tx - iface.txLast
-	iface.rxLast = rx
-	iface.txLast = tx
+	iface.rx = iface.rxCounter.Update(rx)
+	iface.tx = iface.txCounter.Update(tx)
+
+	if iface.usage != nil {
+		var used uint64
+		if !first {
+			used = iface.rx + iface.tx
+		}
+
+		iface.usage.add(now, used)
+	}
 	delta := uint64(now.Sub(iface.lastUpdate) / time.Second)
 
 	if delta > 0 {
 		iface.rxRate = 100 * iface.rx / delta
 		iface.txRate = 100 * iface.tx / delta
+
+		if iface.rxAggregate != nil {
+			size := byteutil.ByteSize(iface.rate)
+
+			iface.rxAggregate.add(now, int64(byteutil.ScaleSize(iface.rxRate, size)))
+			iface.txAggregate.add(now, int64(byteutil.ScaleSize(iface.txRate, size)))
+		}
 	}
 
 	iface.lastUpdate = now
-
-	return nil
 }
@@ -1,25 +1,34 @@
 package metrics
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"maps"
 	stdnet "net"
 	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
 
 	"github.com/lone-faerie/mqttop/config"
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/internal/wireguard"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 func testNet(t *testing.T) (*Net, *config.Config) {
 	t.Helper()
 
-	err := file.SetRoot("testdata/fixtures")
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
 
 	net, err := NewNet(cfg)
 	if err != nil {
@@ -99,6 +108,37 @@ func TestNet_Update(t *testing.T) {
 	}
 }
 
+func TestNet_UpdateSysfs(t *testing.T) {
+	net, _ := testNet(t)
+
+	devs := net.netDev()
+	if dev := devs["eth0"]; dev == nil || dev.RxBytes != 116706680863 {
+		t.Fatalf("NetDev: want eth0 from /proc/net/dev, got %+v", dev)
+	}
+
+	// Without /proc/net/dev, each interface reads its own statistics
+	root, err := vfs.NewRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net.proc = procfs.NewFS(root)
+
+	if devs := net.netDev(); len(devs) != 0 {
+		t.Errorf("NetDev: want none, got %d", len(devs))
+	}
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := uint64(116706680863), net.interfaces["eth0"].rx; got != want {
+		t.Errorf("Rx: want %v, got %v", want, got)
+	}
+	if want, got := uint64(145311386254), net.interfaces["eth0"].tx; got != want {
+		t.Errorf("Tx: want %v, got %v", want, got)
+	}
+}
+
 func TestNet_MarshalJSON(t *testing.T) {
 	net, _ := testNet(t)
 
@@ -125,3 +165,299 @@ func TestNet_MarshalJSON(t *testing.T) {
 		t.Errorf("result differs at char %d\nwant %q\ngot  %q", i, want[:i+1], got[:i+1])
 	}
 }
+
+func TestNet_Counters(t *testing.T) {
+	net, _ := testNet(t)
+
+	// Saved by a previous run, before the counters in the fixture
+	net.SetCounters(map[string]Counter{
+		"eth0/rx":  {Total: 1000, Last: 116706680000},
+		"eth0/tx":  {Total: 2000, Last: 145311386000},
+		"wlan0/rx": {Total: 3000, Last: 0},
+	})
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	counters := net.Counters()
+
+	if want, got := (Counter{Total: 1863, Last: 116706680863}), counters["eth0/rx"]; got != want {
+		t.Errorf("Rx: want %+v, got %+v", want, got)
+	}
+	if want, got := (Counter{Total: 2254, Last: 145311386254}), counters["eth0/tx"]; got != want {
+		t.Errorf("Tx: want %+v, got %+v", want, got)
+	}
+	if _, ok := counters["wlan0/rx"]; ok {
+		t.Error("wlan0: want no counter, got one")
+	}
+}
+
+func TestNet_Usage(t *testing.T) {
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+	cfg.Net.Accounting.Enabled = true
+
+	net, err := NewNet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first update is only the baseline of the counters
+	if want, got := uint64(0), net.Usage()["eth0/day"].Bytes; got != want {
+		t.Errorf("Day: want %d, got %d", want, got)
+	}
+
+	today := dayStart(time.Now())
+
+	net.SetUsage(map[string]Usage{
+		"eth0/day":   {Start: today, Bytes: 1000},
+		"eth0/month": {Start: today.AddDate(-1, 0, 0), Bytes: 2000},
+	})
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := net.Usage()
+
+	if want, got := uint64(1000), usage["eth0/day"].Bytes; got != want {
+		t.Errorf("Day: want %d, got %d", want, got)
+	}
+	// The restored month has ended
+	if want, got := uint64(0), usage["eth0/month"].Bytes; got != want {
+		t.Errorf("Month: want %d, got %d", want, got)
+	}
+}
+
+func TestNet_Namespaces(t *testing.T) {
+	cfg := config.Default()
+	cfg.Net.Exclude = []string{"self_excluded"}
+	cfg.Net.Namespaces = []config.NetNamespaceConfig{
+		{PID: os.Getpid(), Prefix: "self"},
+		{Name: "vpn"},
+		{},
+	}
+
+	ns, ok := newNetNamespace(&cfg.Net.Namespaces[1], nil)
+	if !ok {
+		t.Fatal("newNetNamespace: want namespace")
+	}
+	if want, got := "vpn_wg0", ns.name("wg0"); got != want {
+		t.Errorf("Name: want %q, got %q", want, got)
+	}
+	if want, got := "/run/netns/vpn", ns.path; got != want {
+		t.Errorf("Path: want %q, got %q", want, got)
+	}
+	if _, ok := newNetNamespace(&cfg.Net.Namespaces[2], nil); ok {
+		t.Error("newNetNamespace: want no namespace without a name or pid")
+	}
+
+	// Entering the namespace of the test itself requires CAP_SYS_ADMIN
+	self, _ := newNetNamespace(&cfg.Net.Namespaces[0], nil)
+	if _, err := self.read(); errors.Is(err, unix.EPERM) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	devs, err := procfs.FS{}.NetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Net.Namespaces = cfg.Net.Namespaces[:1]
+
+	net, err := NewNet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dev := range devs {
+		if _, ok := net.interfaces["self_"+dev.Name]; ok == (dev.Name == "lo") {
+			t.Errorf("%s: want included %v", dev.Name, dev.Name != "lo")
+		}
+	}
+}
+
+func TestNet_WireGuard(t *testing.T) {
+	laptop, phone := wireguard.Key{1}, wireguard.Key{2}
+
+	wg := newNetWireGuard(&config.NetWireGuardConfig{
+		Interface: "wg0",
+		Peers: []config.NetWireGuardPeerConfig{
+			{PublicKey: base64.StdEncoding.EncodeToString(laptop[:]), Name: "laptop"},
+			{PublicKey: "malformed", Name: "phone"},
+		},
+	})
+	if wg.timeout != defaultWireGuardTimeout {
+		t.Errorf("Timeout: want %v, got %v", defaultWireGuardTimeout, wg.timeout)
+	}
+
+	now := time.Now()
+	endpoint := netip.MustParseAddrPort("192.0.2.1:51820")
+
+	wg.updated = now
+	wg.peers = []wireguard.Peer{
+		{PublicKey: laptop, Endpoint: endpoint, LastHandshake: now.Add(-42 * time.Second), RxBytes: 100, TxBytes: 200},
+		{PublicKey: phone, LastHandshake: now.Add(-5 * time.Minute)},
+		{PublicKey: wireguard.Key{3}},
+	}
+
+	phoneName := base64.RawURLEncoding.EncodeToString(phone[:6])
+
+	want := map[string]payload.WireGuardPeer{
+		"laptop":   {Endpoint: endpoint, LastHandshake: payload.Some[int64](42), Received: 100, Sent: 200, Connected: true},
+		phoneName:  {LastHandshake: payload.Some[int64](300)},
+		"AwAAAAAA": {},
+	}
+
+	got := wg.toPayload()
+	if len(got) != len(want) {
+		t.Fatalf("Peers: want %v, got %v", want, got)
+	}
+
+	for name, w := range want {
+		if g, ok := got[name]; !ok || g != w {
+			t.Errorf("%s: want %+v, got %+v", name, w, g)
+		}
+	}
+}
+
+func TestNet_Groups(t *testing.T) {
+	root := t.TempDir()
+
+	writeStats := func(name string, rx, tx int) {
+		t.Helper()
+
+		dir := filepath.Join(root, "sys/class/net", name, "statistics")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		for file, v := range map[string]int{"rx_bytes": rx, "tx_bytes": tx} {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(strconv.Itoa(v)+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	writeStats("eth0", 1000, 2000)
+	writeStats("veth1a", 100, 200)
+	writeStats("veth2b", 10, 20)
+	writeStats("vethx", 1, 2)
+
+	cfg := config.Default()
+	cfg.RootFS = root
+	cfg.Net.Exclude = []string{"/^vethx$/"}
+	cfg.Net.Groups = []config.NetGroupConfig{
+		{Name: "docker", Interfaces: []string{"veth*"}},
+		{Name: "vpn", Interfaces: []string{"wg?"}},
+	}
+
+	net, err := NewNet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []string{"docker", "eth0", "vpn"}, slices.Sorted(maps.Keys(net.interfaces)); !slices.Equal(got, want) {
+		t.Fatalf("Interfaces: want %q, got %q", want, got)
+	}
+
+	docker := net.interfaces["docker"]
+	if want, got := []string{"veth1a", "veth2b"}, slices.Sorted(maps.Keys(docker.group.members)); !slices.Equal(got, want) {
+		t.Errorf("Members: want %q, got %q", want, got)
+	}
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeStats("veth1a", 150, 300)
+	writeStats("veth2b", 20, 20)
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := uint64(60), docker.rx; got != want {
+		t.Errorf("Rx: want %v, got %v", want, got)
+	}
+	if want, got := uint64(100), docker.tx; got != want {
+		t.Errorf("Tx: want %v, got %v", want, got)
+	}
+
+	// A removed member stops counting towards the group until rescanned
+	if err := os.RemoveAll(filepath.Join(root, "sys/class/net/veth2b")); err != nil {
+		t.Fatal(err)
+	}
+
+	writeStats("veth1a", 160, 300)
+
+	if err := net.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := uint64(10), docker.rx; got != want {
+		t.Errorf("Rx: want %v, got %v", want, got)
+	}
+
+	if err := net.Rescan(); err != ErrNoChange {
+		t.Errorf("Rescan: want %v, got %v", ErrNoChange, err)
+	}
+	if _, ok := docker.group.members["veth2b"]; ok {
+		t.Error("Rescan: want veth2b removed from the group")
+	}
+	if _, ok := net.interfaces["docker"]; !ok {
+		t.Error("Rescan: want group kept")
+	}
+}
+
+func TestNetMatcher(t *testing.T) {
+	m, err := newNetMatcher(&config.NetConfig{
+		Include: []config.NetIfaceConfig{{Interface: "eth0"}, {Interface: "/^en[ops]/"}},
+		Exclude: []string{"docker?"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		included bool
+		excluded bool
+	}{
+		{"eth0", true, false},
+		{"eth1", false, false},
+		{"enp3s0", true, false},
+		{"wlp2s0", false, false},
+		{"docker0", false, true},
+		{"docker10", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.includeFor(tt.name) != nil; got != tt.included {
+			t.Errorf("%s: want included %v, got %v", tt.name, tt.included, got)
+		}
+		if got := m.excluded(tt.name); got != tt.excluded {
+			t.Errorf("%s: want excluded %v, got %v", tt.name, tt.excluded, got)
+		}
+	}
+
+	for _, cfg := range []config.NetConfig{
+		{Exclude: []string{"/(/"}},
+		{Include: []config.NetIfaceConfig{{Interface: "eth["}}},
+		{Groups: []config.NetGroupConfig{{Interfaces: []string{"veth*"}}}},
+	} {
+		if _, err := newNetMatcher(&cfg); err == nil {
+			t.Errorf("%+v: want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package metrics
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+)
+
+// process is the usage of a single process of [Processes].
+type process struct {
+	pid  int
+	name string
+	cpu  int64 // percent of every cpu in thousandths
+	rss  uint64
+}
+
+// Processes implements the [Metric] interface to provide the processes using
+// the most CPU or memory.
+type Processes struct {
+	proc   procfs.FS
+	count  int
+	sortBy string
+	size   byteutil.ByteSize
+	prec   int // precision of the cpu usage, see [places]
+
+	// ticks are the clock ticks of each process at the last update, and total
+	// is the total jiffies of every cpu, which are in the same unit.
+	ticks map[int]uint64
+	total uint64
+
+	procs int
+	top   []process
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewProcesses returns a new [Processes] initialized from cfg. If the processes
+// can't be read from /proc, a non-nil error that wraps [ErrNotSupported] is
+// returned.
+func NewProcesses(cfg *config.Config) (*Processes, error) {
+	return NewProcessesFromConfig(cfg.Processes, DefaultsOf(cfg))
+}
+
+// NewProcessesFromConfig is like [NewProcesses] but is initialized from the
+// config of the metric and d instead of a full [config.Config].
+func NewProcessesFromConfig(cfg config.ProcessesConfig, d Defaults) (*Processes, error) {
+	p := &Processes{
+		proc:  procfs.NewFS(d.Root),
+		count: cfg.Count,
+		prec:  d.precision(cfg.Precision),
+	}
+
+	switch cfg.SortBy {
+	case "", "cpu":
+		p.sortBy = "cpu"
+	case "memory":
+		p.sortBy = "memory"
+	default:
+		return nil, errNotSupported(p.Type(), fmt.Errorf("unknown sort key %q", cfg.SortBy))
+	}
+
+	if p.count <= 0 {
+		p.count = config.DefaultProcesses.Count
+	}
+
+	p.size = byteutil.MiB
+
+	if cfg.SizeUnit != "" {
+		size, err := byteutil.ParseSize(cfg.SizeUnit)
+		if err != nil {
+			log.WarnError("Unknown processes size unit", config.InvalidValue("processes.size_unit", cfg.SizeUnit))
+		} else {
+			p.size = size
+		}
+	}
+
+	if _, err := p.proc.Procs(); err != nil {
+		return nil, errNotSupported(p.Type(), err)
+	}
+
+	if cfg.Interval > 0 {
+		p.interval = cfg.Interval
+	} else {
+		p.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		p.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		p.topic = d.BaseTopic + "/metric/processes"
+	} else {
+		p.topic = "mqttop/metric/processes"
+	}
+
+	return p, nil
+}
+
+// Type returns the metric type, "processes".
+func (*Processes) Type() string {
+	return "processes"
+}
+
+// Topic returns the topic to publish processes metrics to.
+func (p *Processes) Topic() string {
+	return p.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (p *Processes) SetInterval(d time.Duration) {
+	p.mu.Lock()
+
+	if p.tick != nil && d != p.interval {
+		p.tick.Reset(d)
+	}
+
+	p.interval = d
+
+	p.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (p *Processes) Interval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.interval
+}
+
+func (p *Processes) loop(ctx context.Context) {
+	defer recoverLoop(p.Type())
+
+	p.mu.Lock()
+	p.tick = time.NewTicker(p.interval)
+	p.mu.Unlock()
+
+	defer p.tick.Stop()
+	defer close(p.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("processes started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.tick.C:
+			err = p.Update()
+			if err == ErrNoChange {
+				log.Debug("processes updated, no change")
+			} else {
+				log.Debug("processes updated")
+			}
+
+			ch = p.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the processes updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (p *Processes) Start(ctx context.Context) (err error) {
+	if p.interval == 0 {
+		log.Warn("Processes interval is 0, not starting")
+		return
+	}
+
+	p.once.Do(func() {
+		ctx, p.stop = context.WithCancel(ctx)
+		p.ch = make(chan error)
+
+		go p.loop(ctx)
+	})
+
+	return
+}
+
+// totalJiffies returns the total jiffies of every cpu from /proc/stat.
+func (p *Processes) totalJiffies() (uint64, error) {
+	stat, err := p.proc.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	defer stat.Close()
+
+	line, err := stat.ReadLine()
+	if err != nil {
+		return 0, err
+	}
+
+	cpuNum, total, _, ok := parseCPUStat(line)
+	if !ok || cpuNum != -1 {
+		return 0, errors.New("missing cpu line of /proc/stat")
+	}
+
+	return total, nil
+}
+
+// less reports whether a uses more than b of the sort key, which is used to
+// sort the processes in descending order. Ties are broken by the other usage,
+// then by the pid so that the order is stable between updates.
+func (p *Processes) less(a, b process) int {
+	if p.sortBy == "memory" {
+		if c := cmp.Compare(b.rss, a.rss); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(b.cpu, a.cpu); c != 0 {
+			return c
+		}
+	} else {
+		if c := cmp.Compare(b.cpu, a.cpu); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(b.rss, a.rss); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(a.pid, b.pid)
+}
+
+// Update forces the processes metric to update. The returned error will not
+// be sent on the channel returned by [Processes.Updated] unlike updates that
+// happen automatically every update interval.
+func (p *Processes) Update() (err error) {
+	defer errUpdate(p.Type(), time.Now(), &err)
+
+	procs, err := p.proc.Procs()
+	if err != nil {
+		return err
+	}
+
+	total, err := p.totalJiffies()
+	if err != nil {
+		return err
+	}
+
+	var dTotal uint64
+	if total > p.total {
+		dTotal = total - p.total
+	}
+
+	ticks := make(map[int]uint64, len(procs))
+	all := make([]process, 0, len(procs))
+
+	for _, proc := range procs {
+		// Processes may exit while they are being read, which is skipped
+		// along with any other process that can't be read.
+		s, err := p.proc.ProcStat(proc)
+		if err != nil {
+			continue
+		}
+
+		t := s.UTime + s.STime
+		ticks[s.PID] = t
+
+		var cpu int64
+
+		// If the ticks decreased, the pid was reused by a new process.
+		if last, ok := p.ticks[s.PID]; ok && dTotal > 0 && t >= last {
+			cpu = int64(100_000 * (t - last) / dTotal)
+		}
+
+		all = append(all, process{
+			pid:  s.PID,
+			name: s.Comm,
+			cpu:  cpu,
+			rss:  s.RSS,
+		})
+	}
+
+	slices.SortFunc(all, p.less)
+
+	top := all[:min(p.count, len(all))]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ticks = ticks
+	p.total = total
+
+	if len(all) == p.procs && slices.Equal(top, p.top) {
+		return ErrNoChange
+	}
+
+	p.procs = len(all)
+	p.top = slices.Clip(top)
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (p *Processes) Updated() <-chan error {
+	return p.ch
+}
+
+// Stop stops the Processes from continuing to update. Once stopped, the Processes
+// may not be restarted.
+func (p *Processes) Stop() {
+	p.mu.Lock()
+
+	if p.stop != nil {
+		p.stop()
+	}
+
+	p.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the number of processes and
+// the name of the process using the most.
+func (p *Processes) String() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := strconv.Itoa(p.procs) + " processes"
+	if len(p.top) > 0 {
+		s += ", top " + p.top[0].name + " by " + p.sortBy
+	}
+
+	return s
+}
+
+func (p *Processes) toPayload(pl *payload.Processes) {
+	pl.Count = p.procs
+	pl.SortBy = p.sortBy
+	pl.Top = make([]payload.Process, len(p.top))
+
+	for i, proc := range p.top {
+		pl.Top[i] = payload.Process{
+			PID:    proc.pid,
+			Name:   proc.name,
+			CPU:    payload.Milli(proc.cpu).Round(places(p.prec)),
+			Memory: payload.Size(byteutil.ScaleSize(proc.rss, p.size)),
+		}
+	}
+}
+
+func (p *Processes) fromPayload(pl *payload.Processes) {
+	p.procs = pl.Count
+	p.top = make([]process, len(pl.Top))
+
+	for i, proc := range pl.Top {
+		p.top[i] = process{
+			pid:  proc.PID,
+			name: proc.Name,
+			cpu:  int64(proc.CPU),
+			rss:  byteutil.UnscaleSize(uint64(proc.Memory), p.size),
+		}
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of p to b.
+func (p *Processes) AppendText(b []byte) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var pl payload.Processes
+
+	p.toPayload(&pl)
+
+	return pl.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Processes.AppendText](nil).
+func (p *Processes) MarshalJSON() ([]byte, error) {
+	return p.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of processes, as produced by [Processes.MarshalJSON], into p.
+func (p *Processes) UnmarshalJSON(data []byte) error {
+	var pl payload.Processes
+
+	if err := json.Unmarshal(data, &pl); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.fromPayload(&pl)
+	p.mu.Unlock()
+
+	return nil
+}
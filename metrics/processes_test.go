@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+)
+
+func testProcesses(t *testing.T) (*Processes, *config.Config) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.RootFS = "testdata/fixtures"
+
+	p, err := NewProcesses(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil {
+		t.Fatal("processes is nil")
+	}
+
+	return p, cfg
+}
+
+func TestProcesses(t *testing.T) {
+	p, cfg := testProcesses(t)
+
+	if want, got := "processes", p.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := cfg.Processes.Topic, p.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := 5, p.count; got != want {
+		t.Errorf("Count: want %v, got %v", want, got)
+	}
+	if want, got := "cpu", p.sortBy; got != want {
+		t.Errorf("SortBy: want %q, got %q", want, got)
+	}
+	if want, got := byteutil.MiB, p.size; got != want {
+		t.Errorf("Size: want %v, got %v", want, got)
+	}
+
+	cfg.Processes.SortBy = "name"
+	if _, err := NewProcesses(cfg); err == nil {
+		t.Error("SortBy name: want error")
+	}
+}
+
+func TestProcesses_Update(t *testing.T) {
+	p, _ := testProcesses(t)
+
+	if err := p.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only 26231 has both a stat and a status in the fixtures.
+	if want, got := 1, p.procs; got != want {
+		t.Fatalf("Procs: want %v, got %v", want, got)
+	}
+
+	want := process{pid: 26231, name: "vim", rss: 6716 * 1024}
+	if got := p.top[0]; got != want {
+		t.Errorf("Top: want %+v, got %+v", want, got)
+	}
+
+	if err := p.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	b, err := p.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := `{"count": 1, "sort_by": "cpu", "top": [{"pid": 26231, "name": "vim", "cpu": 0, "memory": 6.558}]}`, string(b); got != want {
+		t.Errorf("MarshalJSON: want %s, got %s", want, got)
+	}
+
+	var p2 Processes
+
+	p2.size = p.size
+	if err := json.Unmarshal(b, &p2); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := p.procs, p2.procs; got != want {
+		t.Errorf("Unmarshal procs: want %v, got %v", want, got)
+	}
+	if want, got := p.top[0].name, p2.top[0].name; got != want {
+		t.Errorf("Unmarshal name: want %q, got %q", want, got)
+	}
+}
+
+// writeProcs writes /proc/stat with the total jiffies and a process for each
+// of ticks with that many clock ticks and rss kB.
+func writeProcs(t *testing.T, dir string, total uint64, ticks, rss []uint64) {
+	t.Helper()
+
+	write := func(name, data string) {
+		name = filepath.Join(dir, "proc", name)
+
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("stat", fmt.Sprintf("cpu  %d 0 0 0 0 0 0 0 0 0\ncpu0 %[1]d 0 0 0 0 0 0 0 0 0\n", total))
+
+	for i := range ticks {
+		pid := i + 1
+		write(fmt.Sprintf("%d/stat", pid), fmt.Sprintf("%d (proc %d) S 0 0 0 0 -1 0 0 0 0 0 %d 0 0 0 20 0 1 0\n", pid, pid, ticks[i]))
+		write(fmt.Sprintf("%d/status", pid), fmt.Sprintf("Name:\tproc %d\nVmRSS:\t%8d kB\n", pid, rss[i]))
+	}
+}
+
+func TestProcesses_UpdateCPU(t *testing.T) {
+	dir := t.TempDir()
+
+	writeProcs(t, dir, 1000, []uint64{100, 100, 100}, []uint64{300, 100, 200})
+
+	cfg := config.Default()
+	cfg.Processes.Count = 2
+
+	p, err := NewProcessesFromConfig(cfg.Processes, Defaults{Root: testRoot(t, dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a previous update, no cpu has been used so the processes are
+	// sorted by memory.
+	if want, got := 3, p.procs; got != want {
+		t.Errorf("Procs: want %v, got %v", want, got)
+	}
+	if len(p.top) != 2 || p.top[0].pid != 1 || p.top[1].pid != 3 {
+		t.Errorf("Top: want pids 1 and 3, got %+v", p.top)
+	}
+
+	writeProcs(t, dir, 2000, []uint64{150, 400, 0}, []uint64{300, 100, 200})
+
+	if err := p.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Process 3 restarted with the same pid, so its usage is unknown.
+	want := []process{
+		{pid: 2, name: "proc 2", cpu: 30_000, rss: 100 * 1024},
+		{pid: 1, name: "proc 1", cpu: 5_000, rss: 300 * 1024},
+	}
+	if len(p.top) != len(want) || p.top[0] != want[0] || p.top[1] != want[1] {
+		t.Errorf("Top: want %+v, got %+v", want, p.top)
+	}
+
+	p.sortBy = "memory"
+
+	if err := p.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.top) != 2 || p.top[0].pid != 1 || p.top[1].pid != 3 {
+		t.Errorf("Top by memory: want pids 1 and 3, got %+v", p.top)
+	}
+}
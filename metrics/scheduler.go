@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// updates is the scheduler shared by every metric that updates many entities
+// at once, such as the disks of [Disks] and the interfaces of [Net], so that
+// the number of goroutines, and the threads blocked in syscalls by them, is
+// bounded no matter how many entities there are.
+var updates scheduler
+
+// scheduler bounds the number of concurrent updates across every
+// [updateGroup] that uses it.
+type scheduler struct {
+	mu  sync.Mutex
+	sem chan struct{}
+}
+
+// SetMaxConcurrentUpdates sets the maximum number of entities, such as disks
+// or network interfaces, that are updated concurrently across every metric.
+// If n <= 0, the maximum is [runtime.GOMAXPROCS]. Updates already running
+// aren't affected. This is called by [New] with the
+// max_concurrent_updates of the config.
+func SetMaxConcurrentUpdates(n int) {
+	updates.setLimit(n)
+}
+
+func (s *scheduler) setLimit(n int) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	s.mu.Lock()
+	s.sem = make(chan struct{}, n)
+	s.mu.Unlock()
+}
+
+// acquire waits for a slot of s, and returns the semaphore it must be
+// released to, in case the limit is changed in the meantime.
+func (s *scheduler) acquire() chan struct{} {
+	s.mu.Lock()
+
+	if s.sem == nil {
+		s.sem = make(chan struct{}, runtime.GOMAXPROCS(0))
+	}
+
+	sem := s.sem
+
+	s.mu.Unlock()
+
+	sem <- struct{}{}
+
+	return sem
+}
+
+// updateGroup is an [errgroup.Group] whose goroutines are scheduled by the
+// shared scheduler. The zero value is ready to use.
+type updateGroup struct {
+	group errgroup.Group
+}
+
+// Go calls fn in a new goroutine once there's a free slot in the scheduler,
+// blocking until then so that no goroutine is started early. fn must not
+// start updates of its own, or it may deadlock.
+func (g *updateGroup) Go(fn func() error) {
+	sem := updates.acquire()
+
+	g.group.Go(func() error {
+		defer func() { <-sem }()
+
+		return fn()
+	})
+}
+
+// Wait waits for every goroutine of g, and returns the first error.
+func (g *updateGroup) Wait() error {
+	return g.group.Wait()
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpdateGroup(t *testing.T) {
+	SetMaxConcurrentUpdates(2)
+	t.Cleanup(func() { SetMaxConcurrentUpdates(0) })
+
+	var running, peak atomic.Int32
+
+	errTest := errors.New("test")
+
+	var group updateGroup
+
+	for i := range 8 {
+		group.Go(func() error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+
+			time.Sleep(5 * time.Millisecond)
+
+			if i == 3 {
+				return errTest
+			}
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != errTest {
+		t.Errorf("Wait: want %v, got %v", errTest, err)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("Peak: want at most 2, got %d", p)
+	}
+}
@@ -0,0 +1,467 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/sysfs"
+)
+
+// smartctlTimeout is the maximum time smartctl may run for each disk.
+const smartctlTimeout = 10 * time.Second
+
+// Bits of the exit status of smartctl. The other bits report the health of the
+// disk, which is read from the output instead.
+const (
+	smartctlParseError = 1 << 0 // the command line couldn't be parsed
+	smartctlOpenError  = 1 << 1 // the device couldn't be opened or is in standby
+)
+
+// ATA SMART attributes read from smartctl.
+const (
+	ataReallocated = 5
+	ataPending     = 197
+)
+
+// smartctlOutput is the part of the JSON output of smartctl used by [Smart].
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeSmartHealth *struct {
+		PercentageUsed int   `json:"percentage_used"`
+		MediaErrors    int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// readSmartctl reads the health of the named disk from smartctl into v. Disks in
+// standby aren't woken up, in which case ok is false and v is unchanged.
+func readSmartctl(ctx context.Context, name string, v *payload.SmartDisk) (ok bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, smartctlTimeout)
+	defer cancel()
+
+	// smartctl exits with a non-zero status if the disk is failing, but the
+	// output is still valid.
+	out, err := runCommand(ctx, "smartctl", "--json", "-n", "standby", "-H", "-A", "/dev/"+name)
+	if len(out) == 0 {
+		return false, err
+	}
+
+	var o smartctlOutput
+
+	if jerr := json.Unmarshal(out, &o); jerr != nil {
+		if err == nil {
+			err = jerr
+		}
+
+		return false, err
+	}
+
+	switch status := o.Smartctl.ExitStatus; {
+	case status&smartctlParseError != 0:
+		return false, fmt.Errorf("smartctl /dev/%s: exit status %d", name, status)
+	case status&smartctlOpenError != 0:
+		return false, nil
+	}
+
+	if o.SmartStatus != nil {
+		v.Passed = payload.Some(o.SmartStatus.Passed)
+	}
+
+	if o.Temperature != nil {
+		v.Temperature = payload.Some(payload.Milli(o.Temperature.Current * 1000))
+	}
+
+	if o.PowerOnTime != nil {
+		v.PowerOnHours = payload.Some(o.PowerOnTime.Hours)
+	}
+
+	if o.ATASmartAttributes != nil {
+		for _, attr := range o.ATASmartAttributes.Table {
+			switch attr.ID {
+			case ataReallocated:
+				v.Reallocated = payload.Some(attr.Raw.Value)
+			case ataPending:
+				v.Pending = payload.Some(attr.Raw.Value)
+			}
+		}
+	}
+
+	if o.NVMeSmartHealth != nil {
+		v.Wear = payload.Some(o.NVMeSmartHealth.PercentageUsed)
+		v.MediaErrors = payload.Some(o.NVMeSmartHealth.MediaErrors)
+	}
+
+	return true, nil
+}
+
+// smartDisk is a single disk of [Smart].
+type smartDisk struct {
+	sysfs.BlockDisk
+
+	smartctl bool // whether the disk is read with smartctl
+	values   payload.SmartDisk
+}
+
+func (d *smartDisk) update(ctx context.Context) (changed bool, err error) {
+	v := d.values
+
+	switch {
+	case d.smartctl:
+		// The temperature of smartctl is preferred over that of hwmon since
+		// reading hwmon may spin up a disk in standby.
+		ok, err := readSmartctl(ctx, d.Name, &v)
+		if !ok {
+			return false, err
+		}
+	case d.HasTemp():
+		temp, err := d.ReadTemp()
+		if err != nil {
+			return false, err
+		}
+
+		v.Temperature = payload.Some(payload.Milli(temp))
+	}
+
+	changed = v != d.values
+	d.values = v
+
+	return
+}
+
+// Smart implements the [Metric] interface to provide the SMART health of every
+// physical disk. If smartctl is installed and permitted, this includes the
+// overall health assessment, temperature, power on hours, and the reallocated
+// and pending sectors of ATA disks or the media errors and wear of NVMe disks.
+// Otherwise, only the temperatures reported by hwmon are provided.
+type Smart struct {
+	disks []smartDisk
+	prec  int // precision of the temperatures, see [places]
+
+	payload payload.Smart
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewSmart returns a new [Smart] initialized from cfg. If there are no disks
+// that report their health or temperature, a non-nil error that wraps
+// [ErrNotSupported] is returned.
+func NewSmart(cfg *config.Config) (*Smart, error) {
+	return NewSmartFromConfig(cfg.Smart, DefaultsOf(cfg))
+}
+
+// NewSmartFromConfig is like [NewSmart] but is initialized from the config of
+// the metric and d instead of a full [config.Config].
+func NewSmartFromConfig(cfg config.SmartConfig, d Defaults) (*Smart, error) {
+	s := &Smart{
+		prec: d.precision(cfg.Precision),
+	}
+
+	disks, err := sysfs.NewFS(d.Root).BlockDisks()
+	if err != nil {
+		return nil, errNotSupported(s.Type(), err)
+	}
+
+	smartctl := cfg.Smartctl
+	if smartctl {
+		if _, err := lookPath("smartctl"); err != nil {
+			log.Debug("smartctl not found, only reading disk temperatures", "err", err)
+			smartctl = false
+		}
+	}
+
+	s.disks = make([]smartDisk, 0, len(disks))
+
+	for i := range disks {
+		disk := smartDisk{BlockDisk: disks[i]}
+
+		if smartctl {
+			ok, err := readSmartctl(context.Background(), disk.Name, &disk.values)
+			if err != nil {
+				log.WarnError("Unable to read SMART, try running as root", err, "disk", disk.Name)
+			} else {
+				// A disk in standby is assumed to support SMART, which is
+				// read once it spins up.
+				disk.smartctl = true
+
+				if !ok {
+					log.Debug("Disk is in standby", "disk", disk.Name)
+				}
+			}
+		}
+
+		if !disk.smartctl && !disk.HasTemp() {
+			continue
+		}
+
+		s.disks = append(s.disks, disk)
+	}
+
+	if len(s.disks) == 0 {
+		return nil, errNotSupported(s.Type(), ErrNotFound)
+	}
+
+	if cfg.Interval > 0 {
+		s.interval = cfg.Interval
+	} else {
+		s.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		s.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		s.topic = d.BaseTopic + "/metric/smart"
+	} else {
+		s.topic = "mqttop/metric/smart"
+	}
+
+	return s, nil
+}
+
+// Type returns the metric type, "smart".
+func (*Smart) Type() string {
+	return "smart"
+}
+
+// Topic returns the topic to publish SMART metrics to.
+func (s *Smart) Topic() string {
+	return s.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (s *Smart) SetInterval(d time.Duration) {
+	s.mu.Lock()
+
+	if s.tick != nil && d != s.interval {
+		s.tick.Reset(d)
+	}
+
+	s.interval = d
+
+	s.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (s *Smart) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.interval
+}
+
+func (s *Smart) loop(ctx context.Context) {
+	defer recoverLoop(s.Type())
+
+	s.mu.Lock()
+	s.tick = time.NewTicker(s.interval)
+	s.mu.Unlock()
+
+	defer s.tick.Stop()
+	defer close(s.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("smart started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tick.C:
+			err = s.Update()
+			if err == ErrNoChange {
+				log.Debug("smart updated, no change")
+			} else {
+				log.Debug("smart updated")
+			}
+
+			ch = s.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the SMART metric updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (s *Smart) Start(ctx context.Context) (err error) {
+	if s.interval == 0 {
+		log.Warn("Smart interval is 0, not starting")
+		return
+	}
+
+	s.once.Do(func() {
+		ctx, s.stop = context.WithCancel(ctx)
+		s.ch = make(chan error)
+
+		go s.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the SMART metric to update. The returned error will not
+// be sent on the channel returned by [Smart.Updated] unlike updates that
+// happen automatically every update interval.
+func (s *Smart) Update() (err error) {
+	defer errUpdate(s.Type(), time.Now(), &err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed bool
+
+	for i := range s.disks {
+		c, err := s.disks[i].update(context.Background())
+		if err != nil {
+			return err
+		}
+
+		changed = changed || c
+	}
+
+	if !changed {
+		return ErrNoChange
+	}
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (s *Smart) Updated() <-chan error {
+	return s.ch
+}
+
+// Stop stops the Smart from continuing to update. Once stopped, the Smart
+// may not be restarted.
+func (s *Smart) Stop() {
+	s.mu.Lock()
+
+	if s.stop != nil {
+		s.stop()
+	}
+
+	s.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the number of disks and how many
+// of them are failing.
+func (s *Smart) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var failing int
+
+	for i := range s.disks {
+		if passed, ok := s.disks[i].values.Passed.Get(); ok && !passed {
+			failing++
+		}
+	}
+
+	return fmt.Sprintf("%d disks (%d failing)", len(s.disks), failing)
+}
+
+func (s *Smart) toPayload(p payload.Smart) {
+	clear(p)
+
+	for i := range s.disks {
+		disk := &s.disks[i]
+
+		v := disk.values
+		v.Model = disk.Model
+
+		if v.Temperature.Valid {
+			v.Temperature.Value = v.Temperature.Value.Round(places(s.prec))
+		}
+
+		p[disk.Name] = v
+	}
+}
+
+func (s *Smart) fromPayload(p payload.Smart) {
+	for name, v := range p {
+		i := 0
+		for i < len(s.disks) && s.disks[i].Name != name {
+			i++
+		}
+
+		if i == len(s.disks) {
+			s.disks = append(s.disks, smartDisk{BlockDisk: sysfs.BlockDisk{Name: name}})
+		}
+
+		s.disks[i].Model = v.Model
+		s.disks[i].values = v
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s *Smart) AppendText(b []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.payload == nil {
+		s.payload = make(payload.Smart, len(s.disks))
+	}
+
+	s.toPayload(s.payload)
+
+	return s.payload.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Smart.AppendText](nil).
+func (s *Smart) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of smart, as produced by [Smart.MarshalJSON], into s. Any
+// disks not already in s are added.
+func (s *Smart) UnmarshalJSON(data []byte) error {
+	var p payload.Smart
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.fromPayload(p)
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/discovery"
+	"github.com/lone-faerie/mqttop/payload"
+)
+
+const (
+	smartctlATA = `{
+  "smartctl": {"exit_status": 0},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 31},
+  "power_on_time": {"hours": 12043},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 0, "string": "0"}},
+    {"id": 9, "name": "Power_On_Hours", "raw": {"value": 12043, "string": "12043"}},
+    {"id": 197, "name": "Current_Pending_Sector", "raw": {"value": 8, "string": "8"}}
+  ]}
+}`
+	smartctlNVMe = `{
+  "smartctl": {"exit_status": 8},
+  "smart_status": {"passed": false},
+  "temperature": {"current": 45},
+  "power_on_time": {"hours": 812},
+  "nvme_smart_health_information_log": {"percentage_used": 2, "media_errors": 3}
+}`
+	smartctlStandby = `{"smartctl": {"exit_status": 2}}`
+)
+
+func smartFiles(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sys/block/sda/device/model":                    "Samsung SSD 870 EVO 1TB \n",
+		"sys/block/sda/device/hwmon/hwmon3/temp1_input": "30000\n",
+		"sys/block/nvme0n1/device/model":                "WD_BLACK SN850X\n",
+		"sys/block/nvme0n1/device/hwmon1/temp1_input":   "44850\n",
+		"sys/block/sdb/device/model":                    "WDC WD40EFRX\n",
+		"sys/block/loop0/size":                          "0\n",
+	})
+
+	return dir
+}
+
+func TestSmart(t *testing.T) {
+	f := &fakeExec{
+		installed: []string{"smartctl"},
+		out: map[string]string{
+			"smartctl --json -n standby -H -A /dev/sda":     smartctlATA,
+			"smartctl --json -n standby -H -A /dev/nvme0n1": smartctlNVMe,
+			"smartctl --json -n standby -H -A /dev/sdb":     smartctlStandby,
+		},
+	}
+	f.install(t)
+
+	d := Defaults{Root: testRoot(t, smartFiles(t)), Interval: time.Second}
+
+	s, err := NewSmartFromConfig(config.SmartConfig{Smartctl: true}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "smart", s.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := "mqttop/metric/smart", s.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+	if want, got := "3 disks (1 failing)", s.String(); got != want {
+		t.Errorf("String: want %q, got %q", want, got)
+	}
+
+	if err := s.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	f.out["smartctl --json -n standby -H -A /dev/sdb"] = `{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true}, "temperature": {"current": 28}}`
+
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p payload.Smart
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	want := payload.Smart{
+		"sda": {
+			Model:        "Samsung SSD 870 EVO 1TB",
+			Passed:       payload.Some(true),
+			Temperature:  payload.Some[payload.Milli](31000),
+			PowerOnHours: payload.Some[int64](12043),
+			Reallocated:  payload.Some[int64](0),
+			Pending:      payload.Some[int64](8),
+		},
+		"nvme0n1": {
+			Model:        "WD_BLACK SN850X",
+			Passed:       payload.Some(false),
+			Temperature:  payload.Some[payload.Milli](45000),
+			PowerOnHours: payload.Some[int64](812),
+			MediaErrors:  payload.Some[int64](3),
+			Wear:         payload.Some(2),
+		},
+		"sdb": {
+			Model:       "WDC WD40EFRX",
+			Passed:      payload.Some(true),
+			Temperature: payload.Some[payload.Milli](28000),
+		},
+	}
+
+	if len(p) != len(want) {
+		t.Errorf("want %d disks, got %d: %s", len(want), len(p), b)
+	}
+	for name, w := range want {
+		if got := p[name]; got != w {
+			t.Errorf("%s: want %+v, got %+v", name, w, got)
+		}
+	}
+
+	var ss Smart
+	if err := json.Unmarshal(b, &ss); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "3 disks (1 failing)", ss.String(); got != want {
+		t.Errorf("round trip: want %q, got %q", want, got)
+	}
+
+	disc := &discovery.Discovery{
+		Origin:     discovery.NewOrigin(),
+		Components: make(map[string]discovery.Component),
+	}
+	s.Discover(disc)
+
+	for _, id := range []string{
+		"mqttop_smart_sda_temperature",
+		"mqttop_smart_sda_problem",
+		"mqttop_smart_sda_power_on_hours",
+		"mqttop_smart_sda_reallocated_sectors",
+		"mqttop_smart_sda_pending_sectors",
+		"mqttop_smart_nvme0n1_media_errors",
+		"mqttop_smart_nvme0n1_wear",
+	} {
+		if _, ok := disc.Components[id]; !ok {
+			t.Errorf("missing component %s", id)
+		}
+	}
+
+	if _, ok := disc.Components["mqttop_smart_nvme0n1_reallocated_sectors"]; ok {
+		t.Error("NVMe disk discovered with reallocated sectors")
+	}
+
+	cmp := disc.Components["mqttop_smart_sda_problem"]
+	if want, got := discovery.BinarySensor, cmp[discovery.Platform]; got != want {
+		t.Errorf("problem platform: want %v, got %v", want, got)
+	}
+	if want, got := "Samsung SSD 870 EVO 1TB (sda) health", cmp[discovery.Name]; got != want {
+		t.Errorf("problem name: want %v, got %v", want, got)
+	}
+}
+
+func TestSmart_Temperatures(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		installed []string
+		smartctl  bool
+	}{
+		{"NotInstalled", nil, true},
+		{"Disabled", []string{"smartctl"}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeExec{installed: tt.installed}
+			f.install(t)
+
+			d := Defaults{Root: testRoot(t, smartFiles(t)), Interval: time.Second}
+
+			s, err := NewSmartFromConfig(config.SmartConfig{Smartctl: tt.smartctl}, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(f.ran) > 0 {
+				t.Errorf("want no commands run, got %q", f.ran)
+			}
+
+			if err := s.Update(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The disk without a hwmon device is skipped.
+			if want, got := "2 disks (0 failing)", s.String(); got != want {
+				t.Errorf("String: want %q, got %q", want, got)
+			}
+
+			b, err := s.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var p payload.Smart
+			if err := json.Unmarshal(b, &p); err != nil {
+				t.Fatal(err)
+			}
+
+			if want, got := payload.Some[payload.Milli](44850), p["nvme0n1"].Temperature; got != want {
+				t.Errorf("nvme0n1 temperature: want %v, got %v", want, got)
+			}
+			if want, got := payload.Some[payload.Milli](30000), p["sda"].Temperature; got != want {
+				t.Errorf("sda temperature: want %v, got %v", want, got)
+			}
+			if p["sda"].Passed.Valid {
+				t.Errorf("sda: want no health without smartctl, got %s", b)
+			}
+
+			disc := &discovery.Discovery{
+				Origin:     discovery.NewOrigin(),
+				Components: make(map[string]discovery.Component),
+			}
+			s.Discover(disc)
+
+			if want, got := 2, len(disc.Components); got != want {
+				t.Errorf("want %d components, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestSmart_Unsupported(t *testing.T) {
+	f := &fakeExec{}
+	f.install(t)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sys/block/loop0/size": "0\n",
+	})
+
+	_, err := NewSmartFromConfig(config.DefaultSmart, Defaults{Root: testRoot(t, dir)})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("want %v, got %v", ErrNotSupported, err)
+	}
+}
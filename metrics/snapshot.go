@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// snapshotSample is the time between the two updates of a snapshot. Metrics
+// that report a rate, such as CPU usage or network throughput, measure it
+// between consecutive updates, so a single update is not enough.
+var snapshotSample = time.Second
+
+// Key returns the key of m in the map returned by [Snapshot]. This is the type
+// of the metric, or "dir/<slug>" for a [Dir].
+func Key(m Metric) string {
+	if d, ok := m.(*Dir); ok {
+		return "dir/" + d.Slug()
+	}
+
+	return m.Type()
+}
+
+// Snapshot returns the JSON-encoded state of every metric enabled in cfg, keyed
+// by [Key], without starting any of them. Each metric is updated twice, about a
+// second apart, so that any rates are measured between the two updates.
+//
+// If some metrics couldn't be updated or encoded, the snapshot of the others is
+// returned along with the errors. If ctx is done before the second update, the
+// metrics are stopped and ctx.Err() is returned.
+func Snapshot(ctx context.Context, cfg *config.Config) (map[string]json.RawMessage, error) {
+	m := New(cfg)
+	defer Stop(m...)
+
+	for _, mm := range m {
+		mm.Update()
+	}
+
+	t := time.NewTimer(snapshotSample)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.C:
+	}
+
+	var (
+		snap = make(map[string]json.RawMessage, len(m))
+		errs []error
+	)
+
+	for _, mm := range m {
+		key := Key(mm)
+
+		if err := mm.Update(); err != nil && err != ErrNoChange {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+
+		b, err := mm.MarshalJSON()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+
+		snap[key] = b
+	}
+
+	return snap, errors.Join(errs...)
+}
@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/payload"
+	"github.com/lone-faerie/mqttop/procfs"
+)
+
+var systemNow = time.Now
+
+// System implements the [Metric] interface to provide the uptime, boot time
+// and load averages of the system.
+type System struct {
+	proc procfs.FS
+	prec int // precision of the load averages, see [places]
+
+	uptime int64 // seconds
+	boot   int64 // Unix seconds
+	load   procfs.LoadAvg
+
+	interval time.Duration
+	tick     *time.Ticker
+	topic    string
+
+	mu   sync.RWMutex
+	once sync.Once
+	stop context.CancelFunc
+	ch   chan error
+}
+
+// NewSystem returns a new [System] initialized from cfg. If the uptime or load
+// averages can't be read from /proc, a non-nil error that wraps
+// [ErrNotSupported] is returned.
+func NewSystem(cfg *config.Config) (*System, error) {
+	return NewSystemFromConfig(cfg.System, DefaultsOf(cfg))
+}
+
+// NewSystemFromConfig is like [NewSystem] but is initialized from the config
+// of the metric and d instead of a full [config.Config].
+func NewSystemFromConfig(cfg config.SystemConfig, d Defaults) (*System, error) {
+	s := &System{
+		proc: procfs.NewFS(d.Root),
+		prec: d.precision(cfg.Precision),
+	}
+
+	uptime, err := s.proc.Uptime()
+	if err != nil {
+		return nil, errNotSupported(s.Type(), err)
+	}
+
+	if _, err := s.proc.LoadAvg(); err != nil {
+		return nil, errNotSupported(s.Type(), err)
+	}
+
+	// The boot time doesn't change, so it's only read once. It's derived from
+	// the uptime if /proc/stat has no btime.
+	if boot, err := s.proc.BootTime(); err == nil {
+		s.boot = boot.Unix()
+	} else {
+		log.Debug("Unable to read boot time", "err", err)
+		s.boot = systemNow().Add(-uptime).Unix()
+	}
+
+	if cfg.Interval > 0 {
+		s.interval = cfg.Interval
+	} else {
+		s.interval = d.Interval
+	}
+
+	if cfg.Topic != "" {
+		s.topic = cfg.Topic
+	} else if d.BaseTopic != "" {
+		s.topic = d.BaseTopic + "/metric/system"
+	} else {
+		s.topic = "mqttop/metric/system"
+	}
+
+	return s, nil
+}
+
+// Type returns the metric type, "system".
+func (*System) Type() string {
+	return "system"
+}
+
+// Topic returns the topic to publish system metrics to.
+func (s *System) Topic() string {
+	return s.topic
+}
+
+// SetInterval sets the update interval for the metric.
+func (s *System) SetInterval(d time.Duration) {
+	s.mu.Lock()
+
+	if s.tick != nil && d != s.interval {
+		s.tick.Reset(d)
+	}
+
+	s.interval = d
+
+	s.mu.Unlock()
+}
+
+// Interval returns the update interval of the metric.
+func (s *System) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.interval
+}
+
+func (s *System) loop(ctx context.Context) {
+	defer recoverLoop(s.Type())
+
+	s.mu.Lock()
+	s.tick = time.NewTicker(s.interval)
+	s.mu.Unlock()
+
+	defer s.tick.Stop()
+	defer close(s.ch)
+
+	var (
+		err error
+		ch  chan error
+	)
+
+	log.Debug("system started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tick.C:
+			err = s.Update()
+			if err == ErrNoChange {
+				log.Debug("system updated, no change")
+			} else {
+				log.Debug("system updated")
+			}
+
+			ch = s.ch
+		case ch <- err:
+			ch = nil
+		}
+	}
+}
+
+// Start starts the system updating. If ctx is cancelled or
+// times out, the metric will stop.
+func (s *System) Start(ctx context.Context) (err error) {
+	if s.interval == 0 {
+		log.Warn("System interval is 0, not starting")
+		return
+	}
+
+	s.once.Do(func() {
+		ctx, s.stop = context.WithCancel(ctx)
+		s.ch = make(chan error)
+
+		go s.loop(ctx)
+	})
+
+	return
+}
+
+// Update forces the system metric to update. The returned error will not
+// be sent on the channel returned by [System.Updated] unlike updates that
+// happen automatically every update interval.
+func (s *System) Update() (err error) {
+	defer errUpdate(s.Type(), time.Now(), &err)
+
+	uptime, err := s.proc.Uptime()
+	if err != nil {
+		return err
+	}
+
+	load, err := s.proc.LoadAvg()
+	if err != nil {
+		return err
+	}
+
+	sec := int64(uptime / time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sec == s.uptime && load == s.load {
+		return ErrNoChange
+	}
+
+	s.uptime = sec
+	s.load = load
+
+	return nil
+}
+
+// Updated returns the channel that updates will be sent on. A received value
+// of [ErrNoChange] indicates there were no changes between updates. Any other non-nil
+// error is the first error encountered during updating and indicates a failed update.
+func (s *System) Updated() <-chan error {
+	return s.ch
+}
+
+// Stop stops the System from continuing to update. Once stopped, the System
+// may not be restarted.
+func (s *System) Stop() {
+	s.mu.Lock()
+
+	if s.stop != nil {
+		s.stop()
+	}
+
+	s.mu.Unlock()
+}
+
+// String implements [fmt.Stringer] and returns the uptime and the 1 minute
+// load average.
+func (s *System) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	load, _ := payload.Milli(s.load.Load1).AppendText(nil)
+
+	return "up " + (time.Duration(s.uptime) * time.Second).String() + ", load " + string(load)
+}
+
+func (s *System) toPayload(p *payload.System) {
+	p.Uptime = s.uptime
+	p.BootTime = s.boot
+	p.Load1 = payload.Milli(s.load.Load1).Round(places(s.prec))
+	p.Load5 = payload.Milli(s.load.Load5).Round(places(s.prec))
+	p.Load15 = payload.Milli(s.load.Load15).Round(places(s.prec))
+}
+
+func (s *System) fromPayload(p *payload.System) {
+	s.uptime = p.Uptime
+	s.boot = p.BootTime
+	s.load = procfs.LoadAvg{
+		Load1:  int64(p.Load1),
+		Load5:  int64(p.Load5),
+		Load15: int64(p.Load15),
+	}
+}
+
+// AppendText implements [encoding/TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s *System) AppendText(b []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var p payload.System
+
+	s.toPayload(&p)
+
+	return p.AppendText(b)
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [System.AppendText](nil).
+func (s *System) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes the JSON-encoded
+// representation of system, as produced by [System.MarshalJSON], into s.
+func (s *System) UnmarshalJSON(data []byte) error {
+	var p payload.System
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.fromPayload(&p)
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestSystem(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/uptime":  "350735.47 234388.90\n",
+		"proc/loadavg": "0.02 1.50 12.05 1/497 11947\n",
+		"proc/stat":    "cpu  301854 612 111922 8979004 3552 2 3944 0 0 0\nbtime 1418183276\n",
+	})
+
+	d := Defaults{Root: testRoot(t, dir), Interval: time.Second}
+
+	s, err := NewSystemFromConfig(config.SystemConfig{}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "system", s.Type(); got != want {
+		t.Errorf("Type: want %q, got %q", want, got)
+	}
+	if want, got := "mqttop/metric/system", s.Topic(); got != want {
+		t.Errorf("Topic: want %q, got %q", want, got)
+	}
+
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(); err != ErrNoChange {
+		t.Errorf("Update: want %v, got %v", ErrNoChange, err)
+	}
+
+	b, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"uptime": 350735, "boot_time": 1418183276, "load1": 0.02, "load5": 1.5, "load15": 12.05}`
+	if string(b) != want {
+		t.Errorf("want %s, got %s", want, b)
+	}
+
+	var ss System
+	if err := json.Unmarshal(b, &ss); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ss.MarshalJSON(); string(b) != want {
+		t.Errorf("round trip: want %s, got %s", want, b)
+	}
+
+	// Without a btime, the boot time is derived from the uptime.
+	writeFiles(t, dir, map[string]string{"proc/stat": "cpu  301854 612 111922 8979004 3552 2 3944 0 0 0\n"})
+
+	fn := systemNow
+	t.Cleanup(func() {
+		systemNow = fn
+	})
+
+	systemNow = func() time.Time { return time.Unix(1418534011, 470e6) }
+
+	if s, err = NewSystemFromConfig(config.SystemConfig{}, d); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := int64(1418183276), s.boot; got != want {
+		t.Errorf("boot: want %v, got %v", want, got)
+	}
+
+	if _, err := NewSystemFromConfig(config.SystemConfig{}, Defaults{Root: testRoot(t, t.TempDir())}); err == nil {
+		t.Error("no /proc: want error")
+	}
+}
@@ -62,6 +62,10 @@ func (c *MockClient) Publish(topic string, qos byte, retained bool, payload inte
 	case string:
 		p = json.RawMessage(v)
 	}
+	if !json.Valid(p) {
+		// Encode payloads that aren't JSON, such as an empty payload, as a string
+		p, _ = json.Marshal(string(p))
+	}
 	e := json.NewEncoder(c.w)
 	e.SetIndent("", "  ")
 	err := e.Encode(map[string]json.RawMessage{topic: p})
@@ -0,0 +1,50 @@
+package payload
+
+// Aggregate is the rolling average and maximum of a value over the last 1, 5,
+// and 15 minutes, in the same unit as the value. A window that is not yet full
+// is aggregated over the values so far.
+type Aggregate struct {
+	Avg1m  Milli `json:"avg_1m"`
+	Max1m  Milli `json:"max_1m"`
+	Avg5m  Milli `json:"avg_5m"`
+	Max5m  Milli `json:"max_5m"`
+	Avg15m Milli `json:"avg_15m"`
+	Max15m Milli `json:"max_15m"`
+}
+
+// Round returns a with each value rounded to prec places after the decimal
+// point. See [Milli.Round].
+func (a Aggregate) Round(prec int) Aggregate {
+	return Aggregate{
+		Avg1m:  a.Avg1m.Round(prec),
+		Max1m:  a.Max1m.Round(prec),
+		Avg5m:  a.Avg5m.Round(prec),
+		Max5m:  a.Max5m.Round(prec),
+		Avg15m: a.Avg15m.Round(prec),
+		Max15m: a.Max15m.Round(prec),
+	}
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of a to b.
+func (a Aggregate) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"avg_1m\": "...)
+	b, _ = a.Avg1m.AppendText(b)
+	b = append(b, ", \"max_1m\": "...)
+	b, _ = a.Max1m.AppendText(b)
+	b = append(b, ", \"avg_5m\": "...)
+	b, _ = a.Avg5m.AppendText(b)
+	b = append(b, ", \"max_5m\": "...)
+	b, _ = a.Max5m.AppendText(b)
+	b = append(b, ", \"avg_15m\": "...)
+	b, _ = a.Avg15m.AppendText(b)
+	b = append(b, ", \"max_15m\": "...)
+	b, _ = a.Max15m.AppendText(b)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Aggregate.AppendText](nil).
+func (a Aggregate) MarshalJSON() ([]byte, error) {
+	return a.AppendText(nil)
+}
@@ -0,0 +1,58 @@
+package payload
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Audio is the payload of the audio metric. The media fields are only included
+// if there is a media player, and each is omitted if the player doesn't report it.
+type Audio struct {
+	// Volume is the volume of the default output as a percent.
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+	// Player is the name of the MPRIS media player.
+	Player string `json:"player,omitempty"`
+	// Status is the playback status of the player, either "playing", "paused",
+	// or "stopped".
+	Status string `json:"status,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Album  string `json:"album,omitempty"`
+}
+
+func appendField(b []byte, key, val string) []byte {
+	if val == "" {
+		return b
+	}
+
+	b = append(b, ", \""...)
+	b = append(b, key...)
+	b = append(b, "\": "...)
+	// Media metadata may contain any characters, so unlike other string fields
+	// it needs to be escaped.
+	q, _ := json.Marshal(val)
+
+	return append(b, q...)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of a to b.
+func (a Audio) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"volume\": "...)
+	b = strconv.AppendInt(b, int64(a.Volume), 10)
+	b = append(b, ", \"muted\": "...)
+	b = strconv.AppendBool(b, a.Muted)
+	b = appendField(b, "player", a.Player)
+	b = appendField(b, "status", a.Status)
+	b = appendField(b, "artist", a.Artist)
+	b = appendField(b, "title", a.Title)
+	b = appendField(b, "album", a.Album)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Audio.AppendText](nil).
+func (a Audio) MarshalJSON() ([]byte, error) {
+	return a.AppendText(nil)
+}
@@ -0,0 +1,71 @@
+package payload
+
+import "strconv"
+
+// Battery is the payload of the battery metric.
+type Battery struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Capacity is the capacity of the battery as a percent.
+	Capacity Optional[int] `json:"capacity,omitzero"`
+	// Power is the power draw of the battery in W.
+	Power Optional[Micro] `json:"power,omitzero"`
+	// Voltage is the voltage of the battery in V.
+	Voltage Optional[Micro] `json:"voltage,omitzero"`
+	// Current is the current of the battery in A, which is negative while
+	// discharging on some systems.
+	Current Optional[Micro] `json:"current,omitzero"`
+	// TimeRemaining is the estimated time remaining of the battery in seconds
+	// while discharging.
+	TimeRemaining Optional[int64] `json:"timeRemaining,omitzero"`
+	// TimeToFull is the estimated time until the battery is full in seconds
+	// while charging.
+	TimeToFull Optional[int64] `json:"timeToFull,omitzero"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of bat to b.
+func (bat Battery) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"kind\": \""...)
+	b = append(b, bat.Kind...)
+	b = append(b, "\", \"status\": \""...)
+	b = append(b, bat.Status...)
+	b = append(b, '"')
+
+	if bat.Capacity.Valid {
+		b = append(b, ", \"capacity\": "...)
+		b = strconv.AppendInt(b, int64(bat.Capacity.Value), 10)
+	}
+
+	if bat.Power.Valid {
+		b = append(b, ", \"power\": "...)
+		b, _ = bat.Power.Value.AppendText(b)
+	}
+
+	if bat.Voltage.Valid {
+		b = append(b, ", \"voltage\": "...)
+		b, _ = bat.Voltage.Value.AppendText(b)
+	}
+
+	if bat.Current.Valid {
+		b = append(b, ", \"current\": "...)
+		b, _ = bat.Current.Value.AppendText(b)
+	}
+
+	if bat.TimeRemaining.Valid {
+		b = append(b, ", \"timeRemaining\": "...)
+		b = strconv.AppendInt(b, bat.TimeRemaining.Value, 10)
+	}
+
+	if bat.TimeToFull.Valid {
+		b = append(b, ", \"timeToFull\": "...)
+		b = strconv.AppendInt(b, bat.TimeToFull.Value, 10)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Battery.AppendText](nil).
+func (bat Battery) MarshalJSON() ([]byte, error) {
+	return bat.AppendText(nil)
+}
@@ -0,0 +1,269 @@
+package payload
+
+import "strconv"
+
+// CPU is the payload of the cpu metric.
+type CPU struct {
+	Name string `json:"name"`
+	// Temperature is the selected temperature of the CPU in °C.
+	Temperature Optional[Milli] `json:"temperature,omitzero"`
+	// TemperatureAggregate is the rolling aggregate of Temperature.
+	TemperatureAggregate Optional[Aggregate] `json:"temperature_aggregate,omitzero"`
+	// Frequency is the selected frequency of the CPU in GHz.
+	Frequency Optional[Micro] `json:"frequency,omitzero"`
+	// SelectionMode is the mode used to select the temperature and frequency
+	// of the CPU from its cores.
+	SelectionMode string `json:"selection_mode,omitempty"`
+	// Usage is the usage of the CPU as a percent, averaged since the last
+	// update.
+	Usage Optional[int] `json:"usage,omitzero"`
+	// UsageMin is the minimum usage of the CPU sampled since the last update.
+	UsageMin Optional[int] `json:"usage_min,omitzero"`
+	// UsageMax is the maximum usage of the CPU sampled since the last update.
+	UsageMax Optional[int] `json:"usage_max,omitzero"`
+	// UsageAggregate is the rolling aggregate of Usage.
+	UsageAggregate Optional[Aggregate] `json:"usage_aggregate,omitzero"`
+	// Boost indicates whether frequency boost (turbo) is enabled.
+	Boost Optional[bool] `json:"boost,omitzero"`
+	// Governor is the scaling governor of the first core.
+	Governor string `json:"governor,omitempty"`
+	// Cores are the payloads of the reported cores, or nil if per-core data
+	// is disabled, in which case they are omitted.
+	Cores []Core `json:"cores,omitempty"`
+}
+
+// Core is the payload of a single core of [CPU].
+type Core struct {
+	// ID is the logical id of the core, which is unique among the cores.
+	ID int `json:"id"`
+	// Core is the physical id of the core, which is shared by the logical
+	// cores of the same physical core, such as with hyper-threading.
+	Core int `json:"core"`
+	// Temperature is the temperature of the core in °C.
+	Temperature Optional[Milli] `json:"temperature,omitzero"`
+	// Frequency is the frequency of the core in GHz.
+	Frequency Optional[Micro] `json:"frequency,omitzero"`
+	// BaseFrequency is the base frequency of the core in GHz.
+	BaseFrequency Optional[Micro] `json:"base_frequency,omitzero"`
+	// MinFrequency is the minimum scaling frequency of the core in GHz.
+	MinFrequency Optional[Micro] `json:"min_frequency,omitzero"`
+	// MaxFrequency is the maximum scaling frequency of the core in GHz.
+	MaxFrequency Optional[Micro] `json:"max_frequency,omitzero"`
+	// Usage is the usage of the core as a percent.
+	Usage Optional[int] `json:"usage,omitzero"`
+}
+
+// CPUInfo is the payload of the static information of the cpu metric, which is
+// published retained to the "/info" subtopic of the metric.
+type CPUInfo struct {
+	Vendor   string `json:"vendor"`
+	Model    string `json:"model"`
+	Family   int    `json:"family"`
+	ModelID  int    `json:"model_id"`
+	Stepping int    `json:"stepping"`
+	// CacheSize is the size of the cache in KiB.
+	CacheSize int `json:"cache_size"`
+	Sockets   int `json:"sockets"`
+	Cores     int `json:"cores"`
+	Threads   int `json:"threads"`
+	// Virtualization is the hardware virtualization extension supported by
+	// the CPU, either "VT-x" or "AMD-V".
+	Virtualization string `json:"virtualization,omitempty"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of c to b.
+func (c CPUInfo) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"vendor\": \""...)
+	b = append(b, c.Vendor...)
+	b = append(b, "\", \"model\": \""...)
+	b = append(b, c.Model...)
+	b = append(b, "\", \"family\": "...)
+	b = strconv.AppendInt(b, int64(c.Family), 10)
+	b = append(b, ", \"model_id\": "...)
+	b = strconv.AppendInt(b, int64(c.ModelID), 10)
+	b = append(b, ", \"stepping\": "...)
+	b = strconv.AppendInt(b, int64(c.Stepping), 10)
+	b = append(b, ", \"cache_size\": "...)
+	b = strconv.AppendInt(b, int64(c.CacheSize), 10)
+	b = append(b, ", \"sockets\": "...)
+	b = strconv.AppendInt(b, int64(c.Sockets), 10)
+	b = append(b, ", \"cores\": "...)
+	b = strconv.AppendInt(b, int64(c.Cores), 10)
+	b = append(b, ", \"threads\": "...)
+	b = strconv.AppendInt(b, int64(c.Threads), 10)
+
+	if c.Virtualization != "" {
+		b = append(b, ", \"virtualization\": \""...)
+		b = append(b, c.Virtualization...)
+		b = append(b, '"')
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [CPUInfo.AppendText](nil).
+func (c CPUInfo) MarshalJSON() ([]byte, error) {
+	return c.AppendText(nil)
+}
+
+// SelectionMode is the payload of the selection mode of the cpu metric, which
+// is published retained to the "/selection_mode" subtopic of the metric.
+type SelectionMode struct {
+	// Mode is the current selection mode.
+	Mode string `json:"selection_mode"`
+	// Options are the valid selection modes.
+	Options []string `json:"options"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s SelectionMode) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"selection_mode\": \""...)
+	b = append(b, s.Mode...)
+	b = append(b, "\", \"options\": ["...)
+
+	for i := range s.Options {
+		b = append(b, '"')
+		b = append(b, s.Options[i]...)
+		b = append(b, '"')
+
+		if i < len(s.Options)-1 {
+			b = append(b, ',', ' ')
+		}
+	}
+
+	return append(b, ']', '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [SelectionMode.AppendText](nil).
+func (s SelectionMode) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of c to b.
+func (c Core) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"id\": "...)
+	b = strconv.AppendInt(b, int64(c.ID), 10)
+	b = append(b, ", \"core\": "...)
+	b = strconv.AppendInt(b, int64(c.Core), 10)
+
+	if c.Temperature.Valid {
+		b = append(b, ", \"temperature\": "...)
+		b, _ = c.Temperature.Value.AppendText(b)
+	}
+
+	if c.Frequency.Valid {
+		b = append(b, ", \"frequency\": "...)
+		b, _ = c.Frequency.Value.AppendText(b)
+	}
+
+	if c.BaseFrequency.Valid {
+		b = append(b, ", \"base_frequency\": "...)
+		b, _ = c.BaseFrequency.Value.AppendText(b)
+	}
+
+	if c.MinFrequency.Valid {
+		b = append(b, ", \"min_frequency\": "...)
+		b, _ = c.MinFrequency.Value.AppendText(b)
+	}
+
+	if c.MaxFrequency.Valid {
+		b = append(b, ", \"max_frequency\": "...)
+		b, _ = c.MaxFrequency.Value.AppendText(b)
+	}
+
+	if c.Usage.Valid {
+		b = append(b, ", \"usage\": "...)
+		b = strconv.AppendInt(b, int64(c.Usage.Value), 10)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Core.AppendText](nil).
+func (c Core) MarshalJSON() ([]byte, error) {
+	return c.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of c to b.
+func (c CPU) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"name\": \""...)
+	b = append(b, c.Name...)
+	b = append(b, '"')
+
+	if c.Temperature.Valid {
+		b = append(b, ", \"temperature\": "...)
+		b, _ = c.Temperature.Value.AppendText(b)
+	}
+
+	if c.TemperatureAggregate.Valid {
+		b = append(b, ", \"temperature_aggregate\": "...)
+		b, _ = c.TemperatureAggregate.Value.AppendText(b)
+	}
+
+	if c.Frequency.Valid {
+		b = append(b, ", \"frequency\": "...)
+		b, _ = c.Frequency.Value.AppendText(b)
+	}
+
+	if c.SelectionMode != "" {
+		b = append(b, ", \"selection_mode\": \""...)
+		b = append(b, c.SelectionMode...)
+		b = append(b, '"')
+	}
+
+	if c.Usage.Valid {
+		b = append(b, ", \"usage\": "...)
+		b = strconv.AppendInt(b, int64(c.Usage.Value), 10)
+	}
+
+	if c.UsageMin.Valid {
+		b = append(b, ", \"usage_min\": "...)
+		b = strconv.AppendInt(b, int64(c.UsageMin.Value), 10)
+	}
+
+	if c.UsageMax.Valid {
+		b = append(b, ", \"usage_max\": "...)
+		b = strconv.AppendInt(b, int64(c.UsageMax.Value), 10)
+	}
+
+	if c.UsageAggregate.Valid {
+		b = append(b, ", \"usage_aggregate\": "...)
+		b, _ = c.UsageAggregate.Value.AppendText(b)
+	}
+
+	if c.Boost.Valid {
+		b = append(b, ", \"boost\": "...)
+		b = strconv.AppendBool(b, c.Boost.Value)
+	}
+
+	if c.Governor != "" {
+		b = append(b, ", \"governor\": \""...)
+		b = append(b, c.Governor...)
+		b = append(b, '"')
+	}
+
+	if c.Cores == nil {
+		return append(b, '}'), nil
+	}
+
+	b = append(b, ", \"cores\": ["...)
+
+	for i := range c.Cores {
+		b, _ = c.Cores[i].AppendText(b)
+
+		if i < len(c.Cores)-1 {
+			b = append(b, ',', ' ')
+		}
+	}
+
+	return append(b, ']', '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [CPU.AppendText](nil).
+func (c CPU) MarshalJSON() ([]byte, error) {
+	return c.AppendText(nil)
+}
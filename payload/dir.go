@@ -0,0 +1,48 @@
+package payload
+
+import "strconv"
+
+// Dir is the payload of the dir metric, of a single directory.
+type Dir struct {
+	Path string `json:"path"`
+	Size Size   `json:"size"`
+	// Watched is the number of watched directories, if the directory is watched.
+	Watched Optional[int] `json:"watched,omitzero"`
+	// Polled is the number of subtrees that are polled instead of watched, if
+	// the directory is watched.
+	Polled Optional[int] `json:"polled,omitzero"`
+	// Reclaimed is the size of the files deleted by the last clean command, if
+	// the directory has been cleaned.
+	Reclaimed Optional[Size] `json:"reclaimed,omitzero"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of d to b.
+func (d Dir) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"path\": \""...)
+	b = append(b, d.Path...)
+	b = append(b, "\", \"size\": "...)
+	b, _ = d.Size.AppendText(b)
+
+	if d.Watched.Valid {
+		b = append(b, ", \"watched\": "...)
+		b = strconv.AppendInt(b, int64(d.Watched.Value), 10)
+	}
+
+	if d.Polled.Valid {
+		b = append(b, ", \"polled\": "...)
+		b = strconv.AppendInt(b, int64(d.Polled.Value), 10)
+	}
+
+	if d.Reclaimed.Valid {
+		b = append(b, ", \"reclaimed\": "...)
+		b, _ = d.Reclaimed.Value.AppendText(b)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Dir.AppendText](nil).
+func (d Dir) MarshalJSON() ([]byte, error) {
+	return d.AppendText(nil)
+}
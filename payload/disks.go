@@ -0,0 +1,120 @@
+package payload
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Disks is the payload of the disks metric, mapped by the unique name of each
+// disk. The disks are encoded in order of their names.
+type Disks map[string]Disk
+
+// Disk is the payload of a single disk of [Disks]. All sizes are scaled to
+// the configured size unit of the disk.
+type Disk struct {
+	Mnt   string `json:"mnt"`
+	Total Size   `json:"total"`
+	Free  Size   `json:"free"`
+	Used  Size   `json:"used"`
+	// Reads is the number of bytes read since the last update.
+	Reads Optional[int64] `json:"reads,omitzero"`
+	// Writes is the number of bytes written since the last update.
+	Writes Optional[int64] `json:"writes,omitzero"`
+	// ReadTotal is the total number of bytes read, which keeps increasing
+	// across restarts.
+	ReadTotal Optional[uint64] `json:"read_total,omitzero"`
+	// WriteTotal is the total number of bytes written, which keeps increasing
+	// across restarts.
+	WriteTotal Optional[uint64] `json:"write_total,omitzero"`
+	// DaysUntilFull is the predicted number of days until the disk is full,
+	// which is missing if the used space is not increasing.
+	DaysUntilFull Optional[Milli] `json:"days_until_full,omitzero"`
+	// ReadOnly indicates if the filesystem is mounted read-only, such as
+	// after it was remounted because of errors.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// Stale indicates if the filesystem stopped responding, in which case
+	// the sizes are of the last update it responded to.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of d to b.
+func (d Disk) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"mnt\": \""...)
+	b = append(b, d.Mnt...)
+	b = append(b, "\", \"total\": "...)
+	b, _ = d.Total.AppendText(b)
+	b = append(b, ", \"free\": "...)
+	b, _ = d.Free.AppendText(b)
+	b = append(b, ", \"used\": "...)
+	b, _ = d.Used.AppendText(b)
+
+	if d.Reads.Valid {
+		b = append(b, ", \"reads\": "...)
+		b = strconv.AppendInt(b, d.Reads.Value, 10)
+	}
+
+	if d.Writes.Valid {
+		b = append(b, ", \"writes\": "...)
+		b = strconv.AppendInt(b, d.Writes.Value, 10)
+	}
+
+	if d.ReadTotal.Valid {
+		b = append(b, ", \"read_total\": "...)
+		b = strconv.AppendUint(b, d.ReadTotal.Value, 10)
+	}
+
+	if d.WriteTotal.Valid {
+		b = append(b, ", \"write_total\": "...)
+		b = strconv.AppendUint(b, d.WriteTotal.Value, 10)
+	}
+
+	if d.DaysUntilFull.Valid {
+		b = append(b, ", \"days_until_full\": "...)
+		b, _ = d.DaysUntilFull.Value.AppendText(b)
+	}
+
+	if d.ReadOnly {
+		b = append(b, ", \"read_only\": true"...)
+	}
+
+	if d.Stale {
+		b = append(b, ", \"stale\": true"...)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Disk.AppendText](nil).
+func (d Disk) MarshalJSON() ([]byte, error) {
+	return d.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of d to b.
+func (d Disks) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	first := true
+
+	for _, name := range slices.Sorted(maps.Keys(d)) {
+		if !first {
+			b = append(b, ',', ' ')
+		}
+
+		b = append(b, '"')
+		b = append(b, name...)
+		b = append(b, '"', ':', ' ')
+		b, _ = d[name].AppendText(b)
+
+		first = false
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Disks.AppendText](nil).
+func (d Disks) MarshalJSON() ([]byte, error) {
+	return d.AppendText(nil)
+}
@@ -0,0 +1,58 @@
+package payload
+
+// Energy is the payload of the energy metric, mapped by the name of each RAPL
+// zone, such as "package-0" or "package-0_core".
+type Energy map[string]EnergyZone
+
+// EnergyZone is the payload of a single zone of [Energy].
+type EnergyZone struct {
+	// Power is the average power of the zone since the last update in W.
+	Power Micro `json:"power"`
+	// Energy is the energy used by the zone in kWh, which is accumulated from
+	// when the metric is started.
+	Energy Milli `json:"energy"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of z to b.
+func (z EnergyZone) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"power\": "...)
+	b, _ = z.Power.AppendText(b)
+	b = append(b, ", \"energy\": "...)
+	b, _ = z.Energy.AppendText(b)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [EnergyZone.AppendText](nil).
+func (z EnergyZone) MarshalJSON() ([]byte, error) {
+	return z.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of e to b.
+func (e Energy) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	first := true
+
+	for name, z := range e {
+		if !first {
+			b = append(b, ',', ' ')
+		}
+
+		b = append(b, '"')
+		b = append(b, name...)
+		b = append(b, '"', ':', ' ')
+		b, _ = z.AppendText(b)
+
+		first = false
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Energy.AppendText](nil).
+func (e Energy) MarshalJSON() ([]byte, error) {
+	return e.AppendText(nil)
+}
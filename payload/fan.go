@@ -0,0 +1,72 @@
+package payload
+
+import "strconv"
+
+// Fans is the payload of the fans metric, mapped by the ID of each fan.
+type Fans map[string]Fan
+
+// Fan is the payload of a single fan of [Fans].
+type Fan struct {
+	Label string `json:"label"`
+	// Speed is the speed of the fan in RPM.
+	Speed int64 `json:"speed"`
+	// PWM is the duty cycle of the fan from 0 to 255, if it has PWM control.
+	PWM Optional[int] `json:"pwm,omitzero"`
+	// Mode is the PWM mode of the fan, either "full", "manual", or "auto".
+	Mode string `json:"mode,omitempty"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of f to b.
+func (f Fan) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"label\": \""...)
+	b = append(b, f.Label...)
+	b = append(b, "\", \"speed\": "...)
+	b = strconv.AppendInt(b, f.Speed, 10)
+
+	if f.PWM.Valid {
+		b = append(b, ", \"pwm\": "...)
+		b = strconv.AppendInt(b, int64(f.PWM.Value), 10)
+	}
+
+	if f.Mode != "" {
+		b = append(b, ", \"mode\": \""...)
+		b = append(b, f.Mode...)
+		b = append(b, '"')
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Fan.AppendText](nil).
+func (f Fan) MarshalJSON() ([]byte, error) {
+	return f.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of fans to b.
+func (fans Fans) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	first := true
+
+	for id, f := range fans {
+		if !first {
+			b = append(b, ',', ' ')
+		}
+
+		b = append(b, '"')
+		b = append(b, id...)
+		b = append(b, '"', ':', ' ')
+		b, _ = f.AppendText(b)
+
+		first = false
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Fans.AppendText](nil).
+func (fans Fans) MarshalJSON() ([]byte, error) {
+	return fans.AppendText(nil)
+}
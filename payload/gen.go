@@ -0,0 +1,362 @@
+//go:build ignore
+
+// This program generates schema_gen.go, which has the JSON Schema of the payload
+// of each metric. Run it with go generate after changing any payload struct.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// schemaDraft is the dialect of the generated schemas.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// metricDoc matches the doc comment of the payload of a metric, such as
+// "CPU is the payload of the cpu metric."
+var metricDoc = regexp.MustCompile(`^\w+ is the payload of the (\w+) metric\b`)
+
+// docLink matches doc links, such as [Config] or [encoding.TextAppender].
+var docLink = regexp.MustCompile(`\[([\w.*]+)\]`)
+
+// docText returns the text of doc with doc links replaced by their names, and
+// lines joined so that only paragraphs are separated.
+func docText(doc *ast.CommentGroup) string {
+	text := strings.TrimSpace(docLink.ReplaceAllString(doc.Text(), "$1"))
+
+	paragraphs := strings.Split(text, "\n\n")
+	for i, p := range paragraphs {
+		paragraphs[i] = strings.ReplaceAll(p, "\n", " ")
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// schema is a JSON Schema. Properties are encoded in the order of the fields.
+type schema struct {
+	Schema               string      `json:"$schema,omitempty"`
+	Title                string      `json:"title,omitempty"`
+	Description          string      `json:"description,omitempty"`
+	Ref                  string      `json:"$ref,omitempty"`
+	Type                 string      `json:"type,omitempty"`
+	Minimum              *int        `json:"minimum,omitempty"`
+	Items                *schema     `json:"items,omitempty"`
+	Properties           *properties `json:"properties,omitempty"`
+	AdditionalProperties *schema     `json:"additionalProperties,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+	Defs                 *properties `json:"$defs,omitempty"`
+}
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+type properties []property
+
+func (p *properties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteByte('{')
+
+	for i, prop := range *p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		v, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(strconv.Quote(prop.name))
+		b.WriteByte(':')
+		b.Write(v)
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+var zero = 0
+
+type generator struct {
+	types   map[string]ast.Expr
+	docs    map[string]string
+	metrics map[string]string
+	buf     bytes.Buffer
+
+	// defs are the structs referenced by the schema being generated.
+	defs []string
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) parse(dir string) error {
+	fset := token.NewFileSet()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == "gen.go" || name == "schema_gen.go" {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range decl.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !ts.Name.IsExported() || ts.TypeParams != nil {
+					continue
+				}
+
+				g.types[ts.Name.Name] = ts.Type
+
+				doc := ts.Doc
+				if doc == nil && len(decl.Specs) == 1 {
+					doc = decl.Doc
+				}
+
+				if doc == nil {
+					continue
+				}
+
+				g.docs[ts.Name.Name] = docText(doc)
+
+				if m := metricDoc.FindStringSubmatch(g.docs[ts.Name.Name]); m != nil {
+					g.metrics[m[1]] = ts.Name.Name
+				}
+			}
+		}
+	}
+
+	if len(g.metrics) == 0 {
+		return fmt.Errorf("no metric payloads in %s", dir)
+	}
+
+	return nil
+}
+
+// schemaOf returns the schema of typ. Structs are referenced by their name in
+// the definitions of the schema, and Optional is the schema of its value
+// since invalid values are omitted. Unknown types are fatal so that new fields
+// aren't silently skipped.
+func (g *generator) schemaOf(typ ast.Expr) *schema {
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		switch typ.Name {
+		case "string":
+			return &schema{Type: "string"}
+		case "bool":
+			return &schema{Type: "boolean"}
+		case "int", "int8", "int16", "int32", "int64":
+			return &schema{Type: "integer"}
+		case "uint", "uint8", "uint16", "uint32", "uint64":
+			return &schema{Type: "integer", Minimum: &zero}
+		case "Milli", "Micro":
+			return &schema{Type: "number"}
+		case "Size":
+			return &schema{Type: "number", Minimum: &zero}
+		}
+
+		switch def := g.types[typ.Name].(type) {
+		case *ast.StructType:
+			if !slices.Contains(g.defs, typ.Name) {
+				g.defs = append(g.defs, typ.Name)
+			}
+
+			return &schema{Ref: "#/$defs/" + typ.Name}
+		case *ast.MapType:
+			return g.schemaOf(def)
+		}
+	case *ast.SelectorExpr:
+		switch exprString(typ) {
+		case "netip.Addr", "netip.AddrPort":
+			return &schema{Type: "string"}
+		}
+	case *ast.IndexExpr:
+		if id, ok := typ.X.(*ast.Ident); ok && id.Name == "Optional" {
+			return g.schemaOf(typ.Index)
+		}
+	case *ast.ArrayType:
+		return &schema{Type: "array", Items: g.schemaOf(typ.Elt)}
+	case *ast.MapType:
+		if id, ok := typ.Key.(*ast.Ident); ok && id.Name == "string" {
+			return &schema{Type: "object", AdditionalProperties: g.schemaOf(typ.Value)}
+		}
+	}
+
+	log.Fatalf("unsupported field type %s", exprString(typ))
+
+	return nil
+}
+
+// structSchema returns the schema of the struct typ. Fields that are omitted
+// if empty or zero are not required.
+func (g *generator) structSchema(name string, typ *ast.StructType) *schema {
+	s := &schema{Type: "object", Description: g.docs[name], Properties: new(properties)}
+
+	for _, f := range typ.Fields.List {
+		var key, opts string
+
+		if f.Tag != nil {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			key, opts, _ = strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+		}
+
+		if key == "-" {
+			continue
+		}
+
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+
+			key := key
+			if key == "" {
+				key = n.Name
+			}
+
+			prop := g.schemaOf(f.Type)
+
+			// Keywords beside $ref are allowed since draft 2019-09
+			if f.Doc != nil {
+				prop.Description = docText(f.Doc)
+			}
+
+			*s.Properties = append(*s.Properties, property{key, prop})
+
+			omit := strings.Split(opts, ",")
+			if !slices.Contains(omit, "omitempty") && !slices.Contains(omit, "omitzero") {
+				s.Required = append(s.Required, key)
+			}
+		}
+	}
+
+	return s
+}
+
+// metricSchema returns the encoded schema of the payload typ of metric.
+func (g *generator) metricSchema(metric, typ string) ([]byte, error) {
+	g.defs = g.defs[:0]
+
+	var s *schema
+
+	switch def := g.types[typ].(type) {
+	case *ast.StructType:
+		s = g.structSchema(typ, def)
+	default:
+		s = g.schemaOf(def)
+		s.Description = g.docs[typ]
+	}
+
+	s.Schema = schemaDraft
+	s.Title = metric
+
+	if len(g.defs) > 0 {
+		s.Defs = new(properties)
+	}
+
+	// Definitions may reference more definitions
+	for i := 0; i < len(g.defs); i++ {
+		name := g.defs[i]
+		*s.Defs = append(*s.Defs, property{name, g.structSchema(name, g.types[name].(*ast.StructType))})
+	}
+
+	return json.MarshalIndent(s, "", "\t")
+}
+
+func exprString(typ ast.Expr) string {
+	var b bytes.Buffer
+
+	format.Node(&b, token.NewFileSet(), typ)
+
+	return b.String()
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.printf("// Code generated by \"go run gen.go\"; DO NOT EDIT.\n\n")
+	g.printf("package payload\n\n")
+
+	g.printf("// schemas are the JSON Schemas of the payload of each metric, by the type of\n")
+	g.printf("// the metric.\n")
+	g.printf("var schemas = map[string]string{\n")
+
+	metrics := make([]string, 0, len(g.metrics))
+	for metric := range g.metrics {
+		metrics = append(metrics, metric)
+	}
+
+	slices.Sort(metrics)
+
+	for _, metric := range metrics {
+		b, err := g.metricSchema(metric, g.metrics[metric])
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.ContainsRune(b, '`') {
+			g.printf("%q: %q,\n", metric, b)
+		} else {
+			g.printf("%q: `%s`,\n", metric, b)
+		}
+	}
+
+	g.printf("}\n")
+
+	return format.Source(g.buf.Bytes())
+}
+
+func main() {
+	output := flag.String("output", "schema_gen.go", "output file")
+	flag.Parse()
+
+	g := &generator{
+		types:   make(map[string]ast.Expr),
+		docs:    make(map[string]string),
+		metrics: make(map[string]string),
+	}
+
+	if err := g.parse("."); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
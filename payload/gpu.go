@@ -0,0 +1,234 @@
+package payload
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// GPU is the payload of the gpu metric.
+type GPU struct {
+	Name string `json:"name"`
+	// Rx is the PCIe receive throughput of the GPU in KB/s.
+	Rx Optional[uint32] `json:"rx,omitzero"`
+	// Tx is the PCIe transmit throughput of the GPU in KB/s.
+	Tx          Optional[uint32]         `json:"tx,omitzero"`
+	Utilization Optional[GPUUtilization] `json:"utilization,omitzero"`
+	// UtilizationAggregate is the rolling aggregate of the GPU utilization.
+	UtilizationAggregate Optional[Aggregate] `json:"utilizationAggregate,omitzero"`
+	// Clock is the graphics clock of the GPU in MHz.
+	Clock Optional[uint32] `json:"clock,omitzero"`
+	// MemClock is the memory clock of the GPU in MHz.
+	MemClock Optional[uint32] `json:"memClock,omitzero"`
+	// RC6 is the percent of time the GPU was in RC6, its idle power state.
+	RC6 Optional[uint32] `json:"rc6,omitzero"`
+	// Engines is the utilization of each engine class of the GPU as percents.
+	Engines map[string]uint32 `json:"engines,omitempty"`
+	// Power is the power usage of the GPU in W.
+	Power Optional[Milli] `json:"power,omitzero"`
+	// MaxPower is the power limit of the GPU in W.
+	MaxPower Optional[Milli] `json:"maxPower,omitzero"`
+	// Persistence indicates if persistence mode is enabled.
+	Persistence Optional[bool] `json:"persistence,omitzero"`
+	// Temperature is the temperature of the GPU in °C.
+	Temperature Optional[uint32] `json:"temperature,omitzero"`
+	// TemperatureAggregate is the rolling aggregate of Temperature.
+	TemperatureAggregate Optional[Aggregate] `json:"temperatureAggregate,omitzero"`
+	// MaxTemp is the slowdown temperature of the GPU in °C.
+	MaxTemp Optional[uint32]    `json:"maxTemp,omitzero"`
+	Memory  Optional[GPUMemory] `json:"memory,omitzero"`
+	// Summary is the summary across all of the GPUs, if enabled.
+	Summary Optional[GPUSummary] `json:"summary,omitzero"`
+}
+
+// GPUUtilization is the utilization of a [GPU] as percents.
+type GPUUtilization struct {
+	GPU    uint32 `json:"gpu"`
+	Memory uint32 `json:"memory"`
+}
+
+// GPUMemory is the memory of a [GPU]. All sizes are scaled to the configured
+// size unit of the GPU.
+type GPUMemory struct {
+	Total Size `json:"total"`
+	Free  Size `json:"free"`
+	Used  Size `json:"used"`
+}
+
+// GPUSummary is the summary across the GPUs, as the sum or maximum of the
+// values of each [GPU]. A value is only valid if it is valid for any GPU.
+type GPUSummary struct {
+	// Count is the number of GPUs summarized.
+	Count int `json:"count"`
+	// MemoryUsed is the total memory used by the GPUs.
+	MemoryUsed Optional[Size] `json:"memoryUsed,omitzero"`
+	// MemoryTotal is the total memory of the GPUs.
+	MemoryTotal Optional[Size] `json:"memoryTotal,omitzero"`
+	// Temperature is the maximum temperature of the GPUs in °C.
+	Temperature Optional[uint32] `json:"temperature,omitzero"`
+	// Power is the total power usage of the GPUs in W.
+	Power Optional[Milli] `json:"power,omitzero"`
+}
+
+// Add adds g to the summary. The memory sizes of every GPU added must be
+// scaled to the same size unit.
+func (s *GPUSummary) Add(g *GPU) {
+	s.Count++
+
+	if m, ok := g.Memory.Get(); ok {
+		s.MemoryUsed = Some(s.MemoryUsed.Value + m.Used)
+		s.MemoryTotal = Some(s.MemoryTotal.Value + m.Total)
+	}
+
+	if t, ok := g.Temperature.Get(); ok && (!s.Temperature.Valid || t > s.Temperature.Value) {
+		s.Temperature = Some(t)
+	}
+
+	if p, ok := g.Power.Get(); ok {
+		s.Power = Some(s.Power.Value + p)
+	}
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s GPUSummary) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"count\": "...)
+	b = strconv.AppendInt(b, int64(s.Count), 10)
+
+	if s.MemoryUsed.Valid {
+		b = append(b, ", \"memoryUsed\": "...)
+		b, _ = s.MemoryUsed.Value.AppendText(b)
+	}
+
+	if s.MemoryTotal.Valid {
+		b = append(b, ", \"memoryTotal\": "...)
+		b, _ = s.MemoryTotal.Value.AppendText(b)
+	}
+
+	if s.Temperature.Valid {
+		b = append(b, ", \"temperature\": "...)
+		b = strconv.AppendUint(b, uint64(s.Temperature.Value), 10)
+	}
+
+	if s.Power.Valid {
+		b = append(b, ", \"power\": "...)
+		b, _ = s.Power.Value.AppendText(b)
+	}
+
+	return append(b, '}'), nil
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of g to b.
+func (g GPU) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"name\": \""...)
+	b = append(b, g.Name...)
+	b = append(b, '"')
+
+	if g.Rx.Valid {
+		b = append(b, ", \"rx\": "...)
+		b = strconv.AppendUint(b, uint64(g.Rx.Value), 10)
+	}
+
+	if g.Tx.Valid {
+		b = append(b, ", \"tx\": "...)
+		b = strconv.AppendUint(b, uint64(g.Tx.Value), 10)
+	}
+
+	if g.Utilization.Valid {
+		b = append(b, ", \"utilization\": {\"gpu\": "...)
+		b = strconv.AppendUint(b, uint64(g.Utilization.Value.GPU), 10)
+		b = append(b, ", \"memory\": "...)
+		b = strconv.AppendUint(b, uint64(g.Utilization.Value.Memory), 10)
+		b = append(b, '}')
+	}
+
+	if g.UtilizationAggregate.Valid {
+		b = append(b, ", \"utilizationAggregate\": "...)
+		b, _ = g.UtilizationAggregate.Value.AppendText(b)
+	}
+
+	if g.Clock.Valid {
+		b = append(b, ", \"clock\": "...)
+		b = strconv.AppendUint(b, uint64(g.Clock.Value), 10)
+	}
+
+	if g.MemClock.Valid {
+		b = append(b, ", \"memClock\": "...)
+		b = strconv.AppendUint(b, uint64(g.MemClock.Value), 10)
+	}
+
+	if g.RC6.Valid {
+		b = append(b, ", \"rc6\": "...)
+		b = strconv.AppendUint(b, uint64(g.RC6.Value), 10)
+	}
+
+	if len(g.Engines) > 0 {
+		b = append(b, ", \"engines\": {"...)
+
+		for i, class := range slices.Sorted(maps.Keys(g.Engines)) {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+
+			b = append(b, '"')
+			b = append(b, class...)
+			b = append(b, "\": "...)
+			b = strconv.AppendUint(b, uint64(g.Engines[class]), 10)
+		}
+
+		b = append(b, '}')
+	}
+
+	if g.Power.Valid {
+		b = append(b, ", \"power\": "...)
+		b, _ = g.Power.Value.AppendText(b)
+	}
+
+	if g.MaxPower.Valid {
+		b = append(b, ", \"maxPower\": "...)
+		b, _ = g.MaxPower.Value.AppendText(b)
+	}
+
+	if g.Persistence.Valid {
+		b = append(b, ", \"persistence\": "...)
+		b = strconv.AppendBool(b, g.Persistence.Value)
+	}
+
+	if g.Temperature.Valid {
+		b = append(b, ", \"temperature\": "...)
+		b = strconv.AppendUint(b, uint64(g.Temperature.Value), 10)
+	}
+
+	if g.TemperatureAggregate.Valid {
+		b = append(b, ", \"temperatureAggregate\": "...)
+		b, _ = g.TemperatureAggregate.Value.AppendText(b)
+	}
+
+	if g.MaxTemp.Valid {
+		b = append(b, ", \"maxTemp\": "...)
+		b = strconv.AppendUint(b, uint64(g.MaxTemp.Value), 10)
+	}
+
+	if g.Memory.Valid {
+		b = append(b, ", \"memory\": {\"total\": "...)
+		b, _ = g.Memory.Value.Total.AppendText(b)
+		b = append(b, ", \"free\": "...)
+		b, _ = g.Memory.Value.Free.AppendText(b)
+		b = append(b, ", \"used\": "...)
+		b, _ = g.Memory.Value.Used.AppendText(b)
+		b = append(b, '}')
+	}
+
+	if g.Summary.Valid {
+		b = append(b, ", \"summary\": "...)
+		b, _ = g.Summary.Value.AppendText(b)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [GPU.AppendText](nil).
+func (g GPU) MarshalJSON() ([]byte, error) {
+	return g.AppendText(nil)
+}
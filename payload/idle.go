@@ -0,0 +1,27 @@
+package payload
+
+import "strconv"
+
+// Idle is the payload of the idle metric.
+type Idle struct {
+	// Idle is how long the user has been idle in seconds.
+	Idle int64 `json:"idle"`
+	// Active indicates if the user has been idle for less than the threshold.
+	Active bool `json:"active"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of i to b.
+func (i Idle) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"idle\": "...)
+	b = strconv.AppendInt(b, i.Idle, 10)
+	b = append(b, ", \"active\": "...)
+	b = strconv.AppendBool(b, i.Active)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Idle.AppendText](nil).
+func (i Idle) MarshalJSON() ([]byte, error) {
+	return i.AppendText(nil)
+}
@@ -0,0 +1,46 @@
+package payload
+
+// Memory is the payload of the memory metric. All sizes are scaled to the
+// configured size unit, and the swap sizes are scaled to the configured swap
+// size unit.
+type Memory struct {
+	Total     Size           `json:"total"`
+	Used      Size           `json:"used"`
+	Available Size           `json:"available"`
+	Cached    Size           `json:"cached"`
+	Free      Size           `json:"free"`
+	SwapTotal Optional[Size] `json:"swapTotal,omitzero"`
+	SwapUsed  Optional[Size] `json:"swapUsed,omitzero"`
+	SwapFree  Optional[Size] `json:"swapFree,omitzero"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of m to b.
+func (m Memory) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"total\": "...)
+	b, _ = m.Total.AppendText(b)
+	b = append(b, ", \"used\": "...)
+	b, _ = m.Used.AppendText(b)
+	b = append(b, ", \"available\": "...)
+	b, _ = m.Available.AppendText(b)
+	b = append(b, ", \"cached\": "...)
+	b, _ = m.Cached.AppendText(b)
+	b = append(b, ", \"free\": "...)
+	b, _ = m.Free.AppendText(b)
+
+	if m.SwapTotal.Valid {
+		b = append(b, ", \"swapTotal\": "...)
+		b, _ = m.SwapTotal.Value.AppendText(b)
+		b = append(b, ", \"swapUsed\": "...)
+		b, _ = m.SwapUsed.Value.AppendText(b)
+		b = append(b, ", \"swapFree\": "...)
+		b, _ = m.SwapFree.Value.AppendText(b)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Memory.AppendText](nil).
+func (m Memory) MarshalJSON() ([]byte, error) {
+	return m.AppendText(nil)
+}
@@ -0,0 +1,198 @@
+package payload
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+)
+
+// Net is the payload of the net metric, mapped by the name of each interface.
+type Net map[string]Interface
+
+// Interface is the payload of a single network interface of [Net]. The rates
+// are scaled to the configured rate unit of the interface. If the interface
+// is not running, only Running and IP are included.
+type Interface struct {
+	Running bool       `json:"running"`
+	IP      netip.Addr `json:"ip,omitzero"`
+	// Download is the number of bytes received since the last update.
+	Download uint64 `json:"download,omitempty"`
+	// Upload is the number of bytes transmitted since the last update.
+	Upload       uint64 `json:"upload,omitempty"`
+	DownloadRate Size   `json:"download_rate,omitempty"`
+	UploadRate   Size   `json:"upload_rate,omitempty"`
+	// DownloadTotal is the total number of bytes received, which keeps
+	// increasing across restarts.
+	DownloadTotal Optional[uint64] `json:"download_total,omitzero"`
+	// UploadTotal is the total number of bytes transmitted, which keeps
+	// increasing across restarts.
+	UploadTotal Optional[uint64] `json:"upload_total,omitzero"`
+	// UsageToday is the number of bytes received and transmitted since the
+	// start of the day.
+	UsageToday Optional[uint64] `json:"usage_today,omitzero"`
+	// UsageMonth is the number of bytes received and transmitted since the
+	// start of the billing month.
+	UsageMonth Optional[uint64] `json:"usage_month,omitzero"`
+	// DownloadRateAggregate is the rolling aggregate of DownloadRate.
+	DownloadRateAggregate Optional[Aggregate] `json:"download_rate_aggregate,omitzero"`
+	// UploadRateAggregate is the rolling aggregate of UploadRate.
+	UploadRateAggregate Optional[Aggregate] `json:"upload_rate_aggregate,omitzero"`
+	// Peers is the status of each peer of a WireGuard interface, mapped by
+	// the name of the peer.
+	Peers map[string]WireGuardPeer `json:"peers,omitempty"`
+}
+
+// WireGuardPeer is the status of a single peer of a WireGuard [Interface].
+type WireGuardPeer struct {
+	Endpoint netip.AddrPort `json:"endpoint,omitzero"`
+	// LastHandshake is the number of seconds since the last handshake with
+	// the peer, which is missing if there never was one.
+	LastHandshake Optional[int64] `json:"last_handshake,omitzero"`
+	// Received is the number of bytes received from the peer.
+	Received uint64 `json:"received"`
+	// Sent is the number of bytes sent to the peer.
+	Sent uint64 `json:"sent"`
+	// Connected indicates if the last handshake is recent enough for the peer
+	// to be considered connected.
+	Connected bool `json:"connected"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of peer to b.
+func (peer WireGuardPeer) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	if peer.Endpoint.IsValid() {
+		b = append(b, "\"endpoint\": \""...)
+		b = peer.Endpoint.AppendTo(b)
+		b = append(b, "\", "...)
+	}
+
+	if peer.LastHandshake.Valid {
+		b = append(b, "\"last_handshake\": "...)
+		b = strconv.AppendInt(b, peer.LastHandshake.Value, 10)
+		b = append(b, ", "...)
+	}
+
+	b = append(b, "\"received\": "...)
+	b = strconv.AppendUint(b, peer.Received, 10)
+	b = append(b, ", \"sent\": "...)
+	b = strconv.AppendUint(b, peer.Sent, 10)
+	b = append(b, ", \"connected\": "...)
+	b = strconv.AppendBool(b, peer.Connected)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [WireGuardPeer.AppendText](nil).
+func (peer WireGuardPeer) MarshalJSON() ([]byte, error) {
+	return peer.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of iface to b.
+func (iface Interface) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"running\": "...)
+	b = strconv.AppendBool(b, iface.Running)
+
+	if iface.IP.IsValid() {
+		b = append(b, ", \"ip\": \""...)
+		b = iface.IP.AppendTo(b)
+		b = append(b, '"')
+	}
+
+	if !iface.Running {
+		return append(b, '}'), nil
+	}
+
+	b = append(b, ", \"download\": "...)
+	b = strconv.AppendUint(b, iface.Download, 10)
+	b = append(b, ", \"upload\": "...)
+	b = strconv.AppendUint(b, iface.Upload, 10)
+	b = append(b, ", \"download_rate\": "...)
+	b, _ = iface.DownloadRate.AppendText(b)
+	b = append(b, ", \"upload_rate\": "...)
+	b, _ = iface.UploadRate.AppendText(b)
+
+	if iface.DownloadTotal.Valid {
+		b = append(b, ", \"download_total\": "...)
+		b = strconv.AppendUint(b, iface.DownloadTotal.Value, 10)
+	}
+
+	if iface.UploadTotal.Valid {
+		b = append(b, ", \"upload_total\": "...)
+		b = strconv.AppendUint(b, iface.UploadTotal.Value, 10)
+	}
+
+	if iface.UsageToday.Valid {
+		b = append(b, ", \"usage_today\": "...)
+		b = strconv.AppendUint(b, iface.UsageToday.Value, 10)
+	}
+
+	if iface.UsageMonth.Valid {
+		b = append(b, ", \"usage_month\": "...)
+		b = strconv.AppendUint(b, iface.UsageMonth.Value, 10)
+	}
+
+	if iface.DownloadRateAggregate.Valid {
+		b = append(b, ", \"download_rate_aggregate\": "...)
+		b, _ = iface.DownloadRateAggregate.Value.AppendText(b)
+	}
+
+	if iface.UploadRateAggregate.Valid {
+		b = append(b, ", \"upload_rate_aggregate\": "...)
+		b, _ = iface.UploadRateAggregate.Value.AppendText(b)
+	}
+
+	if len(iface.Peers) > 0 {
+		b = append(b, ", \"peers\": {"...)
+
+		for i, name := range slices.Sorted(maps.Keys(iface.Peers)) {
+			if i > 0 {
+				b = append(b, ',', ' ')
+			}
+
+			b = strconv.AppendQuote(b, name)
+			b = append(b, ':', ' ')
+			b, _ = iface.Peers[name].AppendText(b)
+		}
+
+		b = append(b, '}')
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Interface.AppendText](nil).
+func (iface Interface) MarshalJSON() ([]byte, error) {
+	return iface.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of n to b.
+func (n Net) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	first := true
+
+	for name, iface := range n {
+		if !first {
+			b = append(b, ',', ' ')
+		}
+
+		b = append(b, '"')
+		b = append(b, name...)
+		b = append(b, '"', ':', ' ')
+		b, _ = iface.AppendText(b)
+
+		first = false
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Net.AppendText](nil).
+func (n Net) MarshalJSON() ([]byte, error) {
+	return n.AppendText(nil)
+}
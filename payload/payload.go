@@ -0,0 +1,191 @@
+// Package payload defines the JSON payloads published by each of the metrics
+// in [github.com/lone-faerie/mqttop/metrics]. The types in this package may be
+// used to decode the published messages, and each implements [encoding.TextAppender]
+// to encode the payload without any allocations.
+//
+// Fixed-point numbers are represented by [Milli] and [Micro], and sizes scaled to
+// a configured unit are represented by [Size]. Fields that may be missing from a
+// payload are represented by [Optional].
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+)
+
+var null = []byte("null")
+
+// Milli is a fixed-point number with 3 decimal places, such as a temperature
+// in millidegrees Celsius.
+type Milli int64
+
+// Float64 returns m as a float64.
+func (m Milli) Float64() float64 {
+	return float64(m) / 1e3
+}
+
+// Round returns m rounded to prec places after the decimal point, rounding
+// half away from zero.
+func (m Milli) Round(prec int) Milli {
+	return Milli(byteutil.RoundDecimal(int64(m), 3, prec))
+}
+
+// AppendText implements [encoding.TextAppender] and appends the decimal
+// representation of m to b, without any insignificant trailing zeros.
+func (m Milli) AppendText(b []byte) ([]byte, error) {
+	return byteutil.AppendDecimalTrim(b, int64(m), 3), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Milli.AppendText](nil).
+func (m Milli) MarshalJSON() ([]byte, error) {
+	return m.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (m *Milli) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, null) {
+		return nil
+	}
+
+	v, err := byteutil.ParseDecimal(data, 3)
+	if err != nil {
+		return err
+	}
+
+	*m = Milli(v)
+
+	return nil
+}
+
+// Micro is a fixed-point number with 6 decimal places, such as a frequency
+// in kHz represented in GHz.
+type Micro int64
+
+// Float64 returns m as a float64.
+func (m Micro) Float64() float64 {
+	return float64(m) / 1e6
+}
+
+// Round returns m rounded to prec places after the decimal point, rounding
+// half away from zero.
+func (m Micro) Round(prec int) Micro {
+	return Micro(byteutil.RoundDecimal(int64(m), 6, prec))
+}
+
+// AppendText implements [encoding.TextAppender] and appends the decimal
+// representation of m to b, without any insignificant trailing zeros.
+func (m Micro) AppendText(b []byte) ([]byte, error) {
+	return byteutil.AppendDecimalTrim(b, int64(m), 6), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Micro.AppendText](nil).
+func (m Micro) MarshalJSON() ([]byte, error) {
+	return m.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (m *Micro) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, null) {
+		return nil
+	}
+
+	v, err := byteutil.ParseDecimal(data, 6)
+	if err != nil {
+		return err
+	}
+
+	*m = Micro(v)
+
+	return nil
+}
+
+// Size is a number of bytes scaled to the unit configured for the metric, as
+// a fixed-point number with 3 decimal places. Insignificant trailing zeros are
+// omitted when encoding.
+type Size uint64
+
+// Float64 returns s as a float64.
+func (s Size) Float64() float64 {
+	return float64(s) / 1e3
+}
+
+// AppendText implements [encoding.TextAppender] and appends the decimal
+// representation of s to b.
+func (s Size) AppendText(b []byte) ([]byte, error) {
+	return byteutil.AppendScaled(b, uint64(s)), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Size.AppendText](nil).
+func (s Size) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, null) {
+		return nil
+	}
+
+	v, err := byteutil.ParseDecimal(data, 3)
+	if err != nil {
+		return err
+	}
+
+	if v < 0 {
+		return &strconv.NumError{Func: "Size.UnmarshalJSON", Num: string(data), Err: strconv.ErrRange}
+	}
+
+	*s = Size(v)
+
+	return nil
+}
+
+// Optional is a value that may be missing from a payload. Fields of this type
+// are tagged with omitzero so they are omitted when not valid.
+type Optional[T any] struct {
+	Value T
+	Valid bool
+}
+
+// Some returns a valid [Optional] with the value v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Valid: true}
+}
+
+// Maybe returns an [Optional] with the value v that is valid if ok is true.
+func Maybe[T any](v T, ok bool) Optional[T] {
+	return Optional[T]{Value: v, Valid: ok}
+}
+
+// Get returns the value of o and whether it is valid.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Valid
+}
+
+// MarshalJSON implements [json.Marshaler]. If o is not valid, the result is null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Valid {
+		return null, nil
+	}
+
+	return json.Marshal(o.Value)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. A value of null leaves o invalid.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, null) {
+		*o = Optional[T]{}
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &o.Value); err != nil {
+		return err
+	}
+
+	o.Valid = true
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package payload
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// roundTripTests are example payloads, which must be encoded the same as they
+// are decoded.
+var roundTripTests = []struct {
+	name string
+	v    json.Marshaler
+	data string
+}{
+	{"CPU", new(CPU), `{"name": "cpu", "temperature": 81, "frequency": 3.124402, "selection_mode": "auto", "usage": 12, "cores": [{"id": 0, "core": 0, "temperature": 68, "frequency": 3.124402, "usage": 3}, {"id": 1, "core": 0, "frequency": 0.8}]}`},
+	{"CPUSampled", new(CPU), `{"name": "cpu", "usage": 12, "usage_min": 2, "usage_max": 97, "cores": []}`},
+	{"CPUAggregate", new(CPU), `{"name": "cpu", "temperature": 81, "temperature_aggregate": {"avg_1m": 72.5, "max_1m": 81, "avg_5m": 70, "max_5m": 81, "avg_15m": 65.25, "max_15m": 90}, "usage": 12, "usage_aggregate": {"avg_1m": 10.5, "max_1m": 30, "avg_5m": 8, "max_5m": 30, "avg_15m": 5.125, "max_15m": 100}, "cores": []}`},
+	{"CPUNoCores", new(CPU), `{"name": "cpu", "usage": 12}`},
+	{"CPUBoost", new(CPU), `{"name": "cpu", "usage": 12, "boost": true, "governor": "powersave", "cores": [{"id": 0, "core": 0, "frequency": 3.124402, "base_frequency": 2.8, "min_frequency": 0.8, "max_frequency": 3.8, "usage": 3}]}`},
+	{"GPUSummary", new(GPU), `{"name": "NVIDIA GeForce RTX 3080", "power": 220.5, "maxPower": 320, "persistence": true, "temperature": 64, "maxTemp": 98, "memory": {"total": 10240, "free": 8192, "used": 2048}, "summary": {"count": 1, "memoryUsed": 2048, "memoryTotal": 10240, "temperature": 64, "power": 220.5}}`},
+	{"CPUInfo", new(CPUInfo), `{"vendor": "GenuineIntel", "model": "Intel(R) Core(TM) i7-8650U CPU @ 1.90GHz", "family": 6, "model_id": 142, "stepping": 10, "cache_size": 8192, "sockets": 1, "cores": 4, "threads": 8, "virtualization": "VT-x"}`},
+	{"SelectionMode", new(SelectionMode), `{"selection_mode": "auto", "options": ["auto", "first", "average", "maximum", "minimum", "random"]}`},
+	{"Memory", new(Memory), `{"total": 14.94, "used": 3.069, "available": 11.871, "cached": 11.451, "free": 0.419, "swapTotal": 975.996, "swapUsed": 0, "swapFree": 975.996}`},
+	{"MemoryNoSwap", new(Memory), `{"total": 14.94, "used": 3.069, "available": 11.871, "cached": 11.451, "free": 0.419}`},
+	{"Disks", new(Disks), `{"root": {"mnt": "/", "total": 232.375, "free": 100, "used": 132.375, "reads": 1, "writes": 2}}`},
+	{"DisksTotal", new(Disks), `{"root": {"mnt": "/", "total": 232.375, "free": 100, "used": 132.375, "reads": 1, "writes": 2, "read_total": 1024, "write_total": 2048}}`},
+	{"DisksPrediction", new(Disks), `{"root": {"mnt": "/", "total": 232.375, "free": 100, "used": 132.375, "days_until_full": 42.125}}`},
+	{"DisksReadOnly", new(Disks), `{"root": {"mnt": "/", "total": 232.375, "free": 100, "used": 132.375, "read_only": true}, "share": {"mnt": "/mnt/share", "total": 1, "free": 1, "used": 0, "stale": true}}`},
+	{"DisksSorted", new(Disks), `{"data": {"mnt": "/mnt/data", "total": 1, "free": 1, "used": 0}, "data_2": {"mnt": "/media/data", "total": 2, "free": 1, "used": 1}, "root": {"mnt": "/", "total": 3, "free": 2, "used": 1}}`},
+	{"Net", new(Net), `{"eth0": {"running": true, "ip": "192.168.1.2", "download": 100, "upload": 200, "download_rate": 0.5, "upload_rate": 1}}`},
+	{"NetTotal", new(Net), `{"eth0": {"running": true, "download": 100, "upload": 200, "download_rate": 0.5, "upload_rate": 1, "download_total": 123456789, "upload_total": 987654321}}`},
+	{"NetUsage", new(Net), `{"eth0": {"running": true, "download": 100, "upload": 200, "download_rate": 0.5, "upload_rate": 1, "usage_today": 300, "usage_month": 123456}}`},
+	{"NetAggregate", new(Net), `{"eth0": {"running": true, "download": 100, "upload": 200, "download_rate": 0.5, "upload_rate": 1, "download_rate_aggregate": {"avg_1m": 0.25, "max_1m": 0.5, "avg_5m": 0.25, "max_5m": 0.5, "avg_15m": 0.25, "max_15m": 0.5}, "upload_rate_aggregate": {"avg_1m": 1, "max_1m": 1, "avg_5m": 1, "max_5m": 1, "avg_15m": 1, "max_15m": 1}}}`},
+	{"NetWireGuard", new(Net), `{"wg0": {"running": true, "download": 100, "upload": 200, "download_rate": 0.5, "upload_rate": 1, "peers": {"laptop": {"endpoint": "192.0.2.1:51820", "last_handshake": 42, "received": 1000, "sent": 2000, "connected": true}, "phone": {"received": 0, "sent": 0, "connected": false}}}}`},
+	{"NetNotRunning", new(Net), `{"wlan0": {"running": false}}`},
+	{"Battery", new(Battery), `{"kind": "Li-ion", "status": "discharging", "capacity": 85, "power": 10.5, "timeRemaining": 3600}`},
+	{"BatteryCharging", new(Battery), `{"kind": "Li-ion", "status": "charging", "capacity": 50, "power": 10.5, "voltage": 12.229, "current": -0.85, "timeToFull": 1800}`},
+	{"Audio", new(Audio), `{"volume": 40, "muted": false, "player": "spotify", "status": "playing", "artist": "Daft Punk", "title": "\"Aerodynamic\"", "album": "Discovery"}`},
+	{"AudioNoPlayer", new(Audio), `{"volume": 0, "muted": true}`},
+	{"Idle", new(Idle), `{"idle": 42, "active": true}`},
+	{"Processes", new(Processes), `{"count": 312, "sort_by": "cpu", "top": [{"pid": 26231, "name": "vim", "cpu": 12.5, "memory": 6.559}, {"pid": 1020, "name": "(sd-pam) \"b\"", "cpu": 0, "memory": 0}]}`},
+	{"ProcessesEmpty", new(Processes), `{"count": 0, "sort_by": "memory", "top": []}`},
+	{"System", new(System), `{"uptime": 350735, "boot_time": 1418183276, "load1": 0.02, "load5": 1.5, "load15": 12.05}`},
+	{"Smart", new(Smart), `{"sda": {"model": "Samsung SSD 870 \"EVO\"", "passed": true, "temperature": 31, "power_on_hours": 12043, "reallocated_sectors": 0, "pending_sectors": 0}}`},
+	{"SmartNVMe", new(Smart), `{"nvme0n1": {"model": "WD_BLACK SN850X", "passed": false, "temperature": 45.85, "power_on_hours": 812, "media_errors": 3, "wear": 2}}`},
+	{"SmartTemperatureOnly", new(Smart), `{"sdb": {"model": "", "temperature": 28}}`},
+	{"Energy", new(Energy), `{"package-0": {"power": 12.5, "energy": 0.042}}`},
+	{"Fans", new(Fans), `{"nct6798_fan1": {"label": "fan1", "speed": 1180, "pwm": 102, "mode": "auto"}}`},
+	{"FansNoPWM", new(Fans), `{"nct6798_fan3": {"label": "fan3", "speed": 0}}`},
+	{"Dir", new(Dir), `{"path": "/tmp", "size": 1.25}`},
+	{"DirWatched", new(Dir), `{"path": "/tmp", "size": 1.25, "watched": 4096, "polled": 3}`},
+	{"DirReclaimed", new(Dir), `{"path": "/tmp", "size": 1.25, "reclaimed": 0.5}`},
+	{"GPUAggregate", new(GPU), `{"name": "gpu", "utilization": {"gpu": 50, "memory": 25}, "utilizationAggregate": {"avg_1m": 45.5, "max_1m": 50, "avg_5m": 40, "max_5m": 75, "avg_15m": 20, "max_15m": 100}, "temperature": 60, "temperatureAggregate": {"avg_1m": 59, "max_1m": 60, "avg_5m": 55, "max_5m": 60, "avg_15m": 50, "max_15m": 65}, "maxTemp": 90}`},
+	{"GPU", new(GPU), `{"name": "gpu", "rx": 1, "tx": 2, "utilization": {"gpu": 50, "memory": 25}, "clock": 1500, "memClock": 7000, "power": 120.5, "maxPower": 250, "temperature": 60, "maxTemp": 90, "memory": {"total": 8, "free": 6.5, "used": 1.5}}`},
+	{"GPUIntel", new(GPU), `{"name": "Intel GPU", "utilization": {"gpu": 25, "memory": 0}, "clock": 1100, "rc6": 75, "engines": {"copy": 0, "render": 20, "video": 5}}`},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range roundTripTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.data), tt.v); err != nil {
+				t.Fatal(err)
+			}
+			b, err := tt.v.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tt.data {
+				t.Errorf("Wanted %s, got %s", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestOptional(t *testing.T) {
+	var cpu CPU
+
+	if err := json.Unmarshal([]byte(`{"name": "cpu", "temperature": null, "cores": []}`), &cpu); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Temperature.Valid {
+		t.Error("Temperature: wanted invalid")
+	}
+	if cpu.Usage.Valid {
+		t.Error("Usage: wanted invalid")
+	}
+	if v, ok := Some(Milli(1500)).Get(); !ok || v.Float64() != 1.5 {
+		t.Errorf("Some: wanted 1.5, got %v", v.Float64())
+	}
+}
+
+func TestGPUSummary(t *testing.T) {
+	var s GPUSummary
+
+	s.Add(&GPU{
+		Power:       Some(Milli(120000)),
+		Temperature: Some[uint32](58),
+		Memory:      Some(GPUMemory{Total: 8192, Used: 1024}),
+	})
+	s.Add(&GPU{
+		Temperature: Some[uint32](71),
+		Memory:      Some(GPUMemory{Total: 4096, Used: 512}),
+	})
+
+	want := GPUSummary{
+		Count:       2,
+		MemoryUsed:  Some(Size(1536)),
+		MemoryTotal: Some(Size(12288)),
+		Temperature: Some[uint32](71),
+		Power:       Some(Milli(120000)),
+	}
+	if s != want {
+		t.Errorf("Wanted %+v, got %+v", want, s)
+	}
+}
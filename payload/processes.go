@@ -0,0 +1,78 @@
+package payload
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Processes is the payload of the processes metric.
+type Processes struct {
+	// Count is the number of processes.
+	Count int `json:"count"`
+	// SortBy is the usage the processes are sorted by, either "cpu" or
+	// "memory".
+	SortBy string `json:"sort_by"`
+	// Top are the processes using the most, in descending order.
+	Top []Process `json:"top"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of p to b.
+func (p Processes) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"count\": "...)
+	b = strconv.AppendInt(b, int64(p.Count), 10)
+	b = append(b, ", \"sort_by\": \""...)
+	b = append(b, p.SortBy...)
+	b = append(b, "\", \"top\": ["...)
+
+	for i := range p.Top {
+		b, _ = p.Top[i].AppendText(b)
+
+		if i < len(p.Top)-1 {
+			b = append(b, ',', ' ')
+		}
+	}
+
+	return append(b, ']', '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Processes.AppendText](nil).
+func (p Processes) MarshalJSON() ([]byte, error) {
+	return p.AppendText(nil)
+}
+
+// Process is the payload of a single process of [Processes].
+type Process struct {
+	PID int `json:"pid"`
+	// Name is the command name of the process.
+	Name string `json:"name"`
+	// CPU is the usage of every CPU by the process as a percent, averaged
+	// since the last update.
+	CPU Milli `json:"cpu"`
+	// Memory is the resident memory of the process, scaled to the configured
+	// size unit.
+	Memory Size `json:"memory"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of p to b.
+func (p Process) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"pid\": "...)
+	b = strconv.AppendInt(b, int64(p.PID), 10)
+	b = append(b, ", \"name\": "...)
+	// The name of a process may contain any characters, so it needs to be
+	// escaped.
+	q, _ := json.Marshal(p.Name)
+	b = append(b, q...)
+	b = append(b, ", \"cpu\": "...)
+	b, _ = p.CPU.AppendText(b)
+	b = append(b, ", \"memory\": "...)
+	b, _ = p.Memory.AppendText(b)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Process.AppendText](nil).
+func (p Process) MarshalJSON() ([]byte, error) {
+	return p.AppendText(nil)
+}
@@ -0,0 +1,25 @@
+package payload
+
+import (
+	"maps"
+	"slices"
+)
+
+//go:generate go run gen.go
+
+// Schema returns the JSON Schema of the payload of the metric of the given
+// type, such as "cpu", or false if there is none. The schema is generated from
+// the payload types of this package, such as [CPU], and includes their docs.
+func Schema(metric string) ([]byte, bool) {
+	s, ok := schemas[metric]
+	if !ok {
+		return nil, false
+	}
+
+	return []byte(s), true
+}
+
+// SchemaTypes returns the sorted types of the metrics that have a schema.
+func SchemaTypes() []string {
+	return slices.Sorted(maps.Keys(schemas))
+}
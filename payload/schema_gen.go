@@ -0,0 +1,933 @@
+// Code generated by "go run gen.go"; DO NOT EDIT.
+
+package payload
+
+// schemas are the JSON Schemas of the payload of each metric, by the type of
+// the metric.
+var schemas = map[string]string{
+	"audio": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "audio",
+	"description": "Audio is the payload of the audio metric. The media fields are only included if there is a media player, and each is omitted if the player doesn't report it.",
+	"type": "object",
+	"properties": {
+		"volume": {
+			"description": "Volume is the volume of the default output as a percent.",
+			"type": "integer"
+		},
+		"muted": {
+			"type": "boolean"
+		},
+		"player": {
+			"description": "Player is the name of the MPRIS media player.",
+			"type": "string"
+		},
+		"status": {
+			"description": "Status is the playback status of the player, either \"playing\", \"paused\", or \"stopped\".",
+			"type": "string"
+		},
+		"artist": {
+			"type": "string"
+		},
+		"title": {
+			"type": "string"
+		},
+		"album": {
+			"type": "string"
+		}
+	},
+	"required": [
+		"volume",
+		"muted"
+	]
+}`,
+	"battery": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "battery",
+	"description": "Battery is the payload of the battery metric.",
+	"type": "object",
+	"properties": {
+		"kind": {
+			"type": "string"
+		},
+		"status": {
+			"type": "string"
+		},
+		"capacity": {
+			"description": "Capacity is the capacity of the battery as a percent.",
+			"type": "integer"
+		},
+		"power": {
+			"description": "Power is the power draw of the battery in W.",
+			"type": "number"
+		},
+		"voltage": {
+			"description": "Voltage is the voltage of the battery in V.",
+			"type": "number"
+		},
+		"current": {
+			"description": "Current is the current of the battery in A, which is negative while discharging on some systems.",
+			"type": "number"
+		},
+		"timeRemaining": {
+			"description": "TimeRemaining is the estimated time remaining of the battery in seconds while discharging.",
+			"type": "integer"
+		},
+		"timeToFull": {
+			"description": "TimeToFull is the estimated time until the battery is full in seconds while charging.",
+			"type": "integer"
+		}
+	},
+	"required": [
+		"kind",
+		"status"
+	]
+}`,
+	"cpu": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "cpu",
+	"description": "CPU is the payload of the cpu metric.",
+	"type": "object",
+	"properties": {
+		"name": {
+			"type": "string"
+		},
+		"temperature": {
+			"description": "Temperature is the selected temperature of the CPU in °C.",
+			"type": "number"
+		},
+		"temperature_aggregate": {
+			"description": "TemperatureAggregate is the rolling aggregate of Temperature.",
+			"$ref": "#/$defs/Aggregate"
+		},
+		"frequency": {
+			"description": "Frequency is the selected frequency of the CPU in GHz.",
+			"type": "number"
+		},
+		"selection_mode": {
+			"description": "SelectionMode is the mode used to select the temperature and frequency of the CPU from its cores.",
+			"type": "string"
+		},
+		"usage": {
+			"description": "Usage is the usage of the CPU as a percent, averaged since the last update.",
+			"type": "integer"
+		},
+		"usage_min": {
+			"description": "UsageMin is the minimum usage of the CPU sampled since the last update.",
+			"type": "integer"
+		},
+		"usage_max": {
+			"description": "UsageMax is the maximum usage of the CPU sampled since the last update.",
+			"type": "integer"
+		},
+		"usage_aggregate": {
+			"description": "UsageAggregate is the rolling aggregate of Usage.",
+			"$ref": "#/$defs/Aggregate"
+		},
+		"boost": {
+			"description": "Boost indicates whether frequency boost (turbo) is enabled.",
+			"type": "boolean"
+		},
+		"governor": {
+			"description": "Governor is the scaling governor of the first core.",
+			"type": "string"
+		},
+		"cores": {
+			"description": "Cores are the payloads of the reported cores, or nil if per-core data is disabled, in which case they are omitted.",
+			"type": "array",
+			"items": {
+				"$ref": "#/$defs/Core"
+			}
+		}
+	},
+	"required": [
+		"name"
+	],
+	"$defs": {
+		"Aggregate": {
+			"description": "Aggregate is the rolling average and maximum of a value over the last 1, 5, and 15 minutes, in the same unit as the value. A window that is not yet full is aggregated over the values so far.",
+			"type": "object",
+			"properties": {
+				"avg_1m": {
+					"type": "number"
+				},
+				"max_1m": {
+					"type": "number"
+				},
+				"avg_5m": {
+					"type": "number"
+				},
+				"max_5m": {
+					"type": "number"
+				},
+				"avg_15m": {
+					"type": "number"
+				},
+				"max_15m": {
+					"type": "number"
+				}
+			},
+			"required": [
+				"avg_1m",
+				"max_1m",
+				"avg_5m",
+				"max_5m",
+				"avg_15m",
+				"max_15m"
+			]
+		},
+		"Core": {
+			"description": "Core is the payload of a single core of CPU.",
+			"type": "object",
+			"properties": {
+				"id": {
+					"description": "ID is the logical id of the core, which is unique among the cores.",
+					"type": "integer"
+				},
+				"core": {
+					"description": "Core is the physical id of the core, which is shared by the logical cores of the same physical core, such as with hyper-threading.",
+					"type": "integer"
+				},
+				"temperature": {
+					"description": "Temperature is the temperature of the core in °C.",
+					"type": "number"
+				},
+				"frequency": {
+					"description": "Frequency is the frequency of the core in GHz.",
+					"type": "number"
+				},
+				"base_frequency": {
+					"description": "BaseFrequency is the base frequency of the core in GHz.",
+					"type": "number"
+				},
+				"min_frequency": {
+					"description": "MinFrequency is the minimum scaling frequency of the core in GHz.",
+					"type": "number"
+				},
+				"max_frequency": {
+					"description": "MaxFrequency is the maximum scaling frequency of the core in GHz.",
+					"type": "number"
+				},
+				"usage": {
+					"description": "Usage is the usage of the core as a percent.",
+					"type": "integer"
+				}
+			},
+			"required": [
+				"id",
+				"core"
+			]
+		}
+	}
+}`,
+	"dir": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "dir",
+	"description": "Dir is the payload of the dir metric, of a single directory.",
+	"type": "object",
+	"properties": {
+		"path": {
+			"type": "string"
+		},
+		"size": {
+			"type": "number",
+			"minimum": 0
+		},
+		"watched": {
+			"description": "Watched is the number of watched directories, if the directory is watched.",
+			"type": "integer"
+		},
+		"polled": {
+			"description": "Polled is the number of subtrees that are polled instead of watched, if the directory is watched.",
+			"type": "integer"
+		},
+		"reclaimed": {
+			"description": "Reclaimed is the size of the files deleted by the last clean command, if the directory has been cleaned.",
+			"type": "number",
+			"minimum": 0
+		}
+	},
+	"required": [
+		"path",
+		"size"
+	]
+}`,
+	"disks": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "disks",
+	"description": "Disks is the payload of the disks metric, mapped by the unique name of each disk. The disks are encoded in order of their names.",
+	"type": "object",
+	"additionalProperties": {
+		"$ref": "#/$defs/Disk"
+	},
+	"$defs": {
+		"Disk": {
+			"description": "Disk is the payload of a single disk of Disks. All sizes are scaled to the configured size unit of the disk.",
+			"type": "object",
+			"properties": {
+				"mnt": {
+					"type": "string"
+				},
+				"total": {
+					"type": "number",
+					"minimum": 0
+				},
+				"free": {
+					"type": "number",
+					"minimum": 0
+				},
+				"used": {
+					"type": "number",
+					"minimum": 0
+				},
+				"reads": {
+					"description": "Reads is the number of bytes read since the last update.",
+					"type": "integer"
+				},
+				"writes": {
+					"description": "Writes is the number of bytes written since the last update.",
+					"type": "integer"
+				},
+				"read_total": {
+					"description": "ReadTotal is the total number of bytes read, which keeps increasing across restarts.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"write_total": {
+					"description": "WriteTotal is the total number of bytes written, which keeps increasing across restarts.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"days_until_full": {
+					"description": "DaysUntilFull is the predicted number of days until the disk is full, which is missing if the used space is not increasing.",
+					"type": "number"
+				},
+				"read_only": {
+					"description": "ReadOnly indicates if the filesystem is mounted read-only, such as after it was remounted because of errors.",
+					"type": "boolean"
+				},
+				"stale": {
+					"description": "Stale indicates if the filesystem stopped responding, in which case the sizes are of the last update it responded to.",
+					"type": "boolean"
+				}
+			},
+			"required": [
+				"mnt",
+				"total",
+				"free",
+				"used"
+			]
+		}
+	}
+}`,
+	"energy": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "energy",
+	"description": "Energy is the payload of the energy metric, mapped by the name of each RAPL zone, such as \"package-0\" or \"package-0_core\".",
+	"type": "object",
+	"additionalProperties": {
+		"$ref": "#/$defs/EnergyZone"
+	},
+	"$defs": {
+		"EnergyZone": {
+			"description": "EnergyZone is the payload of a single zone of Energy.",
+			"type": "object",
+			"properties": {
+				"power": {
+					"description": "Power is the average power of the zone since the last update in W.",
+					"type": "number"
+				},
+				"energy": {
+					"description": "Energy is the energy used by the zone in kWh, which is accumulated from when the metric is started.",
+					"type": "number"
+				}
+			},
+			"required": [
+				"power",
+				"energy"
+			]
+		}
+	}
+}`,
+	"fans": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "fans",
+	"description": "Fans is the payload of the fans metric, mapped by the ID of each fan.",
+	"type": "object",
+	"additionalProperties": {
+		"$ref": "#/$defs/Fan"
+	},
+	"$defs": {
+		"Fan": {
+			"description": "Fan is the payload of a single fan of Fans.",
+			"type": "object",
+			"properties": {
+				"label": {
+					"type": "string"
+				},
+				"speed": {
+					"description": "Speed is the speed of the fan in RPM.",
+					"type": "integer"
+				},
+				"pwm": {
+					"description": "PWM is the duty cycle of the fan from 0 to 255, if it has PWM control.",
+					"type": "integer"
+				},
+				"mode": {
+					"description": "Mode is the PWM mode of the fan, either \"full\", \"manual\", or \"auto\".",
+					"type": "string"
+				}
+			},
+			"required": [
+				"label",
+				"speed"
+			]
+		}
+	}
+}`,
+	"gpu": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "gpu",
+	"description": "GPU is the payload of the gpu metric.",
+	"type": "object",
+	"properties": {
+		"name": {
+			"type": "string"
+		},
+		"rx": {
+			"description": "Rx is the PCIe receive throughput of the GPU in KB/s.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"tx": {
+			"description": "Tx is the PCIe transmit throughput of the GPU in KB/s.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"utilization": {
+			"$ref": "#/$defs/GPUUtilization"
+		},
+		"utilizationAggregate": {
+			"description": "UtilizationAggregate is the rolling aggregate of the GPU utilization.",
+			"$ref": "#/$defs/Aggregate"
+		},
+		"clock": {
+			"description": "Clock is the graphics clock of the GPU in MHz.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"memClock": {
+			"description": "MemClock is the memory clock of the GPU in MHz.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"rc6": {
+			"description": "RC6 is the percent of time the GPU was in RC6, its idle power state.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"engines": {
+			"description": "Engines is the utilization of each engine class of the GPU as percents.",
+			"type": "object",
+			"additionalProperties": {
+				"type": "integer",
+				"minimum": 0
+			}
+		},
+		"power": {
+			"description": "Power is the power usage of the GPU in W.",
+			"type": "number"
+		},
+		"maxPower": {
+			"description": "MaxPower is the power limit of the GPU in W.",
+			"type": "number"
+		},
+		"persistence": {
+			"description": "Persistence indicates if persistence mode is enabled.",
+			"type": "boolean"
+		},
+		"temperature": {
+			"description": "Temperature is the temperature of the GPU in °C.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"temperatureAggregate": {
+			"description": "TemperatureAggregate is the rolling aggregate of Temperature.",
+			"$ref": "#/$defs/Aggregate"
+		},
+		"maxTemp": {
+			"description": "MaxTemp is the slowdown temperature of the GPU in °C.",
+			"type": "integer",
+			"minimum": 0
+		},
+		"memory": {
+			"$ref": "#/$defs/GPUMemory"
+		},
+		"summary": {
+			"description": "Summary is the summary across all of the GPUs, if enabled.",
+			"$ref": "#/$defs/GPUSummary"
+		}
+	},
+	"required": [
+		"name"
+	],
+	"$defs": {
+		"GPUUtilization": {
+			"description": "GPUUtilization is the utilization of a GPU as percents.",
+			"type": "object",
+			"properties": {
+				"gpu": {
+					"type": "integer",
+					"minimum": 0
+				},
+				"memory": {
+					"type": "integer",
+					"minimum": 0
+				}
+			},
+			"required": [
+				"gpu",
+				"memory"
+			]
+		},
+		"Aggregate": {
+			"description": "Aggregate is the rolling average and maximum of a value over the last 1, 5, and 15 minutes, in the same unit as the value. A window that is not yet full is aggregated over the values so far.",
+			"type": "object",
+			"properties": {
+				"avg_1m": {
+					"type": "number"
+				},
+				"max_1m": {
+					"type": "number"
+				},
+				"avg_5m": {
+					"type": "number"
+				},
+				"max_5m": {
+					"type": "number"
+				},
+				"avg_15m": {
+					"type": "number"
+				},
+				"max_15m": {
+					"type": "number"
+				}
+			},
+			"required": [
+				"avg_1m",
+				"max_1m",
+				"avg_5m",
+				"max_5m",
+				"avg_15m",
+				"max_15m"
+			]
+		},
+		"GPUMemory": {
+			"description": "GPUMemory is the memory of a GPU. All sizes are scaled to the configured size unit of the GPU.",
+			"type": "object",
+			"properties": {
+				"total": {
+					"type": "number",
+					"minimum": 0
+				},
+				"free": {
+					"type": "number",
+					"minimum": 0
+				},
+				"used": {
+					"type": "number",
+					"minimum": 0
+				}
+			},
+			"required": [
+				"total",
+				"free",
+				"used"
+			]
+		},
+		"GPUSummary": {
+			"description": "GPUSummary is the summary across the GPUs, as the sum or maximum of the values of each GPU. A value is only valid if it is valid for any GPU.",
+			"type": "object",
+			"properties": {
+				"count": {
+					"description": "Count is the number of GPUs summarized.",
+					"type": "integer"
+				},
+				"memoryUsed": {
+					"description": "MemoryUsed is the total memory used by the GPUs.",
+					"type": "number",
+					"minimum": 0
+				},
+				"memoryTotal": {
+					"description": "MemoryTotal is the total memory of the GPUs.",
+					"type": "number",
+					"minimum": 0
+				},
+				"temperature": {
+					"description": "Temperature is the maximum temperature of the GPUs in °C.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"power": {
+					"description": "Power is the total power usage of the GPUs in W.",
+					"type": "number"
+				}
+			},
+			"required": [
+				"count"
+			]
+		}
+	}
+}`,
+	"idle": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "idle",
+	"description": "Idle is the payload of the idle metric.",
+	"type": "object",
+	"properties": {
+		"idle": {
+			"description": "Idle is how long the user has been idle in seconds.",
+			"type": "integer"
+		},
+		"active": {
+			"description": "Active indicates if the user has been idle for less than the threshold.",
+			"type": "boolean"
+		}
+	},
+	"required": [
+		"idle",
+		"active"
+	]
+}`,
+	"memory": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "memory",
+	"description": "Memory is the payload of the memory metric. All sizes are scaled to the configured size unit, and the swap sizes are scaled to the configured swap size unit.",
+	"type": "object",
+	"properties": {
+		"total": {
+			"type": "number",
+			"minimum": 0
+		},
+		"used": {
+			"type": "number",
+			"minimum": 0
+		},
+		"available": {
+			"type": "number",
+			"minimum": 0
+		},
+		"cached": {
+			"type": "number",
+			"minimum": 0
+		},
+		"free": {
+			"type": "number",
+			"minimum": 0
+		},
+		"swapTotal": {
+			"type": "number",
+			"minimum": 0
+		},
+		"swapUsed": {
+			"type": "number",
+			"minimum": 0
+		},
+		"swapFree": {
+			"type": "number",
+			"minimum": 0
+		}
+	},
+	"required": [
+		"total",
+		"used",
+		"available",
+		"cached",
+		"free"
+	]
+}`,
+	"net": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "net",
+	"description": "Net is the payload of the net metric, mapped by the name of each interface.",
+	"type": "object",
+	"additionalProperties": {
+		"$ref": "#/$defs/Interface"
+	},
+	"$defs": {
+		"Interface": {
+			"description": "Interface is the payload of a single network interface of Net. The rates are scaled to the configured rate unit of the interface. If the interface is not running, only Running and IP are included.",
+			"type": "object",
+			"properties": {
+				"running": {
+					"type": "boolean"
+				},
+				"ip": {
+					"type": "string"
+				},
+				"download": {
+					"description": "Download is the number of bytes received since the last update.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"upload": {
+					"description": "Upload is the number of bytes transmitted since the last update.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"download_rate": {
+					"type": "number",
+					"minimum": 0
+				},
+				"upload_rate": {
+					"type": "number",
+					"minimum": 0
+				},
+				"download_total": {
+					"description": "DownloadTotal is the total number of bytes received, which keeps increasing across restarts.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"upload_total": {
+					"description": "UploadTotal is the total number of bytes transmitted, which keeps increasing across restarts.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"usage_today": {
+					"description": "UsageToday is the number of bytes received and transmitted since the start of the day.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"usage_month": {
+					"description": "UsageMonth is the number of bytes received and transmitted since the start of the billing month.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"download_rate_aggregate": {
+					"description": "DownloadRateAggregate is the rolling aggregate of DownloadRate.",
+					"$ref": "#/$defs/Aggregate"
+				},
+				"upload_rate_aggregate": {
+					"description": "UploadRateAggregate is the rolling aggregate of UploadRate.",
+					"$ref": "#/$defs/Aggregate"
+				},
+				"peers": {
+					"description": "Peers is the status of each peer of a WireGuard interface, mapped by the name of the peer.",
+					"type": "object",
+					"additionalProperties": {
+						"$ref": "#/$defs/WireGuardPeer"
+					}
+				}
+			},
+			"required": [
+				"running"
+			]
+		},
+		"Aggregate": {
+			"description": "Aggregate is the rolling average and maximum of a value over the last 1, 5, and 15 minutes, in the same unit as the value. A window that is not yet full is aggregated over the values so far.",
+			"type": "object",
+			"properties": {
+				"avg_1m": {
+					"type": "number"
+				},
+				"max_1m": {
+					"type": "number"
+				},
+				"avg_5m": {
+					"type": "number"
+				},
+				"max_5m": {
+					"type": "number"
+				},
+				"avg_15m": {
+					"type": "number"
+				},
+				"max_15m": {
+					"type": "number"
+				}
+			},
+			"required": [
+				"avg_1m",
+				"max_1m",
+				"avg_5m",
+				"max_5m",
+				"avg_15m",
+				"max_15m"
+			]
+		},
+		"WireGuardPeer": {
+			"description": "WireGuardPeer is the status of a single peer of a WireGuard Interface.",
+			"type": "object",
+			"properties": {
+				"endpoint": {
+					"type": "string"
+				},
+				"last_handshake": {
+					"description": "LastHandshake is the number of seconds since the last handshake with the peer, which is missing if there never was one.",
+					"type": "integer"
+				},
+				"received": {
+					"description": "Received is the number of bytes received from the peer.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"sent": {
+					"description": "Sent is the number of bytes sent to the peer.",
+					"type": "integer",
+					"minimum": 0
+				},
+				"connected": {
+					"description": "Connected indicates if the last handshake is recent enough for the peer to be considered connected.",
+					"type": "boolean"
+				}
+			},
+			"required": [
+				"received",
+				"sent",
+				"connected"
+			]
+		}
+	}
+}`,
+	"processes": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "processes",
+	"description": "Processes is the payload of the processes metric.",
+	"type": "object",
+	"properties": {
+		"count": {
+			"description": "Count is the number of processes.",
+			"type": "integer"
+		},
+		"sort_by": {
+			"description": "SortBy is the usage the processes are sorted by, either \"cpu\" or \"memory\".",
+			"type": "string"
+		},
+		"top": {
+			"description": "Top are the processes using the most, in descending order.",
+			"type": "array",
+			"items": {
+				"$ref": "#/$defs/Process"
+			}
+		}
+	},
+	"required": [
+		"count",
+		"sort_by",
+		"top"
+	],
+	"$defs": {
+		"Process": {
+			"description": "Process is the payload of a single process of Processes.",
+			"type": "object",
+			"properties": {
+				"pid": {
+					"type": "integer"
+				},
+				"name": {
+					"description": "Name is the command name of the process.",
+					"type": "string"
+				},
+				"cpu": {
+					"description": "CPU is the usage of every CPU by the process as a percent, averaged since the last update.",
+					"type": "number"
+				},
+				"memory": {
+					"description": "Memory is the resident memory of the process, scaled to the configured size unit.",
+					"type": "number",
+					"minimum": 0
+				}
+			},
+			"required": [
+				"pid",
+				"name",
+				"cpu",
+				"memory"
+			]
+		}
+	}
+}`,
+	"smart": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "smart",
+	"description": "Smart is the payload of the smart metric, mapped by the name of each disk.",
+	"type": "object",
+	"additionalProperties": {
+		"$ref": "#/$defs/SmartDisk"
+	},
+	"$defs": {
+		"SmartDisk": {
+			"description": "SmartDisk is the payload of a single disk of Smart. Every value but the model and temperature is only reported by smartctl.",
+			"type": "object",
+			"properties": {
+				"model": {
+					"type": "string"
+				},
+				"passed": {
+					"description": "Passed is the overall SMART health assessment of the disk. If false, the disk is failing or predicted to fail.",
+					"type": "boolean"
+				},
+				"temperature": {
+					"description": "Temperature is the temperature of the disk in °C.",
+					"type": "number"
+				},
+				"power_on_hours": {
+					"description": "PowerOnHours is how long the disk has been powered on in hours.",
+					"type": "integer"
+				},
+				"reallocated_sectors": {
+					"description": "Reallocated is the number of reallocated sectors of an ATA disk.",
+					"type": "integer"
+				},
+				"pending_sectors": {
+					"description": "Pending is the number of sectors of an ATA disk waiting to be reallocated.",
+					"type": "integer"
+				},
+				"media_errors": {
+					"description": "MediaErrors is the number of unrecovered data integrity errors of an NVMe disk.",
+					"type": "integer"
+				},
+				"wear": {
+					"description": "Wear is the estimated percentage of the life of the disk that has been used, which may exceed 100.",
+					"type": "integer"
+				}
+			}
+		}
+	}
+}`,
+	"system": `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "system",
+	"description": "System is the payload of the system metric.",
+	"type": "object",
+	"properties": {
+		"uptime": {
+			"description": "Uptime is how long the system has been up in seconds.",
+			"type": "integer"
+		},
+		"boot_time": {
+			"description": "BootTime is the time the system booted as a Unix timestamp in seconds.",
+			"type": "integer"
+		},
+		"load1": {
+			"description": "Load1 is the load average over the last minute.",
+			"type": "number"
+		},
+		"load5": {
+			"description": "Load5 is the load average over the last 5 minutes.",
+			"type": "number"
+		},
+		"load15": {
+			"description": "Load15 is the load average over the last 15 minutes.",
+			"type": "number"
+		}
+	},
+	"required": [
+		"uptime",
+		"boot_time",
+		"load1",
+		"load5",
+		"load15"
+	]
+}`,
+}
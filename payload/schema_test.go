@@ -0,0 +1,189 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validate checks v against the subset of JSON Schema used by the generated
+// schemas, resolving references in root.
+func validate(root, s map[string]any, v any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: unresolved $ref %s", path, ref)
+		}
+
+		return validate(root, def, v, path)
+	}
+
+	switch s["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", path, v)
+		}
+
+		required, _ := s["required"].([]any)
+
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, key)
+			}
+		}
+
+		props, _ := s["properties"].(map[string]any)
+
+		for key, val := range obj {
+			prop, ok := props[key].(map[string]any)
+			if !ok {
+				prop, ok = s["additionalProperties"].(map[string]any)
+			}
+
+			if !ok {
+				return fmt.Errorf("%s: unknown property %s", path, key)
+			}
+
+			if err := validate(root, prop, val, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", path, v)
+		}
+
+		for i, val := range arr {
+			if err := validate(root, s["items"].(map[string]any), val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: want %s, got %T", path, s["type"], v)
+		}
+
+		if _, err := n.Int64(); err != nil && s["type"] == "integer" {
+			return fmt.Errorf("%s: want integer, got %s", path, n)
+		}
+
+		if f, _ := n.Float64(); s["minimum"] != nil && f < 0 {
+			return fmt.Errorf("%s: want minimum 0, got %s", path, n)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: want string, got %T", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", path, v)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %v", path, s["type"])
+	}
+
+	return nil
+}
+
+func decode(data []byte) (v any, err error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	err = d.Decode(&v)
+
+	return
+}
+
+// TestSchema checks that the example payloads match the schema of their metric.
+func TestSchema(t *testing.T) {
+	metrics := map[reflect.Type]string{
+		reflect.TypeFor[*Audio]():     "audio",
+		reflect.TypeFor[*Battery]():   "battery",
+		reflect.TypeFor[*CPU]():       "cpu",
+		reflect.TypeFor[*Dir]():       "dir",
+		reflect.TypeFor[*Disks]():     "disks",
+		reflect.TypeFor[*Fans]():      "fans",
+		reflect.TypeFor[*GPU]():       "gpu",
+		reflect.TypeFor[*Idle]():      "idle",
+		reflect.TypeFor[*Memory]():    "memory",
+		reflect.TypeFor[*Net]():       "net",
+		reflect.TypeFor[*Processes](): "processes",
+		reflect.TypeFor[*System]():    "system",
+		reflect.TypeFor[*Smart]():     "smart",
+		reflect.TypeFor[*Energy]():    "energy",
+	}
+
+	if got, want := SchemaTypes(), slices.Sorted(maps.Values(metrics)); !slices.Equal(got, want) {
+		t.Fatalf("SchemaTypes: want %v, got %v", want, got)
+	}
+
+	for _, tt := range roundTripTests {
+		metric, ok := metrics[reflect.TypeOf(tt.v)]
+		if !ok {
+			continue
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := Schema(metric)
+			if !ok {
+				t.Fatalf("Schema(%q): not found", metric)
+			}
+
+			var s map[string]any
+
+			if err := json.Unmarshal(b, &s); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := decode([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := validate(s, s, v, metric); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, ok := Schema("missing"); ok {
+		t.Error(`Schema("missing"): want false`)
+	}
+}
+
+// TestGenerated checks that schema_gen.go is up to date with the payload structs.
+func TestGenerated(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("Skipping generate:", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "schema_gen.go")
+
+	if out, err := exec.Command("go", "run", "gen.go", "-output", output).CombinedOutput(); err != nil {
+		t.Fatalf("go run gen.go: %v\n%s", err, out)
+	}
+
+	want, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile("schema_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("schema_gen.go is out of date, run go generate ./payload")
+	}
+}
@@ -0,0 +1,109 @@
+package payload
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Smart is the payload of the smart metric, mapped by the name of each disk.
+type Smart map[string]SmartDisk
+
+// SmartDisk is the payload of a single disk of [Smart]. Every value but the
+// model and temperature is only reported by smartctl.
+type SmartDisk struct {
+	Model string `json:"model,omitempty"`
+	// Passed is the overall SMART health assessment of the disk. If false, the
+	// disk is failing or predicted to fail.
+	Passed Optional[bool] `json:"passed,omitzero"`
+	// Temperature is the temperature of the disk in °C.
+	Temperature Optional[Milli] `json:"temperature,omitzero"`
+	// PowerOnHours is how long the disk has been powered on in hours.
+	PowerOnHours Optional[int64] `json:"power_on_hours,omitzero"`
+	// Reallocated is the number of reallocated sectors of an ATA disk.
+	Reallocated Optional[int64] `json:"reallocated_sectors,omitzero"`
+	// Pending is the number of sectors of an ATA disk waiting to be
+	// reallocated.
+	Pending Optional[int64] `json:"pending_sectors,omitzero"`
+	// MediaErrors is the number of unrecovered data integrity errors of an
+	// NVMe disk.
+	MediaErrors Optional[int64] `json:"media_errors,omitzero"`
+	// Wear is the estimated percentage of the life of the disk that has been
+	// used, which may exceed 100.
+	Wear Optional[int] `json:"wear,omitzero"`
+}
+
+func appendOptionalInt(b []byte, key string, v Optional[int64]) []byte {
+	if !v.Valid {
+		return b
+	}
+
+	b = append(b, ", \""...)
+	b = append(b, key...)
+	b = append(b, "\": "...)
+
+	return strconv.AppendInt(b, v.Value, 10)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of d to b.
+func (d SmartDisk) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"model\": "...)
+	// The model is read from the disk, so it needs to be escaped.
+	q, _ := json.Marshal(d.Model)
+	b = append(b, q...)
+
+	if d.Passed.Valid {
+		b = append(b, ", \"passed\": "...)
+		b = strconv.AppendBool(b, d.Passed.Value)
+	}
+
+	if d.Temperature.Valid {
+		b = append(b, ", \"temperature\": "...)
+		b, _ = d.Temperature.Value.AppendText(b)
+	}
+
+	b = appendOptionalInt(b, "power_on_hours", d.PowerOnHours)
+	b = appendOptionalInt(b, "reallocated_sectors", d.Reallocated)
+	b = appendOptionalInt(b, "pending_sectors", d.Pending)
+	b = appendOptionalInt(b, "media_errors", d.MediaErrors)
+
+	if d.Wear.Valid {
+		b = append(b, ", \"wear\": "...)
+		b = strconv.AppendInt(b, int64(d.Wear.Value), 10)
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [SmartDisk.AppendText](nil).
+func (d SmartDisk) MarshalJSON() ([]byte, error) {
+	return d.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s Smart) AppendText(b []byte) ([]byte, error) {
+	b = append(b, '{')
+
+	first := true
+
+	for name, d := range s {
+		if !first {
+			b = append(b, ',', ' ')
+		}
+
+		b = append(b, '"')
+		b = append(b, name...)
+		b = append(b, '"', ':', ' ')
+		b, _ = d.AppendText(b)
+
+		first = false
+	}
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [Smart.AppendText](nil).
+func (s Smart) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
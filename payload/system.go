@@ -0,0 +1,39 @@
+package payload
+
+import "strconv"
+
+// System is the payload of the system metric.
+type System struct {
+	// Uptime is how long the system has been up in seconds.
+	Uptime int64 `json:"uptime"`
+	// BootTime is the time the system booted as a Unix timestamp in seconds.
+	BootTime int64 `json:"boot_time"`
+	// Load1 is the load average over the last minute.
+	Load1 Milli `json:"load1"`
+	// Load5 is the load average over the last 5 minutes.
+	Load5 Milli `json:"load5"`
+	// Load15 is the load average over the last 15 minutes.
+	Load15 Milli `json:"load15"`
+}
+
+// AppendText implements [encoding.TextAppender] and appends the JSON-encoded
+// representation of s to b.
+func (s System) AppendText(b []byte) ([]byte, error) {
+	b = append(b, "{\"uptime\": "...)
+	b = strconv.AppendInt(b, s.Uptime, 10)
+	b = append(b, ", \"boot_time\": "...)
+	b = strconv.AppendInt(b, s.BootTime, 10)
+	b = append(b, ", \"load1\": "...)
+	b, _ = s.Load1.AppendText(b)
+	b = append(b, ", \"load5\": "...)
+	b, _ = s.Load5.AppendText(b)
+	b = append(b, ", \"load15\": "...)
+	b, _ = s.Load15.AppendText(b)
+
+	return append(b, '}'), nil
+}
+
+// MarshalJSON implements [json.Marshaler] and is equivalent to [System.AppendText](nil).
+func (s System) MarshalJSON() ([]byte, error) {
+	return s.AppendText(nil)
+}
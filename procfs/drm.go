@@ -0,0 +1,136 @@
+package procfs
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// DRMClient is the usage of a DRM client from the fdinfo of one of its file
+// descriptors, /proc/<pid>/fdinfo/<fd>. See
+// https://docs.kernel.org/gpu/drm-usage-stats.html
+type DRMClient struct {
+	Driver string
+	// PDev is the PCI bus location of the device, such as "0000:00:02.0".
+	PDev string
+	// ID is the unique id of the client, which is shared by every file
+	// descriptor of the client, even across processes.
+	ID uint64
+	// Engines is the usage of each engine class of the client.
+	Engines map[string]DRMEngine
+}
+
+// DRMEngine is the usage of an engine class of a [DRMClient]. Drivers report
+// either the time the engine was busy or the cycles it was busy along with
+// the total cycles of the GPU.
+type DRMEngine struct {
+	// Busy is the time the engine was busy in ns, or the cycles if Total is
+	// not 0.
+	Busy uint64
+	// Total is the total cycles of the GPU, or 0 if Busy is a time.
+	Total uint64
+	// Capacity is the number of engines of the class, which is at least 1.
+	Capacity uint64
+}
+
+// DRMClients returns the DRM clients of the file descriptors of p. The same
+// client may be returned more than once. Any file descriptors that can't be
+// read are skipped.
+func (fs FS) DRMClients(p Proc) ([]DRMClient, error) {
+	dir := p.dir + "/fdinfo"
+
+	names, err := fs.root.ReadDirNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []DRMClient
+
+	for _, name := range names {
+		data, err := fs.root.Read(dir + "/" + name)
+		if err != nil {
+			continue
+		}
+
+		if c, ok := ParseDRMFdinfo(data); ok {
+			clients = append(clients, c)
+		}
+	}
+
+	return clients, nil
+}
+
+var (
+	drmDriver      = []byte("drm-driver")
+	drmPDev        = []byte("drm-pdev")
+	drmClientID    = []byte("drm-client-id")
+	drmEngine      = []byte("drm-engine-")
+	drmCapacity    = []byte("drm-engine-capacity-")
+	drmCycles      = []byte("drm-cycles-")
+	drmTotalCycles = []byte("drm-total-cycles-")
+)
+
+// ParseDRMFdinfo parses the contents of /proc/<pid>/fdinfo/<fd> and reports
+// whether it's the fdinfo of a DRM client.
+func ParseDRMFdinfo(data []byte) (DRMClient, bool) {
+	var c DRMClient
+
+	set := func(class []byte, f func(*DRMEngine)) {
+		if c.Engines == nil {
+			c.Engines = make(map[string]DRMEngine)
+		}
+
+		e := c.Engines[string(class)]
+		f(&e)
+		c.Engines[string(class)] = e
+	}
+
+	for line := range bytes.Lines(data) {
+		key, val, ok := bytes.Cut(line, []byte{':'})
+		if !ok {
+			continue
+		}
+
+		val = bytes.TrimSpace(val)
+
+		switch {
+		case bytes.Equal(key, drmDriver):
+			c.Driver = string(val)
+		case bytes.Equal(key, drmPDev):
+			c.PDev = string(val)
+		case bytes.Equal(key, drmClientID):
+			c.ID, _ = strconv.ParseUint(string(val), 10, 64)
+		case bytes.HasPrefix(key, drmCapacity):
+			n, err := strconv.ParseUint(string(val), 10, 64)
+			if err == nil && n > 0 {
+				set(key[len(drmCapacity):], func(e *DRMEngine) { e.Capacity = n })
+			}
+		case bytes.HasPrefix(key, drmEngine):
+			// The time is always in ns.
+			v, _, _ := bytes.Cut(val, []byte{' '})
+
+			n, err := strconv.ParseUint(string(v), 10, 64)
+			if err == nil {
+				set(key[len(drmEngine):], func(e *DRMEngine) { e.Busy = n })
+			}
+		case bytes.HasPrefix(key, drmCycles):
+			n, err := strconv.ParseUint(string(val), 10, 64)
+			if err == nil {
+				set(key[len(drmCycles):], func(e *DRMEngine) { e.Busy = n })
+			}
+		case bytes.HasPrefix(key, drmTotalCycles):
+			n, err := strconv.ParseUint(string(val), 10, 64)
+			if err == nil {
+				set(key[len(drmTotalCycles):], func(e *DRMEngine) { e.Total = n })
+			}
+		}
+	}
+
+	for class, e := range c.Engines {
+		if e.Capacity == 0 {
+			e.Capacity = 1
+			c.Engines[class] = e
+		}
+	}
+
+	return c, c.Driver != ""
+}
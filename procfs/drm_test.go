@@ -0,0 +1,49 @@
+package procfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDRMFdinfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want DRMClient
+	}{
+		{
+			"i915",
+			"pos:\t0\nflags:\t02100002\nmnt_id:\t26\nino:\t685\ndrm-driver:\ti915\ndrm-client-id:\t7\ndrm-pdev:\t0000:00:02.0\ndrm-engine-render:\t25662044495 ns\ndrm-engine-copy:\t0 ns\ndrm-engine-video:\t1048576 ns\ndrm-engine-capacity-video:\t2\n",
+			DRMClient{Driver: "i915", PDev: "0000:00:02.0", ID: 7, Engines: map[string]DRMEngine{
+				"render": {Busy: 25662044495, Capacity: 1},
+				"copy":   {Capacity: 1},
+				"video":  {Busy: 1048576, Capacity: 2},
+			}},
+		},
+		{
+			"xe",
+			"drm-driver:\txe\ndrm-client-id:\t12\ndrm-pdev:\t0000:03:00.0\ndrm-total-cycles-rcs:\t2000000\ndrm-cycles-rcs:\t500000\ndrm-total-cycles-vcs:\t2000000\ndrm-cycles-vcs:\t0\n",
+			DRMClient{Driver: "xe", PDev: "0000:03:00.0", ID: 12, Engines: map[string]DRMEngine{
+				"rcs": {Busy: 500000, Total: 2000000, Capacity: 1},
+				"vcs": {Total: 2000000, Capacity: 1},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseDRMFdinfo([]byte(tt.data))
+			if !ok {
+				t.Fatal("want DRM client")
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	if _, ok := ParseDRMFdinfo([]byte("pos:\t0\nflags:\t02000002\nmnt_id:\t15\nino:\t1057\n")); ok {
+		t.Error("Not DRM: want false")
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"sync"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 // Mount describes a mount according to /proc/1/mounts (or /proc/self/mounts)
@@ -23,8 +23,8 @@ var (
 	nullfs   = []byte("nullfs")
 )
 
-func validFSTypes() (map[string]bool, error) {
-	f, err := Filesystems()
+func (fs FS) validFSTypes() (map[string]bool, error) {
+	f, err := fs.Filesystems()
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +62,7 @@ func validFSTypes() (map[string]bool, error) {
 	return fstypes, nil
 }
 
-const fstabPath = file.Separator + "etc" + file.Separator + "fstab"
+const fstabPath = vfs.Separator + "etc" + vfs.Separator + "fstab"
 
 var (
 	fstab     map[string]bool
@@ -78,11 +78,11 @@ var (
 	swapMnt = []byte("swap")
 )
 
-func fstabDisks() error {
+func (fs FS) fstabDisks() error {
 	fstabMu.Lock()
 	defer fstabMu.Unlock()
 
-	sec, nsec, err := file.ModifyTime(fstabPath)
+	sec, nsec, err := fs.root.ModifyTime(fstabPath)
 	if err != nil {
 		return err
 	}
@@ -102,7 +102,7 @@ func fstabDisks() error {
 		clear(fstab)
 	}
 
-	f, err := file.Open(fstabPath)
+	f, err := fs.root.Open(fstabPath)
 	if err != nil {
 		return err
 	}
@@ -130,7 +130,7 @@ func fstabDisks() error {
 			continue
 		}
 
-		fstab[string(mnt)] = true
+		fstab[unescape(mnt)] = true
 	}
 
 	log.Debug("procfs.MountInfo", "fstab", fstab)
@@ -138,24 +138,19 @@ func fstabDisks() error {
 	return nil
 }
 
-func findMounts(search map[string]*Mount, valid map[string]bool, useFSTab bool) error {
+func (fs FS) findMounts(search map[string]*Mount, valid map[string]bool, useFSTab bool) error {
 	if useFSTab {
 		fstabMu.Lock()
 		defer fstabMu.Unlock()
 	}
 
-	f, err := Mounts()
+	f, err := fs.Mounts()
 	if err != nil {
 		return err
 	}
 
 	defer f.Close()
 
-	var (
-		cols             int
-		dev, mnt, fstype []byte
-	)
-
 	for {
 		line, err := f.ReadLine()
 		if err == io.EOF {
@@ -167,17 +162,11 @@ func findMounts(search map[string]*Mount, valid map[string]bool, useFSTab bool)
 			return err
 		}
 
-		cols, _ = byteutil.Columns(line, &dev, &mnt, &fstype)
-		if cols < 3 {
+		info, ok := parseMount(line)
+		if !ok {
 			continue
 		}
 
-		info := &Mount{
-			Dev:    string(dev),
-			Mnt:    string(mnt),
-			FSType: string(fstype),
-		}
-
 		log.Debug("findMounts", "mnt", info.Mnt, "matchFSTab", useFSTab && fstab[info.Mnt], "matchValid", !useFSTab && valid[info.FSType])
 
 		if (useFSTab && fstab[info.Mnt]) || (!useFSTab && valid[info.FSType]) {
@@ -189,10 +178,54 @@ func findMounts(search map[string]*Mount, valid map[string]bool, useFSTab bool)
 	return nil
 }
 
+// parseMount parses a line of /proc/mounts, returning false if it has less
+// than the 3 columns of the device, mount point and filesystem type.
+func parseMount(line []byte) (*Mount, bool) {
+	var dev, mnt, fstype []byte
+
+	if cols, _ := byteutil.Columns(line, &dev, &mnt, &fstype); cols < 3 {
+		return nil, false
+	}
+
+	return &Mount{
+		Dev:    unescape(dev),
+		Mnt:    unescape(mnt),
+		FSType: unescape(fstype),
+	}, true
+}
+
+// unescape returns b with the octal escapes of whitespace and backslashes
+// replaced, such as "\040" for a space in the mount point "/mnt/my\040disk".
+func unescape(b []byte) string {
+	if bytes.IndexByte(b, '\\') < 0 {
+		return string(b)
+	}
+
+	s := make([]byte, 0, len(b))
+
+	for i := 0; i < len(b); i++ {
+		// Octal escapes above \377 don't fit in a byte, so they are kept.
+		if b[i] == '\\' && i+3 < len(b) && '0' <= b[i+1] && b[i+1] <= '3' && isOctal(b[i+2]) && isOctal(b[i+3]) {
+			s = append(s, (b[i+1]-'0')<<6|(b[i+2]-'0')<<3|(b[i+3]-'0'))
+			i += 3
+
+			continue
+		}
+
+		s = append(s, b[i])
+	}
+
+	return string(s)
+}
+
+func isOctal(c byte) bool {
+	return '0' <= c && c <= '7'
+}
+
 // MountInfo returns the disks mounted on the system, mapped by their mounting point.
 // If useFSTab is true, the disk must be in /etc/fstab to be included.
-func MountInfo(useFSTab bool) (map[string]*Mount, error) {
-	valid, err := validFSTypes()
+func (fs FS) MountInfo(useFSTab bool) (map[string]*Mount, error) {
+	valid, err := fs.validFSTypes()
 	if err != nil {
 		return nil, err
 	}
@@ -200,14 +233,14 @@ func MountInfo(useFSTab bool) (map[string]*Mount, error) {
 	log.Debug("procfs.MountInfo", "validFSTypes", valid)
 
 	if useFSTab {
-		if err = fstabDisks(); err != nil {
+		if err = fs.fstabDisks(); err != nil {
 			return nil, err
 		}
 	}
 
 	search := make(map[string]*Mount)
 
-	if err = findMounts(search, valid, useFSTab); err != nil {
+	if err = fs.findMounts(search, valid, useFSTab); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,145 @@
+package procfs
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+func TestParseMount(t *testing.T) {
+	var tests = []struct {
+		line string
+		want Mount
+		ok   bool
+	}{
+		{"/dev/sda1 / ext4 rw,relatime 0 0", Mount{"/dev/sda1", "/", "ext4"}, true},
+		{"/dev/sdb1 /mnt/my\\040disk ext4 rw 0 0", Mount{"/dev/sdb1", "/mnt/my disk", "ext4"}, true},
+		{"/dev/sdc1\t/mnt/tab\\011and\\134slash\txfs rw 0 0", Mount{"/dev/sdc1", "/mnt/tab\tand\\slash", "xfs"}, true},
+		{"/dev/sdd1 /mnt/not\\400octal ext4", Mount{"/dev/sdd1", "/mnt/not\\400octal", "ext4"}, true},
+		{"/dev/sde1 /mnt/short\\04", Mount{}, false},
+		{"", Mount{}, false},
+	}
+	for _, tt := range tests {
+		m, ok := parseMount([]byte(tt.line))
+		if ok != tt.ok {
+			t.Errorf("%q: want ok=%v, got %v", tt.line, tt.ok, ok)
+			continue
+		}
+		if ok && *m != tt.want {
+			t.Errorf("%q: want %+v, got %+v", tt.line, tt.want, *m)
+		}
+	}
+}
+
+const (
+	testFilesystems = "nodev\tsysfs\nnodev\ttmpfs\n\text4\n\txfs\n\tsquashfs\n\tvfat\n"
+	testMounts      = "sysfs /sys sysfs rw 0 0\n" +
+		"/dev/sda1 / ext4 rw,relatime 0 0\n" +
+		"/dev/sda2 /mnt/my\\040disk xfs rw 0 0\n" +
+		"/dev/loop0 /snap/core squashfs ro 0 0\n" +
+		"tmpfs /run tmpfs rw 0 0\n" +
+		"/dev/sdb1 /boot/efi vfat rw 0 0\n" +
+		"rpool/data /data zfs rw 0 0\n"
+)
+
+func TestMountInfo(t *testing.T) {
+	var tests = []struct {
+		name     string
+		files    map[string]string
+		useFSTab bool
+		want     []string
+	}{
+		{
+			"FSTypes",
+			map[string]string{"proc/filesystems": testFilesystems, "proc/1/mounts": testMounts},
+			false,
+			[]string{"/", "/boot/efi", "/data", "/mnt/my disk"},
+		},
+		{
+			"SelfMounts",
+			map[string]string{"proc/filesystems": testFilesystems, "proc/self/mounts": "/dev/sda1 / ext4 rw 0 0\n"},
+			false,
+			[]string{"/"},
+		},
+		{
+			"FSTab",
+			map[string]string{
+				"proc/filesystems": testFilesystems,
+				"proc/1/mounts":    testMounts,
+				"etc/fstab":        "# <file system> <mount point> <type> <options> <dump> <pass>\nUUID=1234 / ext4 defaults 0 1\nUUID=5678 none swap sw 0 0\n\nUUID=ABCD /boot/efi vfat umask=0077 0 1\n",
+			},
+			true,
+			[]string{"/", "/boot/efi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, data := range tt.files {
+				name = filepath.Join(dir, name)
+
+				if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			root, err := vfs.NewRoot(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mnts, err := NewFS(root).MountInfo(tt.useFSTab)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := slices.Sorted(maps.Keys(mnts)); !slices.Equal(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func FuzzParseMount(f *testing.F) {
+	for _, s := range []string{
+		"/dev/sda1 / ext4 rw,relatime 0 0",
+		"/dev/sdb1 /mnt/my\\040disk ext4 rw 0 0",
+		"tmpfs /run tmpfs rw,nosuid,nodev 0 0",
+		"\\\\\\ \\777 \\0",
+	} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, line []byte) {
+		m, ok := parseMount(line)
+		if !ok {
+			return
+		}
+
+		for _, s := range []string{m.Dev, m.Mnt, m.FSType} {
+			if s == "" {
+				t.Errorf("%q: want non-empty fields, got %+v", line, *m)
+			}
+			if len(s) > len(line) {
+				t.Errorf("%q: field %q longer than line", line, s)
+			}
+		}
+
+		// Without any escapes, the fields are the columns of the line.
+		if !bytes.ContainsRune(line, '\\') && (strings.ContainsAny(m.Mnt, " \t") || !bytes.Contains(line, []byte(m.Mnt))) {
+			t.Errorf("%q: mount point %q not a column of line", line, m.Mnt)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+const netDevPath = MountPath + vfs.Separator + "net" + vfs.Separator + "dev" // /proc/net/dev
+
+// NetDev is the statistics of a network interface in /proc/net/dev.
+type NetDev struct {
+	Name    string
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// NetDev returns the statistics of every network interface in /proc/net/dev,
+// which are those of the network namespace of the current process.
+func (fs FS) NetDev() ([]NetDev, error) {
+	data, err := fs.root.Read(netDevPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseNetDev(data)
+}
+
+// ParseNetDev parses the contents of /proc/net/dev, such as read from within
+// another network namespace.
+func ParseNetDev(data []byte) ([]NetDev, error) {
+	var devs []NetDev
+
+	for line := range bytes.Lines(data) {
+		name, stats, ok := bytes.Cut(line, []byte{':'})
+		if !ok {
+			// The two header lines don't have a colon
+			continue
+		}
+
+		fields := bytes.Fields(stats)
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("%s: malformed line %q", netDevPath, bytes.TrimSpace(line))
+		}
+
+		rx, err := strconv.ParseUint(string(fields[0]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := strconv.ParseUint(string(fields[8]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		devs = append(devs, NetDev{
+			Name:    string(bytes.TrimSpace(name)),
+			RxBytes: rx,
+			TxBytes: tx,
+		})
+	}
+
+	return devs, nil
+}
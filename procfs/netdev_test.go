@@ -0,0 +1,70 @@
+package procfs
+
+import (
+	"slices"
+	"testing"
+)
+
+const netDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func TestParseNetDev(t *testing.T) {
+	var tests = []struct {
+		name string
+		data string
+		want []NetDev
+	}{
+		{
+			"Simple",
+			netDevHeader + `    lo:    1234      10    0    0    0     0          0         0     1234      10    0    0    0     0       0          0
+   wg0: 987654321 1000    0    0    0     0          0         0 123456789   900    0    0    0     0       0          0
+`,
+			[]NetDev{{"lo", 1234, 1234}, {"wg0", 987654321, 123456789}},
+		},
+		{
+			// The columns are only aligned up to their width, so large
+			// counters run into the colon.
+			"NoSpace",
+			netDevHeader + `  eth0:18446744073709551615 1 0 0 0 0 0 0 42 1 0 0 0 0 0 0
+`,
+			[]NetDev{{"eth0", 18446744073709551615, 42}},
+		},
+		{
+			"Names",
+			netDevHeader + `eth0.100: 1 0 0 0 0 0 0 0 2 0 0 0 0 0 0 0
+br-1a2b3c4d5e6f: 3 0 0 0 0 0 0 0 4 0 0 0 0 0 0 0
+veth9f8e@if12: 5 0 0 0 0 0 0 0 6 0 0 0 0 0 0 0
+`,
+			[]NetDev{{"eth0.100", 1, 2}, {"br-1a2b3c4d5e6f", 3, 4}, {"veth9f8e@if12", 5, 6}},
+		},
+		{
+			"Empty",
+			netDevHeader,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devs, err := ParseNetDev([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(devs, tt.want) {
+				t.Errorf("want %+v, got %+v", tt.want, devs)
+			}
+		})
+	}
+
+	for _, data := range []string{
+		"eth0: 1 2 3\n",
+		"eth0: 1 0 0 0 0 0 0 0 x 0 0 0 0 0 0 0\n",
+		"eth0: 18446744073709551616 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n",
+	} {
+		if _, err := ParseNetDev([]byte(data)); err == nil {
+			t.Errorf("%q: want error", data)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package procfs
+
+import (
+	"bytes"
+	"io"
+	"slices"
+
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+const nvidiaGPUsPath = MountPath + vfs.Separator + "driver" + vfs.Separator + "nvidia" + vfs.Separator + "gpus" // /proc/driver/nvidia/gpus
+
+// NvidiaGPU is the information of a GPU reported by the NVIDIA driver in
+// /proc/driver/nvidia/gpus/<bus>/information.
+type NvidiaGPU struct {
+	Model string
+	UUID  string
+	// Bus is the PCI bus location of the GPU, such as "0000:01:00.0".
+	Bus string
+}
+
+// NvidiaGPUs returns the GPUs reported by the NVIDIA driver, sorted by their
+// bus location.
+func (fs FS) NvidiaGPUs() ([]NvidiaGPU, error) {
+	names, err := fs.root.ReadDirNames(nvidiaGPUsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(names)
+
+	gpus := make([]NvidiaGPU, 0, len(names))
+
+	for _, name := range names {
+		gpu, err := fs.readNvidiaGPU(nvidiaGPUsPath + vfs.Separator + name + vfs.Separator + "information")
+		if err != nil {
+			return nil, err
+		}
+
+		if gpu.Bus == "" {
+			gpu.Bus = name
+		}
+
+		gpus = append(gpus, gpu)
+	}
+
+	return gpus, nil
+}
+
+func (fs FS) readNvidiaGPU(path string) (gpu NvidiaGPU, err error) {
+	f, err := fs.root.Open(path)
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	for {
+		line, err := f.ReadLine()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return gpu, err
+		}
+
+		key, val := byteutil.Field(line)
+		val = bytes.TrimSpace(val)
+
+		switch string(key) {
+		case "Model":
+			gpu.Model = string(val)
+		case "GPU UUID":
+			gpu.UUID = string(val)
+		case "Bus Location":
+			gpu.Bus = string(val)
+		}
+	}
+
+	return gpu, nil
+}
@@ -1,9 +1,9 @@
 package procfs
 
 import (
+	"bytes"
+	"fmt"
 	"strconv"
-
-	"github.com/lone-faerie/mqttop/internal/file"
 )
 
 type Proc struct {
@@ -11,8 +11,13 @@ type Proc struct {
 	dir string
 }
 
-func Procs() ([]Proc, error) {
-	d, err := file.OpenDir(MountPath)
+// PID returns the process id of p.
+func (p Proc) PID() int {
+	return p.pid
+}
+
+func (fs FS) Procs() ([]Proc, error) {
+	d, err := fs.root.OpenDir(MountPath)
 	if err != nil {
 		return nil, err
 	}
@@ -37,3 +42,105 @@ func Procs() ([]Proc, error) {
 
 	return procs, nil
 }
+
+// ProcStat is the usage of a process from /proc/<pid>/stat and
+// /proc/<pid>/status.
+type ProcStat struct {
+	PID  int
+	Comm string
+	// UTime and STime are the clock ticks the process has been scheduled in
+	// user and kernel mode.
+	UTime uint64
+	STime uint64
+	// RSS is the resident set size of the process in bytes, which is 0 for
+	// kernel threads.
+	RSS uint64
+}
+
+// ProcStat returns the usage of p. If p has exited, the error wraps
+// [vfs.ErrNotExist].
+func (fs FS) ProcStat(p Proc) (ProcStat, error) {
+	data, err := fs.root.Read(p.dir + "/stat")
+	if err != nil {
+		return ProcStat{}, err
+	}
+
+	s, err := ParseProcStat(data)
+	if err != nil {
+		return ProcStat{}, fmt.Errorf("%s/stat: %w", p.dir, err)
+	}
+
+	data, err = fs.root.Read(p.dir + "/status")
+	if err != nil {
+		return ProcStat{}, err
+	}
+
+	s.RSS = parseVmRSS(data)
+
+	return s, nil
+}
+
+// ParseProcStat parses the contents of /proc/<pid>/stat. The RSS is not
+// parsed, since it's only in pages.
+func ParseProcStat(data []byte) (ProcStat, error) {
+	// The comm is in parentheses and may itself contain spaces and
+	// parentheses, so it ends at the last closing parenthesis.
+	start := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+
+	if start < 0 || end < start {
+		return ProcStat{}, fmt.Errorf("malformed stat %q", bytes.TrimSpace(data))
+	}
+
+	pid, err := strconv.Atoi(string(bytes.TrimSpace(data[:start])))
+	if err != nil {
+		return ProcStat{}, err
+	}
+
+	// The fields after the comm start with the state, which is the 3rd field,
+	// so utime and stime, the 14th and 15th fields, are at 11 and 12.
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 13 {
+		return ProcStat{}, fmt.Errorf("malformed stat %q", bytes.TrimSpace(data))
+	}
+
+	s := ProcStat{
+		PID:  pid,
+		Comm: string(data[start+1 : end]),
+	}
+
+	if s.UTime, err = strconv.ParseUint(string(fields[11]), 10, 64); err != nil {
+		return ProcStat{}, err
+	}
+
+	if s.STime, err = strconv.ParseUint(string(fields[12]), 10, 64); err != nil {
+		return ProcStat{}, err
+	}
+
+	return s, nil
+}
+
+var vmRSSPrefix = []byte("VmRSS:")
+
+// parseVmRSS returns the VmRSS of the contents of /proc/<pid>/status in bytes,
+// or 0 if it's missing.
+func parseVmRSS(data []byte) uint64 {
+	for line := range bytes.Lines(data) {
+		val, ok := bytes.CutPrefix(line, vmRSSPrefix)
+		if !ok {
+			continue
+		}
+
+		// The value is always in kB.
+		kb, _, _ := bytes.Cut(bytes.TrimSpace(val), []byte{' '})
+
+		n, err := strconv.ParseUint(string(kb), 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		return n * 1024
+	}
+
+	return 0
+}
@@ -0,0 +1,43 @@
+package procfs
+
+import "testing"
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want ProcStat
+	}{
+		{"Simple", "26231 (vim) R 5392 7446 5392 34835 7446 4218880 32533 309516 26 82 1677 44 158 99 20 0 1 0 82375 56274944 1981\n", ProcStat{PID: 26231, Comm: "vim", UTime: 1677, STime: 44}},
+		{"Parentheses", "1020 ((a b ) ( c d) ) R 28378 1020 28378 34842 1020 4218880 286 0 0 0 3 4 0 0 20 0\n", ProcStat{PID: 1020, Comm: "(a b ) ( c d) ", UTime: 3, STime: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProcStat([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ParseProcStat([]byte("42 (truncated) S 1 2 3\n")); err == nil {
+		t.Error("Malformed: want error")
+	}
+}
+
+func TestParseVmRSS(t *testing.T) {
+	data := []byte("Name:\tprometheus\nVmHWM:\t    8028 kB\nVmRSS:\t    6716 kB\nRssAnon:\t    2092 kB\n")
+
+	if got, want := parseVmRSS(data), uint64(6716*1024); got != want {
+		t.Errorf("want %d, got %d", want, got)
+	}
+
+	if got := parseVmRSS([]byte("Name:\tkthreadd\n")); got != 0 {
+		t.Errorf("kernel thread: want 0, got %d", got)
+	}
+}
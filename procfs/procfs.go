@@ -3,76 +3,91 @@ package procfs
 
 import (
 	"errors"
-	"io/fs"
 	"path/filepath"
 
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 const (
-	MountPath = file.Separator + "proc" // /proc
+	MountPath = vfs.Separator + "proc" // /proc
 )
 
 const (
-	cpuInfoPath    = MountPath + file.Separator + "cpuinfo"                       // /proc/cpuinfo
-	memInfoPath    = MountPath + file.Separator + "meminfo"                       // /proc/meminfo
-	fsPath         = MountPath + file.Separator + "filesystems"                   // /proc/filesystems
-	statPath       = MountPath + file.Separator + "stat"                          // /proc/stat
-	selfPath       = MountPath + file.Separator + "self"                          // /proc/self
-	mountsPath     = MountPath + file.Separator + "1" + file.Separator + "mounts" // /proc/1/mounts
-	selfMountsPath = selfPath + file.Separator + "mounts"                         // /proc/self/mounts
+	cpuInfoPath    = MountPath + vfs.Separator + "cpuinfo"                      // /proc/cpuinfo
+	memInfoPath    = MountPath + vfs.Separator + "meminfo"                      // /proc/meminfo
+	fsPath         = MountPath + vfs.Separator + "filesystems"                  // /proc/filesystems
+	statPath       = MountPath + vfs.Separator + "stat"                         // /proc/stat
+	selfPath       = MountPath + vfs.Separator + "self"                         // /proc/self
+	mountsPath     = MountPath + vfs.Separator + "1" + vfs.Separator + "mounts" // /proc/1/mounts
+	selfMountsPath = selfPath + vfs.Separator + "mounts"                        // /proc/self/mounts
 )
 
 type (
-	File = file.File
-	Dir  = file.Dir
+	File = vfs.File
+	Dir  = vfs.Dir
 )
 
+// FS is the proc filesystem mounted at /proc in a [vfs.Root]. The zero FS is
+// the proc filesystem of the host, see [vfs.Host].
+type FS struct {
+	root *vfs.Root
+}
+
+// NewFS returns the proc filesystem of root.
+func NewFS(root *vfs.Root) FS {
+	return FS{root: root}
+}
+
+// Root returns the root of fs.
+func (fs FS) Root() *vfs.Root {
+	return fs.root
+}
+
 func Path(elem ...string) string {
-	return MountPath + file.Separator + filepath.Join(elem...)
+	return MountPath + vfs.Separator + filepath.Join(elem...)
 }
 
 // CPUInfo returns the file /proc/cpuinfo
-func CPUInfo() (*File, error) {
-	return file.Open(cpuInfoPath)
+func (fs FS) CPUInfo() (*File, error) {
+	return fs.root.Open(cpuInfoPath)
 }
 
 // MemInfo returns the file /proc/meminfo
-func MemInfo() (*File, error) {
-	return file.Open(memInfoPath)
+func (fs FS) MemInfo() (*File, error) {
+	return fs.root.Open(memInfoPath)
 }
 
 // Stat returns the file /proc/stat
-func Stat() (*File, error) {
-	return file.Open(statPath)
+func (fs FS) Stat() (*File, error) {
+	return fs.root.Open(statPath)
 }
 
 // Self returns the directory /proc/self
-func Self() (*Dir, error) {
-	return file.OpenDir(selfPath)
+func (fs FS) Self() (*Dir, error) {
+	return fs.root.OpenDir(selfPath)
 }
 
 // SelfMounts returns the file /proc/self/mounts
-func SelfMounts() (*File, error) {
-	return file.Open(selfMountsPath)
+func (fs FS) SelfMounts() (*File, error) {
+	return fs.root.Open(selfMountsPath)
 }
 
 // Mounts returns the file /proc/1/mounts, or /proc/self/mounts if
 // /proc/1/mounts cannot be opened
-func Mounts() (*File, error) {
-	f, err := file.Open(mountsPath)
+func (fs FS) Mounts() (*File, error) {
+	f, err := fs.root.Open(mountsPath)
 	if err == nil {
 		return f, err
 	}
 
-	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
-		f, err = file.Open(selfMountsPath)
+	if errors.Is(err, vfs.ErrNotExist) || errors.Is(err, vfs.ErrPermission) {
+		f, err = fs.root.Open(selfMountsPath)
 	}
 
 	return f, err
 }
 
 // Filesystems returns the file /proc/filesystems
-func Filesystems() (*File, error) {
-	return file.Open(fsPath)
+func (fs FS) Filesystems() (*File, error) {
+	return fs.root.Open(fsPath)
 }
@@ -0,0 +1,107 @@
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lone-faerie/mqttop/internal/byteutil"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+const (
+	uptimePath  = MountPath + vfs.Separator + "uptime"  // /proc/uptime
+	loadAvgPath = MountPath + vfs.Separator + "loadavg" // /proc/loadavg
+)
+
+// LoadAvg is the load average of the system in /proc/loadavg, in thousandths.
+type LoadAvg struct {
+	Load1  int64
+	Load5  int64
+	Load15 int64
+}
+
+// LoadAvg returns the 1, 5 and 15 minute load averages in /proc/loadavg.
+func (fs FS) LoadAvg() (LoadAvg, error) {
+	data, err := fs.root.Read(loadAvgPath)
+	if err != nil {
+		return LoadAvg{}, err
+	}
+
+	return ParseLoadAvg(data)
+}
+
+// ParseLoadAvg parses the contents of /proc/loadavg.
+func ParseLoadAvg(data []byte) (LoadAvg, error) {
+	fields := bytes.Fields(data)
+	if len(fields) < 3 {
+		return LoadAvg{}, fmt.Errorf("%s: malformed %q", loadAvgPath, bytes.TrimSpace(data))
+	}
+
+	var (
+		load [3]int64
+		err  error
+	)
+
+	for i := range load {
+		if load[i], err = byteutil.ParseDecimal(fields[i], 3); err != nil {
+			return LoadAvg{}, fmt.Errorf("%s: %w", loadAvgPath, err)
+		}
+	}
+
+	return LoadAvg{Load1: load[0], Load5: load[1], Load15: load[2]}, nil
+}
+
+// Uptime returns how long the system has been up from /proc/uptime.
+func (fs FS) Uptime() (time.Duration, error) {
+	data, err := fs.root.Read(uptimePath)
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseUptime(data)
+}
+
+// ParseUptime parses the contents of /proc/uptime, of which only the uptime
+// is used and not the idle time.
+func ParseUptime(data []byte) (time.Duration, error) {
+	fields := bytes.Fields(data)
+	if len(fields) < 1 {
+		return 0, fmt.Errorf("%s: malformed %q", uptimePath, bytes.TrimSpace(data))
+	}
+
+	ms, err := byteutil.ParseDecimal(fields[0], 3)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", uptimePath, err)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// BootTime returns the time the system booted from the btime line of
+// /proc/stat.
+func (fs FS) BootTime() (time.Time, error) {
+	stat, err := fs.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer stat.Close()
+
+	for line := range stat.Lines() {
+		val, ok := bytes.CutPrefix(line, []byte("btime "))
+		if !ok {
+			continue
+		}
+
+		sec, err := strconv.ParseInt(string(bytes.TrimSpace(val)), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", statPath, err)
+		}
+
+		return time.Unix(sec, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%s: missing btime", statPath)
+}
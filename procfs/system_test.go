@@ -0,0 +1,40 @@
+package procfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLoadAvg(t *testing.T) {
+	got, err := ParseLoadAvg([]byte("0.02 1.50 12.05 1/497 11947\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (LoadAvg{Load1: 20, Load5: 1500, Load15: 12050}); got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	for _, data := range []string{"", "0.02 0.04\n", "0.02 x 0.05 1/497 11947\n"} {
+		if _, err := ParseLoadAvg([]byte(data)); err == nil {
+			t.Errorf("%q: want error", data)
+		}
+	}
+}
+
+func TestParseUptime(t *testing.T) {
+	got, err := ParseUptime([]byte("350735.47 234388.90\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 350735*time.Second + 470*time.Millisecond; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	for _, data := range []string{"", "up\n"} {
+		if _, err := ParseUptime([]byte(data)); err == nil {
+			t.Errorf("%q: want error", data)
+		}
+	}
+}
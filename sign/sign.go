@@ -0,0 +1,282 @@
+// Package sign implements the signing of metric payloads, so that consumers
+// on a shared broker can detect payloads published by anything but the
+// bridge. Each signed payload is wrapped in a JSON object,
+//
+//	{
+//		"alg": "HS256",
+//		"kid": "...",
+//		"ts": 1700000000,
+//		"payload": {...},
+//		"sig": "..."
+//	}
+//
+// where payload is the original JSON payload, and sig is the base64 encoded
+// signature of the topic, timestamp and payload, see [Message]. Signing the
+// topic and timestamp keeps a payload from being replayed to another topic,
+// or once it's older than the MaxAge of the [Verifier].
+//
+// Payloads are signed with either HMAC-SHA256 ("HS256") of a shared secret,
+// or Ed25519 ("EdDSA"), whose public key can verify but not sign payloads.
+package sign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The algorithms of signed payloads.
+const (
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+// The prefixes of encoded Ed25519 keys, which keep public and private keys
+// from being mixed up.
+const (
+	PublicKeyPrefix  = "mqttop-sig-pk-"
+	PrivateKeyPrefix = "mqttop-sig-sk-"
+)
+
+var (
+	// ErrInvalidSignature is returned by [Verifier.Verify] if the signature
+	// doesn't match the payload.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpired is returned by [Verifier.Verify] if the payload is older
+	// than the MaxAge of the verifier.
+	ErrExpired = errors.New("signature expired")
+	// ErrInvalidKey is returned when parsing an invalid key.
+	ErrInvalidKey = errors.New("invalid key")
+)
+
+// signed is the JSON object of a signed payload.
+type signed struct {
+	Alg     string          `json:"alg"`
+	KID     string          `json:"kid"`
+	TS      int64           `json:"ts"`
+	Payload json.RawMessage `json:"payload"`
+	Sig     []byte          `json:"sig"`
+}
+
+// Message returns the message that is signed for the payload of topic at the
+// unix time ts, which is the topic, ts and payload separated by newlines.
+func Message(topic string, ts int64, payload []byte) []byte {
+	msg := make([]byte, 0, len(topic)+len(payload)+22)
+	msg = append(msg, topic...)
+	msg = append(msg, '\n')
+	msg = strconv.AppendInt(msg, ts, 10)
+	msg = append(msg, '\n')
+
+	return append(msg, payload...)
+}
+
+// keyID returns the first 8 bytes of the SHA-256 hash of the key in hex. The
+// id of a HMAC secret is hashed with a prefix, so that it doesn't reveal the
+// hash of the secret itself.
+func keyID(prefix string, key []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prefix))
+	h.Write(key)
+
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// GenerateKey returns a new random Ed25519 private key.
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+// FormatPublicKey returns the encoding of k, as parsed by [ParsePublicKey].
+func FormatPublicKey(k ed25519.PublicKey) string {
+	return PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(k)
+}
+
+// FormatPrivateKey returns the encoding of the seed of k, as parsed by
+// [ParsePrivateKey].
+func FormatPrivateKey(k ed25519.PrivateKey) string {
+	return PrivateKeyPrefix + base64.RawURLEncoding.EncodeToString(k.Seed())
+}
+
+func parseKey(s, prefix string, size int) ([]byte, error) {
+	s, ok := strings.CutPrefix(strings.TrimSpace(s), prefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing prefix %q", ErrInvalidKey, prefix)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	if len(b) != size {
+		return nil, fmt.Errorf("%w: want %d bytes, got %d", ErrInvalidKey, size, len(b))
+	}
+
+	return b, nil
+}
+
+// ParsePublicKey parses the Ed25519 public key s, as formatted by
+// [FormatPublicKey].
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := parseKey(s, PublicKeyPrefix, ed25519.PublicKeySize)
+	return ed25519.PublicKey(b), err
+}
+
+// ParsePrivateKey parses the Ed25519 private key s, as formatted by
+// [FormatPrivateKey].
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := parseKey(s, PrivateKeyPrefix, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+// Signer signs payloads.
+type Signer struct {
+	alg    string
+	kid    string
+	secret []byte
+	priv   ed25519.PrivateKey
+}
+
+// NewHMAC returns a [Signer] of HMAC-SHA256 with the shared secret.
+func NewHMAC(secret []byte) *Signer {
+	return &Signer{alg: HS256, kid: keyID(HS256, secret), secret: secret}
+}
+
+// NewEd25519 returns a [Signer] of Ed25519 with the private key.
+func NewEd25519(priv ed25519.PrivateKey) *Signer {
+	pub := priv.Public().(ed25519.PublicKey)
+	return &Signer{alg: EdDSA, kid: keyID("", pub), priv: priv}
+}
+
+func sum(secret, msg []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(msg)
+
+	return mac.Sum(nil)
+}
+
+// Sign returns the signed payload of topic at the time ts. The payload must be
+// JSON, which is compacted before it's signed.
+func (s *Signer) Sign(topic string, payload []byte, ts time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, payload); err != nil {
+		return nil, err
+	}
+
+	m := signed{
+		Alg:     s.alg,
+		KID:     s.kid,
+		TS:      ts.Unix(),
+		Payload: buf.Bytes(),
+	}
+
+	msg := Message(topic, m.TS, m.Payload)
+
+	if s.priv != nil {
+		m.Sig = ed25519.Sign(s.priv, msg)
+	} else {
+		m.Sig = sum(s.secret, msg)
+	}
+
+	// The payload must be wrapped as the exact bytes that were signed, which
+	// json.Marshal doesn't do if it has any of the HTML characters it escapes.
+	var out bytes.Buffer
+
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(&m); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out.Bytes(), []byte{'\n'}), nil
+}
+
+// Verifier verifies signed payloads.
+type Verifier struct {
+	// MaxAge is how old a signed payload may be, if not 0.
+	MaxAge time.Duration
+
+	alg    string
+	kid    string
+	secret []byte
+	pub    ed25519.PublicKey
+}
+
+// NewHMACVerifier returns a [Verifier] of HMAC-SHA256 with the shared secret.
+func NewHMACVerifier(secret []byte) *Verifier {
+	return &Verifier{alg: HS256, kid: keyID(HS256, secret), secret: secret}
+}
+
+// NewEd25519Verifier returns a [Verifier] of Ed25519 with the public key.
+func NewEd25519Verifier(pub ed25519.PublicKey) *Verifier {
+	return &Verifier{alg: EdDSA, kid: keyID("", pub), pub: pub}
+}
+
+// Verify verifies the signed payload data of topic, and returns the original
+// payload along with the time it was signed. An error wrapping
+// [ErrInvalidSignature] or [ErrExpired] is returned if the payload can't be
+// trusted.
+func (v *Verifier) Verify(topic string, data []byte) ([]byte, time.Time, error) {
+	var m signed
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	ts := time.Unix(m.TS, 0)
+
+	if m.Alg != v.alg {
+		return nil, ts, fmt.Errorf("%w: want %s, got %q", ErrInvalidSignature, v.alg, m.Alg)
+	}
+
+	if m.KID != v.kid {
+		return nil, ts, fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, m.KID)
+	}
+
+	msg := Message(topic, m.TS, m.Payload)
+
+	var ok bool
+
+	if v.pub != nil {
+		ok = ed25519.Verify(v.pub, msg, m.Sig)
+	} else {
+		ok = hmac.Equal(sum(v.secret, msg), m.Sig)
+	}
+
+	if !ok {
+		return nil, ts, ErrInvalidSignature
+	}
+
+	if v.MaxAge > 0 && time.Since(ts) > v.MaxAge {
+		return nil, ts, ErrExpired
+	}
+
+	return m.Payload, ts, nil
+}
+
+// IsSigned reports whether data is a signed payload.
+func IsSigned(data []byte) bool {
+	var m struct {
+		Alg string `json:"alg"`
+		Sig []byte `json:"sig"`
+	}
+
+	return json.Unmarshal(data, &m) == nil && m.Alg != "" && len(m.Sig) > 0
+}
@@ -0,0 +1,127 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := ParsePublicKey(FormatPublicKey(priv.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		good   *Verifier
+		bad    *Verifier
+	}{
+		{"HMAC", NewHMAC([]byte("secret")), NewHMACVerifier([]byte("secret")), NewHMACVerifier([]byte("guess"))},
+		{"Ed25519", NewEd25519(priv), NewEd25519Verifier(pub), NewEd25519Verifier(other.Public().(ed25519.PublicKey))},
+	}
+
+	const topic = "mqttop/metric/cpu"
+
+	payload := []byte(`{ "usage": 12.5 }`)
+	now := time.Now()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.signer.Sign(topic, payload, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !IsSigned(data) || IsSigned(payload) {
+				t.Error("IsSigned: want true for signed only")
+			}
+
+			got, ts, err := tt.good.Verify(topic, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []byte(`{"usage":12.5}`); !bytes.Equal(got, want) {
+				t.Errorf("Payload: want %s, got %s", want, got)
+			}
+
+			if ts.Unix() != now.Unix() {
+				t.Errorf("Time: want %v, got %v", now.Unix(), ts.Unix())
+			}
+
+			if _, _, err := tt.bad.Verify(topic, data); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Wrong key: want %v, got %v", ErrInvalidSignature, err)
+			}
+
+			if _, _, err := tt.good.Verify("mqttop/metric/memory", data); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Wrong topic: want %v, got %v", ErrInvalidSignature, err)
+			}
+
+			tampered := bytes.Replace(data, []byte("12.5"), []byte("99.9"), 1)
+			if _, _, err := tt.good.Verify(topic, tampered); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Tampered: want %v, got %v", ErrInvalidSignature, err)
+			}
+
+			old, err := tt.signer.Sign(topic, payload, now.Add(-time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := *tt.good
+			v.MaxAge = time.Minute
+
+			if _, _, err := v.Verify(topic, old); !errors.Is(err, ErrExpired) {
+				t.Errorf("Old: want %v, got %v", ErrExpired, err)
+			}
+
+			// Characters escaped by json.Marshal are signed as is
+			html := []byte(`{"name":"Tom & Jerry <x>"}`)
+
+			if data, err = tt.signer.Sign(topic, html, now); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _, err = tt.good.Verify(topic, data); err != nil {
+				t.Fatalf("HTML: %v: %s", err, data)
+			}
+
+			if !bytes.Equal(got, html) {
+				t.Errorf("HTML: want %s, got %s", html, got)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParsePrivateKey(FormatPrivateKey(priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Equal(priv) {
+		t.Error("ParsePrivateKey: want round trip")
+	}
+
+	if _, err := ParsePrivateKey(FormatPublicKey(priv.Public().(ed25519.PublicKey))); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Public as private: want %v, got %v", ErrInvalidKey, err)
+	}
+}
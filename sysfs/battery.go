@@ -1,12 +1,10 @@
 package sysfs
 
 import (
-	"io/fs"
 	"log"
-	"sync"
 	"time"
 
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 type batteryFlag uint32
@@ -38,113 +36,104 @@ type Batt struct {
 	isCharging bool
 	flags      batteryFlag
 	Kind       string
-}
 
-var (
-	batteryDir  string
-	batteryErr  error
-	batteryOnce sync.Once
-)
+	root *vfs.Root
+}
 
-func getBattery() (string, error) {
-	dirs, err := file.ReadDirPaths(powerSupplyPath)
+func (fs FS) getBattery() (string, error) {
+	dirs, err := fs.root.ReadDirPaths(powerSupplyPath)
 	if err != nil {
 		return "", err
 	}
 
 	for _, dir := range dirs {
-		if !file.IsDir(dir) {
+		if !fs.root.IsDir(dir) {
 			continue
 		}
 
-		present, err := file.ReadInt(dir + file.Separator + "present")
+		present, err := fs.root.ReadInt(dir + vfs.Separator + "present")
 		if err != nil || present != 1 {
 			continue
 		}
 
-		typ, err := file.ReadString(dir + file.Separator + "type")
+		typ, err := fs.root.ReadString(dir + vfs.Separator + "type")
 		if err == nil && (typ == "Battery" || typ == "UPS") {
 			return dir, nil
 		}
 	}
 
-	return "", fs.ErrNotExist
-}
-
-func findBattery() {
-	batteryDir, batteryErr = getBattery()
+	return "", vfs.ErrNotExist
 }
 
 // Battery returns the directory to the system's battery.
-// If there is no battery on the system, Battery returns [fs.ErrNotExist]
-func Battery() (*Dir, error) {
-	batteryOnce.Do(findBattery)
-
-	if batteryErr != nil {
-		return nil, batteryErr
+// If there is no battery on the system, Battery returns [vfs.ErrNotExist].
+func (fs FS) Battery() (*Dir, error) {
+	dir, err := fs.getBattery()
+	if err != nil {
+		return nil, err
 	}
 
-	return file.OpenDir(batteryDir)
+	return fs.root.OpenDir(dir)
 }
 
 // GetBattery finds the system's battery and determines its supported
 // features. If the is no battery on the system, GetBattery returns
-// [fs.ErrNotExist]
-func GetBattery() (*Batt, error) {
-	dir, err := getBattery()
+// [vfs.ErrNotExist]
+func (fs FS) GetBattery() (*Batt, error) {
+	dir, err := fs.getBattery()
 	if err != nil {
 		return nil, err
 	}
 
-	var b Batt
+	b := Batt{root: fs.root}
 
-	if path := dir + file.Separator + "capacity"; file.Exists(path) {
+	if path := dir + vfs.Separator + "capacity"; fs.root.Exists(path) {
 		b.capacity = path
 		b.flags |= batteryCapacity
 	}
 
-	if path := dir + file.Separator + "charge_now"; file.Exists(path) {
+	if path := dir + vfs.Separator + "charge_now"; fs.root.Exists(path) {
 		b.chargeNow = path
 	}
 
-	if path := dir + file.Separator + "charge_full"; file.Exists(path) {
+	if path := dir + vfs.Separator + "charge_full"; fs.root.Exists(path) {
 		b.chargeFull = path
 	}
 
-	if path := dir + file.Separator + "energy_now"; file.Exists(path) {
+	if path := dir + vfs.Separator + "energy_now"; fs.root.Exists(path) {
 		b.energyNow = path
 	}
 
-	if path := dir + file.Separator + "energy_full"; file.Exists(path) {
+	if path := dir + vfs.Separator + "energy_full"; fs.root.Exists(path) {
 		b.energyFull = path
 	}
 
-	if path := dir + file.Separator + "power_now"; file.Exists(path) {
+	if path := dir + vfs.Separator + "power_now"; fs.root.Exists(path) {
 		b.powerNow = path
 		b.flags |= batteryPower
 	}
 
-	if path := dir + file.Separator + "current_now"; file.Exists(path) {
+	if path := dir + vfs.Separator + "current_now"; fs.root.Exists(path) {
 		b.currentNow = path
 		b.flags |= batteryCurrent
 	}
 
-	if path := dir + file.Separator + "voltage_now"; file.Exists(path) {
+	if path := dir + vfs.Separator + "voltage_now"; fs.root.Exists(path) {
 		b.voltageNow = path
 		b.flags |= batteryVoltage
 	}
 
-	if path := dir + file.Separator + "status"; file.Exists(path) {
+	if path := dir + vfs.Separator + "status"; fs.root.Exists(path) {
 		b.status = path
 		b.flags |= batteryStatus
 	}
 
-	if path := dir + file.Separator + "time_to_empty"; file.Exists(path) {
+	if path := dir + vfs.Separator + "time_to_empty"; fs.root.Exists(path) {
 		b.timeToEmpty = path
 		b.flags |= batteryTime
 	}
 
-	tech, err := file.ReadString(dir + file.Separator + "technology")
+	tech, err := fs.root.ReadString(dir + vfs.Separator + "technology")
 	if err == nil {
 		b.Kind = tech
 	}
@@ -162,17 +151,17 @@ func GetBattery() (*Batt, error) {
 
 // ReadCapacity returns the contents of /sys/class/power_supply/<battery>/capacity.
 func (b *Batt) ReadCapacity() (int64, error) {
-	return file.ReadInt(b.capacity)
+	return b.root.ReadInt(b.capacity)
 }
 
 // ReadCharge returns the contents of /sys/class/power_supply/<battery>/charge_now and
 // /sys/class/power_supply/<battery>/charge_full.
 func (b *Batt) ReadCharge() (now, full int64, err error) {
-	if now, err = file.ReadInt(b.chargeNow); err != nil {
+	if now, err = b.root.ReadInt(b.chargeNow); err != nil {
 		return
 	}
 
-	full, err = file.ReadInt(b.chargeFull)
+	full, err = b.root.ReadInt(b.chargeFull)
 
 	return
 }
@@ -180,38 +169,38 @@ func (b *Batt) ReadCharge() (now, full int64, err error) {
 // ReadEnergy returns the contents of /sys/class/power_supply/<battery>/energy_now and
 // /sys/class/power_supply/<battery>/energy_full.
 func (b *Batt) ReadEnergy() (now, full int64, err error) {
-	if now, err = file.ReadInt(b.energyNow); err != nil {
+	if now, err = b.root.ReadInt(b.energyNow); err != nil {
 		return
 	}
 
-	full, err = file.ReadInt(b.energyFull)
+	full, err = b.root.ReadInt(b.energyFull)
 
 	return
 }
 
 // ReadPower returns the contents of /sys/class/power_supply/<battery>/power_now.
 func (b *Batt) ReadPower() (int64, error) {
-	return file.ReadInt(b.powerNow)
+	return b.root.ReadInt(b.powerNow)
 }
 
 // ReadCurrent returns the contents of /sys/class/power_supply/<battery>/current_now.
 func (b *Batt) ReadCurrent() (int64, error) {
-	return file.ReadInt(b.currentNow)
+	return b.root.ReadInt(b.currentNow)
 }
 
 // ReadVoltage returns the contents of /sys/class/power_supply/<battery>/voltage_now.
 func (b *Batt) ReadVoltage() (int64, error) {
-	return file.ReadInt(b.voltageNow)
+	return b.root.ReadInt(b.voltageNow)
 }
 
 // ReadStatus returns the contents of /sys/class/power_supply/<battery>/status.
 func (b *Batt) ReadStatus() (string, error) {
-	return file.ReadLower(b.status)
+	return b.root.ReadLower(b.status)
 }
 
 // ReadTimeRemaining returns the contents of /sys/class/power_supply/<battery>/time_to_empty.
 func (b *Batt) ReadTimeRemaining() (int64, error) {
-	return file.ReadInt(b.timeToEmpty)
+	return b.root.ReadInt(b.timeToEmpty)
 }
 
 // HasCapacity returns true if b supports reading capacity.
@@ -261,7 +250,7 @@ func (b *Batt) Capacity() (int, error) {
 
 	switch {
 	case b.HasCapacity():
-		i, err := file.ReadInt(b.capacity)
+		i, err := b.root.ReadInt(b.capacity)
 
 		return int(i), err
 	case b.HasCharge():
@@ -274,12 +263,12 @@ func (b *Batt) Capacity() (int, error) {
 		return 0, nil
 	}
 
-	n, err := file.ReadInt(now)
+	n, err := b.root.ReadInt(now)
 	if err != nil {
 		return 0, err
 	}
 
-	f, err := file.ReadInt(full)
+	f, err := b.root.ReadInt(full)
 	if err != nil {
 		return 0, err
 	}
@@ -294,7 +283,7 @@ func (b *Batt) Capacity() (int, error) {
 // Status returns the current status of b. One of "charging", "discharging", "not charging"
 // "full", or "unknown".
 func (b *Batt) Status() (string, error) {
-	stat, err := file.ReadLower(b.status)
+	stat, err := b.root.ReadLower(b.status)
 	if err == nil {
 		b.isCharging = stat == "charging" || stat == "full"
 	}
@@ -324,7 +313,7 @@ func (b *Batt) EstTimeRemaining() (time.Duration, error) {
 	case b.HasTimeRemaining():
 		log.Println("Using time_to_empty")
 
-		x, err := file.ReadInt(b.timeToEmpty)
+		x, err := b.root.ReadInt(b.timeToEmpty)
 
 		return time.Duration(x), err
 	default:
@@ -333,12 +322,12 @@ func (b *Batt) EstTimeRemaining() (time.Duration, error) {
 		return 0, nil
 	}
 
-	x, err := file.ReadUint(xp)
+	x, err := b.root.ReadUint(xp)
 	if err != nil {
 		return 0, err
 	}
 
-	y, err := file.ReadUint(yp)
+	y, err := b.root.ReadUint(yp)
 	if err != nil {
 		return 0, err
 	}
@@ -1,14 +1,18 @@
 package sysfs
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"golang.org/x/sys/unix"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
 	"github.com/lone-faerie/mqttop/log"
 	"github.com/lone-faerie/mqttop/procfs"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 // BlockIO represents the reads and writes of a block device.
@@ -16,6 +20,7 @@ type BlockIO struct {
 	old blockIO
 
 	stat string
+	root *vfs.Root
 }
 
 type blockIO struct {
@@ -24,7 +29,7 @@ type blockIO struct {
 	ticks  int64
 }
 
-func BlockStat(mnt *procfs.Mount) BlockIO {
+func (fs FS) BlockStat(mnt *procfs.Mount) BlockIO {
 	var (
 		name = filepath.Base(mnt.Dev)
 		c    = 0
@@ -35,19 +40,19 @@ func BlockStat(mnt *procfs.Mount) BlockIO {
 
 		log.Debug("BlockStat", "Path", p)
 
-		if _, err := file.Stat(p); err == nil && unix.Access(p, unix.R_OK) == nil {
+		if _, err := fs.root.Stat(p); err == nil && unix.Access(fs.root.Abs(p), unix.R_OK) == nil {
 			log.Debug("OK")
 
 			if c == 0 {
-				return BlockIO{stat: p}
+				return BlockIO{stat: p, root: fs.root}
 			}
 
 			pp := Path("block", dev, name, "stat")
-			if _, err := file.Stat(pp); err == nil {
-				return BlockIO{stat: pp}
+			if _, err := fs.root.Stat(pp); err == nil {
+				return BlockIO{stat: pp, root: fs.root}
 			}
 
-			return BlockIO{stat: p}
+			return BlockIO{stat: p, root: fs.root}
 		} else if mnt.FSType == "zfs" {
 			return BlockIO{}
 		}
@@ -62,8 +67,14 @@ func (b *BlockIO) IsValid() bool {
 	return b.stat != ""
 }
 
+// Total returns the total bytes read and written by the block device since
+// boot, as of the last call to Read.
+func (b *BlockIO) Total() (reads, writes int64) {
+	return b.old.reads * 512, b.old.writes * 512
+}
+
 func (b *BlockIO) Read() (reads, writes, ticks int64, err error) {
-	stat, err := file.Read(b.stat)
+	stat, err := b.root.Read(b.stat)
 	if err != nil {
 		return
 	}
@@ -120,3 +131,91 @@ func (b *BlockIO) Read() (reads, writes, ticks int64, err error) {
 
 	return
 }
+
+// BlockDisk is a physical disk in /sys/block.
+type BlockDisk struct {
+	// Name is the name of the block device, such as "sda" or "nvme0n1".
+	Name string
+	// Model is the model of the disk from device/model, or blank if unknown.
+	Model string
+	// Temp is the path to temp1_input of the hwmon device of the disk, or
+	// blank if the disk doesn't report its temperature. This is provided by
+	// the drivetemp driver for SATA disks, and by the nvme driver.
+	Temp string
+
+	root *vfs.Root
+}
+
+// HasTemp reports whether the disk reports its temperature.
+func (d *BlockDisk) HasTemp() bool {
+	return d.Temp != ""
+}
+
+// ReadTemp returns the temperature of the disk in m°C.
+func (d *BlockDisk) ReadTemp() (int64, error) {
+	return d.root.ReadInt(d.Temp)
+}
+
+// diskHWMon returns the path of the first hwmon directory of the device at
+// path, which is either in the hwmon subdirectory or the device itself.
+func (fs FS) diskHWMon(path string) string {
+	if hwmon := fs.DeviceHWMon(path); hwmon != "" {
+		return hwmon
+	}
+
+	names, err := fs.root.ReadDirNames(path)
+	if err != nil {
+		return ""
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "hwmon") {
+			return filepath.Join(path, name)
+		}
+	}
+
+	return ""
+}
+
+// BlockDisks returns the physical disks in /sys/block, sorted by name. Virtual
+// block devices, such as loop and zram devices, have no device and are skipped.
+func (fs FS) BlockDisks() ([]BlockDisk, error) {
+	names, err := fs.root.ReadDirNames(Path("block"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	slices.Sort(names)
+
+	var disks []BlockDisk
+
+	for _, name := range names {
+		dev := Path("block", name, "device")
+		if !fs.root.Exists(dev) {
+			continue
+		}
+
+		disk := BlockDisk{Name: name, root: fs.root}
+
+		if model, err := fs.root.SysRead(filepath.Join(dev, "model")); err == nil {
+			disk.Model = strings.TrimSpace(string(model))
+		}
+
+		if hwmon := fs.diskHWMon(dev); hwmon != "" {
+			if temp := filepath.Join(hwmon, "temp1_input"); fs.root.Exists(temp) {
+				disk.Temp = temp
+			}
+		}
+
+		log.Debug("Adding disk", "name", disk.Name, "model", disk.Model)
+		disks = append(disks, disk)
+	}
+
+	return disks, nil
+}
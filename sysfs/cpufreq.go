@@ -1,14 +1,12 @@
 package sysfs
 
 import (
-	"errors"
-	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 type CPUFreq struct {
@@ -17,10 +15,12 @@ type CPUFreq struct {
 	Min  int64
 	Max  int64
 	Path string
+
+	root *vfs.Root
 }
 
-func coreFreqs(found []string) ([]string, error) {
-	d, err := CPU()
+func (fs FS) coreFreqs(found []string) ([]string, error) {
+	d, err := fs.CPU()
 	if err != nil {
 		return found, err
 	}
@@ -39,7 +39,7 @@ func coreFreqs(found []string) ([]string, error) {
 		}
 
 		path := filepath.Join(cpuDevicesPath, name, "cpufreq")
-		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if !fs.root.Exists(path) {
 			return nil
 		}
 
@@ -57,8 +57,8 @@ func coreFreqs(found []string) ([]string, error) {
 	return found, err
 }
 
-func policyFreqs(found []string) ([]string, error) {
-	d, err := file.OpenDir(filepath.Join(cpuDevicesPath, "cpufreq"))
+func (fs FS) policyFreqs(found []string) ([]string, error) {
+	d, err := fs.root.OpenDir(filepath.Join(cpuDevicesPath, "cpufreq"))
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +76,7 @@ func policyFreqs(found []string) ([]string, error) {
 			return nil
 		}
 
-		path := filepath.Join(cpuDevicesPath, name)
+		path := filepath.Join(cpuDevicesPath, "cpufreq", name)
 
 		if n := id + 1; n > cap(found) {
 			found = slices.Grow(found, n-cap(found))[:n]
@@ -92,14 +92,14 @@ func policyFreqs(found []string) ([]string, error) {
 	return found, err
 }
 
-func CPUFreqs() ([]CPUFreq, error) {
-	found, err := coreFreqs(nil)
+func (fs FS) CPUFreqs() ([]CPUFreq, error) {
+	found, err := fs.coreFreqs(nil)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(found) == 0 {
-		found, err = policyFreqs(found)
+		found, err = fs.policyFreqs(found)
 		if err != nil {
 			return nil, err
 		}
@@ -112,29 +112,29 @@ func CPUFreqs() ([]CPUFreq, error) {
 	freqs := make([]CPUFreq, len(found))
 
 	for i, dir := range found {
-		base, err := file.ReadInt(filepath.Join(dir, "base_frequency"))
+		base, err := fs.root.ReadInt(filepath.Join(dir, "base_frequency"))
 		if err != nil {
 			return freqs, err
 		}
 
-		max, err := file.ReadInt(filepath.Join(dir, "scaling_max_freq"))
+		max, err := fs.root.ReadInt(filepath.Join(dir, "scaling_max_freq"))
 		if err != nil {
 			continue
 		}
 
-		min, err := file.ReadInt(filepath.Join(dir, "scaling_min_freq"))
+		min, err := fs.root.ReadInt(filepath.Join(dir, "scaling_min_freq"))
 		if err != nil {
 			continue
 		}
 
-		freqs[i] = CPUFreq{base, 0, min, max, filepath.Join(dir, "scaling_cur_freq")}
+		freqs[i] = CPUFreq{Base: base, Min: min, Max: max, Path: filepath.Join(dir, "scaling_cur_freq"), root: fs.root}
 	}
 
 	return freqs, nil
 }
 
 func (f *CPUFreq) Read() (int64, error) {
-	v, err := file.ReadInt(f.Path)
+	v, err := f.root.ReadInt(f.Path)
 	if err == nil {
 		f.curr = v
 	}
@@ -145,3 +145,88 @@ func (f *CPUFreq) Read() (int64, error) {
 func (f CPUFreq) Curr() int64 {
 	return f.curr
 }
+
+// SetCurr sets the current frequency of f as if it had been read.
+func (f *CPUFreq) SetCurr(v int64) {
+	f.curr = v
+}
+
+func (f *CPUFreq) path(name string) string {
+	return filepath.Join(filepath.Dir(f.Path), name)
+}
+
+// Governor returns the scaling governor of f.
+func (f *CPUFreq) Governor() (string, error) {
+	return f.root.ReadString(f.path("scaling_governor"))
+}
+
+// AvailableGovernors returns the scaling governors that may be set for f.
+func (f *CPUFreq) AvailableGovernors() ([]string, error) {
+	s, err := f.root.ReadString(f.path("scaling_available_governors"))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(s), nil
+}
+
+// SetGovernor sets the scaling governor of f. This usually requires root.
+func (f *CPUFreq) SetGovernor(governor string) error {
+	return f.root.WriteString(f.path("scaling_governor"), governor)
+}
+
+// GovernorWritable reports whether the scaling governor of f may be changed by
+// the current process.
+func (f *CPUFreq) GovernorWritable() bool {
+	return f.root.Writable(f.path("scaling_governor"))
+}
+
+// CPUBoost is the frequency boost (turbo) state of the CPU.
+type CPUBoost struct {
+	Path string
+	// inverted indicates the file at Path is 1 when boost is disabled, such as
+	// intel_pstate/no_turbo.
+	inverted bool
+
+	root *vfs.Root
+}
+
+// FindCPUBoost returns the boost state of the CPU, either from cpufreq/boost or
+// intel_pstate/no_turbo. If neither exist, an error is returned.
+func (fs FS) FindCPUBoost() (*CPUBoost, error) {
+	path := filepath.Join(cpuDevicesPath, "cpufreq", "boost")
+	if fs.root.Exists(path) {
+		return &CPUBoost{Path: path, root: fs.root}, nil
+	}
+
+	path = filepath.Join(cpuDevicesPath, "intel_pstate", "no_turbo")
+	if fs.root.Exists(path) {
+		return &CPUBoost{Path: path, inverted: true, root: fs.root}, nil
+	}
+
+	return nil, vfs.ErrNotExist
+}
+
+// Enabled returns whether boost is enabled.
+func (b *CPUBoost) Enabled() (bool, error) {
+	v, err := b.root.ReadInt(b.Path)
+	if err != nil {
+		return false, err
+	}
+
+	return (v != 0) != b.inverted, nil
+}
+
+// SetEnabled enables or disables boost. This usually requires root.
+func (b *CPUBoost) SetEnabled(enabled bool) error {
+	if enabled != b.inverted {
+		return b.root.WriteString(b.Path, "1")
+	}
+
+	return b.root.WriteString(b.Path, "0")
+}
+
+// Writable reports whether the boost state may be changed by the current process.
+func (b *CPUBoost) Writable() bool {
+	return b.root.Writable(b.Path)
+}
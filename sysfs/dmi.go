@@ -14,8 +14,8 @@ type Dmi struct {
 	productVersion string
 }
 
-func OpenDMI() (*Dmi, error) {
-	d, err := DMI()
+func (fs FS) OpenDMI() (*Dmi, error) {
+	d, err := fs.DMI()
 	if err != nil {
 		return nil, err
 	}
@@ -6,21 +6,21 @@ import (
 	"io"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 const (
-	etcPath       = file.Separator + "etc"
-	osReleasePath = etcPath + file.Separator + "os-release"
-	machineIDPath = etcPath + file.Separator + "machine-id"
-	hostnamePath  = etcPath + file.Separator + "hostname"
+	etcPath       = vfs.Separator + "etc"
+	osReleasePath = etcPath + vfs.Separator + "os-release"
+	machineIDPath = etcPath + vfs.Separator + "machine-id"
+	hostnamePath  = etcPath + vfs.Separator + "hostname"
 )
 
 var prettyNameKey = []byte("PRETTY_NAME=")
 
 // OSRelease returns the PRETTY_NAME of /etc/os-release.
-func OSRelease() (name string, err error) {
-	f, err := file.Open(osReleasePath)
+func (fs FS) OSRelease() (name string, err error) {
+	f, err := fs.root.Open(osReleasePath)
 	if err != nil {
 		return "", err
 	}
@@ -50,8 +50,8 @@ func OSRelease() (name string, err error) {
 }
 
 // MachineID returns the SHA256 sum of the contents of /etc/machine-id.
-func MachineID() ([]byte, error) {
-	id, err := file.ReadBytes(machineIDPath)
+func (fs FS) MachineID() ([]byte, error) {
+	id, err := fs.root.ReadBytes(machineIDPath)
 	if err != nil {
 		clear(id)
 
@@ -65,6 +65,6 @@ func MachineID() ([]byte, error) {
 }
 
 // Hostname returns the contents of /etc/hostname.
-func Hostname() (string, error) {
-	return file.ReadString(hostnamePath)
+func (fs FS) Hostname() (string, error) {
+	return fs.root.ReadString(hostnamePath)
 }
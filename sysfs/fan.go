@@ -0,0 +1,154 @@
+package sysfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// PWM modes of a fan, as in pwm<N>_enable. Any value greater than PWMManual is
+// a driver specific automatic mode.
+const (
+	PWMFull   = 0
+	PWMManual = 1
+	PWMAuto   = 2
+)
+
+// Fan is a fan of a hwmon device. The PWM control of fan<N> is assumed to be
+// pwm<N> of the same device, which is the case for most drivers.
+type Fan struct {
+	// Name is the name of the hwmon device.
+	Name string
+	// Label is the label of the fan, or "fan<N>" if it has no label.
+	Label string
+	// Path is the path to fan<N>_input.
+	Path string
+	// PWM is the path to pwm<N>, or blank if the fan has no PWM control.
+	PWM string
+	// Min and Max are the minimum and maximum speed of the fan in RPM from
+	// fan<N>_min and fan<N>_max, or 0 if not reported.
+	Min int64
+	Max int64
+
+	root *vfs.Root
+}
+
+// ID returns the identifier of f that is unique to its hwmon device, in the
+// form "<name>_fan<N>".
+func (f *Fan) ID() string {
+	base := filepath.Base(f.Path)
+	return f.Name + "_" + strings.TrimSuffix(base, "_input")
+}
+
+// ReadSpeed returns the speed of the fan in RPM.
+func (f *Fan) ReadSpeed() (int64, error) {
+	return f.root.ReadInt(f.Path)
+}
+
+// HasPWM reports whether the fan has PWM control.
+func (f *Fan) HasPWM() bool {
+	return f.PWM != ""
+}
+
+// ReadPWM returns the PWM duty cycle of the fan, from 0 to 255.
+func (f *Fan) ReadPWM() (int64, error) {
+	return f.root.ReadInt(f.PWM)
+}
+
+// ReadPWMMode returns the PWM mode of the fan, see [PWMFull], [PWMManual],
+// and [PWMAuto].
+func (f *Fan) ReadPWMMode() (int64, error) {
+	return f.root.ReadInt(f.PWM + "_enable")
+}
+
+// SetPWM sets the PWM duty cycle of the fan. The fan must be in [PWMManual] mode
+// for this to have any effect. This usually requires root.
+func (f *Fan) SetPWM(pwm int) error {
+	return f.root.WriteString(f.PWM, strconv.Itoa(pwm))
+}
+
+// SetPWMMode sets the PWM mode of the fan. This usually requires root.
+func (f *Fan) SetPWMMode(mode int64) error {
+	return f.root.WriteString(f.PWM+"_enable", strconv.FormatInt(mode, 10))
+}
+
+// Writable reports whether the PWM duty cycle and mode of the fan may be changed
+// by the current process.
+func (f *Fan) Writable() bool {
+	return f.HasPWM() && f.root.Writable(f.PWM) && f.root.Writable(f.PWM+"_enable")
+}
+
+// HWMonFans returns the fans of every hwmon device, sorted by path.
+func (fs FS) HWMonFans() ([]Fan, error) {
+	d, err := fs.HWMon()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	defer d.Close()
+
+	var fans []Fan
+
+	err = d.WalkSymlinks(func(path string) error {
+		files, err := fs.root.ReadDirNames(path)
+		if err != nil {
+			return err
+		}
+
+		name, err := fs.root.SysRead(filepath.Join(path, "name"))
+		if err != nil {
+			return nil
+		}
+
+		for _, f := range files {
+			if !strings.HasPrefix(f, "fan") {
+				continue
+			}
+
+			n, ok := strings.CutSuffix(f[3:], "_input")
+			if !ok {
+				continue
+			}
+
+			fan := Fan{
+				Name: string(name),
+				Path: filepath.Join(path, f),
+				root: fs.root,
+			}
+
+			if label, err := fs.root.SysRead(filepath.Join(path, "fan"+n+"_label")); err == nil {
+				fan.Label = string(label)
+			} else {
+				fan.Label = "fan" + n
+			}
+
+			fan.Min, _ = fs.root.ReadInt(filepath.Join(path, "fan"+n+"_min"))
+			fan.Max, _ = fs.root.ReadInt(filepath.Join(path, "fan"+n+"_max"))
+
+			if pwm := filepath.Join(path, "pwm"+n); slices.Contains(files, "pwm"+n) {
+				fan.PWM = pwm
+			}
+
+			log.Debug("Adding fan", "name", fan.Name, "path", fan.Path)
+			fans = append(fans, fan)
+		}
+
+		return nil
+	})
+
+	slices.SortFunc(fans, func(a, b Fan) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return fans, err
+}
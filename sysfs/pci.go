@@ -1,28 +1,31 @@
 package sysfs
 
 import (
+	"bytes"
 	"errors"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/lone-faerie/mqttop/internal/byteutil"
-	"github.com/lone-faerie/mqttop/internal/file"
 )
 
 type Vendor uint32
 
 const (
+	Intel  Vendor = 0x8086
 	MSI    Vendor = 0x1462
 	Nvidia Vendor = 0x10de
 )
 
-func GPUVendor() (Vendor, error) {
-	devs, err := file.ReadDirPaths(pciDevicesPath)
+func (fs FS) GPUVendor() (Vendor, error) {
+	devs, err := fs.root.ReadDirPaths(pciDevicesPath)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, dev := range devs {
-		b, err := file.ReadBytes(filepath.Join(dev, "class"))
+		b, err := fs.root.ReadBytes(filepath.Join(dev, "class"))
 		if err != nil {
 			continue
 		}
@@ -32,7 +35,7 @@ func GPUVendor() (Vendor, error) {
 			continue
 		}
 
-		b, err = file.ReadBytes(filepath.Join(dev, "vendor"))
+		b, err = fs.root.ReadBytes(filepath.Join(dev, "vendor"))
 		if err != nil {
 			continue
 		}
@@ -45,3 +48,89 @@ func GPUVendor() (Vendor, error) {
 
 	return 0, errors.New("GPU not found")
 }
+
+// GPUDevices returns the paths of the PCI display devices of vendor, sorted
+// by their bus location.
+func (fs FS) GPUDevices(vendor Vendor) ([]string, error) {
+	devs, err := fs.root.ReadDirPaths(pciDevicesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(devs)
+
+	var gpus []string
+
+	for _, dev := range devs {
+		b, err := fs.root.ReadBytes(filepath.Join(dev, "class"))
+		if err != nil || byteutil.Btox(b)&0xff0000 != 0x030000 {
+			continue
+		}
+
+		b, err = fs.root.ReadBytes(filepath.Join(dev, "vendor"))
+		if err != nil || Vendor(byteutil.Btox(b)) != vendor {
+			continue
+		}
+
+		gpus = append(gpus, dev)
+	}
+
+	return gpus, nil
+}
+
+// DeviceHWMon returns the path of the first hwmon directory of the device at
+// path, /sys/bus/pci/devices/<bus>/hwmon/hwmon*, or an empty string if none.
+func (fs FS) DeviceHWMon(path string) string {
+	names, err := fs.root.ReadDirNames(filepath.Join(path, "hwmon"))
+	if err != nil {
+		return ""
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "hwmon") {
+			return filepath.Join(path, "hwmon", name)
+		}
+	}
+
+	return ""
+}
+
+// DeviceDRMCard returns the path of the first DRM card of the device at path,
+// /sys/bus/pci/devices/<bus>/drm/card*, or an empty string if none.
+func (fs FS) DeviceDRMCard(path string) string {
+	names, err := fs.root.ReadDirNames(filepath.Join(path, "drm"))
+	if err != nil {
+		return ""
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "card") {
+			return filepath.Join(path, "drm", name)
+		}
+	}
+
+	return ""
+}
+
+var driverPrefix = []byte("DRIVER=")
+
+// DeviceDriver returns the name of the driver bound to the device at path,
+// from /sys/bus/pci/devices/<bus>/uevent, or an empty string if none.
+func (fs FS) DeviceDriver(path string) string {
+	b, err := fs.root.ReadBytes(filepath.Join(path, "uevent"))
+	if err != nil {
+		return ""
+	}
+
+	for line := range bytes.Lines(b) {
+		if driver, ok := bytes.CutPrefix(line, driverPrefix); ok {
+			return string(bytes.TrimSpace(driver))
+		}
+	}
+
+	return ""
+}
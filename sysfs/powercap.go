@@ -0,0 +1,108 @@
+package sysfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
+)
+
+// RAPLZone is a zone of the RAPL (Running Average Power Limit) energy counters
+// in /sys/class/powercap, such as a CPU package or the cores of the package.
+type RAPLZone struct {
+	// ID is the name of the zone in /sys/class/powercap, such as "intel-rapl:0"
+	// or "intel-rapl:0:0" for a subzone of "intel-rapl:0".
+	ID string
+	// Name is the name of the zone, such as "package-0", "core", "uncore",
+	// "dram" or "psys". Subzones are prefixed by the name of their parent, such
+	// as "package-0_core".
+	Name string
+	// Path is the path to energy_uj.
+	Path string
+	// MaxEnergy is the range of the energy counter in µJ, after which it wraps
+	// around to 0, or 0 if not reported.
+	MaxEnergy int64
+
+	root *vfs.Root
+}
+
+// ReadEnergy returns the energy counter of the zone in µJ. This usually
+// requires root.
+func (z *RAPLZone) ReadEnergy() (int64, error) {
+	return z.root.ReadInt(z.Path)
+}
+
+// RAPLZones returns the RAPL zones with energy counters, sorted by ID. Zones of
+// the MMIO interface are skipped if the same zone is provided by the MSR
+// interface.
+func (fs FS) RAPLZones() ([]RAPLZone, error) {
+	names, err := fs.root.ReadDirNames(powercapClassPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	// The zones of the MMIO interface are sorted last, so that they're only
+	// added if there is no zone of the same name.
+	slices.SortFunc(names, func(a, b string) int {
+		if am, bm := strings.Contains(a, "-mmio"), strings.Contains(b, "-mmio"); am != bm {
+			if am {
+				return 1
+			}
+
+			return -1
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	var zones []RAPLZone
+
+	for _, id := range names {
+		path := filepath.Join(powercapClassPath, id)
+		if !strings.Contains(id, "rapl") || !fs.root.Exists(filepath.Join(path, "energy_uj")) {
+			continue
+		}
+
+		name, err := fs.root.ReadString(filepath.Join(path, "name"))
+		if err != nil {
+			continue
+		}
+
+		if strings.Count(id, ":") > 1 {
+			parent := id[:strings.LastIndexByte(id, ':')]
+			if pname, err := fs.root.ReadString(filepath.Join(powercapClassPath, parent, "name")); err == nil {
+				name = pname + "_" + name
+			}
+		}
+
+		if slices.ContainsFunc(zones, func(z RAPLZone) bool { return z.Name == name }) {
+			continue
+		}
+
+		zone := RAPLZone{
+			ID:   id,
+			Name: name,
+			Path: filepath.Join(path, "energy_uj"),
+			root: fs.root,
+		}
+
+		zone.MaxEnergy, _ = fs.root.ReadInt(filepath.Join(path, "max_energy_range_uj"))
+
+		log.Debug("Adding RAPL zone", "id", zone.ID, "name", zone.Name)
+		zones = append(zones, zone)
+	}
+
+	slices.SortFunc(zones, func(a, b RAPLZone) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return zones, nil
+}
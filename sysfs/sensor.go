@@ -7,8 +7,8 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/lone-faerie/mqttop/internal/file"
 	"github.com/lone-faerie/mqttop/log"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
 type Sensor struct {
@@ -16,15 +16,28 @@ type Sensor struct {
 	Label string
 	Path  string
 	Max   int64
+	// High and Crit are the high and critical limits of the sensor reported
+	// by the hardware, from temp<N>_max and temp<N>_crit of a hwmon device or
+	// the high and critical trip points of a thermal zone. They are 0 if not
+	// reported.
+	High  int64
+	Crit  int64
 	value int64
+	valid bool
+
+	root *vfs.Root
 }
 
+// Read reads the value of s. If the value can't be read, such as if the sensor
+// was removed, the last value is returned and s is no longer valid.
 func (s *Sensor) Read() (int64, error) {
-	v, err := file.ReadInt(s.Path)
+	v, err := s.root.ReadInt(s.Path)
 	if err == nil {
 		s.value = v
 	}
 
+	s.valid = err == nil
+
 	return s.value, err
 }
 
@@ -32,8 +45,20 @@ func (s *Sensor) Value() int64 {
 	return s.value
 }
 
-func hwmonSensors(search map[string]bool) (gotCoretemp bool, err error) {
-	d, err := HWMon()
+// Valid reports whether the last read of s was successful. The value of a
+// sensor that hasn't been read yet is not valid.
+func (s *Sensor) Valid() bool {
+	return s != nil && s.valid
+}
+
+// SetValue sets the value of s as if it had been read.
+func (s *Sensor) SetValue(v int64) {
+	s.value = v
+	s.valid = true
+}
+
+func (fs FS) hwmonSensors(search map[string]bool) (gotCoretemp bool, err error) {
+	d, err := fs.HWMon()
 	if err != nil {
 		return
 	}
@@ -49,7 +74,7 @@ func hwmonSensors(search map[string]bool) (gotCoretemp bool, err error) {
 			gotCoretemp = true
 		}
 
-		files, err := file.ReadDirNames(path)
+		files, err := fs.root.ReadDirNames(path)
 		if err != nil {
 			return err
 		}
@@ -69,8 +94,8 @@ func hwmonSensors(search map[string]bool) (gotCoretemp bool, err error) {
 	return
 }
 
-func coretempSensors(search map[string]bool) (gotCoretemp bool, err error) {
-	d, err := Coretemp()
+func (fs FS) coretempSensors(search map[string]bool) (gotCoretemp bool, err error) {
+	d, err := fs.Coretemp()
 	if err != nil {
 		return
 	}
@@ -78,7 +103,7 @@ func coretempSensors(search map[string]bool) (gotCoretemp bool, err error) {
 	defer d.Close()
 
 	err = d.WalkSymlinks(func(path string) error {
-		files, err := file.ReadDirNames(path)
+		files, err := fs.root.ReadDirNames(path)
 		if err != nil {
 			return err
 		}
@@ -98,9 +123,9 @@ func coretempSensors(search map[string]bool) (gotCoretemp bool, err error) {
 	return
 }
 
-func HWMonSensors() ([]Sensor, error) {
+func (fs FS) HWMonSensors() ([]Sensor, error) {
 	search := make(map[string]bool)
-	gotCoretemp, err := hwmonSensors(search)
+	gotCoretemp, err := fs.hwmonSensors(search)
 
 	if err != nil {
 		log.Debug("hwmonSensors error", "cause", err)
@@ -113,7 +138,7 @@ func HWMonSensors() ([]Sensor, error) {
 
 	if !gotCoretemp {
 		log.Debug("Didn't get coretemp")
-		if gotCoretemp, err = coretempSensors(search); err != nil {
+		if gotCoretemp, err = fs.coretempSensors(search); err != nil {
 			return nil, err
 		}
 	}
@@ -121,12 +146,12 @@ func HWMonSensors() ([]Sensor, error) {
 	sensors := make([]Sensor, 0, len(search))
 
 	for path := range search {
-		name, err := file.SysRead(filepath.Join(path, "name"))
+		name, err := fs.root.SysRead(filepath.Join(path, "name"))
 		if err != nil {
 			continue
 		}
 
-		files, err := file.ReadDirNames(path)
+		files, err := fs.root.ReadDirNames(path)
 		if err != nil {
 			continue
 		}
@@ -139,27 +164,32 @@ func HWMonSensors() ([]Sensor, error) {
 				continue
 			}
 
-			label, err := file.SysRead(basepath + "label")
+			label, err := fs.root.SysRead(basepath + "label")
 			if err != nil {
 				continue
 			}
 
-			max, _ := file.ReadInt(basepath + "max")
-
-			if crit, _ := file.ReadInt(basepath + "crit"); crit > max {
-				max = crit
-			}
+			high, _ := fs.root.ReadInt(basepath + "max")
+			crit, _ := fs.root.ReadInt(basepath + "crit")
 
 			log.Debug("Adding sensor", "name", name, "path", fpath)
-			sensors = append(sensors, Sensor{string(name), string(label), fpath, max, 0})
+			sensors = append(sensors, Sensor{
+				Name:  string(name),
+				Label: string(label),
+				Path:  fpath,
+				Max:   max(high, crit),
+				High:  high,
+				Crit:  crit,
+				root:  fs.root,
+			})
 		}
 	}
 
 	return sensors, nil
 }
 
-func ThermalSensors() ([]Sensor, error) {
-	d, err := Thermal()
+func (fs FS) ThermalSensors() ([]Sensor, error) {
+	d, err := fs.Thermal()
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			err = nil
@@ -177,7 +207,7 @@ func ThermalSensors() ([]Sensor, error) {
 			return nil
 		}
 
-		p := filepath.Join(thermalClassPath, name)
+		p := filepath.Join(d.Name(), name)
 
 		basepath, err := filepath.EvalSymlinks(p)
 		if err != nil {
@@ -185,24 +215,24 @@ func ThermalSensors() ([]Sensor, error) {
 		}
 
 		path := filepath.Join(basepath, "temp")
-		if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, err = fs.root.Stat(path); errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 
-		label, err := file.SysRead(filepath.Join(basepath, "type"))
+		label, err := fs.root.SysRead(filepath.Join(basepath, "type"))
 		if err != nil {
 			return nil
 		}
 
-		var max, crit int64
+		var high, crit int64
 
 		for i := 0; true; i++ {
 			fname := filepath.Join(basepath, "trip_point_"+strconv.Itoa(i)+"_temp")
-			if _, err = os.Stat(fname); errors.Is(err, os.ErrNotExist) {
+			if _, err = fs.root.Stat(fname); errors.Is(err, os.ErrNotExist) {
 				break
 			}
 
-			typ, err := file.SysRead(filepath.Join(basepath, "trip_point_"+strconv.Itoa(i)+"_type"))
+			typ, err := fs.root.SysRead(filepath.Join(basepath, "trip_point_"+strconv.Itoa(i)+"_type"))
 			if err != nil {
 				continue
 			}
@@ -211,14 +241,14 @@ func ThermalSensors() ([]Sensor, error) {
 
 			switch string(typ) {
 			case "high":
-				val = &max
+				val = &high
 			case "critical":
 				val = &crit
 			default:
 				continue
 			}
 
-			x, err := file.ReadInt(fname)
+			x, err := fs.root.ReadInt(fname)
 			if err != nil {
 				continue
 			}
@@ -226,12 +256,16 @@ func ThermalSensors() ([]Sensor, error) {
 			*val = x
 		}
 
-		if crit > max {
-			max = crit
-		}
-
 		log.Debug("Adding sensor", "path", path)
-		sensors = append(sensors, Sensor{name, string(label), path, max, 0})
+		sensors = append(sensors, Sensor{
+			Name:  name,
+			Label: string(label),
+			Path:  path,
+			Max:   max(high, crit),
+			High:  high,
+			Crit:  crit,
+			root:  fs.root,
+		})
 
 		return nil
 	})
@@ -1,95 +1,112 @@
-// Package procfs provides access to various files in /sys and /etc
+// Package sysfs provides access to various files in /sys and /etc
 package sysfs
 
 import (
 	"path/filepath"
 
-	"github.com/lone-faerie/mqttop/internal/file"
+	"github.com/lone-faerie/mqttop/vfs"
 )
 
-const MountPath = file.Separator + "sys" // /sys
+const MountPath = vfs.Separator + "sys" // /sys
 
 const (
-	classPath        = MountPath + file.Separator + "class"                       // /sys/class
-	hwmonClassPath   = classPath + file.Separator + "hwmon"                       // /sys/class/hwmon
-	thermalClassPath = classPath + file.Separator + "thermal"                     // /sys/class/thermal
-	netClassPath     = classPath + file.Separator + "net"                         // /sys/class/net
-	powerSupplyPath  = classPath + file.Separator + "power_supply"                // /sys/class/power_supply
-	dmiClassPath     = classPath + file.Separator + "dmi"                         // /sys/class/dmi
-	dmiIDPath        = classPath + file.Separator + "dmi" + file.Separator + "id" // /sys/class/dmi/id
+	classPath         = MountPath + vfs.Separator + "class"                      // /sys/class
+	hwmonClassPath    = classPath + vfs.Separator + "hwmon"                      // /sys/class/hwmon
+	thermalClassPath  = classPath + vfs.Separator + "thermal"                    // /sys/class/thermal
+	netClassPath      = classPath + vfs.Separator + "net"                        // /sys/class/net
+	powerSupplyPath   = classPath + vfs.Separator + "power_supply"               // /sys/class/power_supply
+	powercapClassPath = classPath + vfs.Separator + "powercap"                   // /sys/class/powercap
+	dmiClassPath      = classPath + vfs.Separator + "dmi"                        // /sys/class/dmi
+	dmiIDPath         = classPath + vfs.Separator + "dmi" + vfs.Separator + "id" // /sys/class/dmi/id
 )
 
 const (
-	devicesPath         = MountPath + file.Separator + "devices"                                         // /sys/devices
-	platformDevicesPath = devicesPath + file.Separator + "platform"                                      // /sys/devices/platform
-	systemDevicesPath   = devicesPath + file.Separator + "system"                                        // /sys/devices/system
-	coretempPath        = platformDevicesPath + file.Separator + "coretemp.0" + file.Separator + "hwmon" // /sys/devices/platfotm/coretemp.0/hwmon
-	cpuDevicesPath      = systemDevicesPath + file.Separator + "cpu"                                     // /sys/devices/system/cpu
+	devicesPath         = MountPath + vfs.Separator + "devices"                                        // /sys/devices
+	platformDevicesPath = devicesPath + vfs.Separator + "platform"                                     // /sys/devices/platform
+	systemDevicesPath   = devicesPath + vfs.Separator + "system"                                       // /sys/devices/system
+	coretempPath        = platformDevicesPath + vfs.Separator + "coretemp.0" + vfs.Separator + "hwmon" // /sys/devices/platfotm/coretemp.0/hwmon
+	cpuDevicesPath      = systemDevicesPath + vfs.Separator + "cpu"                                    // /sys/devices/system/cpu
 )
 
 const (
-	busPath        = MountPath + file.Separator + "bus"      // /sys/bus
-	pciBusPath     = busPath + file.Separator + "pci"        // /sys/bus/pci
-	pciDevicesPath = pciBusPath + file.Separator + "devices" // /sys/bus/pci/devices
+	busPath        = MountPath + vfs.Separator + "bus"      // /sys/bus
+	pciBusPath     = busPath + vfs.Separator + "pci"        // /sys/bus/pci
+	pciDevicesPath = pciBusPath + vfs.Separator + "devices" // /sys/bus/pci/devices
 )
 
 type (
-	File = file.File
-	Dir  = file.Dir
+	File = vfs.File
+	Dir  = vfs.Dir
 )
 
+// FS is the sysfs mounted at /sys in a [vfs.Root], along with /etc of the
+// same root. The zero FS is the sysfs of the host, see [vfs.Host].
+type FS struct {
+	root *vfs.Root
+}
+
+// NewFS returns the sysfs of root.
+func NewFS(root *vfs.Root) FS {
+	return FS{root: root}
+}
+
+// Root returns the root of fs.
+func (fs FS) Root() *vfs.Root {
+	return fs.root
+}
+
 func Path(elem ...string) string {
-	return MountPath + file.Separator + filepath.Join(elem...)
+	return MountPath + vfs.Separator + filepath.Join(elem...)
 }
 
 // HWMon returns the directory /sys/class/hwmon
-func HWMon() (*Dir, error) {
-	return file.OpenDir(hwmonClassPath)
+func (fs FS) HWMon() (*Dir, error) {
+	return fs.root.OpenDir(hwmonClassPath)
 }
 
 // Thermal returns the directory /sys/class/thermal
-func Thermal() (*Dir, error) {
-	return file.OpenDir(thermalClassPath)
+func (fs FS) Thermal() (*Dir, error) {
+	return fs.root.OpenDir(thermalClassPath)
 }
 
 // Coretemp returns the directory /sys/devices/platform/coretemp.0/hwmon
-func Coretemp() (*Dir, error) {
-	return file.OpenDir(coretempPath)
+func (fs FS) Coretemp() (*Dir, error) {
+	return fs.root.OpenDir(coretempPath)
 }
 
 // CPU returns the directory /sys/devices/system/cpu
-func CPU() (*Dir, error) {
-	return file.OpenDir(cpuDevicesPath)
+func (fs FS) CPU() (*Dir, error) {
+	return fs.root.OpenDir(cpuDevicesPath)
 }
 
-func Net() (*Dir, error) {
-	return file.OpenDir(netClassPath)
+func (fs FS) Net() (*Dir, error) {
+	return fs.root.OpenDir(netClassPath)
 }
 
 // NetDevice returns the directory /sys/class/net/<iface>
-func NetDevice(iface string) (*Dir, error) {
-	return file.OpenDir(netClassPath + file.Separator + iface)
+func (fs FS) NetDevice(iface string) (*Dir, error) {
+	return fs.root.OpenDir(netClassPath + vfs.Separator + iface)
 }
 
 // NetStatistics returns the contents of /sys/class/net/<iface>/statistics/rx_bytes and
 // /sys/class/net/<iface>/statistics/tx_bytes
-func NetStatistics(iface string) (rx, tx uint64, err error) {
-	path := netClassPath + file.Separator + iface + file.Separator + "statistics"
-	if rx, err = file.ReadUint(path + file.Separator + "rx_bytes"); err != nil {
+func (fs FS) NetStatistics(iface string) (rx, tx uint64, err error) {
+	path := netClassPath + vfs.Separator + iface + vfs.Separator + "statistics"
+	if rx, err = fs.root.ReadUint(path + vfs.Separator + "rx_bytes"); err != nil {
 		return
 	}
 
-	tx, err = file.ReadUint(path + file.Separator + "tx_bytes")
+	tx, err = fs.root.ReadUint(path + vfs.Separator + "tx_bytes")
 
 	return
 }
 
 // PowerSupply returns the directory /sys/class/power_supply
-func PowerSupply() (*Dir, error) {
-	return file.OpenDir(powerSupplyPath)
+func (fs FS) PowerSupply() (*Dir, error) {
+	return fs.root.OpenDir(powerSupplyPath)
 }
 
 // DMI returns the directory /sys/class/dmi
-func DMI() (*Dir, error) {
-	return file.OpenDir(dmiIDPath)
+func (fs FS) DMI() (*Dir, error) {
+	return fs.root.OpenDir(dmiIDPath)
 }
@@ -0,0 +1,21 @@
+//go:build notrace
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// Supported indicates whether tracing was compiled in.
+const Supported = false
+
+// New returns [ErrNotSupported], since tracing wasn't compiled in.
+func New(_ context.Context, cfg *config.TracingConfig, _ string) (Provider, error) {
+	if _, err := endpoint(cfg); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,58 @@
+//go:build !notrace
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// Supported indicates whether tracing was compiled in.
+const Supported = true
+
+// New returns a [Provider] exporting the spans to the endpoint of cfg. The
+// instance identifies the bridge among other instances of the service.
+func New(ctx context.Context, cfg *config.TracingConfig, instance string) (Provider, error) {
+	u, err := endpoint(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid sample_ratio %v, must be from 0 to 1", cfg.SampleRatio)
+	}
+
+	exp, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(u.String()),
+		otlptracehttp.WithURLPath(u.Path),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", ServiceName),
+	}
+
+	if instance != "" {
+		attrs = append(attrs, attribute.String("service.instance.id", instance))
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	), nil
+}
@@ -0,0 +1,58 @@
+// Package tracing implements the OpenTelemetry tracing of the update and
+// publish pipeline of the bridge. The traces are exported with OTLP over
+// HTTP to a collector, such as Jaeger or Tempo.
+//
+// Tracing may be excluded by building with the "notrace" tag, which drops
+// the dependency on the OpenTelemetry SDK. The bridge then uses a no-op
+// tracer, and [New] returns an error.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// Name is the name of the tracer of the bridge.
+const Name = "github.com/lone-faerie/mqttop/bridge"
+
+// The default service name and path of the OTLP/HTTP endpoint.
+const (
+	ServiceName = "mqttop"
+	DefaultPath = "/v1/traces"
+)
+
+// Provider is a [trace.TracerProvider] that exports its spans until it's
+// shut down.
+type Provider interface {
+	trace.TracerProvider
+	// Shutdown exports any remaining spans and stops the provider.
+	Shutdown(ctx context.Context) error
+}
+
+// ErrNotSupported is returned by [New] if built with the "notrace" tag.
+var ErrNotSupported = errors.New("tracing not supported, built with notrace")
+
+// endpoint returns the parsed Endpoint of cfg, with the default path if it has
+// none.
+func endpoint(cfg *config.TracingConfig) (*url.URL, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint %q, must be an http or https URL", cfg.Endpoint)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = DefaultPath
+	}
+
+	return u, nil
+}
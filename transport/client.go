@@ -0,0 +1,209 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// client is a [Transport] as an [mqtt.Client]. Each operation runs in its
+// own goroutine, which completes its token.
+type client struct {
+	t    Transport
+	opts *mqtt.ClientOptions
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Client returns t as an [mqtt.Client], such as for [bridge.WithClient]. The
+// options of the client have no broker or will, and AddRoute does nothing.
+// Messages are received with QoS 0 and without the retained flag. The paho
+// client of an [MQTT] transport is returned as is.
+func Client(t Transport) mqtt.Client {
+	if t, ok := t.(*mqttTransport); ok {
+		return t.c
+	}
+
+	c := &client{t: t, opts: mqtt.NewClientOptions()}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	return c
+}
+
+func (c *client) context() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ctx
+}
+
+func (c *client) IsConnected() bool {
+	return c.t.Connected()
+}
+
+func (c *client) IsConnectionOpen() bool {
+	return c.t.Connected()
+}
+
+// Connect connects the transport. The client may be connected again after
+// it's disconnected.
+func (c *client) Connect() mqtt.Token {
+	c.mu.Lock()
+
+	if c.ctx.Err() != nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+
+	ctx := c.ctx
+
+	c.mu.Unlock()
+
+	return run(func() error { return c.t.Connect(ctx) })
+}
+
+// Disconnect cancels any pending operations and closes the transport, which
+// may be connected again by Connect.
+func (c *client) Disconnect(_ uint) {
+	c.mu.Lock()
+	c.cancel()
+	c.mu.Unlock()
+
+	c.t.Close()
+}
+
+func (c *client) Publish(topic string, qos byte, retained bool, payload any) mqtt.Token {
+	var data []byte
+
+	switch p := payload.(type) {
+	case []byte:
+		data = p
+	case string:
+		data = []byte(p)
+	case *bytes.Buffer:
+		data = p.Bytes()
+	case bytes.Buffer:
+		data = p.Bytes()
+	default:
+		return done(fmt.Errorf("unknown payload type %T", payload))
+	}
+
+	ctx := c.context()
+
+	return run(func() error { return c.t.Publish(ctx, topic, qos, retained, data) })
+}
+
+func (c *client) handler(callback mqtt.MessageHandler) Handler {
+	return func(topic string, payload []byte) {
+		callback(c, &message{topic: topic, payload: payload})
+	}
+}
+
+func (c *client) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	ctx := c.context()
+
+	return run(func() error { return c.t.Subscribe(ctx, topic, qos, c.handler(callback)) })
+}
+
+func (c *client) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	ctx := c.context()
+	h := c.handler(callback)
+
+	return run(func() error {
+		for filter, qos := range filters {
+			if err := c.t.Subscribe(ctx, filter, qos, h); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (c *client) Unsubscribe(topics ...string) mqtt.Token {
+	ctx := c.context()
+
+	return run(func() error { return c.t.Unsubscribe(ctx, topics...) })
+}
+
+func (c *client) AddRoute(string, mqtt.MessageHandler) {}
+
+func (c *client) OptionsReader() mqtt.ClientOptionsReader {
+	return mqtt.NewOptionsReader(c.opts)
+}
+
+// token is an [mqtt.Token] completed by a goroutine.
+type token struct {
+	done chan struct{}
+	err  error
+}
+
+// run returns a token completed with the result of f, which is called in a
+// new goroutine.
+func run(f func() error) *token {
+	t := &token{done: make(chan struct{})}
+
+	go func() {
+		t.err = f()
+		close(t.done)
+	}()
+
+	return t
+}
+
+// done returns a token completed with err.
+func done(err error) *token {
+	t := &token{done: make(chan struct{}), err: err}
+	close(t.done)
+
+	return t
+}
+
+func (t *token) Wait() bool {
+	<-t.done
+	return true
+}
+
+func (t *token) WaitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-t.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (t *token) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *token) Error() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// message is an [mqtt.Message] received by a [Transport].
+type message struct {
+	topic   string
+	payload []byte
+}
+
+func (m *message) Duplicate() bool   { return false }
+func (m *message) Qos() byte         { return 0 }
+func (m *message) Retained() bool    { return false }
+func (m *message) Topic() string     { return m.topic }
+func (m *message) MessageID() uint16 { return 0 }
+func (m *message) Payload() []byte   { return m.payload }
+func (m *message) Ack()              {}
@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// memTransport is a [Transport] that delivers each publish to the handler of
+// the exact topic.
+type memTransport struct {
+	mu        sync.Mutex
+	connected bool
+	subs      map[string]Handler
+}
+
+func (m *memTransport) Connect(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connected = true
+
+	return nil
+}
+
+func (m *memTransport) Publish(_ context.Context, topic string, _ byte, _ bool, payload []byte) error {
+	m.mu.Lock()
+	h := m.subs[topic]
+	m.mu.Unlock()
+
+	if h != nil {
+		h(topic, payload)
+	}
+
+	return nil
+}
+
+func (m *memTransport) Subscribe(_ context.Context, filter string, _ byte, h Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subs[filter] = h
+
+	return nil
+}
+
+func (m *memTransport) Unsubscribe(_ context.Context, filters ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, filter := range filters {
+		delete(m.subs, filter)
+	}
+
+	return nil
+}
+
+func (m *memTransport) Connected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.connected
+}
+
+func (m *memTransport) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connected = false
+
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	c := Client(&memTransport{subs: make(map[string]Handler)})
+
+	if tok := c.Connect(); !tok.WaitTimeout(time.Second) || tok.Error() != nil {
+		t.Fatalf("Connect: %v", tok.Error())
+	}
+
+	if !c.IsConnected() {
+		t.Error("IsConnected: want true")
+	}
+
+	msgs := make(chan mqtt.Message, 3)
+
+	tok := c.Subscribe("mqttop/bridge/update", 0, func(_ mqtt.Client, msg mqtt.Message) {
+		msgs <- msg
+	})
+	if tok.Wait(); tok.Error() != nil {
+		t.Fatal(tok.Error())
+	}
+
+	for _, payload := range []any{"string", []byte("bytes"), bytes.NewBufferString("buffer")} {
+		if tok := c.Publish("mqttop/bridge/update", 0, false, payload); tok.Wait() && tok.Error() != nil {
+			t.Errorf("Publish(%T): %v", payload, tok.Error())
+		}
+	}
+
+	for _, want := range []string{"string", "bytes", "buffer"} {
+		if msg := <-msgs; msg.Topic() != "mqttop/bridge/update" || string(msg.Payload()) != want {
+			t.Errorf("Message: want %q, got %q", want, msg.Payload())
+		}
+	}
+
+	if tok := c.Publish("mqttop/bridge/update", 0, false, 42); tok.Wait() && tok.Error() == nil {
+		t.Error("Publish(int): want error")
+	}
+
+	if opts := c.OptionsReader(); opts.WillTopic() != "" {
+		t.Error("WillTopic: want empty")
+	}
+
+	c.Disconnect(0)
+
+	if c.IsConnected() {
+		t.Error("IsConnected: want false")
+	}
+}
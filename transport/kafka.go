@@ -0,0 +1,151 @@
+//go:build !nokafka
+
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"sync/atomic"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// KafkaSupported indicates whether the Kafka transport was compiled in.
+const KafkaSupported = true
+
+const kafkaDefaultTopic = "mqttop"
+
+// Kafka is a publish-only [Transport] that produces to a Kafka topic with the
+// franz-go client. Since MQTT topics aren't valid Kafka topics, each message
+// is produced to the topic of the config keyed by its MQTT topic, and
+// partitioned by its key like the Java producer does. Subscriptions are
+// ignored, so commands aren't available.
+type Kafka struct {
+	opts []kgo.Opt
+
+	mu     sync.Mutex
+	client *kgo.Client
+
+	connected atomic.Bool
+	warnOnce  sync.Once
+}
+
+func newKafka(cfg *config.TransportConfig, name string) (Transport, error) {
+	return NewKafka(cfg, name), nil
+}
+
+// NewKafka returns a new [Kafka] transport of cfg, which isn't connected yet.
+func NewKafka(cfg *config.TransportConfig, name string) *Kafka {
+	topic := cfg.Topic
+	if topic == "" {
+		topic = kafkaDefaultTopic
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Servers...),
+		kgo.ClientID(name),
+		kgo.DefaultProduceTopic(topic),
+	}
+
+	if cfg.Username != "" || cfg.Password != "" {
+		opts = append(opts, kgo.SASL(plain.Auth{
+			User: cfg.Username,
+			Pass: cfg.Password,
+		}.AsMechanism()))
+	}
+
+	if cfg.TLS {
+		opts = append(opts, kgo.DialTLSConfig(new(tls.Config)))
+	}
+
+	return &Kafka{opts: opts}
+}
+
+// Connect connects to the first of the servers that accepts the connection.
+// The client reconnects to the brokers as needed by each produce. A closed
+// transport may be connected again.
+func (k *Kafka) Connect(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.client != nil {
+		return nil
+	}
+
+	client, err := kgo.NewClient(k.opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return err
+	}
+
+	k.client = client
+	k.connected.Store(true)
+
+	return nil
+}
+
+// Publish produces a record of payload keyed by topic, waiting for it to be
+// acknowledged. The qos and retained flag are ignored.
+func (k *Kafka) Publish(ctx context.Context, topic string, _ byte, _ bool, payload []byte) error {
+	k.mu.Lock()
+	client := k.client
+	k.mu.Unlock()
+
+	if client == nil {
+		return ErrClosed
+	}
+
+	err := client.ProduceSync(ctx, &kgo.Record{Key: []byte(topic), Value: payload}).FirstErr()
+	if err != nil {
+		log.Debug("Unable to produce to Kafka", "error", err)
+	}
+
+	k.connected.Store(err == nil)
+
+	return err
+}
+
+// Subscribe does nothing, since the transport is publish-only.
+func (k *Kafka) Subscribe(_ context.Context, filter string, _ byte, _ Handler) error {
+	k.warnOnce.Do(func() {
+		log.Warn("Kafka transport is publish-only, ignoring subscriptions", "topic", filter)
+	})
+
+	return nil
+}
+
+// Unsubscribe does nothing, since the transport is publish-only.
+func (k *Kafka) Unsubscribe(context.Context, ...string) error {
+	return nil
+}
+
+// Connected reports whether the last request succeeded.
+func (k *Kafka) Connected() bool {
+	return k.connected.Load()
+}
+
+// Close closes the connections to the brokers.
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.connected.Store(false)
+
+	if k.client == nil {
+		return nil
+	}
+
+	k.client.Close()
+	k.client = nil
+
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build nokafka
+
+package transport
+
+import (
+	"fmt"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// KafkaSupported indicates whether the Kafka transport was compiled in.
+const KafkaSupported = false
+
+func newKafka(*config.TransportConfig, string) (Transport, error) {
+	return nil, fmt.Errorf("%w, built with nokafka", ErrNotSupported)
+}
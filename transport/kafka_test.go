@@ -0,0 +1,146 @@
+//go:build !nokafka
+
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestKafka(t *testing.T) {
+	k, err := kfake.NewCluster(
+		kfake.NumBrokers(1),
+		kfake.SeedTopics(1, "mqttop"),
+		kfake.EnableSASL(),
+		kfake.Superuser("PLAIN", "user", "pass"),
+	)
+	if err != nil {
+		t.Skip("Skipping, unable to start Kafka cluster:", err)
+	}
+
+	defer k.Close()
+
+	cfg := config.Default()
+	cfg.Transport = config.TransportConfig{
+		Type:     config.TransportKafka,
+		Servers:  k.ListenAddrs(),
+		Username: "user",
+		Password: "pass",
+	}
+
+	tr, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, true, []byte(`{"usage":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tr.Connected() {
+		t.Error("Connected: want true")
+	}
+
+	if err := tr.Subscribe(ctx, "mqttop/bridge/update", 0, nil); err != nil {
+		t.Errorf("Subscribe: want nil, got %v", err)
+	}
+
+	// A closed transport may be connected again
+	if err := tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, false, nil); err != ErrClosed {
+		t.Errorf("Closed: want %v, got %v", ErrClosed, err)
+	}
+
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, false, []byte(`{"usage":2}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(k.ListenAddrs()...),
+		kgo.SASL(plain.Auth{User: "user", Pass: "pass"}.AsMechanism()),
+		kgo.ConsumeTopics("mqttop"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer consumer.Close()
+
+	var records []*kgo.Record
+
+	for len(records) < 2 && ctx.Err() == nil {
+		fetches := consumer.PollFetches(ctx)
+		records = append(records, fetches.Records()...)
+	}
+
+	want := []string{`{"usage":1}`, `{"usage":2}`}
+
+	if len(records) != len(want) {
+		t.Fatalf("Records: want %d, got %d", len(want), len(records))
+	}
+
+	for i, r := range records {
+		if string(r.Key) != "mqttop/metric/cpu" || string(r.Value) != want[i] {
+			t.Errorf("Record %d: want mqttop/metric/cpu %s, got %s %s", i, want[i], r.Key, r.Value)
+		}
+	}
+}
+
+func TestKafka_Auth(t *testing.T) {
+	k, err := kfake.NewCluster(
+		kfake.NumBrokers(1),
+		kfake.SeedTopics(1, "mqttop"),
+		kfake.EnableSASL(),
+		kfake.Superuser("PLAIN", "user", "pass"),
+	)
+	if err != nil {
+		t.Skip("Skipping, unable to start Kafka cluster:", err)
+	}
+
+	defer k.Close()
+
+	cfg := &config.TransportConfig{
+		Type:     config.TransportKafka,
+		Servers:  k.ListenAddrs(),
+		Username: "user",
+		Password: "wrong",
+	}
+
+	tr := NewKafka(cfg, "test")
+
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := tr.Connect(ctx); err == nil {
+		t.Error("Connect: want authentication error")
+	}
+
+	if tr.Connected() {
+		t.Error("Connected: want false")
+	}
+}
@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"context"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttTransport is the paho client as a [Transport].
+type mqttTransport struct {
+	c mqtt.Client
+}
+
+// MQTT returns c as a [Transport]. Adapting it back with [Client] returns c,
+// so that its publishes keep their order and its options are still read.
+func MQTT(c mqtt.Client) Transport {
+	return &mqttTransport{c}
+}
+
+// wait waits for t, or returns the error of ctx if it's done first.
+func wait(ctx context.Context, t mqtt.Token) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.Done():
+		return t.Error()
+	}
+}
+
+func (t *mqttTransport) Connect(ctx context.Context) error {
+	return wait(ctx, t.c.Connect())
+}
+
+func (t *mqttTransport) Publish(ctx context.Context, topic string, qos byte, retained bool, payload []byte) error {
+	return wait(ctx, t.c.Publish(topic, qos, retained, payload))
+}
+
+func (t *mqttTransport) Subscribe(ctx context.Context, filter string, qos byte, h Handler) error {
+	return wait(ctx, t.c.Subscribe(filter, qos, func(_ mqtt.Client, msg mqtt.Message) {
+		h(msg.Topic(), msg.Payload())
+	}))
+}
+
+func (t *mqttTransport) Unsubscribe(ctx context.Context, filters ...string) error {
+	return wait(ctx, t.c.Unsubscribe(filters...))
+}
+
+func (t *mqttTransport) Connected() bool {
+	return t.c.IsConnected()
+}
+
+func (t *mqttTransport) Close() error {
+	t.c.Disconnect(250)
+	return nil
+}
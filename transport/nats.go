@@ -0,0 +1,261 @@
+//go:build !nonats
+
+package transport
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/lone-faerie/mqttop/config"
+	"github.com/lone-faerie/mqttop/log"
+)
+
+// NATSSupported indicates whether the NATS transport was compiled in.
+const NATSSupported = true
+
+const (
+	natsTimeout   = 10 * time.Second
+	natsReconnect = 2 * time.Second
+)
+
+// NATS is a [Transport] that publishes to core NATS with the nats.go client.
+// Topics are mapped to subjects by replacing "/" with ".", and the wildcards
+// "+" and "#" with "*" and ">". Messages aren't retained, since core NATS has
+// no persistence.
+type NATS struct {
+	url  string
+	opts []nats.Option
+
+	mu   sync.Mutex
+	nc   *nats.Conn
+	subs map[string]*natsSub
+}
+
+// natsSub is a subscription to the subject of a topic filter.
+type natsSub struct {
+	filter string
+	sub    *nats.Subscription
+
+	mu sync.Mutex
+	h  Handler
+}
+
+func newNATS(cfg *config.TransportConfig, name string) (Transport, error) {
+	return NewNATS(cfg, name), nil
+}
+
+// NewNATS returns a new [NATS] transport of cfg, which isn't connected yet.
+func NewNATS(cfg *config.TransportConfig, name string) *NATS {
+	opts := []nats.Option{
+		nats.Name(name),
+		nats.DontRandomize(),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(natsReconnect),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.WarnError("Lost connection to NATS server, reconnecting", err)
+			}
+		}),
+		nats.ReconnectHandler(func(*nats.Conn) {
+			log.Info("Reconnected to NATS server")
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			log.Warn("NATS server error", "error", err)
+		}),
+	}
+
+	if cfg.Username != "" || cfg.Password != "" {
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
+	}
+
+	if cfg.TLS {
+		opts = append(opts, nats.Secure())
+	}
+
+	return &NATS{
+		url:  strings.Join(cfg.Servers, ","),
+		opts: opts,
+		subs: make(map[string]*natsSub),
+	}
+}
+
+// Subject returns the NATS subject of the MQTT topic or topic filter.
+func Subject(topic string) string {
+	levels := strings.Split(topic, "/")
+	for i, level := range levels {
+		switch level {
+		case "+":
+			levels[i] = "*"
+		case "#":
+			levels[i] = ">"
+		default:
+			levels[i] = strings.Map(func(r rune) rune {
+				switch r {
+				case '.', ' ', '\t', '\r', '\n', '*', '>':
+					return '_'
+				}
+
+				return r
+			}, level)
+		}
+	}
+
+	return strings.Join(levels, ".")
+}
+
+// topicOf returns the MQTT topic of a subject received by sub. A subscription
+// to a single topic receives the topic as is.
+func (sub *natsSub) topicOf(subject string) string {
+	if !strings.ContainsAny(sub.filter, "+#") {
+		return sub.filter
+	}
+
+	return strings.ReplaceAll(subject, ".", "/")
+}
+
+func (sub *natsSub) handle(msg *nats.Msg) {
+	sub.mu.Lock()
+	h := sub.h
+	sub.mu.Unlock()
+
+	h(sub.topicOf(msg.Subject), msg.Data)
+}
+
+// subscribe subscribes sub on nc, waiting for the server to process it.
+func (sub *natsSub) subscribe(ctx context.Context, nc *nats.Conn) (err error) {
+	sub.sub, err = nc.Subscribe(Subject(sub.filter), sub.handle)
+	if err != nil {
+		return err
+	}
+
+	return nc.FlushWithContext(ctx)
+}
+
+// Connect connects to the first of the servers that accepts the connection,
+// and keeps reconnecting to the servers until Close is called if the
+// connection is lost. The subscriptions are resubscribed once connected,
+// including after the transport is closed and connected again.
+func (n *NATS) Connect(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.nc != nil {
+		return nil
+	}
+
+	timeout := natsTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+
+	nc, err := nats.Connect(n.url, append(n.opts, nats.Timeout(timeout))...)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range n.subs {
+		if err := sub.subscribe(ctx, nc); err != nil {
+			nc.Close()
+			return err
+		}
+	}
+
+	n.nc = nc
+
+	return nil
+}
+
+// Publish publishes payload to the subject of topic. The qos and retained
+// flag are ignored.
+func (n *NATS) Publish(_ context.Context, topic string, _ byte, _ bool, payload []byte) error {
+	n.mu.Lock()
+	nc := n.nc
+	n.mu.Unlock()
+
+	if nc == nil {
+		return ErrClosed
+	}
+
+	return nc.Publish(Subject(topic), payload)
+}
+
+// Subscribe subscribes to the subject of filter. The qos is ignored.
+func (n *NATS) Subscribe(ctx context.Context, filter string, _ byte, h Handler) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if sub, ok := n.subs[filter]; ok {
+		sub.mu.Lock()
+		sub.h = h
+		sub.mu.Unlock()
+
+		return nil
+	}
+
+	sub := &natsSub{filter: filter, h: h}
+	n.subs[filter] = sub
+
+	if n.nc == nil {
+		// Subscribed once connected
+		return nil
+	}
+
+	return sub.subscribe(ctx, n.nc)
+}
+
+// Unsubscribe unsubscribes from the subjects of filters.
+func (n *NATS) Unsubscribe(_ context.Context, filters ...string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var err error
+
+	for _, filter := range filters {
+		sub, ok := n.subs[filter]
+		if !ok {
+			continue
+		}
+
+		delete(n.subs, filter)
+
+		if sub.sub != nil {
+			if e := sub.sub.Unsubscribe(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+
+	return err
+}
+
+// Connected reports whether the transport is connected to a server.
+func (n *NATS) Connected() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.nc != nil && n.nc.IsConnected()
+}
+
+// Close closes the connection and stops reconnecting.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.nc == nil {
+		return nil
+	}
+
+	// Pending messages are flushed before the connection is closed
+	n.nc.Close()
+	n.nc = nil
+
+	for _, sub := range n.subs {
+		sub.sub = nil
+	}
+
+	return nil
+}
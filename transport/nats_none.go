@@ -0,0 +1,16 @@
+//go:build nonats
+
+package transport
+
+import (
+	"fmt"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// NATSSupported indicates whether the NATS transport was compiled in.
+const NATSSupported = false
+
+func newNATS(*config.TransportConfig, string) (Transport, error) {
+	return nil, fmt.Errorf("%w, built with nonats", ErrNotSupported)
+}
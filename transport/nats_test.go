@@ -0,0 +1,213 @@
+//go:build !nonats
+
+package transport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		topic, want string
+	}{
+		{"mqttop/metric/cpu", "mqttop.metric.cpu"},
+		{"mqttop/+/cpu", "mqttop.*.cpu"},
+		{"mqttop/#", "mqttop.>"},
+		{"mqttop/dir/my.dir name", "mqttop.dir.my_dir_name"},
+		{"mqttop/cpu/$schema", "mqttop.cpu.$schema"},
+	}
+
+	for _, tt := range tests {
+		if got := Subject(tt.topic); got != tt.want {
+			t.Errorf("Subject(%q): want %q, got %q", tt.topic, tt.want, got)
+		}
+	}
+}
+
+// newNATSServer starts an embedded NATS server requiring the user "user"
+// with the password "pass".
+func newNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{
+		Host:     "127.0.0.1",
+		Port:     server.RANDOM_PORT,
+		Username: "user",
+		Password: "pass",
+		NoLog:    true,
+		NoSigs:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.Start()
+
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Skip("Skipping, NATS server not ready")
+	}
+
+	t.Cleanup(s.Shutdown)
+
+	return s
+}
+
+func TestNATS(t *testing.T) {
+	s := newNATSServer(t)
+	cfg := &config.TransportConfig{
+		Type:     config.TransportNATS,
+		Servers:  []string{s.ClientURL()},
+		Username: "user",
+		Password: "pass",
+	}
+
+	// peer publishes and subscribes to the server as another client
+	peer, err := nats.Connect(s.ClientURL(), nats.UserInfo("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer peer.Close()
+
+	pubs := make(chan *nats.Msg, 10)
+
+	if _, err := peer.ChanSubscribe("mqttop.>", pubs); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewNATS(cfg, "test")
+
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgs := make(chan string, 2)
+	handler := func(topic string, payload []byte) {
+		msgs <- topic + " " + string(payload)
+	}
+
+	// Subscriptions before connecting are subscribed once connected
+	if err := tr.Subscribe(ctx, "mqttop/bridge/update", 0, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tr.Connected() {
+		t.Error("Connected: want true")
+	}
+
+	if err := tr.Subscribe(ctx, "mqttop/+/set", 0, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, true, []byte(`{"usage":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := nextMsg(ctx, t, pubs, "mqttop.metric.cpu"), `{"usage":1}`; got != want {
+		t.Errorf("Publish: want %q, got %q", want, got)
+	}
+
+	peer.Publish("mqttop.bridge.update", []byte("hi"))
+
+	if got, want := <-msgs, "mqttop/bridge/update hi"; got != want {
+		t.Errorf("Exact: want %q, got %q", want, got)
+	}
+
+	peer.Publish("mqttop.power.set", []byte("off"))
+
+	if got, want := <-msgs, "mqttop/power/set off"; got != want {
+		t.Errorf("Wildcard: want %q, got %q", want, got)
+	}
+
+	if err := tr.Unsubscribe(ctx, "mqttop/+/set"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A closed transport may be connected again, keeping its subscriptions
+	if err := tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, false, nil); err != ErrClosed {
+		t.Errorf("Closed: want %v, got %v", ErrClosed, err)
+	}
+
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	peer.Publish("mqttop.power.set", []byte("off"))
+	peer.Publish("mqttop.bridge.update", []byte("again"))
+
+	if got, want := <-msgs, "mqttop/bridge/update again"; got != want {
+		t.Errorf("Resubscribe: want %q, got %q", want, got)
+	}
+
+	if err := tr.Publish(ctx, "mqttop/metric/cpu", 0, false, []byte(`{"usage":2}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := nextMsg(ctx, t, pubs, "mqttop.metric.cpu"), `{"usage":2}`; got != want {
+		t.Errorf("Reconnect: want %q, got %q", want, got)
+	}
+}
+
+// nextMsg returns the payload of the next message of msgs to subject.
+func nextMsg(ctx context.Context, t *testing.T, msgs <-chan *nats.Msg, subject string) string {
+	t.Helper()
+
+	for {
+		select {
+		case msg := <-msgs:
+			if msg.Subject == subject {
+				return string(msg.Data)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for %s", subject)
+		}
+	}
+}
+
+func TestNATS_Auth(t *testing.T) {
+	s := newNATSServer(t)
+	cfg := config.Default()
+	cfg.Transport = config.TransportConfig{
+		Type:    config.TransportNATS,
+		Servers: []string{s.ClientURL()},
+	}
+
+	tr, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := tr.Connect(ctx); err == nil || !strings.Contains(strings.ToLower(err.Error()), "authorization violation") {
+		t.Errorf("Connect: want authorization error, got %v", err)
+	}
+
+	if tr.Connected() {
+		t.Error("Connected: want false")
+	}
+}
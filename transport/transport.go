@@ -0,0 +1,82 @@
+// Package transport abstracts the message system the bridge publishes to,
+// so that the collectors and scheduling of the bridge may be reused without an
+// MQTT broker. The bridge publishes to a [Transport] adapted to an
+// [mqtt.Client] by [Client], and the paho client is itself a Transport by
+// [MQTT].
+//
+// Topics are always MQTT topics, which each transport maps to its own names,
+// such as NATS subjects. Features that only MQTT has, such as retained
+// messages, QoS and the Last Will and Testament, are ignored by the other
+// transports.
+//
+// The NATS and Kafka transports use the nats.go and franz-go clients, and may
+// be excluded by building with the nonats and nokafka tags.
+package transport
+
+import (
+	"context"
+	"errors"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+// A Handler handles a message published to a topic.
+type Handler func(topic string, payload []byte)
+
+// Transport publishes and subscribes to the topics of a message system.
+type Transport interface {
+	// Connect connects to the server, and keeps reconnecting until Close is
+	// called if the connection is lost.
+	Connect(ctx context.Context) error
+	// Publish publishes payload to topic. The qos and retained flag are
+	// ignored by transports without them.
+	Publish(ctx context.Context, topic string, qos byte, retained bool, payload []byte) error
+	// Subscribe calls h with each message published to the topic filter,
+	// which may have the MQTT wildcards "+" and "#". Subscribing to the same
+	// filter again replaces its handler.
+	Subscribe(ctx context.Context, filter string, qos byte, h Handler) error
+	// Unsubscribe stops calling the handlers of the topic filters.
+	Unsubscribe(ctx context.Context, filters ...string) error
+	// Connected reports whether the transport is connected.
+	Connected() bool
+	// Close closes the connection. The transport may be connected again by
+	// Connect, keeping its subscriptions.
+	Close() error
+}
+
+var (
+	// ErrClosed is returned by a [Transport] that was closed and hasn't been
+	// connected again.
+	ErrClosed = errors.New("transport closed")
+	// ErrNotSupported is returned by [Open] for a transport that wasn't
+	// compiled in.
+	ErrNotSupported = errors.New("transport not supported")
+)
+
+// Open returns the [Transport] of cfg, which isn't connected yet. The client
+// id of the MQTT config identifies the client to the servers of every
+// transport.
+func Open(cfg *config.Config) (Transport, error) {
+	t := &cfg.Transport
+
+	switch t.Type {
+	case "", config.TransportMQTT:
+		return MQTT(mqtt.NewClient(cfg.MQTT.ClientOptions())), nil
+	case config.TransportNATS:
+		if len(t.Servers) == 0 {
+			return nil, errors.New("transport nats requires servers")
+		}
+
+		return newNATS(t, cfg.MQTT.ClientID)
+	case config.TransportKafka:
+		if len(t.Servers) == 0 {
+			return nil, errors.New("transport kafka requires servers")
+		}
+
+		return newKafka(t, cfg.MQTT.ClientID)
+	}
+
+	return nil, config.InvalidValue("transport.type", t.Type)
+}
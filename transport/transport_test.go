@@ -0,0 +1,41 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lone-faerie/mqttop/config"
+)
+
+func TestOpen(t *testing.T) {
+	cfg := config.Default()
+	cfg.MQTT.Broker = "tcp://127.0.0.1:1883"
+
+	tr, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The paho client of the MQTT transport is adapted back as is
+	opts := Client(tr).OptionsReader()
+	if servers := opts.Servers(); len(servers) != 1 || servers[0].Host != "127.0.0.1:1883" {
+		t.Errorf("MQTT: want broker 127.0.0.1:1883, got %v", servers)
+	}
+
+	cfg.Transport = config.TransportConfig{Type: config.TransportNATS}
+	if _, err := Open(cfg); err == nil {
+		t.Error("No servers: want error")
+	}
+
+	cfg.Transport = config.TransportConfig{Type: config.TransportKafka, Servers: []string{"localhost:9092"}}
+	if tr, err := Open(cfg); KafkaSupported != (err == nil) || !KafkaSupported && !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Kafka: want supported %t, got %v", KafkaSupported, err)
+	} else if tr != nil {
+		tr.Close()
+	}
+
+	cfg.Transport = config.TransportConfig{Type: "amqp", Servers: []string{"localhost"}}
+	if _, err := Open(cfg); err == nil {
+		t.Error("Unknown type: want error")
+	}
+}
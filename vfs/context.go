@@ -0,0 +1,71 @@
+package vfs
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// await calls fn in a new goroutine and returns its result, or the error of
+// ctx if ctx is done first. A system call on a filesystem that stopped
+// responding, such as a network share, can't be interrupted, so fn is left to
+// return in the background.
+func await[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+
+	ch := make(chan result, 1)
+
+	go func() {
+		v, err := fn()
+		ch <- result{v, err}
+	}()
+
+	select {
+	case res := <-ch:
+		return res.v, res.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// ReadContext is like [Root.Read] but returns the error of ctx if ctx is done
+// before the named file is read.
+func (r *Root) ReadContext(ctx context.Context, name string) ([]byte, error) {
+	return await(ctx, func() ([]byte, error) {
+		return r.Read(name)
+	})
+}
+
+// ReadDirContext is like [Root.ReadDir] but returns the error of ctx if ctx is
+// done before the named directory is read.
+func (r *Root) ReadDirContext(ctx context.Context, name string) ([]os.DirEntry, error) {
+	return await(ctx, func() ([]os.DirEntry, error) {
+		return r.ReadDir(name)
+	})
+}
+
+// StatContext is like [Root.Stat] but returns the error of ctx if ctx is done
+// before the named file is stat'd.
+func (r *Root) StatContext(ctx context.Context, name string) (os.FileInfo, error) {
+	return await(ctx, func() (os.FileInfo, error) {
+		return r.Stat(name)
+	})
+}
+
+// StatfsContext is like [Root.Statfs] but returns the error of ctx if ctx is
+// done before the filesystem of the named file is stat'd.
+func (r *Root) StatfsContext(ctx context.Context, name string) (unix.Statfs_t, error) {
+	return await(ctx, func() (unix.Statfs_t, error) {
+		return r.Statfs(name)
+	})
+}
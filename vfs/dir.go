@@ -1,4 +1,4 @@
-package file
+package vfs
 
 import (
 	"os"
@@ -16,23 +16,23 @@ const (
 // Dir wraps an [os.File] for conveniently working with files
 // within the directory.
 type Dir struct {
+	root      *Root
 	f         *os.File
 	opened    bool
 	names     []string
 	namesType uint8
 }
 
-// Open opens the named directory. If successful, methods on the returned
-// directory can be used for reading; the associated file descriptor has mode
-// O_RDONLY. The path of the directory will be prefixed with the root path,
-// either / or the value of $MQTTOP_ROOTFS_PATH.
-func OpenDir(name string) (*Dir, error) {
-	f, err := open(name)
+// OpenDir opens the named directory in r. If successful, methods on the
+// returned directory can be used for reading; the associated file descriptor
+// has mode O_RDONLY.
+func (r *Root) OpenDir(name string) (*Dir, error) {
+	f, err := r.open(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Dir{f: f, opened: true}, nil
+	return &Dir{root: r, f: f, opened: true}, nil
 }
 
 // Close closes the underlying [os.File] of d, rendering it unusable for I/O.
@@ -47,7 +47,7 @@ func (d *Dir) Close() error {
 // will be reopened.
 func (d *Dir) Reset() error {
 	if !d.opened {
-		newF, err := open(d.f.Name())
+		newF, err := d.root.open(d.f.Name())
 		if err != nil {
 			return err
 		}
@@ -60,7 +60,7 @@ func (d *Dir) Reset() error {
 	return nil
 }
 
-// Name returns the name of the file as presented to Open.
+// Name returns the name of the directory joined with the directory of its root.
 //
 // It is safe to call Name after [Dir.Close].
 func (d *Dir) Name() string {
@@ -205,7 +205,7 @@ func (d *Dir) Walk(fn func(string, bool) error) error {
 	for _, name := range names {
 		name = dirPath(dirName, name)
 
-		info, err := Stat(name)
+		info, err := d.root.Stat(name)
 		if err != nil {
 			return err
 		}
@@ -288,22 +288,22 @@ func (d *Dir) ContainsAllFunc(fn func(string) bool) bool {
 
 // Open opens the named file in dir for reading.
 func (d *Dir) Open(name string) (*File, error) {
-	return Open(filepath.Join(d.f.Name(), name))
+	return d.root.Open(filepath.Join(d.f.Name(), name))
 }
 
 // OpenDir opens the named directory in dir for reading.
 func (d *Dir) OpenDir(name string) (*Dir, error) {
-	return OpenDir(filepath.Join(d.f.Name(), name))
+	return d.root.OpenDir(filepath.Join(d.f.Name(), name))
 }
 
 // Read reads the named file in dir and returns the contents.
 func (d *Dir) Read(name string) ([]byte, error) {
-	return Read(filepath.Join(d.f.Name(), name))
+	return d.root.Read(filepath.Join(d.f.Name(), name))
 }
 
 // ReadBytes reads the named file in dir using syscalls and returns the contents.
 func (d *Dir) ReadBytes(name string) ([]byte, error) {
-	return SysRead(filepath.Join(d.f.Name(), name))
+	return d.root.SysRead(filepath.Join(d.f.Name(), name))
 }
 
 // ReadString reads the named file in dir using syscalls and returns the contents as a string.
@@ -315,10 +315,10 @@ func (d *Dir) ReadString(name string) (string, error) {
 
 // ReadUint reads the named file in dir using syscalls and returns the contents parsed as a uint64.
 func (d *Dir) ReadUint(name string) (uint64, error) {
-	return ReadUint(filepath.Join(d.f.Name(), name))
+	return d.root.ReadUint(filepath.Join(d.f.Name(), name))
 }
 
 // ReadUint reads the named file in dir using syscalls and returns the contents parsed as a int64.
 func (d *Dir) ReadInt(name string) (int64, error) {
-	return ReadInt(filepath.Join(d.f.Name(), name))
+	return d.root.ReadInt(filepath.Join(d.f.Name(), name))
 }
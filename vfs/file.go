@@ -1,35 +1,47 @@
-// Package file provides an interface for working with files and directories
-// under a root directory.
-package file
+// Package vfs provides helpers for reading the files of a filesystem under
+// a root directory, such as /proc and /sys of the host mounted in a container.
+//
+// All names are opened in a [Root], which joins them with its directory:
+//
+//	root, err := vfs.NewRoot("/host")
+//	if err != nil {
+//		return err
+//	}
+//
+//	temp, err := root.ReadInt("/sys/class/thermal/thermal_zone0/temp") // reads /host/sys/...
+//
+// The root of the host is returned by [Host], which is / unless the
+// environment variable $MQTTOP_ROOTFS_PATH is set. The files may also be read
+// with a [context.Context], such as by [Root.ReadContext], for filesystems
+// that may stop responding.
+package vfs
 
 import (
 	"bufio"
-	//	"io/fs"
 	"os"
-	"path/filepath"
 )
 
 const Separator = string(os.PathSeparator) // Path separator, most likely "/"
 
 // File wraps an [os.File] with a buffer for convenient line reading.
 type File struct {
+	root   *Root
 	f      *os.File
 	r      *bufio.Reader
 	buf    []byte
 	opened bool
 }
 
-// Open opens the named file for reading. If successful, methods on the
+// Open opens the named file in r for reading. If successful, methods on the
 // returned file can be used for reading; the associated file descriptor
-// has mode O_RDONLY. The path of the file will be prefixed with the root
-// path, either / or the value of $MQTTOP_ROOTFS_PATH.
-func Open(name string) (*File, error) {
-	f, err := open(name)
+// has mode O_RDONLY.
+func (r *Root) Open(name string) (*File, error) {
+	f, err := r.open(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return &File{f: f, opened: true}, nil
+	return &File{root: r, f: f, opened: true}, nil
 }
 
 // Close closes the underlying [os.File] of f, rendering it unusable for I/O.
@@ -48,7 +60,7 @@ func (f *File) Reset() error {
 			return err
 		}
 	} else {
-		newF, err := open(f.f.Name())
+		newF, err := f.root.open(f.f.Name())
 		if err != nil {
 			return err
 		}
@@ -63,7 +75,7 @@ func (f *File) Reset() error {
 	return nil
 }
 
-// Name returns the name of the file as presented to Open.
+// Name returns the name of the file joined with the directory of its root.
 //
 // It is safe to call Name after [File.Close].
 func (f *File) Name() string {
@@ -102,24 +114,3 @@ func (f *File) ReadLine() (line []byte, err error) {
 
 	return
 }
-
-// Canonical returns the absolute path of the given path elements,
-// joined into a single path, and with symlinks followed.
-func Canonical(elem ...string) string {
-	path := filepath.Join(elem...)
-
-	symp, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		return path
-	}
-
-	symp, _ = abs(symp)
-
-	return symp
-}
-
-// Abs returns the absolute representation of path.
-func Abs(path string) string {
-	path, _ = abs(path)
-	return path
-}
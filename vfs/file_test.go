@@ -1,4 +1,4 @@
-package file
+package vfs
 
 import (
 	"io"
@@ -7,7 +7,7 @@ import (
 
 func BenchmarkReadLines(b *testing.B) {
 	for b.Loop() {
-		f, err := Open("tmp")
+		f, err := Host().Open("tmp")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -31,7 +31,7 @@ func BenchmarkReadLines(b *testing.B) {
 
 func BenchmarkIterLines(b *testing.B) {
 	for b.Loop() {
-		f, err := Open("tmp")
+		f, err := Host().Open("tmp")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -1,4 +1,4 @@
-package file
+package vfs
 
 import (
 	"bufio"
@@ -6,10 +6,10 @@ import (
 	"path/filepath"
 )
 
-// DirNames returns an iterator over names of files in the named directory.
-func DirNames(name string) iter.Seq[string] {
+// DirNames returns an iterator over names of files in the named directory in r.
+func (r *Root) DirNames(name string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		d, err := OpenDir(name)
+		d, err := r.OpenDir(name)
 		if err != nil {
 			return
 		}
@@ -30,15 +30,16 @@ func DirNames(name string) iter.Seq[string] {
 	}
 }
 
-// DirPaths returns an iterator over paths of files in the named directory.
-func DirPaths(name string) iter.Seq[string] {
+// DirPaths returns an iterator over paths of files in the named directory in r.
+func (r *Root) DirPaths(name string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		d, err := OpenDir(name)
+		d, err := r.OpenDir(name)
 		if err != nil {
 			return
 		}
 
 		names, err := d.ReadNames()
+		dirName := d.Name()
 
 		d.Close()
 
@@ -47,7 +48,7 @@ func DirPaths(name string) iter.Seq[string] {
 		}
 
 		for i := range names {
-			path := name + Separator + names[i]
+			path := dirName + Separator + names[i]
 
 			if !yield(path) {
 				return
@@ -56,15 +57,16 @@ func DirPaths(name string) iter.Seq[string] {
 	}
 }
 
-// DirSymlinks returns an iterator over paths of files in the named directory after evaluating symlinks.
-func DirSymlinks(name string) iter.Seq[string] {
+// DirSymlinks returns an iterator over paths of files in the named directory in r after evaluating symlinks.
+func (r *Root) DirSymlinks(name string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		d, err := OpenDir(name)
+		d, err := r.OpenDir(name)
 		if err != nil {
 			return
 		}
 
 		names, err := d.ReadNames()
+		dirName := d.Name()
 
 		d.Close()
 
@@ -73,7 +75,7 @@ func DirSymlinks(name string) iter.Seq[string] {
 		}
 
 		for i := range names {
-			path := name + Separator + names[i]
+			path := dirName + Separator + names[i]
 
 			symp, err := filepath.EvalSymlinks(path)
 			if err != nil {
@@ -0,0 +1,68 @@
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// Read reads the named file in r and returns the contents.
+func (r *Root) Read(name string) ([]byte, error) {
+	f, err := r.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// ReadDir reads the contents of the named directory in r and returns all its
+// directory entries.
+func (r *Root) ReadDir(name string) ([]os.DirEntry, error) {
+	name, err := r.abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadDir(name)
+}
+
+// ReadDirNames reads the contents of the named directory in r and returns a slice of the names of files
+// in the directory.
+func (r *Root) ReadDirNames(name string) ([]string, error) {
+	d, err := r.OpenDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.Close()
+
+	return d.ReadNames()
+}
+
+// ReadDirPaths reads the contents of the named directory in r and returns a slice of the paths of files
+// in the directory.
+func (r *Root) ReadDirPaths(name string) ([]string, error) {
+	d, err := r.OpenDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.Close()
+
+	return d.ReadPaths()
+}
+
+// ReadDirSymlinks reads the contents of the named directory in r and returns a slice of the paths of files
+// in the directory after following symlinks.
+func (r *Root) ReadDirSymlinks(name string) ([]string, error) {
+	d, err := r.OpenDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.Close()
+
+	return d.ReadSymlinks()
+}
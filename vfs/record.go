@@ -0,0 +1,56 @@
+package vfs
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	recording atomic.Bool
+	recordMu  sync.Mutex
+	recorded  map[string]struct{}
+)
+
+// Record starts recording the names of the files and directories that are
+// opened, read or stat'd in any [Root], such as by [Root.Read] or [Root.Stat].
+// The names are recorded as absolute paths, before being joined with the
+// directory of the root. Any names already recorded are discarded.
+func Record() {
+	recordMu.Lock()
+	recorded = make(map[string]struct{})
+	recordMu.Unlock()
+	recording.Store(true)
+}
+
+// Recorded stops recording and returns the sorted names recorded since the
+// last call to [Record].
+func Recorded() []string {
+	recording.Store(false)
+
+	recordMu.Lock()
+	defer recordMu.Unlock()
+
+	names := make([]string, 0, len(recorded))
+	for name := range recorded {
+		names = append(names, name)
+	}
+	recorded = nil
+
+	slices.Sort(names)
+
+	return names
+}
+
+func record(dir, name string) {
+	if dir != Separator && within(dir, name) {
+		name = Separator + strings.TrimLeft(name[len(dir):], Separator)
+	}
+
+	recordMu.Lock()
+	if recorded != nil {
+		recorded[name] = struct{}{}
+	}
+	recordMu.Unlock()
+}
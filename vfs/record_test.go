@@ -0,0 +1,38 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "proc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "proc", "stat"), []byte("cpu 1 2 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root.Read("/proc/uptime")
+	Record()
+	root.Read("/proc/stat")
+	root.Stat("/proc")
+	root.Read("/proc/missing")
+	names := Recorded()
+	root.Read("/proc/meminfo")
+
+	if want := []string{"/proc", "/proc/missing", "/proc/stat"}; !slices.Equal(names, want) {
+		t.Errorf("Recorded: want %q, got %q", want, names)
+	}
+	if names = Recorded(); len(names) != 0 {
+		t.Errorf("Recorded: want none after stopping, got %q", names)
+	}
+}
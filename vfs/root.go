@@ -0,0 +1,126 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// Root is a directory that is used as the root of the filesystem, such as
+// the root of the host mounted in a container. Names opened in a root are
+// joined with its directory, unless they're already within it, such as the
+// names returned by [Dir.ReadPaths] or [File.Name].
+//
+// A nil *Root is the same as the root returned by [Host].
+type Root struct {
+	dir string
+}
+
+// hostRoot is the root returned by Host, from $MQTTOP_ROOTFS_PATH.
+var hostRoot = sync.OnceValue(func() *Root {
+	if s, ok := os.LookupEnv("MQTTOP_ROOTFS_PATH"); ok && len(s) > 0 {
+		return &Root{dir: filepath.Clean(s)}
+	}
+
+	return &Root{dir: Separator}
+})
+
+// Host returns the root of the host filesystem, which is the value of
+// $MQTTOP_ROOTFS_PATH if set, otherwise /.
+func Host() *Root {
+	return hostRoot()
+}
+
+// NewRoot returns a new [Root] of the directory dir, after following symlinks.
+// If dir is empty, the root returned by [Host] is returned.
+func NewRoot(dir string) (*Root, error) {
+	if dir == "" {
+		return Host(), nil
+	}
+
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &PathError{Op: "root", Path: dir, Err: ErrNotDir}
+	}
+
+	return &Root{dir: dir}, nil
+}
+
+// Dir returns the directory of r.
+func (r *Root) Dir() string {
+	if r == nil {
+		r = Host()
+	}
+
+	return r.dir
+}
+
+// String implements [fmt.Stringer] and returns the directory of r.
+func (r *Root) String() string {
+	return r.Dir()
+}
+
+func (r *Root) abs(name string) (string, error) {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir := r.Dir()
+
+	if recording.Load() {
+		record(dir, name)
+	}
+
+	if dir == Separator || within(dir, name) {
+		return name, nil
+	}
+
+	return filepath.Join(dir, name[1:]), nil
+}
+
+// within reports whether name is dir or a name in dir.
+func within(dir, name string) bool {
+	return strings.HasPrefix(name, dir) && (len(name) == len(dir) || name[len(dir)] == os.PathSeparator)
+}
+
+func (r *Root) open(name string) (*os.File, error) {
+	name, err := r.abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(name)
+}
+
+func (r *Root) sysOpen(name string) (int, error) {
+	name, err := r.abs(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return unix.Open(name, unix.O_RDONLY, 0)
+}
+
+// Abs returns the absolute representation of name joined with the
+// directory of r.
+func (r *Root) Abs(name string) string {
+	name, _ = r.abs(name)
+	return name
+}
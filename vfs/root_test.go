@@ -0,0 +1,111 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "proc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "proc", "uptime"), []byte("42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root, err := NewRoot(""); err != nil || root != Host() {
+		t.Errorf("NewRoot(\"\"): want %v, got %v, %v", Host(), root, err)
+	}
+	if _, err := NewRoot(filepath.Join(dir, "proc", "uptime")); !errors.Is(err, ErrNotDir) {
+		t.Errorf("NewRoot(file): want %v, got %v", ErrNotDir, err)
+	}
+	if _, err := NewRoot(filepath.Join(dir, "missing")); !errors.Is(err, ErrNotExist) {
+		t.Errorf("NewRoot(missing): want %v, got %v", ErrNotExist, err)
+	}
+
+	n, err := root.ReadInt("/proc/uptime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Errorf("ReadInt: want 42, got %d", n)
+	}
+
+	// Names already within the root aren't joined again
+	want := filepath.Join(root.Dir(), "proc", "uptime")
+	for _, name := range []string{"/proc/uptime", want} {
+		if got := root.Abs(name); got != want {
+			t.Errorf("Abs(%q): want %q, got %q", name, want, got)
+		}
+	}
+
+	d, err := root.OpenDir("/proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer d.Close()
+
+	paths, err := d.ReadPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != want {
+		t.Fatalf("ReadPaths: want [%s], got %v", want, paths)
+	}
+	if n, err := root.ReadInt(paths[0]); err != nil || n != 42 {
+		t.Errorf("ReadInt(%q): want 42, got %d, %v", paths[0], n, err)
+	}
+}
+
+func TestRoot_Nil(t *testing.T) {
+	var root *Root
+
+	if got, want := root.Dir(), Host().Dir(); got != want {
+		t.Errorf("Dir: want %q, got %q", want, got)
+	}
+	if got, want := root.Abs("/proc"), Host().Abs("/proc"); got != want {
+		t.Errorf("Abs: want %q, got %q", want, got)
+	}
+}
+
+func TestRoot_ReadContext(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := root.ReadContext(t.Context(), "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "data" {
+		t.Errorf("ReadContext: want %q, got %q", "data", got)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := root.ReadContext(ctx, "/file"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadContext: want %v, got %v", context.Canceled, err)
+	}
+	if _, err := root.StatfsContext(ctx, "/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("StatfsContext: want %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,109 @@
+package vfs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Stat returns a FileInfo describing the named file in r.
+func (r *Root) Stat(name string) (os.FileInfo, error) {
+	name, err := r.abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Stat(name)
+}
+
+// Lstat is like [Root.Stat] but doesn't follow the named file if it's a symlink.
+func (r *Root) Lstat(name string) (os.FileInfo, error) {
+	name, err := r.abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Lstat(name)
+}
+
+// Exists reports whether the named file exists in r.
+func (r *Root) Exists(name string) bool {
+	_, err := r.Stat(name)
+	return err == nil
+}
+
+// IsDir reports whether the named file in r is a directory.
+func (r *Root) IsDir(name string) bool {
+	info, err := r.Stat(name)
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
+
+// AccessTime returns the last access time of the named file in r.
+func (r *Root) AccessTime(name string) (sec, nsec int64, err error) {
+	name, err = r.abs(name)
+	if err != nil {
+		return
+	}
+
+	var stat unix.Stat_t
+
+	if err = unix.Stat(name, &stat); err != nil {
+		return
+	}
+
+	sec, nsec = stat.Atim.Unix()
+
+	return
+}
+
+// ModifyTime returns the last modify time of the named file in r.
+func (r *Root) ModifyTime(name string) (sec, nsec int64, err error) {
+	name, err = r.abs(name)
+	if err != nil {
+		return
+	}
+
+	var stat unix.Stat_t
+
+	if err = unix.Stat(name, &stat); err != nil {
+		return
+	}
+
+	sec, nsec = stat.Mtim.Unix()
+
+	return
+}
+
+// ChangeTime returns the last change time of the named file in r.
+func (r *Root) ChangeTime(name string) (sec, nsec int64, err error) {
+	name, err = r.abs(name)
+	if err != nil {
+		return
+	}
+
+	var stat unix.Stat_t
+
+	if err = unix.Stat(name, &stat); err != nil {
+		return
+	}
+
+	sec, nsec = stat.Ctim.Unix()
+
+	return
+}
+
+// Statfs returns information about the filesystem of the named file in r.
+func (r *Root) Statfs(name string) (stat unix.Statfs_t, err error) {
+	name, err = r.abs(name)
+	if err != nil {
+		return
+	}
+
+	err = unix.Statfs(name, &stat)
+
+	return
+}
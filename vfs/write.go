@@ -0,0 +1,56 @@
+package vfs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Write writes data to the named file in r, which must already exist. This is
+// intended for writing to the attributes of sysfs, so the file is not created.
+// The file is truncated the same as a shell redirection, which sysfs ignores.
+func (r *Root) Write(name string, data []byte) error {
+	name, err := r.abs(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteString is the same as [Root.Write] but writes the contents of s.
+func (r *Root) WriteString(name, s string) error {
+	return r.Write(name, []byte(s))
+}
+
+// Writable reports whether the named file exists in r and may be written to by
+// the current process.
+func (r *Root) Writable(name string) bool {
+	name, err := r.abs(name)
+	if err != nil {
+		return false
+	}
+
+	return unix.Access(name, unix.W_OK) == nil
+}
+
+// Remove removes the named file or empty directory in r. A symlink is removed
+// instead of the file it links to.
+func (r *Root) Remove(name string) error {
+	name, err := r.abs(name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}